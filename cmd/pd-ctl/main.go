@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 
@@ -28,13 +29,19 @@ import (
 )
 
 var (
-	url    string
-	detach bool
+	url      string
+	detach   bool
+	caPath   string
+	certPath string
+	keyPath  string
 )
 
 func init() {
 	flag.StringVarP(&url, "pd", "u", "http://127.0.0.1:2379", "The pd address")
 	flag.BoolVarP(&detach, "detach", "d", false, "Run pdctl without readline")
+	flag.StringVar(&caPath, "cacert", os.Getenv("PD_CACERT"), "The path of the CA certificate to verify pd with HTTPS, or PD_CACERT")
+	flag.StringVar(&certPath, "cert", os.Getenv("PD_CERT"), "The path of the client certificate, or PD_CERT")
+	flag.StringVar(&keyPath, "key", os.Getenv("PD_KEY"), "The path of the client key, or PD_KEY")
 }
 
 func main() {
@@ -42,7 +49,10 @@ func main() {
 	if pdAddr != "" {
 		os.Args = append(os.Args, "-u", pdAddr)
 	}
-	flag.Parse()
+	flags, args := splitArgs(os.Args[1:])
+	if err := flag.CommandLine.Parse(flags); err != nil {
+		os.Exit(2)
+	}
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc,
@@ -62,19 +72,20 @@ func main() {
 		}
 	}()
 	var input []string
+	// The command is read from stdin if it is not in the arguments.
 	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
+	if len(args) == 0 && (stat.Mode()&os.ModeCharDevice) == 0 {
 		detach = true
 		b, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
-		input = strings.Split(strings.TrimSpace(string(b[:])), " ")
+		input = strings.Fields(string(b))
 	}
 	if detach {
-		pdctl.Start(append(os.Args[1:], input...))
-		return
+		args = append(args, input...)
+		os.Exit(pdctl.Start(append(args, pdFlags()...)))
 	}
 	loop()
 }
@@ -82,7 +93,8 @@ func main() {
 func loop() {
 	l, err := readline.NewEx(&readline.Config{
 		Prompt:            "\033[31m»\033[0m ",
-		HistoryFile:       "/tmp/readline.tmp",
+		HistoryFile:       historyFile(),
+		AutoComplete:      pdctl.GetCompleter(),
 		InterruptPrompt:   "^C",
 		EOFPrompt:         "^D",
 		HistorySearchFold: true,
@@ -105,8 +117,59 @@ func loop() {
 		if line == "exit" {
 			os.Exit(0)
 		}
-		args := strings.Split(strings.TrimSpace(line), " ")
-		args = append(args, "-u", url)
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		args = append(args, pdFlags()...)
 		pdctl.Start(args)
 	}
 }
+
+// pdFlags returns the flags passed to the commands to connect pd.
+func pdFlags() []string {
+	flags := []string{"-u", url}
+	for name, value := range map[string]string{"cacert": caPath, "cert": certPath, "key": keyPath} {
+		if value != "" {
+			flags = append(flags, "--"+name, value)
+		}
+	}
+	return flags
+}
+
+// splitArgs separates the flags of pd-ctl from the command, the flags of the
+// command are unknown to pd-ctl, so they are left to the command.
+func splitArgs(args []string) ([]string, []string) {
+	var flags, cmdArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var f *flag.Flag
+		if strings.HasPrefix(arg, "--") {
+			f = flag.Lookup(strings.SplitN(arg[2:], "=", 2)[0])
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			f = flag.CommandLine.ShorthandLookup(arg[1:2])
+		}
+		if f == nil {
+			cmdArgs = append(cmdArgs, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		// The value follows the flag, such as `-u 127.0.0.1:2379`.
+		hasValue := strings.Contains(arg, "=") || (!strings.HasPrefix(arg, "--") && len(arg) > 2)
+		if f.Value.Type() != "bool" && !hasValue && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags, cmdArgs
+}
+
+// historyFile returns the file to keep the command history, which is
+// ~/.pd_ctl_history by default.
+func historyFile() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "/tmp/readline.tmp"
+	}
+	return filepath.Join(home, ".pd_ctl_history")
+}
@@ -1,3 +1,21 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// pd-recover rebuilds the cluster meta in a new PD cluster after the data of
+// PD is lost, so the TiKV stores can rejoin it. The cluster ID must match the
+// one in the TiKV logs, and the alloc ID must be larger than any ID allocated
+// before, e.g. the largest store, region or peer ID in the TiKV logs and
+// plus a safe margin. The new PD cluster should be restarted after recovery.
 package main
 
 import (
@@ -12,6 +30,7 @@ import (
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 )
 
@@ -20,6 +39,7 @@ var (
 	allocID     = flag.Uint64("alloc-id", 0, "please make sure alloced ID is safe")
 	clusterID   = flag.Uint64("cluster-id", 0, "please make cluster ID match with tikv")
 	maxReplicas = flag.Int("max-replicas", 3, "max replicas is the number of replicas for each region")
+	timeout     = flag.Duration("timeout", requestTimeout, "timeout of the recovery request")
 )
 
 const (
@@ -38,12 +58,13 @@ func exitErr(err error) {
 func main() {
 	flag.Parse()
 	if *clusterID == 0 {
-		fmt.Println("please specify safe alloc-id")
-		return
+		exitErr(errors.New("please specify safe cluster-id"))
 	}
 	if *allocID == 0 {
-		fmt.Println("please specify safe cluster-id")
-		return
+		exitErr(errors.New("please specify safe alloc-id"))
+	}
+	if *maxReplicas <= 0 {
+		exitErr(errors.New("max-replicas should be positive"))
 	}
 
 	rootPath := path.Join(pdRootPath, strconv.FormatUint(*clusterID, 10))
@@ -58,7 +79,8 @@ func main() {
 	if err != nil {
 		exitErr(err)
 	}
-	ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(client.Ctx(), *timeout)
 	defer cancel()
 
 	var ops []clientv3.Op
@@ -92,8 +114,7 @@ func main() {
 		exitErr(err)
 	}
 	if !resp.Succeeded {
-		fmt.Println("failed to recover: the cluster is already bootstrapped")
-		return
+		exitErr(errors.New("failed to recover: the cluster is already bootstrapped"))
 	}
 	fmt.Println("recover success! please restart the PD cluster")
 }
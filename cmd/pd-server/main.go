@@ -15,6 +15,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -45,6 +46,18 @@ func main() {
 		log.Fatalf("parse cmd flags error: %s\n", err)
 	}
 
+	if cfg.ConfigCheck {
+		for _, msg := range cfg.WarningMsgs {
+			fmt.Println("warning:", msg)
+		}
+		if err = cfg.Check(); err != nil {
+			fmt.Println("config check failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("config check successful")
+		os.Exit(0)
+	}
+
 	err = logutil.InitLogger(&cfg.Log)
 	if err != nil {
 		log.Fatalf("initalize logger error: %s\n", err)
@@ -61,6 +74,10 @@ func main() {
 
 	metricutil.Push(&cfg.Metric)
 
+	err = server.PrepareDiscoveryCluster(cfg)
+	if err != nil {
+		log.Fatal("discovery error ", err)
+	}
 	err = server.PrepareJoinCluster(cfg)
 	if err != nil {
 		log.Fatal("join error ", err)
@@ -81,6 +98,13 @@ func main() {
 	go svr.Run()
 
 	sig := <-sc
+	for sig == syscall.SIGHUP {
+		log.Info("Got signal to reload config.")
+		if err = svr.ReloadConfig(); err != nil {
+			log.Errorf("reload config failed - %v", err)
+		}
+		sig = <-sc
+	}
 	svr.Close()
 	log.Infof("Got signal [%d] to exit.", sig)
 	switch sig {
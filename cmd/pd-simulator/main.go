@@ -0,0 +1,191 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// pd-simulator runs a case of simulated stores and regions against PD, the
+// stores send heartbeats and follow the scheduling of PD like TiKV. It
+// reports how many ticks the scheduling takes to finish the case and how
+// many changes of the regions it makes, so the changes of the schedulers can
+// be evaluated before release. PD is embedded if -pd is not set.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/faketikv"
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+	"golang.org/x/net/context"
+)
+
+var (
+	pdAddr   = flag.String("pd", "", "the URL of PD, an embedded PD is started if it's empty")
+	caseName = flag.String("case", "balance-leader", "the case to simulate")
+	listCase = flag.Bool("list", false, "list the cases")
+	tick     = flag.Duration("tick", 100*time.Millisecond, "the interval of a tick")
+	maxTicks = flag.Int64("max-ticks", 6000, "the ticks to run at most, 0 means no limit")
+	logLevel = flag.String("L", "warn", "log level: debug, info, warn, error, fatal")
+)
+
+const embeddedPDTimeout = 10 * time.Second
+
+func exitErr(err error) {
+	fmt.Println(errors.ErrorStack(err))
+	os.Exit(1)
+}
+
+func main() {
+	flag.Parse()
+	if *listCase {
+		fmt.Println(strings.Join(faketikv.CaseNames(), "\n"))
+		return
+	}
+	simCase := faketikv.NewCase(*caseName)
+	if simCase == nil {
+		exitErr(errors.Errorf("unknown case %s, the cases are %s", *caseName, strings.Join(faketikv.CaseNames(), ", ")))
+	}
+	if err := logutil.InitLogger(&logutil.LogConfig{Level: *logLevel}); err != nil {
+		exitErr(err)
+	}
+
+	finished, err := run(simCase)
+	if err != nil {
+		exitErr(err)
+	}
+	if !finished {
+		os.Exit(1)
+	}
+}
+
+// run simulates the case and prints the result, it returns whether the case
+// is finished.
+func run(simCase *faketikv.Case) (bool, error) {
+	addr := *pdAddr
+	if addr == "" {
+		svr, cleanup, err := startEmbeddedPD()
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		defer cleanup()
+		addr = svr.GetConfig().ClientUrls
+	}
+
+	driver := faketikv.NewDriver(addr, simCase)
+	defer driver.Close()
+	if err := driver.Prepare(); err != nil {
+		return false, errors.Trace(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sc
+		cancel()
+	}()
+
+	start := time.Now()
+	finished, err := driver.Run(ctx, *tick, *maxTicks)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	printResult(finished, driver, time.Since(start))
+	return finished, nil
+}
+
+func printResult(finished bool, driver *faketikv.Driver, cost time.Duration) {
+	if finished {
+		fmt.Printf("case %s finished in %d ticks (%v)\n", *caseName, driver.Ticks(), cost)
+	} else {
+		fmt.Printf("case %s not finished in %d ticks (%v)\n", *caseName, driver.Ticks(), cost)
+	}
+	stats := driver.Stats()
+	fmt.Printf("add peer: %d, remove peer: %d, transfer leader: %d\n",
+		stats.AddPeer, stats.RemovePeer, stats.TransferLeader)
+
+	leaders, regions := driver.StoreCounts()
+	stores := make([]uint64, 0, len(regions))
+	for id := range regions {
+		stores = append(stores, id)
+	}
+	sort.Sort(uint64Slice(stores))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STORE\tLEADERS\tREGIONS")
+	for _, id := range stores {
+		fmt.Fprintf(w, "%d\t%d\t%d\n", id, leaders[id], regions[id])
+	}
+	w.Flush()
+}
+
+// startEmbeddedPD starts a single PD listening on the free ports of
+// localhost, the returned function stops it and removes its data.
+func startEmbeddedPD() (*server.Server, func(), error) {
+	cfg := server.NewTestSingleConfig()
+	clientURL, err := freeURL()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	peerURL, err := freeURL()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	cfg.ClientUrls, cfg.AdvertiseClientUrls = clientURL, clientURL
+	cfg.PeerUrls, cfg.AdvertisePeerUrls = peerURL, peerURL
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", cfg.Name, peerURL)
+
+	svr := server.CreateServer(cfg)
+	cleanup := func() {
+		svr.Close()
+		os.RemoveAll(cfg.DataDir)
+	}
+	if err = svr.StartEtcd(api.NewHandler(svr)); err != nil {
+		cleanup()
+		return nil, nil, errors.Trace(err)
+	}
+	go svr.Run()
+
+	for start := time.Now(); !svr.IsLeader(); time.Sleep(100 * time.Millisecond) {
+		if time.Since(start) > embeddedPDTimeout {
+			cleanup()
+			return nil, nil, errors.New("the embedded PD is not the leader in time")
+		}
+	}
+	log.Infof("the embedded PD is started on %s", clientURL)
+	return svr, cleanup, nil
+}
+
+func freeURL() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer l.Close()
+	return fmt.Sprintf("http://%s", l.Addr()), nil
+}
+
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
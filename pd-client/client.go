@@ -14,8 +14,11 @@
 package pd
 
 import (
+	"fmt"
+	"math/rand"
 	"net"
-	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,16 +29,20 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 // Client is a PD (Placement Driver) client.
 // It should not be used after calling Close().
+// The client follows the members and the leader of PD, the requests except
+// the TSO requests are retried with backoff when the leader changes.
 type Client interface {
 	// GetClusterID gets the cluster ID from PD.
 	GetClusterID(ctx context.Context) uint64
 	// GetTS gets a timestamp from PD.
 	GetTS(ctx context.Context) (int64, int64, error)
-	// GetTSAsync gets a timestamp from PD, without block the caller.
+	// GetTSAsync gets a timestamp from PD, without block the caller unless
+	// too many requests are pending.
 	GetTSAsync(ctx context.Context) TSFuture
 	// GetRegion gets a region and its leader Peer from PD by key.
 	// The region may expire after split. Caller is responsible for caching and
@@ -43,7 +50,9 @@ type Client interface {
 	// Also it may return nil if PD finds no Region for the key temporarily,
 	// client should retry later.
 	GetRegion(ctx context.Context, key []byte) (*metapb.Region, *metapb.Peer, error)
-	// GetRegionByID gets a region and its leader Peer from PD by id.
+	// GetRegionByID gets a region and its leader Peer from PD by id, the region
+	// is nil if it's not found. It helps to resolve the region IDs in the error
+	// messages or logs.
 	GetRegionByID(ctx context.Context, regionID uint64) (*metapb.Region, *metapb.Peer, error)
 	// GetStore gets a store from PD by store id.
 	// The store may expire later. Caller is responsible for caching and taking care
@@ -62,9 +71,21 @@ type tsoRequest struct {
 }
 
 const (
+	// pdTimeout is the default timeout of a request to PD.
 	pdTimeout             = 3 * time.Second
 	maxMergeTSORequests   = 10000
 	maxInitClusterRetries = 100
+	// maxLeaderRetries is the max times to retry a request after updating the
+	// leader.
+	maxLeaderRetries = 10
+	// The backoff before retrying a request grows exponentially from
+	// retryBackoffBase to retryBackoffMax, it's long enough to elect a new
+	// leader in total.
+	retryBackoffBase = 100 * time.Millisecond
+	retryBackoffMax  = time.Second
+	// updateLeaderInterval is the interval to update the members and the leader
+	// if no request fails.
+	updateLeaderInterval = 10 * time.Second
 )
 
 var (
@@ -77,12 +98,14 @@ var (
 )
 
 type client struct {
-	urls        []string
 	clusterID   uint64
 	tsoRequests chan *tsoRequest
 
 	connMu struct {
 		sync.RWMutex
+		// urls are the client URLs of the PD members, they are updated from
+		// the members.
+		urls        []string
 		clientConns map[string]*grpc.ClientConn
 		leader      string
 	}
@@ -90,29 +113,52 @@ type client struct {
 	tsDeadlineCh  chan deadline
 	checkLeaderCh chan struct{}
 
+	// timeout is the timeout of a request to PD, a call may send several
+	// requests and it's bounded by its context.
+	timeout  time.Duration
+	security SecurityOption
+	dialOpts []grpc.DialOption
+	dialer   func(addr string, timeout time.Duration) (net.Conn, error)
+	// membersChanged is called after the leader or the members change.
+	membersChanged MembersChangedCallback
+	// grpcOpts are the options to dial the connections, they are built from
+	// the options above.
+	grpcOpts []grpc.DialOption
+
 	wg     sync.WaitGroup
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-// NewClient creates a PD client.
-func NewClient(pdAddrs []string) (Client, error) {
+// NewClient creates a PD client, the options configure how to connect PD.
+func NewClient(pdAddrs []string, opts ...ClientOption) (Client, error) {
 	log.Infof("[pd] create pd client with endpoints %v", pdAddrs)
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &client{
-		urls:          addrsToUrls(pdAddrs),
 		tsoRequests:   make(chan *tsoRequest, maxMergeTSORequests),
 		tsDeadlineCh:  make(chan deadline, 1),
 		checkLeaderCh: make(chan struct{}, 1),
+		timeout:       pdTimeout,
+		dialer:        defaultDialer,
 		ctx:           ctx,
 		cancel:        cancel,
 	}
+	c.connMu.urls = addrsToUrls(pdAddrs)
 	c.connMu.clientConns = make(map[string]*grpc.ClientConn)
+	for _, opt := range opts {
+		opt(c)
+	}
+	grpcOpts, err := c.grpcDialOptions()
+	if err != nil {
+		cancel()
+		return nil, errors.Trace(err)
+	}
+	c.grpcOpts = grpcOpts
 
 	if err := c.initClusterID(); err != nil {
 		return nil, errors.Trace(err)
 	}
-	if err := c.updateLeader(); err != nil {
+	if err := c.updateLeader(c.ctx); err != nil {
 		return nil, errors.Trace(err)
 	}
 	log.Infof("[pd] init cluster id %v", c.clusterID)
@@ -122,8 +168,6 @@ func NewClient(pdAddrs []string) (Client, error) {
 	go c.tsCancelLoop()
 	go c.leaderLoop()
 
-	// TODO: Update addrs from server continuously by using GetMember.
-
 	return c, nil
 }
 
@@ -131,7 +175,7 @@ func (c *client) initClusterID() error {
 	ctx, cancel := context.WithCancel(c.ctx)
 	defer cancel()
 	for i := 0; i < maxInitClusterRetries; i++ {
-		for _, u := range c.urls {
+		for _, u := range c.getURLs() {
 			members, err := c.getMembers(ctx, u)
 			if err != nil || members.GetHeader() == nil {
 				log.Errorf("[pd] failed to get cluster id: %v", err)
@@ -147,24 +191,67 @@ func (c *client) initClusterID() error {
 	return errors.Trace(errFailInitClusterID)
 }
 
-func (c *client) updateLeader() error {
-	ctx, cancel := context.WithCancel(c.ctx)
-	defer cancel()
-	for _, u := range c.urls {
+func (c *client) getURLs() []string {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.connMu.urls
+}
+
+// updateLeader updates the members and the leader from any member, it stops
+// once the ctx is done.
+func (c *client) updateLeader(ctx context.Context) error {
+	c.connMu.RLock()
+	oldLeader := c.connMu.leader
+	c.connMu.RUnlock()
+	urls := c.getURLs()
+	for _, u := range urls {
 		members, err := c.getMembers(ctx, u)
+		if ctx.Err() != nil {
+			return errors.Trace(ctx.Err())
+		}
 		if err != nil || members.GetLeader() == nil || len(members.GetLeader().GetClientUrls()) == 0 {
 			continue
 		}
-		if err = c.switchLeader(members.GetLeader().GetClientUrls()); err != nil {
+		urlsChanged := c.updateURLs(members.GetMembers())
+		if err = c.switchLeader(ctx, members.GetLeader().GetClientUrls()); err != nil {
 			return errors.Trace(err)
 		}
+		leader := members.GetLeader().GetClientUrls()[0]
+		if c.membersChanged != nil && (urlsChanged || leader != oldLeader) {
+			c.membersChanged(leader, c.getURLs())
+		}
 		return nil
 	}
-	return errors.Errorf("failed to get leader from %v", c.urls)
+	return errors.Errorf("failed to get leader from %v", urls)
+}
+
+// updateURLs replaces the URLs by the client URLs of the members, so the
+// client keeps working after the members change. It returns whether the URLs
+// are changed.
+func (c *client) updateURLs(members []*pdpb.Member) bool {
+	urls := make([]string, 0, len(members))
+	for _, m := range members {
+		urls = append(urls, m.GetClientUrls()...)
+	}
+	if len(urls) == 0 {
+		return false
+	}
+	sort.Strings(urls)
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if reflect.DeepEqual(c.connMu.urls, urls) {
+		return false
+	}
+	log.Infof("[pd] update member urls, old: %v, new: %v", c.connMu.urls, urls)
+	c.connMu.urls = urls
+	return true
 }
 
 func (c *client) getMembers(ctx context.Context, url string) (*pdpb.GetMembersResponse, error) {
-	cc, err := c.getOrCreateGRPCConn(url)
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	cc, err := c.getOrCreateGRPCConn(ctx, url)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -175,7 +262,7 @@ func (c *client) getMembers(ctx context.Context, url string) (*pdpb.GetMembersRe
 	return members, nil
 }
 
-func (c *client) switchLeader(addrs []string) error {
+func (c *client) switchLeader(ctx context.Context, addrs []string) error {
 	// FIXME: How to safely compare leader urls? For now, only allows one client url.
 	addr := addrs[0]
 
@@ -188,7 +275,7 @@ func (c *client) switchLeader(addrs []string) error {
 	}
 
 	log.Infof("[pd] leader switches to: %v, previous: %v", addr, oldLeader)
-	if _, err := c.getOrCreateGRPCConn(addr); err != nil {
+	if _, err := c.getOrCreateGRPCConn(ctx, addr); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -198,7 +285,9 @@ func (c *client) switchLeader(addrs []string) error {
 	return nil
 }
 
-func (c *client) getOrCreateGRPCConn(addr string) (*grpc.ClientConn, error) {
+// getOrCreateGRPCConn returns the connection to the address, dialing it is
+// bounded by the ctx and the timeout of the client if it blocks.
+func (c *client) getOrCreateGRPCConn(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 	c.connMu.RLock()
 	conn, ok := c.connMu.clientConns[addr]
 	c.connMu.RUnlock()
@@ -206,17 +295,9 @@ func (c *client) getOrCreateGRPCConn(addr string) (*grpc.ClientConn, error) {
 		return conn, nil
 	}
 
-	cc, err := grpc.Dial(addr, grpc.WithDialer(func(addr string, d time.Duration) (net.Conn, error) {
-		u, err := url.Parse(addr)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		// For tests.
-		if u.Scheme == "unix" || u.Scheme == "unixs" {
-			return net.DialTimeout(u.Scheme, u.Host, d)
-		}
-		return net.DialTimeout("tcp", u.Host, d)
-	}), grpc.WithInsecure()) // TODO: Support HTTPS.
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, addr, c.grpcOpts...)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -241,12 +322,12 @@ func (c *client) leaderLoop() {
 	for {
 		select {
 		case <-c.checkLeaderCh:
-		case <-time.After(time.Minute):
+		case <-time.After(updateLeaderInterval):
 		case <-ctx.Done():
 			return
 		}
 
-		if err := c.updateLeader(); err != nil {
+		if err := c.updateLeader(ctx); err != nil {
 			log.Errorf("[pd] failed updateLeader: %v", err)
 		}
 	}
@@ -321,7 +402,7 @@ func (c *client) tsLoop() {
 			}
 			done := make(chan struct{})
 			dl := deadline{
-				timer:  time.After(pdTimeout),
+				timer:  time.After(c.timeout),
 				done:   done,
 				cancel: cancel,
 			}
@@ -362,6 +443,7 @@ func (c *client) processTSORequests(stream pdpb.PD_TsoClient, requests []*tsoReq
 		return errors.Trace(err)
 	}
 	requestDuration.WithLabelValues("tso").Observe(time.Since(start).Seconds())
+	tsoBatchSize.Observe(float64(len(requests)))
 	if err == nil && resp.GetCount() != uint32(len(requests)) {
 		err = errTSOLength
 	}
@@ -439,8 +521,21 @@ func (c *client) GetTSAsync(ctx context.Context) TSFuture {
 	req.ctx = ctx
 	req.physical = 0
 	req.logical = 0
-	c.tsoRequests <- req
-
+	// The concurrent requests are merged into one TSO request of the stream.
+	// The caller only blocks if maxMergeTSORequests requests are piled up,
+	// until there is room for the request, ctx is done or the client is
+	// closed.
+	if c.ctx.Err() != nil {
+		req.done <- errors.Trace(errClosing)
+		return req
+	}
+	select {
+	case c.tsoRequests <- req:
+	case <-ctx.Done():
+		req.done <- errors.Trace(ctx.Err())
+	case <-c.ctx.Done():
+		req.done <- errors.Trace(errClosing)
+	}
 	return req
 }
 
@@ -474,17 +569,22 @@ func (c *client) GetTS(ctx context.Context) (int64, int64, error) {
 func (c *client) GetRegion(ctx context.Context, key []byte) (*metapb.Region, *metapb.Peer, error) {
 	start := time.Now()
 	defer func() { cmdDuration.WithLabelValues("get_region").Observe(time.Since(start).Seconds()) }()
-	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
-	resp, err := c.leaderClient().GetRegion(ctx, &pdpb.GetRegionRequest{
-		Header:    c.requestHeader(),
-		RegionKey: key,
+	var resp *pdpb.GetRegionResponse
+	err := c.readRetry(ctx, func(ctx context.Context, cli pdpb.PDClient) error {
+		var err error
+		resp, err = cli.GetRegion(ctx, &pdpb.GetRegionRequest{
+			Header:    c.requestHeader(),
+			RegionKey: key,
+		})
+		return err
 	})
 	requestDuration.WithLabelValues("get_region").Observe(time.Since(start).Seconds())
-	cancel()
 
+	if err == nil {
+		err = headerError(resp.GetHeader())
+	}
 	if err != nil {
 		cmdFailedDuration.WithLabelValues("get_region").Observe(time.Since(start).Seconds())
-		c.scheduleCheckLeader()
 		return nil, nil, errors.Trace(err)
 	}
 	return resp.GetRegion(), resp.GetLeader(), nil
@@ -493,36 +593,128 @@ func (c *client) GetRegion(ctx context.Context, key []byte) (*metapb.Region, *me
 func (c *client) GetRegionByID(ctx context.Context, regionID uint64) (*metapb.Region, *metapb.Peer, error) {
 	start := time.Now()
 	defer func() { cmdDuration.WithLabelValues("get_region_byid").Observe(time.Since(start).Seconds()) }()
-	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
-	resp, err := c.leaderClient().GetRegionByID(ctx, &pdpb.GetRegionByIDRequest{
-		Header:   c.requestHeader(),
-		RegionId: regionID,
+	var resp *pdpb.GetRegionResponse
+	err := c.readRetry(ctx, func(ctx context.Context, cli pdpb.PDClient) error {
+		var err error
+		resp, err = cli.GetRegionByID(ctx, &pdpb.GetRegionByIDRequest{
+			Header:   c.requestHeader(),
+			RegionId: regionID,
+		})
+		return err
 	})
 	requestDuration.WithLabelValues("get_region_byid").Observe(time.Since(start).Seconds())
-	cancel()
 
+	if err == nil {
+		err = headerError(resp.GetHeader())
+	}
 	if err != nil {
 		cmdFailedDuration.WithLabelValues("get_region_byid").Observe(time.Since(start).Seconds())
-		c.scheduleCheckLeader()
 		return nil, nil, errors.Trace(err)
 	}
 	return resp.GetRegion(), resp.GetLeader(), nil
 }
 
+// readRetry calls the read-only request on the leader. If it fails because
+// the leader is unavailable or the server is not the leader any more, the
+// request is also sent to the followers, which forward it to the leader, so it
+// works if only the connection to the leader is broken. Otherwise the request
+// is retried after a backoff and updating the leader.
+func (c *client) readRetry(ctx context.Context, request func(context.Context, pdpb.PDClient) error) error {
+	var err error
+	for i := 0; i < maxLeaderRetries; i++ {
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err = request(reqCtx, c.leaderClient())
+		cancel()
+		if err != nil && isRetryableError(err) && c.followerRequest(ctx, request) == nil {
+			return nil
+		}
+		if err == nil || !isRetryableError(err) || ctx.Err() != nil || i == maxLeaderRetries-1 {
+			break
+		}
+		log.Warnf("[pd] request failed, retry after updating the leader: %v", err)
+		select {
+		case <-time.After(retryBackoff(i)):
+		case <-ctx.Done():
+			return errors.Trace(err)
+		case <-c.ctx.Done():
+			return errors.Trace(errClosing)
+		}
+		if e := c.updateLeader(ctx); e != nil {
+			log.Errorf("[pd] failed updateLeader: %v", e)
+		}
+	}
+	return errors.Trace(err)
+}
+
+// followerRequest sends the request to the followers until one succeeds.
+func (c *client) followerRequest(ctx context.Context, request func(context.Context, pdpb.PDClient) error) error {
+	c.connMu.RLock()
+	leader := c.connMu.leader
+	c.connMu.RUnlock()
+
+	err := errors.New("[pd] no follower")
+	for _, u := range c.getURLs() {
+		if u == leader {
+			continue
+		}
+		cc, e := c.getOrCreateGRPCConn(ctx, u)
+		if e != nil {
+			err = e
+			continue
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err = request(reqCtx, pdpb.NewPDClient(cc))
+		cancel()
+		if err == nil {
+			log.Warnf("[pd] leader %s is unavailable, the request is served by %s", leader, u)
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Trace(err)
+}
+
+// isRetryableError returns whether the request may succeed on the new leader.
+// The not leader error of the server may be wrapped, so its code is lost.
+func isRetryableError(err error) bool {
+	switch grpc.Code(errors.Cause(err)) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return strings.Contains(err.Error(), "not leader")
+}
+
+// retryBackoff returns the backoff before the attempt-th retry, it's jittered
+// in [d/2, d) so the clients don't retry at the same time.
+func retryBackoff(attempt int) time.Duration {
+	d := retryBackoffMax
+	if attempt < 16 && retryBackoffBase<<uint(attempt) < retryBackoffMax {
+		d = retryBackoffBase << uint(attempt)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
 func (c *client) GetStore(ctx context.Context, storeID uint64) (*metapb.Store, error) {
 	start := time.Now()
 	defer func() { cmdDuration.WithLabelValues("get_store").Observe(time.Since(start).Seconds()) }()
-	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
-	resp, err := c.leaderClient().GetStore(ctx, &pdpb.GetStoreRequest{
-		Header:  c.requestHeader(),
-		StoreId: storeID,
+	var resp *pdpb.GetStoreResponse
+	err := c.readRetry(ctx, func(ctx context.Context, cli pdpb.PDClient) error {
+		var err error
+		resp, err = cli.GetStore(ctx, &pdpb.GetStoreRequest{
+			Header:  c.requestHeader(),
+			StoreId: storeID,
+		})
+		return err
 	})
 	requestDuration.WithLabelValues("get_store").Observe(time.Since(start).Seconds())
-	cancel()
 
+	if err == nil {
+		err = headerError(resp.GetHeader())
+	}
 	if err != nil {
 		cmdFailedDuration.WithLabelValues("get_store").Observe(time.Since(start).Seconds())
-		c.scheduleCheckLeader()
 		return nil, errors.Trace(err)
 	}
 	store := resp.GetStore()
@@ -535,6 +727,37 @@ func (c *client) GetStore(ctx context.Context, storeID uint64) (*metapb.Store, e
 	return store, nil
 }
 
+// HeaderError is the error in the header of a response, the callers can tell
+// the errors apart by the type.
+type HeaderError struct {
+	Type    pdpb.ErrorType
+	Message string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("[pd] %s: %s", e.Type, e.Message)
+}
+
+// ErrorType returns the type of the error in the response header, it's
+// UNKNOWN if the request fails for other reasons.
+func ErrorType(err error) pdpb.ErrorType {
+	if err == nil {
+		return pdpb.ErrorType_OK
+	}
+	if e, ok := errors.Cause(err).(*HeaderError); ok {
+		return e.Type
+	}
+	return pdpb.ErrorType_UNKNOWN
+}
+
+// headerError returns the error in the response header.
+func headerError(header *pdpb.ResponseHeader) error {
+	if err := header.GetError(); err != nil {
+		return &HeaderError{Type: err.GetType(), Message: err.GetMessage()}
+	}
+	return nil
+}
+
 func (c *client) requestHeader() *pdpb.RequestHeader {
 	return &pdpb.RequestHeader{
 		ClusterId: c.clusterID,
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -28,6 +29,7 @@ import (
 	"github.com/pingcap/pd/server/api"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 func TestClient(t *testing.T) {
@@ -181,6 +183,28 @@ func (s *testClientSuite) TestTSO(c *C) {
 	}
 }
 
+func (s *testClientSuite) TestTSOAsync(c *C) {
+	futures := make([]TSFuture, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		futures = append(futures, s.client.GetTSAsync(context.Background()))
+	}
+
+	var last int64
+	for _, f := range futures {
+		p, l, err := f.Wait()
+		c.Assert(err, IsNil)
+		c.Assert(p<<18+l, Greater, last)
+		last = p<<18 + l
+	}
+
+	// The requests fail after the client is closed.
+	cli, err := NewClient(s.srv.GetEndpoints())
+	c.Assert(err, IsNil)
+	cli.Close()
+	_, _, err = cli.GetTSAsync(context.Background()).Wait()
+	c.Assert(errors.Cause(err), Equals, errClosing)
+}
+
 func (s *testClientSuite) TestTSORace(c *C) {
 	var wg sync.WaitGroup
 	begin := make(chan struct{})
@@ -200,6 +224,42 @@ func (s *testClientSuite) TestTSORace(c *C) {
 	wg.Wait()
 }
 
+func (s *testClientSuite) TestRetryBackoff(c *C) {
+	for i := 0; i < 100; i++ {
+		d := retryBackoffBase << uint(i)
+		if i >= 16 || d > retryBackoffMax {
+			d = retryBackoffMax
+		}
+		backoff := retryBackoff(i)
+		c.Assert(backoff, GreaterEqual, d/2)
+		c.Assert(backoff, Less, d)
+	}
+}
+
+func (s *testClientSuite) TestIsRetryableError(c *C) {
+	c.Assert(isRetryableError(grpc.Errorf(codes.Unavailable, "transport is closing")), IsTrue)
+	c.Assert(isRetryableError(errors.Trace(grpc.Errorf(codes.DeadlineExceeded, "timeout"))), IsTrue)
+	// The not leader error wrapped by the server.
+	c.Assert(isRetryableError(grpc.Errorf(codes.Unknown, "rpc error: code = 14 desc = not leader")), IsTrue)
+	c.Assert(isRetryableError(grpc.Errorf(codes.Unknown, "operator not found")), IsFalse)
+}
+
+func (s *testClientSuite) TestFollowerRequest(c *C) {
+	cli, err := NewClient(s.srv.GetEndpoints())
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	// The leader is unavailable, the read-only requests are sent to the other
+	// members.
+	addr := "unix://pd_client_test_nonexistent.sock"
+	c.Assert(cli.(*client).switchLeader(context.Background(), []string{addr}), IsNil)
+	_, err = cli.GetStore(context.Background(), store.GetId())
+	c.Assert(err, IsNil)
+	cli.(*client).connMu.RLock()
+	c.Assert(cli.(*client).connMu.leader, Equals, addr)
+	cli.(*client).connMu.RUnlock()
+}
+
 func (s *testClientSuite) TestGetRegion(c *C) {
 	req := &pdpb.RegionHeartbeatRequest{
 		Header: newHeader(s.srv),
@@ -230,6 +290,11 @@ func (s *testClientSuite) TestGetRegionByID(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(r, DeepEquals, region)
 	c.Assert(leader, DeepEquals, peer)
+
+	r, leader, err = s.client.GetRegionByID(context.Background(), 100)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+	c.Assert(leader, IsNil)
 }
 
 func (s *testClientSuite) TestGetStore(c *C) {
@@ -257,4 +322,13 @@ func (s *testClientSuite) TestGetStore(c *C) {
 	n, err = s.client.GetStore(context.Background(), store.GetId())
 	c.Assert(err, IsNil)
 	c.Assert(n, IsNil)
+
+	// The errors that are not retryable fail fast.
+	start := time.Now()
+	_, err = s.client.GetStore(context.Background(), 100)
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, retryBackoffBase)
+
+	c.Assert(ErrorType(nil), Equals, pdpb.ErrorType_OK)
+	c.Assert(ErrorType(errClosing), Equals, pdpb.ErrorType_UNKNOWN)
 }
@@ -15,7 +15,9 @@ package pd
 
 import (
 	"path/filepath"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
@@ -136,6 +138,84 @@ func (s *testLeaderChangeSuite) TestLeaderChange(c *C) {
 	c.Error("failed getTS from new leader after 10 seconds")
 }
 
+func (s *testLeaderChangeSuite) TestLeaderChangeRetry(c *C) {
+	svrs, endpoints, closeFunc := s.prepareClusterN(c, 3)
+	defer closeFunc()
+
+	cli, err := NewClient(endpoints)
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	leader := s.mustGetLeader(c, cli.(*client), endpoints)
+	s.verifyLeader(c, cli.(*client), leader)
+
+	svrs[leader].Close()
+	delete(svrs, leader)
+
+	// The request to the old leader fails, it's retried until the new leader
+	// is elected.
+	n, err := cli.GetStore(context.Background(), store.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(n, DeepEquals, store)
+	cli.(*client).connMu.RLock()
+	defer cli.(*client).connMu.RUnlock()
+	c.Assert(cli.(*client).connMu.leader, Not(Equals), leader)
+}
+
+func (s *testLeaderChangeSuite) TestUpdateURLs(c *C) {
+	svrs, endpoints, closeFunc := s.prepareClusterN(c, 3)
+	defer closeFunc()
+
+	// The client is created with one member, it gets the others from PD.
+	sort.Strings(endpoints)
+	cli, err := NewClient(endpoints[:1])
+	c.Assert(err, IsNil)
+	defer cli.Close()
+	c.Assert(cli.(*client).getURLs(), DeepEquals, endpoints)
+
+	// The client keeps working after the member is closed.
+	for _, svr := range svrs {
+		if svr.GetEndpoints()[0] == endpoints[0] {
+			svr.Close()
+		}
+	}
+	_, err = cli.GetStore(context.Background(), store.GetId())
+	c.Assert(err, IsNil)
+}
+
+func (s *testLeaderChangeSuite) TestMembersChangedCallback(c *C) {
+	svrs, endpoints, closeFunc := s.prepareClusterN(c, 3)
+	defer closeFunc()
+
+	var mu sync.Mutex
+	var leaders []string
+	callback := func(leader string, urls []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		leaders = append(leaders, leader)
+		c.Assert(urls, HasLen, 3)
+	}
+	cli, err := NewClient(endpoints[:1], WithMembersChangedCallback(callback))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	// It's called when the leader is found the first time.
+	leader := s.mustGetLeader(c, cli.(*client), endpoints)
+	mu.Lock()
+	c.Assert(leaders, HasLen, 1)
+	c.Assert(leaders[0], Equals, leader)
+	mu.Unlock()
+
+	svrs[leader].Close()
+	delete(svrs, leader)
+	_, err = cli.GetStore(context.Background(), store.GetId())
+	c.Assert(err, IsNil)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(len(leaders) >= 2, IsTrue)
+	c.Assert(leaders[len(leaders)-1], Not(Equals), leader)
+}
+
 func (s *testLeaderChangeSuite) TestLeaderTransfer(c *C) {
 	servers, endpoints, closeFunc := s.prepareClusterN(c, 2)
 	defer closeFunc()
@@ -13,7 +13,10 @@
 
 package pd
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/juju/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 var (
 	cmdDuration = prometheus.NewHistogramVec(
@@ -42,10 +45,35 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of handled requests.",
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
 		}, []string{"type"})
+
+	tsoBatchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd_client",
+			Subsystem: "request",
+			Name:      "handle_tso_batch_size",
+			Help:      "Bucketed histogram of the count of merged cmds in a TSO request.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
+		})
 )
 
 func init() {
 	prometheus.MustRegister(cmdDuration)
 	prometheus.MustRegister(cmdFailedDuration)
 	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(tsoBatchSize)
+}
+
+// RegisterMetrics registers the metrics of the client to the registerer. The
+// metrics are registered to prometheus.DefaultRegisterer by default, so it is
+// only needed by the applications which export the metrics by their own
+// registry. The counts of the cmds and the requests are the counts of the
+// histograms.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{cmdDuration, cmdFailedDuration, requestDuration, tsoBatchSize}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,44 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Suite(&testMetricsSuite{})
+
+type testMetricsSuite struct{}
+
+func (s *testMetricsSuite) TestRegisterMetrics(c *C) {
+	registry := prometheus.NewRegistry()
+	c.Assert(RegisterMetrics(registry), IsNil)
+	// The metrics can't be registered twice.
+	c.Assert(RegisterMetrics(registry), NotNil)
+
+	tsoBatchSize.Observe(10)
+	families, err := registry.Gather()
+	c.Assert(err, IsNil)
+	names := make(map[string]bool)
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	c.Assert(names["pd_client_request_handle_tso_batch_size"], IsTrue)
+}
+
+func (s *testMetricsSuite) TestDefaultRegisterer(c *C) {
+	// The metrics are registered to the default registerer already.
+	c.Assert(RegisterMetrics(prometheus.DefaultRegisterer), NotNil)
+}
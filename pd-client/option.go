@@ -0,0 +1,145 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/juju/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ClientOption configures the client created by NewClient.
+type ClientOption func(c *client)
+
+// SecurityOption is the paths of the certificates to connect PD with TLS.
+type SecurityOption struct {
+	// CAPath is the path of the CA certificate to verify PD.
+	CAPath string
+	// CertPath and KeyPath are the paths of the certificate and the key of
+	// the client, they are set together if PD verifies the clients.
+	CertPath string
+	KeyPath  string
+}
+
+// ToTLSConfig loads the certificates, it returns nil if none of the paths is
+// set.
+func (s SecurityOption) ToTLSConfig() (*tls.Config, error) {
+	if s.CAPath == "" && s.CertPath == "" && s.KeyPath == "" {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{}
+	if s.CAPath != "" {
+		ca, err := ioutil.ReadFile(s.CAPath)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("failed to parse the CA certificate %s", s.CAPath)
+		}
+	}
+	if (s.CertPath == "") != (s.KeyPath == "") {
+		return nil, errors.New("the certificate and the key should be set together")
+	}
+	if s.CertPath != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// WithTimeout sets the timeout of a request to PD, the default is 3s. A call
+// may retry the requests when the leader changes, it's bounded by the context
+// of the call, so the callers set the deadlines of the contexts to limit the
+// latency of the calls.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *client) {
+		c.timeout = timeout
+	}
+}
+
+// MembersChangedCallback is called after the client finds the leader or the
+// members of PD changed, the leader is the URL of the leader, and the urls are
+// the client URLs of the members. It's called synchronously by the requests
+// that find the change, so it should return quickly.
+type MembersChangedCallback func(leader string, urls []string)
+
+// WithMembersChangedCallback sets the callback called after the leader or the
+// members change, including when the client finds the leader the first time.
+func WithMembersChangedCallback(callback MembersChangedCallback) ClientOption {
+	return func(c *client) {
+		c.membersChanged = callback
+	}
+}
+
+// WithSecurity connects PD with TLS by the certificates.
+func WithSecurity(security SecurityOption) ClientOption {
+	return func(c *client) {
+		c.security = security
+	}
+}
+
+// WithGRPCDialOptions adds the options to dial the gRPC connections to PD.
+func WithGRPCDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *client) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// WithDialer replaces the dialer of the connections to PD, such as to dial by
+// a proxy. The addr passed to the dialer is the URL of the PD member.
+func WithDialer(dialer func(addr string, timeout time.Duration) (net.Conn, error)) ClientOption {
+	return func(c *client) {
+		c.dialer = dialer
+	}
+}
+
+// defaultDialer dials the host of the URL, the unix sockets are for tests.
+func defaultDialer(addr string, timeout time.Duration) (net.Conn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if u.Scheme == "unix" || u.Scheme == "unixs" {
+		return net.DialTimeout("unix", u.Host, timeout)
+	}
+	return net.DialTimeout("tcp", u.Host, timeout)
+}
+
+// grpcDialOptions returns the options to dial the gRPC connections by the
+// client options.
+func (c *client) grpcDialOptions() ([]grpc.DialOption, error) {
+	tlsCfg, err := c.security.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	opts := []grpc.DialOption{grpc.WithDialer(c.dialer)}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	return append(opts, c.dialOpts...), nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func (s *testClientSuite) TestWithDialer(c *C) {
+	var dials int32
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return defaultDialer(addr, timeout)
+	}
+	cli, err := NewClient(s.srv.GetEndpoints(), WithDialer(dialer), WithGRPCDialOptions(grpc.WithBlock()))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	_, _, err = cli.GetTS(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(atomic.LoadInt32(&dials), Greater, int32(0))
+}
+
+func (s *testClientSuite) TestContextDeadline(c *C) {
+	cli, err := NewClient(s.srv.GetEndpoints(), WithTimeout(time.Second))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+	c.Assert(cli.(*client).timeout, Equals, time.Second)
+
+	// Nothing listens on the address, the requests keep failing.
+	addr := "unix://pd_client_test_nonexistent.sock"
+	c.Assert(cli.(*client).switchLeader(context.Background(), []string{addr}), IsNil)
+	cli.(*client).connMu.Lock()
+	cli.(*client).connMu.urls = []string{addr}
+	cli.(*client).connMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cli.GetStore(ctx, 1)
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, 500*time.Millisecond)
+}
+
+var _ = Suite(&testSecurityOptionSuite{})
+
+type testSecurityOptionSuite struct{}
+
+func (s *testSecurityOptionSuite) TestToTLSConfig(c *C) {
+	tlsCfg, err := SecurityOption{}.ToTLSConfig()
+	c.Assert(err, IsNil)
+	c.Assert(tlsCfg, IsNil)
+
+	dir, err := ioutil.TempDir("", "pd_client_test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	badPath := filepath.Join(dir, "bad.pem")
+	c.Assert(ioutil.WriteFile(badPath, []byte("not a certificate"), 0644), IsNil)
+
+	_, err = SecurityOption{CAPath: filepath.Join(dir, "missing.pem")}.ToTLSConfig()
+	c.Assert(err, NotNil)
+	_, err = SecurityOption{CAPath: badPath}.ToTLSConfig()
+	c.Assert(err, ErrorMatches, "failed to parse the CA certificate .*")
+	_, err = SecurityOption{CertPath: badPath}.ToTLSConfig()
+	c.Assert(err, ErrorMatches, "the certificate and the key should be set together")
+	_, err = SecurityOption{CertPath: badPath, KeyPath: badPath}.ToTLSConfig()
+	c.Assert(err, NotNil)
+
+	// The client isn't created with the bad certificates.
+	_, err = NewClient([]string{"127.0.0.1:0"}, WithSecurity(SecurityOption{CAPath: badPath}))
+	c.Assert(err, NotNil)
+}
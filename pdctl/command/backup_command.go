@@ -0,0 +1,88 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupPrefix  = "pd/api/v1/backup"
+	restorePrefix = "pd/api/v1/backup/restore"
+)
+
+// NewBackupCommand return a backup subcommand of rootCmd
+func NewBackupCommand() *cobra.Command {
+	b := &cobra.Command{
+		Use:   "backup <file>",
+		Short: "backup the cluster metadata to a file",
+		Run:   backupCommandFunc,
+	}
+	b.AddCommand(NewRestoreCommand())
+	return b
+}
+
+// NewRestoreCommand return a restore subcommand of backupCmd
+func NewRestoreCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "restore the cluster metadata from a backup file",
+		Run:   restoreCommandFunc,
+	}
+	return r
+}
+
+func backupCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, backupPrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to backup the cluster: %s\n", err)
+		return
+	}
+	if err = ioutil.WriteFile(args[0], []byte(r), 0644); err != nil {
+		printErrorf("Failed to write the backup file: %s\n", err)
+		return
+	}
+	fmt.Println("Success!")
+}
+
+func restoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		printErrorf("Failed to read the backup file: %s\n", err)
+		return
+	}
+	req, err := getRequest(cmd, restorePrefix, http.MethodPost, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		printErrorf("Failed to restore the cluster: %s\n", err)
+		return
+	}
+	if _, err = dail(req); err != nil {
+		printErrorf("Failed to restore the cluster: %s\n", err)
+		return
+	}
+	fmt.Println("Success!")
+}
@@ -14,29 +14,70 @@
 package command
 
 import (
-	"fmt"
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-const clusterPrefix = "pd/api/v1/cluster"
+const (
+	clusterPrefix       = "pd/api/v1/cluster"
+	clusterStatusPrefix = "pd/api/v1/cluster/status"
+)
+
+// clusterInfo combines the cluster meta and the cluster status.
+type clusterInfo struct {
+	ID                uint64     `json:"id"`
+	MaxPeerCount      uint32     `json:"max_peer_count"`
+	IsBootstrapped    bool       `json:"is_bootstrapped"`
+	RaftBootstrapTime *time.Time `json:"raft_bootstrap_time,omitempty"`
+}
 
 // NewClusterCommand return a cluster subcommand of rootCmd
 func NewClusterCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cluster",
-		Short: "show the cluster information",
+		Short: "show the cluster information, it fails if the cluster is not bootstrapped",
 		Run:   showClusterCommandFunc,
 	}
 	return cmd
 }
 
 func showClusterCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+	var info clusterInfo
 	r, err := doRequest(cmd, clusterPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get the cluster information: %s", err)
+		printErrorf("Failed to get the cluster information: %s", err)
 		return
 	}
-	fmt.Println(r)
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		printError(err)
+		return
+	}
+	r, err = doRequest(cmd, clusterStatusPrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get the cluster status: %s", err)
+		return
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		printError(err)
+		return
+	}
+	if info.RaftBootstrapTime != nil && info.RaftBootstrapTime.IsZero() {
+		info.RaftBootstrapTime = nil
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		printError(err)
+		return
+	}
+	printJSON(cmd, string(data))
+	if !info.IsBootstrapped {
+		printError("The cluster is not bootstrapped")
+	}
 }
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -27,6 +28,7 @@ var (
 	configPrefix    = "pd/api/v1/config"
 	schedulePrefix  = "pd/api/v1/config/schedule"
 	replicatePrefix = "pd/api/v1/config/replicate"
+	namespacePrefix = "pd/api/v1/namespaces"
 )
 
 // NewConfigCommand return a config subcommand of rootCmd
@@ -43,11 +45,14 @@ func NewConfigCommand() *cobra.Command {
 // NewShowConfigCommand return a show subcommand of configCmd
 func NewShowConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
-		Use:   "show",
+		Use:   "show [all|schedule|replication|namespace]",
 		Short: "show config of PD",
 		Run:   showConfigCommandFunc,
 	}
 	sc.AddCommand(NewShowAllConfigCommand())
+	sc.AddCommand(NewShowScheduleConfigCommand())
+	sc.AddCommand(NewShowReplicationConfigCommand())
+	sc.AddCommand(NewShowNamespaceConfigCommand())
 	return sc
 }
 
@@ -61,6 +66,36 @@ func NewShowAllConfigCommand() *cobra.Command {
 	return sc
 }
 
+// NewShowScheduleConfigCommand return a show schedule subcommand of show subcommand
+func NewShowScheduleConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "schedule",
+		Short: "show schedule config of PD",
+		Run:   showConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewShowReplicationConfigCommand return a show replication subcommand of show subcommand
+func NewShowReplicationConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "replication",
+		Short: "show replication config of PD",
+		Run:   showReplicationConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewShowNamespaceConfigCommand return a show namespace subcommand of show subcommand
+func NewShowNamespaceConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "namespace [<name>]",
+		Short: "show the config of all namespaces or a namespace",
+		Run:   showNamespaceConfigCommandFunc,
+	}
+	return sc
+}
+
 // NewSetConfigCommand return a set subcommand of configCmd
 func NewSetConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
@@ -72,21 +107,52 @@ func NewSetConfigCommand() *cobra.Command {
 }
 
 func showConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
 	r, err := doRequest(cmd, schedulePrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get config: %s", err)
+		printErrorf("Failed to get config: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
 }
 
 func showAllConfigCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, configPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get config: %s", err)
+		printErrorf("Failed to get config: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
+}
+
+func showReplicationConfigCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, replicatePrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get config: %s", err)
+		return
+	}
+	printJSON(cmd, r)
+}
+
+func showNamespaceConfigCommandFunc(cmd *cobra.Command, args []string) {
+	prefix := namespacePrefix
+	switch len(args) {
+	case 0:
+	case 1:
+		prefix += "/" + args[0]
+	default:
+		printError(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get config: %s", err)
+		return
+	}
+	printJSON(cmd, r)
 }
 
 func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
@@ -95,9 +161,20 @@ func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
 	val, err := strconv.ParseFloat(value, 64)
 	if err != nil {
 		val = value
+		if b, err := strconv.ParseBool(value); err == nil {
+			val = b
+		}
+	}
+	// A dotted key sets the field of a sub config, e.g. store-score.flow-weight.
+	keys := strings.Split(key, ".")
+	data[keys[len(keys)-1]] = val
+	for i := len(keys) - 2; i >= 0; i-- {
+		data = map[string]interface{}{keys[i]: data}
 	}
-	data[key] = val
 	reqData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
 	req, err := getRequest(cmd, path, http.MethodPost, "application/json", bytes.NewBuffer(reqData))
 	if err != nil {
 		return err
@@ -111,13 +188,13 @@ func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
 
 func setConfigCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 2 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 	opt, val := args[0], args[1]
 	err := postConfigDataWithPath(cmd, opt, val, configPrefix)
 	if err != nil {
-		fmt.Printf("Failed to set config: %s", err)
+		printErrorf("Failed to set config: %s", err)
 		return
 	}
 	fmt.Println("Success!")
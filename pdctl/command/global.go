@@ -35,8 +35,29 @@ var (
 
 	pingPrefix     = "pd/ping"
 	errInvalidAddr = errors.New("Invalid pd address, Cannot get connect to it")
+
+	// failed is set when a command fails, it decides the exit status of
+	// pd-ctl in the single-command mode.
+	failed bool
 )
 
+// Failed returns whether a command failed since the last call.
+func Failed() bool {
+	f := failed
+	failed = false
+	return f
+}
+
+func printError(a ...interface{}) {
+	failed = true
+	fmt.Println(a...)
+}
+
+func printErrorf(format string, a ...interface{}) {
+	failed = true
+	fmt.Printf(format, a...)
+}
+
 func getRequest(cmd *cobra.Command, prefix string, method string, bodyType string, body io.Reader) (*http.Request, error) {
 	if method == "" {
 		method = http.MethodGet
@@ -82,6 +103,18 @@ func genResponseError(r *http.Response) error {
 	return errors.Errorf("[%d] %s", r.StatusCode, res)
 }
 
+// offlineCommands are the commands which work without pd, such as decoding a
+// TSO from the logs.
+var offlineCommands = map[string]struct{}{
+	"tso": {},
+}
+
+// IsOfflineCommand returns whether the command works without pd.
+func IsOfflineCommand(cmd *cobra.Command) bool {
+	_, ok := offlineCommands[cmd.Name()]
+	return ok
+}
+
 // InitPDClient initialize pd client from cmd
 func InitPDClient(cmd *cobra.Command) error {
 	addr, err := cmd.Flags().GetString("pd")
@@ -92,17 +125,45 @@ func InitPDClient(cmd *cobra.Command) error {
 	if pdClient != nil {
 		return nil
 	}
+	security, err := getSecurityOption(cmd)
+	if err != nil {
+		return err
+	}
+	tlsCfg, err := security.ToTLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsCfg != nil {
+		dailClient.Transport = &http.Transport{TLSClientConfig: tlsCfg}
+	}
 	err = validPDAddr(addr)
 	if err != nil {
 		return err
 	}
-	pdClient, err = pd.NewClient([]string{addr})
+	pdClient, err = pd.NewClient([]string{addr}, pd.WithSecurity(security))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// getSecurityOption returns the certificates to connect pd by the --cacert,
+// --cert and --key flags.
+func getSecurityOption(cmd *cobra.Command) (pd.SecurityOption, error) {
+	var paths []string
+	for _, name := range []string{"cacert", "cert", "key"} {
+		p, err := cmd.Flags().GetString(name)
+		if err != nil {
+			return pd.SecurityOption{}, err
+		}
+		paths = append(paths, p)
+	}
+	if (paths[1] == "") != (paths[2] == "") {
+		return pd.SecurityOption{}, errors.New("--cert and --key should be set together")
+	}
+	return pd.SecurityOption{CAPath: paths[0], CertPath: paths[1], KeyPath: paths[2]}, nil
+}
+
 func getClient() (pd.Client, error) {
 	if pdClient == nil {
 		return nil, errors.New("Must initialized pdClient firstly")
@@ -129,6 +190,7 @@ func getAddressFromCmd(cmd *cobra.Command, prefix string) string {
 }
 
 func printResponseError(r *http.Response) {
+	failed = true
 	fmt.Printf("[%d]:", r.StatusCode)
 	io.Copy(os.Stdout, r.Body)
 }
@@ -142,7 +204,7 @@ func validPDAddr(pd string) error {
 		u.Scheme = "http"
 	}
 	addr := u.String()
-	reps, err := http.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
+	reps, err := dailClient.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
 	if err != nil {
 		return err
 	}
@@ -157,14 +219,14 @@ func validPDAddr(pd string) error {
 func postJSON(cmd *cobra.Command, prefix string, input map[string]interface{}) {
 	data, err := json.Marshal(input)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
 	url := getAddressFromCmd(cmd, prefix)
-	r, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	r, err := dailClient.Post(url, "application/json", bytes.NewBuffer(data))
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 	defer r.Body.Close()
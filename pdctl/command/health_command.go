@@ -0,0 +1,114 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/spf13/cobra"
+)
+
+const healthPrefix = "pd/health"
+
+// memberHealth mirrors the response of the health API of a member.
+type memberHealth struct {
+	Name   string `json:"name"`
+	Ready  bool   `json:"ready"`
+	Error  string `json:"error,omitempty"`
+	Leader bool   `json:"leader"`
+}
+
+// NewHealthCommand return a health subcommand of rootCmd
+func NewHealthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "show the health of the members, it fails if any member is not ready",
+		Run:   showHealthCommandFunc,
+	}
+	return cmd
+}
+
+func showHealthCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get pd members: %s", err)
+		return
+	}
+	var members struct {
+		Members []*pdpb.Member `json:"members"`
+	}
+	if err = json.Unmarshal([]byte(r), &members); err != nil {
+		printError(err)
+		return
+	}
+
+	results := make([]*memberHealth, 0, len(members.Members))
+	healthy := true
+	for _, m := range members.Members {
+		h := getMemberHealth(m)
+		healthy = healthy && h.Ready
+		results = append(results, h)
+	}
+	if isJSONOutput(cmd) {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			printError(err)
+			return
+		}
+		printJSON(cmd, string(data))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tLEADER\tREADY\tERROR")
+		for _, h := range results {
+			fmt.Fprintf(w, "%s\t%v\t%v\t%s\n", h.Name, h.Leader, h.Ready, h.Error)
+		}
+		w.Flush()
+	}
+	if !healthy {
+		printError("Some members are not ready")
+	}
+}
+
+// getMemberHealth requests the health API of the member by its first client
+// URL, the member is not ready if it can't be reached.
+func getMemberHealth(m *pdpb.Member) *memberHealth {
+	h := &memberHealth{Name: m.GetName()}
+	if len(m.GetClientUrls()) == 0 {
+		h.Error = "no client URL"
+		return h
+	}
+	url := strings.TrimSuffix(m.GetClientUrls()[0], "/") + "/" + healthPrefix
+	resp, err := dailClient.Get(url)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	defer resp.Body.Close()
+	// The health API responds the health with 503 if the member is not
+	// ready, so the body is decoded regardless of the status code.
+	if err = json.NewDecoder(resp.Body).Decode(h); err != nil {
+		h.Ready, h.Error = false, fmt.Sprintf("[%d] %v", resp.StatusCode, err)
+	}
+	return h
+}
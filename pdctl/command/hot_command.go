@@ -14,62 +14,237 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
+	"text/tabwriter"
 
+	gh "github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 )
 
 const (
-	hotRegionsPrefix = "pd/api/v1/hotspot/regions"
-	hotStoresPrefix  = "pd/api/v1/hotspot/stores"
+	hotRegionsPrefix     = "pd/api/v1/hotspot/regions"
+	hotReadRegionsPrefix = "pd/api/v1/hotspot/regions/read"
+	hotStoresPrefix      = "pd/api/v1/hotspot/stores"
 )
 
+// hotRegionStat mirrors the hot region statistics in the response of the
+// hotspot API.
+type hotRegionStat struct {
+	RegionID     uint64 `json:"region_id"`
+	WrittenBytes uint64 `json:"written_bytes"`
+	ReadBytes    uint64 `json:"read_bytes"`
+	HotDegree    int    `json:"hot_degree"`
+	StoreID      uint64 `json:"-"`
+}
+
+type hotRegionsStat struct {
+	WrittenBytes uint64          `json:"total_written_bytes"`
+	ReadBytes    uint64          `json:"total_read_bytes"`
+	RegionsCount int             `json:"regions_count"`
+	RegionsStat  []hotRegionStat `json:"statistics"`
+}
+
+type storeHotRegionInfos struct {
+	AsPeer   map[uint64]*hotRegionsStat `json:"as_peer"`
+	AsLeader map[uint64]*hotRegionsStat `json:"as_leader"`
+}
+
+// hotRegionsByWritten sorts the hot regions by the written bytes in
+// descending order, and by the region id for the same written bytes.
+type hotRegionsByWritten []hotRegionStat
+
+func (r hotRegionsByWritten) Len() int      { return len(r) }
+func (r hotRegionsByWritten) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r hotRegionsByWritten) Less(i, j int) bool {
+	if r[i].WrittenBytes != r[j].WrittenBytes {
+		return r[i].WrittenBytes > r[j].WrittenBytes
+	}
+	return r[i].RegionID < r[j].RegionID
+}
+
+// hotRegionsByRead sorts the hot regions by the read bytes in descending
+// order, and by the region id for the same read bytes.
+type hotRegionsByRead []hotRegionStat
+
+func (r hotRegionsByRead) Len() int      { return len(r) }
+func (r hotRegionsByRead) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r hotRegionsByRead) Less(i, j int) bool {
+	if r[i].ReadBytes != r[j].ReadBytes {
+		return r[i].ReadBytes > r[j].ReadBytes
+	}
+	return r[i].RegionID < r[j].RegionID
+}
+
+// storesByWritten sorts the store ids by the written bytes of the stores in
+// descending order, and by the id for the same written bytes.
+type storesByWritten struct {
+	ids     []uint64
+	written map[uint64]uint64
+}
+
+func (s *storesByWritten) Len() int      { return len(s.ids) }
+func (s *storesByWritten) Swap(i, j int) { s.ids[i], s.ids[j] = s.ids[j], s.ids[i] }
+func (s *storesByWritten) Less(i, j int) bool {
+	if s.written[s.ids[i]] != s.written[s.ids[j]] {
+		return s.written[s.ids[i]] > s.written[s.ids[j]]
+	}
+	return s.ids[i] < s.ids[j]
+}
+
 // NewHotSpotCommand return a hot subcommand of rootCmd
 func NewHotSpotCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "hot",
 		Short: "show the hotspot status of the cluster",
 	}
-	cmd.AddCommand(NewHotRegionCommand())
+	cmd.AddCommand(NewHotWriteRegionCommand())
+	cmd.AddCommand(NewHotReadRegionCommand())
 	cmd.AddCommand(NewHotStoreCommand())
 	return cmd
 }
 
-// NewHotRegionCommand return a hot regions subcommand of hotSpotCmd
-func NewHotRegionCommand() *cobra.Command {
+// NewHotWriteRegionCommand return a hot write subcommand of hotSpotCmd
+func NewHotWriteRegionCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "region",
-		Short: "show the hot regions",
-		Run:   showHotRegionsCommandFunc,
+		Use:     "write",
+		Aliases: []string{"region"},
+		Short:   "show the hot write regions ranked by the written bytes",
+		Run:     showHotWriteRegionsCommandFunc,
 	}
 	return cmd
 }
 
-func showHotRegionsCommandFunc(cmd *cobra.Command, args []string) {
-	r, err := doRequest(cmd, hotRegionsPrefix, http.MethodGet)
-	if err != nil {
-		fmt.Printf("Failed to get hotspot: %s", err)
-		return
+// NewHotReadRegionCommand return a hot read subcommand of hotSpotCmd
+func NewHotReadRegionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "read",
+		Short: "show the hot read regions ranked by the read bytes",
+		Run:   showHotReadRegionsCommandFunc,
 	}
-	fmt.Println(r)
+	return cmd
 }
 
 // NewHotStoreCommand return a hot stores subcommand of hotSpotCmd
 func NewHotStoreCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "store",
-		Short: "show the hot stores",
+		Short: "show the stores ranked by the written bytes",
 		Run:   showHotStoresCommandFunc,
 	}
 	return cmd
 }
 
+func showHotWriteRegionsCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, hotRegionsPrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get hotspot: %s", err)
+		return
+	}
+	if isJSONOutput(cmd) {
+		printJSON(cmd, r)
+		return
+	}
+	var infos storeHotRegionInfos
+	if err = json.Unmarshal([]byte(r), &infos); err != nil {
+		printError(err)
+		return
+	}
+	regions := hotLeaderRegions(&infos)
+	sort.Sort(hotRegionsByWritten(regions))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tREGION\tLEADER STORE\tWRITTEN\tHOT DEGREE")
+	for i, s := range regions {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%d\n", i+1, s.RegionID, s.StoreID, gh.IBytes(s.WrittenBytes), s.HotDegree)
+	}
+	w.Flush()
+}
+
+func showHotReadRegionsCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, hotReadRegionsPrefix, http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get hotspot: %s", err)
+		return
+	}
+	if isJSONOutput(cmd) {
+		printJSON(cmd, r)
+		return
+	}
+	var infos storeHotRegionInfos
+	if err = json.Unmarshal([]byte(r), &infos); err != nil {
+		printError(err)
+		return
+	}
+	regions := hotLeaderRegions(&infos)
+	sort.Sort(hotRegionsByRead(regions))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tREGION\tLEADER STORE\tREAD\tHOT DEGREE")
+	for i, s := range regions {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%d\n", i+1, s.RegionID, s.StoreID, gh.IBytes(s.ReadBytes), s.HotDegree)
+	}
+	w.Flush()
+}
+
+// hotLeaderRegions returns the hot regions by their leader stores, each hot
+// region is counted once.
+func hotLeaderRegions(infos *storeHotRegionInfos) []hotRegionStat {
+	var regions []hotRegionStat
+	for storeID, stat := range infos.AsLeader {
+		for _, s := range stat.RegionsStat {
+			s.StoreID = storeID
+			regions = append(regions, s)
+		}
+	}
+	return regions
+}
+
 func showHotStoresCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, hotStoresPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get hotspot: %s", err)
+		printErrorf("Failed to get hotspot: %s", err)
+		return
+	}
+	if isJSONOutput(cmd) {
+		printJSON(cmd, r)
 		return
 	}
-	fmt.Println(r)
+	var written map[uint64]uint64
+	if err = json.Unmarshal([]byte(r), &written); err != nil {
+		printError(err)
+		return
+	}
+	var write, read storeHotRegionInfos
+	for prefix, infos := range map[string]*storeHotRegionInfos{hotRegionsPrefix: &write, hotReadRegionsPrefix: &read} {
+		r, err = doRequest(cmd, prefix, http.MethodGet)
+		if err != nil {
+			printErrorf("Failed to get hotspot: %s", err)
+			return
+		}
+		if err = json.Unmarshal([]byte(r), infos); err != nil {
+			printError(err)
+			return
+		}
+	}
+
+	stores := make([]uint64, 0, len(written))
+	for id := range written {
+		stores = append(stores, id)
+	}
+	sort.Sort(&storesByWritten{ids: stores, written: written})
+	count := func(stats map[uint64]*hotRegionsStat, id uint64) int {
+		if stat, ok := stats[id]; ok {
+			return stat.RegionsCount
+		}
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tSTORE\tWRITTEN\tHOT WRITE LEADERS\tHOT WRITE PEERS\tHOT READ LEADERS")
+	for i, id := range stores {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%d\t%d\t%d\n", i+1, id, gh.IBytes(written[id]),
+			count(write.AsLeader, id), count(write.AsPeer, id), count(read.AsLeader, id))
+	}
+	w.Flush()
 }
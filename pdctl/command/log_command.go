@@ -0,0 +1,93 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/spf13/cobra"
+)
+
+const logPrefix = "pd/api/v1/admin/log"
+
+// NewLogCommand return a log subcommand of rootCmd
+func NewLogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log <level> [member_name]",
+		Short: "set the log level of the member, which is the pd in the address flag by default",
+		Run:   setLogLevelCommandFunc,
+	}
+	return cmd
+}
+
+func setLogLevelCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 && len(args) != 2 {
+		printError(cmd.UsageString())
+		return
+	}
+	url := getAddressFromCmd(cmd, logPrefix)
+	if len(args) == 2 {
+		addr, err := getMemberClientURL(cmd, args[1])
+		if err != nil {
+			printErrorf("Failed to get the member: %s\n", err)
+			return
+		}
+		url = strings.TrimSuffix(addr, "/") + "/" + logPrefix
+	}
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		printError(err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+	if err != nil {
+		printError(err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err = dail(req); err != nil {
+		printErrorf("Failed to set the log level: %s\n", err)
+		return
+	}
+	fmt.Println("Success!")
+}
+
+// getMemberClientURL returns the first client URL of the member.
+func getMemberClientURL(cmd *cobra.Command, name string) (string, error) {
+	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
+	if err != nil {
+		return "", err
+	}
+	var members struct {
+		Members []*pdpb.Member `json:"members"`
+	}
+	if err = json.Unmarshal([]byte(r), &members); err != nil {
+		return "", err
+	}
+	for _, m := range members.Members {
+		if m.GetName() == name {
+			if len(m.GetClientUrls()) == 0 {
+				return "", errors.Errorf("member %s has no client URL", name)
+			}
+			return m.GetClientUrls()[0], nil
+		}
+	}
+	return "", errors.Errorf("member %s not found", name)
+}
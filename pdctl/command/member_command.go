@@ -16,6 +16,7 @@ package command
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
@@ -29,63 +30,168 @@ var (
 // NewMemberCommand return a member subcommand of rootCmd
 func NewMemberCommand() *cobra.Command {
 	m := &cobra.Command{
-		Use:   "member [leader|delete]",
+		Use:   "member [leader|delete|leader_priority]",
 		Short: "show the pd member status",
 		Run:   showMemberCommandFunc,
 	}
 	m.AddCommand(NewLeaderMemberCommand())
 	m.AddCommand(NewDeleteMemberCommand())
+	m.AddCommand(NewLeaderPriorityMemberCommand())
 	return m
 }
 
+// NewLeaderPriorityMemberCommand return a leader priority subcommand of memberCmd
+func NewLeaderPriorityMemberCommand() *cobra.Command {
+	l := &cobra.Command{
+		Use:   "leader_priority <member_name> <priority>",
+		Short: "set the priority of the member to be the leader",
+		Run:   setLeaderPriorityMemberCommandFunc,
+	}
+	return l
+}
+
 // NewDeleteMemberCommand return a delete subcommand of memberCmd
 func NewDeleteMemberCommand() *cobra.Command {
 	d := &cobra.Command{
-		Use:   "delete <member_name>",
-		Short: "delete the member",
+		Use:   "delete [name|id] <member>",
+		Short: "delete the member by the name or the id",
 		Run:   deleteMemberCommandFunc,
 	}
+	d.AddCommand(&cobra.Command{
+		Use:   "name <member_name>",
+		Short: "delete the member by the name",
+		Run:   deleteMemberByNameCommandFunc,
+	})
+	d.AddCommand(&cobra.Command{
+		Use:   "id <member_id>",
+		Short: "delete the member by the id",
+		Run:   deleteMemberByIDCommandFunc,
+	})
 	return d
 }
 
 // NewLeaderMemberCommand return a leader subcommand of memberCmd
 func NewLeaderMemberCommand() *cobra.Command {
 	l := &cobra.Command{
-		Use:   "leader",
-		Short: "show the leader member status",
+		Use:   "leader [show|resign|transfer]",
+		Short: "show the leader member status, resign or transfer the leader",
 		Run:   getLeaderMemberCommandFunc,
 	}
+	l.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "show the leader member status",
+		Run:   getLeaderMemberCommandFunc,
+	})
+	l.AddCommand(&cobra.Command{
+		Use:   "resign",
+		Short: "resign the leader, the other members campaign first",
+		Run:   resignLeaderMemberCommandFunc,
+	})
+	l.AddCommand(&cobra.Command{
+		Use:   "transfer <member_name>",
+		Short: "transfer the leader to the member",
+		Run:   transferLeaderMemberCommandFunc,
+	})
 	return l
 }
 
 func showMemberCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get pd members: %s", err)
+		printErrorf("Failed to get pd members: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
 }
 
 func deleteMemberCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: member delete <member_name>")
+		printError(cmd.UsageString())
+		return
+	}
+	deleteMember(cmd, args[0], fmt.Sprintf(memberPrefix, args[0]))
+}
+
+func deleteMemberByNameCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
 		return
 	}
-	prefix := fmt.Sprintf(memberPrefix, args[0])
+	deleteMember(cmd, args[0], fmt.Sprintf(memberPrefix, "name/"+args[0]))
+}
+
+func deleteMemberByIDCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("member_id should be a number")
+		return
+	}
+	deleteMember(cmd, args[0], fmt.Sprintf(memberPrefix, "id/"+args[0]))
+}
+
+func deleteMember(cmd *cobra.Command, member, prefix string) {
 	_, err := doRequest(cmd, prefix, http.MethodDelete)
 	if err != nil {
-		fmt.Printf("Failed to delete member %s: %s", args[0], err)
+		printErrorf("Failed to delete member %s: %s", member, err)
 		return
 	}
 	fmt.Println("Success!")
 }
 
+func setLeaderPriorityMemberCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		printError("Usage: member leader_priority <member_name> <priority>")
+		return
+	}
+	priority, err := strconv.Atoi(args[1])
+	if err != nil {
+		printError("priority should be a number")
+		return
+	}
+	input := map[string]interface{}{
+		"leader-priority": priority,
+	}
+	postJSON(cmd, fmt.Sprintf(memberPrefix, args[0]), input)
+}
+
 func getLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
 	r, err := doRequest(cmd, leaderMemberPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get the leader of pd members: %s", err)
+		printErrorf("Failed to get the leader of pd members: %s", err)
+		return
+	}
+	printJSON(cmd, r)
+}
+
+func resignLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+	_, err := doRequest(cmd, leaderMemberPrefix+"/resign", http.MethodPost)
+	if err != nil {
+		printErrorf("Failed to resign the leader: %s", err)
+		return
+	}
+	fmt.Println("Success!")
+}
+
+func transferLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	_, err := doRequest(cmd, leaderMemberPrefix+"/transfer/"+args[0], http.MethodPost)
+	if err != nil {
+		printErrorf("Failed to transfer the leader to %s: %s", args[0], err)
 		return
 	}
-	fmt.Println(r)
+	fmt.Println("Success!")
 }
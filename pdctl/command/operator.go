@@ -33,6 +33,8 @@ func NewOperatorCommand() *cobra.Command {
 		Short: "operator commands",
 	}
 	c.AddCommand(NewShowOperatorCommand())
+	c.AddCommand(NewOperatorHistoryCommand())
+	c.AddCommand(NewOperatorDryRunCommand())
 	c.AddCommand(NewAddOperatorCommand())
 	c.AddCommand(NewRemoveOperatorCommand())
 	return c
@@ -55,16 +57,72 @@ func showOperatorCommandFunc(cmd *cobra.Command, args []string) {
 	} else if len(args) == 1 {
 		path = fmt.Sprintf("%s?kind=%s", operatorsPrefix, args[0])
 	} else {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	r, err := doRequest(cmd, path, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
+}
+
+// NewOperatorHistoryCommand returns a command to show the finished operators.
+func NewOperatorHistoryCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "history [start_time]",
+		Short: "show the operators finished since the start time, which is a unix timestamp",
+		Run:   showOperatorHistoryCommandFunc,
+	}
+	return c
+}
+
+func showOperatorHistoryCommandFunc(cmd *cobra.Command, args []string) {
+	path := operatorsPrefix + "/history"
+	if len(args) == 1 {
+		if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+			printError(err)
+			return
+		}
+		path = fmt.Sprintf("%s?start=%s", path, args[0])
+	} else if len(args) > 1 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	r, err := doRequest(cmd, path, http.MethodGet)
+	if err != nil {
+		printError(err)
+		return
+	}
+	printJSON(cmd, r)
+}
+
+// NewOperatorDryRunCommand returns a command to show the operators created in
+// dry-run mode.
+func NewOperatorDryRunCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "dry-run",
+		Short: "show the operators created by the schedulers in dry-run mode",
+		Run:   showOperatorDryRunCommandFunc,
+	}
+	return c
+}
+
+func showOperatorDryRunCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	r, err := doRequest(cmd, operatorsPrefix+"/dry-run", http.MethodGet)
+	if err != nil {
+		printError(err)
+		return
+	}
+	printJSON(cmd, r)
 }
 
 // NewAddOperatorCommand returns a command to add operators.
@@ -76,6 +134,8 @@ func NewAddOperatorCommand() *cobra.Command {
 	c.AddCommand(NewTransferLeaderCommand())
 	c.AddCommand(NewTransferRegionCommand())
 	c.AddCommand(NewTransferPeerCommand())
+	c.AddCommand(NewAddPeerCommand())
+	c.AddCommand(NewRemovePeerCommand())
 	return c
 }
 
@@ -91,13 +151,13 @@ func NewTransferLeaderCommand() *cobra.Command {
 
 func transferLeaderCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 2 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
@@ -120,13 +180,13 @@ func NewTransferRegionCommand() *cobra.Command {
 
 func transferRegionCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) <= 2 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
@@ -149,13 +209,13 @@ func NewTransferPeerCommand() *cobra.Command {
 
 func transferPeerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 3 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
@@ -167,6 +227,64 @@ func transferPeerCommandFunc(cmd *cobra.Command, args []string) {
 	postJSON(cmd, operatorsPrefix, input)
 }
 
+// NewAddPeerCommand returns a command to add a peer of a region.
+func NewAddPeerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add-peer <region_id> <to_store_id>",
+		Short: "add a region peer on the specified store",
+		Run:   addPeerCommandFunc,
+	}
+	return c
+}
+
+func addPeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+// NewRemovePeerCommand returns a command to remove a peer of a region.
+func NewRemovePeerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "remove-peer <region_id> <from_store_id>",
+		Short: "remove the region peer on the specified store",
+		Run:   removePeerCommandFunc,
+	}
+	return c
+}
+
+func removePeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
 // NewRemoveOperatorCommand returns a command to remove operators.
 func NewRemoveOperatorCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -179,14 +297,14 @@ func NewRemoveOperatorCommand() *cobra.Command {
 
 func removeOperatorCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	path := operatorsPrefix + "/" + args[0]
 	_, err := doRequest(cmd, path, http.MethodDelete)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 }
@@ -0,0 +1,389 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+	"github.com/spf13/cobra"
+)
+
+// The formats of the --output flag.
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+// CheckOutputFlags checks the --output and --jq flags of the command.
+func CheckOutputFlags(cmd *cobra.Command) error {
+	if output := getOutputFormat(cmd); output != "" && output != outputJSON && output != outputTable {
+		return errors.Errorf("unknown output format %s, it should be json or table", output)
+	}
+	_, err := parseSelector(getSelector(cmd))
+	return err
+}
+
+func getOutputFormat(cmd *cobra.Command) string {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return ""
+	}
+	return output
+}
+
+func getSelector(cmd *cobra.Command) string {
+	s, err := cmd.Flags().GetString("jq")
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// isJSONOutput returns whether the command should print the JSON of the
+// response by printJSON instead of its own format, which is the case when
+// --output=json or --jq is set.
+func isJSONOutput(cmd *cobra.Command) bool {
+	return getOutputFormat(cmd) == outputJSON || getSelector(cmd) != ""
+}
+
+// printJSON prints the JSON data by the --jq and --output flags. The fields
+// are selected by --jq, then they are printed as indented JSON, the strings
+// are printed without quotes, or as a table with --output=table.
+func printJSON(cmd *cobra.Command, data string) {
+	selector := getSelector(cmd)
+	output := getOutputFormat(cmd)
+	if selector == "" && output != outputTable {
+		fmt.Println(data)
+		return
+	}
+	steps, err := parseSelector(selector)
+	if err != nil {
+		printError(err)
+		return
+	}
+	v, err := decodeOrderedJSON(data)
+	if err != nil {
+		printErrorf("Failed to parse the response: %s\n", err)
+		return
+	}
+	values, err := selectJSON(v, steps)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	if output == outputTable {
+		if len(values) == 1 {
+			printTable(os.Stdout, values[0])
+		} else {
+			printTable(os.Stdout, values)
+		}
+		return
+	}
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			fmt.Println(s)
+			continue
+		}
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println(string(b))
+	}
+}
+
+// orderedObject is a JSON object which keeps the order of the keys, so the
+// fields are printed in the order of the response.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// decodeOrderedJSON decodes the JSON data, the objects are decoded as
+// orderedObject and the numbers as json.Number to keep the uint64 IDs.
+func decodeOrderedJSON(data string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after the JSON value")
+	}
+	return v, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch t {
+	case json.Delim('{'):
+		o := &orderedObject{values: make(map[string]interface{})}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			key := k.(string)
+			v, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if _, ok := o.values[key]; !ok {
+				o.keys = append(o.keys, key)
+			}
+			o.values[key] = v
+		}
+		_, err = dec.Token()
+		return o, errors.Trace(err)
+	case json.Delim('['):
+		a := []interface{}{}
+		for dec.More() {
+			v, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			a = append(a, v)
+		}
+		_, err = dec.Token()
+		return a, errors.Trace(err)
+	}
+	return t, nil
+}
+
+// selectorStep is a step of the --jq selector, which gets a field of an
+// object, an element of an array, or iterates the elements of an array.
+type selectorStep struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// parseSelector parses the jq-like selector, such as `.stores[].store.id`.
+// The selector `.` or "" selects the whole value.
+func parseSelector(s string) ([]selectorStep, error) {
+	if s == "" || s == "." {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, ".") {
+		return nil, errors.Errorf("invalid selector %s, it should start with '.'", s)
+	}
+	var steps []selectorStep
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			j := i + 1
+			for j < len(s) && s[j] != '.' && s[j] != '[' && s[j] != ']' {
+				j++
+			}
+			if j == i+1 {
+				if j < len(s) && s[j] == '[' {
+					i = j
+					continue
+				}
+				return nil, errors.Errorf("invalid selector %s, the field name is empty", s)
+			}
+			steps = append(steps, selectorStep{key: s[i+1 : j]})
+			i = j
+		case '[':
+			j := strings.IndexByte(s[i:], ']')
+			if j < 0 {
+				return nil, errors.Errorf("invalid selector %s, ']' is missing", s)
+			}
+			index := s[i+1 : i+j]
+			if index == "" {
+				steps = append(steps, selectorStep{iterate: true})
+			} else {
+				n, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, errors.Errorf("invalid selector %s, the index %s is not a number", s, index)
+				}
+				steps = append(steps, selectorStep{index: n, isIndex: true})
+			}
+			i += j + 1
+		default:
+			return nil, errors.Errorf("invalid selector %s", s)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSON selects the values by the steps, a missing field or element is
+// selected as null like jq.
+func selectJSON(v interface{}, steps []selectorStep) ([]interface{}, error) {
+	values := []interface{}{v}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range values {
+			if v == nil {
+				next = append(next, nil)
+				continue
+			}
+			switch {
+			case step.iterate:
+				a, ok := v.([]interface{})
+				if !ok {
+					return nil, errors.New("cannot iterate over a value which is not an array")
+				}
+				next = append(next, a...)
+			case step.isIndex:
+				a, ok := v.([]interface{})
+				if !ok {
+					return nil, errors.New("cannot index a value which is not an array")
+				}
+				index := step.index
+				if index < 0 {
+					index += len(a)
+				}
+				if index < 0 || index >= len(a) {
+					next = append(next, nil)
+				} else {
+					next = append(next, a[index])
+				}
+			default:
+				o, ok := v.(*orderedObject)
+				if !ok {
+					return nil, errors.Errorf("cannot get the field %s of a value which is not an object", step.key)
+				}
+				next = append(next, o.values[step.key])
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// printTable prints the value as a table. The objects in an array are the
+// rows, and their nested fields are the columns, such as `store.id`. The
+// fields of an object are printed in the rows of KEY and VALUE.
+func printTable(out io.Writer, v interface{}) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	switch v := v.(type) {
+	case *orderedObject:
+		fields := flattenObject(v)
+		fmt.Fprintln(w, "KEY\tVALUE")
+		for _, k := range fields.keys {
+			fmt.Fprintf(w, "%s\t%s\n", k, formatTableCell(fields.values[k]))
+		}
+	case []interface{}:
+		var rows []*orderedObject
+		columns := &orderedObject{values: make(map[string]interface{})}
+		for _, e := range v {
+			o, ok := e.(*orderedObject)
+			if !ok {
+				o = &orderedObject{keys: []string{"VALUE"}, values: map[string]interface{}{"VALUE": e}}
+			} else {
+				o = flattenObject(o)
+			}
+			for _, k := range o.keys {
+				if _, ok := columns.values[k]; !ok {
+					columns.keys = append(columns.keys, k)
+					columns.values[k] = nil
+				}
+			}
+			rows = append(rows, o)
+		}
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(columns.keys, "\t")))
+		for _, o := range rows {
+			cells := make([]string, 0, len(columns.keys))
+			for _, k := range columns.keys {
+				cells = append(cells, formatTableCell(o.values[k]))
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	default:
+		fmt.Fprintln(w, formatTableCell(v))
+	}
+}
+
+// flattenObject flattens the nested objects, the keys of their fields are
+// joined by '.'.
+func flattenObject(o *orderedObject) *orderedObject {
+	flat := &orderedObject{values: make(map[string]interface{})}
+	for _, k := range o.keys {
+		nested, ok := o.values[k].(*orderedObject)
+		if !ok {
+			flat.keys = append(flat.keys, k)
+			flat.values[k] = o.values[k]
+			continue
+		}
+		nested = flattenObject(nested)
+		for _, nk := range nested.keys {
+			key := k + "." + nk
+			flat.keys = append(flat.keys, key)
+			flat.values[key] = nested.values[nk]
+		}
+	}
+	return flat
+}
+
+// formatTableCell formats a value in a table cell, the arrays are printed as
+// compact JSON.
+func formatTableCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/spf13/cobra"
+)
+
+func TestCommand(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testOutputSuite{})
+
+type testOutputSuite struct{}
+
+const testStoresJSON = `{
+  "count": 2,
+  "stores": [
+    {"store": {"id": 1, "address": "127.0.0.1:20160"}, "status": {"leader_count": 3}},
+    {"store": {"id": 18446744073709551615, "address": "127.0.0.1:20161"}, "status": {"leader_count": 0}}
+  ]
+}`
+
+func newTestOutputCommand(output, selector string) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("output", output, "")
+	cmd.Flags().String("jq", selector, "")
+	return cmd
+}
+
+func (s *testOutputSuite) TestCheckOutputFlags(c *C) {
+	c.Assert(CheckOutputFlags(newTestOutputCommand("", "")), IsNil)
+	c.Assert(CheckOutputFlags(newTestOutputCommand("json", ".count")), IsNil)
+	c.Assert(CheckOutputFlags(newTestOutputCommand("table", ".stores[]")), IsNil)
+	c.Assert(CheckOutputFlags(newTestOutputCommand("yaml", "")), NotNil)
+	c.Assert(CheckOutputFlags(newTestOutputCommand("", "count")), NotNil)
+
+	c.Assert(isJSONOutput(newTestOutputCommand("", "")), IsFalse)
+	c.Assert(isJSONOutput(newTestOutputCommand("table", "")), IsFalse)
+	c.Assert(isJSONOutput(newTestOutputCommand("json", "")), IsTrue)
+	c.Assert(isJSONOutput(newTestOutputCommand("", ".count")), IsTrue)
+}
+
+func (s *testOutputSuite) TestParseSelector(c *C) {
+	steps, err := parseSelector("")
+	c.Assert(err, IsNil)
+	c.Assert(steps, HasLen, 0)
+	steps, err = parseSelector(".")
+	c.Assert(err, IsNil)
+	c.Assert(steps, HasLen, 0)
+
+	steps, err = parseSelector(".stores[].store.id")
+	c.Assert(err, IsNil)
+	c.Assert(steps, DeepEquals, []selectorStep{
+		{key: "stores"},
+		{iterate: true},
+		{key: "store"},
+		{key: "id"},
+	})
+	steps, err = parseSelector(".[1].name")
+	c.Assert(err, IsNil)
+	c.Assert(steps, DeepEquals, []selectorStep{
+		{index: 1, isIndex: true},
+		{key: "name"},
+	})
+
+	for _, s := range []string{"stores", ".stores[", ".stores[a]", "..count", ".stores]"} {
+		_, err = parseSelector(s)
+		c.Assert(err, NotNil, Commentf("selector %s", s))
+	}
+}
+
+func (s *testOutputSuite) TestSelectJSON(c *C) {
+	v, err := decodeOrderedJSON(testStoresJSON)
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		selector string
+		expect   string
+	}{
+		{".count", `[2]`},
+		{".stores[].store.id", `[1,18446744073709551615]`},
+		{".stores[-1].store.address", `["127.0.0.1:20161"]`},
+		{".stores[2].store", `[null]`},
+		{".missing.field", `[null]`},
+		{".stores[0].status", `[{"leader_count":3}]`},
+	}
+	for _, t := range testCases {
+		steps, err := parseSelector(t.selector)
+		c.Assert(err, IsNil)
+		values, err := selectJSON(v, steps)
+		c.Assert(err, IsNil)
+		b, err := json.Marshal(values)
+		c.Assert(err, IsNil)
+		c.Assert(string(b), Equals, t.expect, Commentf("selector %s", t.selector))
+	}
+
+	for _, selector := range []string{".count[]", ".count.id", ".stores.id", ".count[0]"} {
+		steps, err := parseSelector(selector)
+		c.Assert(err, IsNil)
+		_, err = selectJSON(v, steps)
+		c.Assert(err, NotNil, Commentf("selector %s", selector))
+	}
+}
+
+func (s *testOutputSuite) TestDecodeOrderedJSON(c *C) {
+	v, err := decodeOrderedJSON(`{"z": 1, "a": {"y": true, "b": null}, "m": ["x", 2]}`)
+	c.Assert(err, IsNil)
+	o, ok := v.(*orderedObject)
+	c.Assert(ok, IsTrue)
+	c.Assert(o.keys, DeepEquals, []string{"z", "a", "m"})
+	b, err := json.Marshal(v)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"z":1,"a":{"y":true,"b":null},"m":["x",2]}`)
+
+	_, err = decodeOrderedJSON(`{"a": 1} {"b": 2}`)
+	c.Assert(err, NotNil)
+	_, err = decodeOrderedJSON(`{"a": 1`)
+	c.Assert(err, NotNil)
+}
+
+func (s *testOutputSuite) TestPrintTable(c *C) {
+	v, err := decodeOrderedJSON(testStoresJSON)
+	c.Assert(err, IsNil)
+	steps, err := parseSelector(".stores")
+	c.Assert(err, IsNil)
+	values, err := selectJSON(v, steps)
+	c.Assert(err, IsNil)
+
+	var b bytes.Buffer
+	printTable(&b, values[0])
+	c.Assert(b.String(), Equals, ""+
+		"STORE.ID              STORE.ADDRESS    STATUS.LEADER_COUNT\n"+
+		"1                     127.0.0.1:20160  3\n"+
+		"18446744073709551615  127.0.0.1:20161  0\n")
+
+	b.Reset()
+	o, err := decodeOrderedJSON(`{"name": "pd1", "labels": [{"key": "zone"}], "leader": {"id": 1}}`)
+	c.Assert(err, IsNil)
+	printTable(&b, o)
+	c.Assert(b.String(), Equals, ""+
+		"KEY        VALUE\n"+
+		"name       pd1\n"+
+		"labels     [{\"key\":\"zone\"}]\n"+
+		"leader.id  1\n")
+
+	b.Reset()
+	printTable(&b, []interface{}{"a", json.Number("1")})
+	c.Assert(b.String(), Equals, "VALUE\na\n1\n")
+}
+
+func (s *testOutputSuite) TestFormatTableCell(c *C) {
+	c.Assert(formatTableCell(nil), Equals, "")
+	c.Assert(formatTableCell("s"), Equals, "s")
+	c.Assert(formatTableCell(json.Number("18446744073709551615")), Equals, "18446744073709551615")
+	c.Assert(formatTableCell(true), Equals, "true")
+	c.Assert(formatTableCell([]interface{}{"a", json.Number("1")}), Equals, `["a",1]`)
+}
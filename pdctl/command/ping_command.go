@@ -35,7 +35,7 @@ func showPingCommandFunc(cmd *cobra.Command, args []string) {
 	start := time.Now()
 	_, err := doRequest(cmd, pingPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 	elapsed := time.Since(start)
@@ -15,24 +15,43 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/spf13/cobra"
 )
 
 var (
-	regionsPrefix   = "pd/api/v1/regions"
-	regionIDPrefix  = "pd/api/v1/region/id"
-	regionKeyPrefix = "pd/api/v1/region/key"
+	regionsPrefix        = "pd/api/v1/regions"
+	regionIDPrefix       = "pd/api/v1/region/id"
+	regionKeyPrefix      = "pd/api/v1/region/key"
+	regionsSiblingPrefix = "pd/api/v1/regions/sibling"
+	regionsCheckPrefix   = "pd/api/v1/regions/check"
 )
 
+// regionInfo is the region returned by the region API.
 type regionInfo struct {
-	Region *metapb.Region `json:"region"`
-	Leader *metapb.Peer   `json:"leader"`
+	*metapb.Region
+	Leader          *metapb.Peer
+	DownPeers       []*pdpb.PeerStats
+	PendingPeers    []*metapb.Peer
+	WrittenBytes    uint64
+	ReadBytes       uint64
+	WrittenKeys     uint64
+	ReadKeys        uint64
+	ApproximateSize uint64
+	ApproximateKeys uint64
+}
+
+type regionsInfo struct {
+	Count   int           `json:"count"`
+	Regions []*regionInfo `json:"regions"`
 }
 
 // NewRegionCommand return a region subcommand of rootCmd
@@ -43,25 +62,35 @@ func NewRegionCommand() *cobra.Command {
 		Run:   showRegionCommandFunc,
 	}
 	r.AddCommand(NewRegionWithKeyCommand())
+	r.AddCommand(NewRegionSiblingCommand())
+	r.AddCommand(NewRegionCheckCommand())
 	return r
 }
 
 func showRegionCommandFunc(cmd *cobra.Command, args []string) {
-	var prefix string
-	prefix = regionsPrefix
-	if len(args) == 1 {
-		if _, err := strconv.Atoi(args[0]); err != nil {
-			fmt.Println("region_id should be a number")
+	if len(args) > 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	if len(args) == 0 {
+		r, err := doRequest(cmd, regionsPrefix, http.MethodGet)
+		if err != nil {
+			printErrorf("Failed to get regions: %s\n", err)
 			return
 		}
-		prefix = regionIDPrefix + "/" + args[0]
+		printRegions(cmd, r, false)
+		return
 	}
-	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("region_id should be a number")
+		return
+	}
+	r, err := doRequest(cmd, regionIDPrefix+"/"+args[0], http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get region: %s", err)
+		printErrorf("Failed to get region: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printRegion(cmd, r)
 }
 
 // NewRegionWithKeyCommand return a region with key subcommand of regionCmd
@@ -77,7 +106,7 @@ func NewRegionWithKeyCommand() *cobra.Command {
 
 func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
@@ -93,22 +122,188 @@ func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 	case "pb", "proto", "protobuf":
 		key, err = decodeProtobufText(args[0])
 		if err != nil {
-			fmt.Println("Error: ", err)
+			printError("Error: ", err)
 			return
 		}
 	default:
-		fmt.Println("Error: unknown format")
+		printError("Error: unknown format")
 		return
 	}
 	// TODO: Deal with path escaped
 	prefix := regionKeyPrefix + "/" + key
 	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get region: %s", err)
+		printErrorf("Failed to get region: %s\n", err)
+		return
+	}
+	printRegion(cmd, r)
+}
+
+// NewRegionSiblingCommand returns a command to show the adjacent regions.
+func NewRegionSiblingCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "sibling <region_id>",
+		Short: "show the adjacent regions of the region",
+		Run:   showRegionSiblingCommandFunc,
+	}
+	return r
+}
+
+func showRegionSiblingCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("region_id should be a number")
+		return
+	}
+	r, err := doRequest(cmd, regionsSiblingPrefix+"/"+args[0], http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get the sibling regions: %s\n", err)
+		return
+	}
+	printRegions(cmd, r, true)
+}
+
+// NewRegionCheckCommand returns a command to show the abnormal regions.
+func NewRegionCheckCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "check [miss-peer|extra-peer|down-peer|pending-peer|empty-region]",
+		Short: "show the regions with abnormal status",
+	}
+	for kind, short := range map[string]string{
+		"miss-peer":    "show the regions with less peers than required",
+		"extra-peer":   "show the regions with more peers than required",
+		"down-peer":    "show the regions with down peers",
+		"pending-peer": "show the regions with pending peers",
+		"empty-region": "show the regions whose approximate size is at most 1MB",
+	} {
+		r.AddCommand(&cobra.Command{
+			Use:   kind,
+			Short: short,
+			Run:   showRegionCheckCommandFunc,
+		})
+	}
+	return r
+}
+
+func showRegionCheckCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, regionsCheckPrefix+"/"+cmd.Name(), http.MethodGet)
+	if err != nil {
+		printErrorf("Failed to get the abnormal regions: %s\n", err)
+		return
+	}
+	printRegions(cmd, r, true)
+}
+
+// isRegionTextOutput returns whether the regions are printed in the format of
+// formatRegion, they are printed in JSON unless --output=table is set.
+func isRegionTextOutput(cmd *cobra.Command) bool {
+	return getOutputFormat(cmd) == outputTable && getSelector(cmd) == ""
+}
+
+func printRegion(cmd *cobra.Command, r string) {
+	if !isRegionTextOutput(cmd) {
+		printJSON(cmd, r)
+		return
+	}
+	var region *regionInfo
+	if err := json.Unmarshal([]byte(r), &region); err != nil {
+		printError(err)
+		return
+	}
+	if region == nil || region.Region == nil {
+		printError("region not found")
 		return
 	}
-	fmt.Println(r)
+	fmt.Print(formatRegion(region, true))
+}
 
+// printRegions prints the regions, the leaders and the stats are not in the
+// response of listing all regions.
+func printRegions(cmd *cobra.Command, r string, withStats bool) {
+	if !isRegionTextOutput(cmd) {
+		printJSON(cmd, r)
+		return
+	}
+	var regions regionsInfo
+	if err := json.Unmarshal([]byte(r), &regions); err != nil {
+		printError(err)
+		return
+	}
+	fmt.Printf("count: %d\n", regions.Count)
+	for _, region := range regions.Regions {
+		fmt.Println()
+		fmt.Print(formatRegion(region, withStats))
+	}
+}
+
+// formatRegion formats the meta, the leader, the peers and the stats of the
+// region, the keys are escaped in the format of `region key --format=pb`.
+func formatRegion(region *regionInfo, withStats bool) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "region %d\n", region.GetId())
+	fmt.Fprintf(&b, "  start key: \"%s\"\n", encodeProtobufText(region.GetStartKey()))
+	fmt.Fprintf(&b, "  end key: \"%s\"\n", encodeProtobufText(region.GetEndKey()))
+	fmt.Fprintf(&b, "  epoch: conf_ver %d, version %d\n", region.GetRegionEpoch().GetConfVer(), region.GetRegionEpoch().GetVersion())
+
+	downSeconds := make(map[uint64]uint64)
+	for _, s := range region.DownPeers {
+		downSeconds[s.GetPeer().GetId()] = s.GetDownSeconds()
+	}
+	pending := make(map[uint64]bool)
+	for _, p := range region.PendingPeers {
+		pending[p.GetId()] = true
+	}
+	fmt.Fprintf(&b, "  peers:\n")
+	if !withStats {
+		for _, p := range region.GetPeers() {
+			fmt.Fprintf(&b, "    peer %d on store %d\n", p.GetId(), p.GetStoreId())
+		}
+		return b.String()
+	}
+	for _, p := range region.GetPeers() {
+		var states []string
+		if p.GetId() == region.Leader.GetId() {
+			states = append(states, "leader")
+		}
+		if s, ok := downSeconds[p.GetId()]; ok {
+			states = append(states, fmt.Sprintf("down %ds", s))
+		}
+		if pending[p.GetId()] {
+			states = append(states, "pending")
+		}
+		fmt.Fprintf(&b, "    peer %d on store %d", p.GetId(), p.GetStoreId())
+		if len(states) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(states, ", "))
+		}
+		fmt.Fprintln(&b)
+	}
+	if region.Leader == nil {
+		fmt.Fprintf(&b, "  no leader\n")
+	}
+	fmt.Fprintf(&b, "  written: %d bytes, %d keys\n", region.WrittenBytes, region.WrittenKeys)
+	fmt.Fprintf(&b, "  read: %d bytes, %d keys\n", region.ReadBytes, region.ReadKeys)
+	fmt.Fprintf(&b, "  approximate size: %d, keys: %d\n", region.ApproximateSize, region.ApproximateKeys)
+	return b.String()
+}
+
+// encodeProtobufText escapes the key in the way decodeProtobufText decodes.
+func encodeProtobufText(key []byte) string {
+	var b bytes.Buffer
+	for _, c := range key {
+		if c >= 0x20 && c < 0x7f && c != '\\' && c != '"' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\%03o", c)
+		}
+	}
+	return b.String()
 }
 
 func decodeProtobufText(text string) (string, error) {
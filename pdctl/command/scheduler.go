@@ -14,6 +14,7 @@
 package command
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -34,31 +35,38 @@ func NewSchedulerCommand() *cobra.Command {
 	c.AddCommand(NewShowSchedulerCommand())
 	c.AddCommand(NewAddSchedulerCommand())
 	c.AddCommand(NewRemoveSchedulerCommand())
+	c.AddCommand(NewPauseSchedulerCommand())
+	c.AddCommand(NewResumeSchedulerCommand())
 	return c
 }
 
 // NewShowSchedulerCommand returns a command to show schedulers.
 func NewShowSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "show",
+		Use:   "show [--paused]",
 		Short: "show schedulers",
 		Run:   showSchedulerCommandFunc,
 	}
+	c.Flags().Bool("paused", false, "only show the paused schedulers")
 	return c
 }
 
 func showSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 0 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
-	r, err := doRequest(cmd, schedulersPrefix, http.MethodGet)
+	path := schedulersPrefix
+	if paused, _ := cmd.Flags().GetBool("paused"); paused {
+		path += "?status=paused"
+	}
+	r, err := doRequest(cmd, path, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
 }
 
 // NewAddSchedulerCommand returns a command to add scheduler.
@@ -67,13 +75,99 @@ func NewAddSchedulerCommand() *cobra.Command {
 		Use:   "add <scheduler>",
 		Short: "add a scheduler",
 	}
+	c.AddCommand(NewBalanceLeaderSchedulerCommand())
+	c.AddCommand(NewBalanceRegionSchedulerCommand())
+	c.AddCommand(NewHotRegionSchedulerCommand())
+	c.AddCommand(NewHotReadRegionSchedulerCommand())
 	c.AddCommand(NewGrantLeaderSchedulerCommand())
 	c.AddCommand(NewEvictLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleRegionSchedulerCommand())
+	c.AddCommand(NewScatterRangeSchedulerCommand())
+	return c
+}
+
+// NewBalanceLeaderSchedulerCommand returns a command to add a balance-leader-scheduler.
+func NewBalanceLeaderSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-leader-scheduler",
+		Short: "add a scheduler to balance leaders between stores",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewBalanceRegionSchedulerCommand returns a command to add a balance-region-scheduler.
+func NewBalanceRegionSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-region-scheduler",
+		Short: "add a scheduler to balance regions between stores",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewHotRegionSchedulerCommand returns a command to add a balance-hot-region-scheduler.
+func NewHotRegionSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-hot-region-scheduler",
+		Short: "add a scheduler to balance the hot write regions between stores",
+		Run:   addSchedulerCommandFunc,
+	}
 	return c
 }
 
+// NewHotReadRegionSchedulerCommand returns a command to add a balance-hot-read-region-scheduler.
+func NewHotReadRegionSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-hot-read-region-scheduler",
+		Short: "add a scheduler to balance the hot read regions between stores",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+func addSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	postJSON(cmd, schedulersPrefix, input)
+}
+
+// NewScatterRangeSchedulerCommand returns a command to add a scatter-range-scheduler.
+func NewScatterRangeSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "scatter-range-scheduler <range_name> <start_key> <end_key>",
+		Short: "add a scheduler to scatter the regions in a hex encoded key range",
+		Run:   addScatterRangeSchedulerCommandFunc,
+	}
+	return c
+}
+
+func addScatterRangeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		printError(cmd.UsageString())
+		return
+	}
+	for _, key := range args[1:] {
+		if _, err := hex.DecodeString(key); err != nil {
+			printErrorf("key %s should be hex encoded\n", key)
+			return
+		}
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["range_name"] = args[0]
+	input["start_key"] = args[1]
+	input["end_key"] = args[2]
+	postJSON(cmd, schedulersPrefix, input)
+}
+
 // NewGrantLeaderSchedulerCommand returns a command to add a grant-leader-scheduler.
 func NewGrantLeaderSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -96,13 +190,13 @@ func NewEvictLeaderSchedulerCommand() *cobra.Command {
 
 func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		printError(cmd.UsageString())
 		return
 	}
 
 	storeID, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
@@ -115,9 +209,9 @@ func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
 // NewShuffleLeaderSchedulerCommand returns a command to add a shuffle-leader-scheduler.
 func NewShuffleLeaderSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "shuffle-leader-scheduler",
+		Use:   "shuffle-leader-scheduler [limit]",
 		Short: "add a scheduler to shuffle leaders between stores",
-		Run:   addSchedulerCommandFunc,
+		Run:   addShuffleSchedulerCommandFunc,
 	}
 	return c
 }
@@ -125,21 +219,29 @@ func NewShuffleLeaderSchedulerCommand() *cobra.Command {
 // NewShuffleRegionSchedulerCommand returns a command to add a shuffle-region-scheduler.
 func NewShuffleRegionSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "shuffle-region-scheduler",
+		Use:   "shuffle-region-scheduler [limit]",
 		Short: "add a scheduler to shuffle regions between stores",
-		Run:   addSchedulerCommandFunc,
+		Run:   addShuffleSchedulerCommandFunc,
 	}
 	return c
 }
 
-func addSchedulerCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 0 {
-		fmt.Println(cmd.UsageString())
+func addShuffleSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		printError(cmd.UsageString())
 		return
 	}
 
 	input := make(map[string]interface{})
 	input["name"] = cmd.Name()
+	if len(args) == 1 {
+		limit, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			printError(err)
+			return
+		}
+		input["limit"] = limit
+	}
 	postJSON(cmd, schedulersPrefix, input)
 }
 
@@ -155,14 +257,48 @@ func NewRemoveSchedulerCommand() *cobra.Command {
 
 func removeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.Usage())
+		printError(cmd.UsageString())
 		return
 	}
 
 	path := schedulersPrefix + "/" + args[0]
 	_, err := doRequest(cmd, path, http.MethodDelete)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
+		return
+	}
+}
+
+// NewPauseSchedulerCommand returns a command to pause a scheduler.
+func NewPauseSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "pause <scheduler>",
+		Short: "pause a scheduler until it is resumed or the leader changes",
+		Run:   pauseOrResumeSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewResumeSchedulerCommand returns a command to resume a scheduler.
+func NewResumeSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "resume <scheduler>",
+		Short: "resume a paused scheduler",
+		Run:   pauseOrResumeSchedulerCommandFunc,
+	}
+	return c
+}
+
+func pauseOrResumeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+
+	path := fmt.Sprintf("%s/%s/%s", schedulersPrefix, args[0], cmd.Name())
+	_, err := doRequest(cmd, path, http.MethodPost)
+	if err != nil {
+		printError(err)
 		return
 	}
 }
@@ -29,11 +29,15 @@ var (
 // NewStoreCommand return a store subcommand of rootCmd
 func NewStoreCommand() *cobra.Command {
 	s := &cobra.Command{
-		Use:   "store [delete] <store_id>",
+		Use:   "store [delete|destroyed|label|weight|limit] <store_id>",
 		Short: "show the store status",
 		Run:   showStoreCommandFunc,
 	}
 	s.AddCommand(NewDeleteStoreCommand())
+	s.AddCommand(NewDestroyedStoreCommand())
+	s.AddCommand(NewLabelStoreCommand())
+	s.AddCommand(NewWeightStoreCommand())
+	s.AddCommand(NewLimitStoreCommand())
 	return s
 }
 
@@ -47,38 +51,156 @@ func NewDeleteStoreCommand() *cobra.Command {
 	return d
 }
 
+// NewDestroyedStoreCommand returns a destroyed subcommand of storeCmd
+func NewDestroyedStoreCommand() *cobra.Command {
+	d := &cobra.Command{
+		Use:   "destroyed <store_id>",
+		Short: "confirm that the tombstone store is physically destroyed",
+		Run:   destroyedStoreCommandFunc,
+	}
+	return d
+}
+
+// NewLabelStoreCommand returns a label subcommand of storeCmd
+func NewLabelStoreCommand() *cobra.Command {
+	l := &cobra.Command{
+		Use:   "label <store_id> <key> <value> [<key> <value>]...",
+		Short: "set the labels of the store, a label with empty value is removed",
+		Run:   labelStoreCommandFunc,
+	}
+	return l
+}
+
+// NewWeightStoreCommand returns a weight subcommand of storeCmd
+func NewWeightStoreCommand() *cobra.Command {
+	w := &cobra.Command{
+		Use:   "weight <store_id> <leader_weight> <region_weight>",
+		Short: "set the leader and region weights of the store",
+		Run:   weightStoreCommandFunc,
+	}
+	return w
+}
+
+// NewLimitStoreCommand returns a limit subcommand of storeCmd
+func NewLimitStoreCommand() *cobra.Command {
+	l := &cobra.Command{
+		Use:   "limit <store_id> <snapshot_limit>",
+		Short: "set the max snapshot count of the store, 0 means max-snapshot-count",
+		Run:   limitStoreCommandFunc,
+	}
+	return l
+}
+
 func showStoreCommandFunc(cmd *cobra.Command, args []string) {
 	var prefix string
 	prefix = storesPrefix
 	if len(args) == 1 {
 		if _, err := strconv.Atoi(args[0]); err != nil {
-			fmt.Println("store_id should be a number")
+			printError("store_id should be a number")
 			return
 		}
 		prefix = fmt.Sprintf(storePrefix, args[0])
 	}
 	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get store: %s", err)
+		printErrorf("Failed to get store: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printJSON(cmd, r)
 }
 
 func deleteStoreCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: store delete <store_id>")
+		printError("Usage: store delete <store_id>")
 		return
 	}
 	if _, err := strconv.Atoi(args[0]); err != nil {
-		fmt.Println("store_id should be a number")
+		printError("store_id should be a number")
 		return
 	}
 	prefix := fmt.Sprintf(storePrefix, args[0])
 	_, err := doRequest(cmd, prefix, http.MethodDelete)
 	if err != nil {
-		fmt.Printf("Failed to delete store %s: %s", args[0], err)
+		printErrorf("Failed to delete store %s: %s", args[0], err)
 		return
 	}
 	fmt.Println("Success!")
 }
+
+func destroyedStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/destroyed"
+	postJSON(cmd, prefix, map[string]interface{}{})
+}
+
+func labelStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("store_id should be a number")
+		return
+	}
+	input := make(map[string]interface{})
+	for i := 1; i < len(args); i += 2 {
+		input[args[i]] = args[i+1]
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/label"
+	postJSON(cmd, prefix, input)
+}
+
+func weightStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("store_id should be a number")
+		return
+	}
+	leader, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || leader <= 0 {
+		printError("leader_weight should be a positive number")
+		return
+	}
+	region, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || region <= 0 {
+		printError("region_weight should be a positive number")
+		return
+	}
+	input := map[string]interface{}{
+		"leader": leader,
+		"region": region,
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/weight"
+	postJSON(cmd, prefix, input)
+}
+
+func limitStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		printError(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		printError("store_id should be a number")
+		return
+	}
+	limit, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		printError("snapshot_limit should be a number")
+		return
+	}
+	input := map[string]interface{}{
+		"snapshot_limit": limit,
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/limit"
+	postJSON(cmd, prefix, input)
+}
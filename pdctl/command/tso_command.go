@@ -14,6 +14,7 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -26,6 +27,13 @@ const (
 	logicalBits       = 0x3FFFF
 )
 
+// tsoInfo is the decoded TSO, the physical time is in milliseconds.
+type tsoInfo struct {
+	System   time.Time `json:"system"`
+	Physical uint64    `json:"physical"`
+	Logical  uint64    `json:"logical"`
+}
+
 // NewTSOCommand return a ping subcommand of rootCmd
 func NewTSOCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -38,17 +46,28 @@ func NewTSOCommand() *cobra.Command {
 
 func showTSOCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: tso <timestamp>")
+		printError("Usage: tso <timestamp>")
 		return
 	}
 	ts, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		fmt.Printf("Failed to parse TSO: %s", err)
+		printErrorf("Failed to parse TSO: %s\n", err)
+		return
+	}
+	info := &tsoInfo{
+		Physical: ts >> physicalShiftBits,
+		Logical:  ts & logicalBits,
+	}
+	info.System = time.Unix(0, int64(info.Physical)*int64(time.Millisecond))
+	if isJSONOutput(cmd) {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			printError(err)
+			return
+		}
+		printJSON(cmd, string(data))
 		return
 	}
-	logical := ts & logicalBits
-	physical := ts >> physicalShiftBits
-	physicalTime := time.Unix(int64(physical/1000), 0)
-	fmt.Println("system: ", physicalTime)
-	fmt.Println("logic: ", logical)
+	fmt.Println("system: ", info.System)
+	fmt.Println("logic: ", info.Logical)
 }
@@ -15,15 +15,21 @@ package pdctl
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/chzyer/readline"
 	"github.com/pingcap/pd/pdctl/command"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // CommandFlags are flags that used in all Commands
 type CommandFlags struct {
-	URL string
+	URL      string
+	CAPath   string
+	CertPath string
+	KeyPath  string
+	Output   string
+	Selector string
 }
 
 var (
@@ -36,6 +42,11 @@ var (
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&commandFlags.URL, "pd", "u", "http://127.0.0.1:2379", "pd address")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CAPath, "cacert", "", "path of the CA certificate to verify pd with HTTPS")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CertPath, "cert", "", "path of the client certificate")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.KeyPath, "key", "", "path of the client key")
+	rootCmd.PersistentFlags().StringVarP(&commandFlags.Output, "output", "o", "", "the output format, json or table")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.Selector, "jq", "", "select the fields of the output, such as .stores[].store.id")
 	rootCmd.AddCommand(
 		command.NewConfigCommand(),
 		command.NewRegionCommand(),
@@ -49,22 +60,64 @@ func init() {
 		command.NewTSOCommand(),
 		command.NewHotSpotCommand(),
 		command.NewClusterCommand(),
+		command.NewHealthCommand(),
+		command.NewLogCommand(),
+		command.NewBackupCommand(),
 	)
 	cobra.EnablePrefixMatching = true
 }
 
-// Start run Command
-func Start(args []string) {
+// Start runs the command, it returns the exit status, which is 1 if the
+// command fails.
+func Start(args []string) int {
+	// The flags keep the values of the last command in the interactive mode.
+	commandFlags.Output, commandFlags.Selector = "", ""
 	rootCmd.SetArgs(args)
 	rootCmd.SilenceErrors = true
 	rootCmd.ParseFlags(args)
-	err := command.InitPDClient(rootCmd)
+	err := command.CheckOutputFlags(rootCmd)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return 1
+	}
+	if c, _, err := rootCmd.Find(args); err != nil || !command.IsOfflineCommand(c) {
+		if err = command.InitPDClient(rootCmd); err != nil {
+			fmt.Println(err)
+			return 1
+		}
 	}
 	rootCmd.SetUsageTemplate(command.UsageTemplate)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(rootCmd.UsageString())
+		return 1
+	}
+	if command.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// GetCompleter returns the completer of the commands and their flags for the
+// interactive mode, `help` completes the commands as well.
+func GetCompleter() *readline.PrefixCompleter {
+	items := genCompleter(rootCmd, true)
+	items = append(items, readline.PcItem("help", genCompleter(rootCmd, false)...))
+	return readline.NewPrefixCompleter(items...)
+}
+
+func genCompleter(cmd *cobra.Command, withFlags bool) []readline.PrefixCompleterInterface {
+	var items []readline.PrefixCompleterInterface
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() {
+			continue
+		}
+		children := genCompleter(c, withFlags)
+		if withFlags {
+			c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+				children = append(children, readline.PcItem("--"+f.Name))
+			})
+		}
+		items = append(items, readline.PcItem(c.Name(), children...))
 	}
+	return items
 }
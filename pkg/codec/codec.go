@@ -0,0 +1,122 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/juju/errors"
+)
+
+const (
+	encGroupSize = 8
+	encMarker    = byte(0xFF)
+	encPad       = byte(0x0)
+
+	signMask uint64 = 0x8000000000000000
+)
+
+var tablePrefix = []byte{'t'}
+
+// EncodeBytes guarantees the encoded value is in ascending order for
+// comparison, the same as the memcomparable format TiKV uses for region keys.
+// The data is encoded in groups of 8 bytes, each group is padded with 0 and
+// followed by a marker, the marker is 0xFF minus the number of padding bytes.
+func EncodeBytes(data []byte) []byte {
+	dLen := len(data)
+	result := make([]byte, 0, (dLen/encGroupSize+1)*(encGroupSize+1))
+	for idx := 0; idx <= dLen; idx += encGroupSize {
+		remain := dLen - idx
+		padCount := 0
+		if remain >= encGroupSize {
+			result = append(result, data[idx:idx+encGroupSize]...)
+		} else {
+			padCount = encGroupSize - remain
+			result = append(result, data[idx:]...)
+			result = append(result, make([]byte, padCount)...)
+		}
+		result = append(result, encMarker-byte(padCount))
+	}
+	return result
+}
+
+// DecodeBytes decodes the bytes encoded by EncodeBytes, it returns the left
+// bytes and the decoded bytes.
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	data := make([]byte, 0, len(b))
+	for {
+		if len(b) < encGroupSize+1 {
+			return nil, nil, errors.New("insufficient bytes to decode value")
+		}
+		groupBytes := b[:encGroupSize+1]
+		group := groupBytes[:encGroupSize]
+		marker := groupBytes[encGroupSize]
+
+		padCount := encMarker - marker
+		if padCount > encGroupSize {
+			return nil, nil, errors.Errorf("invalid marker byte, group bytes %q", groupBytes)
+		}
+		realGroupSize := encGroupSize - int(padCount)
+		data = append(data, group[:realGroupSize]...)
+		b = b[encGroupSize+1:]
+
+		if padCount != 0 {
+			// Check validity of padding bytes.
+			if !bytes.Equal(group[realGroupSize:], make([]byte, padCount)) {
+				return nil, nil, errors.Errorf("invalid padding byte, group bytes %q", groupBytes)
+			}
+			break
+		}
+	}
+	return b, data, nil
+}
+
+// EncodeInt appends the encoded int64 to b, the sign bit is flipped so the
+// encoded values keep the order of the values.
+func EncodeInt(b []byte, v int64) []byte {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], uint64(v)^signMask)
+	return append(b, data[:]...)
+}
+
+// DecodeInt decodes the int64 encoded by EncodeInt, it returns the left bytes
+// and the decoded value.
+func DecodeInt(b []byte) ([]byte, int64, error) {
+	if len(b) < 8 {
+		return nil, 0, errors.New("insufficient bytes to decode value")
+	}
+	v := int64(binary.BigEndian.Uint64(b[:8]) ^ signMask)
+	return b[8:], v, nil
+}
+
+// GenerateTableKey returns the encoded key of the table prefix, it is the
+// start key of the regions of the table.
+func GenerateTableKey(tableID int64) []byte {
+	return EncodeBytes(EncodeInt(append([]byte(nil), tablePrefix...), tableID))
+}
+
+// DecodeTableID decodes the table id of an encoded key, it returns 0 if the
+// key doesn't belong to a table.
+func DecodeTableID(key []byte) int64 {
+	_, key, err := DecodeBytes(key)
+	if err != nil || !bytes.HasPrefix(key, tablePrefix) {
+		return 0
+	}
+	_, tableID, err := DecodeInt(key[len(tablePrefix):])
+	if err != nil {
+		return 0
+	}
+	return tableID
+}
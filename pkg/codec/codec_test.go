@@ -0,0 +1,72 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func TestCodec(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testCodecSuite{})
+
+type testCodecSuite struct{}
+
+func (s *testCodecSuite) TestBytes(c *C) {
+	tbl := [][]byte{
+		{},
+		{0x00},
+		[]byte("1234567"),
+		[]byte("12345678"),
+		[]byte("123456789"),
+	}
+	for _, data := range tbl {
+		left, decoded, err := DecodeBytes(append(EncodeBytes(data), 'x'))
+		c.Assert(err, IsNil)
+		c.Assert(left, DeepEquals, []byte("x"))
+		c.Assert(bytes.Equal(decoded, data), IsTrue)
+	}
+
+	// The encoded bytes keep the order.
+	c.Assert(bytes.Compare(EncodeBytes([]byte("a")), EncodeBytes([]byte("a\x00"))), Equals, -1)
+	c.Assert(bytes.Compare(EncodeBytes([]byte("12345678")), EncodeBytes([]byte("123456789"))), Equals, -1)
+
+	_, _, err := DecodeBytes([]byte("1234"))
+	c.Assert(err, NotNil)
+}
+
+func (s *testCodecSuite) TestInt(c *C) {
+	for _, v := range []int64{-1, 0, 1, 1 << 40} {
+		left, decoded, err := DecodeInt(EncodeInt(nil, v))
+		c.Assert(err, IsNil)
+		c.Assert(left, HasLen, 0)
+		c.Assert(decoded, Equals, v)
+	}
+	c.Assert(bytes.Compare(EncodeInt(nil, -1), EncodeInt(nil, 1)), Equals, -1)
+}
+
+func (s *testCodecSuite) TestTableID(c *C) {
+	c.Assert(DecodeTableID(GenerateTableKey(42)), Equals, int64(42))
+	// A key of a row in the table.
+	key := EncodeInt([]byte("t"), 42)
+	key = append(key, []byte("_r12345")...)
+	c.Assert(DecodeTableID(EncodeBytes(key)), Equals, int64(42))
+	c.Assert(DecodeTableID(nil), Equals, int64(0))
+	c.Assert(DecodeTableID(EncodeBytes([]byte("m_meta"))), Equals, int64(0))
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !failpoint
+
+package failpoint
+
+import "github.com/juju/errors"
+
+// Enable returns an error, the failpoints can't be enabled without the
+// failpoint tag.
+func Enable(name, terms string) error {
+	return errors.Errorf("failpoint %s can't be enabled, build with the failpoint tag", name)
+}
+
+// Disable does nothing, the failpoints are always disabled without the
+// failpoint tag.
+func Disable(name string) {}
+
+// Status returns that the failpoint is not enabled.
+func Status(name string) (string, bool) {
+	return "", false
+}
+
+// Eval never returns, the failpoints are always disabled without the
+// failpoint tag.
+func Eval(name string) (interface{}, bool) {
+	return nil, false
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build failpoint
+
+package failpoint
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// envFailpoints is the environment variable to enable the failpoints.
+const envFailpoints = "PD_FAILPOINTS"
+
+var failpoints = struct {
+	sync.RWMutex
+	m map[string]*failpoint
+}{m: make(map[string]*failpoint)}
+
+func init() {
+	if err := enableFromEnv(os.Getenv(envFailpoints)); err != nil {
+		panic(fmt.Sprintf("invalid %s: %v", envFailpoints, err))
+	}
+}
+
+// enableFromEnv enables the failpoints of the value of PD_FAILPOINTS, which
+// is the `name=terms` separated by ';'.
+func enableFromEnv(value string) error {
+	for _, s := range strings.Split(value, ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("failpoint %q should be name=terms", s)
+		}
+		if err := Enable(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// Enable enables the failpoint with the terms, the previous terms of the
+// failpoint are replaced.
+func Enable(name, terms string) error {
+	fp, err := newFailpoint(name, terms)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	failpoints.Lock()
+	defer failpoints.Unlock()
+	failpoints.m[name] = fp
+	return nil
+}
+
+// Disable disables the failpoint.
+func Disable(name string) {
+	failpoints.Lock()
+	defer failpoints.Unlock()
+	delete(failpoints.m, name)
+}
+
+// Status returns the terms of the failpoint and whether it's enabled.
+func Status(name string) (string, bool) {
+	failpoints.RLock()
+	defer failpoints.RUnlock()
+	if fp, ok := failpoints.m[name]; ok {
+		return fp.desc, true
+	}
+	return "", false
+}
+
+// Eval evaluates the failpoint, it returns the value of the failpoint and
+// whether the failpoint returns. A disabled failpoint never returns.
+func Eval(name string) (interface{}, bool) {
+	failpoints.RLock()
+	fp, ok := failpoints.m[name]
+	failpoints.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return fp.eval()
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build failpoint
+
+package failpoint
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testEnabledSuite{})
+
+type testEnabledSuite struct{}
+
+func (s *testEnabledSuite) TestEnable(c *C) {
+	_, ok := Eval("test")
+	c.Assert(ok, IsFalse)
+
+	c.Assert(Enable("test", "return(1)"), IsNil)
+	terms, ok := Status("test")
+	c.Assert(ok, IsTrue)
+	c.Assert(terms, Equals, "return(1)")
+	v, ok := Eval("test")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 1)
+
+	// The invalid terms don't replace the enabled ones.
+	c.Assert(Enable("test", "return(1"), NotNil)
+	v, ok = Eval("test")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 1)
+
+	Disable("test")
+	_, ok = Status("test")
+	c.Assert(ok, IsFalse)
+	_, ok = Eval("test")
+	c.Assert(ok, IsFalse)
+}
+
+func (s *testEnabledSuite) TestEnableFromEnv(c *C) {
+	defer Disable("test1")
+	defer Disable("test2")
+
+	c.Assert(enableFromEnv(`test1=return(true) ; test2=1*off->return;`), IsNil)
+	v, ok := Eval("test1")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, true)
+	_, ok = Eval("test2")
+	c.Assert(ok, IsFalse)
+	_, ok = Eval("test2")
+	c.Assert(ok, IsTrue)
+
+	c.Assert(enableFromEnv("test1"), NotNil)
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failpoint injects failures into the code for tests. A failpoint is
+// evaluated by its name in the code, and it does nothing unless it's enabled
+// with the terms, such as `return(true)` or `sleep(100)`.
+//
+// The failpoints can only be enabled in the builds with the `failpoint` tag,
+// e.g. `go test -tags failpoint`, they are always disabled in other builds.
+// In a build with the tag, the failpoints are also enabled by the environment
+// variable PD_FAILPOINTS, e.g. `PD_FAILPOINTS='name1=return(1);name2=panic'`.
+//
+// The terms are separated by `->`, the first term which is not used up is
+// evaluated. A term is `[percent%][count*]action[(arg)]`, the actions are:
+//
+//	off:          do nothing
+//	return(arg):  the failpoint returns the arg, which is a bool, an integer,
+//	              a quoted string or the raw string
+//	sleep(ms):    sleep for the milliseconds, the failpoint doesn't return
+//	panic:        panic
+//
+// The term is evaluated with the probability of the percent, and it's used up
+// after being evaluated count times. For example, `1*return("error")->off`
+// returns "error" once, then does nothing.
+package failpoint
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// The actions of the failpoint terms.
+const (
+	actionOff    = "off"
+	actionReturn = "return"
+	actionSleep  = "sleep"
+	actionPanic  = "panic"
+)
+
+type term struct {
+	percent float64
+	// count is the remaining times to evaluate the term, -1 means no limit.
+	count  int
+	action string
+	arg    interface{}
+}
+
+// failpoint is an enabled failpoint with its terms.
+type failpoint struct {
+	sync.Mutex
+	name  string
+	desc  string
+	terms []*term
+}
+
+func newFailpoint(name, desc string) (*failpoint, error) {
+	fp := &failpoint{name: name, desc: desc}
+	for _, s := range strings.Split(desc, "->") {
+		t, err := parseTerm(strings.TrimSpace(s))
+		if err != nil {
+			return nil, errors.Annotatef(err, "failpoint %s", name)
+		}
+		fp.terms = append(fp.terms, t)
+	}
+	return fp, nil
+}
+
+func parseTerm(s string) (*term, error) {
+	t := &term{percent: 1, count: -1}
+	// The percent and the count are before the argument, which may contain
+	// '%' or '*'.
+	head := s
+	if i := strings.Index(s, "("); i >= 0 {
+		head = s[:i]
+	}
+	if i := strings.Index(head, "%"); i >= 0 {
+		percent, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, errors.Errorf("invalid percent in term %q", s)
+		}
+		t.percent, s, head = percent/100, s[i+1:], head[i+1:]
+	}
+	if i := strings.Index(head, "*"); i >= 0 {
+		count, err := strconv.Atoi(s[:i])
+		if err != nil || count < 0 {
+			return nil, errors.Errorf("invalid count in term %q", s)
+		}
+		t.count, s = count, s[i+1:]
+	}
+
+	var arg string
+	hasArg := false
+	if i := strings.Index(s, "("); i >= 0 {
+		if !strings.HasSuffix(s, ")") {
+			return nil, errors.Errorf("invalid term %q, ')' is missing", s)
+		}
+		s, arg, hasArg = s[:i], s[i+1:len(s)-1], true
+	}
+	t.action = s
+	switch t.action {
+	case actionOff, actionPanic:
+		if hasArg {
+			return nil, errors.Errorf("action %s takes no argument", t.action)
+		}
+	case actionReturn:
+		t.arg = parseArg(arg)
+	case actionSleep:
+		ms, err := strconv.Atoi(arg)
+		if err != nil || ms < 0 {
+			return nil, errors.Errorf("invalid sleep time %q", arg)
+		}
+		t.arg = time.Duration(ms) * time.Millisecond
+	default:
+		return nil, errors.Errorf("unknown action %q", t.action)
+	}
+	return t, nil
+}
+
+// parseArg parses the argument of return as a bool, an integer or a quoted
+// string, otherwise it's the raw string. An empty argument is nil.
+func parseArg(arg string) interface{} {
+	if arg == "" {
+		return nil
+	}
+	if arg == "true" || arg == "false" {
+		return arg == "true"
+	}
+	if n, err := strconv.Atoi(arg); err == nil {
+		return n
+	}
+	if s, err := strconv.Unquote(arg); err == nil {
+		return s
+	}
+	return arg
+}
+
+// eval evaluates the first term which is not used up, it returns the value
+// and whether the failpoint returns.
+func (fp *failpoint) eval() (interface{}, bool) {
+	fp.Lock()
+	var t *term
+	for _, candidate := range fp.terms {
+		if candidate.count != 0 {
+			t = candidate
+			break
+		}
+	}
+	if t == nil || (t.percent < 1 && rand.Float64() >= t.percent) {
+		fp.Unlock()
+		return nil, false
+	}
+	if t.count > 0 {
+		t.count--
+	}
+	fp.Unlock()
+
+	switch t.action {
+	case actionReturn:
+		return t.arg, true
+	case actionSleep:
+		time.Sleep(t.arg.(time.Duration))
+	case actionPanic:
+		panic(fmt.Sprintf("failpoint %s panics", fp.name))
+	}
+	return nil, false
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failpoint
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testFailpointSuite{})
+
+type testFailpointSuite struct{}
+
+func (s *testFailpointSuite) TestParseTerm(c *C) {
+	tbl := []struct {
+		s       string
+		percent float64
+		count   int
+		action  string
+		arg     interface{}
+	}{
+		{"off", 1, -1, actionOff, nil},
+		{"return", 1, -1, actionReturn, nil},
+		{"return(true)", 1, -1, actionReturn, true},
+		{"return(10)", 1, -1, actionReturn, 10},
+		{`return("a->b")`, 1, -1, actionReturn, "a->b"},
+		{"return(50%*)", 1, -1, actionReturn, "50%*"},
+		{"sleep(100)", 1, -1, actionSleep, 100 * time.Millisecond},
+		{"50%panic", 0.5, -1, actionPanic, nil},
+		{"2*return(1)", 1, 2, actionReturn, 1},
+		{"10%3*sleep(1)", 0.1, 3, actionSleep, time.Millisecond},
+	}
+	for _, t := range tbl {
+		parsed, err := parseTerm(t.s)
+		c.Assert(err, IsNil, Commentf("%s", t.s))
+		c.Assert(*parsed, DeepEquals, term{percent: t.percent, count: t.count, action: t.action, arg: t.arg}, Commentf("%s", t.s))
+	}
+
+	for _, s := range []string{"", "unknown", "off(1)", "panic()", "return(1", "sleep", "sleep(-1)", "101%off", "x%off", "-1*off", "x*off"} {
+		_, err := parseTerm(s)
+		c.Assert(err, NotNil, Commentf("%s", s))
+	}
+}
+
+func (s *testFailpointSuite) TestEval(c *C) {
+	fp, err := newFailpoint("test", `2*return("error")->1*off->return(true)`)
+	c.Assert(err, IsNil)
+	for i := 0; i < 2; i++ {
+		v, ok := fp.eval()
+		c.Assert(ok, IsTrue)
+		c.Assert(v, Equals, "error")
+	}
+	_, ok := fp.eval()
+	c.Assert(ok, IsFalse)
+	for i := 0; i < 3; i++ {
+		v, ok := fp.eval()
+		c.Assert(ok, IsTrue)
+		c.Assert(v, Equals, true)
+	}
+
+	// All terms are used up.
+	fp, err = newFailpoint("test", "1*return")
+	c.Assert(err, IsNil)
+	_, ok = fp.eval()
+	c.Assert(ok, IsTrue)
+	_, ok = fp.eval()
+	c.Assert(ok, IsFalse)
+
+	// The term is never evaluated with 0 percent, so its count is kept.
+	fp, err = newFailpoint("test", "0%1*return->return(2)")
+	c.Assert(err, IsNil)
+	for i := 0; i < 10; i++ {
+		_, ok = fp.eval()
+		c.Assert(ok, IsFalse)
+	}
+
+	fp, err = newFailpoint("test", "sleep(50)")
+	c.Assert(err, IsNil)
+	start := time.Now()
+	_, ok = fp.eval()
+	c.Assert(ok, IsFalse)
+	c.Assert(time.Since(start) >= 50*time.Millisecond, IsTrue)
+
+	fp, err = newFailpoint("test", "panic")
+	c.Assert(err, IsNil)
+	c.Assert(func() { fp.eval() }, PanicMatches, "failpoint test panics")
+}
@@ -0,0 +1,186 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+const (
+	defaultStoreCapacity = 1 << 40
+	defaultRegionSize    = 96 << 20
+)
+
+// Store is a simulated store of a case.
+type Store struct {
+	ID       uint64
+	Capacity uint64
+}
+
+// Region is a simulated region of a case, the leader is one of the peers.
+type Region struct {
+	ID     uint64
+	Peers  []*metapb.Peer
+	Leader *metapb.Peer
+	Size   uint64
+}
+
+// Event adds the stores to the cluster at the tick of the simulation.
+type Event struct {
+	Tick   int64
+	Stores []*Store
+}
+
+// Case is a workload of the simulation. The stores and the regions are in the
+// cluster at the start, the stores of the events join later, and the case is
+// finished once all events happen and the checker returns true. The IDs of
+// the case are at most MaxID.
+type Case struct {
+	Stores  []*Store
+	Regions []*Region
+	Events  []*Event
+	MaxID   uint64
+	Checker func(*RaftEngine) bool
+}
+
+var cases = map[string]func() *Case{
+	"balance-leader":    newBalanceLeaderCase,
+	"add-nodes":         newAddNodesCase,
+	"add-nodes-dynamic": newAddNodesDynamicCase,
+}
+
+// NewCase creates the case by the name, it returns nil if there is no such
+// case.
+func NewCase(name string) *Case {
+	if f, ok := cases[name]; ok {
+		return f()
+	}
+	return nil
+}
+
+// CaseNames returns the names of the cases in order.
+func CaseNames() []string {
+	names := make([]string, 0, len(cases))
+	for name := range cases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// idAllocator allocates the IDs of a case from 1.
+type idAllocator struct {
+	id uint64
+}
+
+func (a *idAllocator) nextID() uint64 {
+	a.id++
+	return a.id
+}
+
+// newStores creates the stores with the default capacity.
+func newStores(alloc *idAllocator, count int) []*Store {
+	stores := make([]*Store, 0, count)
+	for i := 0; i < count; i++ {
+		stores = append(stores, &Store{ID: alloc.nextID(), Capacity: defaultStoreCapacity})
+	}
+	return stores
+}
+
+// newRegions creates the regions with a peer on each of the stores, the
+// leader of a region is chosen by leaderIndex.
+func newRegions(alloc *idAllocator, count int, stores []*Store, leaderIndex func(i int) int) []*Region {
+	regions := make([]*Region, 0, count)
+	for i := 0; i < count; i++ {
+		region := &Region{ID: alloc.nextID(), Size: defaultRegionSize}
+		for _, store := range stores {
+			region.Peers = append(region.Peers, &metapb.Peer{Id: alloc.nextID(), StoreId: store.ID})
+		}
+		region.Leader = region.Peers[leaderIndex(i)]
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// isBalanced checks whether the counts are balanced, the diff of the counts is
+// tolerated similar to the min balance diff of PD.
+func isBalanced(counts map[uint64]int) bool {
+	min, max := math.MaxInt32, 0
+	for _, count := range counts {
+		if count < min {
+			min = count
+		}
+		if count > max {
+			max = count
+		}
+	}
+	return float64(max-min) <= math.Max(2, 2*math.Sqrt(float64(max)))
+}
+
+// newBalanceLeaderCase has 3 stores with all leaders on the first store, the
+// leaders should be balanced.
+func newBalanceLeaderCase() *Case {
+	alloc := &idAllocator{}
+	stores := newStores(alloc, 3)
+	regions := newRegions(alloc, 300, stores, func(int) int { return 0 })
+	return &Case{
+		Stores:  stores,
+		Regions: regions,
+		MaxID:   alloc.id,
+		Checker: func(r *RaftEngine) bool {
+			return isBalanced(r.leaderCounts())
+		},
+	}
+}
+
+// newAddNodesCase has 8 stores with all regions on the first 3 stores, the
+// regions and the leaders should be balanced.
+func newAddNodesCase() *Case {
+	alloc := &idAllocator{}
+	stores := newStores(alloc, 8)
+	regions := newRegions(alloc, 400, stores[:3], func(i int) int { return i % 3 })
+	return &Case{
+		Stores:  stores,
+		Regions: regions,
+		MaxID:   alloc.id,
+		Checker: func(r *RaftEngine) bool {
+			return isBalanced(r.regionCounts()) && isBalanced(r.leaderCounts())
+		},
+	}
+}
+
+// newAddNodesDynamicCase starts from 3 stores, one store joins every 100
+// ticks until there are 8 stores, the regions and the leaders should be
+// balanced.
+func newAddNodesDynamicCase() *Case {
+	alloc := &idAllocator{}
+	stores := newStores(alloc, 8)
+	regions := newRegions(alloc, 400, stores[:3], func(i int) int { return i % 3 })
+	var events []*Event
+	for i, store := range stores[3:] {
+		events = append(events, &Event{Tick: int64((i + 1) * 100), Stores: []*Store{store}})
+	}
+	return &Case{
+		Stores:  stores[:3],
+		Regions: regions,
+		Events:  events,
+		MaxID:   alloc.id,
+		Checker: func(r *RaftEngine) bool {
+			return isBalanced(r.regionCounts()) && isBalanced(r.leaderCounts())
+		},
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"net"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const (
+	pdTimeout            = 3 * time.Second
+	maxResponseQueueSize = 1024
+)
+
+// Client is the connection from a simulated store to the PD leader. It
+// keeps a region heartbeat stream, the responses of the stream are received
+// by the channel of Responses.
+type Client struct {
+	clusterID uint64
+	conn      *grpc.ClientConn
+	pd        pdpb.PDClient
+	stream    pdpb.PD_RegionHeartbeatClient
+	respCh    chan *pdpb.RegionHeartbeatResponse
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewClient creates a client to the PD leader, addr is the URL of any PD
+// member.
+func NewClient(addr string) (*Client, error) {
+	conn, err := dial(addr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pdTimeout)
+	members, err := pdpb.NewPDClient(conn).GetMembers(ctx, &pdpb.GetMembersRequest{})
+	cancel()
+	if err != nil {
+		conn.Close()
+		return nil, errors.Trace(err)
+	}
+	leaderURLs := members.GetLeader().GetClientUrls()
+	if len(leaderURLs) == 0 {
+		conn.Close()
+		return nil, errors.New("no leader of PD")
+	}
+	if leaderURLs[0] != addr {
+		conn.Close()
+		if conn, err = dial(leaderURLs[0]); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	c := &Client{
+		clusterID: members.GetHeader().GetClusterId(),
+		conn:      conn,
+		pd:        pdpb.NewPDClient(conn),
+		respCh:    make(chan *pdpb.RegionHeartbeatResponse, maxResponseQueueSize),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	if c.stream, err = c.pd.RegionHeartbeat(c.ctx); err != nil {
+		c.Close()
+		return nil, errors.Trace(err)
+	}
+	go c.receiveRegionHeartbeat()
+	return c, nil
+}
+
+func dial(addr string) (*grpc.ClientConn, error) {
+	conn, err := grpc.Dial(addr, grpc.WithDialer(func(addr string, d time.Duration) (net.Conn, error) {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if u.Scheme == "unix" || u.Scheme == "unixs" {
+			return net.DialTimeout(u.Scheme, u.Host, d)
+		}
+		return net.DialTimeout("tcp", u.Host, d)
+	}), grpc.WithInsecure())
+	return conn, errors.Trace(err)
+}
+
+func (c *Client) receiveRegionHeartbeat() {
+	defer close(c.respCh)
+	for {
+		resp, err := c.stream.Recv()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				log.Errorf("receive region heartbeat response meet error: %v", err)
+			}
+			return
+		}
+		select {
+		case c.respCh <- resp:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Responses returns the channel of the region heartbeat responses, it is
+// closed once the stream is broken.
+func (c *Client) Responses() <-chan *pdpb.RegionHeartbeatResponse {
+	return c.respCh
+}
+
+// ClusterID returns the cluster ID of PD.
+func (c *Client) ClusterID() uint64 {
+	return c.clusterID
+}
+
+func (c *Client) requestHeader() *pdpb.RequestHeader {
+	return &pdpb.RequestHeader{ClusterId: c.clusterID}
+}
+
+func checkResponseHeader(header *pdpb.ResponseHeader) error {
+	if err := header.GetError(); err != nil {
+		return errors.Errorf("[%s] %s", err.GetType(), err.GetMessage())
+	}
+	return nil
+}
+
+// Bootstrap bootstraps the cluster with the store and the region.
+func (c *Client) Bootstrap(store *metapb.Store, region *metapb.Region) error {
+	ctx, cancel := context.WithTimeout(c.ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.Bootstrap(ctx, &pdpb.BootstrapRequest{
+		Header: c.requestHeader(),
+		Store:  store,
+		Region: region,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return checkResponseHeader(resp.GetHeader())
+}
+
+// AllocID allocates an ID from PD.
+func (c *Client) AllocID() (uint64, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.AllocID(ctx, &pdpb.AllocIDRequest{Header: c.requestHeader()})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return resp.GetId(), checkResponseHeader(resp.GetHeader())
+}
+
+// PutStore puts the store to the cluster.
+func (c *Client) PutStore(store *metapb.Store) error {
+	ctx, cancel := context.WithTimeout(c.ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.PutStore(ctx, &pdpb.PutStoreRequest{
+		Header: c.requestHeader(),
+		Store:  store,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return checkResponseHeader(resp.GetHeader())
+}
+
+// StoreHeartbeat sends the store heartbeat.
+func (c *Client) StoreHeartbeat(stats *pdpb.StoreStats) error {
+	ctx, cancel := context.WithTimeout(c.ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.StoreHeartbeat(ctx, &pdpb.StoreHeartbeatRequest{
+		Header: c.requestHeader(),
+		Stats:  stats,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return checkResponseHeader(resp.GetHeader())
+}
+
+// RegionHeartbeat sends the region heartbeat by the stream, the response is
+// received by Responses.
+func (c *Client) RegionHeartbeat(req *pdpb.RegionHeartbeatRequest) error {
+	req.Header = c.requestHeader()
+	return errors.Trace(c.stream.Send(req))
+}
+
+// Close closes the client.
+func (c *Client) Close() {
+	c.cancel()
+	if err := c.conn.Close(); err != nil {
+		log.Errorf("close the connection to PD meet error: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"golang.org/x/net/context"
+)
+
+// Stats counts the changes of the regions in a simulation, which is the
+// movement cost of the scheduling.
+type Stats struct {
+	AddPeer        int `json:"add_peer"`
+	RemovePeer     int `json:"remove_peer"`
+	TransferLeader int `json:"transfer_leader"`
+}
+
+// Driver drives the simulation of a case against PD tick by tick.
+type Driver struct {
+	pdAddr  string
+	simCase *Case
+	raft    *RaftEngine
+	nodes   []*Node
+	events  []*Event
+	tick    int64
+	stats   Stats
+}
+
+// NewDriver creates the driver of the case, pdAddr is the URL of any PD
+// member.
+func NewDriver(pdAddr string, c *Case) *Driver {
+	return &Driver{
+		pdAddr:  pdAddr,
+		simCase: c,
+		raft:    NewRaftEngine(c),
+		events:  c.Events,
+	}
+}
+
+// Prepare bootstraps the cluster by the first store and region of the case,
+// and puts the other stores. The IDs of the case are allocated from PD first,
+// so they are not reused by PD. The cluster should not be bootstrapped.
+func (d *Driver) Prepare() error {
+	if len(d.simCase.Stores) == 0 || len(d.simCase.Regions) == 0 {
+		return errors.New("the case has no store or region")
+	}
+	first := d.simCase.Regions[0]
+	node, err := d.newNode(d.simCase.Stores[0])
+	if err != nil {
+		return errors.Trace(err)
+	}
+	peer := d.raft.getRegion(first.ID).getStorePeer(node.GetId())
+	if peer == nil {
+		return errors.Errorf("the first region has no peer on store %d", node.GetId())
+	}
+	region := &metapb.Region{
+		Id:          first.ID,
+		Peers:       []*metapb.Peer{peer},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	if err = node.client.Bootstrap(node.Store, region); err != nil {
+		return errors.Annotate(err, "bootstrap the cluster")
+	}
+
+	for {
+		id, err := node.client.AllocID()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if id > d.simCase.MaxID {
+			break
+		}
+	}
+
+	for _, s := range d.simCase.Stores[1:] {
+		if err = d.addNode(s); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (d *Driver) newNode(s *Store) (*Node, error) {
+	node, err := NewNode(s, d.pdAddr, d.raft, &d.stats)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	d.nodes = append(d.nodes, node)
+	d.raft.addStore(node.GetId())
+	return node, nil
+}
+
+// addNode adds the store to the cluster.
+func (d *Driver) addNode(s *Store) error {
+	node, err := d.newNode(s)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(node.client.PutStore(node.Store))
+}
+
+// Tick runs the events of the tick, then runs the stores for the tick.
+func (d *Driver) Tick() error {
+	d.tick++
+	for len(d.events) > 0 && d.events[0].Tick <= d.tick {
+		for _, s := range d.events[0].Stores {
+			log.Infof("[tick %d] store %d joins the cluster", d.tick, s.ID)
+			if err := d.addNode(s); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		d.events = d.events[1:]
+	}
+	for _, node := range d.nodes {
+		node.Tick()
+	}
+	return nil
+}
+
+// Finished returns whether all events of the case happen and the checker of
+// the case passes.
+func (d *Driver) Finished() bool {
+	return len(d.events) == 0 && d.simCase.Checker(d.raft)
+}
+
+// Run ticks every interval until the case is finished, the ticks reach
+// maxTicks or ctx is done. It returns whether the case is finished, maxTicks
+// 0 means no limit.
+func (d *Driver) Run(ctx context.Context, interval time.Duration, maxTicks int64) (bool, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for maxTicks == 0 || d.tick < maxTicks {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false, nil
+		}
+		if err := d.Tick(); err != nil {
+			return false, errors.Trace(err)
+		}
+		if d.Finished() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Ticks returns the count of the ticks run.
+func (d *Driver) Ticks() int64 {
+	return d.tick
+}
+
+// Stats returns the changes of the regions so far.
+func (d *Driver) Stats() Stats {
+	return d.stats
+}
+
+// StoreCounts returns the count of the leaders and the regions of each store.
+func (d *Driver) StoreCounts() (leaders map[uint64]int, regions map[uint64]int) {
+	return d.raft.leaderCounts(), d.raft.regionCounts()
+}
+
+// Close closes the connections of the stores.
+func (d *Driver) Close() {
+	for _, node := range d.nodes {
+		node.Close()
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+	"golang.org/x/net/context"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testDriverSuite{})
+
+type testDriverSuite struct{}
+
+func mustStartPD(c *C) (*server.Server, func()) {
+	cfg := server.NewTestSingleConfig()
+	svr := server.CreateServer(cfg)
+	c.Assert(svr.StartEtcd(api.NewHandler(svr)), IsNil)
+	go svr.Run()
+	for i := 0; i < 100 && !svr.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(svr.IsLeader(), IsTrue)
+	return svr, func() {
+		svr.Close()
+		os.RemoveAll(cfg.DataDir)
+	}
+}
+
+func (s *testDriverSuite) TestBalanceLeader(c *C) {
+	svr, cleanup := mustStartPD(c)
+	defer cleanup()
+
+	alloc := &idAllocator{}
+	stores := newStores(alloc, 3)
+	simCase := &Case{
+		Stores:  stores,
+		Regions: newRegions(alloc, 30, stores, func(int) int { return 0 }),
+		MaxID:   alloc.id,
+		Checker: func(r *RaftEngine) bool {
+			return isBalanced(r.leaderCounts())
+		},
+	}
+	driver := NewDriver(svr.GetConfig().ClientUrls, simCase)
+	defer driver.Close()
+	c.Assert(driver.Prepare(), IsNil)
+	c.Assert(driver.Finished(), IsFalse)
+
+	finished, err := driver.Run(context.Background(), 10*time.Millisecond, 3000)
+	c.Assert(err, IsNil)
+	c.Assert(finished, IsTrue)
+	stats := driver.Stats()
+	c.Assert(stats.TransferLeader, Greater, 0)
+	c.Assert(stats.AddPeer, Equals, 0)
+
+	// The cluster is bootstrapped already.
+	c.Assert(NewDriver(svr.GetConfig().ClientUrls, simCase).Prepare(), NotNil)
+}
+
+func (s *testDriverSuite) TestTasks(c *C) {
+	alloc := &idAllocator{}
+	stores := newStores(alloc, 4)
+	region := newRegions(alloc, 1, stores[:3], func(int) int { return 0 })[0]
+	r := NewRaftEngine(&Case{Regions: []*Region{region}})
+	for _, store := range stores {
+		r.addStore(store.ID)
+	}
+	simRegion := r.getRegion(region.ID)
+	stats := &Stats{}
+	response := func(resp *pdpb.RegionHeartbeatResponse) *pdpb.RegionHeartbeatResponse {
+		resp.RegionId = region.ID
+		resp.RegionEpoch = simRegion.GetRegionEpoch()
+		return resp
+	}
+
+	// The new peer is pending until the snapshot is sent.
+	peer := &metapb.Peer{Id: alloc.nextID(), StoreId: stores[3].ID}
+	t := newTask(simRegion, response(&pdpb.RegionHeartbeatResponse{
+		ChangePeer: &pdpb.ChangePeer{Peer: peer, ChangeType: pdpb.ConfChangeType_AddNode},
+	}))
+	c.Assert(t.step(r, stats), IsFalse)
+	c.Assert(simRegion.GetPeers(), HasLen, 4)
+	c.Assert(simRegion.isPending(peer.GetId()), IsTrue)
+	c.Assert(r.receivingSnaps[stores[3].ID], Equals, 1)
+	for i := 0; i < defaultRegionSize/snapshotSizePerTick-1; i++ {
+		c.Assert(t.step(r, stats), IsFalse)
+	}
+	c.Assert(t.step(r, stats), IsTrue)
+	c.Assert(simRegion.isPending(peer.GetId()), IsFalse)
+	c.Assert(r.receivingSnaps[stores[3].ID], Equals, 0)
+	c.Assert(stats.AddPeer, Equals, 1)
+
+	// Transfer the leader, then remove the old leader.
+	oldLeader := simRegion.leader
+	t = newTask(simRegion, response(&pdpb.RegionHeartbeatResponse{
+		TransferLeader: &pdpb.TransferLeader{Peer: peer},
+	}))
+	c.Assert(t.step(r, stats), IsTrue)
+	c.Assert(simRegion.leader.GetStoreId(), Equals, stores[3].ID)
+	t = newTask(simRegion, response(&pdpb.RegionHeartbeatResponse{
+		ChangePeer: &pdpb.ChangePeer{Peer: oldLeader, ChangeType: pdpb.ConfChangeType_RemoveNode},
+	}))
+	c.Assert(t.step(r, stats), IsTrue)
+	c.Assert(simRegion.getPeer(oldLeader.GetId()), IsNil)
+	c.Assert(*stats, DeepEquals, Stats{AddPeer: 1, RemovePeer: 1, TransferLeader: 1})
+	c.Assert(r.regionCounts(), DeepEquals, map[uint64]int{1: 0, 2: 1, 3: 1, 4: 1})
+	c.Assert(r.leaderCounts(), DeepEquals, map[uint64]int{1: 0, 2: 0, 3: 0, 4: 1})
+}
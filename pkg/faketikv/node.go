@@ -0,0 +1,172 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+const (
+	storeHeartbeatTicks  = 10
+	regionHeartbeatTicks = 10
+)
+
+// Node is a simulated store. It reports the store and the regions it leads
+// to PD, and runs the tasks of the responses.
+type Node struct {
+	*metapb.Store
+	capacity  uint64
+	startTime time.Time
+	tick      int64
+	client    *Client
+	raft      *RaftEngine
+	stats     *Stats
+	// The running tasks by the region IDs, a region has one task at most.
+	tasks map[uint64]task
+}
+
+// NewNode creates the simulated store connected to PD.
+func NewNode(s *Store, pdAddr string, raft *RaftEngine, stats *Stats) (*Node, error) {
+	client, err := NewClient(pdAddr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Node{
+		Store: &metapb.Store{
+			Id:      s.ID,
+			Address: fmt.Sprintf("mock://tikv-%d", s.ID),
+		},
+		capacity:  s.Capacity,
+		startTime: time.Now(),
+		client:    client,
+		raft:      raft,
+		stats:     stats,
+		tasks:     make(map[uint64]task),
+	}, nil
+}
+
+// Tick runs the node for a tick.
+func (n *Node) Tick() {
+	n.tick++
+	n.receiveResponses()
+	n.stepTasks()
+	if n.tick%storeHeartbeatTicks == 1 {
+		n.storeHeartbeat()
+	}
+	n.regionHeartbeats()
+}
+
+func (n *Node) receiveResponses() {
+	for {
+		select {
+		case resp, ok := <-n.client.Responses():
+			if !ok {
+				return
+			}
+			n.handleResponse(resp)
+		default:
+			return
+		}
+	}
+}
+
+func (n *Node) handleResponse(resp *pdpb.RegionHeartbeatResponse) {
+	if err := checkResponseHeader(resp.GetHeader()); err != nil {
+		log.Warnf("[store %d] region heartbeat meet error: %v", n.GetId(), err)
+		return
+	}
+	region := n.raft.getRegion(resp.GetRegionId())
+	if region == nil || region.leader.GetStoreId() != n.GetId() {
+		return
+	}
+	if _, ok := n.tasks[region.GetId()]; ok {
+		return
+	}
+	// The response is for a stale region, PD will ask again once it gets the
+	// latest region.
+	epoch := resp.GetRegionEpoch()
+	if epoch.GetConfVer() != region.GetRegionEpoch().GetConfVer() || epoch.GetVersion() != region.GetRegionEpoch().GetVersion() {
+		return
+	}
+	if t := newTask(region, resp); t != nil {
+		log.Debugf("[store %d] [region %d] %s", n.GetId(), region.GetId(), t.desc())
+		n.tasks[region.GetId()] = t
+	}
+}
+
+func (n *Node) stepTasks() {
+	ids := make([]uint64, 0, len(n.tasks))
+	for id := range n.tasks {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	for _, id := range ids {
+		if n.tasks[id].step(n.raft, n.stats) {
+			delete(n.tasks, id)
+		}
+	}
+}
+
+func (n *Node) storeHeartbeat() {
+	count, size := n.raft.storeStats(n.GetId())
+	available := uint64(0)
+	if n.capacity > size {
+		available = n.capacity - size
+	}
+	stats := &pdpb.StoreStats{
+		StoreId:            n.GetId(),
+		Capacity:           n.capacity,
+		Available:          available,
+		UsedSize:           size,
+		RegionCount:        uint32(count),
+		SendingSnapCount:   uint32(n.raft.sendingSnaps[n.GetId()]),
+		ReceivingSnapCount: uint32(n.raft.receivingSnaps[n.GetId()]),
+		StartTime:          uint32(n.startTime.Unix()),
+	}
+	if err := n.client.StoreHeartbeat(stats); err != nil {
+		log.Warnf("[store %d] store heartbeat meet error: %v", n.GetId(), err)
+	}
+}
+
+// regionHeartbeats reports the regions led by the node, a region is reported
+// once it is changed or every regionHeartbeatTicks.
+func (n *Node) regionHeartbeats() {
+	for _, region := range n.raft.leaderRegions(n.GetId()) {
+		if !region.changed && (n.tick+int64(region.GetId()))%regionHeartbeatTicks != 0 {
+			continue
+		}
+		req := &pdpb.RegionHeartbeatRequest{
+			Region:       cloneRegion(region.Region),
+			Leader:       region.leader,
+			PendingPeers: append([]*metapb.Peer(nil), region.pendingPeers...),
+		}
+		if err := n.client.RegionHeartbeat(req); err != nil {
+			log.Warnf("[store %d] [region %d] region heartbeat meet error: %v", n.GetId(), region.GetId(), err)
+			continue
+		}
+		region.changed = false
+	}
+}
+
+// Close closes the connection to PD.
+func (n *Node) Close() {
+	n.client.Close()
+}
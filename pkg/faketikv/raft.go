@@ -0,0 +1,197 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// simRegion is the state of a region in the simulation.
+type simRegion struct {
+	*metapb.Region
+	leader       *metapb.Peer
+	pendingPeers []*metapb.Peer
+	size         uint64
+	// changed is set once the region is changed, so the leader reports it
+	// without waiting for the next heartbeat interval.
+	changed bool
+}
+
+func (r *simRegion) getPeer(peerID uint64) *metapb.Peer {
+	for _, p := range r.GetPeers() {
+		if p.GetId() == peerID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *simRegion) getStorePeer(storeID uint64) *metapb.Peer {
+	for _, p := range r.GetPeers() {
+		if p.GetStoreId() == storeID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *simRegion) isPending(peerID uint64) bool {
+	for _, p := range r.pendingPeers {
+		if p.GetId() == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *simRegion) removePending(peerID uint64) {
+	for i, p := range r.pendingPeers {
+		if p.GetId() == peerID {
+			r.pendingPeers = append(r.pendingPeers[:i], r.pendingPeers[i+1:]...)
+			return
+		}
+	}
+}
+
+// RaftEngine keeps the regions of the simulation, which is the truth of the
+// cluster. The regions are changed by the tasks of the simulated stores, and
+// the leaders report them to PD.
+type RaftEngine struct {
+	regions map[uint64]*simRegion
+	stores  []uint64
+	// The snapshots being sent and received by the stores.
+	sendingSnaps   map[uint64]int
+	receivingSnaps map[uint64]int
+}
+
+// NewRaftEngine creates the raft engine with the regions of the case.
+func NewRaftEngine(c *Case) *RaftEngine {
+	r := &RaftEngine{
+		regions:        make(map[uint64]*simRegion),
+		sendingSnaps:   make(map[uint64]int),
+		receivingSnaps: make(map[uint64]int),
+	}
+	for i, region := range c.Regions {
+		// The regions are newer than the region to bootstrap the cluster, as
+		// if they were split from it.
+		meta := &metapb.Region{
+			Id:          region.ID,
+			Peers:       region.Peers,
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 2, Version: 2},
+		}
+		// The regions are adjacent in the order of the case, the keys of
+		// the region i are [k{i}, k{i+1}) and the first and the last regions
+		// cover the whole key space.
+		if i > 0 {
+			meta.StartKey = []byte(fmt.Sprintf("k%08d", i))
+		}
+		if i < len(c.Regions)-1 {
+			meta.EndKey = []byte(fmt.Sprintf("k%08d", i+1))
+		}
+		r.regions[region.ID] = &simRegion{
+			Region:  meta,
+			leader:  region.Leader,
+			size:    region.Size,
+			changed: true,
+		}
+	}
+	return r
+}
+
+func (r *RaftEngine) getRegion(regionID uint64) *simRegion {
+	return r.regions[regionID]
+}
+
+// regionIDs returns the IDs of the regions in order.
+func (r *RaftEngine) regionIDs() []uint64 {
+	ids := make([]uint64, 0, len(r.regions))
+	for id := range r.regions {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	return ids
+}
+
+// leaderRegions returns the regions whose leaders are on the store.
+func (r *RaftEngine) leaderRegions(storeID uint64) []*simRegion {
+	var regions []*simRegion
+	for _, id := range r.regionIDs() {
+		if region := r.regions[id]; region.leader.GetStoreId() == storeID {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// storeStats returns the count and the total size of the regions which have
+// peers on the store.
+func (r *RaftEngine) storeStats(storeID uint64) (count int, size uint64) {
+	for _, region := range r.regions {
+		if region.getStorePeer(storeID) != nil {
+			count++
+			size += region.size
+		}
+	}
+	return count, size
+}
+
+// addStore adds the store which joins the cluster, so it is counted by the
+// checkers even if it has no peer.
+func (r *RaftEngine) addStore(storeID uint64) {
+	r.stores = append(r.stores, storeID)
+}
+
+func (r *RaftEngine) newStoreCounts() map[uint64]int {
+	counts := make(map[uint64]int, len(r.stores))
+	for _, id := range r.stores {
+		counts[id] = 0
+	}
+	return counts
+}
+
+// leaderCounts returns the count of the leaders on each store.
+func (r *RaftEngine) leaderCounts() map[uint64]int {
+	counts := r.newStoreCounts()
+	for _, region := range r.regions {
+		counts[region.leader.GetStoreId()]++
+	}
+	return counts
+}
+
+// regionCounts returns the count of the peers on each store.
+func (r *RaftEngine) regionCounts() map[uint64]int {
+	counts := r.newStoreCounts()
+	for _, region := range r.regions {
+		for _, p := range region.GetPeers() {
+			counts[p.GetStoreId()]++
+		}
+	}
+	return counts
+}
+
+// cloneRegion clones the meta of the region to report, the region may be
+// changed by the tasks after it is sent.
+func cloneRegion(region *metapb.Region) *metapb.Region {
+	return proto.Clone(region).(*metapb.Region)
+}
+
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
@@ -0,0 +1,151 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faketikv
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// snapshotSizePerTick is the size of a snapshot sent in a tick.
+const snapshotSizePerTick = 32 << 20
+
+// task is a change of a region asked by PD, it is run by the leader store of
+// the region tick by tick.
+type task interface {
+	// step runs the task for a tick, it returns true once the task is done.
+	step(r *RaftEngine, stats *Stats) bool
+	desc() string
+}
+
+// newTask creates the task of the region heartbeat response, it returns nil
+// if there is nothing to do.
+func newTask(region *simRegion, resp *pdpb.RegionHeartbeatResponse) task {
+	regionID := region.GetId()
+	if changePeer := resp.GetChangePeer(); changePeer != nil {
+		peer := proto.Clone(changePeer.GetPeer()).(*metapb.Peer)
+		switch changePeer.GetChangeType() {
+		case pdpb.ConfChangeType_AddNode:
+			return &addPeerTask{regionID: regionID, peer: peer, size: region.size}
+		case pdpb.ConfChangeType_RemoveNode:
+			return &removePeerTask{regionID: regionID, peer: peer}
+		}
+	}
+	if transferLeader := resp.GetTransferLeader(); transferLeader != nil {
+		return &transferLeaderTask{regionID: regionID, peer: transferLeader.GetPeer()}
+	}
+	return nil
+}
+
+// addPeerTask adds the peer, the peer is pending until the snapshot of the
+// region is sent to it.
+type addPeerTask struct {
+	regionID  uint64
+	peer      *metapb.Peer
+	fromStore uint64
+	size      uint64
+	started   bool
+}
+
+func (t *addPeerTask) step(r *RaftEngine, stats *Stats) bool {
+	region := r.getRegion(t.regionID)
+	if region == nil {
+		return true
+	}
+	if !t.started {
+		if region.getPeer(t.peer.GetId()) != nil || region.getStorePeer(t.peer.GetStoreId()) != nil {
+			return true
+		}
+		region.Peers = append(region.Peers, t.peer)
+		region.pendingPeers = append(region.pendingPeers, t.peer)
+		region.RegionEpoch.ConfVer++
+		region.changed = true
+		t.fromStore = region.leader.GetStoreId()
+		r.sendingSnaps[t.fromStore]++
+		r.receivingSnaps[t.peer.GetStoreId()]++
+		t.started = true
+		return false
+	}
+	if t.size > snapshotSizePerTick {
+		t.size -= snapshotSizePerTick
+		return false
+	}
+	r.sendingSnaps[t.fromStore]--
+	r.receivingSnaps[t.peer.GetStoreId()]--
+	if region.isPending(t.peer.GetId()) {
+		region.removePending(t.peer.GetId())
+		region.changed = true
+		stats.AddPeer++
+	}
+	return true
+}
+
+func (t *addPeerTask) desc() string {
+	return fmt.Sprintf("add peer %d on store %d", t.peer.GetId(), t.peer.GetStoreId())
+}
+
+// removePeerTask removes the peer, the leader can't be removed.
+type removePeerTask struct {
+	regionID uint64
+	peer     *metapb.Peer
+}
+
+func (t *removePeerTask) step(r *RaftEngine, stats *Stats) bool {
+	region := r.getRegion(t.regionID)
+	if region == nil || region.leader.GetId() == t.peer.GetId() {
+		return true
+	}
+	for i, p := range region.GetPeers() {
+		if p.GetId() == t.peer.GetId() {
+			region.Peers = append(region.Peers[:i], region.Peers[i+1:]...)
+			region.removePending(p.GetId())
+			region.RegionEpoch.ConfVer++
+			region.changed = true
+			stats.RemovePeer++
+			break
+		}
+	}
+	return true
+}
+
+func (t *removePeerTask) desc() string {
+	return fmt.Sprintf("remove peer %d on store %d", t.peer.GetId(), t.peer.GetStoreId())
+}
+
+// transferLeaderTask transfers the leader to the peer, the pending peers
+// can't be the leader.
+type transferLeaderTask struct {
+	regionID uint64
+	peer     *metapb.Peer
+}
+
+func (t *transferLeaderTask) step(r *RaftEngine, stats *Stats) bool {
+	region := r.getRegion(t.regionID)
+	if region == nil {
+		return true
+	}
+	if p := region.getPeer(t.peer.GetId()); p != nil && !region.isPending(p.GetId()) && p.GetId() != region.leader.GetId() {
+		region.leader = p
+		region.changed = true
+		stats.TransferLeader++
+	}
+	return true
+}
+
+func (t *transferLeaderTask) desc() string {
+	return fmt.Sprintf("transfer leader to peer %d on store %d", t.peer.GetId(), t.peer.GetStoreId())
+}
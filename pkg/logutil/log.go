@@ -16,10 +16,13 @@ package logutil
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/pkg/capnslog"
@@ -31,6 +34,7 @@ const (
 	defaultLogTimeFormat = "2006/01/02 15:04:05"
 	defaultLogMaxSize    = 300 // MB
 	defaultLogFormat     = "text"
+	defaultLogOutput     = "stderr"
 	defaultLogLevel      = log.InfoLevel
 )
 
@@ -38,7 +42,7 @@ const (
 type FileLogConfig struct {
 	// Log filename, leave empty to disable file log.
 	Filename string `toml:"filename" json:"filename"`
-	// Is log rotate enabled. TODO.
+	// Rotate the log file every day besides by the size.
 	LogRotate bool `toml:"log-rotate" json:"log-rotate"`
 	// Max size for a single file, in MB.
 	MaxSize int `toml:"max-size" json:"max-size"`
@@ -56,8 +60,13 @@ type LogConfig struct {
 	Format string `toml:"format" json:"format"`
 	// Disable automatic timestamps in output.
 	DisableTimestamp bool `toml:"disable-timestamp" json:"disable-timestamp"`
+	// Log output if file log is disabled, one of stderr or stdout.
+	Output string `toml:"output" json:"output"`
 	// File log config.
 	File FileLogConfig `toml:"file" json:"file"`
+	// Error log config, the logs of error level and above are also written
+	// to it, leave the filename empty to disable.
+	ErrorFile FileLogConfig `toml:"error-file" json:"error-file"`
 }
 
 // redirectFormatter will redirect etcd logs to logrus logs.
@@ -123,22 +132,30 @@ func (hook *contextHook) Levels() []log.Level {
 	return log.AllLevels
 }
 
+var logLevels = map[string]log.Level{
+	"fatal":   log.FatalLevel,
+	"error":   log.ErrorLevel,
+	"warn":    log.WarnLevel,
+	"warning": log.WarnLevel,
+	"debug":   log.DebugLevel,
+	"info":    log.InfoLevel,
+}
+
 func stringToLogLevel(level string) log.Level {
-	switch strings.ToLower(level) {
-	case "fatal":
-		return log.FatalLevel
-	case "error":
-		return log.ErrorLevel
-	case "warn", "warning":
-		return log.WarnLevel
-	case "debug":
-		return log.DebugLevel
-	case "info":
-		return log.InfoLevel
+	if l, ok := logLevels[strings.ToLower(level)]; ok {
+		return l
 	}
 	return defaultLogLevel
 }
 
+// CheckLevel returns an error if the log level is unknown.
+func CheckLevel(level string) error {
+	if _, ok := logLevels[strings.ToLower(level)]; !ok {
+		return errors.Errorf("unknown log level %s", level)
+	}
+	return nil
+}
+
 // textFormatter is for compatability with ngaut/log
 type textFormatter struct {
 	DisableTimestamp bool
@@ -190,11 +207,29 @@ func stringToLogFormatter(format string, disableTimestamp bool) log.Formatter {
 	}
 }
 
-// InitFileLog initializes file based logging options.
-func InitFileLog(cfg *FileLogConfig) error {
+func stringToLogOutput(output string) (io.Writer, error) {
+	switch strings.ToLower(output) {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	}
+	return nil, errors.Errorf("unknown log output %s", output)
+}
+
+// rotatedFile is the log file rotated by the size, and also at every midnight
+// if log-rotate is on.
+type rotatedFile struct {
+	*lumberjack.Logger
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// newFileLogger creates the rotated log file.
+func newFileLogger(cfg *FileLogConfig) (*rotatedFile, error) {
 	if st, err := os.Stat(cfg.Filename); err == nil {
 		if st.IsDir() {
-			return errors.New("can't use directory as log file name")
+			return nil, errors.New("can't use directory as log file name")
 		}
 	}
 	if cfg.MaxSize == 0 {
@@ -202,32 +237,138 @@ func InitFileLog(cfg *FileLogConfig) error {
 	}
 
 	// use lumberjack to logrotate
-	output := &lumberjack.Logger{
-		Filename:   cfg.Filename,
-		MaxSize:    cfg.MaxSize,
-		MaxBackups: cfg.MaxBackups,
-		MaxAge:     cfg.MaxDays,
-		LocalTime:  true,
+	output := &rotatedFile{
+		Logger: &lumberjack.Logger{
+			Filename:   cfg.Filename,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxDays,
+			LocalTime:  true,
+		},
+		quit: make(chan struct{}),
 	}
+	if cfg.LogRotate {
+		go output.rotateDaily()
+	}
+	return output, nil
+}
+
+// rotateDaily rotates the log file at every midnight until the file is closed.
+func (f *rotatedFile) rotateDaily() {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+		case <-f.quit:
+			timer.Stop()
+			return
+		}
+		if err := f.Rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "rotate log file %s failed: %v\n", f.Filename, err)
+		}
+	}
+}
+
+// Close stops the daily rotation and closes the file.
+func (f *rotatedFile) Close() error {
+	f.quitOnce.Do(func() { close(f.quit) })
+	return errors.Trace(f.Logger.Close())
+}
 
+// InitFileLog initializes file based logging options.
+func InitFileLog(cfg *FileLogConfig) error {
+	output, err := newFileLogger(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	log.SetOutput(output)
 	return nil
 }
 
+// NewFileLogger creates a logger writing to the file in the format of cfg, it
+// is for the logs which are kept apart from the main log. The returned closer
+// closes the file and must be called when the logger is no longer used.
+func NewFileLogger(cfg *LogConfig, file *FileLogConfig) (*log.Logger, io.Closer, error) {
+	output, err := newFileLogger(file)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	format := cfg.Format
+	if format == "" {
+		format = defaultLogFormat
+	}
+	return &log.Logger{
+		Out:       output,
+		Formatter: stringToLogFormatter(format, cfg.DisableTimestamp),
+		Hooks:     make(log.LevelHooks),
+		Level:     log.InfoLevel,
+	}, output, nil
+}
+
+// errorFileHook writes the logs of error level and above to the error log.
+type errorFileHook struct {
+	sync.Mutex
+	out       io.Writer
+	formatter log.Formatter
+}
+
+// Fire implements logrus.Hook interface.
+func (hook *errorFileHook) Fire(entry *log.Entry) error {
+	serialized, err := hook.formatter.Format(entry)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	hook.Lock()
+	defer hook.Unlock()
+	_, err = hook.out.Write(serialized)
+	return errors.Trace(err)
+}
+
+// Levels implements logrus.Hook interface.
+func (hook *errorFileHook) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel}
+}
+
+// SetLevel changes the log level at runtime.
+func SetLevel(level string) {
+	log.SetLevel(stringToLogLevel(level))
+}
+
 // InitLogger initalizes PD's logger.
 func InitLogger(cfg *LogConfig) error {
 	log.SetLevel(stringToLogLevel(cfg.Level))
+	// The hooks are reset in case the logger is initialized again.
+	log.StandardLogger().Hooks = make(log.LevelHooks)
 	log.AddHook(&contextHook{})
 
 	if cfg.Format == "" {
 		cfg.Format = defaultLogFormat
 	}
-	log.SetFormatter(stringToLogFormatter(cfg.Format, cfg.DisableTimestamp))
+	formatter := stringToLogFormatter(cfg.Format, cfg.DisableTimestamp)
+	log.SetFormatter(formatter)
 
 	// etcd log
 	capnslog.SetFormatter(&redirectFormatter{})
 
+	if len(cfg.ErrorFile.Filename) != 0 {
+		output, err := newFileLogger(&cfg.ErrorFile)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		log.AddHook(&errorFileHook{out: output, formatter: formatter})
+	}
+
 	if len(cfg.File.Filename) == 0 {
+		if cfg.Output == "" {
+			cfg.Output = defaultLogOutput
+		}
+		output, err := stringToLogOutput(cfg.Output)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		log.SetOutput(output)
 		return nil
 	}
 
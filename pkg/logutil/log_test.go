@@ -15,6 +15,9 @@ package logutil
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
 	"strings"
 	"testing"
 
@@ -49,6 +52,8 @@ func (s *testLogSuite) TestStringToLogLevel(c *C) {
 	c.Assert(stringToLogLevel("debug"), Equals, log.DebugLevel)
 	c.Assert(stringToLogLevel("info"), Equals, log.InfoLevel)
 	c.Assert(stringToLogLevel("whatever"), Equals, log.InfoLevel)
+	c.Assert(CheckLevel("Debug"), IsNil)
+	c.Assert(CheckLevel("whatever"), NotNil)
 }
 
 // TestLogging assure log format and log redirection works.
@@ -76,3 +81,54 @@ func (s *testLogSuite) TestLogging(c *C) {
 	c.Assert(entry, Matches, logPattern)
 	c.Assert(strings.Contains(entry, "log_test.go"), IsTrue)
 }
+
+func (s *testLogSuite) TestErrorFile(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	errorFile := path.Join(dir, "error.log")
+	conf := &LogConfig{Level: "info", ErrorFile: FileLogConfig{Filename: errorFile}}
+	c.Assert(InitLogger(conf), IsNil)
+	log.SetOutput(s.buf)
+	defer s.buf.Reset()
+
+	log.Warnf("this message should not be sent to error log")
+	log.Errorf("this message should be sent to error log")
+	data, err := ioutil.ReadFile(errorFile)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, logPattern)
+	c.Assert(strings.Contains(string(data), "should be sent to error log"), IsTrue)
+	c.Assert(strings.Contains(string(data), "log_test.go"), IsTrue)
+	c.Assert(strings.Count(s.buf.String(), "\n"), Equals, 2)
+}
+
+func (s *testLogSuite) TestOutput(c *C) {
+	conf := &LogConfig{Level: "info", Output: "stdout"}
+	c.Assert(InitLogger(conf), IsNil)
+	c.Assert(log.StandardLogger().Out, Equals, os.Stdout)
+
+	conf = &LogConfig{Level: "info", Output: "whatever"}
+	c.Assert(InitLogger(conf), NotNil)
+}
+
+func (s *testLogSuite) TestCloseFileLogger(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	file := &FileLogConfig{Filename: path.Join(dir, "slow.log"), LogRotate: true}
+	logger, closer, err := NewFileLogger(&LogConfig{}, file)
+	c.Assert(err, IsNil)
+	logger.Info("this message should be sent to the file")
+	c.Assert(closer.Close(), IsNil)
+	// The daily rotation is stopped.
+	_, ok := <-closer.(*rotatedFile).quit
+	c.Assert(ok, IsFalse)
+	// It can be closed again.
+	c.Assert(closer.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(file.Filename)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), "should be sent to the file"), IsTrue)
+}
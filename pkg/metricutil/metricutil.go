@@ -34,6 +34,17 @@ type MetricConfig struct {
 	PushJob      string            `toml:"job" json:"job"`
 	PushAddress  string            `toml:"address" json:"address"`
 	PushInterval typeutil.Duration `toml:"interval" json:"interval"`
+	// PushInstance is the instance label of the pushed metrics, the hostname
+	// is used if it is empty.
+	PushInstance string `toml:"instance" json:"instance"`
+}
+
+// groupingKey returns the labels to group the pushed metrics.
+func (cfg *MetricConfig) groupingKey() map[string]string {
+	if cfg.PushInstance != "" {
+		return map[string]string{"instance": cfg.PushInstance}
+	}
+	return push.HostnameGroupingKey()
 }
 
 func runesHasLowerNeighborAt(runes []rune, idx int) bool {
@@ -62,10 +73,10 @@ func camelCaseToSnakeCase(str string) string {
 }
 
 // prometheusPushClient pushs metrics to Prometheus Pushgateway.
-func prometheusPushClient(job, addr string, interval time.Duration) {
+func prometheusPushClient(job string, groupingKey map[string]string, addr string, interval time.Duration) {
 	for {
 		err := push.FromGatherer(
-			job, push.HostnameGroupingKey(),
+			job, groupingKey,
 			addr,
 			prometheus.DefaultGatherer,
 		)
@@ -87,5 +98,5 @@ func Push(cfg *MetricConfig) {
 	log.Info("start Prometheus push client")
 
 	interval := cfg.PushInterval.Duration
-	go prometheusPushClient(cfg.PushJob, cfg.PushAddress, interval)
+	go prometheusPushClient(cfg.PushJob, cfg.groupingKey(), cfg.PushAddress, interval)
 }
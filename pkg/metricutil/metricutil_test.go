@@ -14,6 +14,7 @@
 package metricutil
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -84,3 +85,13 @@ func (s *testMetricsSuite) TestCoverage(c *C) {
 		Push(cfg)
 	}
 }
+
+func (s *testMetricsSuite) TestGroupingKey(c *C) {
+	cfg := &MetricConfig{}
+	hostname, err := os.Hostname()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.groupingKey(), DeepEquals, map[string]string{"instance": hostname})
+
+	cfg.PushInstance = "pd1"
+	c.Assert(cfg.groupingKey(), DeepEquals, map[string]string{"instance": "pd1"})
+}
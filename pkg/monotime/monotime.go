@@ -0,0 +1,24 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package monotime reads a monotonic clock, which isn't affected by the
+// adjustments of the wall clock.
+package monotime
+
+import "time"
+
+// Since returns the elapsed time on the monotonic clock since t, a reading
+// returned by Now.
+func Since(t time.Duration) time.Duration {
+	return Now() - t
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !linux
+
+package monotime
+
+import "time"
+
+var start = time.Now()
+
+// Now returns the time elapsed since the process started. It's only
+// monotonic when built with Go 1.9 or later.
+func Now() time.Duration {
+	return time.Since(start)
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monotime
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func TestMonotime(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testMonotimeSuite{})
+
+type testMonotimeSuite struct{}
+
+func (s *testMonotimeSuite) TestSince(c *C) {
+	start := Now()
+	time.Sleep(10 * time.Millisecond)
+	elapsed := Since(start)
+	c.Assert(elapsed >= 10*time.Millisecond, IsTrue)
+	c.Assert(elapsed < time.Second, IsTrue)
+}
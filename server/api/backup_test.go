@@ -0,0 +1,69 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testBackupSuite{})
+
+type testBackupSuite struct{}
+
+func (s *testBackupSuite) TestBackupRestore(c *C) {
+	svr1, cleanup1 := mustNewServer(c)
+	defer cleanup1()
+	svr2, cleanup2 := mustNewServer(c)
+	defer cleanup2()
+	mustBootstrapCluster(c, svr1)
+	mustBootstrapCluster(c, svr2)
+
+	grpcPDClient := mustNewGrpcClient(c, svr1.GetAddr())
+	resp, err := grpcPDClient.AllocID(context.Background(), &pdpb.AllocIDRequest{Header: newRequestHeader(svr1.ClusterID())})
+	c.Assert(err, IsNil)
+	mustPutStore(c, svr1, &metapb.Store{Id: 3, Address: "localhost:3"})
+	c.Assert(svr1.SetNamespace(&server.Namespace{Name: "ns1", StoreIDs: []uint64{3}}), IsNil)
+	cfg := svr1.GetScheduleConfig()
+	cfg.LeaderScheduleLimit = 16
+	c.Assert(svr1.SetScheduleConfig(*cfg), IsNil)
+
+	addr1 := mustUnixAddrToHTTPAddr(c, svr1.GetAddr())
+	backup := &server.ClusterBackup{}
+	c.Assert(readJSONWithURL(fmt.Sprintf("%s%s/api/v1/backup", addr1, apiPrefix), backup), IsNil)
+	c.Assert(backup.ClusterID, Equals, svr1.ClusterID())
+	c.Assert(backup.Stores, HasLen, 2)
+	c.Assert(backup.AllocID >= resp.GetId(), IsTrue)
+
+	addr2 := mustUnixAddrToHTTPAddr(c, svr2.GetAddr())
+	postData, err := json.Marshal(backup)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(unixClient, fmt.Sprintf("%s%s/api/v1/backup/restore", addr2, apiPrefix), postData), IsNil)
+
+	c.Assert(svr2.GetRaftCluster().GetStores(), HasLen, 2)
+	c.Assert(svr2.GetNamespace("ns1"), DeepEquals, backup.Namespaces[0])
+	c.Assert(svr2.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(16))
+
+	// The ids in the backup are not allocated again.
+	grpcPDClient = mustNewGrpcClient(c, svr2.GetAddr())
+	resp, err = grpcPDClient.AllocID(context.Background(), &pdpb.AllocIDRequest{Header: newRequestHeader(svr2.ClusterID())})
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetId(), Greater, backup.AllocID)
+}
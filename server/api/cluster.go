@@ -39,7 +39,7 @@ func (h *clusterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *clusterHandler) GetClusterStatus(w http.ResponseWriter, r *http.Request) {
 	status, err := h.svr.GetClusterStatus()
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	h.rd.JSON(w, http.StatusOK, status)
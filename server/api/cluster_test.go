@@ -64,9 +64,11 @@ func (s *testClusterInfo) TestGetClusterStatus(c *C) {
 	status := server.ClusterStatus{}
 	err := readJSONWithURL(url, &status)
 	c.Assert(status.RaftBootstrapTime.IsZero(), IsTrue)
+	c.Assert(status.IsBootstrapped, IsFalse)
 	now := time.Now()
 	mustBootstrapCluster(c, s.svr)
 	err = readJSONWithURL(url, &status)
 	c.Assert(err, IsNil)
 	c.Assert(status.RaftBootstrapTime.After(now), IsTrue)
+	c.Assert(status.IsBootstrapped, IsTrue)
 }
@@ -17,7 +17,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strings"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
 )
@@ -43,21 +46,31 @@ func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	if err = checkConfigItems(data); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	err = json.Unmarshal(data, &config.Schedule)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	err = json.Unmarshal(data, &config.Replication)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = h.svr.SetScheduleConfig(config.Schedule); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = h.svr.SetReplicationConfig(config.Replication); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	h.svr.SetScheduleConfig(config.Schedule)
-	h.svr.SetReplicationConfig(config.Replication)
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
@@ -69,11 +82,14 @@ func (h *confHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
 	config := h.svr.GetScheduleConfig()
 	err := readJSON(r.Body, config)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
-	h.svr.SetScheduleConfig(*config)
+	if err = h.svr.SetScheduleConfig(*config); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
@@ -85,10 +101,39 @@ func (h *confHandler) SetReplication(w http.ResponseWriter, r *http.Request) {
 	config := h.svr.GetReplicationConfig()
 	err := readJSON(r.Body, config)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
-	h.svr.SetReplicationConfig(*config)
+	if err = h.svr.SetReplicationConfig(*config); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, nil)
 }
+
+// checkConfigItems returns an error if an item of the posted config is in
+// neither the schedule config nor the replication config, so a misspelled
+// item is not ignored silently.
+func checkConfigItems(data []byte) error {
+	var items map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return errors.Trace(err)
+	}
+	for item := range items {
+		if !hasJSONField(reflect.TypeOf(server.ScheduleConfig{}), item) &&
+			!hasJSONField(reflect.TypeOf(server.ReplicationConfig{}), item) {
+			return errors.Errorf("config item %s not found", item)
+		}
+	}
+	return nil
+}
+
+func hasJSONField(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return true
+		}
+	}
+	return false
+}
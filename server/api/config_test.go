@@ -76,6 +76,14 @@ func (s *testConfigSuite) TestConfigAll(c *C) {
 		cfg.Replication.LocationLabels = []string{"zone", "rack"}
 		cfg.Schedule.RegionScheduleLimit = 10
 		c.Assert(cfg, DeepEquals, newCfg)
+
+		// The unknown items and the invalid values are rejected.
+		postData, err = json.Marshal(map[string]int{"max-replica": 3})
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.hc, addr, postData), NotNil)
+		postData, err = json.Marshal(map[string]string{"region-schedule-limit": "10"})
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.hc, addr, postData), NotNil)
 	}
 }
 
@@ -103,7 +111,25 @@ func (s *testConfigSuite) TestConfigSchedule(c *C) {
 		sc1 := &server.ScheduleConfig{}
 		readJSON(resp.Body, sc1)
 
-		c.Assert(*sc, Equals, *sc1)
+		c.Assert(*sc, DeepEquals, *sc1)
+
+		sc.ThrottleWindows = []server.ThrottleWindow{{StartTime: "09:00", EndTime: "18:00", RegionScheduleLimit: 2}}
+		postData, err = json.Marshal(sc)
+		c.Assert(err, IsNil)
+		err = postJSON(s.hc, postAddr, postData)
+		c.Assert(err, IsNil)
+		resp, err = s.hc.Get(addr)
+		c.Assert(err, IsNil)
+		sc1 = &server.ScheduleConfig{}
+		readJSON(resp.Body, sc1)
+		c.Assert(*sc, DeepEquals, *sc1)
+
+		// The invalid windows are rejected.
+		sc.ThrottleWindows[0].EndTime = "9am"
+		postData, err = json.Marshal(sc)
+		c.Assert(err, IsNil)
+		err = postJSON(s.hc, postAddr, postData)
+		c.Assert(err, NotNil)
 	}
 }
 
@@ -141,5 +167,10 @@ func (s *testConfigSuite) TestConfigReplication(c *C) {
 		err = readJSON(resp.Body, rc3)
 
 		c.Assert(*rc, DeepEquals, *rc3)
+
+		// max-replicas can't be 0.
+		postData, err = json.Marshal(map[string]int{"max-replicas": 0})
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.hc, postAddr, postData), NotNil)
 	}
 }
@@ -16,7 +16,9 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
 )
@@ -36,7 +38,7 @@ func newFeedHandler(svr *server.Server, rd *render.Render) *feedHandler {
 func (h *feedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
@@ -48,7 +50,7 @@ func (h *feedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	offset, err := strconv.ParseUint(offsetStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -78,3 +80,51 @@ func (h *eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	evts := cluster.FetchEvents(0, true)
 	h.rd.JSON(w, http.StatusOK, evts)
 }
+
+type clusterEventsHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newClusterEventsHandler(svr *server.Server, rd *render.Render) *clusterEventsHandler {
+	return &clusterEventsHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// ServeHTTP returns the cluster event history in the time range [start, end),
+// which are unix timestamps in seconds.
+func (h *clusterEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start, err := parseUnixTime(r, "start")
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseUnixTime(r, "end")
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	evts, err := h.svr.GetClusterEvents(start, end)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, evts)
+}
+
+// parseUnixTime parses the query parameter in unix seconds, it returns the
+// zero time if the parameter is not set.
+func parseUnixTime(r *http.Request, name string) (time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	ts, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Trace(err)
+	}
+	return time.Unix(ts, 0), nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testEventSuite{})
+
+type testEventSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testEventSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	httpAddr := mustUnixAddrToHTTPAddr(c, addr)
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", httpAddr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testEventSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testEventSuite) TestEvents(c *C) {
+	// The operator events are still served.
+	var opEvts []*server.LogEvent
+	c.Assert(readJSONWithURL(s.urlPrefix+"/events", &opEvts), IsNil)
+}
+
+func (s *testEventSuite) TestClusterEvents(c *C) {
+	evts := s.mustWaitClusterEvents(c, s.urlPrefix+"/cluster_events", 2)
+	c.Assert(evts[0].Type, Equals, "leader")
+	c.Assert(evts[1].Type, Equals, "bootstrap")
+
+	start := time.Now()
+	cfg := s.svr.GetScheduleConfig()
+	cfg.LeaderScheduleLimit++
+	c.Assert(s.svr.SetScheduleConfig(*cfg), IsNil)
+
+	c.Assert(readJSONWithURL(fmt.Sprintf("%s/cluster_events?start=%d", s.urlPrefix, start.Add(time.Hour).Unix()), &evts), IsNil)
+	c.Assert(evts, HasLen, 0)
+	evts = s.mustWaitClusterEvents(c, fmt.Sprintf("%s/cluster_events?start=%d", s.urlPrefix, start.Unix()), 1)
+	c.Assert(evts[len(evts)-1].Type, Equals, "config")
+	c.Assert(readJSONWithURL(fmt.Sprintf("%s/cluster_events?end=%d", s.urlPrefix, evts[0].Time.Add(-time.Second).Unix()), &evts), IsNil)
+	c.Assert(evts, HasLen, 0)
+
+	resp, err := unixClient.Get(s.urlPrefix + "/cluster_events?start=abc")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+// mustWaitClusterEvents waits until there are at least count events, as they
+// are saved in the background.
+func (s *testEventSuite) mustWaitClusterEvents(c *C, url string, count int) []*server.ClusterEvent {
+	var evts []*server.ClusterEvent
+	for i := 0; i < 100; i++ {
+		c.Assert(readJSONWithURL(url, &evts), IsNil)
+		if len(evts) >= count {
+			return evts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("wait for %d cluster events timeout", count)
+	return nil
+}
@@ -40,7 +40,7 @@ func newHistoryHandler(handler *server.Handler, r *render.Render) *historyHandle
 func (h *historyHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
 	ops, err := h.GetHistoryOperators()
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -52,7 +52,7 @@ func (h *historyHandler) GetOperatorsOfKind(w http.ResponseWriter, r *http.Reque
 	l := mux.Vars(r)["limit"]
 	limit, err := strconv.Atoi(l)
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	if limit <= 0 {
@@ -66,7 +66,7 @@ func (h *historyHandler) GetOperatorsOfKind(w http.ResponseWriter, r *http.Reque
 	}
 	ops, err := h.GetHistoryOperatorsOfKind(kind)
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	if limit > len(ops) {
@@ -75,3 +75,15 @@ func (h *historyHandler) GetOperatorsOfKind(w http.ResponseWriter, r *http.Reque
 
 	h.r.JSON(w, http.StatusOK, ops[:limit])
 }
+
+func (h *historyHandler) GetSplits(w http.ResponseWriter, r *http.Request) {
+	startKey := r.URL.Query().Get("start_key")
+	endKey := r.URL.Query().Get("end_key")
+	ops, err := h.GetSplitHistories([]byte(startKey), []byte(endKey))
+	if err != nil {
+		h.r.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, ops)
+}
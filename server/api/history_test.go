@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -116,3 +117,84 @@ func (s *testHistorySuite) TestHistroyOperators(c *C) {
 		c.Assert(len(res), Equals, t.result)
 	}
 }
+
+func (s *testHistorySuite) TestOperatorRecords(c *C) {
+	start := time.Now().Unix()
+	c.Assert(addTransferLeaderOperator(s.cli, s.urlPrefix, 2, 1), IsNil)
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/operators/2", s.urlPrefix), nil)
+	c.Assert(err, IsNil)
+	resp, err := s.cli.Do(req)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	resp.Body.Close()
+
+	var records []struct {
+		RegionID uint64               `json:"region_id"`
+		State    server.OperatorState `json:"state"`
+	}
+	resp, err = s.cli.Get(fmt.Sprintf("%s/operators/history?start=%d", s.urlPrefix, start))
+	c.Assert(err, IsNil)
+	c.Assert(readJSON(resp.Body, &records), IsNil)
+	c.Assert(len(records), Greater, 0)
+	record := records[len(records)-1]
+	c.Assert(record.RegionID, Equals, uint64(2))
+	c.Assert(record.State, Equals, server.OperatorCanceled)
+
+	// No operator is finished in the future.
+	resp, err = s.cli.Get(fmt.Sprintf("%s/operators/history?start=%d", s.urlPrefix, time.Now().Unix()+10))
+	c.Assert(err, IsNil)
+	records = nil
+	c.Assert(readJSON(resp.Body, &records), IsNil)
+	c.Assert(records, HasLen, 0)
+
+	resp, err = s.cli.Get(fmt.Sprintf("%s/operators/history?start=x", s.urlPrefix))
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	resp.Body.Close()
+}
+
+func (s *testHistorySuite) TestDryRunOperators(c *C) {
+	// No operator is created in dry-run mode as it is disabled.
+	resp, err := s.cli.Get(fmt.Sprintf("%s/operators/dry-run", s.urlPrefix))
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var ops []interface{}
+	c.Assert(readJSON(resp.Body, &ops), IsNil)
+	c.Assert(ops, HasLen, 0)
+}
+
+func (s *testHistorySuite) TestSplitHistory(c *C) {
+	// Split region 3 [b, f) to [b, d) and [d, f).
+	left := newTestRegionInfo(3, 1, []byte("b"), []byte("d"))
+	right := newTestRegionInfo(4, 1, []byte("d"), []byte("f"))
+	req := &pdpb.ReportSplitRequest{
+		Header: &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()},
+		Left:   left.Region,
+		Right:  right.Region,
+	}
+	_, err := s.grpcPDClient.ReportSplit(context.Background(), req)
+	c.Assert(err, IsNil)
+
+	tbl := []struct {
+		startKey string
+		endKey   string
+		result   int
+	}{
+		{"", "", 1},
+		{"a", "b", 0},
+		{"a", "c", 1},
+		{"e", "", 1},
+		{"f", "", 0},
+	}
+
+	for _, t := range tbl {
+		url := fmt.Sprintf("%s/history/split?start_key=%s&end_key=%s", s.urlPrefix, t.startKey, t.endKey)
+		resp, err := s.cli.Get(url)
+		c.Assert(err, IsNil)
+		c.Assert(resp.StatusCode, Equals, 200)
+		res := []interface{}{}
+		err = readJSON(resp.Body, &res)
+		c.Assert(err, IsNil)
+		c.Assert(len(res), Equals, t.result)
+	}
+}
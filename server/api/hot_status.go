@@ -36,6 +36,23 @@ func (h *hotStatusHandler) GetHotRegions(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, h.GetHotWriteRegions())
 }
 
+func (h *hotStatusHandler) GetHotReadRegions(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.Handler.GetHotReadRegions())
+}
+
+func (h *hotStatusHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("type")
+	if kind == "" {
+		kind = "write"
+	}
+	heatmap, err := h.Handler.GetHeatmap(kind)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, heatmap)
+}
+
 func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, h.GetHotWriteStores())
 }
@@ -38,7 +38,7 @@ func newLabelsHandler(svr *server.Server, rd *render.Render) *labelsHandler {
 func (h *labelsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 	var labels []*metapb.StoreLabel
@@ -59,7 +59,7 @@ func (h *labelsHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *labelsHandler) GetStores(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
@@ -67,7 +67,7 @@ func (h *labelsHandler) GetStores(w http.ResponseWriter, r *http.Request) {
 	value := r.URL.Query().Get("value")
 	filter, err := newStoresLabelFilter(name, value)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -80,7 +80,7 @@ func (h *labelsHandler) GetStores(w http.ResponseWriter, r *http.Request) {
 	for _, s := range stores {
 		store, status, err := cluster.GetStore(s.GetId())
 		if err != nil {
-			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			h.rd.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 
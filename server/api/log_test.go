@@ -0,0 +1,55 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLogSuite{})
+
+type testLogSuite struct {
+	hc *http.Client
+}
+
+func (s *testLogSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testLogSuite) TestSetLogLevel(c *C) {
+	_, svrs, clean := mustNewCluster(c, 2)
+	defer clean()
+	defer log.SetLevel(log.GetLevel())
+
+	leader := mustWaitLeader(c, svrs)
+	follower := svrs[0]
+	if follower == leader {
+		follower = svrs[1]
+	}
+	addr := mustUnixAddrToHTTPAddr(c, follower.GetAddr()+apiPrefix+"/api/v1/admin/log")
+
+	// The level is set on the server requested, it is not redirected.
+	c.Assert(postJSON(s.hc, addr, []byte(`"debug"`)), IsNil)
+	c.Assert(log.GetLevel(), Equals, log.DebugLevel)
+	c.Assert(follower.GetConfig().Log.Level, Equals, "debug")
+	c.Assert(leader.GetConfig().Log.Level, Not(Equals), "debug")
+
+	c.Assert(postJSON(s.hc, addr, []byte(`"whatever"`)), NotNil)
+	c.Assert(postJSON(s.hc, addr, []byte(`debug`)), NotNil)
+	c.Assert(postJSON(s.hc, addr, []byte(`"warn"`)), IsNil)
+	c.Assert(log.GetLevel(), Equals, log.WarnLevel)
+}
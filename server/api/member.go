@@ -16,10 +16,10 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/pkg/etcdutil"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
@@ -40,14 +40,12 @@ func newMemberListHandler(svr *server.Server, rd *render.Render) *memberListHand
 }
 
 func (h *memberListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	client := h.svr.GetClient()
-
-	members, err := server.GetMembers(client)
+	members, err := h.svr.GetMemberList()
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
-	ret := make(map[string][]*pdpb.Member)
+	ret := make(map[string][]*server.Member)
 	ret["members"] = members
 	h.rd.JSON(w, http.StatusOK, ret)
 }
@@ -70,14 +68,18 @@ func (h *memberDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	// step 1. get etcd id
 	// TODO: GetPDMembers.
 	var id uint64
-	name := (mux.Vars(r))["name"]
+	vars := mux.Vars(r)
+	name, idStr := vars["name"], vars["id"]
+	if idStr != "" {
+		name = idStr
+	}
 	listResp, err := etcdutil.ListEtcdMembers(client)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	for _, m := range listResp.Members {
-		if name == m.Name {
+		if (idStr == "" && name == m.Name) || (idStr != "" && idStr == strconv.FormatUint(m.ID, 10)) {
 			id = m.ID
 			break
 		}
@@ -90,12 +92,63 @@ func (h *memberDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	// step 2. remove member by id
 	_, err = etcdutil.RemoveEtcdMember(client, id)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	// step 3. delete the info of the member
+	if err = h.svr.DeleteMemberInfo(id); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	h.rd.JSON(w, http.StatusOK, fmt.Sprintf("removed, pd: %s", name))
 }
 
+type memberLeaderPriorityHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newMemberLeaderPriorityHandler(svr *server.Server, rd *render.Render) *memberLeaderPriorityHandler {
+	return &memberLeaderPriorityHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *memberLeaderPriorityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		LeaderPriority *int `json:"leader-priority"`
+	}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.LeaderPriority == nil {
+		h.rd.JSON(w, http.StatusBadRequest, "missing leader-priority")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	members, err := server.GetMembers(h.svr.GetClient())
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	for _, m := range members {
+		if m.GetName() != name {
+			continue
+		}
+		if err = h.svr.SetMemberLeaderPriority(m.GetMemberId(), *input.LeaderPriority); err != nil {
+			h.rd.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusOK, nil)
+		return
+	}
+	h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+}
+
 type leaderHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -111,9 +164,27 @@ func newLeaderHandler(svr *server.Server, rd *render.Render) *leaderHandler {
 func (h *leaderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	leader, err := h.svr.GetLeader()
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	h.rd.JSON(w, http.StatusOK, leader)
 }
+
+// Resign resigns the leadership of the leader.
+func (h *leaderHandler) Resign(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ResignLeader(""); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Transfer transfers the leadership to the member with the name.
+func (h *leaderHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ResignLeader(mux.Vars(r)["next_leader"]); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -49,7 +49,7 @@ func relaxEqualStings(c *C, a, b []string) {
 }
 
 func checkListResponse(c *C, body []byte, cfgs []*server.Config) {
-	got := make(map[string][]*pdpb.Member)
+	got := make(map[string][]*server.Member)
 	json.Unmarshal(body, &got)
 
 	c.Assert(len(got["members"]), Equals, len(cfgs))
@@ -62,6 +62,10 @@ func checkListResponse(c *C, body []byte, cfgs []*server.Config) {
 
 			relaxEqualStings(c, memb.ClientUrls, strings.Split(cfg.ClientUrls, ","))
 			relaxEqualStings(c, memb.PeerUrls, strings.Split(cfg.PeerUrls, ","))
+			c.Assert(memb.BinaryVersion, Equals, server.PDReleaseVersion)
+			c.Assert(memb.GitHash, Equals, server.PDGitHash)
+			c.Assert(memb.DeployPath, Not(Equals), "")
+			c.Assert(memb.StartTimestamp, Not(Equals), int64(0))
 		}
 	}
 }
@@ -84,6 +88,13 @@ func (s *testMemberAPISuite) TestMemberList(c *C) {
 }
 
 func (s *testMemberAPISuite) TestMemberDelete(c *C) {
+	s.testMemberDelete(c, false)
+	s.testMemberDelete(c, true)
+}
+
+// testMemberDelete deletes a stopped member of a new cluster by the name or
+// the id.
+func (s *testMemberAPISuite) testMemberDelete(c *C, byID bool) {
 	cfgs, svrs, clean := mustNewCluster(c, 3)
 	defer clean()
 
@@ -103,6 +114,10 @@ func (s *testMemberAPISuite) TestMemberDelete(c *C) {
 		}
 	}
 	clientURL := cfgs[rand.Intn(len(cfgs))].ClientUrls
+	deleted := server.Name()
+	if byID {
+		deleted = fmt.Sprintf("id/%d", server.ID())
+	}
 
 	server.Close()
 	time.Sleep(5 * time.Second)
@@ -119,9 +134,15 @@ func (s *testMemberAPISuite) TestMemberDelete(c *C) {
 			checker: Equals,
 			status:  http.StatusNotFound,
 		},
+		{
+			// delete a nonexistent pd by id
+			name:    fmt.Sprintf("id/%d", rand.Int63()),
+			checker: Equals,
+			status:  http.StatusNotFound,
+		},
 		{
 			// delete a pd randomly
-			name:    server.Name(),
+			name:    deleted,
 			checker: Equals,
 			status:  http.StatusOK,
 		},
@@ -131,6 +152,12 @@ func (s *testMemberAPISuite) TestMemberDelete(c *C) {
 			checker: Equals,
 			status:  http.StatusNotFound,
 		},
+		{
+			// delete it again by name
+			name:    "name/" + server.Name(),
+			checker: Equals,
+			status:  http.StatusNotFound,
+		},
 	}
 
 	for _, t := range table {
@@ -183,3 +210,29 @@ func (s *testMemberAPISuite) TestMemberLeader(c *C) {
 	c.Assert(got.GetClientUrls(), DeepEquals, leader.GetClientUrls())
 	c.Assert(got.GetMemberId(), Equals, leader.GetMemberId())
 }
+
+func (s *testMemberAPISuite) TestLeaderTransfer(c *C) {
+	cfgs, svrs, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *server.Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	parts := []string{cfgs[rand.Intn(len(cfgs))].ClientUrls, apiPrefix, "/api/v1/leader/transfer/unknown"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	c.Assert(postJSON(s.hc, addr, nil), NotNil)
+
+	parts = []string{leader.GetConfig().ClientUrls, apiPrefix, "/api/v1/leader/transfer/", follower.Name()}
+	addr = mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	c.Assert(postJSON(s.hc, addr, nil), IsNil)
+	for i := 0; i < 100 && !follower.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(follower.IsLeader(), IsTrue)
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type namespaceHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newNamespaceHandler(svr *server.Server, rd *render.Render) *namespaceHandler {
+	return &namespaceHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *namespaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetNamespaces())
+}
+
+func (h *namespaceHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ns := &server.Namespace{}
+	if err := readJSON(r.Body, ns); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.svr.SetNamespace(ns); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *namespaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ns := h.svr.GetNamespace(mux.Vars(r)["name"])
+	if ns == nil {
+		h.rd.JSON(w, http.StatusNotFound, "namespace not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, ns)
+}
+
+func (h *namespaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.DeleteNamespace(mux.Vars(r)["name"]); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
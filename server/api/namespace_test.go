@@ -0,0 +1,70 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testNamespaceSuite{})
+
+type testNamespaceSuite struct {
+	hc *http.Client
+}
+
+func (s *testNamespaceSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testNamespaceSuite) TestNamespaces(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	urlPrefix := strings.Join([]string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/namespaces"}, "")
+	listAddr := mustUnixAddrToHTTPAddr(c, urlPrefix)
+	nsAddr := mustUnixAddrToHTTPAddr(c, urlPrefix+"/ns1")
+
+	ns := &server.Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1}}
+	postData, err := json.Marshal(ns)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.hc, listAddr, postData), IsNil)
+
+	// The store is already in ns1.
+	postData, err = json.Marshal(&server.Namespace{Name: "ns2", StoreIDs: []uint64{1}})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.hc, listAddr, postData), NotNil)
+
+	var namespaces []*server.Namespace
+	resp, err := s.hc.Get(listAddr)
+	c.Assert(err, IsNil)
+	c.Assert(readJSON(resp.Body, &namespaces), IsNil)
+	c.Assert(namespaces, DeepEquals, []*server.Namespace{ns})
+
+	req, err := http.NewRequest("DELETE", nsAddr, nil)
+	c.Assert(err, IsNil)
+	resp, err = s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = s.hc.Get(nsAddr)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
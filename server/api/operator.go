@@ -16,6 +16,7 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/server"
@@ -39,19 +40,50 @@ func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	regionID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	op, err := h.GetOperator(regionID)
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	h.r.JSON(w, http.StatusOK, op)
 }
 
+// GetRecords returns the operators finished or canceled since the start time,
+// which is a unix timestamp in seconds, 0 by default.
+func (h *operatorHandler) GetRecords(w http.ResponseWriter, r *http.Request) {
+	var start int64
+	if s := r.URL.Query().Get("start"); s != "" {
+		var err error
+		if start, err = strconv.ParseInt(s, 10, 64); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	records, err := h.GetOperatorRecords(time.Unix(start, 0))
+	if err != nil {
+		h.r.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, records)
+}
+
+func (h *operatorHandler) GetDryRuns(w http.ResponseWriter, r *http.Request) {
+	ops, err := h.GetDryRunOperators()
+	if err != nil {
+		h.r.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, ops)
+}
+
 func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 	var (
 		results []server.Operator
@@ -63,7 +95,7 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		results, err = h.GetOperators()
 		if err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	} else {
@@ -75,9 +107,11 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 				ops, err = h.GetLeaderOperators()
 			case "region":
 				ops, err = h.GetRegionOperators()
+			case "replica":
+				ops, err = h.GetReplicaOperators()
 			}
 			if err != nil {
-				h.r.JSON(w, http.StatusInternalServerError, err.Error())
+				h.r.JSON(w, errorStatus(err), err.Error())
 				return
 			}
 			results = append(results, ops...)
@@ -90,7 +124,7 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := readJSON(r.Body, &input); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -113,7 +147,7 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "transfer-region":
@@ -132,7 +166,7 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferRegionOperator(uint64(regionID), storeIDs); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "transfer-peer":
@@ -152,7 +186,37 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+	case "add-peer":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		storeID, ok := input["store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to add peer to")
+			return
+		}
+		if err := h.AddAddPeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+	case "remove-peer":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		storeID, ok := input["store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to remove peer from")
+			return
+		}
+		if err := h.AddRemovePeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	default:
@@ -168,12 +232,12 @@ func (h *operatorHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	regionID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	if err = h.RemoveOperator(regionID); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
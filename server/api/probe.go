@@ -0,0 +1,86 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+// probeHandler serves the liveness and readiness probes, e.g. of Kubernetes,
+// and the health of the server.
+type probeHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newProbeHandler(svr *server.Server, rd *render.Render) *probeHandler {
+	return &probeHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// Live responds OK if the server is running, a follower is live even if it
+// can't reach the leader.
+func (h *probeHandler) Live(w http.ResponseWriter, r *http.Request) {
+	if !h.svr.IsLive() {
+		h.rd.JSON(w, http.StatusServiceUnavailable, "server is closed")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Ready responds OK if the server is synced with the cluster and can serve
+// the requests by itself or by redirecting them to the leader.
+func (h *probeHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.CheckReady(); err != nil {
+		h.rd.JSON(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// health is the health of a member, the etcd status is included to explain
+// the stalls of PD.
+type health struct {
+	Name   string             `json:"name"`
+	Ready  bool               `json:"ready"`
+	Error  string             `json:"error,omitempty"`
+	Leader bool               `json:"leader"`
+	Etcd   *server.EtcdStatus `json:"etcd"`
+}
+
+// Health responds the health of the server, it is OK if the server is ready.
+func (h *probeHandler) Health(w http.ResponseWriter, r *http.Request) {
+	etcdStatus, err := h.svr.GetEtcdStatus()
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	result := &health{
+		Name:   h.svr.Name(),
+		Ready:  true,
+		Leader: h.svr.IsLeader(),
+		Etcd:   etcdStatus,
+	}
+	status := http.StatusOK
+	if err = h.svr.CheckReady(); err != nil {
+		result.Ready, result.Error = false, err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	h.rd.JSON(w, status, result)
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testProbeSuite{})
+
+type testProbeSuite struct {
+	hc *http.Client
+}
+
+func (s *testProbeSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testProbeSuite) checkProbe(c *C, svr *server.Server, probe string, status int) {
+	addr := mustUnixAddrToHTTPAddr(c, svr.GetAddr()+apiPrefix+probe)
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, status)
+}
+
+func (s *testProbeSuite) TestProbe(c *C) {
+	_, svrs, clean := mustNewCluster(c, 2)
+	defer clean()
+
+	leader := mustWaitLeader(c, svrs)
+	for _, svr := range svrs {
+		s.checkProbe(c, svr, "/live", http.StatusOK)
+		s.checkProbe(c, svr, "/ready", http.StatusOK)
+	}
+
+	// The leader is live but not ready after it loses the quorum.
+	for _, svr := range svrs {
+		if svr != leader {
+			svr.Close()
+		}
+	}
+	s.checkProbe(c, leader, "/live", http.StatusOK)
+	s.checkProbe(c, leader, "/ready", http.StatusServiceUnavailable)
+}
+
+func (s *testProbeSuite) TestHealth(c *C) {
+	_, svrs, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	leader := mustWaitLeader(c, svrs)
+	s.checkProbe(c, leader, "/health", http.StatusOK)
+
+	var h health
+	addr := mustUnixAddrToHTTPAddr(c, leader.GetAddr()+apiPrefix+"/health")
+	c.Assert(readJSONWithURL(addr, &h), IsNil)
+	c.Assert(h.Name, Equals, leader.Name())
+	c.Assert(h.Ready, IsTrue)
+	c.Assert(h.Leader, IsTrue)
+	c.Assert(h.Etcd.ID, Equals, leader.ID())
+	c.Assert(h.Etcd.Leader, Equals, leader.ID())
+	c.Assert(h.Etcd.Term, Greater, uint64(0))
+	c.Assert(h.Etcd.DBSize, Greater, int64(0))
+	c.Assert(h.Etcd.WALFsyncCount, Greater, uint64(0))
+}
@@ -14,6 +14,7 @@
 package api
 
 import (
+	"bytes"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -25,6 +26,9 @@ import (
 
 const (
 	redirectorHeader = "PD-Redirector"
+	// maxRedirectBodySize is the max size of the request body buffered to be
+	// proxied to the leader.
+	maxRedirectBodySize = 32 << 20
 )
 
 const (
@@ -32,6 +36,10 @@ const (
 	errRedirectToNotLeader = "redirect to not leader"
 )
 
+// redirector proxies the requests to a follower to the leader, so the clients
+// can send requests to any member. A request is redirected at most once, the
+// redirectorHeader marks the redirected requests, so a request is never
+// bounced between the members which don't agree on the leader.
 type redirector struct {
 	s *server.Server
 }
@@ -70,6 +78,9 @@ func (h *redirector) ServeHTTP(w http.ResponseWriter, r *http.Request, next http
 	newCustomReverseProxies(urls).ServeHTTP(w, r)
 }
 
+// customReverseProxies proxies a request to the first URL responding to it.
+// It needs no hop limit, the request carries the redirectorHeader, so the
+// member it's proxied to never proxies it again.
 type customReverseProxies struct {
 	urls    []url.URL
 	clients []*http.Client
@@ -97,10 +108,23 @@ func newCustomReverseProxies(urls []url.URL) *customReverseProxies {
 }
 
 func (p *customReverseProxies) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The body is read once, so it's buffered to be sent to every URL.
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRedirectBodySize))
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	for i, client := range p.clients {
 		r.RequestURI = ""
 		r.URL.Host = p.urls[i].Host
 		r.URL.Scheme = p.urls[i].Scheme
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
 
 		resp, err := client.Do(r)
 		if err != nil {
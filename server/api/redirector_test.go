@@ -14,8 +14,13 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -42,6 +47,54 @@ func (s *testRedirectorSuite) TestRedirect(c *C) {
 	}
 }
 
+func (s *testRedirectorSuite) TestRedirectPost(c *C) {
+	_, svrs, cleanup := mustNewCluster(c, 3)
+	defer cleanup()
+
+	var follower *server.Server
+	leader := mustWaitLeader(c, svrs)
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	// The body of the request to the follower is proxied to the leader.
+	unixAddr := []string{follower.GetAddr(), apiPrefix, "/api/v1/config"}
+	httpAddr := mustUnixAddrToHTTPAddr(c, strings.Join(unixAddr, ""))
+	postData, err := json.Marshal(map[string]interface{}{"region-schedule-limit": 17})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(newUnixSocketClient(), httpAddr, postData), IsNil)
+	c.Assert(leader.GetScheduleConfig().RegionScheduleLimit, Equals, uint64(17))
+}
+
+func (s *testRedirectorSuite) TestRedirectBodyLimit(c *C) {
+	var received int32
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer leader.Close()
+	u, err := url.Parse(leader.URL)
+	c.Assert(err, IsNil)
+	proxies := newCustomReverseProxies([]url.URL{*u})
+
+	// The body larger than the limit is not proxied.
+	req, err := http.NewRequest("POST", "/pd/api/v1/config", bytes.NewReader(make([]byte, maxRedirectBodySize+1)))
+	c.Assert(err, IsNil)
+	resp := httptest.NewRecorder()
+	proxies.ServeHTTP(resp, req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+	c.Assert(atomic.LoadInt32(&received), Equals, int32(0))
+
+	req, err = http.NewRequest("POST", "/pd/api/v1/config", bytes.NewReader(make([]byte, maxRedirectBodySize)))
+	c.Assert(err, IsNil)
+	resp = httptest.NewRecorder()
+	proxies.ServeHTTP(resp, req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	c.Assert(atomic.LoadInt32(&received), Equals, int32(1))
+}
+
 func (s *testRedirectorSuite) TestReconnect(c *C) {
 	_, svrs, cleanup := mustNewCluster(c, 3)
 	defer cleanup()
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"strconv"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server"
@@ -33,6 +34,12 @@ type regionsInfo struct {
 	Regions []*metapb.Region `json:"regions"`
 }
 
+// fullRegionsInfo is the regions with the leaders and the stats.
+type fullRegionsInfo struct {
+	Count   int                  `json:"count"`
+	Regions []*server.RegionInfo `json:"regions"`
+}
+
 type regionHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -48,7 +55,7 @@ func newRegionHandler(svr *server.Server, rd *render.Render) *regionHandler {
 func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
@@ -56,7 +63,7 @@ func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	regionIDStr := vars["id"]
 	regionID, err := strconv.ParseUint(regionIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -64,10 +71,26 @@ func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, regionInfo)
 }
 
+func (h *regionHandler) GetRegionFlowByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	regionID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	flow, err := h.svr.GetHandler().GetRegionFlow(regionID)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, flow)
+}
+
 func (h *regionHandler) GetRegionByKey(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 	vars := mux.Vars(r)
@@ -88,17 +111,97 @@ func newRegionsHandler(svr *server.Server, rd *render.Render) *regionsHandler {
 	}
 }
 
-func (h *regionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+const defaultRegionLimit = 16
+
+func (h *regionsHandler) ScanRegionsByKey(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
-	regions := cluster.GetRegions()
+	startKey := r.URL.Query().Get("key")
+	limit := defaultRegionLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	regions := cluster.ScanRegionsByKey([]byte(startKey), limit)
 	regionsInfo := &regionsInfo{
 		Count:   len(regions),
 		Regions: regions,
 	}
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
+
+// GetSiblingRegions returns the adjacent regions of the region, the previous
+// one is the first if there are both.
+func (h *regionsHandler) GetSiblingRegions(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := cluster.GetRegionInfoByID(regionID)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, "region not found")
+		return
+	}
+
+	siblings := &fullRegionsInfo{Regions: make([]*server.RegionInfo, 0, 2)}
+	prev, next := cluster.GetAdjacentRegions(region)
+	for _, sibling := range []*server.RegionInfo{prev, next} {
+		if sibling != nil {
+			siblings.Regions = append(siblings.Regions, sibling)
+		}
+	}
+	siblings.Count = len(siblings.Regions)
+	h.rd.JSON(w, http.StatusOK, siblings)
+}
+
+// GetAbnormalRegions returns the regions of the kind in the path, which is one
+// of miss-peer, extra-peer, down-peer, pending-peer and empty-region.
+func (h *regionsHandler) GetAbnormalRegions(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	regions, err := cluster.GetAbnormalRegions(mux.Vars(r)["kind"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &fullRegionsInfo{
+		Count:   len(regions),
+		Regions: regions,
+	})
+}
+
+func (h *regionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	regions := cluster.GetRegions()
+	err := writeJSONList(w, "regions", len(regions), func(i int) interface{} {
+		return regions[i]
+	})
+	if err != nil {
+		log.Errorf("write regions meet error: %v", err)
+	}
+}
@@ -15,6 +15,7 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -88,3 +89,109 @@ func (s *testRegionSuite) TestRegion(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(r2, DeepEquals, r)
 }
+
+func (s *testRegionSuite) TestScanRegions(c *C) {
+	// The regions are at the end, so the scan doesn't meet the regions of
+	// the other tests.
+	r3 := newTestRegionInfo(3, 1, []byte("w"), []byte("x"))
+	r4 := newTestRegionInfo(4, 1, []byte("x"), []byte("y"))
+	r5 := newTestRegionInfo(5, 1, []byte("y"), []byte("z"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r3)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r4)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r5)
+
+	url := fmt.Sprintf("%s/regions/key?key=%s&limit=%d", s.urlPrefix, "ww", 2)
+	regions := &regionsInfo{}
+	err := readJSONWithURL(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(regions.Count, Equals, 2)
+	c.Assert(regions.Regions[0], DeepEquals, r3.Region)
+	c.Assert(regions.Regions[1], DeepEquals, r4.Region)
+
+	url = fmt.Sprintf("%s/regions/key?key=%s", s.urlPrefix, "x")
+	err = readJSONWithURL(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(regions.Count, Equals, 2)
+	c.Assert(regions.Regions[0], DeepEquals, r4.Region)
+	c.Assert(regions.Regions[1], DeepEquals, r5.Region)
+}
+
+func (s *testRegionSuite) TestRegionFlow(c *C) {
+	r := newTestRegionInfo(6, 1, []byte("b"), []byte("c"))
+	r.WrittenBytes, r.ReadBytes, r.WrittenKeys, r.ReadKeys = 6000, 3000, 600, 300
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+
+	url := fmt.Sprintf("%s/region/id/%d/flow", s.urlPrefix, r.GetId())
+	flow := &server.RegionFlow{}
+	err := readJSONWithURL(url, flow)
+	c.Assert(err, IsNil)
+	// The first heartbeat is considered as reported in 60s.
+	c.Assert(flow.BytesWritten, Equals, float64(100))
+	c.Assert(flow.BytesRead, Equals, float64(50))
+	c.Assert(flow.KeysWritten, Equals, float64(10))
+	c.Assert(flow.KeysRead, Equals, float64(5))
+}
+
+func (s *testRegionSuite) TestSiblingRegions(c *C) {
+	r10 := newTestRegionInfo(10, 1, []byte("m"), []byte("n"))
+	r11 := newTestRegionInfo(11, 1, []byte("n"), []byte("o"))
+	r12 := newTestRegionInfo(12, 1, []byte("o"), []byte("p"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r10)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r11)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r12)
+
+	siblings := &fullRegionsInfo{}
+	err := readJSONWithURL(fmt.Sprintf("%s/regions/sibling/%d", s.urlPrefix, r11.GetId()), siblings)
+	c.Assert(err, IsNil)
+	c.Assert(siblings.Count, Equals, 2)
+	c.Assert(siblings.Regions[0], DeepEquals, r10)
+	c.Assert(siblings.Regions[1], DeepEquals, r12)
+
+	resp, err := unixClient.Get(fmt.Sprintf("%s/regions/sibling/%d", s.urlPrefix, 100))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testRegionSuite) TestAbnormalRegions(c *C) {
+	r20 := newTestRegionInfo(20, 1, []byte("f"), []byte("g"))
+	for i := uint64(2); i <= 4; i++ {
+		r20.Peers = append(r20.Peers, &metapb.Peer{Id: 200 + i, StoreId: i})
+	}
+	r21 := newTestRegionInfo(21, 1, []byte("g"), []byte("h"))
+	r21.Peers = append(r21.Peers, &metapb.Peer{Id: 212, StoreId: 2})
+	r21.DownPeers = []*pdpb.PeerStats{{Peer: r21.Peers[1], DownSeconds: 3600}}
+	r22 := newTestRegionInfo(22, 1, []byte("h"), []byte("i"))
+	r22.Peers = append(r22.Peers, &metapb.Peer{Id: 222, StoreId: 2})
+	r22.PendingPeers = []*metapb.Peer{r22.Peers[1]}
+	r23 := newTestRegionInfo(23, 1, []byte("i"), []byte("j"))
+	r24 := newTestRegionInfo(24, 1, []byte("j"), []byte("k"))
+	for _, r := range []*server.RegionInfo{r20, r21, r22, r23, r24} {
+		mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+	}
+
+	checkRegions := func(kind string) []uint64 {
+		regions := &fullRegionsInfo{}
+		err := readJSONWithURL(fmt.Sprintf("%s/regions/check/%s", s.urlPrefix, kind), regions)
+		c.Assert(err, IsNil)
+		c.Assert(regions.Count, Equals, len(regions.Regions))
+		var ids []uint64
+		for _, r := range regions.Regions {
+			if r.GetId() >= 20 && r.GetId() <= 24 {
+				ids = append(ids, r.GetId())
+			}
+		}
+		return ids
+	}
+	c.Assert(checkRegions("miss-peer"), DeepEquals, []uint64{21, 22, 23, 24})
+	c.Assert(checkRegions("extra-peer"), DeepEquals, []uint64{20})
+	c.Assert(checkRegions("down-peer"), DeepEquals, []uint64{21})
+	c.Assert(checkRegions("pending-peer"), DeepEquals, []uint64{22})
+	// The heartbeat doesn't report the region sizes, so none is known empty.
+	c.Assert(checkRegions("empty-region"), HasLen, 0)
+
+	resp, err := unixClient.Get(fmt.Sprintf("%s/regions/check/%s", s.urlPrefix, "whatever"))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
@@ -31,11 +31,14 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	historyHanlder := newHistoryHandler(handler, rd)
 	router.HandleFunc("/api/v1/history", historyHanlder.GetOperators).Methods("GET")
+	router.HandleFunc("/api/v1/history/split", historyHanlder.GetSplits).Methods("GET")
 	router.HandleFunc("/api/v1/history/{kind}/{limit}", historyHanlder.GetOperatorsOfKind).Methods("GET")
 
 	operatorHandler := newOperatorHandler(handler, rd)
 	router.HandleFunc("/api/v1/operators", operatorHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/operators", operatorHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/operators/history", operatorHandler.GetRecords).Methods("GET")
+	router.HandleFunc("/api/v1/operators/dry-run", operatorHandler.GetDryRuns).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
 
@@ -43,10 +46,16 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.Post).Methods("POST")
 	router.HandleFunc("/api/v1/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/schedulers/{name}/pause", schedulerHandler.Pause).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}/resume", schedulerHandler.Resume).Methods("POST")
 
 	router.Handle("/api/v1/cluster", newClusterHandler(svr, rd)).Methods("GET")
 	router.HandleFunc("/api/v1/cluster/status", newClusterHandler(svr, rd).GetClusterStatus).Methods("GET")
 
+	backupHandler := newBackupHandler(svr, rd)
+	router.HandleFunc("/api/v1/backup", backupHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/backup/restore", backupHandler.Restore).Methods("POST")
+
 	confHandler := newConfHandler(svr, rd)
 	router.HandleFunc("/api/v1/config", confHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/config", confHandler.Post).Methods("POST")
@@ -55,9 +64,26 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/config/replicate", confHandler.SetReplication).Methods("POST")
 	router.HandleFunc("/api/v1/config/replicate", confHandler.GetReplication).Methods("GET")
 
+	ruleHandler := newRuleHandler(svr, rd)
+	router.HandleFunc("/api/v1/config/rules", ruleHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/config/rules", ruleHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/config/rule/{id}", ruleHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/config/rule/{id}", ruleHandler.Delete).Methods("DELETE")
+
+	namespaceHandler := newNamespaceHandler(svr, rd)
+	router.HandleFunc("/api/v1/namespaces", namespaceHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/namespaces", namespaceHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{name}", namespaceHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/namespaces/{name}", namespaceHandler.Delete).Methods("DELETE")
+
 	storeHandler := newStoreHandler(svr, rd)
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}/label", storeHandler.SetLabels).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/limit", storeHandler.SetLimit).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/destroyed", storeHandler.SetDestroyed).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/region-size-histogram", storeHandler.GetRegionSizeHistogram).Methods("GET")
 	router.Handle("/api/v1/stores", newStoresHandler(svr, rd)).Methods("GET")
 
 	labelsHandler := newLabelsHandler(svr, rd)
@@ -66,20 +92,39 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	hotStatusHandler := newHotStatusHandler(handler, rd)
 	router.HandleFunc("/api/v1/hotspot/regions", hotStatusHandler.GetHotRegions).Methods("GET")
+	router.HandleFunc("/api/v1/hotspot/regions/read", hotStatusHandler.GetHotReadRegions).Methods("GET")
 	router.HandleFunc("/api/v1/hotspot/stores", hotStatusHandler.GetHotStores).Methods("GET")
+	router.HandleFunc("/api/v1/hotspot/heatmap", hotStatusHandler.GetHeatmap).Methods("GET")
 	router.Handle("/api/v1/events", newEventsHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/cluster_events", newClusterEventsHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/feed", newFeedHandler(svr, rd)).Methods("GET")
 
+	statsHandler := newStatsHandler(handler, rd)
+	router.HandleFunc("/api/v1/stats", statsHandler.Test).Methods("GET")
+	router.HandleFunc("/api/v1/stats/search", statsHandler.Search).Methods("POST")
+	router.HandleFunc("/api/v1/stats/query", statsHandler.Query).Methods("POST")
+
 	regionHandler := newRegionHandler(svr, rd)
 	router.HandleFunc("/api/v1/region/id/{id}", regionHandler.GetRegionByID).Methods("GET")
+	router.HandleFunc("/api/v1/region/id/{id}/flow", regionHandler.GetRegionFlowByID).Methods("GET")
 	router.HandleFunc("/api/v1/region/key/{key}", regionHandler.GetRegionByKey).Methods("GET")
 
-	router.Handle("/api/v1/regions", newRegionsHandler(svr, rd)).Methods("GET")
+	regionsHandler := newRegionsHandler(svr, rd)
+	router.HandleFunc("/api/v1/regions/key", regionsHandler.ScanRegionsByKey).Methods("GET")
+	router.HandleFunc("/api/v1/regions/sibling/{id}", regionsHandler.GetSiblingRegions).Methods("GET")
+	router.HandleFunc("/api/v1/regions/check/{kind}", regionsHandler.GetAbnormalRegions).Methods("GET")
+	router.Handle("/api/v1/regions", regionsHandler).Methods("GET")
 	router.Handle("/api/v1/version", newVersionHandler(rd)).Methods("GET")
 
 	router.Handle("/api/v1/members", newMemberListHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/members/{name}", newMemberDeleteHandler(svr, rd)).Methods("DELETE")
-	router.Handle("/api/v1/leader", newLeaderHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/members/name/{name}", newMemberDeleteHandler(svr, rd)).Methods("DELETE")
+	router.Handle("/api/v1/members/id/{id}", newMemberDeleteHandler(svr, rd)).Methods("DELETE")
+	router.Handle("/api/v1/members/{name}", newMemberLeaderPriorityHandler(svr, rd)).Methods("POST")
+	leaderHandler := newLeaderHandler(svr, rd)
+	router.Handle("/api/v1/leader", leaderHandler).Methods("GET")
+	router.HandleFunc("/api/v1/leader/resign", leaderHandler.Resign).Methods("POST")
+	router.HandleFunc("/api/v1/leader/transfer/{next_leader}", leaderHandler.Transfer).Methods("POST")
 
 	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
 	return router
@@ -0,0 +1,68 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type ruleHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRuleHandler(svr *server.Server, rd *render.Render) *ruleHandler {
+	return &ruleHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *ruleHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetPlacementRules())
+}
+
+func (h *ruleHandler) Post(w http.ResponseWriter, r *http.Request) {
+	rule := &server.Rule{}
+	if err := readJSON(r.Body, rule); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.svr.SetPlacementRule(rule); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *ruleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rule := h.svr.GetPlacementRule(mux.Vars(r)["id"])
+	if rule == nil {
+		h.rd.JSON(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rule)
+}
+
+func (h *ruleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.DeletePlacementRule(mux.Vars(r)["id"]); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testRuleSuite{})
+
+type testRuleSuite struct {
+	hc *http.Client
+}
+
+func (s *testRuleSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testRuleSuite) TestRules(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	urlPrefix := strings.Join([]string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/config"}, "")
+	rulesAddr := mustUnixAddrToHTTPAddr(c, urlPrefix+"/rules")
+	ruleAddr := mustUnixAddrToHTTPAddr(c, urlPrefix+"/rule/r1")
+
+	rule := &server.Rule{
+		ID:       "r1",
+		StartKey: "7480",
+		EndKey:   "7490",
+		Count:    2,
+		LabelConstraints: []server.LabelConstraint{
+			{Key: "zone", Op: server.LabelConstraintIn, Values: []string{"z1"}},
+		},
+	}
+	postData, err := json.Marshal(rule)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.hc, rulesAddr, postData), IsNil)
+
+	// Invalid rules are rejected.
+	for _, bad := range []*server.Rule{
+		{ID: "r2", Count: 0},
+		{ID: "r2", Count: 1, StartKey: "xx"},
+		{ID: "r2", Count: 1, StartKey: "90", EndKey: "80"},
+		{ID: "r2", Count: 1, Role: "learner"},
+		{ID: "r2", Count: 1, Role: "observer"},
+		{ID: "r2", Count: 1, LabelConstraints: []server.LabelConstraint{{Key: "zone", Op: "eq"}}},
+	} {
+		postData, err = json.Marshal(bad)
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.hc, rulesAddr, postData), NotNil)
+	}
+
+	var rules []*server.Rule
+	resp, err := s.hc.Get(rulesAddr)
+	c.Assert(err, IsNil)
+	c.Assert(readJSON(resp.Body, &rules), IsNil)
+	c.Assert(rules, HasLen, 1)
+	c.Assert(rules[0].ID, Equals, "r1")
+	c.Assert(rules[0].Role, Equals, server.RuleRoleVoter)
+
+	got := &server.Rule{}
+	resp, err = s.hc.Get(ruleAddr)
+	c.Assert(err, IsNil)
+	c.Assert(readJSON(resp.Body, got), IsNil)
+	c.Assert(got.Count, Equals, 2)
+	c.Assert(got.LabelConstraints, DeepEquals, rule.LabelConstraints)
+
+	req, err := http.NewRequest("DELETE", ruleAddr, nil)
+	c.Assert(err, IsNil)
+	resp, err = s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = s.hc.Get(ruleAddr)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
@@ -33,10 +33,20 @@ func newSchedulerHandler(handler *server.Handler, r *render.Render) *schedulerHa
 	}
 }
 
+// List returns the names of the schedulers, only the paused ones if the status
+// is paused.
 func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
-	schedulers, err := h.GetSchedulers()
+	var (
+		schedulers []string
+		err        error
+	)
+	if r.URL.Query().Get("status") == "paused" {
+		schedulers, err = h.GetPausedSchedulers()
+	} else {
+		schedulers, err = h.GetSchedulers()
+	}
 	if err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 	h.r.JSON(w, http.StatusOK, schedulers)
@@ -45,7 +55,7 @@ func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := readJSON(r.Body, &input); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -58,7 +68,7 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	switch name {
 	case "balance-leader-scheduler":
 		if err := h.AddBalanceLeaderScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "grant-leader-scheduler":
@@ -68,7 +78,7 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddGrantLeaderScheduler(uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "evict-leader-scheduler":
@@ -78,22 +88,54 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddEvictLeaderScheduler(uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+	case "scatter-range-scheduler":
+		rangeName, ok := input["range_name"].(string)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing range name")
+			return
+		}
+		startKey, _ := input["start_key"].(string)
+		endKey, _ := input["end_key"].(string)
+		if err := h.AddScatterRangeScheduler(rangeName, startKey, endKey); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "shuffle-leader-scheduler":
-		if err := h.AddShuffleLeaderScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		limit, _ := input["limit"].(float64)
+		if err := h.AddShuffleLeaderScheduler(uint64(limit)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "shuffle-region-scheduler":
-		if err := h.AddShuffleRegionScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		limit, _ := input["limit"].(float64)
+		if err := h.AddShuffleRegionScheduler(uint64(limit)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	default:
-		h.r.JSON(w, http.StatusBadRequest, "unknown scheduler")
-		return
+		if !server.IsSchedulerRegistered(name) {
+			h.r.JSON(w, http.StatusBadRequest, "unknown scheduler")
+			return
+		}
+		// Other registered schedulers take string arguments.
+		var args []string
+		if items, ok := input["args"].([]interface{}); ok {
+			for _, item := range items {
+				arg, ok := item.(string)
+				if !ok {
+					h.r.JSON(w, http.StatusBadRequest, "invalid scheduler arguments")
+					return
+				}
+				args = append(args, arg)
+			}
+		}
+		if err := h.AddSchedulerByType(name, args...); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
 	}
 
 	h.r.JSON(w, http.StatusOK, nil)
@@ -103,7 +145,25 @@ func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	name := mux.Vars(r)["name"]
 
 	if err := h.RemoveScheduler(name); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *schedulerHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.PauseScheduler(mux.Vars(r)["name"]); err != nil {
+		h.r.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *schedulerHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.ResumeScheduler(mux.Vars(r)["name"]); err != nil {
+		h.r.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
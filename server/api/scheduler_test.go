@@ -0,0 +1,120 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+const testSchedulerType = "test-scheduler"
+
+// testScheduler is a scheduler registered outside package server, it only
+// checks its store in Prepare.
+type testScheduler struct {
+	storeID uint64
+}
+
+func (s *testScheduler) GetName() string {
+	return fmt.Sprintf("%s-%d", testSchedulerType, s.storeID)
+}
+
+func (s *testScheduler) GetResourceKind() server.ResourceKind {
+	return server.LeaderKind
+}
+
+func (s *testScheduler) GetResourceLimit() uint64 {
+	return 1
+}
+
+func (s *testScheduler) Prepare(cluster server.Cluster) error {
+	if cluster.GetStore(s.storeID) == nil {
+		return errors.Errorf("store %d not found", s.storeID)
+	}
+	return nil
+}
+
+func (s *testScheduler) Cleanup(cluster server.Cluster) {}
+
+func (s *testScheduler) Schedule(cluster server.Cluster, influence server.OpInfluence) server.Operator {
+	return nil
+}
+
+var _ = Suite(&testSchedulerSuite{})
+
+type testSchedulerSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testSchedulerSuite) SetUpSuite(c *C) {
+	server.RegisterScheduler(testSchedulerType, func(opt server.ScheduleOptions, args []string) (server.Scheduler, error) {
+		if len(args) != 1 {
+			return nil, errors.New("should specify the store id")
+		}
+		storeID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return &testScheduler{storeID: storeID}, nil
+	})
+
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	httpAddr := mustUnixAddrToHTTPAddr(c, addr)
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", httpAddr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testSchedulerSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testSchedulerSuite) TestRegisteredScheduler(c *C) {
+	c.Assert(server.IsSchedulerRegistered(testSchedulerType), IsTrue)
+
+	postScheduler := func(args ...string) error {
+		data, err := json.Marshal(map[string]interface{}{"name": testSchedulerType, "args": args})
+		c.Assert(err, IsNil)
+		return postJSON(unixClient, s.urlPrefix+"/schedulers", data)
+	}
+	c.Assert(postScheduler(), NotNil)
+	c.Assert(postScheduler("100"), NotNil)
+	c.Assert(postScheduler("1"), IsNil)
+
+	var schedulers []string
+	c.Assert(readJSONWithURL(s.urlPrefix+"/schedulers", &schedulers), IsNil)
+	found := false
+	for _, name := range schedulers {
+		found = found || name == "test-scheduler-1"
+	}
+	c.Assert(found, IsTrue)
+
+	req, err := http.NewRequest("DELETE", s.urlPrefix+"/schedulers/test-scheduler-1", nil)
+	c.Assert(err, IsNil)
+	resp, err := unixClient.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+}
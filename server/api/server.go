@@ -18,6 +18,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
 	"github.com/urfave/negroni"
 )
 
@@ -29,8 +30,17 @@ func NewHandler(svr *server.Server) http.Handler {
 
 	recovery := negroni.NewRecovery()
 	engine.Use(recovery)
+	engine.Use(newSlowLogger(svr))
 
 	router := mux.NewRouter()
+	// The probes and the log level are of the server itself, so they are not
+	// redirected.
+	probeHandler := newProbeHandler(svr, render.New(render.Options{IndentJSON: true}))
+	router.HandleFunc(apiPrefix+"/live", probeHandler.Live).Methods("GET")
+	router.HandleFunc(apiPrefix+"/ready", probeHandler.Ready).Methods("GET")
+	router.HandleFunc(apiPrefix+"/health", probeHandler.Health).Methods("GET")
+	logHandler := newLogHandler(svr, render.New(render.Options{IndentJSON: true}))
+	router.HandleFunc(apiPrefix+"/api/v1/admin/log", logHandler.Handle).Methods("POST")
 	router.PathPrefix(apiPrefix).Handler(negroni.New(
 		newRedirector(svr),
 		negroni.Wrap(createRouter(apiPrefix, svr)),
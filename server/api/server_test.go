@@ -191,9 +191,15 @@ func mustPutStore(c *C, s *server.Server, store *metapb.Store) {
 
 func mustRegionHeartBeat(c *C, client pdpb.PD_RegionHeartbeatClient, clusterID uint64, region *server.RegionInfo) {
 	req := &pdpb.RegionHeartbeatRequest{
-		Header: newRequestHeader(clusterID),
-		Region: region.Region,
-		Leader: region.Leader,
+		Header:       newRequestHeader(clusterID),
+		Region:       region.Region,
+		Leader:       region.Leader,
+		BytesWritten: region.WrittenBytes,
+		BytesRead:    region.ReadBytes,
+		KeysWritten:  region.WrittenKeys,
+		KeysRead:     region.ReadKeys,
+		DownPeers:    region.DownPeers,
+		PendingPeers: region.PendingPeers,
 	}
 
 	err := client.Send(req)
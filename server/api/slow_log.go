@@ -0,0 +1,36 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pingcap/pd/server"
+)
+
+// slowLogger logs the API requests taking longer than the slow log threshold.
+type slowLogger struct {
+	s *server.Server
+}
+
+func newSlowLogger(s *server.Server) *slowLogger {
+	return &slowLogger{s: s}
+}
+
+func (h *slowLogger) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	next(w, r)
+	h.s.LogSlowRequest("http", r.Method+" "+r.URL.Path, r.RemoteAddr, r.URL.RawQuery, time.Since(start))
+}
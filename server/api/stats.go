@@ -0,0 +1,102 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+// The stats handler serves the API of the Grafana SimpleJSON datasource, the
+// datasource URL is http://{pd}/pd/api/v1/stats.
+type statsHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newStatsHandler(handler *server.Handler, rd *render.Render) *statsHandler {
+	return &statsHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+type statsSearchRequest struct {
+	Target string `json:"target"`
+}
+
+type statsQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// Test is used by the datasource to test the connection.
+func (h *statsHandler) Test(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Search returns the names of the series which contain the requested target.
+func (h *statsHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var req statsSearchRequest
+	if r.ContentLength != 0 {
+		if err := readJSON(r.Body, &req); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	targets, err := h.GetStatsTargets()
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	matched := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if strings.Contains(target, req.Target) {
+			matched = append(matched, target)
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, matched)
+}
+
+// Query returns the series of the requested targets in the time range.
+func (h *statsHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req statsQueryRequest
+	if err := readJSON(r.Body, &req); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	to := req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	targets := make([]string, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		targets = append(targets, t.Target)
+	}
+	series, err := h.GetStatsSeries(targets, req.Range.From, to)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, series)
+}
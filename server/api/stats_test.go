@@ -0,0 +1,84 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testStatsSuite{})
+
+type testStatsSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStatsSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	httpAddr := mustUnixAddrToHTTPAddr(c, addr)
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", httpAddr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testStatsSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStatsSuite) postJSON(c *C, url string, data interface{}, result interface{}) int {
+	body, err := json.Marshal(data)
+	c.Assert(err, IsNil)
+	resp, err := unixClient.Post(url, "application/json", bytes.NewBuffer(body))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && result != nil {
+		c.Assert(json.NewDecoder(resp.Body).Decode(result), IsNil)
+	}
+	return resp.StatusCode
+}
+
+func (s *testStatsSuite) TestStats(c *C) {
+	resp, err := unixClient.Get(s.urlPrefix + "/stats")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	// The stats are collected every minute, there are no samples yet.
+	var targets []string
+	c.Assert(s.postJSON(c, s.urlPrefix+"/stats/search", map[string]string{"target": "store"}, &targets), Equals, http.StatusOK)
+	c.Assert(targets, HasLen, 0)
+
+	query := map[string]interface{}{
+		"range":   map[string]time.Time{"from": time.Now().Add(-time.Hour), "to": time.Now()},
+		"targets": []map[string]string{{"target": "region.count"}},
+	}
+	var series []*server.StatsSeries
+	c.Assert(s.postJSON(c, s.urlPrefix+"/stats/query", query, &series), Equals, http.StatusOK)
+	c.Assert(series, HasLen, 1)
+	c.Assert(series[0].Target, Equals, "region.count")
+	c.Assert(series[0].Datapoints, HasLen, 0)
+
+	c.Assert(s.postJSON(c, s.urlPrefix+"/stats/query", "abc", nil), Equals, http.StatusBadRequest)
+}
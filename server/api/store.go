@@ -16,9 +16,11 @@ package api
 import (
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -36,12 +38,22 @@ type storeStatus struct {
 	StoreID            uint64            `json:"store_id"`
 	Capacity           typeutil.ByteSize `json:"capacity"`
 	Available          typeutil.ByteSize `json:"available"`
+	UsedSize           typeutil.ByteSize `json:"used_size"`
 	LeaderCount        int               `json:"leader_count"`
+	LeaderSize         typeutil.ByteSize `json:"leader_size"`
 	RegionCount        int               `json:"region_count"`
+	RegionSize         typeutil.ByteSize `json:"region_size"`
+	PendingPeerCount   int               `json:"pending_peer_count"`
 	SendingSnapCount   uint32            `json:"sending_snap_count"`
 	ReceivingSnapCount uint32            `json:"receiving_snap_count"`
 	ApplyingSnapCount  uint32            `json:"applying_snap_count"`
 	IsBusy             bool              `json:"is_busy"`
+	BytesWritten       uint64            `json:"bytes_written"`
+	KeysWritten        uint64            `json:"keys_written"`
+	LeaderWeight       float64           `json:"leader_weight"`
+	RegionWeight       float64           `json:"region_weight"`
+	SnapshotLimit      uint64            `json:"snapshot_limit"`
+	Destroyed          bool              `json:"destroyed"`
 
 	StartTS         time.Time         `json:"start_ts"`
 	LastHeartbeatTS time.Time         `json:"last_heartbeat_ts"`
@@ -53,7 +65,13 @@ type storeInfo struct {
 	Status *storeStatus `json:"status"`
 }
 
-const downStateName = "Down"
+const (
+	downStateName = "Down"
+	// storeDownTime is the time an up store is shown as down after it stops
+	// sending heartbeats. It is independent of max-store-down-time, which is the
+	// time to repair the replicas of the store.
+	storeDownTime = time.Minute
+)
 
 func newStoreInfo(store *metapb.Store, status *server.StoreStatus) *storeInfo {
 	s := &storeInfo{
@@ -65,18 +83,28 @@ func newStoreInfo(store *metapb.Store, status *server.StoreStatus) *storeInfo {
 			StoreID:            status.StoreId,
 			Capacity:           typeutil.ByteSize(status.Capacity),
 			Available:          typeutil.ByteSize(status.Available),
+			UsedSize:           typeutil.ByteSize(status.UsedSize),
 			LeaderCount:        status.LeaderCount,
+			LeaderSize:         typeutil.ByteSize(status.LeaderSize),
 			RegionCount:        status.RegionCount,
+			RegionSize:         typeutil.ByteSize(status.RegionSize),
+			PendingPeerCount:   status.PendingPeerCount,
 			SendingSnapCount:   status.SendingSnapCount,
 			ReceivingSnapCount: status.ReceivingSnapCount,
 			ApplyingSnapCount:  status.ApplyingSnapCount,
 			IsBusy:             status.IsBusy,
+			BytesWritten:       status.BytesWritten,
+			KeysWritten:        status.KeysWritten,
+			LeaderWeight:       status.Settings.LeaderWeight,
+			RegionWeight:       status.Settings.RegionWeight,
+			SnapshotLimit:      status.Settings.SnapshotLimit,
+			Destroyed:          status.Settings.Destroyed,
 			StartTS:            status.GetStartTS(),
 			LastHeartbeatTS:    status.LastHeartbeatTS,
 			Uptime:             typeutil.NewDuration(status.GetUptime()),
 		},
 	}
-	if store.State == metapb.StoreState_Up && status.IsDown() {
+	if store.State == metapb.StoreState_Up && status.IsDown(storeDownTime) {
 		s.Store.StateName = downStateName
 	}
 	return s
@@ -102,7 +130,7 @@ func newStoreHandler(svr *server.Server, rd *render.Render) *storeHandler {
 func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
@@ -110,13 +138,13 @@ func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	storeIDStr := vars["id"]
 	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	store, status, err := cluster.GetStore(storeID)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -127,7 +155,7 @@ func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
@@ -135,7 +163,7 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	storeIDStr := vars["id"]
 	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -147,13 +175,157 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// SetLabels updates the labels of a store by a JSON map of the labels, a label
+// is removed if the value is empty.
+func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	var input map[string]string
+	if err = readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labels := make([]*metapb.StoreLabel, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, &metapb.StoreLabel{Key: k, Value: input[k]})
+	}
+
+	if err = cluster.UpdateStoreLabels(storeID, labels); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+type storeWeight struct {
+	Leader float64 `json:"leader"`
+	Region float64 `json:"region"`
+}
+
+// SetWeight sets the leader and region weights of a store.
+func (h *storeHandler) SetWeight(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	var input storeWeight
+	if err = readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	if err = cluster.SetStoreWeight(storeID, input.Leader, input.Region); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+type storeLimit struct {
+	SnapshotLimit uint64 `json:"snapshot_limit"`
+}
+
+// SetLimit sets the max snapshot count of a store, 0 resets it to
+// max-snapshot-count.
+func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	var input storeLimit
+	if err = readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	if err = cluster.SetStoreSnapshotLimit(storeID, input.SnapshotLimit); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// SetDestroyed confirms that a tombstone store is physically destroyed, then
+// it can be removed by the tombstone store GC.
+func (h *storeHandler) SetDestroyed(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	if err = cluster.ConfirmStoreDestroyed(storeID); err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// GetRegionSizeHistogram returns the counts of the regions on a store by their
+// approximate sizes.
+func (h *storeHandler) GetRegionSizeHistogram(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	histogram, err := cluster.GetStoreRegionSizeHistogram(storeID)
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, histogram)
+}
+
 type storesHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -169,35 +341,33 @@ func newStoresHandler(svr *server.Server, rd *render.Render) *storesHandler {
 func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		h.rd.JSON(w, errorStatus(server.ErrNotBootstrapped), server.ErrNotBootstrapped.Error())
 		return
 	}
 
-	stores := cluster.GetStores()
-	storesInfo := &storesInfo{
-		Stores: make([]*storeInfo, 0, len(stores)),
-	}
-
 	urlFilter, err := newStoreStateFilter(r.URL)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, errorStatus(err), err.Error())
 		return
 	}
 
-	stores = urlFilter.filter(cluster.GetStores())
+	stores := urlFilter.filter(cluster.GetStores())
+	infos := make([]*storeInfo, 0, len(stores))
 	for _, s := range stores {
 		store, status, err := cluster.GetStore(s.GetId())
 		if err != nil {
-			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			h.rd.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 
-		storeInfo := newStoreInfo(store, status)
-		storesInfo.Stores = append(storesInfo.Stores, storeInfo)
+		infos = append(infos, newStoreInfo(store, status))
+	}
+	err = writeJSONList(w, "stores", len(infos), func(i int) interface{} {
+		return infos[i]
+	})
+	if err != nil {
+		log.Errorf("write stores meet error: %v", err)
 	}
-	storesInfo.Count = len(storesInfo.Stores)
-
-	h.rd.JSON(w, http.StatusOK, storesInfo)
 }
 
 type storeStateFilter struct {
@@ -23,6 +23,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server"
 )
 
@@ -119,6 +120,19 @@ func (s *testStoreSuite) TestStoreGet(c *C) {
 	checkStoresInfo(c, []*storeInfo{info}, s.stores[:1])
 }
 
+func (s *testStoreSuite) TestRegionSizeHistogram(c *C) {
+	url := fmt.Sprintf("%s/store/4/region-size-histogram", s.urlPrefix)
+	histogram := &server.RegionSizeHistogram{}
+	err := readJSONWithURL(url, histogram)
+	c.Assert(err, IsNil)
+	c.Assert(histogram.StoreID, Equals, uint64(4))
+	c.Assert(histogram.Count, Equals, 0)
+	c.Assert(histogram.Buckets, HasLen, 12)
+
+	url = fmt.Sprintf("%s/store/100/region-size-histogram", s.urlPrefix)
+	c.Assert(readJSONWithURL(url, histogram), NotNil)
+}
+
 func (s *testStoreSuite) TestStoreDelete(c *C) {
 	table := []struct {
 		id     int
@@ -130,7 +144,11 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 		},
 		{
 			id:     7,
-			status: http.StatusInternalServerError,
+			status: http.StatusGone,
+		},
+		{
+			id:     100,
+			status: http.StatusNotFound,
 		},
 	}
 	client := newUnixSocketClient()
@@ -145,6 +163,32 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 	}
 }
 
+func (s *testStoreSuite) TestStoreSettings(c *C) {
+	url := fmt.Sprintf("%s/store/4", s.urlPrefix)
+	info := new(storeInfo)
+	c.Assert(readJSONWithURL(url, info), IsNil)
+	c.Assert(info.Status.LeaderWeight, Equals, 1.0)
+	c.Assert(info.Status.RegionWeight, Equals, 1.0)
+	c.Assert(info.Status.SnapshotLimit, Equals, uint64(0))
+
+	c.Assert(postJSON(unixClient, url+"/label", []byte(`{"zone":"z1","host":"h1"}`)), IsNil)
+	c.Assert(postJSON(unixClient, url+"/label", []byte(`{"host":""}`)), IsNil)
+	c.Assert(postJSON(unixClient, url+"/weight", []byte(`{"leader":2,"region":0.5}`)), IsNil)
+	c.Assert(postJSON(unixClient, url+"/weight", []byte(`{"leader":0,"region":1}`)), NotNil)
+	c.Assert(postJSON(unixClient, url+"/limit", []byte(`{"snapshot_limit":8}`)), IsNil)
+	c.Assert(postJSON(unixClient, fmt.Sprintf("%s/store/100/limit", s.urlPrefix), []byte(`{"snapshot_limit":8}`)), NotNil)
+
+	c.Assert(readJSONWithURL(url, info), IsNil)
+	c.Assert(info.Store.GetLabels(), DeepEquals, []*metapb.StoreLabel{{Key: "zone", Value: "z1"}})
+	c.Assert(info.Status.LeaderWeight, Equals, 2.0)
+	c.Assert(info.Status.RegionWeight, Equals, 0.5)
+	c.Assert(info.Status.SnapshotLimit, Equals, uint64(8))
+
+	c.Assert(postJSON(unixClient, url+"/label", []byte(`{"zone":""}`)), IsNil)
+	c.Assert(postJSON(unixClient, url+"/weight", []byte(`{"leader":1,"region":1}`)), IsNil)
+	c.Assert(postJSON(unixClient, url+"/limit", []byte(`{"snapshot_limit":0}`)), IsNil)
+}
+
 func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	table := []struct {
 		u    string
@@ -202,3 +246,40 @@ func (s *testStoreSuite) TestDownState(c *C) {
 	storeInfo = newStoreInfo(store, status)
 	c.Assert(storeInfo.Store.StateName, Equals, downStateName)
 }
+
+func (s *testStoreSuite) TestStoreStatus(c *C) {
+	now := time.Now()
+	status := &server.StoreStatus{
+		StoreStats: &pdpb.StoreStats{
+			StoreId:            1,
+			Capacity:           100,
+			Available:          50,
+			UsedSize:           40,
+			SendingSnapCount:   1,
+			ReceivingSnapCount: 2,
+			ApplyingSnapCount:  3,
+			StartTime:          uint32(now.Add(-time.Hour).Unix()),
+			BytesWritten:       1024,
+			KeysWritten:        8,
+		},
+		LeaderCount:      4,
+		RegionCount:      5,
+		PendingPeerCount: 6,
+		LastHeartbeatTS:  now,
+	}
+	info := newStoreInfo(s.stores[0], status).Status
+	c.Assert(info.StoreID, Equals, uint64(1))
+	c.Assert(info.Capacity, Equals, typeutil.ByteSize(100))
+	c.Assert(info.Available, Equals, typeutil.ByteSize(50))
+	c.Assert(info.UsedSize, Equals, typeutil.ByteSize(40))
+	c.Assert(info.SendingSnapCount, Equals, uint32(1))
+	c.Assert(info.ReceivingSnapCount, Equals, uint32(2))
+	c.Assert(info.ApplyingSnapCount, Equals, uint32(3))
+	c.Assert(info.BytesWritten, Equals, uint64(1024))
+	c.Assert(info.KeysWritten, Equals, uint64(8))
+	c.Assert(info.LeaderCount, Equals, 4)
+	c.Assert(info.RegionCount, Equals, 5)
+	c.Assert(info.PendingPeerCount, Equals, 6)
+	c.Assert(info.StartTS.Unix(), Equals, now.Add(-time.Hour).Unix())
+	c.Assert(info.Uptime.Duration >= time.Hour, IsTrue)
+}
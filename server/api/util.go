@@ -14,16 +14,71 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server"
 )
 
+// errorStatusCodes are the HTTP status codes of the typed errors, the other
+// errors are internal server errors.
+var errorStatusCodes = map[pdpb.ErrorType]int{
+	pdpb.ErrorType_NOT_BOOTSTRAPPED:     http.StatusServiceUnavailable,
+	pdpb.ErrorType_ALREADY_BOOTSTRAPPED: http.StatusConflict,
+	pdpb.ErrorType_STORE_TOMBSTONE:      http.StatusGone,
+}
+
+// errorStatus returns the HTTP status code of the error by its type.
+func errorStatus(err error) int {
+	if server.IsNotFound(err) {
+		return http.StatusNotFound
+	}
+	if code, ok := errorStatusCodes[server.ErrorType(err)]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// jsonListBufferSize is the buffer size to write a JSON list.
+const jsonListBufferSize = 64 * 1024
+
+// writeJSONList writes {"count": n, "<key>": [...]} with status OK in the
+// indented format of the renderer, item returns the i-th of the n items. The
+// items are encoded one by one directly to w, so a huge list is never encoded
+// into one byte slice in memory. The status is written already if it returns
+// an error.
+func writeJSONList(w http.ResponseWriter, key string, n int, item func(i int) interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriterSize(w, jsonListBufferSize)
+	fmt.Fprintf(bw, "{\n  \"count\": %d,\n  %q: [", n, key)
+	for i := 0; i < n; i++ {
+		data, err := json.MarshalIndent(item(i), "    ", "  ")
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		bw.WriteString("\n    ")
+		bw.Write(data)
+	}
+	if n > 0 {
+		bw.WriteString("\n  ")
+	}
+	bw.WriteString("]\n}")
+	return errors.Trace(bw.Flush())
+}
+
 func readJSON(r io.ReadCloser, data interface{}) error {
 	defer r.Close()
 
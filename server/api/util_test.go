@@ -0,0 +1,54 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testUtilSuite{})
+
+type testUtilSuite struct{}
+
+func (s *testUtilSuite) TestErrorStatus(c *C) {
+	c.Assert(errorStatus(server.ErrNotBootstrapped), Equals, http.StatusServiceUnavailable)
+	c.Assert(errorStatus(errors.Annotate(server.ErrNotBootstrapped, "get stores")), Equals, http.StatusServiceUnavailable)
+	c.Assert(errorStatus(errors.New("unknown")), Equals, http.StatusInternalServerError)
+	c.Assert(errorStatus(errors.Trace(&server.NotFoundError{Message: "store 1 not found"})), Equals, http.StatusNotFound)
+}
+
+func (s *testUtilSuite) TestWriteJSONList(c *C) {
+	for _, n := range []int{0, 1, 3} {
+		regions := make([]*metapb.Region, 0, n)
+		for i := 0; i < n; i++ {
+			regions = append(regions, &metapb.Region{Id: uint64(i), StartKey: []byte{byte(i)}})
+		}
+		w := httptest.NewRecorder()
+		err := writeJSONList(w, "regions", n, func(i int) interface{} { return regions[i] })
+		c.Assert(err, IsNil)
+		c.Assert(w.Code, Equals, http.StatusOK)
+
+		// The output is the same as the renderer's.
+		expected, err := json.MarshalIndent(&regionsInfo{Count: n, Regions: regions}, "", "  ")
+		c.Assert(err, IsNil)
+		c.Assert(w.Body.String(), Equals, string(expected))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// ClusterBackup is a snapshot of the metadata kept by PD. The regions are not
+// in it, they are reported again by the stores after a restore. AllocID is
+// the max id which may have been allocated in the cluster.
+type ClusterBackup struct {
+	ClusterID    uint64            `json:"cluster_id"`
+	MaxPeerCount uint32            `json:"max_peer_count"`
+	AllocID      uint64            `json:"alloc_id"`
+	Stores       []*metapb.Store   `json:"stores"`
+	Schedule     ScheduleConfig    `json:"schedule"`
+	Replication  ReplicationConfig `json:"replication"`
+	Rules        []*Rule           `json:"rules,omitempty"`
+	Namespaces   []*Namespace      `json:"namespaces,omitempty"`
+}
+
+// GetClusterBackup takes a snapshot of the metadata of the cluster.
+func (s *Server) GetClusterBackup() (*ClusterBackup, error) {
+	cluster := s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(ErrNotBootstrapped)
+	}
+	allocID, err := s.idAlloc.loadEnd()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &ClusterBackup{
+		ClusterID:    s.clusterID,
+		MaxPeerCount: cluster.GetConfig().GetMaxPeerCount(),
+		AllocID:      allocID,
+		Stores:       cluster.GetStores(),
+		Schedule:     *s.GetScheduleConfig(),
+		Replication:  *s.GetReplicationConfig(),
+		Rules:        s.GetPlacementRules(),
+		Namespaces:   s.GetNamespaces(),
+	}, nil
+}
+
+// RestoreClusterBackup imports a backup into the cluster, which should be
+// freshly bootstrapped. The cluster keeps its own cluster id, the stores,
+// config, placement rules and namespaces in the backup are added or replace
+// the existing ones, and the ids in the backup won't be allocated again.
+func (s *Server) RestoreClusterBackup(backup *ClusterBackup) error {
+	cluster := s.GetRaftCluster()
+	if cluster == nil {
+		return errors.Trace(ErrNotBootstrapped)
+	}
+	if err := backup.Schedule.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := backup.Replication.validate(); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := s.idAlloc.rebase(backup.AllocID); err != nil {
+		return errors.Trace(err)
+	}
+	meta := proto.Clone(cluster.GetConfig()).(*metapb.Cluster)
+	if backup.MaxPeerCount != 0 {
+		meta.MaxPeerCount = backup.MaxPeerCount
+	}
+	if err := cluster.putConfig(meta); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.SetReplicationConfig(backup.Replication); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.SetScheduleConfig(backup.Schedule); err != nil {
+		return errors.Trace(err)
+	}
+	for _, store := range backup.Stores {
+		// The tombstone stores are gone, they never come back.
+		if store.GetState() == metapb.StoreState_Tombstone {
+			continue
+		}
+		if err := cluster.putStore(store); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, rule := range backup.Rules {
+		if err := s.SetPlacementRule(rule); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, ns := range backup.Namespaces {
+		if err := s.SetNamespace(ns); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	log.Infof("cluster %d is restored from the backup of cluster %d", s.clusterID, backup.ClusterID)
+	return nil
+}
@@ -30,6 +30,21 @@ const (
 	bootstrapBalanceDiff  = 2
 )
 
+func init() {
+	RegisterScheduler("balance-leader-scheduler", func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		return newBalanceLeaderScheduler(opt), nil
+	})
+	RegisterScheduler("balance-region-scheduler", func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		return newBalanceRegionScheduler(opt), nil
+	})
+	RegisterScheduler(hotRegionScheduleName, func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		return newBalanceHotRegionScheduler(opt), nil
+	})
+	RegisterScheduler(hotReadRegionScheduleName, func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		return newBalanceHotReadRegionScheduler(opt), nil
+	})
+}
+
 // BalanceType : the perspective of balance
 type BalanceType int
 
@@ -47,23 +62,53 @@ func minBalanceDiff(count uint64) float64 {
 	return math.Sqrt(float64(count))
 }
 
-// shouldBalance returns true if we should balance the source and target store.
-// The min balance diff provides a buffer to make the cluster stable, so that we
-// don't need to schedule very frequently.
-func shouldBalance(source, target *storeInfo, kind ResourceKind) bool {
+// shouldBalance returns true if we should balance the source and target store
+// by moving the region. The min balance diff provides a buffer to make the
+// cluster stable, so that we don't need to schedule very frequently. The
+// tolerant size ratio makes sure the move doesn't make the target store the
+// next source store, so the region won't be moved back.
+func shouldBalance(source, target *StoreInfo, region *RegionInfo, kind ResourceKind, opt ScheduleOptions) bool {
+	leaderPolicy, weights := opt.GetLeaderSchedulePolicy(), opt.GetStoreScore()
 	sourceCount := source.resourceCount(kind)
-	sourceScore := source.resourceScore(kind)
-	targetScore := target.resourceScore(kind)
+	sourceScore := source.resourceScore(kind, leaderPolicy, weights)
+	targetScore := target.resourceScore(kind, leaderPolicy, weights)
 	if targetScore >= sourceScore {
 		return false
 	}
 	diffRatio := 1 - targetScore/sourceScore
 	diffCount := diffRatio * float64(sourceCount)
-	return diffCount >= minBalanceDiff(sourceCount)
+	if diffCount < minBalanceDiff(sourceCount) {
+		return false
+	}
+	influence := balanceInfluence(source, target, region, kind, leaderPolicy, weights)
+	return sourceScore-targetScore >= opt.GetTolerantSizeRatio()*influence
 }
 
-func adjustBalanceLimit(cluster *clusterInfo, kind ResourceKind) uint64 {
-	stores := cluster.getStores()
+// balanceInfluence returns how much moving the region reduces the score diff
+// of the source and target stores, which is the sum of the score changes.
+func balanceInfluence(source, target *StoreInfo, region *RegionInfo, kind ResourceKind, leaderPolicy string, weights StoreScoreConfig) float64 {
+	size := float64(region.regionSize())
+	switch kind {
+	case LeaderKind:
+		if leaderPolicy != LeaderSchedulePolicySize {
+			size = 1
+		}
+		return size/source.status.Settings.LeaderWeight + size/target.status.Settings.LeaderWeight
+	case RegionKind:
+		var influence float64
+		for _, store := range []*StoreInfo{source, target} {
+			if store.status.GetCapacity() > 0 {
+				influence += weights.RegionSizeWeight * size / store.capacityFactor(weights)
+			}
+		}
+		return influence
+	default:
+		return 0
+	}
+}
+
+func adjustBalanceLimit(cluster Cluster, kind ResourceKind) uint64 {
+	stores := cluster.GetStores()
 	counts := make([]float64, 0, len(stores))
 	for _, s := range stores {
 		if s.isUp() {
@@ -75,21 +120,24 @@ func adjustBalanceLimit(cluster *clusterInfo, kind ResourceKind) uint64 {
 }
 
 type balanceLeaderScheduler struct {
-	opt      *scheduleOption
+	opt      ScheduleOptions
 	limit    uint64
 	selector Selector
 }
 
-func newBalanceLeaderScheduler(opt *scheduleOption) *balanceLeaderScheduler {
+func newBalanceLeaderScheduler(opt ScheduleOptions) *balanceLeaderScheduler {
 	var filters []Filter
 	filters = append(filters, newBlockFilter())
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
+	filters = append(filters, newRejectLeaderFilter(opt))
+	filters = append(filters, newLeaderStorageThresholdFilter(opt))
 
 	return &balanceLeaderScheduler{
 		opt:      opt,
 		limit:    1,
-		selector: newBalanceSelector(LeaderKind, filters),
+		selector: newBalanceSelector(LeaderKind, opt, filters),
 	}
 }
 
@@ -105,19 +153,28 @@ func (l *balanceLeaderScheduler) GetResourceLimit() uint64 {
 	return minUint64(l.limit, l.opt.GetLeaderScheduleLimit())
 }
 
-func (l *balanceLeaderScheduler) Prepare(cluster *clusterInfo) error { return nil }
+func (l *balanceLeaderScheduler) Prepare(cluster Cluster) error { return nil }
 
-func (l *balanceLeaderScheduler) Cleanup(cluster *clusterInfo) {}
+func (l *balanceLeaderScheduler) Cleanup(cluster Cluster) {}
 
-func (l *balanceLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
-	region, newLeader := scheduleTransferLeader(cluster, l.selector)
+func (l *balanceLeaderScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	for _, cluster := range getNamespaceClusters(cluster, l.opt.getNamespaces()) {
+		if op := l.schedule(cluster, influence); op != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+func (l *balanceLeaderScheduler) schedule(cluster Cluster, influence OpInfluence) Operator {
+	region, newLeader := scheduleTransferLeader(cluster, l.selector, l.opt.GetLeaderSchedulePolicy(), influence)
 	if region == nil {
 		return nil
 	}
 
-	source := cluster.getStore(region.Leader.GetStoreId())
-	target := cluster.getStore(newLeader.GetStoreId())
-	if !shouldBalance(source, target, l.GetResourceKind()) {
+	source := influence.apply(cluster.GetStore(region.Leader.GetStoreId()))
+	target := influence.apply(cluster.GetStore(newLeader.GetStoreId()))
+	if !shouldBalance(source, target, region, l.GetResourceKind(), l.opt) {
 		return nil
 	}
 	l.limit = adjustBalanceLimit(cluster, l.GetResourceKind())
@@ -125,21 +182,23 @@ func (l *balanceLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 }
 
 type balanceRegionScheduler struct {
-	opt      *scheduleOption
+	opt      ScheduleOptions
 	rep      *Replication
 	cache    *idCache
 	limit    uint64
 	selector Selector
 }
 
-func newBalanceRegionScheduler(opt *scheduleOption) *balanceRegionScheduler {
+func newBalanceRegionScheduler(opt ScheduleOptions) *balanceRegionScheduler {
 	cache := newIDCache(storeCacheInterval, 4*storeCacheInterval)
 
 	var filters []Filter
 	filters = append(filters, newCacheFilter(cache))
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
 	filters = append(filters, newSnapshotCountFilter(opt))
+	filters = append(filters, newPendingPeerCountFilter(opt))
 	filters = append(filters, newStorageThresholdFilter(opt))
 
 	return &balanceRegionScheduler{
@@ -147,7 +206,7 @@ func newBalanceRegionScheduler(opt *scheduleOption) *balanceRegionScheduler {
 		rep:      opt.GetReplication(),
 		cache:    cache,
 		limit:    1,
-		selector: newBalanceSelector(RegionKind, filters),
+		selector: newBalanceSelector(RegionKind, opt, filters),
 	}
 }
 
@@ -163,23 +222,38 @@ func (s *balanceRegionScheduler) GetResourceLimit() uint64 {
 	return minUint64(s.limit, s.opt.GetRegionScheduleLimit())
 }
 
-func (s *balanceRegionScheduler) Prepare(cluster *clusterInfo) error { return nil }
+func (s *balanceRegionScheduler) Prepare(cluster Cluster) error { return nil }
+
+func (s *balanceRegionScheduler) Cleanup(cluster Cluster) {}
 
-func (s *balanceRegionScheduler) Cleanup(cluster *clusterInfo) {}
+func (s *balanceRegionScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	for _, cluster := range getNamespaceClusters(cluster, s.opt.getNamespaces()) {
+		if op := s.schedule(cluster, influence); op != nil {
+			return op
+		}
+	}
+	return nil
+}
 
-func (s *balanceRegionScheduler) Schedule(cluster *clusterInfo) Operator {
-	// Select a peer from the store with most regions.
-	region, oldPeer := scheduleRemovePeer(cluster, s.selector)
+func (s *balanceRegionScheduler) schedule(cluster Cluster, influence OpInfluence) Operator {
+	// Move a peer out of the low space stores first, the stores are not
+	// balanced by score in this case.
+	region, oldPeer := scheduleRemovePeer(cluster, s.selector, influence, newLowSpaceFilter(s.opt))
+	evacuate := region != nil
+	if region == nil {
+		// Select a peer from the store with most regions.
+		region, oldPeer = scheduleRemovePeer(cluster, s.selector, influence)
+	}
 	if region == nil {
 		return nil
 	}
 
 	// We don't schedule region with abnormal number of replicas.
-	if len(region.GetPeers()) != s.rep.GetMaxReplicas() {
+	if len(region.GetPeers()) != s.opt.getRegionMaxReplicas(region) {
 		return nil
 	}
 
-	op := s.transferPeer(cluster, region, oldPeer)
+	op := s.transferPeer(cluster, region, oldPeer, evacuate, influence)
 	if op == nil {
 		// We can't transfer peer from this store now, so we add it to the cache
 		// and skip it for a while.
@@ -188,20 +262,22 @@ func (s *balanceRegionScheduler) Schedule(cluster *clusterInfo) Operator {
 	return op
 }
 
-func (s *balanceRegionScheduler) transferPeer(cluster *clusterInfo, region *RegionInfo, oldPeer *metapb.Peer) Operator {
+func (s *balanceRegionScheduler) transferPeer(cluster Cluster, region *RegionInfo, oldPeer *metapb.Peer, evacuate bool, influence OpInfluence) Operator {
 	// scoreGuard guarantees that the distinct score will not decrease.
-	stores := cluster.getRegionStores(region)
-	source := cluster.getStore(oldPeer.GetStoreId())
+	stores := cluster.GetRegionStores(region)
+	source := influence.apply(cluster.GetStore(oldPeer.GetStoreId()))
 	scoreGuard := newDistinctScoreFilter(s.rep, stores, source)
 
 	checker := newReplicaChecker(s.opt, cluster)
-	newPeer, _ := checker.selectBestPeer(region, scoreGuard)
+	checker.influence = influence
+	filters := append(checker.placementFilters(region, oldPeer), scoreGuard)
+	newPeer, _ := checker.selectBestPeer(region, filters...)
 	if newPeer == nil {
 		return nil
 	}
 
-	target := cluster.getStore(newPeer.GetStoreId())
-	if !shouldBalance(source, target, s.GetResourceKind()) {
+	target := influence.apply(cluster.GetStore(newPeer.GetStoreId()))
+	if !evacuate && !shouldBalance(source, target, region, s.GetResourceKind(), s.opt) {
 		return nil
 	}
 	s.limit = adjustBalanceLimit(cluster, s.GetResourceKind())
@@ -211,42 +287,66 @@ func (s *balanceRegionScheduler) transferPeer(cluster *clusterInfo, region *Regi
 
 // replicaChecker ensures region has the best replicas.
 type replicaChecker struct {
-	opt     *scheduleOption
+	opt     ScheduleOptions
 	rep     *Replication
-	cluster *clusterInfo
+	rules   *placementRules
+	cluster Cluster
 	filters []Filter
+	// influence is added to the scores of the candidate stores.
+	influence OpInfluence
 }
 
-func newReplicaChecker(opt *scheduleOption, cluster *clusterInfo) *replicaChecker {
+func newReplicaChecker(opt ScheduleOptions, cluster Cluster) *replicaChecker {
 	var filters []Filter
 	filters = append(filters, newHealthFilter(opt))
 	filters = append(filters, newSnapshotCountFilter(opt))
+	filters = append(filters, newPendingPeerCountFilter(opt))
 
 	return &replicaChecker{
 		opt:     opt,
 		rep:     opt.GetReplication(),
+		rules:   opt.getRules(),
 		cluster: cluster,
 		filters: filters,
 	}
 }
 
+// Check returns the operator to repair the replicas of the region. The
+// operators are ReplicaKind except the priority ones, so they are limited
+// separately from the region balance.
 func (r *replicaChecker) Check(region *RegionInfo) Operator {
+	op := r.check(region)
+	if regionOp, ok := op.(*regionOperator); ok && regionOp.Kind == RegionKind {
+		regionOp.Kind = ReplicaKind
+	}
+	return op
+}
+
+func (r *replicaChecker) check(region *RegionInfo) Operator {
 	if op := r.checkDownPeer(region); op != nil {
 		return op
 	}
 	if op := r.checkOfflinePeer(region); op != nil {
 		return op
 	}
+	if op := r.checkNamespace(region); op != nil {
+		return op
+	}
 
-	if len(region.GetPeers()) < r.rep.GetMaxReplicas() {
-		newPeer, _ := r.selectBestPeer(region, r.filters...)
+	if rules := r.rules.getRegionRules(region); len(rules) > 0 {
+		return r.checkRules(region, rules)
+	}
+
+	if len(region.GetPeers()) < r.opt.getRegionMaxReplicas(region) {
+		filters := append([]Filter{newSpecialEngineFilter()}, r.filters...)
+		newPeer, _ := r.selectBestPeer(region, filters...)
 		if newPeer == nil {
 			return nil
 		}
 		return newAddPeer(region, newPeer)
 	}
 
-	if len(region.GetPeers()) > r.rep.GetMaxReplicas() {
+	if len(region.GetPeers()) > r.opt.getRegionMaxReplicas(region) {
 		oldPeer, _ := r.selectWorstPeer(region)
 		if oldPeer == nil {
 			return nil
@@ -254,6 +354,10 @@ func (r *replicaChecker) Check(region *RegionInfo) Operator {
 		return newRemovePeer(region, oldPeer)
 	}
 
+	// Don't move replicas of the region until its pending peers catch up.
+	if len(region.PendingPeers) > 0 {
+		return nil
+	}
 	return r.checkBestReplacement(region)
 }
 
@@ -263,21 +367,22 @@ func (r *replicaChecker) selectBestPeer(region *RegionInfo, filters ...Filter) (
 	filters = append(filters, newStateFilter(r.opt))
 	filters = append(filters, newStorageThresholdFilter(r.opt))
 	filters = append(filters, newExcludedFilter(nil, region.GetStoreIds()))
+	filters = append(filters, newRegionNamespaceFilter(r.opt, region))
 
 	var (
-		bestStore *storeInfo
+		bestStore *StoreInfo
 		bestScore float64
 	)
 
 	// Select the store with best distinct score.
 	// If the scores are the same, select the store with minimal region score.
-	stores := r.cluster.getRegionStores(region)
-	for _, store := range r.cluster.getStores() {
+	stores := r.cluster.GetRegionStores(region)
+	for _, store := range r.influence.applyStores(r.cluster.GetStores()) {
 		if filterTarget(store, filters) {
 			continue
 		}
 		score := r.rep.GetDistinctScore(stores, store)
-		if bestStore == nil || compareStoreScore(store, score, bestStore, bestScore) > 0 {
+		if bestStore == nil || compareStoreScore(store, score, bestStore, bestScore, r.opt.GetStoreScore()) > 0 {
 			bestStore = store
 			bestScore = score
 		}
@@ -287,7 +392,7 @@ func (r *replicaChecker) selectBestPeer(region *RegionInfo, filters ...Filter) (
 		return nil, 0
 	}
 
-	newPeer, err := r.cluster.allocPeer(bestStore.GetId())
+	newPeer, err := r.cluster.AllocPeer(bestStore.GetId())
 	if err != nil {
 		log.Errorf("failed to allocate peer: %v", err)
 		return nil, 0
@@ -298,19 +403,19 @@ func (r *replicaChecker) selectBestPeer(region *RegionInfo, filters ...Filter) (
 // selectWorstPeer returns the worst peer in the region.
 func (r *replicaChecker) selectWorstPeer(region *RegionInfo, filters ...Filter) (*metapb.Peer, float64) {
 	var (
-		worstStore *storeInfo
+		worstStore *StoreInfo
 		worstScore float64
 	)
 
 	// Select the store with lowest distinct score.
 	// If the scores are the same, select the store with maximal region score.
-	stores := r.cluster.getRegionStores(region)
+	stores := r.cluster.GetRegionStores(region)
 	for _, store := range stores {
 		if filterSource(store, filters) {
 			continue
 		}
 		score := r.rep.GetDistinctScore(stores, store)
-		if worstStore == nil || compareStoreScore(store, score, worstStore, worstScore) < 0 {
+		if worstStore == nil || compareStoreScore(store, score, worstStore, worstScore, r.opt.GetStoreScore()) < 0 {
 			worstStore = store
 			worstScore = score
 		}
@@ -323,11 +428,78 @@ func (r *replicaChecker) selectWorstPeer(region *RegionInfo, filters ...Filter)
 }
 
 // selectBestReplacement returns the best peer to replace the region peer.
-func (r *replicaChecker) selectBestReplacement(region *RegionInfo, peer *metapb.Peer) (*metapb.Peer, float64) {
+func (r *replicaChecker) selectBestReplacement(region *RegionInfo, peer *metapb.Peer, filters ...Filter) (*metapb.Peer, float64) {
 	// Get a new region without the peer we are going to replace.
 	newRegion := region.clone()
 	newRegion.RemoveStorePeer(peer.GetStoreId())
-	return r.selectBestPeer(newRegion, newExcludedFilter(nil, region.GetStoreIds()))
+	filters = append(filters, newExcludedFilter(nil, region.GetStoreIds()))
+	filters = append(filters, r.placementFilters(region, peer)...)
+	return r.selectBestPeer(newRegion, filters...)
+}
+
+// getMaxReplicas returns the replica count required by the placement rules
+// of the region, or max-replicas if there is no rule for it.
+func (r *replicaChecker) getMaxReplicas(region *RegionInfo) int {
+	rules := r.rules.getRegionRules(region)
+	if len(rules) == 0 {
+		return r.opt.getRegionMaxReplicas(region)
+	}
+	count := 0
+	for _, rule := range rules {
+		count += rule.Count
+	}
+	return count
+}
+
+// placementFilters returns the filters to keep the replacement of the peer
+// matching the placement rule the peer belongs to. The peers not placed by
+// rules are never replaced to the stores of special engines.
+func (r *replicaChecker) placementFilters(region *RegionInfo, peer *metapb.Peer) []Filter {
+	rules := r.rules.getRegionRules(region)
+	if len(rules) == 0 {
+		return []Filter{newSpecialEngineFilter()}
+	}
+	if rule := fitRules(r.cluster, region, rules).peerRules[peer.GetId()]; rule != nil {
+		return []Filter{newRuleFilter(rule)}
+	}
+	return []Filter{newSpecialEngineFilter()}
+}
+
+// checkRules makes the replicas of the region satisfy the placement rules.
+// A missing replica is moved from the peers not needed by any rule if
+// possible, otherwise it is added.
+func (r *replicaChecker) checkRules(region *RegionInfo, rules []*Rule) Operator {
+	fit := fitRules(r.cluster, region, rules)
+	if rule := fit.unfilled; rule != nil {
+		filters := append([]Filter{newRuleFilter(rule)}, r.filters...)
+		for _, oldPeer := range fit.extra {
+			newPeer, _ := r.selectBestReplacement(region, oldPeer, filters...)
+			if newPeer == nil {
+				return nil
+			}
+			return newTransferPeer(region, oldPeer, newPeer)
+		}
+		newPeer, _ := r.selectBestPeer(region, filters...)
+		if newPeer == nil {
+			return nil
+		}
+		return newAddPeer(region, newPeer)
+	}
+
+	if len(fit.extra) > 0 {
+		fitted := make(map[uint64]struct{})
+		for _, peer := range region.GetPeers() {
+			if _, ok := fit.peerRules[peer.GetId()]; ok {
+				fitted[peer.GetStoreId()] = struct{}{}
+			}
+		}
+		oldPeer, _ := r.selectWorstPeer(region, newExcludedFilter(fitted, nil))
+		if oldPeer == nil {
+			return nil
+		}
+		return newRemovePeer(region, oldPeer)
+	}
+	return nil
 }
 
 func (r *replicaChecker) checkDownPeer(region *RegionInfo) Operator {
@@ -336,7 +508,7 @@ func (r *replicaChecker) checkDownPeer(region *RegionInfo) Operator {
 		if peer == nil {
 			continue
 		}
-		store := r.cluster.getStore(peer.GetStoreId())
+		store := r.cluster.GetStore(peer.GetStoreId())
 		if store == nil {
 			log.Infof("lost the store %d,maybe you are recovering the PD cluster.", peer.GetStoreId())
 			return nil
@@ -347,14 +519,64 @@ func (r *replicaChecker) checkDownPeer(region *RegionInfo) Operator {
 		if stats.GetDownSeconds() < uint64(r.opt.GetMaxStoreDownTime().Seconds()) {
 			continue
 		}
-		return newRemovePeer(region, peer)
+		return r.repairDownPeer(region, peer)
+	}
+
+	// The peers on the stores not sending heartbeats for max-store-down-time
+	// are repaired too, even if the leader doesn't report them.
+	for _, peer := range region.GetPeers() {
+		if store := r.cluster.GetStore(peer.GetStoreId()); store != nil && r.isStoreDown(store) {
+			return r.repairDownPeer(region, peer)
+		}
 	}
 	return nil
 }
 
+// isStoreDown checks whether the store hasn't sent heartbeats for
+// max-store-down-time. A store that has sent no heartbeat since the leader
+// started is not down, as it may be just slow to connect to the new leader.
+func (r *replicaChecker) isStoreDown(store *StoreInfo) bool {
+	return !store.status.LastHeartbeatTS.IsZero() && store.downTime() >= r.opt.GetMaxStoreDownTime()
+}
+
+// isUrgent checks whether the region is down to at most one healthy replica,
+// so losing one more store may lose its data. The pending peers are not
+// healthy as they may not catch up with the leader yet.
+func (r *replicaChecker) isUrgent(region *RegionInfo) bool {
+	var healthy int
+	for _, peer := range region.GetPeers() {
+		if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+			continue
+		}
+		if store := r.cluster.GetStore(peer.GetStoreId()); store == nil || r.isStoreDown(store) {
+			continue
+		}
+		healthy++
+	}
+	return healthy <= 1 && healthy < r.getMaxReplicas(region)
+}
+
+func (r *replicaChecker) repairDownPeer(region *RegionInfo, peer *metapb.Peer) Operator {
+	// Remove the down peer directly if the region has redundant replicas.
+	if len(region.GetPeers()) > r.getMaxReplicas(region) {
+		return newRemovePeer(region, peer)
+	}
+
+	// Add a replacement before removing the down peer, so the region
+	// keeps enough healthy replicas during the repair.
+	newPeer, _ := r.selectBestReplacement(region, peer)
+	if newPeer == nil {
+		return newRemovePeer(region, peer)
+	}
+	return newTransferPeer(region, peer, newPeer)
+}
+
+// checkOfflinePeer moves replicas out of offline stores. The operators have
+// higher priority than balance operators, so that the stores can be removed
+// in time.
 func (r *replicaChecker) checkOfflinePeer(region *RegionInfo) Operator {
 	for _, peer := range region.GetPeers() {
-		store := r.cluster.getStore(peer.GetStoreId())
+		store := r.cluster.GetStore(peer.GetStoreId())
 		if store == nil {
 			log.Infof("lost the store %d,maybe you are recovering the PD cluster.", peer.GetStoreId())
 			return nil
@@ -364,20 +586,38 @@ func (r *replicaChecker) checkOfflinePeer(region *RegionInfo) Operator {
 		}
 
 		// check the number of replicas firstly
-		if len(region.GetPeers()) > r.opt.GetMaxReplicas() {
-			return newRemovePeer(region, peer)
+		if len(region.GetPeers()) > r.getMaxReplicas(region) {
+			return newPriorityRemovePeer(region, peer)
 		}
 
-		newPeer, _ := r.selectBestPeer(region)
+		newPeer, _ := r.selectBestPeer(region, r.placementFilters(region, peer)...)
 		if newPeer == nil {
 			return nil
 		}
-		return newTransferPeer(region, peer, newPeer)
+		return newPriorityTransferPeer(region, peer, newPeer)
 	}
 
 	return nil
 }
 
+// checkNamespace moves the replicas out of the stores not in the namespace
+// of the region.
+func (r *replicaChecker) checkNamespace(region *RegionInfo) Operator {
+	namespace := r.opt.getNamespaces().getRegionNamespace(region)
+	for _, peer := range region.GetPeers() {
+		store := r.cluster.GetStore(peer.GetStoreId())
+		if store == nil || r.opt.getNamespaces().getStoreNamespace(store) == namespace {
+			continue
+		}
+		newPeer, _ := r.selectBestReplacement(region, peer)
+		if newPeer == nil {
+			return nil
+		}
+		return newTransferPeer(region, peer, newPeer)
+	}
+	return nil
+}
+
 func (r *replicaChecker) checkBestReplacement(region *RegionInfo) Operator {
 	oldPeer, oldScore := r.selectWorstPeer(region)
 	if oldPeer == nil {
@@ -398,13 +638,14 @@ func (r *replicaChecker) checkBestReplacement(region *RegionInfo) Operator {
 type RegionStat struct {
 	RegionID     uint64 `json:"region_id"`
 	WrittenBytes uint64 `json:"written_bytes"`
+	ReadBytes    uint64 `json:"read_bytes,omitempty"`
 	// HotDegree records the hot region update times
 	HotDegree int `json:"hot_degree"`
 	// LastUpdateTime used to calculate average write
 	LastUpdateTime time.Time `json:"last_update_time"`
 	StoreID        uint64    `json:"-"`
-	// antiCount used to eliminate some noise when remove region in cache
-	antiCount int
+	// flow is the decayed flow in bytes per second.
+	flow float64
 	// version used to check the region split times
 	version uint64
 }
@@ -419,13 +660,18 @@ func (m RegionsStat) Less(i, j int) bool { return m[i].WrittenBytes < m[j].Writt
 // HotRegionsStat records all hot regions statistics
 type HotRegionsStat struct {
 	WrittenBytes uint64      `json:"total_written_bytes"`
+	ReadBytes    uint64      `json:"total_read_bytes,omitempty"`
 	RegionsCount int         `json:"regions_count"`
 	RegionsStat  RegionsStat `json:"statistics"`
 }
 
-type balanceHotRegionScheduler struct {
+// baseHotScheduler is the common part of the schedulers balancing the hot
+// regions of a flow kind, it transfers the hot leaders from the store with
+// the most hot leaders to the followers on the stores with less.
+type baseHotScheduler struct {
 	sync.RWMutex
-	opt   *scheduleOption
+	opt   ScheduleOptions
+	kind  hotFlowKind
 	limit uint64
 
 	// store id -> hot regions statistics as the role of replica
@@ -435,9 +681,10 @@ type balanceHotRegionScheduler struct {
 	r                  *rand.Rand
 }
 
-func newBalanceHotRegionScheduler(opt *scheduleOption) *balanceHotRegionScheduler {
-	return &balanceHotRegionScheduler{
+func newBaseHotScheduler(opt ScheduleOptions, kind hotFlowKind) *baseHotScheduler {
+	return &baseHotScheduler{
 		opt:                opt,
+		kind:               kind,
 		limit:              1,
 		statisticsAsPeer:   make(map[uint64]*HotRegionsStat),
 		statisticsAsLeader: make(map[uint64]*HotRegionsStat),
@@ -445,98 +692,192 @@ func newBalanceHotRegionScheduler(opt *scheduleOption) *balanceHotRegionSchedule
 	}
 }
 
-func (h *balanceHotRegionScheduler) GetName() string {
-	return "balance-hot-region-scheduler"
-}
-
-func (h *balanceHotRegionScheduler) GetResourceKind() ResourceKind {
+func (h *baseHotScheduler) GetResourceKind() ResourceKind {
 	return PriorityKind
 }
 
-func (h *balanceHotRegionScheduler) GetResourceLimit() uint64 {
+func (h *baseHotScheduler) GetResourceLimit() uint64 {
 	return h.limit
 }
 
-func (h *balanceHotRegionScheduler) Prepare(cluster *clusterInfo) error { return nil }
+func (h *baseHotScheduler) Prepare(cluster Cluster) error { return nil }
 
-func (h *balanceHotRegionScheduler) Cleanup(cluster *clusterInfo) {}
+func (h *baseHotScheduler) Cleanup(cluster Cluster) {}
 
-func (h *balanceHotRegionScheduler) Schedule(cluster *clusterInfo) Operator {
-	h.calcScore(cluster)
+// flowBytes returns the bytes of the flow kind of the scheduler.
+func (h *baseHotScheduler) flowBytes(writtenBytes, readBytes uint64) uint64 {
+	if h.kind == hotReadFlow {
+		return readBytes
+	}
+	return writtenBytes
+}
 
-	// balance by peer
-	srcRegion, srcPeer, destPeer := h.balanceByPeer(cluster)
-	if srcRegion != nil {
-		return newPriorityTransferPeer(srcRegion, srcPeer, destPeer)
+func (h *baseHotScheduler) calcScore(cluster Cluster) {
+	h.Lock()
+	defer h.Unlock()
+
+	stats := cluster.getHotRegionStoreStats(h.kind, h.opt.GetHotRegionThreshold())
+	h.statisticsAsPeer = stats.AsPeer
+	h.statisticsAsLeader = stats.AsLeader
+}
+
+func (h *baseHotScheduler) adjustBalanceLimit(storeID uint64, t BalanceType) {
+	var srcStatistics *HotRegionsStat
+	var allStatistics map[uint64]*HotRegionsStat
+	switch t {
+	case byPeer:
+		srcStatistics = h.statisticsAsPeer[storeID]
+		allStatistics = h.statisticsAsPeer
+	case byLeader:
+		srcStatistics = h.statisticsAsLeader[storeID]
+		allStatistics = h.statisticsAsLeader
 	}
 
-	// balance by leader
-	srcRegion, newLeader := h.balanceByLeader(cluster)
-	if srcRegion != nil {
-		return newPriorityTransferLeader(srcRegion, newLeader)
+	var hotRegionTotalCount float64
+	for _, m := range allStatistics {
+		hotRegionTotalCount += float64(m.RegionsStat.Len())
 	}
 
-	return nil
+	avgRegionCount := hotRegionTotalCount / float64(len(allStatistics))
+	// Multiplied by hotRegionLimitFactor to avoid transfer back and forth
+	limit := uint64((float64(srcStatistics.RegionsStat.Len()) - avgRegionCount) * hotRegionLimitFactor)
+	h.limit = maxUint64(1, limit)
 }
 
-func (h *balanceHotRegionScheduler) calcScore(cluster *clusterInfo) {
-	h.Lock()
-	defer h.Unlock()
+func (h *baseHotScheduler) balanceByLeader(cluster Cluster) (*RegionInfo, *metapb.Peer) {
+	var (
+		maxFlowBytes           uint64
+		srcStoreID             uint64
+		maxHotStoreRegionCount int
+	)
 
-	h.statisticsAsPeer = make(map[uint64]*HotRegionsStat)
-	h.statisticsAsLeader = make(map[uint64]*HotRegionsStat)
-	items := cluster.writeStatistics.elems()
-	for _, item := range items {
-		r, ok := item.value.(*RegionStat)
-		if !ok {
-			continue
+	// select srcStoreId by leader
+	for storeID, statistics := range h.statisticsAsLeader {
+		count, flowBytes := statistics.RegionsStat.Len(), h.flowBytes(statistics.WrittenBytes, statistics.ReadBytes)
+		if count >= 2 && (count > maxHotStoreRegionCount || (count == maxHotStoreRegionCount && flowBytes > maxFlowBytes)) {
+			maxHotStoreRegionCount = count
+			maxFlowBytes = flowBytes
+			srcStoreID = storeID
 		}
-		if r.HotDegree < hotRegionLowThreshold {
+	}
+	if srcStoreID == 0 {
+		return nil, nil
+	}
+
+	// select destPeer
+	for _, i := range h.r.Perm(h.statisticsAsLeader[srcStoreID].RegionsStat.Len()) {
+		rs := h.statisticsAsLeader[srcStoreID].RegionsStat[i]
+		srcRegion := cluster.GetRegion(rs.RegionID)
+		if srcRegion == nil || len(srcRegion.DownPeers) != 0 || len(srcRegion.PendingPeers) != 0 {
 			continue
 		}
 
-		regionInfo := cluster.getRegion(r.RegionID)
-		leaderStoreID := regionInfo.Leader.GetStoreId()
-		storeIDs := regionInfo.GetStoreIds()
-		for storeID := range storeIDs {
-			peerStat, ok := h.statisticsAsPeer[storeID]
-			if !ok {
-				peerStat = &HotRegionsStat{
-					RegionsStat: make(RegionsStat, 0, storeHotRegionsDefaultLen),
-				}
-				h.statisticsAsPeer[storeID] = peerStat
-			}
-			leaderStat, ok := h.statisticsAsLeader[storeID]
-			if !ok {
-				leaderStat = &HotRegionsStat{
-					RegionsStat: make(RegionsStat, 0, storeHotRegionsDefaultLen),
-				}
-				h.statisticsAsLeader[storeID] = leaderStat
-			}
+		destPeer := h.selectDestStoreByLeader(srcRegion, srcStoreID, h.flowBytes(rs.WrittenBytes, rs.ReadBytes))
+		if destPeer != nil {
+			h.adjustBalanceLimit(srcStoreID, byLeader)
+			return srcRegion, destPeer
+		}
+	}
+	return nil, nil
+}
+
+func (h *baseHotScheduler) selectDestStoreByLeader(srcRegion *RegionInfo, srcStoreID uint64, regionFlowBytes uint64) *metapb.Peer {
+	sr := h.statisticsAsLeader[srcStoreID]
+	srcFlowBytes := h.flowBytes(sr.WrittenBytes, sr.ReadBytes)
+	srcHotRegionsCount := sr.RegionsStat.Len()
 
-			stat := RegionStat{
-				RegionID:       r.RegionID,
-				WrittenBytes:   r.WrittenBytes,
-				HotDegree:      r.HotDegree,
-				LastUpdateTime: r.LastUpdateTime,
-				StoreID:        storeID,
-				antiCount:      r.antiCount,
-				version:        r.version,
+	var (
+		destPeer     *metapb.Peer
+		minFlowBytes uint64 = math.MaxUint64
+	)
+	minRegionsCount := int(math.MaxInt32)
+	for storeID, peer := range srcRegion.GetFollowers() {
+		if s, ok := h.statisticsAsLeader[storeID]; ok {
+			flowBytes := h.flowBytes(s.WrittenBytes, s.ReadBytes)
+			if srcHotRegionsCount-s.RegionsStat.Len() > 1 && minRegionsCount > s.RegionsStat.Len() {
+				destPeer = peer
+				minFlowBytes = flowBytes
+				minRegionsCount = s.RegionsStat.Len()
+				continue
 			}
-			peerStat.WrittenBytes += r.WrittenBytes
-			peerStat.RegionsCount++
-			peerStat.RegionsStat = append(peerStat.RegionsStat, stat)
-
-			if storeID == leaderStoreID {
-				leaderStat.WrittenBytes += r.WrittenBytes
-				leaderStat.RegionsCount++
-				leaderStat.RegionsStat = append(leaderStat.RegionsStat, stat)
+			if minRegionsCount == s.RegionsStat.Len() && minFlowBytes > flowBytes &&
+				uint64(float64(srcFlowBytes)*hotRegionScheduleFactor) > flowBytes+2*regionFlowBytes {
+				minFlowBytes = flowBytes
+				destPeer = peer
 			}
+		} else {
+			destPeer = peer
+			break
+		}
+	}
+	return destPeer
+}
+
+func (h *baseHotScheduler) GetStatus() *StoreHotRegionInfos {
+	h.RLock()
+	defer h.RUnlock()
+	asPeer := make(map[uint64]*HotRegionsStat, len(h.statisticsAsPeer))
+	for id, stat := range h.statisticsAsPeer {
+		clone := *stat
+		asPeer[id] = &clone
+	}
+	asLeader := make(map[uint64]*HotRegionsStat, len(h.statisticsAsLeader))
+	for id, stat := range h.statisticsAsLeader {
+		clone := *stat
+		asLeader[id] = &clone
+	}
+	return &StoreHotRegionInfos{
+		AsPeer:   asPeer,
+		AsLeader: asLeader,
+	}
+}
+
+// StoreHotRegionInfos : used to get human readable description for hot regions.
+type StoreHotRegionInfos struct {
+	AsPeer   map[uint64]*HotRegionsStat `json:"as_peer"`
+	AsLeader map[uint64]*HotRegionsStat `json:"as_leader"`
+}
+
+// balanceHotRegionScheduler balances the write hot regions, it moves the hot
+// peers from the store with the most hot peers, and transfers the hot leaders
+// if no peer can be moved.
+type balanceHotRegionScheduler struct {
+	*baseHotScheduler
+}
+
+func newBalanceHotRegionScheduler(opt ScheduleOptions) *balanceHotRegionScheduler {
+	return &balanceHotRegionScheduler{
+		baseHotScheduler: newBaseHotScheduler(opt, hotWriteFlow),
+	}
+}
+
+func (h *balanceHotRegionScheduler) GetName() string {
+	return hotRegionScheduleName
+}
+
+func (h *balanceHotRegionScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	h.calcScore(cluster)
+
+	// balance by peer, it is paused if the region schedule limit is 0.
+	if h.opt.GetRegionScheduleLimit() > 0 {
+		srcRegion, srcPeer, destPeer := h.balanceByPeer(cluster)
+		if srcRegion != nil {
+			return newPriorityTransferPeer(srcRegion, srcPeer, destPeer)
 		}
 	}
+
+	// balance by leader, it is paused if the leader schedule limit is 0.
+	if h.opt.GetLeaderScheduleLimit() > 0 {
+		srcRegion, newLeader := h.balanceByLeader(cluster)
+		if srcRegion != nil {
+			return newPriorityTransferLeader(srcRegion, newLeader)
+		}
+	}
+
+	return nil
 }
 
-func (h *balanceHotRegionScheduler) balanceByPeer(cluster *clusterInfo) (*RegionInfo, *metapb.Peer, *metapb.Peer) {
+func (h *balanceHotRegionScheduler) balanceByPeer(cluster Cluster) (*RegionInfo, *metapb.Peer, *metapb.Peer) {
 	var (
 		maxWrittenBytes        uint64
 		srcStoreID             uint64
@@ -545,6 +886,10 @@ func (h *balanceHotRegionScheduler) balanceByPeer(cluster *clusterInfo) (*Region
 
 	// get the srcStoreId
 	for storeID, statistics := range h.statisticsAsPeer {
+		// The peers on special engines are placed by the rules.
+		if store := cluster.GetStore(storeID); store != nil && store.isSpecialEngine() {
+			continue
+		}
 		count, writtenBytes := statistics.RegionsStat.Len(), statistics.WrittenBytes
 		if count >= 2 && (count > maxHotStoreRegionCount || (count == maxHotStoreRegionCount && writtenBytes > maxWrittenBytes)) {
 			maxHotStoreRegionCount = count
@@ -556,20 +901,24 @@ func (h *balanceHotRegionScheduler) balanceByPeer(cluster *clusterInfo) (*Region
 		return nil, nil, nil
 	}
 
-	stores := cluster.getStores()
+	stores := cluster.GetStores()
 	var destStoreID uint64
 	for _, i := range h.r.Perm(h.statisticsAsPeer[srcStoreID].RegionsStat.Len()) {
 		rs := h.statisticsAsPeer[srcStoreID].RegionsStat[i]
-		srcRegion := cluster.getRegion(rs.RegionID)
+		srcRegion := cluster.GetRegion(rs.RegionID)
 		if len(srcRegion.DownPeers) != 0 || len(srcRegion.PendingPeers) != 0 {
 			continue
 		}
 
 		var filters []Filter
 		filters = append(filters, newExcludedFilter(srcRegion.GetStoreIds(), srcRegion.GetStoreIds()))
-		filters = append(filters, newDistinctScoreFilter(h.opt.GetReplication(), stores, cluster.getLeaderStore(srcRegion)))
+		filters = append(filters, newDistinctScoreFilter(h.opt.GetReplication(), stores, cluster.GetLeaderStore(srcRegion)))
 		filters = append(filters, newStateFilter(h.opt))
+		filters = append(filters, newSpecialEngineFilter())
+		filters = append(filters, newSnapshotCountFilter(h.opt))
+		filters = append(filters, newPendingPeerCountFilter(h.opt))
 		filters = append(filters, newStorageThresholdFilter(h.opt))
+		filters = append(filters, newRegionNamespaceFilter(h.opt, srcRegion))
 		destStoreIDs := make([]uint64, 0, len(stores))
 		for _, store := range stores {
 			if filterTarget(store, filters) {
@@ -595,7 +944,7 @@ func (h *balanceHotRegionScheduler) balanceByPeer(cluster *clusterInfo) (*Region
 				return nil, nil, nil
 			}
 
-			destPeer, err := cluster.allocPeer(destStoreID)
+			destPeer, err := cluster.AllocPeer(destStoreID)
 			if err != nil {
 				log.Errorf("failed to allocate peer: %v", err)
 				return nil, nil, nil
@@ -639,127 +988,34 @@ func (h *balanceHotRegionScheduler) selectDestStoreByPeer(candidateStoreIDs []ui
 	return destStoreID
 }
 
-func (h *balanceHotRegionScheduler) adjustBalanceLimit(storeID uint64, t BalanceType) {
-	var srcStatistics *HotRegionsStat
-	var allStatistics map[uint64]*HotRegionsStat
-	switch t {
-	case byPeer:
-		srcStatistics = h.statisticsAsPeer[storeID]
-		allStatistics = h.statisticsAsPeer
-	case byLeader:
-		srcStatistics = h.statisticsAsLeader[storeID]
-		allStatistics = h.statisticsAsLeader
-	}
-
-	var hotRegionTotalCount float64
-	for _, m := range allStatistics {
-		hotRegionTotalCount += float64(m.RegionsStat.Len())
-	}
-
-	avgRegionCount := hotRegionTotalCount / float64(len(allStatistics))
-	// Multiplied by hotRegionLimitFactor to avoid transfer back and forth
-	limit := uint64((float64(srcStatistics.RegionsStat.Len()) - avgRegionCount) * hotRegionLimitFactor)
-	h.limit = maxUint64(1, limit)
+// balanceHotReadRegionScheduler transfers the leaders of read hot regions from
+// the store serving the most hot reads, reads are only served by leaders so
+// it never needs to move peers.
+type balanceHotReadRegionScheduler struct {
+	*baseHotScheduler
 }
 
-func (h *balanceHotRegionScheduler) balanceByLeader(cluster *clusterInfo) (*RegionInfo, *metapb.Peer) {
-	var (
-		maxWrittenBytes        uint64
-		srcStoreID             uint64
-		maxHotStoreRegionCount int
-	)
-
-	// select srcStoreId by leader
-	for storeID, statistics := range h.statisticsAsLeader {
-		if statistics.RegionsStat.Len() < 2 {
-			continue
-		}
-
-		if maxHotStoreRegionCount < statistics.RegionsStat.Len() {
-			maxHotStoreRegionCount = statistics.RegionsStat.Len()
-			maxWrittenBytes = statistics.WrittenBytes
-			srcStoreID = storeID
-			continue
-		}
-
-		if maxHotStoreRegionCount == statistics.RegionsStat.Len() && maxWrittenBytes < statistics.WrittenBytes {
-			maxWrittenBytes = statistics.WrittenBytes
-			srcStoreID = storeID
-		}
+func newBalanceHotReadRegionScheduler(opt ScheduleOptions) *balanceHotReadRegionScheduler {
+	return &balanceHotReadRegionScheduler{
+		baseHotScheduler: newBaseHotScheduler(opt, hotReadFlow),
 	}
-	if srcStoreID == 0 {
-		return nil, nil
-	}
-
-	// select destPeer
-	for _, i := range h.r.Perm(h.statisticsAsLeader[srcStoreID].RegionsStat.Len()) {
-		rs := h.statisticsAsLeader[srcStoreID].RegionsStat[i]
-		srcRegion := cluster.getRegion(rs.RegionID)
-		if len(srcRegion.DownPeers) != 0 || len(srcRegion.PendingPeers) != 0 {
-			continue
-		}
-
-		destPeer := h.selectDestStoreByLeader(srcRegion)
-		if destPeer != nil {
-			h.adjustBalanceLimit(srcStoreID, byLeader)
-			return srcRegion, destPeer
-		}
-	}
-	return nil, nil
 }
 
-func (h *balanceHotRegionScheduler) selectDestStoreByLeader(srcRegion *RegionInfo) *metapb.Peer {
-	sr := h.statisticsAsLeader[srcRegion.Leader.GetStoreId()]
-	srcWrittenBytes := sr.WrittenBytes
-	srcHotRegionsCount := sr.RegionsStat.Len()
-
-	var (
-		destPeer        *metapb.Peer
-		minWrittenBytes uint64 = math.MaxUint64
-	)
-	minRegionsCount := int(math.MaxInt32)
-	for storeID, peer := range srcRegion.GetFollowers() {
-		if s, ok := h.statisticsAsLeader[storeID]; ok {
-			if srcHotRegionsCount-s.RegionsStat.Len() > 1 && minRegionsCount > s.RegionsStat.Len() {
-				destPeer = peer
-				minWrittenBytes = s.WrittenBytes
-				minRegionsCount = s.RegionsStat.Len()
-				continue
-			}
-			if minRegionsCount == s.RegionsStat.Len() && minWrittenBytes > s.WrittenBytes &&
-				uint64(float64(srcWrittenBytes)*hotRegionScheduleFactor) > s.WrittenBytes+2*srcRegion.WrittenBytes {
-				minWrittenBytes = s.WrittenBytes
-				destPeer = peer
-			}
-		} else {
-			destPeer = peer
-			break
-		}
-	}
-	return destPeer
+func (h *balanceHotReadRegionScheduler) GetName() string {
+	return hotReadRegionScheduleName
 }
 
-// StoreHotRegionInfos : used to get human readable description for hot regions.
-type StoreHotRegionInfos struct {
-	AsPeer   map[uint64]*HotRegionsStat `json:"as_peer"`
-	AsLeader map[uint64]*HotRegionsStat `json:"as_leader"`
-}
+func (h *balanceHotReadRegionScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	h.calcScore(cluster)
 
-func (h *balanceHotRegionScheduler) GetStatus() *StoreHotRegionInfos {
-	h.RLock()
-	defer h.RUnlock()
-	asPeer := make(map[uint64]*HotRegionsStat, len(h.statisticsAsPeer))
-	for id, stat := range h.statisticsAsPeer {
-		clone := *stat
-		asPeer[id] = &clone
-	}
-	asLeader := make(map[uint64]*HotRegionsStat, len(h.statisticsAsLeader))
-	for id, stat := range h.statisticsAsLeader {
-		clone := *stat
-		asLeader[id] = &clone
+	// It is paused if the leader schedule limit is 0.
+	if h.opt.GetLeaderScheduleLimit() == 0 {
+		return nil
 	}
-	return &StoreHotRegionInfos{
-		AsPeer:   asPeer,
-		AsLeader: asLeader,
+
+	srcRegion, newLeader := h.balanceByLeader(cluster)
+	if srcRegion != nil {
+		return newPriorityTransferLeader(srcRegion, newLeader)
 	}
+	return nil
 }
@@ -31,27 +31,27 @@ func newTestClusterInfo(cluster *clusterInfo) *testClusterInfo {
 }
 
 func (c *testClusterInfo) setStoreUp(storeID uint64) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.State = metapb.StoreState_Up
 	store.status.LastHeartbeatTS = time.Now()
 	c.putStore(store)
 }
 
 func (c *testClusterInfo) setStoreDown(storeID uint64) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.State = metapb.StoreState_Up
 	store.status.LastHeartbeatTS = time.Time{}
 	c.putStore(store)
 }
 
 func (c *testClusterInfo) setStoreOffline(storeID uint64) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.State = metapb.StoreState_Offline
 	c.putStore(store)
 }
 
 func (c *testClusterInfo) setStoreBusy(storeID uint64, busy bool) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.IsBusy = busy
 	store.status.LastHeartbeatTS = time.Now()
 	c.putStore(store)
@@ -68,6 +68,7 @@ func (c *testClusterInfo) addRegionStore(storeID uint64, regionCount int) {
 	store := newStoreInfo(&metapb.Store{Id: storeID})
 	store.status.LastHeartbeatTS = time.Now()
 	store.status.RegionCount = regionCount
+	store.status.RegionSize = uint64(regionCount) * defaultRegionSize
 	store.status.Capacity = uint64(1024)
 	store.status.Available = store.status.Capacity
 	c.putStore(store)
@@ -75,7 +76,16 @@ func (c *testClusterInfo) addRegionStore(storeID uint64, regionCount int) {
 
 func (c *testClusterInfo) addLabelsStore(storeID uint64, regionCount int, labels map[string]string) {
 	c.addRegionStore(storeID, regionCount)
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
+	for k, v := range labels {
+		store.Labels = append(store.Labels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	c.putStore(store)
+}
+
+func (c *testClusterInfo) addLabelsLeaderStore(storeID uint64, leaderCount int, labels map[string]string) {
+	c.addLeaderStore(storeID, leaderCount)
+	store := c.GetStore(storeID)
 	for k, v := range labels {
 		store.Labels = append(store.Labels, &metapb.StoreLabel{Key: k, Value: v})
 	}
@@ -84,10 +94,10 @@ func (c *testClusterInfo) addLabelsStore(storeID uint64, regionCount int, labels
 
 func (c *testClusterInfo) addLeaderRegion(regionID uint64, leaderID uint64, followerIds ...uint64) {
 	region := &metapb.Region{Id: regionID}
-	leader, _ := c.allocPeer(leaderID)
+	leader, _ := c.AllocPeer(leaderID)
 	region.Peers = []*metapb.Peer{leader}
 	for _, id := range followerIds {
-		peer, _ := c.allocPeer(id)
+		peer, _ := c.AllocPeer(id)
 		region.Peers = append(region.Peers, peer)
 	}
 	c.putRegion(newRegionInfo(region, leader))
@@ -98,7 +108,7 @@ func (c *testClusterInfo) LoadRegion(regionID uint64, followerIds ...uint64) {
 	region := &metapb.Region{Id: regionID}
 	region.Peers = []*metapb.Peer{}
 	for _, id := range followerIds {
-		peer, _ := c.allocPeer(id)
+		peer, _ := c.AllocPeer(id)
 		region.Peers = append(region.Peers, peer)
 	}
 	c.putRegion(newRegionInfo(region, nil))
@@ -106,10 +116,10 @@ func (c *testClusterInfo) LoadRegion(regionID uint64, followerIds ...uint64) {
 
 func (c *testClusterInfo) addLeaderRegionWithWriteInfo(regionID uint64, leaderID uint64, writtenBytes uint64, followerIds ...uint64) {
 	region := &metapb.Region{Id: regionID}
-	leader, _ := c.allocPeer(leaderID)
+	leader, _ := c.AllocPeer(leaderID)
 	region.Peers = []*metapb.Peer{leader}
 	for _, id := range followerIds {
-		peer, _ := c.allocPeer(id)
+		peer, _ := c.AllocPeer(id)
 		region.Peers = append(region.Peers, peer)
 	}
 	r := newRegionInfo(region, leader)
@@ -118,26 +128,53 @@ func (c *testClusterInfo) addLeaderRegionWithWriteInfo(regionID uint64, leaderID
 	c.putRegion(r)
 }
 
+func (c *testClusterInfo) addLeaderRegionWithReadInfo(regionID uint64, leaderID uint64, readBytes uint64, followerIds ...uint64) {
+	region := &metapb.Region{Id: regionID}
+	leader, _ := c.AllocPeer(leaderID)
+	region.Peers = []*metapb.Peer{leader}
+	for _, id := range followerIds {
+		peer, _ := c.AllocPeer(id)
+		region.Peers = append(region.Peers, peer)
+	}
+	r := newRegionInfo(region, leader)
+	r.ReadBytes = readBytes
+	c.updateReadStatus(r)
+	c.putRegion(r)
+}
+
 func (c *testClusterInfo) updateLeaderCount(storeID uint64, leaderCount int) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.LeaderCount = leaderCount
 	c.putStore(store)
 }
 
+func (c *testClusterInfo) updateLeaderSize(storeID uint64, leaderSize uint64) {
+	store := c.GetStore(storeID)
+	store.status.LeaderSize = leaderSize
+	c.putStore(store)
+}
+
 func (c *testClusterInfo) updateRegionCount(storeID uint64, regionCount int) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.RegionCount = regionCount
+	store.status.RegionSize = uint64(regionCount) * defaultRegionSize
 	c.putStore(store)
 }
 
 func (c *testClusterInfo) updateSnapshotCount(storeID uint64, snapshotCount int) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.ApplyingSnapCount = uint32(snapshotCount)
 	c.putStore(store)
 }
 
+func (c *testClusterInfo) updatePendingPeerCount(storeID uint64, pendingPeerCount int) {
+	store := c.GetStore(storeID)
+	store.status.PendingPeerCount = pendingPeerCount
+	c.putStore(store)
+}
+
 func (c *testClusterInfo) updateStorageRatio(storeID uint64, usedRatio, availableRatio float64) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.Capacity = uint64(1024)
 	store.status.UsedSize = uint64(float64(store.status.Capacity) * usedRatio)
 	store.status.Available = uint64(float64(store.status.Capacity) * availableRatio)
@@ -145,7 +182,7 @@ func (c *testClusterInfo) updateStorageRatio(storeID uint64, usedRatio, availabl
 }
 
 func (c *testClusterInfo) updateStorageWrittenBytes(storeID uint64, BytesWritten uint64) {
-	store := c.getStore(storeID)
+	store := c.GetStore(storeID)
 	store.status.BytesWritten = BytesWritten
 	c.putStore(store)
 }
@@ -200,24 +237,58 @@ func (s *testBalanceSpeedSuite) TestBalanceSpeed(c *C) {
 func (s *testBalanceSpeedSuite) testBalanceSpeed(c *C, tests []testBalanceSpeedCase, capaGB uint64) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	region := newRegionInfo(&metapb.Region{Id: 1}, nil)
 
 	for _, t := range tests {
 		tc.addLeaderStore(1, int(t.sourceCount))
 		tc.addLeaderStore(2, int(t.targetCount))
-		source := cluster.getStore(1)
-		target := cluster.getStore(2)
-		c.Assert(shouldBalance(source, target, LeaderKind), Equals, t.expectedResult)
+		source := cluster.GetStore(1)
+		target := cluster.GetStore(2)
+		c.Assert(shouldBalance(source, target, region, LeaderKind, opt), Equals, t.expectedResult)
 	}
 
 	for _, t := range tests {
 		tc.addRegionStore(1, int(t.sourceCount))
 		tc.addRegionStore(2, int(t.targetCount))
-		source := cluster.getStore(1)
-		target := cluster.getStore(2)
-		c.Assert(shouldBalance(source, target, RegionKind), Equals, t.expectedResult)
+		source := cluster.GetStore(1)
+		target := cluster.GetStore(2)
+		c.Assert(shouldBalance(source, target, region, RegionKind, opt), Equals, t.expectedResult)
 	}
 }
 
+func (s *testBalanceSpeedSuite) TestTolerantSizeRatio(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	cfg, opt := newTestScheduleConfig()
+
+	// Stores 1 and 2 have 100 and 88 regions of 1MB.
+	tc.addRegionStore(1, 100)
+	tc.addRegionStore(2, 88)
+	source, target := cluster.GetStore(1), cluster.GetStore(2)
+	region := newRegionInfo(&metapb.Region{Id: 1}, nil)
+	c.Assert(shouldBalance(source, target, region, RegionKind, opt), IsTrue)
+
+	// Moving a 8MB region makes store 2 larger than store 1, then it would
+	// be moved back.
+	region.ApproximateSize = 8 * defaultRegionSize
+	c.Assert(shouldBalance(source, target, region, RegionKind, opt), IsFalse)
+	cfg.TolerantSizeRatio = 0.5
+	c.Assert(shouldBalance(source, target, region, RegionKind, opt), IsTrue)
+	cfg.TolerantSizeRatio = defaultTolerantSizeRatio
+
+	// The leaders are balanced by size.
+	cfg.LeaderSchedulePolicy = LeaderSchedulePolicySize
+	tc.addLeaderStore(1, 100)
+	tc.addLeaderStore(2, 50)
+	tc.updateLeaderSize(1, 100*defaultRegionSize)
+	tc.updateLeaderSize(2, 80*defaultRegionSize)
+	source, target = cluster.GetStore(1), cluster.GetStore(2)
+	c.Assert(shouldBalance(source, target, region, LeaderKind, opt), IsTrue)
+	region.ApproximateSize = 12 * defaultRegionSize
+	c.Assert(shouldBalance(source, target, region, LeaderKind, opt), IsFalse)
+}
+
 func (s *testBalanceSpeedSuite) TestBalanceLimit(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
@@ -249,7 +320,7 @@ func (s *testBalanceLeaderSchedulerSuite) SetUpTest(c *C) {
 }
 
 func (s *testBalanceLeaderSchedulerSuite) schedule() Operator {
-	return s.lb.Schedule(s.cluster)
+	return s.lb.Schedule(s.cluster, nil)
 }
 
 func (s *testBalanceLeaderSchedulerSuite) TestBalanceLimit(c *C) {
@@ -289,6 +360,111 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceLimit(c *C) {
 	c.Check(s.schedule(), NotNil)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestRejectLeader(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.LabelProperty = LabelPropertyConfig{
+		RejectLeader: {{Key: "zone", Value: "z2"}},
+	}
+	lb := newBalanceLeaderScheduler(opt)
+
+	// Stores:     1    2    3
+	// Leaders:    10   0    5
+	// Region1:    L    F    F
+	s.tc.addLabelsLeaderStore(1, 10, map[string]string{"zone": "z1"})
+	s.tc.addLabelsLeaderStore(2, 0, map[string]string{"zone": "z2"})
+	s.tc.addLabelsLeaderStore(3, 5, map[string]string{"zone": "z3"})
+	s.tc.addLeaderRegion(1, 1, 2, 3)
+	// Store 2 rejects leaders, so the leader is transferred to store 3.
+	checkTransferLeader(c, lb.Schedule(s.cluster, nil), 1, 3)
+
+	// No leader is transferred into store 2.
+	s.tc.updateLeaderCount(1, 5)
+	s.tc.updateLeaderCount(3, 10)
+	s.tc.addLeaderRegion(1, 3, 1, 2)
+	checkTransferLeader(c, lb.Schedule(s.cluster, nil), 3, 1)
+	s.tc.updateLeaderCount(1, 10)
+	c.Assert(lb.Schedule(s.cluster, nil), IsNil)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestOpInfluence(c *C) {
+	// Stores:     1    2    3
+	// Leaders:    10   0    2
+	// Region1:    L    F    F
+	s.tc.addLeaderStore(1, 10)
+	s.tc.addLeaderStore(2, 0)
+	s.tc.addLeaderStore(3, 2)
+	s.tc.addLeaderRegion(1, 1, 2, 3)
+	checkTransferLeader(c, s.schedule(), 1, 2)
+
+	// Store 2 is receiving 3 leaders.
+	checkTransferLeader(c, s.lb.Schedule(s.cluster, OpInfluence{2: {leaderCount: 3}}), 1, 3)
+	// The stores are balanced once the running operators finish.
+	influence := OpInfluence{
+		1: {leaderCount: -6},
+		2: {leaderCount: 4},
+		3: {leaderCount: 2},
+	}
+	c.Assert(s.lb.Schedule(s.cluster, influence), IsNil)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestHighSpace(c *C) {
+	// Stores:     1    2    3
+	// Leaders:    10   0    5
+	// Region1:    L    F    F
+	s.tc.addLeaderStore(1, 10)
+	s.tc.addLeaderStore(2, 0)
+	s.tc.addLeaderStore(3, 5)
+	s.tc.addLeaderRegion(1, 1, 2, 3)
+	checkTransferLeader(c, s.schedule(), 1, 2)
+
+	// Store 2 is over high-space-ratio.
+	s.tc.updateStorageRatio(2, 0.85, 0.15)
+	checkTransferLeader(c, s.schedule(), 1, 3)
+	s.tc.updateStorageRatio(2, 0.5, 0.5)
+	checkTransferLeader(c, s.schedule(), 1, 2)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestLeaderSchedulePolicy(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	lb := newBalanceLeaderScheduler(opt)
+
+	// Stores:     1      2     3
+	// Leaders:    10     10    10
+	// Size:       100MB  10MB  10MB
+	// Region1:    L      F     F
+	for id := uint64(1); id <= 3; id++ {
+		s.tc.addLeaderStore(id, 10)
+		s.tc.updateLeaderSize(id, 10*defaultRegionSize)
+	}
+	s.tc.updateLeaderSize(1, 100*defaultRegionSize)
+	s.tc.addLeaderRegion(1, 1, 2, 3)
+
+	// The leader counts are balanced.
+	c.Assert(cfg.LeaderSchedulePolicy, Equals, LeaderSchedulePolicyCount)
+	c.Check(lb.Schedule(s.cluster, nil), IsNil)
+
+	// The leader sizes are not balanced.
+	cfg.LeaderSchedulePolicy = LeaderSchedulePolicySize
+	checkTransferLeaderFrom(c, lb.Schedule(s.cluster, nil), 1)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestStoreWeight(c *C) {
+	// Stores:     1    2    3
+	// Leaders:    10   10   9
+	// Region1:    F    L    F
+	s.tc.addLeaderStore(1, 10)
+	s.tc.addLeaderStore(2, 10)
+	s.tc.addLeaderStore(3, 9)
+	s.tc.addLeaderRegion(1, 2, 1, 3)
+	c.Check(s.schedule(), IsNil)
+
+	// The leader score of store 1 is 10/4.
+	settings := newStoreSettings()
+	settings.LeaderWeight = 4
+	c.Assert(s.cluster.putStoreSettings(1, settings), IsNil)
+	checkTransferLeader(c, s.schedule(), 2, 1)
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestBalanceFilter(c *C) {
 	// Stores:     1    2    3    4
 	// Leaders:    1    2    3   10
@@ -337,6 +513,30 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceSelector(c *C) {
 	checkTransferLeader(c, s.schedule(), 3, 1)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestNamespace(c *C) {
+	// Stores:     1    2    3    4    5    6
+	// Namespace:  -    -    -   ns1  ns1  ns1
+	// Leaders:   10   10   10    0    0    0
+	// Region1:    L    F    -    F    -    -
+	s.lb.opt.getNamespaces().setNamespace(&Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{4, 5, 6}})
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		s.tc.addLeaderStore(storeID, 10)
+	}
+	for storeID := uint64(4); storeID <= 6; storeID++ {
+		s.tc.addLeaderStore(storeID, 0)
+	}
+	s.tc.putRegion(newTableRegion(1, 2, 1, 2, 4))
+	// The stores are balanced in their namespaces.
+	c.Assert(s.schedule(), IsNil)
+
+	// Stores:     1    2    3    4    5    6
+	// Leaders:   10   10   10   10    0    0
+	// Region2:    -    -    -    L    F    F
+	s.tc.updateLeaderCount(4, 10)
+	s.tc.putRegion(newTableRegion(2, 1, 4, 5, 6))
+	checkTransferLeaderFrom(c, s.schedule(), 4)
+}
+
 var _ = Suite(&testBalanceRegionSchedulerSuite{})
 
 type testBalanceRegionSchedulerSuite struct{}
@@ -357,24 +557,173 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance(c *C) {
 	tc.addRegionStore(4, 9)
 	// Add region 1 with leader in store 4.
 	tc.addLeaderRegion(1, 4)
-	checkTransferPeer(c, sb.Schedule(cluster), 4, 1)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 4, 1)
 
 	// Test stateFilter.
 	tc.setStoreOffline(1)
 	// Test min balance diff (>=2).
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
 	// 9 - 6 >= 2
 	tc.updateRegionCount(2, 6)
 	sb.cache.delete(4)
 	// When store 1 is offline, it will be filtered,
 	// store 2 becomes the store with least regions.
-	checkTransferPeer(c, sb.Schedule(cluster), 4, 2)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 4, 2)
 
 	// Test MaxReplicas.
 	opt.SetMaxReplicas(3)
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
+	opt.SetMaxReplicas(1)
+	c.Assert(sb.Schedule(cluster, nil), NotNil)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestNamespace(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3)
+	sb := newBalanceRegionScheduler(opt)
+	opt.namespaces.setNamespace(&Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{4, 5, 6, 7}})
+
+	// Stores 1, 2, 3 have the most regions but their regions can't be moved
+	// out of the default namespace, the stores of ns1 are balanced.
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		tc.addRegionStore(storeID, 100)
+	}
+	tc.addRegionStore(4, 10)
+	tc.addRegionStore(5, 1)
+	tc.addRegionStore(6, 1)
+	tc.addRegionStore(7, 0)
+	tc.putRegion(newTableRegion(1, 2, 1, 2, 3))
+	tc.putRegion(newTableRegion(2, 1, 4, 5, 6))
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 4, 7)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestOpInfluence(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	sb := newBalanceRegionScheduler(opt)
+
 	opt.SetMaxReplicas(1)
-	c.Assert(sb.Schedule(cluster), NotNil)
+
+	tc.addRegionStore(1, 6)
+	tc.addRegionStore(2, 8)
+	tc.addRegionStore(3, 8)
+	tc.addRegionStore(4, 9)
+	tc.addLeaderRegion(1, 4)
+	op := sb.Schedule(cluster, nil)
+	checkTransferPeer(c, op, 4, 1)
+
+	// The stores are balanced once the running operator finishes.
+	c.Assert(sb.Schedule(cluster, newOpInfluence([]Operator{op})), IsNil)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestStoreScore(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	sb := newBalanceRegionScheduler(opt)
+
+	opt.SetMaxReplicas(1)
+
+	// Store 2 has 4 times the capacity of store 1.
+	tc.addRegionStore(1, 8)
+	tc.addRegionStore(2, 24)
+	store := cluster.GetStore(2)
+	store.status.Capacity = 4096
+	store.status.Available = 4096
+	tc.putStore(store)
+	tc.addLeaderRegion(1, 1)
+	tc.addLeaderRegion(2, 2)
+
+	// Store 1 is fuller by the used ratio of the capacity.
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 2)
+
+	// Store 2 has more regions relative to the square root of the capacity.
+	cfg.StoreScore.CapacityWeight = 0.5
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 2, 1)
+
+	// The leaders and the written bytes are added to the score.
+	store = cluster.GetStore(1)
+	store.status.LeaderCount = 1
+	store.status.BytesWritten = 1024
+	weights := opt.GetStoreScore()
+	score := store.regionScore(weights)
+	weights.LeaderCountWeight = 2
+	c.Assert(store.regionScore(weights), Equals, score+2*defaultRegionSize/32)
+	weights.LeaderCountWeight = 0
+	weights.FlowWeight = 1
+	c.Assert(store.regionScore(weights), Equals, score+1024/32)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestLowSpace(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	sb := newBalanceRegionScheduler(opt)
+
+	opt.SetMaxReplicas(1)
+
+	// The stores are balanced.
+	tc.addRegionStore(1, 10)
+	tc.addRegionStore(2, 10)
+	tc.addRegionStore(3, 11)
+	tc.addLeaderRegion(1, 1)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
+
+	// Store 1 is over high-space-ratio but not low-space-ratio.
+	tc.updateStorageRatio(1, 0.85, 0.15)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
+
+	// The peers are moved out of store 1 after it is low on space, to the
+	// stores under high-space-ratio.
+	tc.updateStorageRatio(1, 0.95, 0.05)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 2)
+	tc.updateStorageRatio(2, 0.85, 0.15)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 3)
+
+	cfg.LowSpaceRatio = 0.99
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestBalanceBySize(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	sb := newBalanceRegionScheduler(opt)
+
+	opt.SetMaxReplicas(1)
+
+	// Stores 1 and 2 have the same region count, but the regions of store 2
+	// are much larger.
+	tc.addRegionStore(1, 0)
+	tc.addRegionStore(2, 0)
+	for i := uint64(1); i <= 10; i++ {
+		region := &metapb.Region{Id: i}
+		leader, _ := tc.AllocPeer(1)
+		region.Peers = []*metapb.Peer{leader}
+		r := newRegionInfo(region, leader)
+		r.ApproximateSize = defaultRegionSize
+		tc.putRegion(r)
+	}
+	for i := uint64(11); i <= 20; i++ {
+		region := &metapb.Region{Id: i}
+		leader, _ := tc.AllocPeer(2)
+		region.Peers = []*metapb.Peer{leader}
+		r := newRegionInfo(region, leader)
+		r.ApproximateSize = 10 * defaultRegionSize
+		tc.putRegion(r)
+	}
+	tc.updateStoreStatus(1)
+	tc.updateStoreStatus(2)
+	c.Assert(tc.GetStore(1).regionCount(), Equals, tc.GetStore(2).regionCount())
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 2, 1)
 }
 
 func (s *testBalanceRegionSchedulerSuite) TestReplicas3(c *C) {
@@ -394,51 +743,51 @@ func (s *testBalanceRegionSchedulerSuite) TestReplicas3(c *C) {
 	tc.addLeaderRegion(1, 1, 2, 3)
 	// This schedule try to replace peer in store 1, but we have no other stores,
 	// so store 1 will be set in the cache and skipped next schedule.
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
 	c.Assert(sb.cache.get(1), IsTrue)
 
 	// Store 4 has smaller region score than store 2.
 	tc.addLabelsStore(4, 2, map[string]string{"zone": "z1", "rack": "r2", "host": "h1"})
-	checkTransferPeer(c, sb.Schedule(cluster), 2, 4)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 2, 4)
 
 	// Store 5 has smaller region score than store 1.
 	tc.addLabelsStore(5, 2, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
 	sb.cache.delete(1) // Delete store 1 from cache, or it will be skipped.
-	checkTransferPeer(c, sb.Schedule(cluster), 1, 5)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 5)
 
 	// Store 6 has smaller region score than store 5.
 	tc.addLabelsStore(6, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
-	checkTransferPeer(c, sb.Schedule(cluster), 1, 6)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 6)
 
 	// Store 7 has the same region score with store 6, but in a different host.
 	tc.addLabelsStore(7, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h2"})
-	checkTransferPeer(c, sb.Schedule(cluster), 1, 7)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 7)
 
 	// If store 7 is not available, we wait.
 	tc.setStoreDown(7)
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
 	c.Assert(sb.cache.get(1), IsTrue)
 	tc.setStoreUp(7)
-	checkTransferPeer(c, sb.Schedule(cluster), 2, 7)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 2, 7)
 	sb.cache.delete(1)
-	checkTransferPeer(c, sb.Schedule(cluster), 1, 7)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 7)
 
 	// Store 8 has smaller region score than store 7, but the distinct score decrease.
 	tc.addLabelsStore(8, 1, map[string]string{"zone": "z1", "rack": "r2", "host": "h3"})
-	checkTransferPeer(c, sb.Schedule(cluster), 1, 7)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 1, 7)
 
 	// Take down 4,5,6,7
 	tc.setStoreDown(4)
 	tc.setStoreDown(5)
 	tc.setStoreDown(6)
 	tc.setStoreDown(7)
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
 	c.Assert(sb.cache.get(1), IsTrue)
 	sb.cache.delete(1)
 
 	// Store 9 has different zone with other stores but larger region score than store 1.
 	tc.addLabelsStore(9, 9, map[string]string{"zone": "z2", "rack": "r1", "host": "h1"})
-	c.Assert(sb.Schedule(cluster), IsNil)
+	c.Assert(sb.Schedule(cluster, nil), IsNil)
 }
 
 func (s *testBalanceRegionSchedulerSuite) TestReplicas5(c *C) {
@@ -460,22 +809,22 @@ func (s *testBalanceRegionSchedulerSuite) TestReplicas5(c *C) {
 
 	// Store 6 has smaller region score.
 	tc.addLabelsStore(6, 1, map[string]string{"zone": "z5", "rack": "r2", "host": "h1"})
-	checkTransferPeer(c, sb.Schedule(cluster), 5, 6)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 5, 6)
 
 	// Store 7 has smaller region score and higher distinct score.
 	tc.addLabelsStore(7, 5, map[string]string{"zone": "z6", "rack": "r1", "host": "h1"})
-	checkTransferPeer(c, sb.Schedule(cluster), 5, 7)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 5, 7)
 
 	// Store 1 has smaller region score and higher distinct score.
 	tc.addLeaderRegion(1, 2, 3, 4, 5, 6)
-	checkTransferPeer(c, sb.Schedule(cluster), 5, 1)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 5, 1)
 
 	// Store 6 has smaller region score and higher distinct score.
 	tc.addLabelsStore(11, 9, map[string]string{"zone": "z1", "rack": "r2", "host": "h1"})
 	tc.addLabelsStore(12, 8, map[string]string{"zone": "z2", "rack": "r2", "host": "h1"})
 	tc.addLabelsStore(13, 7, map[string]string{"zone": "z3", "rack": "r2", "host": "h1"})
 	tc.addLeaderRegion(1, 2, 3, 11, 12, 13)
-	checkTransferPeer(c, sb.Schedule(cluster), 11, 6)
+	checkTransferPeer(c, sb.Schedule(cluster, nil), 11, 6)
 }
 
 var _ = Suite(&testReplicaCheckerSuite{})
@@ -500,7 +849,7 @@ func (s *testReplicaCheckerSuite) TestBasic(c *C) {
 	tc.addLeaderRegion(1, 1, 2)
 
 	// Region has 2 peers, we need to add a new peer.
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 	checkAddPeer(c, rc.Check(region), 4)
 
 	// Test healthFilter.
@@ -517,36 +866,67 @@ func (s *testReplicaCheckerSuite) TestBasic(c *C) {
 	// If snapshotCount < MaxSnapshotCount, we can add peer again.
 	tc.updateSnapshotCount(4, 1)
 	checkAddPeer(c, rc.Check(region), 4)
+	// The snapshot limit of the store replaces MaxSnapshotCount.
+	settings := newStoreSettings()
+	settings.SnapshotLimit = 3
+	c.Assert(cluster.putStoreSettings(4, settings), IsNil)
+	tc.updateSnapshotCount(4, 3)
+	checkAddPeer(c, rc.Check(region), 4)
+	c.Assert(cluster.putStoreSettings(4, newStoreSettings()), IsNil)
+	tc.updateSnapshotCount(4, 1)
+
+	// Test pendingPeerCountFilter.
+	// If pendingPeerCount > MaxPendingPeerCount, we add to store 3.
+	tc.updatePendingPeerCount(4, 17)
+	checkAddPeer(c, rc.Check(region), 3)
+	// If pendingPeerCount < MaxPendingPeerCount, we can add peer again.
+	tc.updatePendingPeerCount(4, 0)
+	checkAddPeer(c, rc.Check(region), 4)
 
 	// Test storageThresholdFilter.
-	// If availableRatio < storageAvailableRatioThreshold(0.2), we can not add peer.
+	// If usedRatio > HighSpaceRatio(0.8), we can not add peer.
 	tc.updateStorageRatio(4, 0.9, 0.1)
 	checkAddPeer(c, rc.Check(region), 3)
 	tc.updateStorageRatio(4, 0.5, 0.1)
 	checkAddPeer(c, rc.Check(region), 3)
-	// If availableRatio > storageAvailableRatioThreshold(0.2), we can add peer again.
+	// If usedRatio < HighSpaceRatio(0.8), we can add peer again.
 	tc.updateStorageRatio(4, 0.7, 0.3)
 	checkAddPeer(c, rc.Check(region), 4)
 
 	// Add peer in store 4, and we have enough replicas.
-	peer4, _ := cluster.allocPeer(4)
+	peer4, _ := cluster.AllocPeer(4)
 	region.Peers = append(region.Peers, peer4)
 	c.Assert(rc.Check(region), IsNil)
 
 	// Add peer in store 3, and we have redundant replicas.
-	peer3, _ := cluster.allocPeer(3)
+	peer3, _ := cluster.AllocPeer(3)
 	region.Peers = append(region.Peers, peer3)
 	checkRemovePeer(c, rc.Check(region), 1)
 	region.RemoveStorePeer(1)
 
-	// Peer in store 2 is down, remove it.
+	// Peer in store 2 is down, replace it with store 1.
 	tc.setStoreDown(2)
 	downPeer := &pdpb.PeerStats{
 		Peer:        region.GetStorePeer(2),
 		DownSeconds: 24 * 60 * 60,
 	}
 	region.DownPeers = append(region.DownPeers, downPeer)
+	checkTransferPeer(c, rc.Check(region), 2, 1)
+
+	// If there is no store to replace it, remove it.
+	tc.setStoreDown(1)
+	checkRemovePeer(c, rc.Check(region), 2)
+	tc.setStoreUp(1)
+
+	// The region has redundant replicas, remove the down peer.
+	peer1, _ := cluster.AllocPeer(1)
+	region.Peers = append(region.Peers, peer1)
 	checkRemovePeer(c, rc.Check(region), 2)
+	region.RemoveStorePeer(1)
+
+	// The peer is not down long enough.
+	region.DownPeers[0].DownSeconds = 60
+	c.Assert(rc.Check(region), IsNil)
 	region.DownPeers = nil
 	c.Assert(rc.Check(region), IsNil)
 
@@ -568,11 +948,39 @@ func (s *testReplicaCheckerSuite) TestLostStore(c *C) {
 	// This happens only in recovering the PD cluster
 	// should not panic
 	tc.addLeaderRegion(1, 1, 2, 3)
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 	op := rc.Check(region)
 	c.Assert(op, IsNil)
 }
 
+func (s *testReplicaCheckerSuite) TestDownStore(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	cfg, opt := newTestScheduleConfig()
+	rc := newReplicaChecker(opt, cluster)
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 1)
+	tc.addRegionStore(4, 1)
+	tc.addLeaderRegion(1, 1, 2, 3)
+	region := cluster.GetRegion(1)
+	c.Assert(rc.Check(region), IsNil)
+
+	// Store 3 stops sending heartbeats, its peer is replaced after
+	// max-store-down-time though the leader doesn't report it.
+	store := cluster.GetStore(3)
+	store.status.LastHeartbeatTS = time.Now().Add(-time.Minute * 10)
+	c.Assert(cluster.putStore(store), IsNil)
+	c.Assert(rc.Check(region), IsNil)
+	cfg.MaxStoreDownTime.Duration = time.Minute * 5
+	checkTransferPeer(c, rc.Check(region), 3, 4)
+
+	// A store that has sent no heartbeat is not known to be down.
+	tc.setStoreDown(3)
+	c.Assert(rc.Check(region), IsNil)
+}
+
 func (s *testReplicaCheckerSuite) TestOffline(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
@@ -588,20 +996,20 @@ func (s *testReplicaCheckerSuite) TestOffline(c *C) {
 	tc.addLabelsStore(4, 4, map[string]string{"zone": "z3", "rack": "r2", "host": "h1"})
 
 	tc.addLeaderRegion(1, 1)
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 
 	// Store 2 has different zone and smallest region score.
 	checkAddPeer(c, rc.Check(region), 2)
-	peer2, _ := cluster.allocPeer(2)
+	peer2, _ := cluster.AllocPeer(2)
 	region.Peers = append(region.Peers, peer2)
 
 	// Store 3 has different zone and smallest region score.
 	checkAddPeer(c, rc.Check(region), 3)
-	peer3, _ := cluster.allocPeer(3)
+	peer3, _ := cluster.AllocPeer(3)
 	region.Peers = append(region.Peers, peer3)
 
 	// Store 4 has the same zone with store 3 and larger region score.
-	peer4, _ := cluster.allocPeer(4)
+	peer4, _ := cluster.AllocPeer(4)
 	region.Peers = append(region.Peers, peer4)
 	checkRemovePeer(c, rc.Check(region), 4)
 
@@ -642,9 +1050,9 @@ func (s *testReplicaCheckerSuite) TestDistinctScore(c *C) {
 
 	// We need 3 replicas.
 	tc.addLeaderRegion(1, 1)
-	region := tc.getRegion(1)
+	region := tc.GetRegion(1)
 	checkAddPeer(c, rc.Check(region), 2)
-	peer2, _ := cluster.allocPeer(2)
+	peer2, _ := cluster.AllocPeer(2)
 	region.Peers = append(region.Peers, peer2)
 
 	// Store 1,2,3 have the same zone, rack, and host.
@@ -674,12 +1082,12 @@ func (s *testReplicaCheckerSuite) TestDistinctScore(c *C) {
 	checkAddPeer(c, rc.Check(region), 7)
 
 	// Add peer to store 7.
-	peer7, _ := cluster.allocPeer(7)
+	peer7, _ := cluster.AllocPeer(7)
 	region.Peers = append(region.Peers, peer7)
 
 	// Replace peer in store 1 with store 6 because it has a different rack.
 	checkTransferPeer(c, rc.Check(region), 1, 6)
-	peer6, _ := cluster.allocPeer(6)
+	peer6, _ := cluster.AllocPeer(6)
 	region.Peers = append(region.Peers, peer6)
 	checkRemovePeer(c, rc.Check(region), 1)
 	region.RemoveStorePeer(1)
@@ -701,13 +1109,143 @@ func (s *testReplicaCheckerSuite) TestDistinctScore(c *C) {
 	// So replace peer in store 2 with store 10.
 	tc.addLabelsStore(10, 1, map[string]string{"zone": "z3", "rack": "r1", "host": "h1"})
 	checkTransferPeer(c, rc.Check(region), 2, 10)
-	peer10, _ := cluster.allocPeer(10)
+	// Don't replace peers while the region has pending peers.
+	region.PendingPeers = []*metapb.Peer{peer6}
+	c.Assert(rc.Check(region), IsNil)
+	region.PendingPeers = nil
+	peer10, _ := cluster.AllocPeer(10)
 	region.Peers = append(region.Peers, peer10)
 	checkRemovePeer(c, rc.Check(region), 2)
 	region.RemoveStorePeer(2)
 	c.Assert(rc.Check(region), IsNil)
 }
 
+func (s *testReplicaCheckerSuite) TestPlacementRules(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	rc := newReplicaChecker(opt, cluster)
+
+	tc.addLabelsStore(1, 1, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(2, 2, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(3, 3, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(4, 4, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(5, 5, map[string]string{"zone": "z3"})
+	tc.addLeaderRegion(1, 1, 3, 5)
+	region := tc.GetRegion(1)
+
+	// 3 replicas satisfy max-replicas.
+	c.Assert(rc.Check(region), IsNil)
+
+	// Place 2 replicas in z1 and 2 in z2, the replica in z3 is moved to z1.
+	z1 := LabelConstraint{Key: "zone", Op: LabelConstraintIn, Values: []string{"z1"}}
+	z2 := LabelConstraint{Key: "zone", Op: LabelConstraintIn, Values: []string{"z2"}}
+	opt.rules.setRule(newTestRule("z1", "", "", 2, z1))
+	opt.rules.setRule(newTestRule("z2", "", "", 2, z2))
+	checkTransferPeer(c, rc.Check(region), 5, 2)
+	region.RemoveStorePeer(5)
+	peer2, _ := cluster.AllocPeer(2)
+	region.Peers = append(region.Peers, peer2)
+
+	// Add the missing replica in z2.
+	checkAddPeer(c, rc.Check(region), 4)
+	peer4, _ := cluster.AllocPeer(4)
+	region.Peers = append(region.Peers, peer4)
+	c.Assert(rc.Check(region), IsNil)
+
+	// Remove the replica not needed by any rule.
+	opt.rules.setRule(newTestRule("z2", "", "", 1, z2))
+	checkRemovePeer(c, rc.Check(region), 4)
+
+	// The replacement of an offline replica matches its rule.
+	opt.rules.setRule(newTestRule("z2", "", "", 2, z2))
+	tc.addLabelsStore(6, 6, map[string]string{"zone": "z2"})
+	tc.setStoreOffline(3)
+	checkTransferPeer(c, rc.Check(region), 3, 6)
+
+	// Regions across the boundary of the rules are placed by max-replicas.
+	opt.rules.deleteRule("z1")
+	opt.rules.deleteRule("z2")
+	opt.rules.setRule(newTestRule("t", "7480", "7490", 1))
+	tc.setStoreUp(3)
+	checkRemovePeer(c, rc.Check(region), 4)
+
+	// The voters are placed by max-replicas if no rule places voters.
+	learner := newTestRule("learner", "", "", 1)
+	learner.Role = "learner"
+	opt.rules.setRule(learner)
+	region.RemoveStorePeer(4)
+	c.Assert(rc.Check(region), IsNil)
+}
+
+func (s *testReplicaCheckerSuite) TestEngineRules(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	rc := newReplicaChecker(opt, cluster)
+
+	tc.addLabelsStore(1, 1, map[string]string{})
+	tc.addLabelsStore(2, 1, map[string]string{})
+	// The store labeled with an ordinary engine is not special.
+	tc.addLabelsStore(3, 3, map[string]string{EngineLabelKey: "tikv"})
+	tc.addLabelsStore(4, 0, map[string]string{EngineLabelKey: "columnar"})
+	tc.addLeaderRegion(1, 1, 2)
+	region := tc.GetRegion(1)
+	c.Assert(tc.GetStore(3).isSpecialEngine(), IsFalse)
+	c.Assert(tc.GetStore(4).isSpecialEngine(), IsTrue)
+
+	// The missing replica is not added to the store of a special engine.
+	checkAddPeer(c, rc.Check(region), 3)
+	peer3, _ := cluster.AllocPeer(3)
+	region.Peers = append(region.Peers, peer3)
+	c.Assert(rc.Check(region), IsNil)
+
+	// The rules not targeting the engine never place replicas to it.
+	opt.rules.setRule(newTestRule("all", "", "", 4))
+	c.Assert(rc.Check(region), IsNil)
+
+	// A rule targeting the engine adds a replica to it.
+	opt.rules.setRule(newTestRule("all", "", "", 3))
+	opt.rules.setRule(newTestRule("columnar", "", "", 1, LabelConstraint{Key: EngineLabelKey, Op: LabelConstraintIn, Values: []string{"columnar"}}))
+	checkAddPeer(c, rc.Check(region), 4)
+	peer4, _ := cluster.AllocPeer(4)
+	region.Peers = append(region.Peers, peer4)
+	c.Assert(rc.Check(region), IsNil)
+}
+
+func (s *testReplicaCheckerSuite) TestNamespace(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	rc := newReplicaChecker(opt, cluster)
+
+	for storeID := uint64(1); storeID <= 6; storeID++ {
+		tc.addRegionStore(storeID, int(storeID))
+	}
+	opt.namespaces.setNamespace(&Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{4, 5, 6}})
+
+	// Add replicas in the stores of the namespace.
+	region := newTableRegion(1, 1, 4, 5)
+	tc.putRegion(region)
+	checkAddPeer(c, rc.Check(region), 6)
+
+	// Other regions are not placed in the namespace.
+	region = newTableRegion(2, 2, 1, 2)
+	tc.putRegion(region)
+	checkAddPeer(c, rc.Check(region), 3)
+
+	// Move the replicas out of the stores not in the namespace.
+	region = newTableRegion(3, 1, 4, 5, 1)
+	tc.putRegion(region)
+	checkTransferPeer(c, rc.Check(region), 1, 6)
+	region = newTableRegion(4, 2, 1, 2, 4)
+	tc.putRegion(region)
+	checkTransferPeer(c, rc.Check(region), 4, 3)
+}
+
 func (s *testReplicaCheckerSuite) TestDistinctScore2(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
@@ -725,19 +1263,43 @@ func (s *testReplicaCheckerSuite) TestDistinctScore2(c *C) {
 	tc.addLabelsStore(6, 1, map[string]string{"zone": "z3", "host": "h1"})
 
 	tc.addLeaderRegion(1, 1, 2, 4)
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 
 	checkAddPeer(c, rc.Check(region), 6)
-	peer6, _ := cluster.allocPeer(6)
+	peer6, _ := cluster.AllocPeer(6)
 	region.Peers = append(region.Peers, peer6)
 
 	checkAddPeer(c, rc.Check(region), 5)
-	peer5, _ := cluster.allocPeer(5)
+	peer5, _ := cluster.AllocPeer(5)
 	region.Peers = append(region.Peers, peer5)
 
 	c.Assert(rc.Check(region), IsNil)
 }
 
+func (s *testReplicaCheckerSuite) TestDistinctScoreMissingLabels(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3, "zone", "host")
+
+	rc := newReplicaChecker(opt, cluster)
+
+	tc.addLabelsStore(1, 1, map[string]string{"zone": "z1", "host": "h1"})
+	tc.addLabelsStore(2, 2, map[string]string{"zone": "z1", "host": "h2"})
+	tc.addLabelsStore(3, 1, map[string]string{"zone": "z2", "host": "h1"})
+	tc.addLabelsStore(4, 1, map[string]string{"zone": "z3"})
+
+	// Store 4 has no host label, but it is the only one in zone z3.
+	tc.addLeaderRegion(1, 1, 3)
+	checkAddPeer(c, rc.Check(cluster.GetRegion(1)), 4)
+
+	// One of the replicas in zone z1 is moved to zone z3, store 2 has more
+	// regions.
+	tc.addLeaderRegion(1, 1, 2, 3)
+	checkTransferPeer(c, rc.Check(cluster.GetRegion(1)), 2, 4)
+}
+
 func checkAddPeer(c *C, bop Operator, storeID uint64) {
 	var op *changePeerOperator
 	switch t := bop.(type) {
@@ -818,7 +1380,7 @@ func (s *testBalanceHotRegionSchedulerSuite) TestBalance(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
-	_, opt := newTestScheduleConfig()
+	cfg, opt := newTestScheduleConfig()
 	hb := newBalanceHotRegionScheduler(opt)
 
 	// Add stores 1, 2, 3, 4, 5 with region counts 3, 2, 2, 2, 0.
@@ -844,11 +1406,19 @@ func (s *testBalanceHotRegionSchedulerSuite) TestBalance(c *C) {
 	tc.addLeaderRegionWithWriteInfo(1, 1, 512*1024*regionHeartBeatReportInterval, 2, 3)
 	tc.addLeaderRegionWithWriteInfo(2, 1, 512*1024*regionHeartBeatReportInterval, 3, 4)
 	tc.addLeaderRegionWithWriteInfo(3, 1, 512*1024*regionHeartBeatReportInterval, 2, 4)
-	hotRegionLowThreshold = 0
+	cfg.HotRegionThreshold = 0
 
 	// Will transfer a hot region from store 1 to store 5, because the total count of peers
 	// which is hot for store 1 is more larger than other stores.
-	checkTransferPeer(c, hb.Schedule(cluster), 1, 5)
+	checkTransferPeer(c, hb.Schedule(cluster, nil), 1, 5)
+
+	// Moving the hot peers is paused by the region schedule limit.
+	cfg.RegionScheduleLimit = 0
+	checkTransferLeaderFrom(c, hb.Schedule(cluster, nil), 1)
+	cfg.LeaderScheduleLimit = 0
+	c.Assert(hb.Schedule(cluster, nil), IsNil)
+	cfg.RegionScheduleLimit = defaultRegionScheduleLimit
+	cfg.LeaderScheduleLimit = defaultLeaderScheduleLimit
 
 	// After transfer a hot region from store 1 to store 5
 	//| region_id | leader_sotre | follower_store | follower_store | written_bytes |
@@ -867,5 +1437,53 @@ func (s *testBalanceHotRegionSchedulerSuite) TestBalance(c *C) {
 
 	// We can find that the leader of all hot regions are on store 1,
 	// so one of the leader will transfer to another store.
-	checkTransferLeaderFrom(c, hb.Schedule(cluster), 1)
+	checkTransferLeaderFrom(c, hb.Schedule(cluster, nil), 1)
+}
+
+var _ = Suite(&testBalanceHotReadRegionSchedulerSuite{})
+
+type testBalanceHotReadRegionSchedulerSuite struct{}
+
+func (s *testBalanceHotReadRegionSchedulerSuite) TestBalance(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.HotRegionThreshold = 0
+	hb := newBalanceHotReadRegionScheduler(opt)
+
+	tc.addRegionStore(1, 3)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 2)
+	tc.addRegionStore(4, 2)
+
+	// Region 1, 2 and 3 are read hot regions, region 4 is cold.
+	//| region_id | leader_sotre | follower_store | follower_store | read_bytes |
+	//|-----------|--------------|----------------|----------------|------------|
+	//|     1     |       1      |        2       |       3        |    512KB   |
+	//|     2     |       1      |        3       |       4        |    512KB   |
+	//|     3     |       1      |        2       |       4        |    512KB   |
+	//|     4     |       2      |        1       |       3        |      1KB   |
+	tc.addLeaderRegionWithReadInfo(1, 1, 512*1024*regionHeartBeatReportInterval, 2, 3)
+	tc.addLeaderRegionWithReadInfo(2, 1, 512*1024*regionHeartBeatReportInterval, 3, 4)
+	tc.addLeaderRegionWithReadInfo(3, 1, 512*1024*regionHeartBeatReportInterval, 2, 4)
+	tc.addLeaderRegionWithReadInfo(4, 2, 1024*regionHeartBeatReportInterval, 1, 3)
+	c.Assert(cluster.hotCache.readFlow.len(), Equals, 3)
+
+	// All leaders of the read hot regions are on store 1, so one of them will
+	// be transferred to another store.
+	op := hb.Schedule(cluster, nil)
+	checkTransferLeaderFrom(c, op, 1)
+	c.Assert(hb.GetStatus().AsLeader[1].RegionsCount, Equals, 3)
+
+	// It is paused by the leader schedule limit.
+	cfg.LeaderScheduleLimit = 0
+	c.Assert(hb.Schedule(cluster, nil), IsNil)
+	cfg.LeaderScheduleLimit = defaultLeaderScheduleLimit
+
+	// The read hot leaders are balanced.
+	tc.addLeaderRegionWithReadInfo(1, 1, 512*1024*regionHeartBeatReportInterval, 2, 3)
+	tc.addLeaderRegionWithReadInfo(2, 3, 512*1024*regionHeartBeatReportInterval, 1, 4)
+	tc.addLeaderRegionWithReadInfo(3, 4, 512*1024*regionHeartBeatReportInterval, 1, 2)
+	c.Assert(hb.Schedule(cluster, nil), IsNil)
 }
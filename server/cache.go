@@ -14,6 +14,7 @@
 package server
 
 import (
+	"bytes"
 	"math/rand"
 	"sync"
 	"time"
@@ -23,17 +24,21 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
 )
 
 var (
 	errStoreNotFound = func(storeID uint64) error {
-		return errors.Errorf("store %v not found", storeID)
+		return newNotFoundError("store %v not found", storeID)
+	}
+	errStoreTombstone = func(storeID uint64) error {
+		return newError(pdpb.ErrorType_STORE_TOMBSTONE, "store %v is tombstone", storeID)
 	}
 	errStoreIsBlocked = func(storeID uint64) error {
 		return errors.Errorf("store %v is blocked", storeID)
 	}
 	errRegionNotFound = func(regionID uint64) error {
-		return errors.Errorf("region %v not found", regionID)
+		return newNotFoundError("region %v not found", regionID)
 	}
 	errRegionIsStale = func(region *metapb.Region, origin *metapb.Region) error {
 		return errors.Errorf("region is stale: region %v origin %v", region, origin)
@@ -41,16 +46,16 @@ var (
 )
 
 type storesInfo struct {
-	stores map[uint64]*storeInfo
+	stores map[uint64]*StoreInfo
 }
 
 func newStoresInfo() *storesInfo {
 	return &storesInfo{
-		stores: make(map[uint64]*storeInfo),
+		stores: make(map[uint64]*StoreInfo),
 	}
 }
 
-func (s *storesInfo) getStore(storeID uint64) *storeInfo {
+func (s *storesInfo) getStore(storeID uint64) *StoreInfo {
 	store, ok := s.stores[storeID]
 	if !ok {
 		return nil
@@ -58,10 +63,19 @@ func (s *storesInfo) getStore(storeID uint64) *storeInfo {
 	return store.clone()
 }
 
-func (s *storesInfo) setStore(store *storeInfo) {
+func (s *storesInfo) setStore(store *StoreInfo) {
+	// PD doesn't persist when a store is buried, a tombstone store without the
+	// time, e.g. it's loaded after PD restarts, is counted from now.
+	if store.isTombstone() && store.status.tombstoneTS.IsZero() {
+		store.status.tombstoneTS = time.Now()
+	}
 	s.stores[store.GetId()] = store
 }
 
+func (s *storesInfo) deleteStore(storeID uint64) {
+	delete(s.stores, storeID)
+}
+
 func (s *storesInfo) blockStore(storeID uint64) error {
 	store, ok := s.stores[storeID]
 	if !ok {
@@ -82,8 +96,8 @@ func (s *storesInfo) unblockStore(storeID uint64) {
 	store.unblock()
 }
 
-func (s *storesInfo) getStores() []*storeInfo {
-	stores := make([]*storeInfo, 0, len(s.stores))
+func (s *storesInfo) getStores() []*StoreInfo {
+	stores := make([]*StoreInfo, 0, len(s.stores))
 	for _, store := range s.stores {
 		stores = append(stores, store.clone())
 	}
@@ -108,16 +122,35 @@ func (s *storesInfo) setLeaderCount(storeID uint64, leaderCount int) {
 	}
 }
 
+func (s *storesInfo) setLeaderSize(storeID uint64, leaderSize uint64) {
+	if store, ok := s.stores[storeID]; ok {
+		store.status.LeaderSize = leaderSize
+	}
+}
+
 func (s *storesInfo) setRegionCount(storeID uint64, regionCount int) {
 	if store, ok := s.stores[storeID]; ok {
 		store.status.RegionCount = regionCount
 	}
 }
 
+func (s *storesInfo) setRegionSize(storeID uint64, regionSize uint64) {
+	if store, ok := s.stores[storeID]; ok {
+		store.status.RegionSize = regionSize
+	}
+}
+
+func (s *storesInfo) setPendingPeerCount(storeID uint64, pendingPeerCount int) {
+	if store, ok := s.stores[storeID]; ok {
+		store.status.PendingPeerCount = pendingPeerCount
+	}
+}
+
 // regionMap wraps a map[uint64]*RegionInfo and supports randomly pick a region.
 type regionMap struct {
-	m   map[uint64]*regionEntry
-	ids []uint64
+	m         map[uint64]*regionEntry
+	ids       []uint64
+	totalSize uint64
 }
 
 type regionEntry struct {
@@ -148,11 +181,22 @@ func (rm *regionMap) Get(id uint64) *RegionInfo {
 	return nil
 }
 
+// TotalSize returns the total approximate size of the regions.
+func (rm *regionMap) TotalSize() uint64 {
+	if rm == nil {
+		return 0
+	}
+	return rm.totalSize
+}
+
 func (rm *regionMap) Put(region *RegionInfo) {
 	if old, ok := rm.m[region.GetId()]; ok {
+		rm.totalSize -= old.regionSize()
+		rm.totalSize += region.regionSize()
 		old.RegionInfo = region
 		return
 	}
+	rm.totalSize += region.regionSize()
 	rm.m[region.GetId()] = &regionEntry{
 		RegionInfo: region,
 		pos:        len(rm.ids),
@@ -160,11 +204,13 @@ func (rm *regionMap) Put(region *RegionInfo) {
 	rm.ids = append(rm.ids, region.GetId())
 }
 
-func (rm *regionMap) RandomRegion() *RegionInfo {
+// RandomRegion picks a region by randIntn, which returns a random number in
+// [0, n).
+func (rm *regionMap) RandomRegion(randIntn func(n int) int) *RegionInfo {
 	if rm.Len() == 0 {
 		return nil
 	}
-	return rm.Get(rm.ids[rand.Intn(rm.Len())])
+	return rm.Get(rm.ids[randIntn(rm.Len())])
 }
 
 func (rm *regionMap) Delete(id uint64) {
@@ -176,24 +222,35 @@ func (rm *regionMap) Delete(id uint64) {
 		last := rm.m[rm.ids[len-1]]
 		last.pos = old.pos
 		rm.ids[last.pos] = last.GetId()
+		rm.totalSize -= old.regionSize()
 		delete(rm.m, id)
 		rm.ids = rm.ids[:len-1]
 	}
 }
 
+// regionsInfo indexes the regions by ranges, IDs and stores. A region in it is
+// never modified once it's added, a change of the region replaces it with a
+// new one, so the regions got without cloning can still be read after the
+// lock of the cache is released.
 type regionsInfo struct {
-	tree      *regionTree
-	regions   *regionMap            // regionID -> regionInfo
-	leaders   map[uint64]*regionMap // storeID -> regionID -> regionInfo
-	followers map[uint64]*regionMap // storeID -> regionID -> regionInfo
+	tree         *regionTree
+	regions      *regionMap            // regionID -> regionInfo
+	leaders      map[uint64]*regionMap // storeID -> regionID -> regionInfo
+	followers    map[uint64]*regionMap // storeID -> regionID -> regionInfo
+	pendingPeers map[uint64]*regionMap // storeID -> regionID -> regionInfo
+	// randIntn picks the random regions, a seeded one replays the
+	// scheduling in tests.
+	randIntn func(n int) int
 }
 
 func newRegionsInfo() *regionsInfo {
 	return &regionsInfo{
-		tree:      newRegionTree(),
-		regions:   newRegionMap(),
-		leaders:   make(map[uint64]*regionMap),
-		followers: make(map[uint64]*regionMap),
+		tree:         newRegionTree(),
+		regions:      newRegionMap(),
+		leaders:      make(map[uint64]*regionMap),
+		followers:    make(map[uint64]*regionMap),
+		pendingPeers: make(map[uint64]*regionMap),
+		randIntn:     rand.Intn,
 	}
 }
 
@@ -213,6 +270,8 @@ func (r *regionsInfo) setRegion(region *RegionInfo) {
 }
 
 func (r *regionsInfo) addRegion(region *RegionInfo) {
+	r.shareKeys(region)
+
 	// Add to tree and regions.
 	r.tree.update(region.Region)
 	r.regions.Put(region)
@@ -242,6 +301,38 @@ func (r *regionsInfo) addRegion(region *RegionInfo) {
 			store.Put(region)
 		}
 	}
+
+	// Add to pending peers.
+	for _, peer := range region.PendingPeers {
+		storeID := peer.GetStoreId()
+		store, ok := r.pendingPeers[storeID]
+		if !ok {
+			store = newRegionMap()
+			r.pendingPeers[storeID] = store
+		}
+		store.Put(region)
+	}
+}
+
+// shareKeys makes the region share the boundary keys with its adjacent
+// regions, so each key is only kept once in memory. The keys are replaced on a
+// copy of the region meta, since the meta may be referenced out of the cache,
+// e.g. by the heartbeat request.
+func (r *regionsInfo) shareKeys(region *RegionInfo) {
+	prev, next := r.tree.getAdjacentRegions(region.Region)
+	shareStart := prev != nil && len(prev.EndKey) > 0 && bytes.Equal(prev.EndKey, region.StartKey)
+	shareEnd := next != nil && len(next.StartKey) > 0 && bytes.Equal(next.StartKey, region.EndKey)
+	if !shareStart && !shareEnd {
+		return
+	}
+	meta := *region.Region
+	if shareStart {
+		meta.StartKey = prev.EndKey
+	}
+	if shareEnd {
+		meta.EndKey = next.StartKey
+	}
+	region.Region = &meta
 }
 
 func (r *regionsInfo) removeRegion(region *RegionInfo) {
@@ -249,12 +340,24 @@ func (r *regionsInfo) removeRegion(region *RegionInfo) {
 	r.tree.remove(region.Region)
 	r.regions.Delete(region.GetId())
 
-	// Remove from leaders and followers.
+	// Remove from leaders, followers and pending peers.
 	for _, peer := range region.GetPeers() {
 		storeID := peer.GetStoreId()
 		r.leaders[storeID].Delete(region.GetId())
 		r.followers[storeID].Delete(region.GetId())
+		r.pendingPeers[storeID].Delete(region.GetId())
+	}
+}
+
+// getOverlaps returns the regions whose ranges are overlapped with the region.
+func (r *regionsInfo) getOverlaps(region *metapb.Region) []*RegionInfo {
+	var overlaps []*RegionInfo
+	for _, item := range r.tree.getOverlaps(region) {
+		if origin := r.regions.Get(item.region.GetId()); origin != nil {
+			overlaps = append(overlaps, origin)
+		}
 	}
+	return overlaps
 }
 
 func (r *regionsInfo) searchRegion(regionKey []byte) *RegionInfo {
@@ -265,10 +368,45 @@ func (r *regionsInfo) searchRegion(regionKey []byte) *RegionInfo {
 	return r.getRegion(region.GetId())
 }
 
+// scanRange returns at most limit regions from the one that contains the
+// startKey to the endKey, limit <= 0 means no limit and an empty endKey means
+// no end. The regions are not cloned, they should not be modified.
+func (r *regionsInfo) scanRange(startKey, endKey []byte, limit int) []*RegionInfo {
+	var regions []*RegionInfo
+	r.tree.scanRange(startKey, func(meta *metapb.Region) bool {
+		if limit > 0 && len(regions) >= limit {
+			return false
+		}
+		if len(endKey) > 0 && bytes.Compare(meta.GetStartKey(), endKey) >= 0 {
+			return false
+		}
+		if region := r.regions.Get(meta.GetId()); region != nil {
+			regions = append(regions, region)
+		}
+		return true
+	})
+	return regions
+}
+
+// getAdjacentRegions returns the regions right before and after the region.
+func (r *regionsInfo) getAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo) {
+	var prev, next *RegionInfo
+	p, n := r.tree.getAdjacentRegions(region.Region)
+	if p != nil {
+		prev = r.getRegion(p.GetId())
+	}
+	if n != nil {
+		next = r.getRegion(n.GetId())
+	}
+	return prev, next
+}
+
+// getRegions returns all regions without cloning them, they should not be
+// modified.
 func (r *regionsInfo) getRegions() []*RegionInfo {
 	regions := make([]*RegionInfo, 0, r.regions.Len())
 	for _, region := range r.regions.m {
-		regions = append(regions, region.clone())
+		regions = append(regions, region.RegionInfo)
 	}
 	return regions
 }
@@ -289,6 +427,14 @@ func (r *regionsInfo) getStoreRegionCount(storeID uint64) int {
 	return r.getStoreLeaderCount(storeID) + r.getStoreFollowerCount(storeID)
 }
 
+func (r *regionsInfo) getStoreRegionSize(storeID uint64) uint64 {
+	return r.leaders[storeID].TotalSize() + r.followers[storeID].TotalSize()
+}
+
+func (r *regionsInfo) getStoreLeaderSize(storeID uint64) uint64 {
+	return r.leaders[storeID].TotalSize()
+}
+
 func (r *regionsInfo) getStoreLeaderCount(storeID uint64) int {
 	return r.leaders[storeID].Len()
 }
@@ -297,23 +443,43 @@ func (r *regionsInfo) getStoreFollowerCount(storeID uint64) int {
 	return r.followers[storeID].Len()
 }
 
+// getStoreRegions returns the regions having peers on the store without
+// cloning them, they should not be modified.
+func (r *regionsInfo) getStoreRegions(storeID uint64) []*RegionInfo {
+	leaders, followers := r.leaders[storeID], r.followers[storeID]
+	regions := make([]*RegionInfo, 0, leaders.Len()+followers.Len())
+	for _, rm := range []*regionMap{leaders, followers} {
+		if rm == nil {
+			continue
+		}
+		for _, region := range rm.m {
+			regions = append(regions, region.RegionInfo)
+		}
+	}
+	return regions
+}
+
+func (r *regionsInfo) getStorePendingPeerCount(storeID uint64) int {
+	return r.pendingPeers[storeID].Len()
+}
+
 func (r *regionsInfo) randRegion() *RegionInfo {
-	return randRegion(r.regions)
+	return r.randRegionIn(r.regions)
 }
 
 func (r *regionsInfo) randLeaderRegion(storeID uint64) *RegionInfo {
-	return randRegion(r.leaders[storeID])
+	return r.randRegionIn(r.leaders[storeID])
 }
 
 func (r *regionsInfo) randFollowerRegion(storeID uint64) *RegionInfo {
-	return randRegion(r.followers[storeID])
+	return r.randRegionIn(r.followers[storeID])
 }
 
 const randomRegionMaxRetry = 10
 
-func randRegion(regions *regionMap) *RegionInfo {
+func (r *regionsInfo) randRegionIn(regions *regionMap) *RegionInfo {
 	for i := 0; i < randomRegionMaxRetry; i++ {
-		region := regions.RandomRegion()
+		region := regions.RandomRegion(r.randIntn)
 		if region == nil {
 			return nil
 		}
@@ -333,23 +499,34 @@ type clusterInfo struct {
 	stores  *storesInfo
 	regions *regionsInfo
 
-	activeRegions   int
-	writeStatistics *lruCache
+	activeRegions int
+	hotCache      *hotSpotCache
+	flows         *regionFlows
+	// opt is the schedule option of the server, it is nil if the cluster info
+	// isn't loaded by the server.
+	opt *scheduleOption
+
+	// regionBuffer buffers the region metas to save to kv if it is not nil,
+	// otherwise they are saved to kv directly.
+	regionBuffer *regionBuffer
 }
 
 func newClusterInfo(id IDAllocator) *clusterInfo {
 	return &clusterInfo{
-		id:              id,
-		stores:          newStoresInfo(),
-		regions:         newRegionsInfo(),
-		writeStatistics: newLRUCache(writeStatLRUMaxLen),
+		id:       id,
+		stores:   newStoresInfo(),
+		regions:  newRegionsInfo(),
+		hotCache: newHotSpotCache(),
+		flows:    newRegionFlows(),
 	}
 }
 
 // Return nil if cluster is not bootstrapped.
-func loadClusterInfo(id IDAllocator, kv *kv) (*clusterInfo, error) {
+func loadClusterInfo(id IDAllocator, kv *kv, opt *scheduleOption) (*clusterInfo, error) {
 	c := newClusterInfo(id)
 	c.kv = kv
+	c.opt = opt
+	c.regionBuffer = newRegionBuffer(kv)
 
 	c.meta = &metapb.Cluster{}
 	ok, err := kv.loadMeta(c.meta)
@@ -364,6 +541,9 @@ func loadClusterInfo(id IDAllocator, kv *kv) (*clusterInfo, error) {
 	if err := kv.loadStores(c.stores, kvRangeLimit); err != nil {
 		return nil, errors.Trace(err)
 	}
+	if err := kv.loadStoreSettings(c.stores); err != nil {
+		return nil, errors.Trace(err)
+	}
 	log.Infof("load %v stores cost %v", c.stores.getStoreCount(), time.Since(start))
 
 	start = time.Now()
@@ -372,6 +552,11 @@ func loadClusterInfo(id IDAllocator, kv *kv) (*clusterInfo, error) {
 	}
 	log.Infof("load %v regions cost %v", c.regions.getRegionCount(), time.Since(start))
 
+	for _, region := range reconcileRegions(c.regions) {
+		log.Infof("[region %d] removed, it is covered by newer regions", region.GetId())
+		c.regionBuffer.deleteRegion(region.Region)
+	}
+
 	return c, nil
 }
 
@@ -379,7 +564,7 @@ func (c *clusterInfo) allocID() (uint64, error) {
 	return c.id.Alloc()
 }
 
-func (c *clusterInfo) allocPeer(storeID uint64) (*metapb.Peer, error) {
+func (c *clusterInfo) AllocPeer(storeID uint64) (*metapb.Peer, error) {
 	peerID, err := c.allocID()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -413,19 +598,19 @@ func (c *clusterInfo) putMetaLocked(meta *metapb.Cluster) error {
 	return nil
 }
 
-func (c *clusterInfo) getStore(storeID uint64) *storeInfo {
+func (c *clusterInfo) GetStore(storeID uint64) *StoreInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.stores.getStore(storeID)
 }
 
-func (c *clusterInfo) putStore(store *storeInfo) error {
+func (c *clusterInfo) putStore(store *StoreInfo) error {
 	c.Lock()
 	defer c.Unlock()
 	return c.putStoreLocked(store.clone())
 }
 
-func (c *clusterInfo) putStoreLocked(store *storeInfo) error {
+func (c *clusterInfo) putStoreLocked(store *StoreInfo) error {
 	if c.kv != nil {
 		if err := c.kv.saveStore(store.Store); err != nil {
 			return errors.Trace(err)
@@ -435,19 +620,48 @@ func (c *clusterInfo) putStoreLocked(store *storeInfo) error {
 	return nil
 }
 
-func (c *clusterInfo) blockStore(storeID uint64) error {
+func (c *clusterInfo) putStoreSettings(storeID uint64, settings StoreSettings) error {
+	c.Lock()
+	defer c.Unlock()
+	store := c.stores.getStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	if c.kv != nil {
+		if err := c.kv.saveStoreSettings(storeID, settings); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	store.status.Settings = settings
+	c.stores.setStore(store)
+	return nil
+}
+
+func (c *clusterInfo) deleteStore(store *StoreInfo) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.kv != nil {
+		if err := c.kv.deleteStore(store.Store); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	c.stores.deleteStore(store.GetId())
+	return nil
+}
+
+func (c *clusterInfo) BlockStore(storeID uint64) error {
 	c.Lock()
 	defer c.Unlock()
 	return errors.Trace(c.stores.blockStore(storeID))
 }
 
-func (c *clusterInfo) unblockStore(storeID uint64) {
+func (c *clusterInfo) UnblockStore(storeID uint64) {
 	c.Lock()
 	defer c.Unlock()
 	c.stores.unblockStore(storeID)
 }
 
-func (c *clusterInfo) getStores() []*storeInfo {
+func (c *clusterInfo) GetStores() []*StoreInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.stores.getStores()
@@ -467,67 +681,44 @@ func (c *clusterInfo) getStoreCount() int {
 
 func (c *clusterInfo) getStoresWriteStat() map[uint64]uint64 {
 	res := make(map[uint64]uint64)
-	for _, s := range c.getStores() {
+	for _, s := range c.GetStores() {
 		res[s.GetId()] = s.status.GetBytesWritten()
 	}
 	return res
 }
 
-func (c *clusterInfo) getClusterTotalWrittenBytes() uint64 {
-	var totalWrittenBytes uint64
-	for _, s := range c.stores.getStores() {
-		if s.isUp() {
-			totalWrittenBytes += s.status.GetBytesWritten()
-		}
-	}
-	return totalWrittenBytes
-}
-
-func (c *clusterInfo) getRegion(regionID uint64) *RegionInfo {
+func (c *clusterInfo) GetRegion(regionID uint64) *RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.regions.getRegion(regionID)
 }
 
-// updateWriteStatCache updates statistic for a region if it's hot, or remove it from statistics if it cools down
-func (c *clusterInfo) updateWriteStatCache(region *RegionInfo, hotRegionThreshold uint64) {
-	var v *RegionStat
-	key := region.GetId()
-	value, isExist := c.writeStatistics.peek(key)
-	newItem := &RegionStat{
-		RegionID:       region.GetId(),
-		WrittenBytes:   region.WrittenBytes,
-		LastUpdateTime: time.Now(),
-		StoreID:        region.Leader.GetStoreId(),
-		version:        region.GetRegionEpoch().GetVersion(),
-		antiCount:      hotRegionAntiCount,
-	}
-
-	if isExist {
-		v = value.(*RegionStat)
-		newItem.HotDegree = v.HotDegree + 1
-	}
+func (c *clusterInfo) searchRegion(regionKey []byte) *RegionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.searchRegion(regionKey)
+}
 
-	if region.WrittenBytes < hotRegionThreshold {
-		if !isExist {
-			return
-		}
-		if v.antiCount <= 0 {
-			c.writeStatistics.remove(key)
-			return
-		}
-		// eliminate some noise
-		newItem.HotDegree = v.HotDegree - 1
-		newItem.antiCount = v.antiCount - 1
-		newItem.WrittenBytes = v.WrittenBytes
+func (c *clusterInfo) ScanRegions(startKey, endKey []byte, limit int) []*RegionInfo {
+	c.RLock()
+	regions := c.regions.scanRange(startKey, endKey, limit)
+	c.RUnlock()
+	for i, region := range regions {
+		regions[i] = region.clone()
 	}
-	c.writeStatistics.add(key, newItem)
+	return regions
 }
 
-func (c *clusterInfo) searchRegion(regionKey []byte) *RegionInfo {
+func (c *clusterInfo) GetAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo) {
 	c.RLock()
 	defer c.RUnlock()
-	return c.regions.searchRegion(regionKey)
+	return c.regions.getAdjacentRegions(region)
+}
+
+// getRegionFlow returns the recent flow of the region, nil if the region has
+// no flow reported.
+func (c *clusterInfo) getRegionFlow(regionID uint64) *RegionFlow {
+	return c.flows.get(regionID)
 }
 
 func (c *clusterInfo) putRegion(region *RegionInfo) error {
@@ -537,31 +728,86 @@ func (c *clusterInfo) putRegion(region *RegionInfo) error {
 }
 
 func (c *clusterInfo) putRegionLocked(region *RegionInfo) error {
+	if err := c.saveRegionMeta(region.Region); err != nil {
+		return errors.Trace(err)
+	}
+	c.regions.setRegion(region)
+	return nil
+}
+
+// saveRegionMeta saves the region meta to the buffer or kv.
+func (c *clusterInfo) saveRegionMeta(region *metapb.Region) error {
+	if c.regionBuffer != nil {
+		c.regionBuffer.saveRegion(region)
+		return nil
+	}
 	if c.kv != nil {
-		if err := c.kv.saveRegion(region.Region); err != nil {
-			return errors.Trace(err)
+		return errors.Trace(c.kv.saveRegion(region))
+	}
+	return nil
+}
+
+// saveRegionMetas saves the region meta and deletes the metas of the regions
+// it covers. The changes are buffered unless write is true or there is no
+// buffer.
+func (c *clusterInfo) saveRegionMetas(region *metapb.Region, deletes []*RegionInfo, write bool) error {
+	if c.regionBuffer != nil && !write {
+		for _, item := range deletes {
+			c.regionBuffer.deleteRegion(item.Region)
+		}
+		c.regionBuffer.saveRegion(region)
+		return nil
+	}
+	if c.regionBuffer != nil {
+		metas := make([]*metapb.Region, 0, len(deletes))
+		for _, item := range deletes {
+			metas = append(metas, item.Region)
 		}
+		return errors.Trace(c.regionBuffer.write([]*metapb.Region{region}, metas))
+	}
+	if c.kv != nil {
+		for _, item := range deletes {
+			if err := c.kv.deleteRegion(item.Region); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return errors.Trace(c.kv.saveRegion(region))
 	}
-	c.regions.setRegion(region)
 	return nil
 }
 
+// getRegions returns the clones of all regions, they are cloned after the
+// read lock is released, so listing a huge number of regions doesn't block the
+// heartbeats.
 func (c *clusterInfo) getRegions() []*RegionInfo {
+	regions := c.snapshotRegions()
+	for i, region := range regions {
+		regions[i] = region.clone()
+	}
+	return regions
+}
+
+// snapshotRegions returns all regions without cloning them, it's for the
+// readers which don't modify the regions.
+func (c *clusterInfo) snapshotRegions() []*RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.regions.getRegions()
 }
 
-func (c *clusterInfo) randomRegion() *RegionInfo {
+func (c *clusterInfo) RandomRegion() *RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.regions.randRegion()
 }
 
 func (c *clusterInfo) getMetaRegions() []*metapb.Region {
-	c.RLock()
-	defer c.RUnlock()
-	return c.regions.getMetaRegions()
+	regions := c.snapshotRegions()
+	metas := make([]*metapb.Region, 0, len(regions))
+	for _, region := range regions {
+		metas = append(metas, proto.Clone(region.Region).(*metapb.Region))
+	}
+	return metas
 }
 
 func (c *clusterInfo) getRegionCount() int {
@@ -582,22 +828,28 @@ func (c *clusterInfo) getStoreLeaderCount(storeID uint64) int {
 	return c.regions.getStoreLeaderCount(storeID)
 }
 
-func (c *clusterInfo) randLeaderRegion(storeID uint64) *RegionInfo {
+func (c *clusterInfo) getStoreRegions(storeID uint64) []*RegionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.getStoreRegions(storeID)
+}
+
+func (c *clusterInfo) RandLeaderRegion(storeID uint64) *RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.regions.randLeaderRegion(storeID)
 }
 
-func (c *clusterInfo) randFollowerRegion(storeID uint64) *RegionInfo {
+func (c *clusterInfo) RandFollowerRegion(storeID uint64) *RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.regions.randFollowerRegion(storeID)
 }
 
-func (c *clusterInfo) getRegionStores(region *RegionInfo) []*storeInfo {
+func (c *clusterInfo) GetRegionStores(region *RegionInfo) []*StoreInfo {
 	c.RLock()
 	defer c.RUnlock()
-	var stores []*storeInfo
+	var stores []*StoreInfo
 	for id := range region.GetStoreIds() {
 		if store := c.stores.getStore(id); store != nil {
 			stores = append(stores, store)
@@ -606,17 +858,17 @@ func (c *clusterInfo) getRegionStores(region *RegionInfo) []*storeInfo {
 	return stores
 }
 
-func (c *clusterInfo) getLeaderStore(region *RegionInfo) *storeInfo {
+func (c *clusterInfo) GetLeaderStore(region *RegionInfo) *StoreInfo {
 	c.RLock()
 	defer c.RUnlock()
 	return c.stores.getStore(region.Leader.GetStoreId())
 
 }
 
-func (c *clusterInfo) getFollowerStores(region *RegionInfo) []*storeInfo {
+func (c *clusterInfo) GetFollowerStores(region *RegionInfo) []*StoreInfo {
 	c.RLock()
 	defer c.RUnlock()
-	var stores []*storeInfo
+	var stores []*StoreInfo
 	for id := range region.GetFollowers() {
 		if store := c.stores.getStore(id); store != nil {
 			stores = append(stores, store)
@@ -651,101 +903,205 @@ func (c *clusterInfo) handleStoreHeartbeat(stats *pdpb.StoreStats) error {
 
 func (c *clusterInfo) updateStoreStatus(id uint64) {
 	c.stores.setLeaderCount(id, c.regions.getStoreLeaderCount(id))
+	c.stores.setLeaderSize(id, c.regions.getStoreLeaderSize(id))
 	c.stores.setRegionCount(id, c.regions.getStoreRegionCount(id))
+	c.stores.setRegionSize(id, c.regions.getStoreRegionSize(id))
+	c.stores.setPendingPeerCount(id, c.regions.getStorePendingPeerCount(id))
 }
 
-// handleRegionHeartbeat updates the region information.
+// handleRegionHeartbeat updates the region information. Most heartbeats
+// change nothing but the flows, they are checked under the read lock, so
+// the heartbeats of different regions are handled concurrently and only the
+// changed regions take the write lock.
 func (c *clusterInfo) handleRegionHeartbeat(region *RegionInfo) error {
+	if v, ok := failpoint.Eval("regionHeartbeatErr"); ok {
+		return errors.Errorf("failpoint: handle region heartbeat failed: %v", v)
+	}
+
+	region = region.clone()
+	c.RLock()
+	origin := c.regions.regions.Get(region.GetId())
+	saveKV, saveCache, err := checkRegionUpdate(origin, region)
+	if err != nil {
+		c.RUnlock()
+		return errors.Trace(err)
+	}
+	// The reported bytes are converted to the rates before the region is put
+	// into the cache, the regions in the cache are never modified.
+	c.flows.update(region, time.Now())
+	c.updateWriteStatus(region)
+	c.updateReadStatus(region)
+	c.RUnlock()
+
+	if saveKV || saveCache {
+		if err = c.updateRegion(region); err != nil {
+			return errors.Trace(err)
+		}
+	} else {
+		regionHeartbeatUpdateCounter.WithLabelValues("none").Inc()
+	}
+
+	return nil
+}
+
+// checkRegionUpdate checks the region of a heartbeat against its origin in
+// the cache, it returns whether to save the region to KV and to the cache.
+// KV is saved if meta is updated, the cache is saved if meta or leader is
+// updated, or any down/pending peer or the approximate size is reported.
+func checkRegionUpdate(origin, region *RegionInfo) (saveKV, saveCache bool, err error) {
+	if origin == nil {
+		return true, true, nil
+	}
+	r := region.GetRegionEpoch()
+	o := origin.GetRegionEpoch()
+	// Region meta is stale, return an error.
+	if r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer() {
+		return false, false, errors.Trace(errRegionIsStale(region.Region, origin.Region))
+	}
+	if r.GetVersion() > o.GetVersion() || r.GetConfVer() > o.GetConfVer() {
+		saveKV, saveCache = true, true
+	}
+	if region.Leader.GetId() != origin.Leader.GetId() {
+		saveCache = true
+	}
+	if len(region.DownPeers) > 0 || len(region.PendingPeers) > 0 {
+		saveCache = true
+	}
+	if len(origin.DownPeers) > 0 || len(origin.PendingPeers) > 0 {
+		saveCache = true
+	}
+	if region.ApproximateSize != origin.ApproximateSize || region.ApproximateKeys != origin.ApproximateKeys {
+		saveCache = true
+	}
+	return saveKV, saveCache, nil
+}
+
+// updateRegion saves the changed region of a heartbeat.
+func (c *clusterInfo) updateRegion(region *RegionInfo) error {
 	c.Lock()
 	defer c.Unlock()
 
-	region = region.clone()
-	origin := c.regions.getRegion(region.GetId())
+	// The region may be updated after it's checked under the read lock, e.g.
+	// by a batch split or another heartbeat, so it's checked again.
+	origin := c.regions.regions.Get(region.GetId())
+	saveKV, saveCache, err := checkRegionUpdate(origin, region)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !saveCache {
+		regionHeartbeatUpdateCounter.WithLabelValues("none").Inc()
+		return nil
+	}
 
-	// Save to KV if meta is updated.
-	// Save to cache if meta or leader is updated, or contains any down/pending peer.
-	var saveKV, saveCache bool
 	if origin == nil {
 		log.Infof("[region %d] Insert new region {%v}", region.GetId(), region)
-		saveKV, saveCache = true, true
+		// The new region is reported by its leader, so it is active.
+		if region.Leader.GetId() != 0 {
+			c.activeRegions++
+		}
 	} else {
 		r := region.GetRegionEpoch()
 		o := origin.GetRegionEpoch()
-		// Region meta is stale, return an error.
-		if r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer() {
-			return errors.Trace(errRegionIsStale(region.Region, origin.Region))
-		}
 		if r.GetVersion() > o.GetVersion() {
 			log.Infof("[region %d] %s, Version changed from {%d} to {%d}", region.GetId(), diffRegionKeyInfo(origin, region), o.GetVersion(), r.GetVersion())
-			saveKV, saveCache = true, true
 		}
 		if r.GetConfVer() > o.GetConfVer() {
 			log.Infof("[region %d] %s, ConfVer changed from {%d} to {%d}", region.GetId(), diffRegionPeersInfo(origin, region), o.GetConfVer(), r.GetConfVer())
-			saveKV, saveCache = true, true
 		}
 		if region.Leader.GetId() != origin.Leader.GetId() {
 			log.Infof("[region %d] Leader changed from {%v} to {%v}", region.GetId(), origin.GetPeer(origin.Leader.GetId()), region.GetPeer(region.Leader.GetId()))
 			if origin.Leader.GetId() == 0 {
 				c.activeRegions++
 			}
-			saveCache = true
 		}
-		if len(region.DownPeers) > 0 || len(region.PendingPeers) > 0 {
-			saveCache = true
+	}
+
+	// Regions covered by a newer region are merged into it, remove them.
+	var overlaps []*RegionInfo
+	if saveKV {
+		for _, item := range c.regions.getOverlaps(region.Region) {
+			if item.GetId() != region.GetId() && isRegionOlder(item.Region, region.Region) && containsRegion(region.Region, item.Region) {
+				overlaps = append(overlaps, item)
+			}
 		}
-		if len(origin.DownPeers) > 0 || len(origin.PendingPeers) > 0 {
-			saveCache = true
+	}
+	if saveKV && (c.kv != nil || c.regionBuffer != nil) {
+		// The splits and merges are written at once, the other changes are
+		// buffered.
+		isSplitOrMerge := origin == nil || len(overlaps) > 0 ||
+			region.GetRegionEpoch().GetVersion() > origin.GetRegionEpoch().GetVersion()
+		if err := c.saveRegionMetas(region.Region, overlaps, isSplitOrMerge); err != nil {
+			return errors.Trace(err)
 		}
+		regionHeartbeatUpdateCounter.WithLabelValues("kv").Inc()
 	}
 
-	if saveKV && c.kv != nil {
-		if err := c.kv.saveRegion(region.Region); err != nil {
-			return errors.Trace(err)
+	for _, item := range overlaps {
+		log.Infof("[region %d] removed, it is covered by region %d", item.GetId(), region.GetId())
+		c.regions.removeRegion(item)
+		c.flows.remove(item.GetId())
+		for _, p := range item.Peers {
+			c.updateStoreStatus(p.GetStoreId())
 		}
 	}
 
-	if saveCache {
-		c.regions.setRegion(region)
+	c.regions.setRegion(region)
+	regionHeartbeatUpdateCounter.WithLabelValues("cache").Inc()
 
-		// Update related stores.
-		if origin != nil {
-			for _, p := range origin.Peers {
-				c.updateStoreStatus(p.GetStoreId())
-			}
-		}
-		for _, p := range region.Peers {
+	// Update related stores.
+	if origin != nil {
+		for _, p := range origin.Peers {
 			c.updateStoreStatus(p.GetStoreId())
 		}
 	}
-
-	c.updateWriteStatus(region)
+	for _, p := range region.Peers {
+		c.updateStoreStatus(p.GetStoreId())
+	}
 
 	return nil
 }
 
 func (c *clusterInfo) updateWriteStatus(region *RegionInfo) {
-	var WrittenBytesPerSec uint64
-	v, isExist := c.writeStatistics.peek(region.GetId())
-	if isExist {
-		interval := time.Now().Sub(v.(*RegionStat).LastUpdateTime).Seconds()
+	region.WrittenBytes = c.updateHotStatus(hotWriteFlow, region, region.WrittenBytes, c.flows.getAverage().BytesWritten, hotRegionMinWriteRate)
+}
+
+func (c *clusterInfo) updateReadStatus(region *RegionInfo) {
+	region.ReadBytes = c.updateHotStatus(hotReadFlow, region, region.ReadBytes, c.flows.getAverage().BytesRead, hotRegionMinReadRate)
+}
+
+// getHotRegionFlowRatio returns hot-region-flow-ratio of the schedule config,
+// or the default one if the cluster info isn't loaded by the server.
+func (c *clusterInfo) getHotRegionFlowRatio() float64 {
+	if c.opt == nil {
+		return defaultHotRegionFlowRatio
+	}
+	return c.opt.GetHotRegionFlowRatio()
+}
+
+// updateHotStatus converts the bytes reported by the region heartbeat to the
+// rate and updates the hot cache of the flow kind, it returns the rate, or the
+// reported bytes if the last report is too recent to compute the rate.
+//
+// Both the written and the read bytes are reported by the heartbeats. A region
+// is hot if its rate is hot-region-flow-ratio times the average rate of the
+// regions, so the threshold adapts to the size and the load of the cluster.
+// The min rate keeps the idle clusters from hot regions.
+func (c *clusterInfo) updateHotStatus(kind hotFlowKind, region *RegionInfo, bytes uint64, avgBytesPerSec float64, minRate uint64) uint64 {
+	var bytesPerSec uint64
+	if v := c.hotCache.getRegionStat(kind, region.GetId()); v != nil {
+		interval := time.Now().Sub(v.LastUpdateTime).Seconds()
 		if interval < minHotRegionReportInterval {
-			return
+			return bytes
 		}
-		WrittenBytesPerSec = uint64(float64(region.WrittenBytes) / interval)
+		bytesPerSec = uint64(float64(bytes) / interval)
 	} else {
-		WrittenBytesPerSec = uint64(float64(region.WrittenBytes) / float64(regionHeartBeatReportInterval))
+		bytesPerSec = uint64(float64(bytes) / float64(regionHeartBeatReportInterval))
 	}
-	region.WrittenBytes = WrittenBytesPerSec
-
-	// hotRegionThreshold is use to pick hot region
-	// suppose the number of the hot regions is writeStatLRUMaxLen
-	// and we use total written Bytes past storeHeartBeatReportInterval seconds to divide the number of hot regions
-	// divide 2 because the store reports data about two times than the region record write to rocksdb
-	divisor := float64(writeStatLRUMaxLen) * 2 * storeHeartBeatReportInterval
-	hotRegionThreshold := uint64(float64(c.getClusterTotalWrittenBytes()) / divisor)
 
-	if hotRegionThreshold < hotRegionMinWriteRate {
-		hotRegionThreshold = hotRegionMinWriteRate
+	hotRegionThreshold := uint64(avgBytesPerSec * c.getHotRegionFlowRatio())
+	if hotRegionThreshold < minRate {
+		hotRegionThreshold = minRate
 	}
-	c.updateWriteStatCache(region, hotRegionThreshold)
+	c.hotCache.update(kind, region, bytesPerSec, hotRegionThreshold, time.Now())
+	return bytesPerSec
 }
@@ -14,8 +14,11 @@
 package server
 
 import (
+	"fmt"
 	"math/rand"
+	"sync"
 	"sync/atomic"
+	"testing"
 
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
@@ -39,8 +42,8 @@ func checkStaleRegion(origin *metapb.Region, region *metapb.Region) error {
 }
 
 // Create n stores (0..n).
-func newTestStores(n uint64) []*storeInfo {
-	stores := make([]*storeInfo, 0, n)
+func newTestStores(n uint64) []*StoreInfo {
+	stores := make([]*StoreInfo, 0, n)
 	for i := uint64(0); i < n; i++ {
 		store := &metapb.Store{
 			Id: i,
@@ -166,6 +169,7 @@ func (s *testRegionsInfoSuite) Test(c *C) {
 	}
 	for i := uint64(0); i < n; i++ {
 		c.Assert(cache.randFollowerRegion(i), IsNil)
+		c.Assert(cache.getStorePendingPeerCount(i), Equals, cache.getStoreRegionCount(i))
 	}
 }
 
@@ -193,11 +197,34 @@ func checkRegionsKV(c *C, kv *kv, regions []*RegionInfo) {
 	}
 }
 
+// checkRegionKVNotSaved checks the region is not saved to kv since the
+// revision.
+func checkRegionKVNotSaved(c *C, kv *kv, region *RegionInfo, revision int64) {
+	if kv != nil {
+		c.Assert(getRegionKVRevision(c, kv, region), Equals, revision)
+	}
+}
+
+func getRegionKVRevision(c *C, kv *kv, region *RegionInfo) int64 {
+	if kv == nil {
+		return 0
+	}
+	resp, err := kvGet(kv.client, kv.regionPath(region.GetId()))
+	c.Assert(err, IsNil)
+	c.Assert(resp.Kvs, HasLen, 1)
+	return resp.Kvs[0].ModRevision
+}
+
 func checkRegions(c *C, cache *regionsInfo, regions []*RegionInfo) {
 	regionCount := make(map[uint64]int)
 	leaderCount := make(map[uint64]int)
 	followerCount := make(map[uint64]int)
+	pendingPeerCount := make(map[uint64]int)
 	for _, region := range regions {
+		for _, peer := range region.PendingPeers {
+			pendingPeerCount[peer.StoreId]++
+			checkRegion(c, cache.pendingPeers[peer.StoreId].Get(region.Id), region)
+		}
 		for _, peer := range region.Peers {
 			regionCount[peer.StoreId]++
 			if peer.Id == region.Leader.Id {
@@ -220,6 +247,9 @@ func checkRegions(c *C, cache *regionsInfo, regions []*RegionInfo) {
 	for id, count := range followerCount {
 		c.Assert(cache.getStoreFollowerCount(id), Equals, count)
 	}
+	for id, count := range pendingPeerCount {
+		c.Assert(cache.getStorePendingPeerCount(id), Equals, count)
+	}
 
 	for _, region := range cache.getRegions() {
 		checkRegion(c, region, regions[region.GetId()])
@@ -238,6 +268,7 @@ func (s *testClusterInfoSuite) Test(c *C) {
 	tests = append(tests, s.testStoreHeartbeat)
 	tests = append(tests, s.testRegionHeartbeat)
 	tests = append(tests, s.testRegionSplitAndMerge)
+	tests = append(tests, s.testRemoveMergedRegions)
 
 	// Test without kv.
 	{
@@ -266,7 +297,7 @@ func (s *testClusterInfoSuite) TestLoadClusterInfo(c *C) {
 	kv := server.kv
 
 	// Cluster is not bootstrapped.
-	cluster, err := loadClusterInfo(server.idAlloc, kv)
+	cluster, err := loadClusterInfo(server.idAlloc, kv, server.scheduleOpt)
 	c.Assert(err, IsNil)
 	c.Assert(cluster, IsNil)
 
@@ -277,7 +308,7 @@ func (s *testClusterInfoSuite) TestLoadClusterInfo(c *C) {
 	stores := mustSaveStores(c, kv, n)
 	regions := mustSaveRegions(c, kv, n)
 
-	cluster, err = loadClusterInfo(server.idAlloc, kv)
+	cluster, err = loadClusterInfo(server.idAlloc, kv, server.scheduleOpt)
 	c.Assert(err, IsNil)
 	c.Assert(cluster, NotNil)
 
@@ -293,6 +324,106 @@ func (s *testClusterInfoSuite) TestLoadClusterInfo(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestPrepared(c *C) {
+	cache := newClusterInfo(newMockIDAllocator())
+	regions := newTestRegions(10, 3)
+	for _, region := range regions {
+		cache.putStore(newStoreInfo(&metapb.Store{Id: region.Leader.GetStoreId()}))
+	}
+
+	// The loaded regions are not active until they are reported.
+	for _, region := range regions[:3] {
+		c.Assert(cache.putRegion(newRegionInfo(region.Region, nil)), IsNil)
+	}
+	c.Assert(cache.isPrepared(), IsFalse)
+	c.Assert(cache.handleRegionHeartbeat(regions[1]), IsNil)
+	c.Assert(cache.isPrepared(), IsFalse)
+
+	// The new regions reported by the leaders are active.
+	for _, region := range regions[3:] {
+		c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+	}
+	c.Assert(cache.isPrepared(), IsTrue)
+}
+
+func (s *testClusterInfoSuite) TestRegionsSnapshot(c *C) {
+	cache := newClusterInfo(newMockIDAllocator())
+	regions := newTestRegions(3, 3)
+	for _, region := range regions {
+		c.Assert(cache.putRegion(region), IsNil)
+	}
+
+	// The snapshot isn't changed by the later updates.
+	snapshot := cache.snapshotRegions()
+	region := regions[0].clone()
+	region.Leader = region.GetPeers()[1]
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 1}
+	c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+	c.Assert(snapshot, HasLen, 3)
+	for _, r := range snapshot {
+		if r.GetId() == region.GetId() {
+			c.Assert(r.Leader, DeepEquals, regions[0].Leader)
+		}
+	}
+
+	// The listed regions are cloned.
+	for _, r := range cache.getRegions() {
+		r.Leader = nil
+	}
+	for _, r := range cache.getMetaRegions() {
+		r.Peers = nil
+	}
+	for _, r := range cache.ScanRegions(nil, nil, 0) {
+		r.StartKey = []byte("x")
+	}
+	c.Assert(cache.GetRegion(region.GetId()).Leader, DeepEquals, region.Leader)
+	for _, r := range cache.getRegions() {
+		c.Assert(r.Leader, NotNil)
+		c.Assert(r.GetPeers(), HasLen, 3)
+		c.Assert(r.GetStartKey(), DeepEquals, []byte{byte(r.GetId())})
+	}
+}
+
+func (s *testClusterInfoSuite) TestConcurrentRegionHeartbeat(c *C) {
+	n, np := uint64(10), uint64(3)
+	cache := newClusterInfo(newMockIDAllocator())
+	regions := newTestRegions(n, np)
+	for i := uint64(0); i < n; i++ {
+		cache.putStore(newStoreInfo(&metapb.Store{Id: i}))
+	}
+
+	// The heartbeats of different regions are handled concurrently, each
+	// region transfers its leader around its peers.
+	const rounds = 30
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region *RegionInfo) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				r := region.clone()
+				r.Leader = r.GetPeers()[i%len(r.GetPeers())]
+				r.RegionEpoch = &metapb.RegionEpoch{ConfVer: uint64(i/2 + 1), Version: 1}
+				c.Assert(cache.handleRegionHeartbeat(r), IsNil)
+			}
+		}(region)
+	}
+	wg.Wait()
+
+	var leaders int
+	for _, region := range regions {
+		r := cache.GetRegion(region.GetId())
+		c.Assert(r.Leader, DeepEquals, region.GetPeers()[(rounds-1)%len(region.GetPeers())])
+		c.Assert(r.GetRegionEpoch().GetConfVer(), Equals, uint64((rounds-1)/2+1))
+	}
+	for i := uint64(0); i < n; i++ {
+		store := cache.GetStore(i)
+		c.Assert(store.status.RegionCount, Equals, int(np))
+		leaders += store.status.LeaderCount
+	}
+	c.Assert(leaders, Equals, int(n))
+}
+
 func (s *testClusterInfoSuite) testStoreHeartbeat(c *C, cache *clusterInfo) {
 	n, np := uint64(3), uint64(3)
 	stores := newTestStores(n)
@@ -315,7 +446,7 @@ func (s *testClusterInfoSuite) testStoreHeartbeat(c *C, cache *clusterInfo) {
 
 		c.Assert(cache.handleStoreHeartbeat(storeStats), IsNil)
 
-		stats = cache.getStore(store.GetId()).status
+		stats = cache.GetStore(store.GetId()).status
 		c.Assert(stats.LastHeartbeatTS.IsZero(), IsFalse)
 	}
 
@@ -350,9 +481,20 @@ func (s *testClusterInfoSuite) testRegionHeartbeat(c *C, cache *clusterInfo) {
 		checkRegionsKV(c, cache.kv, regions[:i+1])
 
 		// region is the same, not updated.
+		revision := getRegionKVRevision(c, cache.kv, region)
 		c.Assert(cache.handleRegionHeartbeat(region), IsNil)
 		checkRegions(c, cache.regions, regions[:i+1])
 		checkRegionsKV(c, cache.kv, regions[:i+1])
+		checkRegionKVNotSaved(c, cache.kv, region, revision)
+
+		// leader changed, only the cache is updated.
+		origin := region.clone()
+		region.Leader = region.Peers[(i+1)%len(region.Peers)]
+		c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+		checkRegions(c, cache.regions, regions[:i+1])
+		checkRegionKVNotSaved(c, cache.kv, region, revision)
+		region.Leader = origin.Leader
+		c.Assert(cache.handleRegionHeartbeat(region), IsNil)
 
 		epoch := region.clone().GetRegionEpoch()
 
@@ -435,10 +577,10 @@ func (s *testClusterInfoSuite) testRegionHeartbeat(c *C, cache *clusterInfo) {
 	}
 
 	for _, region := range regions {
-		for _, store := range cache.getRegionStores(region) {
+		for _, store := range cache.GetRegionStores(region) {
 			c.Assert(region.GetStorePeer(store.GetId()), NotNil)
 		}
-		for _, store := range cache.getFollowerStores(region) {
+		for _, store := range cache.GetFollowerStores(region) {
 			peer := region.GetStorePeer(store.GetId())
 			c.Assert(peer.GetId(), Not(Equals), region.Leader.GetId())
 		}
@@ -468,7 +610,7 @@ func heartbeatRegions(c *C, cache *clusterInfo, regions []*metapb.Region) {
 
 		c.Assert(cache.handleRegionHeartbeat(r), IsNil)
 
-		checkRegion(c, cache.getRegion(r.GetId()), r)
+		checkRegion(c, cache.GetRegion(r.GetId()), r)
 		checkRegion(c, cache.searchRegion(r.StartKey), r)
 
 		if len(r.EndKey) > 0 {
@@ -481,7 +623,7 @@ func heartbeatRegions(c *C, cache *clusterInfo, regions []*metapb.Region) {
 	for _, region := range regions {
 		r := newRegionInfo(region, nil)
 
-		checkRegion(c, cache.getRegion(r.GetId()), r)
+		checkRegion(c, cache.GetRegion(r.GetId()), r)
 		checkRegion(c, cache.searchRegion(r.StartKey), r)
 
 		if len(r.EndKey) > 0 {
@@ -529,6 +671,42 @@ func (s *testClusterInfoSuite) testRegionSplitAndMerge(c *C, cache *clusterInfo)
 	}
 }
 
+func (s *testClusterInfoSuite) testRemoveMergedRegions(c *C, cache *clusterInfo) {
+	for _, store := range newTestStores(3) {
+		cache.putStore(store)
+	}
+	regions := newTestRegions(3, 3)
+	for _, region := range regions {
+		region.RegionEpoch = &metapb.RegionEpoch{Version: 1}
+		c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+	}
+
+	// Region 1 splits, region 0 is not removed.
+	region := regions[1].clone()
+	region.StartKey = []byte{1, 0}
+	region.RegionEpoch.Version++
+	c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+	c.Assert(cache.GetRegion(0), NotNil)
+	c.Assert(cache.getRegionCount(), Equals, 3)
+
+	// Region 0 is merged into region 2.
+	region = regions[2].clone()
+	region.StartKey = regions[0].StartKey
+	region.RegionEpoch.Version = 3
+	c.Assert(cache.handleRegionHeartbeat(region), IsNil)
+	c.Assert(cache.GetRegion(0), IsNil)
+	c.Assert(cache.GetRegion(1), IsNil)
+	c.Assert(cache.getRegionCount(), Equals, 1)
+	for _, store := range cache.GetStores() {
+		c.Assert(store.status.RegionCount, Equals, 1)
+	}
+	if cache.kv != nil {
+		ok, err := cache.kv.loadRegion(0, &metapb.Region{})
+		c.Assert(err, IsNil)
+		c.Assert(ok, IsFalse)
+	}
+}
+
 var _ = Suite(&testClusterUtilSuite{})
 
 type testClusterUtilSuite struct{}
@@ -556,6 +734,35 @@ func (s *testClusterUtilSuite) TestCheckStaleRegion(c *C) {
 	c.Assert(checkStaleRegion(region, origin), NotNil)
 }
 
+func (s *testClusterUtilSuite) TestRegionSizeHistogram(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addRegionStore(1, 0)
+	tc.addRegionStore(2, 0)
+	for i, size := range []uint64{0, 1 << 19, 3 << 20, 3 << 20, 2 << 30} {
+		tc.addLeaderRegion(uint64(i+1), 1, 2)
+		region := cluster.GetRegion(uint64(i + 1))
+		region.ApproximateSize = size
+		tc.putRegion(region)
+	}
+	tc.addLeaderRegion(6, 2)
+	c.Assert(cluster.getStoreRegions(2), HasLen, 6)
+
+	h := newRegionSizeHistogram(1, cluster.getStoreRegions(1))
+	c.Assert(h.Count, Equals, 5)
+	c.Assert(h.Unknown, Equals, 1)
+	c.Assert(h.Buckets, HasLen, regionSizeHistogramBuckets+1)
+	c.Assert(h.Buckets[0].Count, Equals, 1)
+	c.Assert(h.Buckets[2].Min, Equals, uint64(2<<20))
+	c.Assert(h.Buckets[2].Max, Equals, uint64(4<<20))
+	c.Assert(h.Buckets[2].Count, Equals, 2)
+	c.Assert(h.Buckets[2].Size, Equals, uint64(6<<20))
+	last := h.Buckets[regionSizeHistogramBuckets]
+	c.Assert(last.Min, Equals, uint64(1<<30))
+	c.Assert(last.Max, Equals, uint64(0))
+	c.Assert(last.Count, Equals, 1)
+}
+
 // mockIDAllocator mocks IDAllocator and it is only used for test.
 type mockIDAllocator struct {
 	base uint64
@@ -577,6 +784,7 @@ func (s *testRegionMapSuite) TestRegionMap(c *C) {
 	var empty *regionMap
 	c.Assert(empty.Len(), Equals, 0)
 	c.Assert(empty.Get(1), IsNil)
+	c.Assert(empty.TotalSize(), Equals, uint64(0))
 
 	rm := newRegionMap()
 	s.check(c, rm)
@@ -604,6 +812,7 @@ func (s *testRegionMapSuite) regionInfo(id uint64) *RegionInfo {
 		Region: &metapb.Region{
 			Id: id,
 		},
+		ApproximateSize: id,
 	}
 }
 
@@ -618,6 +827,12 @@ func (s *testRegionMapSuite) check(c *C, rm *regionMap, ids ...uint64) {
 	}
 	// Check Len.
 	c.Assert(rm.Len(), Equals, len(ids))
+	// Check TotalSize.
+	var total uint64
+	for _, id := range ids {
+		total += id
+	}
+	c.Assert(rm.TotalSize(), Equals, total)
 	// Check id set.
 	expect := make(map[uint64]struct{})
 	for _, id := range ids {
@@ -634,3 +849,117 @@ func (s *testRegionMapSuite) check(c *C, rm *regionMap, ids ...uint64) {
 	c.Assert(set1, DeepEquals, expect)
 	c.Assert(set2, DeepEquals, expect)
 }
+
+func (s *testRegionsInfoSuite) TestScanRange(c *C) {
+	cache := newRegionsInfo()
+	regions := newTestRegions(10, 3)
+	for _, region := range regions {
+		cache.addRegion(region)
+	}
+
+	checkScan := func(startKey, endKey []byte, limit int, expect []*RegionInfo) {
+		scanned := cache.scanRange(startKey, endKey, limit)
+		c.Assert(scanned, HasLen, len(expect))
+		for i := range expect {
+			checkRegion(c, scanned[i], expect[i])
+		}
+	}
+	checkScan(nil, nil, 0, regions)
+	checkScan([]byte{3}, nil, 0, regions[3:])
+	checkScan([]byte{3}, nil, 2, regions[3:5])
+	checkScan([]byte{3}, []byte{6}, 0, regions[3:6])
+	checkScan([]byte{3}, []byte{6}, 2, regions[3:5])
+	// The region containing the start key is the first.
+	checkScan([]byte{3, 1}, []byte{6, 1}, 0, regions[3:7])
+	checkScan([]byte{6}, []byte{3}, 0, nil)
+}
+
+func (s *testRegionsInfoSuite) TestShareKeys(c *C) {
+	cache := newRegionsInfo()
+	regions := newBenchmarkRegions(3)
+	for _, region := range regions {
+		cache.setRegion(region.clone())
+	}
+
+	r0, r1, r2 := cache.regions.Get(0), cache.regions.Get(1), cache.regions.Get(2)
+	c.Assert(&r1.StartKey[0], Equals, &r0.EndKey[0])
+	c.Assert(&r2.StartKey[0], Equals, &r1.EndKey[0])
+	for _, region := range regions {
+		checkRegion(c, cache.getRegion(region.GetId()), region)
+	}
+
+	// The meta of the caller is not changed.
+	meta := regions[1].Region
+	startKey, endKey := meta.StartKey, meta.EndKey
+	cache.setRegion(regions[1])
+	c.Assert(&meta.StartKey[0], Equals, &startKey[0])
+	c.Assert(&meta.EndKey[0], Equals, &endKey[0])
+	r1 = cache.regions.Get(1)
+	c.Assert(&r1.StartKey[0], Equals, &r0.EndKey[0])
+	c.Assert(&r1.EndKey[0], Equals, &r2.StartKey[0])
+}
+
+// newBenchmarkRegions creates n continuous regions of 3 peers in 3 stores.
+func newBenchmarkRegions(n int) []*RegionInfo {
+	regions := make([]*RegionInfo, 0, n)
+	for i := 0; i < n; i++ {
+		peers := make([]*metapb.Peer, 0, 3)
+		for j := 0; j < 3; j++ {
+			peers = append(peers, &metapb.Peer{
+				Id:      uint64(i*3 + j),
+				StoreId: uint64(j),
+			})
+		}
+		region := &metapb.Region{
+			Id:          uint64(i),
+			Peers:       peers,
+			StartKey:    []byte(fmt.Sprintf("%020d", i)),
+			EndKey:      []byte(fmt.Sprintf("%020d", i+1)),
+			RegionEpoch: &metapb.RegionEpoch{Version: 1, ConfVer: 1},
+		}
+		regions = append(regions, newRegionInfo(region, peers[0]))
+	}
+	return regions
+}
+
+func BenchmarkRegionsInfoSetRegion(b *testing.B) {
+	cache := newRegionsInfo()
+	regions := newBenchmarkRegions(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, region := range regions {
+		cache.setRegion(region)
+	}
+}
+
+func BenchmarkHandleRegionHeartbeat(b *testing.B) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	regions := newBenchmarkRegions(1000)
+	for _, region := range regions {
+		cluster.handleRegionHeartbeat(region)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cluster.handleRegionHeartbeat(regions[i%len(regions)])
+	}
+}
+
+func (s *testClusterInfoSuite) TestHotRegionFlowRatio(c *C) {
+	_, opt := newTestScheduleConfig()
+	cluster := newClusterInfo(newMockIDAllocator())
+	c.Assert(cluster.getHotRegionFlowRatio(), Equals, float64(defaultHotRegionFlowRatio))
+	cluster.opt = opt
+
+	// The flow of the region is 3 times the average.
+	region := newTestRegions(1, 3)[0]
+	bytes := uint64(3 * 1024 * regionHeartBeatReportInterval)
+	cluster.updateHotStatus(hotWriteFlow, region, bytes, 1024, 0)
+	c.Assert(cluster.hotCache.getRegionStat(hotWriteFlow, region.GetId()), IsNil)
+
+	cfg := *opt.load()
+	cfg.HotRegionFlowRatio = 2
+	opt.store(&cfg)
+	cluster.updateHotStatus(hotWriteFlow, region, bytes, 1024, 0)
+	c.Assert(cluster.hotCache.getRegionStat(hotWriteFlow, region.GetId()), NotNil)
+}
@@ -17,11 +17,14 @@ import (
 	"fmt"
 	"math"
 	"path"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/etcd/clientv3"
+	"github.com/gogo/protobuf/proto"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -33,7 +36,7 @@ const (
 
 // Error instances
 var (
-	ErrNotBootstrapped = errors.New("TiKV cluster is not bootstrapped, please start TiKV first")
+	ErrNotBootstrapped = newError(pdpb.ErrorType_NOT_BOOTSTRAPPED, "TiKV cluster is not bootstrapped, please start TiKV first")
 )
 
 // RaftCluster is used for cluster config management.
@@ -58,14 +61,24 @@ type RaftCluster struct {
 
 	coordinator *coordinator
 
+	heatmap *heatmapColumns
+	stats   *statsSeries
+
 	wg   sync.WaitGroup
 	quit chan struct{}
+	// hbQueues are the queues of the region heartbeat workers.
+	hbQueues []chan *regionHeartbeatTask
 
 	status *ClusterStatus
+
+	// downStores are the stores alerted to be down, only accessed by
+	// runBackgroundJobs.
+	downStores map[uint64]struct{}
 }
 
 // ClusterStatus saves some state information
 type ClusterStatus struct {
+	IsBootstrapped    bool      `json:"is_bootstrapped"`
 	RaftBootstrapTime time.Time `json:"raft_bootstrap_time,omitempty"`
 }
 
@@ -75,6 +88,7 @@ func newRaftCluster(s *Server, clusterID uint64) *RaftCluster {
 		running:     false,
 		clusterID:   clusterID,
 		clusterRoot: s.getClusterRootPath(),
+		downStores:  make(map[uint64]struct{}),
 	}
 }
 
@@ -98,7 +112,7 @@ func (c *RaftCluster) start() error {
 		return nil
 	}
 
-	cluster, err := loadClusterInfo(c.s.idAlloc, c.s.kv)
+	cluster, err := loadClusterInfo(c.s.idAlloc, c.s.kv, c.s.scheduleOpt)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -107,11 +121,26 @@ func (c *RaftCluster) start() error {
 	}
 	c.cachedCluster = cluster
 	c.coordinator = newCoordinator(c.cachedCluster, c.s.scheduleOpt)
+	c.heatmap = newHeatmapColumns(heatmapMaxColumns)
+	c.stats = newStatsSeries(statsMaxSamples)
 	c.quit = make(chan struct{})
+	c.hbQueues = make([]chan *regionHeartbeatTask, regionHeartbeatWorkers)
 
-	c.wg.Add(2)
+	c.wg.Add(3)
 	go c.runCoordinator()
 	go c.runBackgroundJobs(backgroundJobInterval)
+	go c.runHeatmap(heatmapInterval)
+	c.wg.Add(1)
+	go c.runFlushRegions(regionFlushInterval)
+	for i := range c.hbQueues {
+		c.hbQueues[i] = make(chan *regionHeartbeatTask, regionHeartbeatQueueSize)
+		c.wg.Add(1)
+		go c.runRegionHeartbeatWorker(c.hbQueues[i])
+	}
+	if c.s.kv.regionKV != nil {
+		c.wg.Add(1)
+		go c.runSyncRegions(regionKVSyncInterval)
+	}
 
 	c.running = true
 
@@ -147,7 +176,9 @@ func (c *RaftCluster) isRunning() bool {
 
 // GetConfig gets the config information.
 func (s *Server) GetConfig() *Config {
+	s.cfgLock.RLock()
 	cfg := s.cfg.clone()
+	s.cfgLock.RUnlock()
 	cfg.Schedule = *s.scheduleOpt.load()
 	cfg.Replication = *s.scheduleOpt.rep.load()
 	return cfg
@@ -160,12 +191,16 @@ func (s *Server) GetScheduleConfig() *ScheduleConfig {
 	return cfg
 }
 
-// SetScheduleConfig sets the balance config information.
-func (s *Server) SetScheduleConfig(cfg ScheduleConfig) {
-	s.scheduleOpt.store(&cfg)
-	s.scheduleOpt.persist(s.kv)
-	s.cfg.Schedule = cfg
-	log.Infof("schedule config is updated: %+v, old: %+v", cfg, s.cfg.Schedule)
+// SetScheduleConfig sets the balance config information. The config is
+// persisted by the leader before it takes effect, so a new leader goes on
+// with it rather than the one in its config file.
+func (s *Server) SetScheduleConfig(cfg ScheduleConfig) error {
+	if err := cfg.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleConfigLock.Lock()
+	defer s.scheduleConfigLock.Unlock()
+	return s.setScheduleConfigLocked(cfg, *s.scheduleOpt.rep.load())
 }
 
 // GetReplicationConfig get the replication config
@@ -175,12 +210,110 @@ func (s *Server) GetReplicationConfig() *ReplicationConfig {
 	return cfg
 }
 
-// SetReplicationConfig sets the replication config
-func (s *Server) SetReplicationConfig(cfg ReplicationConfig) {
-	s.scheduleOpt.rep.store(&cfg)
-	s.scheduleOpt.persist(s.kv)
-	s.cfg.Replication = cfg
-	log.Infof("replication is updated: %+v, old: %+v", cfg, s.cfg.Replication)
+// SetReplicationConfig sets the replication config.
+// The regions are checked against the new max-replicas on heartbeats, and the
+// replicas are added or removed under replica-schedule-limit.
+func (s *Server) SetReplicationConfig(cfg ReplicationConfig) error {
+	if err := cfg.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleConfigLock.Lock()
+	defer s.scheduleConfigLock.Unlock()
+	return s.setScheduleConfigLocked(*s.scheduleOpt.load(), cfg)
+}
+
+// setScheduleConfigLocked persists the schedule and replication config in
+// one write before they take effect. scheduleConfigLock must be held, so the
+// config persisted with one of them is never stale.
+func (s *Server) setScheduleConfigLocked(cfg ScheduleConfig, rep ReplicationConfig) error {
+	if err := s.kv.saveConfig(&Config{Schedule: cfg, Replication: rep}); err != nil {
+		return errors.Trace(err)
+	}
+	if old := *s.scheduleOpt.load(); !reflect.DeepEqual(cfg, old) {
+		s.scheduleOpt.store(&cfg)
+		log.Infof("schedule config is updated: %+v, old: %+v", cfg, old)
+		s.recordClusterEvent(clusterEventConfig, "schedule config is updated: %+v, old: %+v", cfg, old)
+	}
+	if old := *s.scheduleOpt.rep.load(); !reflect.DeepEqual(rep, old) {
+		s.scheduleOpt.rep.store(&rep)
+		log.Infof("replication is updated: %+v, old: %+v", rep, old)
+		s.recordClusterEvent(clusterEventConfig, "replication config is updated: %+v, old: %+v", rep, old)
+	}
+	return nil
+}
+
+// GetPlacementRules returns all placement rules.
+func (s *Server) GetPlacementRules() []*Rule {
+	return s.scheduleOpt.rules.getRules()
+}
+
+// GetPlacementRule returns the placement rule, nil if it is not found.
+func (s *Server) GetPlacementRule(id string) *Rule {
+	return s.scheduleOpt.rules.getRule(id)
+}
+
+// SetPlacementRule adds or replaces a placement rule. Only voter rules are
+// accepted, so no key range is left with rules placing no voters.
+func (s *Server) SetPlacementRule(rule *Rule) error {
+	if err := rule.adjust(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.kv.saveRule(rule); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.rules.setRule(rule)
+	log.Infof("placement rule is updated: %+v", rule)
+	return nil
+}
+
+// DeletePlacementRule deletes a placement rule.
+func (s *Server) DeletePlacementRule(id string) error {
+	if err := s.kv.deleteRule(id); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.rules.deleteRule(id)
+	log.Infof("placement rule %s is deleted", id)
+	return nil
+}
+
+// GetNamespaces returns all namespaces.
+func (s *Server) GetNamespaces() []*Namespace {
+	return s.scheduleOpt.namespaces.getNamespaces()
+}
+
+// GetNamespace returns the namespace, nil if it is not found.
+func (s *Server) GetNamespace(name string) *Namespace {
+	return s.scheduleOpt.namespaces.getNamespace(name)
+}
+
+// SetNamespace adds or replaces a namespace.
+func (s *Server) SetNamespace(ns *Namespace) error {
+	s.namespaceLock.Lock()
+	defer s.namespaceLock.Unlock()
+
+	if err := s.scheduleOpt.namespaces.checkNamespace(ns); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.kv.saveNamespace(ns); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.namespaces.setNamespace(ns)
+	log.Infof("namespace is updated: %+v", ns)
+	return nil
+}
+
+// DeleteNamespace deletes a namespace, its tables and stores are moved back
+// to the default namespace.
+func (s *Server) DeleteNamespace(name string) error {
+	s.namespaceLock.Lock()
+	defer s.namespaceLock.Unlock()
+
+	if err := s.kv.deleteNamespace(name); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.namespaces.deleteNamespace(name)
+	log.Infof("namespace %s is deleted", name)
+	return nil
 }
 
 func (s *Server) getClusterRootPath() string {
@@ -200,7 +333,7 @@ func (s *Server) GetRaftCluster() *RaftCluster {
 func (s *Server) GetCluster() *metapb.Cluster {
 	return &metapb.Cluster{
 		Id:           s.clusterID,
-		MaxPeerCount: uint32(s.cfg.Replication.MaxReplicas),
+		MaxPeerCount: uint32(s.scheduleOpt.GetMaxReplicas()),
 	}
 }
 
@@ -214,6 +347,7 @@ func (s *Server) GetClusterStatus() (*ClusterStatus, error) {
 	}
 	clone := &ClusterStatus{}
 	*clone = *s.cluster.status
+	clone.IsBootstrapped = s.cluster.running
 	return clone, nil
 }
 
@@ -292,7 +426,7 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 
 	clusterMeta := metapb.Cluster{
 		Id:           clusterID,
-		MaxPeerCount: uint32(s.cfg.Replication.MaxReplicas),
+		MaxPeerCount: uint32(s.scheduleOpt.GetMaxReplicas()),
 	}
 
 	// Set cluster meta
@@ -338,10 +472,11 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	}
 	if !resp.Succeeded {
 		log.Warnf("cluster %d already bootstrapped", clusterID)
-		return nil, errors.Errorf("cluster %d already bootstrapped", clusterID)
+		return nil, newError(pdpb.ErrorType_ALREADY_BOOTSTRAPPED, "cluster %d already bootstrapped", clusterID)
 	}
 
 	log.Infof("bootstrap cluster %d ok", clusterID)
+	s.recordClusterEvent(clusterEventBootstrap, "cluster %d is bootstrapped with store %d", clusterID, req.GetStore().GetId())
 
 	if err := s.cluster.start(); err != nil {
 		return nil, errors.Trace(err)
@@ -366,7 +501,7 @@ func (c *RaftCluster) GetRegionInfoByKey(regionKey []byte) *RegionInfo {
 
 // GetRegionByID gets region and leader peer by regionID from cluster.
 func (c *RaftCluster) GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Peer) {
-	region := c.cachedCluster.getRegion(regionID)
+	region := c.cachedCluster.GetRegion(regionID)
 	if region == nil {
 		return nil, nil
 	}
@@ -375,7 +510,24 @@ func (c *RaftCluster) GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Pe
 
 // GetRegionInfoByID gets regionInfo by regionID from cluster.
 func (c *RaftCluster) GetRegionInfoByID(regionID uint64) *RegionInfo {
-	return c.cachedCluster.getRegion(regionID)
+	return c.cachedCluster.GetRegion(regionID)
+}
+
+// GetAdjacentRegions returns the regions before and after the region, nil if
+// there is no such region.
+func (c *RaftCluster) GetAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo) {
+	return c.cachedCluster.GetAdjacentRegions(region)
+}
+
+// ScanRegionsByKey scans at most limit regions from the one that contains the
+// startKey, in the ascending order of start keys.
+func (c *RaftCluster) ScanRegionsByKey(startKey []byte, limit int) []*metapb.Region {
+	regions := c.cachedCluster.ScanRegions(startKey, nil, limit)
+	metas := make([]*metapb.Region, 0, len(regions))
+	for _, region := range regions {
+		metas = append(metas, region.Region)
+	}
+	return metas
 }
 
 // GetRegions gets regions from cluster.
@@ -383,6 +535,125 @@ func (c *RaftCluster) GetRegions() []*metapb.Region {
 	return c.cachedCluster.getMetaRegions()
 }
 
+// The kinds of the abnormal regions.
+const (
+	RegionCheckMissPeer    = "miss-peer"
+	RegionCheckExtraPeer   = "extra-peer"
+	RegionCheckDownPeer    = "down-peer"
+	RegionCheckPendingPeer = "pending-peer"
+	RegionCheckEmptyRegion = "empty-region"
+)
+
+// emptyRegionApproximateSize is the max approximate size of an empty region.
+const emptyRegionApproximateSize = 1 << 20
+
+// GetAbnormalRegions returns the regions of the kind sorted by ID: the regions
+// with less or more peers than required, with down or pending peers, or the
+// empty regions. A region is empty if its reported approximate size is at
+// most 1MB, the regions whose size is unknown are not counted.
+func (c *RaftCluster) GetAbnormalRegions(kind string) ([]*RegionInfo, error) {
+	var check func(*RegionInfo) bool
+	checker := c.coordinator.checker
+	switch kind {
+	case RegionCheckMissPeer:
+		check = func(region *RegionInfo) bool {
+			return len(region.GetPeers()) < checker.getMaxReplicas(region)
+		}
+	case RegionCheckExtraPeer:
+		check = func(region *RegionInfo) bool {
+			return len(region.GetPeers()) > checker.getMaxReplicas(region)
+		}
+	case RegionCheckDownPeer:
+		check = func(region *RegionInfo) bool { return len(region.DownPeers) > 0 }
+	case RegionCheckPendingPeer:
+		check = func(region *RegionInfo) bool { return len(region.PendingPeers) > 0 }
+	case RegionCheckEmptyRegion:
+		check = func(region *RegionInfo) bool {
+			return region.ApproximateSize != 0 && region.ApproximateSize <= emptyRegionApproximateSize
+		}
+	default:
+		return nil, errors.Errorf("unknown region check %s", kind)
+	}
+
+	regions := make([]*RegionInfo, 0)
+	for _, region := range c.cachedCluster.snapshotRegions() {
+		if check(region) {
+			regions = append(regions, region.clone())
+		}
+	}
+	sort.Sort(regionsByID(regions))
+	return regions, nil
+}
+
+type regionsByID []*RegionInfo
+
+func (r regionsByID) Len() int           { return len(r) }
+func (r regionsByID) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r regionsByID) Less(i, j int) bool { return r[i].GetId() < r[j].GetId() }
+
+// regionSizeHistogramBuckets is the number of the bounded buckets of the region
+// size histogram, their upper bounds double from 1MB to 1GB.
+const regionSizeHistogramBuckets = 11
+
+// RegionSizeBucket counts the regions whose approximate sizes are in
+// [Min, Max), the last bucket has no upper bound and its Max is 0.
+type RegionSizeBucket struct {
+	Min   uint64 `json:"min"`
+	Max   uint64 `json:"max"`
+	Count int    `json:"count"`
+	Size  uint64 `json:"size"`
+}
+
+// RegionSizeHistogram counts the regions on a store by their approximate
+// sizes, the regions whose sizes are not reported yet are counted as unknown.
+type RegionSizeHistogram struct {
+	StoreID uint64              `json:"store_id"`
+	Count   int                 `json:"count"`
+	Unknown int                 `json:"unknown"`
+	Buckets []*RegionSizeBucket `json:"buckets"`
+}
+
+func newRegionSizeHistogram(storeID uint64, regions []*RegionInfo) *RegionSizeHistogram {
+	h := &RegionSizeHistogram{
+		StoreID: storeID,
+		Count:   len(regions),
+	}
+	var min uint64
+	for max := uint64(defaultRegionSize); len(h.Buckets) < regionSizeHistogramBuckets; max *= 2 {
+		h.Buckets = append(h.Buckets, &RegionSizeBucket{Min: min, Max: max})
+		min = max
+	}
+	h.Buckets = append(h.Buckets, &RegionSizeBucket{Min: min})
+
+	for _, region := range regions {
+		size := region.ApproximateSize
+		if size == 0 {
+			h.Unknown++
+			continue
+		}
+		bucket := h.Buckets[len(h.Buckets)-1]
+		for _, b := range h.Buckets {
+			if size < b.Max {
+				bucket = b
+				break
+			}
+		}
+		bucket.Count++
+		bucket.Size += size
+	}
+	return h
+}
+
+// GetStoreRegionSizeHistogram returns the histogram of the approximate sizes
+// of the regions on the store, it helps to find the stores dominated by a few
+// giant regions.
+func (c *RaftCluster) GetStoreRegionSizeHistogram(storeID uint64) (*RegionSizeHistogram, error) {
+	if c.cachedCluster.GetStore(storeID) == nil {
+		return nil, errors.Trace(errStoreNotFound(storeID))
+	}
+	return newRegionSizeHistogram(storeID, c.cachedCluster.getStoreRegions(storeID)), nil
+}
+
 // GetStores gets stores from cluster.
 func (c *RaftCluster) GetStores() []*metapb.Store {
 	return c.cachedCluster.getMetaStores()
@@ -394,9 +665,9 @@ func (c *RaftCluster) GetStore(storeID uint64) (*metapb.Store, *StoreStatus, err
 		return nil, nil, errors.New("invalid zero store id")
 	}
 
-	store := c.cachedCluster.getStore(storeID)
+	store := c.cachedCluster.GetStore(storeID)
 	if store == nil {
-		return nil, nil, errors.Errorf("invalid store ID %d, not found", storeID)
+		return nil, nil, errors.Trace(errStoreNotFound(storeID))
 	}
 
 	return store.Store, store.status, nil
@@ -413,7 +684,7 @@ func (c *RaftCluster) putStore(store *metapb.Store) error {
 	cluster := c.cachedCluster
 
 	// Store address can not be the same as other stores.
-	for _, s := range cluster.getStores() {
+	for _, s := range cluster.GetStores() {
 		// It's OK to start a new store on the same address if the old store has been removed.
 		if s.isTombstone() {
 			continue
@@ -423,8 +694,9 @@ func (c *RaftCluster) putStore(store *metapb.Store) error {
 		}
 	}
 
-	s := cluster.getStore(store.GetId())
-	if s == nil {
+	s := cluster.GetStore(store.GetId())
+	isNew := s == nil
+	if isNew {
 		// Add a new store.
 		s = newStoreInfo(store)
 	} else {
@@ -434,13 +706,83 @@ func (c *RaftCluster) putStore(store *metapb.Store) error {
 	}
 
 	// Check location labels.
-	for _, k := range c.s.cfg.Replication.LocationLabels {
+	for _, k := range c.s.scheduleOpt.rep.GetLocationLabels() {
 		if v := s.getLabelValue(k); len(v) == 0 {
 			return errors.Errorf("missing location label %q in store %v", k, s)
 		}
 	}
 
-	return cluster.putStore(s)
+	if err := cluster.putStore(s); err != nil {
+		return errors.Trace(err)
+	}
+	if isNew {
+		c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is added", s.GetId(), s.GetAddress())
+	}
+	return nil
+}
+
+// UpdateStoreLabels adds or updates the labels of a store, a label with empty
+// value is removed. The labels are replaced by the ones in the config of the
+// store when it restarts.
+func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLabel) error {
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	meta := proto.Clone(store.Store).(*metapb.Store)
+	for _, label := range labels {
+		var updated []*metapb.StoreLabel
+		for _, l := range meta.GetLabels() {
+			if l.GetKey() != label.GetKey() {
+				updated = append(updated, l)
+			}
+		}
+		if label.GetValue() != "" {
+			updated = append(updated, label)
+		}
+		meta.Labels = updated
+	}
+	return errors.Trace(c.putStore(meta))
+}
+
+// SetStoreWeight sets the leader and region weights of a store.
+func (c *RaftCluster) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	settings := store.status.Settings
+	settings.LeaderWeight, settings.RegionWeight = leaderWeight, regionWeight
+	if err := settings.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := c.cachedCluster.putStoreSettings(storeID, settings); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[store %d] leader weight is set to %v, region weight is set to %v", storeID, leaderWeight, regionWeight)
+	return nil
+}
+
+// SetStoreSnapshotLimit sets the max snapshot count of a store, 0 means
+// max-snapshot-count in the schedule config.
+func (c *RaftCluster) SetStoreSnapshotLimit(storeID uint64, limit uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	settings := store.status.Settings
+	settings.SnapshotLimit = limit
+	if err := c.cachedCluster.putStoreSettings(storeID, settings); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[store %d] snapshot limit is set to %d", storeID, limit)
+	return nil
 }
 
 // RemoveStore marks a store as offline in cluster.
@@ -451,7 +793,7 @@ func (c *RaftCluster) RemoveStore(storeID uint64) error {
 
 	cluster := c.cachedCluster
 
-	store := cluster.getStore(storeID)
+	store := cluster.GetStore(storeID)
 	if store == nil {
 		return errors.Trace(errStoreNotFound(storeID))
 	}
@@ -462,12 +804,16 @@ func (c *RaftCluster) RemoveStore(storeID uint64) error {
 	}
 
 	if store.isTombstone() {
-		return errors.New("store has been removed")
+		return errors.Trace(errStoreTombstone(storeID))
 	}
 
 	store.State = metapb.StoreState_Offline
 	log.Warnf("[store %d] store %s has been Offline", store.GetId(), store.GetAddress())
-	return cluster.putStore(store)
+	if err := cluster.putStore(store); err != nil {
+		return errors.Trace(err)
+	}
+	c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is offline", store.GetId(), store.GetAddress())
+	return nil
 }
 
 // BuryStore marks a store as tombstone in cluster.
@@ -480,7 +826,7 @@ func (c *RaftCluster) BuryStore(storeID uint64, force bool) error {
 
 	cluster := c.cachedCluster
 
-	store := cluster.getStore(storeID)
+	store := cluster.GetStore(storeID)
 	if store == nil {
 		return errors.Trace(errStoreNotFound(storeID))
 	}
@@ -499,8 +845,41 @@ func (c *RaftCluster) BuryStore(storeID uint64, force bool) error {
 
 	store.State = metapb.StoreState_Tombstone
 	store.status = newStoreStatus()
+	store.status.tombstoneTS = time.Now()
 	log.Warnf("[store %d] store %s has been Tombstone", store.GetId(), store.GetAddress())
-	return cluster.putStore(store)
+	if err := cluster.putStore(store); err != nil {
+		return errors.Trace(err)
+	}
+	c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is tombstone", store.GetId(), store.GetAddress())
+	return nil
+}
+
+// ConfirmStoreDestroyed confirms that a tombstone store is physically
+// destroyed. Only the destroyed stores are removed by the tombstone store GC,
+// the others are kept as tombstone, so they are still rejected if they come
+// back online with the data of the removed peers.
+func (c *RaftCluster) ConfirmStoreDestroyed(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	if !store.isTombstone() {
+		return errors.Errorf("store %d is not tombstone, please remove it first", storeID)
+	}
+	if store.status.Settings.Destroyed {
+		return nil
+	}
+	settings := store.status.Settings
+	settings.Destroyed = true
+	if err := c.cachedCluster.putStoreSettings(storeID, settings); err != nil {
+		return errors.Trace(err)
+	}
+	log.Warnf("[store %d] store %s is confirmed to be destroyed", store.GetId(), store.GetAddress())
+	c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is destroyed", store.GetId(), store.GetAddress())
+	return nil
 }
 
 func (c *RaftCluster) checkStores() {
@@ -520,6 +899,58 @@ func (c *RaftCluster) checkStores() {
 	}
 }
 
+// gcTombstoneStores removes the tombstone stores which have been buried for
+// longer than the retention and confirmed to be destroyed.
+func (c *RaftCluster) gcTombstoneStores() {
+	opt := c.coordinator.opt
+	if !opt.IsTombstoneStoreGCEnabled() {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	cluster := c.cachedCluster
+	for _, store := range cluster.GetStores() {
+		if !store.isTombstone() || store.tombstoneTime() < opt.GetTombstoneStoreRetention() {
+			continue
+		}
+		// The store may come back online with its data if it's removed before
+		// it's destroyed.
+		if !store.status.Settings.Destroyed {
+			continue
+		}
+		// The store is buried forcibly, wait for its regions to be removed.
+		if cluster.getStoreRegionCount(store.GetId()) != 0 {
+			continue
+		}
+		if err := cluster.deleteStore(store); err != nil {
+			log.Errorf("remove tombstone store %v failed: %v", store, err)
+			continue
+		}
+		clearStoreMetrics(store.GetId())
+		log.Infof("removed tombstone store %v", store)
+		c.s.recordClusterEvent(clusterEventStoreState, "tombstone store %d at %s is removed", store.GetId(), store.GetAddress())
+	}
+}
+
+// collectReplicaMetrics counts the regions with less or more peers than
+// required, they are repaired by the replica checker, e.g. after max-replicas
+// is changed.
+func (c *RaftCluster) collectReplicaMetrics() (float64, float64) {
+	var missPeerCount, extraPeerCount float64
+	checker := c.coordinator.checker
+	for _, region := range c.cachedCluster.snapshotRegions() {
+		maxReplicas := checker.getMaxReplicas(region)
+		if len(region.GetPeers()) < maxReplicas {
+			missPeerCount++
+		} else if len(region.GetPeers()) > maxReplicas {
+			extraPeerCount++
+		}
+	}
+	return missPeerCount, extraPeerCount
+}
+
 func (c *RaftCluster) collectMetrics() {
 	cluster := c.cachedCluster
 
@@ -527,18 +958,23 @@ func (c *RaftCluster) collectMetrics() {
 	storeDownCount := 0
 	storeOfflineCount := 0
 	storeTombstoneCount := 0
+	offlineRegionCount := 0
 	storageSize := uint64(0)
 	storageCapacity := uint64(0)
 	minLeaderScore, maxLeaderScore := math.MaxFloat64, float64(0.0)
+	leaderPolicy := c.coordinator.opt.GetLeaderSchedulePolicy()
+	weights := c.coordinator.opt.GetStoreScore()
 	minRegionScore, maxRegionScore := math.MaxFloat64, float64(0.0)
 
-	for _, s := range cluster.getStores() {
+	sample := newStatsSample(time.Now())
+	for _, s := range cluster.GetStores() {
 		// Store state.
 		switch s.GetState() {
 		case metapb.StoreState_Up:
 			storeUpCount++
 		case metapb.StoreState_Offline:
 			storeOfflineCount++
+			offlineRegionCount += s.status.RegionCount
 		case metapb.StoreState_Tombstone:
 			storeTombstoneCount++
 		}
@@ -548,16 +984,17 @@ func (c *RaftCluster) collectMetrics() {
 		if s.downTime() >= c.coordinator.opt.GetMaxStoreDownTime() {
 			storeDownCount++
 		}
+		sample.addStore(s)
 
 		// Store stats.
 		storageSize += s.storageSize()
 		storageCapacity += s.status.GetCapacity()
 
 		// Balance score.
-		minLeaderScore = math.Min(minLeaderScore, s.leaderScore())
-		maxLeaderScore = math.Max(maxLeaderScore, s.leaderScore())
-		minRegionScore = math.Min(minRegionScore, s.regionScore())
-		maxRegionScore = math.Max(maxRegionScore, s.regionScore())
+		minLeaderScore = math.Min(minLeaderScore, s.leaderScore(leaderPolicy))
+		maxLeaderScore = math.Max(maxLeaderScore, s.leaderScore(leaderPolicy))
+		minRegionScore = math.Min(minRegionScore, s.regionScore(weights))
+		maxRegionScore = math.Max(maxRegionScore, s.regionScore(weights))
 	}
 
 	metrics := make(map[string]float64)
@@ -565,7 +1002,9 @@ func (c *RaftCluster) collectMetrics() {
 	metrics["store_down_count"] = float64(storeDownCount)
 	metrics["store_offline_count"] = float64(storeOfflineCount)
 	metrics["store_tombstone_count"] = float64(storeTombstoneCount)
+	metrics["store_offline_region_count"] = float64(offlineRegionCount)
 	metrics["region_count"] = float64(cluster.getRegionCount())
+	metrics["miss_peer_region_count"], metrics["extra_peer_region_count"] = c.collectReplicaMetrics()
 	metrics["storage_size"] = float64(storageSize)
 	metrics["storage_capacity"] = float64(storageCapacity)
 	metrics["leader_balance_ratio"] = 1 - minLeaderScore/maxLeaderScore
@@ -574,6 +1013,8 @@ func (c *RaftCluster) collectMetrics() {
 	for label, value := range metrics {
 		clusterStatusGauge.WithLabelValues(label).Set(value)
 	}
+	sample.addClusterMetrics(metrics)
+	c.stats.add(sample)
 
 	c.coordinator.collectSchedulerMetrics()
 	c.coordinator.collectHotSpotMetrics()
@@ -591,11 +1032,60 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 			return
 		case <-ticker.C:
 			c.checkStores()
+			c.checkDownStores()
+			c.gcTombstoneStores()
+			c.cachedCluster.flows.prune(time.Now())
 			c.collectMetrics()
 		}
 	}
 }
 
+// runFlushRegions flushes the buffered region metas until the cluster is
+// stopped.
+func (c *RaftCluster) runFlushRegions(interval time.Duration) {
+	defer c.wg.Done()
+	c.cachedCluster.regionBuffer.run(interval, c.quit)
+}
+
+// runSyncRegions saves all regions to etcd periodically, so that a new
+// leader can load them when the local region storage is used.
+func (c *RaftCluster) runSyncRegions(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+			regions := c.cachedCluster.getMetaRegions()
+			if err := c.s.kv.syncRegions(regions); err != nil {
+				log.Errorf("sync regions to etcd meet error: %v", err)
+				continue
+			}
+			log.Infof("sync %d regions to etcd", len(regions))
+		}
+	}
+}
+
+func (c *RaftCluster) runHeatmap(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.quit:
+			return
+		case now := <-ticker.C:
+			c.heatmap.add(c.cachedCluster.newHeatmapColumn(now))
+		}
+	}
+}
+
 // GetConfig gets config from cluster.
 func (c *RaftCluster) GetConfig() *metapb.Cluster {
 	return c.cachedCluster.getMeta()
@@ -605,7 +1095,11 @@ func (c *RaftCluster) putConfig(meta *metapb.Cluster) error {
 	if meta.GetId() != c.clusterID {
 		return errors.Errorf("invalid cluster %v, mismatch cluster id %d", meta, c.clusterID)
 	}
-	return c.cachedCluster.putMeta(meta)
+	if err := c.cachedCluster.putMeta(meta); err != nil {
+		return errors.Trace(err)
+	}
+	c.s.recordClusterEvent(clusterEventConfig, "cluster config is updated: %v", meta)
+	return nil
 }
 
 // FetchEvents fetches the operator events.
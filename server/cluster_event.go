@@ -0,0 +1,137 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+)
+
+// The types of the cluster events.
+const (
+	clusterEventBootstrap  = "bootstrap"
+	clusterEventLeader     = "leader"
+	clusterEventStoreState = "store_state"
+	clusterEventConfig     = "config"
+)
+
+const (
+	// maxClusterEvents is the number of the recent cluster events kept.
+	maxClusterEvents = 1000
+	// maxLoadClusterEvents is the max number of events loaded at once.
+	maxLoadClusterEvents = 1000
+	// The events are dropped if there are more pending ones.
+	maxPendingClusterEvents = 1024
+)
+
+// ClusterEvent is a significant event of the cluster, e.g. a store state
+// transition or a leader election. The id is the time of the event in
+// nanoseconds, so the events are ordered by time.
+type ClusterEvent struct {
+	ID      uint64    `json:"id"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Member  string    `json:"member"`
+	Message string    `json:"message"`
+}
+
+// clusterEventIDs allocates increasing event ids from the time, the events
+// happening at the same nanosecond get different ids.
+var clusterEventIDs struct {
+	sync.Mutex
+	last uint64
+}
+
+func newClusterEventID(t time.Time) uint64 {
+	clusterEventIDs.Lock()
+	defer clusterEventIDs.Unlock()
+	id := uint64(t.UnixNano())
+	if id <= clusterEventIDs.last {
+		id = clusterEventIDs.last + 1
+	}
+	clusterEventIDs.last = id
+	return id
+}
+
+// recordClusterEvent adds an event to the event history. It's called under
+// the locks of the cluster, so the event is saved in the background by
+// clusterEventLoop, a failure is logged and doesn't fail the caller.
+func (s *Server) recordClusterEvent(typ string, format string, args ...interface{}) {
+	now := time.Now()
+	evt := &ClusterEvent{
+		ID:      newClusterEventID(now),
+		Type:    typ,
+		Time:    now,
+		Member:  s.Name(),
+		Message: fmt.Sprintf(format, args...),
+	}
+	select {
+	case s.clusterEvents <- evt:
+	default:
+		log.Warnf("too many pending cluster events, drop event %+v", evt)
+	}
+}
+
+// clusterEventLoop saves the recorded events, the oldest events are dropped
+// once there are more than maxClusterEvents.
+func (s *Server) clusterEventLoop() {
+	defer s.wg.Done()
+
+	ctx := s.client.Ctx()
+	for {
+		select {
+		case evt := <-s.clusterEvents:
+			s.saveClusterEvent(evt)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) saveClusterEvent(evt *ClusterEvent) {
+	if err := s.kv.saveClusterEvent(evt); err != nil {
+		log.Errorf("save cluster event %+v meet error: %v", evt, err)
+		return
+	}
+	if err := s.kv.trimClusterEvents(maxClusterEvents); err != nil {
+		log.Errorf("trim cluster events meet error: %v", err)
+	}
+}
+
+// GetClusterEvents returns the cluster events in the time range [start, end)
+// from the oldest, a zero end means no upper bound.
+func (s *Server) GetClusterEvents(start, end time.Time) ([]*ClusterEvent, error) {
+	if s.kv == nil {
+		return nil, errors.New("server is not started")
+	}
+	epoch := time.Unix(0, 0)
+	var startID uint64
+	if start.After(epoch) {
+		startID = uint64(start.UnixNano())
+	}
+	endID := uint64(math.MaxUint64)
+	if !end.IsZero() {
+		if !end.After(epoch) {
+			return nil, nil
+		}
+		endID = uint64(end.UnixNano())
+	}
+	evts, err := s.kv.loadClusterEvents(startID, endID, maxLoadClusterEvents)
+	return evts, errors.Trace(err)
+}
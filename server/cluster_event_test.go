@@ -0,0 +1,72 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testClusterEventSuite{})
+
+type testClusterEventSuite struct{}
+
+func (s *testClusterEventSuite) TestClusterEvents(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	evts := mustWaitClusterEvents(c, svr, time.Time{}, 1)
+	c.Assert(evts[0].Type, Equals, clusterEventLeader)
+	c.Assert(evts[0].Member, Equals, svr.Name())
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		svr.recordClusterEvent(clusterEventConfig, "event %d", i)
+	}
+	evts = mustWaitClusterEvents(c, svr, start, 3)
+	for i, evt := range evts {
+		c.Assert(evt.Message, Equals, []string{"event 0", "event 1", "event 2"}[i])
+	}
+	evts, err := svr.GetClusterEvents(time.Time{}, start)
+	c.Assert(err, IsNil)
+	c.Assert(evts, HasLen, 1)
+
+	// The oldest events are dropped.
+	c.Assert(svr.kv.trimClusterEvents(2), IsNil)
+	evts, err = svr.GetClusterEvents(time.Time{}, time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(evts, HasLen, 2)
+	c.Assert(evts[0].Message, Equals, "event 1")
+
+	// The ids are increasing even at the same time.
+	now := time.Now()
+	c.Assert(newClusterEventID(now), Less, newClusterEventID(now))
+}
+
+// mustWaitClusterEvents waits until the count of events since start are
+// saved in the background.
+func mustWaitClusterEvents(c *C, svr *Server, start time.Time, count int) []*ClusterEvent {
+	for i := 0; i < 100; i++ {
+		evts, err := svr.GetClusterEvents(start, time.Time{})
+		c.Assert(err, IsNil)
+		if len(evts) >= count {
+			c.Assert(evts, HasLen, count)
+			return evts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("wait for %d cluster events timeout", count)
+	return nil
+}
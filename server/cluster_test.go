@@ -14,8 +14,10 @@
 package server
 
 import (
+	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
@@ -345,7 +347,7 @@ func (s *testClusterSuite) testPutStore(c *C, clusterID uint64, store *metapb.St
 func (s *testClusterSuite) resetStoreState(c *C, storeID uint64, state metapb.StoreState) {
 	cluster := s.svr.GetRaftCluster().cachedCluster
 	c.Assert(cluster, NotNil)
-	store := cluster.getStore(storeID)
+	store := cluster.GetStore(storeID)
 	c.Assert(store, NotNil)
 	store.State = state
 	cluster.putStore(store)
@@ -494,6 +496,32 @@ func (s *testClusterSuite) TestClosedChannel(c *C) {
 	cluster.stop()
 }
 
+func (s *testClusterSuite) TestSetNamespaceConcurrently(c *C) {
+	// Only one of the namespaces claiming the same store is set.
+	const count = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded []string
+	)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := s.svr.SetNamespace(&Namespace{Name: name, StoreIDs: []uint64{100}}); err == nil {
+				mu.Lock()
+				succeeded = append(succeeded, name)
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("concurrent-ns%d", i))
+	}
+	wg.Wait()
+	c.Assert(succeeded, HasLen, 1)
+	c.Assert(s.svr.GetNamespace(succeeded[0]).StoreIDs, DeepEquals, []uint64{100})
+	c.Assert(s.svr.DeleteNamespace(succeeded[0]), IsNil)
+	c.Assert(s.svr.GetNamespaces(), HasLen, 0)
+}
+
 func (s *testClusterSuite) TestGetPDMembers(c *C) {
 
 	req := &pdpb.GetMembersRequest{
@@ -504,4 +532,61 @@ func (s *testClusterSuite) TestGetPDMembers(c *C) {
 	c.Assert(err, IsNil)
 	// A more strict test can be found at api/member_test.go
 	c.Assert(len(resp.GetMembers()), Not(Equals), 0)
+
+	// The cluster ID is checked only if it's set.
+	_, err = s.grpcPDClient.GetMembers(context.Background(), &pdpb.GetMembersRequest{})
+	c.Assert(err, IsNil)
+	req.Header = newRequestHeader(s.svr.ClusterID() + 1)
+	_, err = s.grpcPDClient.GetMembers(context.Background(), req)
+	c.Assert(err, ErrorMatches, ".*mismatch cluster id.*")
+}
+
+func (s *testClusterSuite) TestGCTombstoneStores(c *C) {
+	clusterID := s.svr.clusterID
+	s.tryBootstrapCluster(c, s.grpcPDClient, clusterID, "127.0.0.1:0")
+	cluster := s.svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+
+	store := s.newStore(c, 0, "127.0.0.1:22222")
+	_, err := putStore(c, s.grpcPDClient, clusterID, store)
+	c.Assert(err, IsNil)
+	// Only the tombstone stores can be destroyed.
+	c.Assert(cluster.ConfirmStoreDestroyed(store.GetId()), NotNil)
+	c.Assert(cluster.BuryStore(store.GetId(), true), IsNil)
+
+	// The time of a loaded tombstone store is counted from now.
+	stores := newStoresInfo()
+	c.Assert(s.svr.kv.loadStores(stores, kvRangeLimit), IsNil)
+	c.Assert(stores.getStore(store.GetId()).tombstoneTime() < time.Minute, IsTrue)
+
+	opt := s.svr.scheduleOpt
+	origin := opt.load()
+	defer opt.store(origin)
+	cfg := *origin
+
+	// GC is disabled.
+	cfg.TombstoneStoreRetention.Duration = 0
+	opt.store(&cfg)
+	cluster.gcTombstoneStores()
+	c.Assert(cluster.cachedCluster.GetStore(store.GetId()), NotNil)
+
+	// The store is not buried long enough.
+	cfg.EnableTombstoneStoreGC = true
+	cfg.TombstoneStoreRetention.Duration = time.Hour
+	opt.store(&cfg)
+	cluster.gcTombstoneStores()
+	c.Assert(cluster.cachedCluster.GetStore(store.GetId()), NotNil)
+
+	// The store is not confirmed to be destroyed.
+	cfg.TombstoneStoreRetention.Duration = 0
+	opt.store(&cfg)
+	cluster.gcTombstoneStores()
+	c.Assert(cluster.cachedCluster.GetStore(store.GetId()), NotNil)
+
+	c.Assert(cluster.ConfirmStoreDestroyed(store.GetId()), IsNil)
+	cluster.gcTombstoneStores()
+	c.Assert(cluster.cachedCluster.GetStore(store.GetId()), IsNil)
+	ok, err := s.svr.kv.loadStore(store.GetId(), &metapb.Store{})
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
 }
@@ -564,7 +564,7 @@ func (s *testClusterWorkerSuite) TestStoreHeartbeat(c *C) {
 	resp := s.heartbeatStore(c, 0, stats)
 	c.Assert(resp, NotNil)
 
-	store := cluster.cachedCluster.getStore(storeID)
+	store := cluster.cachedCluster.GetStore(storeID)
 	c.Assert(stats, DeepEquals, store.status.StoreStats)
 }
 
@@ -596,4 +596,28 @@ func (s *testClusterWorkerSuite) TestReportSplit(c *C) {
 	c.Assert(op.Origin.GetEndKey(), BytesEquals, right.GetEndKey())
 	c.Assert(op.Origin.GetPeers(), HasLen, 1)
 	c.Assert(op.Origin.GetPeers()[0], DeepEquals, peer)
+
+	// Check split histories.
+	c.Assert(cluster.coordinator.getSplitHistories([]byte("b"), []byte("c")), HasLen, 1)
+	c.Assert(cluster.coordinator.getSplitHistories([]byte("ccc"), nil), HasLen, 0)
+}
+
+func (s *testClusterWorkerSuite) TestPushToStore(c *C) {
+	cluster := s.svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+
+	region, _ := cluster.GetRegionByKey([]byte("a"))
+	leaderPeer := s.chooseRegionLeader(c, region)
+	storeID := leaderPeer.GetStoreId()
+
+	// No stream for the store before heartbeat.
+	c.Assert(s.svr.sendStoreError(storeID, pdpb.ErrorType_UNKNOWN, "test error"), NotNil)
+
+	resp := s.heartbeatRegion(c, s.clusterID, 0, region, leaderPeer)
+	c.Assert(resp, IsNil)
+
+	c.Assert(s.svr.sendStoreError(storeID, pdpb.ErrorType_UNKNOWN, "test error"), IsNil)
+	resp = <-s.heartbeatRespCh
+	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_UNKNOWN)
+	c.Assert(resp.GetHeader().GetError().GetMessage(), Equals, "test error")
 }
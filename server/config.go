@@ -24,7 +24,9 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/coreos/etcd/embed"
+	"github.com/coreos/etcd/pkg/types"
 	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/pkg/logutil"
 	"github.com/pingcap/pd/pkg/metricutil"
 	"github.com/pingcap/pd/pkg/testutil"
@@ -37,6 +39,8 @@ type Config struct {
 
 	Version bool `json:"-"`
 
+	ConfigCheck bool `json:"-"`
+
 	ClientUrls          string `toml:"client-urls" json:"client-urls"`
 	PeerUrls            string `toml:"peer-urls" json:"peer-urls"`
 	AdvertiseClientUrls string `toml:"advertise-client-urls" json:"advertise-client-urls"`
@@ -51,6 +55,10 @@ type Config struct {
 	// Join to an existing pd cluster, a string of endpoints.
 	Join string `toml:"join" json:"join"`
 
+	// DiscoverySRV is the domain to resolve the initial cluster from its DNS
+	// SRV records as etcd does.
+	DiscoverySRV string `toml:"discovery-srv" json:"discovery-srv"`
+
 	// LeaderLease time, if leader doesn't update its TTL
 	// in etcd after lease time, etcd will expire the leader key
 	// and other servers can campaign the leader again.
@@ -60,12 +68,22 @@ type Config struct {
 	// Log related config.
 	Log logutil.LogConfig `toml:"log" json:"log"`
 
+	// SlowLog is the config of the slow request log.
+	SlowLog SlowLogConfig `toml:"slow-log" json:"slow-log"`
+
+	// Alert is the config of the alerts on critical events.
+	Alert AlertConfig `toml:"alert" json:"alert"`
+
 	// Backward compatibility.
 	LogFileDeprecated  string `toml:"log-file" json:"log-file"`
 	LogLevelDeprecated string `toml:"log-level" json:"log-level"`
 
 	// TsoSaveInterval is the interval to save timestamp.
 	TsoSaveInterval typeutil.Duration `toml:"tso-save-interval" json:"tso-save-interval"`
+	// TsoMaxClockJump is how far the clock can jump, the leader stops serving
+	// the TSO if the clock jumps further. It must be greater than the
+	// tso-save-interval.
+	TsoMaxClockJump typeutil.Duration `toml:"tso-max-clock-jump" json:"tso-max-clock-jump"`
 
 	Metric metricutil.MetricConfig `toml:"metric" json:"metric"`
 
@@ -79,11 +97,24 @@ type Config struct {
 	// AutoCompactionRetention for mvcc key value store in hour. 0 means disable auto compaction.
 	// the default retention is 1 hour
 	AutoCompactionRetention int `toml:"auto-compaction-retention" json:"auto-compaction-retention"`
+	// SnapshotCount is the number of committed transactions to trigger a
+	// snapshot of the embedded etcd. 0 means use the default count of etcd.
+	SnapshotCount uint64 `toml:"snapshot-count" json:"snapshot-count"`
+
+	// UseRegionStorage enables saving region metas in a local storage on
+	// the leader, they are synced to etcd periodically.
+	UseRegionStorage bool `toml:"use-region-storage" json:"use-region-storage"`
 
-	tickMs     uint64
-	electionMs uint64
+	// TickInterval is the heartbeat interval of the embedded etcd.
+	TickInterval typeutil.Duration `toml:"tick-interval" json:"tick-interval"`
+	// ElectionInterval is the election timeout of the embedded etcd, it must
+	// be at least 5 times of TickInterval. A shorter one detects a failed etcd
+	// leader sooner, at the cost of more spurious elections.
+	ElectionInterval typeutil.Duration `toml:"election-interval" json:"election-interval"`
 
 	configFile string
+	// unknownItems are the items in the config file which are not decoded.
+	unknownItems []string
 
 	// For all warnings during parsing.
 	WarningMsgs []string
@@ -91,6 +122,26 @@ type Config struct {
 	// Only test can change them.
 	nextRetryDelay             time.Duration
 	disableStrictReconfigCheck bool
+	// leaderPriorityCheckInterval is the interval the leader checks if it
+	// should hand the leadership over to a member with higher priority.
+	leaderPriorityCheckInterval time.Duration
+}
+
+// SlowLogConfig is the config of the slow request log.
+type SlowLogConfig struct {
+	// Threshold is the duration beyond which a gRPC or HTTP request is
+	// logged, 0 disables the slow log.
+	Threshold typeutil.Duration `toml:"threshold" json:"threshold"`
+	// File is the slow log file, the slow requests are written to the log
+	// if the filename is empty.
+	File logutil.FileLogConfig `toml:"file" json:"file"`
+}
+
+// AlertConfig is the config of the alerts on critical events.
+type AlertConfig struct {
+	// WebhookURLs are the urls the alerts are posted to in JSON, leave it
+	// empty to disable the alerts.
+	WebhookURLs typeutil.StringSlice `toml:"webhook-urls" json:"webhook-urls"`
 }
 
 // NewConfig creates a new config.
@@ -102,6 +153,7 @@ func NewConfig() *Config {
 	fs.BoolVar(&cfg.Version, "V", false, "print version information and exit")
 	fs.BoolVar(&cfg.Version, "version", false, "print version information and exit")
 	fs.StringVar(&cfg.configFile, "config", "", "Config file")
+	fs.BoolVar(&cfg.ConfigCheck, "config-check", false, "check config file validity and exit")
 
 	fs.StringVar(&cfg.Name, "name", defaultName, "human-readable name for this pd member")
 
@@ -112,6 +164,7 @@ func NewConfig() *Config {
 	fs.StringVar(&cfg.AdvertisePeerUrls, "advertise-peer-urls", "", "advertise url for peer traffic (default '${peer-urls}')")
 	fs.StringVar(&cfg.InitialCluster, "initial-cluster", "", "initial cluster configuration for bootstrapping, e,g. pd=http://127.0.0.1:2380")
 	fs.StringVar(&cfg.Join, "join", "", "join to an existing cluster (usage: cluster's '${advertise-client-urls}'")
+	fs.StringVar(&cfg.DiscoverySRV, "discovery-srv", "", "DNS domain used to bootstrap the initial cluster by SRV records")
 
 	fs.StringVar(&cfg.Log.Level, "L", "", "log level: debug, info, warn, error, fatal (default 'info')")
 	fs.StringVar(&cfg.Log.File.Filename, "log-file", "", "log file path")
@@ -121,8 +174,11 @@ func NewConfig() *Config {
 
 const (
 	defaultLeaderLease             = int64(3)
+	defaultTsoMaxClockJump         = time.Minute
 	defaultNextRetryDelay          = time.Second
 	defaultAutoCompactionRetention = 1
+	// maxQuotaBackendBytes is the max backend quota suggested by etcd.
+	maxQuotaBackendBytes = 8 * typeutil.ByteSize(1<<30)
 
 	defaultName                = "pd"
 	defaultClientUrls          = "http://127.0.0.1:2379"
@@ -133,9 +189,9 @@ const (
 	// We can enlarge both a little to reduce the network aggression.
 	// now embed etcd use TickMs for heartbeat, we will update
 	// after embed etcd decouples tick and heartbeat.
-	defaultTickMs = uint64(500)
-	// embed etcd has a check that `5 * tick > election`
-	defaultElectionMs = uint64(3000)
+	defaultTickInterval = 500 * time.Millisecond
+	// embed etcd has a check that `election >= 5 * tick`
+	defaultElectionInterval = 3000 * time.Millisecond
 )
 
 func adjustString(v *string, defValue string) {
@@ -194,6 +250,10 @@ func (c *Config) Parse(arguments []string) error {
 			msg := fmt.Sprintf("log-level in %s is deprecated, use [log] instead", c.configFile)
 			c.WarningMsgs = append(c.WarningMsgs, msg)
 		}
+		for _, item := range c.unknownItems {
+			msg := fmt.Sprintf("config item %s in %s is unknown", item, c.configFile)
+			c.WarningMsgs = append(c.WarningMsgs, msg)
+		}
 	}
 
 	// Parse again to replace with command line options.
@@ -214,6 +274,9 @@ func (c *Config) validate() error {
 	if c.Join != "" && c.InitialCluster != "" {
 		return errors.New("-initial-cluster and -join can not be provided at the same time")
 	}
+	if c.DiscoverySRV != "" && (c.Join != "" || c.InitialCluster != "") {
+		return errors.New("-discovery-srv can not be provided with -initial-cluster or -join")
+	}
 	return nil
 }
 
@@ -230,7 +293,8 @@ func (c *Config) adjust() error {
 	adjustString(&c.PeerUrls, defaultPeerUrls)
 	adjustString(&c.AdvertisePeerUrls, c.PeerUrls)
 
-	if len(c.InitialCluster) == 0 {
+	// The initial cluster is resolved at startup with discovery-srv.
+	if len(c.InitialCluster) == 0 && c.DiscoverySRV == "" {
 		// The advertise peer urls may be http://127.0.0.1:2380,http://127.0.0.1:2381
 		// so the initial cluster is pd=http://127.0.0.1:2380,pd=http://127.0.0.1:2381
 		items := strings.Split(c.AdvertisePeerUrls, ",")
@@ -247,22 +311,38 @@ func (c *Config) adjust() error {
 	adjustInt64(&c.LeaderLease, defaultLeaderLease)
 
 	adjustDuration(&c.TsoSaveInterval, time.Duration(defaultLeaderLease)*time.Second)
+	if c.TsoSaveInterval.Duration < updateTimestampStep {
+		return errors.Errorf("tso-save-interval %v should be at least %v", c.TsoSaveInterval.Duration, updateTimestampStep)
+	}
+	adjustDuration(&c.TsoMaxClockJump, defaultTsoMaxClockJump)
+	if c.TsoMaxClockJump.Duration <= c.TsoSaveInterval.Duration {
+		return errors.Errorf("tso-max-clock-jump %v should be greater than tso-save-interval %v", c.TsoMaxClockJump.Duration, c.TsoSaveInterval.Duration)
+	}
 
 	if c.nextRetryDelay == 0 {
 		c.nextRetryDelay = defaultNextRetryDelay
 	}
+	if c.leaderPriorityCheckInterval == 0 {
+		c.leaderPriorityCheckInterval = defaultLeaderPriorityCheckInterval
+	}
 	if c.AutoCompactionRetention == 0 {
 		c.AutoCompactionRetention = defaultAutoCompactionRetention
 	}
+	if c.QuotaBackendBytes > maxQuotaBackendBytes {
+		return errors.Errorf("quota-backend-bytes %v should not be larger than %v", c.QuotaBackendBytes, maxQuotaBackendBytes)
+	}
 
-	adjustUint64(&c.tickMs, defaultTickMs)
-	adjustUint64(&c.electionMs, defaultElectionMs)
+	adjustDuration(&c.TickInterval, defaultTickInterval)
+	adjustDuration(&c.ElectionInterval, defaultElectionInterval)
+	if c.ElectionInterval.Duration < 5*c.TickInterval.Duration {
+		return errors.Errorf("election-interval %v should be at least 5 times of tick-interval %v", c.ElectionInterval.Duration, c.TickInterval.Duration)
+	}
 
 	adjustString(&c.Metric.PushJob, c.Name)
 
 	c.Schedule.adjust()
 	c.Replication.adjust()
-	return nil
+	return errors.Trace(c.Schedule.validate())
 }
 
 func (c *Config) clone() *Config {
@@ -280,8 +360,72 @@ func (c *Config) String() string {
 
 // configFromFile loads config from file.
 func (c *Config) configFromFile(path string) error {
-	_, err := toml.DecodeFile(path, c)
-	return errors.Trace(err)
+	meta, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.unknownItems = c.unknownItems[:0]
+	for _, key := range meta.Undecoded() {
+		c.unknownItems = append(c.unknownItems, key.String())
+	}
+	return nil
+}
+
+// Check checks the parsed config more strictly than starting the server, the
+// unknown config items and the urls are checked too, all the problems found
+// are reported in one error. It is used by the config-check mode to catch a
+// bad config before restarting a member.
+func (c *Config) Check() error {
+	var problems []string
+	addProblem := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, item := range c.unknownItems {
+		addProblem(errors.Errorf("unknown config item %s", item))
+	}
+	urls := []struct {
+		name string
+		urls string
+	}{
+		{"client-urls", c.ClientUrls},
+		{"advertise-client-urls", c.AdvertiseClientUrls},
+		{"peer-urls", c.PeerUrls},
+		{"advertise-peer-urls", c.AdvertisePeerUrls},
+	}
+	for _, u := range urls {
+		if _, err := types.NewURLs(strings.Split(u.urls, ",")); err != nil {
+			addProblem(errors.Errorf("invalid %s %q: %v", u.name, u.urls, err))
+		}
+	}
+	// The initial cluster is generated by join or discovery-srv if it is set.
+	if c.Join != "" {
+		if _, err := types.NewURLs(strings.Split(c.Join, ",")); err != nil {
+			addProblem(errors.Errorf("invalid join %q: %v", c.Join, err))
+		}
+	} else if c.DiscoverySRV == "" {
+		m, err := types.NewURLsMap(c.InitialCluster)
+		if err != nil {
+			addProblem(errors.Errorf("invalid initial-cluster %q: %v", c.InitialCluster, err))
+		} else if _, ok := m[c.Name]; !ok {
+			addProblem(errors.Errorf("initial-cluster %q doesn't contain the member %s", c.InitialCluster, c.Name))
+		}
+	}
+	if etcdCfg, err := c.genEmbedEtcdConfig(); err != nil {
+		addProblem(err)
+	} else {
+		addProblem(etcdCfg.Validate())
+	}
+
+	addProblem(c.Schedule.validate())
+	addProblem(c.Replication.validate())
+
+	if len(problems) > 0 {
+		return errors.Errorf("%d problems found: %s", len(problems), strings.Join(problems, "; "))
+	}
+	return nil
 }
 
 // ScheduleConfig is the schedule configuration.
@@ -291,30 +435,273 @@ type ScheduleConfig struct {
 	MaxSnapshotCount uint64 `toml:"max-snapshot-count,omitempty" json:"max-snapshot-count"`
 	// MaxStoreDownTime is the max duration after which
 	// a store will be considered to be down if it hasn't reported heartbeats.
+	// A down store is shown as Down by the API, and its replicas are repaired.
 	MaxStoreDownTime typeutil.Duration `toml:"max-store-down-time,omitempty" json:"max-store-down-time"`
+	// If the pending peer count of one store is greater than this value,
+	// it will never be used as a target store.
+	MaxPendingPeerCount uint64 `toml:"max-pending-peer-count,omitempty" json:"max-pending-peer-count"`
 	// LeaderScheduleLimit is the max coexist leader schedules.
+	// The schedule limits can be updated by API, setting a limit to 0 pauses
+	// the kind of schedules.
 	LeaderScheduleLimit uint64 `toml:"leader-schedule-limit,omitempty" json:"leader-schedule-limit"`
 	// RegionScheduleLimit is the max coexist region schedules.
 	RegionScheduleLimit uint64 `toml:"region-schedule-limit,omitempty" json:"region-schedule-limit"`
 	// ReplicaScheduleLimit is the max coexist replica schedules.
 	ReplicaScheduleLimit uint64 `toml:"replica-schedule-limit,omitempty" json:"replica-schedule-limit"`
+	// EnableTombstoneStoreGC removes the tombstone stores confirmed to be
+	// destroyed after they have been buried for TombstoneStoreRetention. The
+	// ones buried before the confirmation is introduced are kept until they
+	// are confirmed too.
+	EnableTombstoneStoreGC  bool              `toml:"enable-tombstone-store-gc" json:"enable-tombstone-store-gc"`
+	TombstoneStoreRetention typeutil.Duration `toml:"tombstone-store-retention,omitempty" json:"tombstone-store-retention"`
+	// HotRegionThreshold is the number of continuous heartbeats a region's
+	// write flow must be hot for before it is scheduled as a hot region.
+	HotRegionThreshold uint64 `toml:"hot-region-threshold,omitempty" json:"hot-region-threshold"`
+	// HotRegionFlowRatio is the multiple of the average flow of the regions
+	// beyond which the flow of a region is hot.
+	HotRegionFlowRatio float64 `toml:"hot-region-flow-ratio,omitempty" json:"hot-region-flow-ratio"`
+	// LeaderSchedulePolicy is the score used to balance leaders, "count"
+	// balances the leader count and "size" balances the leader region size.
+	LeaderSchedulePolicy string `toml:"leader-schedule-policy,omitempty" json:"leader-schedule-policy"`
+	// TolerantSizeRatio is the tolerance of balance against moving regions
+	// back and forth between stores. A store is balanced only if its score is
+	// larger than the target by the ratio of the effect of one move.
+	TolerantSizeRatio float64 `toml:"tolerant-size-ratio,omitempty" json:"tolerant-size-ratio"`
+	// ThrottleWindows are the daily time windows in which the region
+	// schedules are throttled, e.g. in the business hours. The replica
+	// schedules are not throttled.
+	ThrottleWindows []ThrottleWindow `toml:"throttle-windows,omitempty" json:"throttle-windows"`
+	// EnableDryRun makes the schedulers only report the operators they create
+	// instead of dispatching them, the checkers are not affected.
+	EnableDryRun bool `toml:"enable-dry-run" json:"enable-dry-run"`
+	// DisableScheduling stops all the schedulers and checkers, e.g. during
+	// backups and upgrades. The heartbeats are still handled and the running
+	// operators still go on.
+	DisableScheduling bool `toml:"disable-scheduling" json:"disable-scheduling"`
+	// LabelProperty is the properties of the stores with some labels, e.g.
+	// the stores with the reject-leader labels don't keep leaders.
+	LabelProperty LabelPropertyConfig `toml:"label-property,omitempty" json:"label-property"`
+	// HighSpaceRatio is the used space ratio over which a store gets no new
+	// replicas or leaders.
+	HighSpaceRatio float64 `toml:"high-space-ratio,omitempty" json:"high-space-ratio"`
+	// LowSpaceRatio is the used space ratio over which a store is low on
+	// space, the replicas on it are moved out to the other stores. It should
+	// be larger than HighSpaceRatio.
+	LowSpaceRatio float64 `toml:"low-space-ratio,omitempty" json:"low-space-ratio"`
+	// StoreScore is the weights of the region score used to balance regions.
+	StoreScore StoreScoreConfig `toml:"store-score" json:"store-score"`
+}
+
+// StoreScoreConfig is the weights of the store region score. The score is the
+// weighted sum of the region size, the leader count and the written bytes, on
+// the capacity to the power of CapacityWeight. For example, a lower
+// CapacityWeight fills the small SSD stores in a mixed-capacity cluster
+// faster, and a FlowWeight keeps the replicas off the busy stores.
+type StoreScoreConfig struct {
+	// RegionSizeWeight is the weight of the region size.
+	RegionSizeWeight float64 `toml:"region-size-weight,omitempty" json:"region-size-weight"`
+	// LeaderCountWeight is the weight of the leader count, a leader counts
+	// as a region of the default region size.
+	LeaderCountWeight float64 `toml:"leader-count-weight,omitempty" json:"leader-count-weight"`
+	// CapacityWeight is the power of the capacity, in (0, 1]. 1 balances the
+	// regions by the used ratio of the capacity.
+	CapacityWeight float64 `toml:"capacity-weight,omitempty" json:"capacity-weight"`
+	// FlowWeight is the weight of the bytes written in the last store
+	// heartbeat.
+	FlowWeight float64 `toml:"flow-weight,omitempty" json:"flow-weight"`
+}
+
+func (c *StoreScoreConfig) validate() error {
+	if c.RegionSizeWeight <= 0 || c.LeaderCountWeight < 0 || c.FlowWeight < 0 {
+		return errors.New("region-size-weight should be larger than 0, leader-count-weight and flow-weight should not be negative")
+	}
+	if c.CapacityWeight <= 0 || c.CapacityWeight > 1 {
+		return errors.Errorf("capacity-weight %v should be in (0, 1]", c.CapacityWeight)
+	}
+	return nil
+}
+
+func (c *StoreScoreConfig) adjust() {
+	adjustFloat64(&c.RegionSizeWeight, defaultRegionSizeWeight)
+	adjustFloat64(&c.CapacityWeight, defaultCapacityWeight)
+}
+
+// Label properties.
+const (
+	// RejectLeader makes the stores reject leaders, the leaders on them are
+	// transferred to other stores.
+	RejectLeader = "reject-leader"
+)
+
+// StoreLabel is a store label of a label property.
+type StoreLabel struct {
+	Key   string `toml:"key" json:"key"`
+	Value string `toml:"value" json:"value"`
+}
+
+// LabelPropertyConfig maps the label properties to the store labels, a store
+// has a property if it has any of the labels of the property.
+type LabelPropertyConfig map[string][]StoreLabel
+
+func (c LabelPropertyConfig) validate() error {
+	for typ, labels := range c {
+		if typ != RejectLeader {
+			return errors.Errorf("unsupported label property %q", typ)
+		}
+		for _, label := range labels {
+			if label.Key == "" {
+				return errors.Errorf("label key of property %s is empty", typ)
+			}
+		}
+	}
+	return nil
+}
+
+// hasProperty checks whether the store labels have the property.
+func (c LabelPropertyConfig) hasProperty(typ string, labels []*metapb.StoreLabel) bool {
+	for _, l := range c[typ] {
+		for _, label := range labels {
+			if label.GetKey() == l.Key && label.GetValue() == l.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ThrottleWindow limits the region schedules to RegionScheduleLimit from
+// StartTime to EndTime every day, 0 disables the region schedules. The times
+// are "15:04" in the local time zone, a window ends on the next day if its end
+// time is not later than the start time.
+type ThrottleWindow struct {
+	StartTime           string `toml:"start-time" json:"start-time"`
+	EndTime             string `toml:"end-time" json:"end-time"`
+	RegionScheduleLimit uint64 `toml:"region-schedule-limit" json:"region-schedule-limit"`
+
+	// start and end are the parsed times in minutes since midnight, they are
+	// equal if the window is not parsed.
+	start int
+	end   int
+}
+
+// parseDayMinute parses the "15:04" time to the minutes since midnight.
+func parseDayMinute(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parse validates the window and keeps the parsed times.
+func (w *ThrottleWindow) parse() error {
+	start, err := parseDayMinute(w.StartTime)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	end, err := parseDayMinute(w.EndTime)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if start == end {
+		return errors.Errorf("throttle window %s-%s is empty", w.StartTime, w.EndTime)
+	}
+	w.start, w.end = start, end
+	return nil
 }
 
+// contains checks whether the time is in the window, a window not parsed
+// contains nothing.
+func (w *ThrottleWindow) contains(t time.Time) bool {
+	if w.start == w.end {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// Leader schedule policies.
+const (
+	LeaderSchedulePolicyCount = "count"
+	LeaderSchedulePolicySize  = "size"
+)
+
 const (
 	defaultMaxReplicas          = 3
 	defaultMaxSnapshotCount     = 3
 	defaultMaxStoreDownTime     = time.Hour
+	defaultMaxPendingPeerCount  = 16
 	defaultLeaderScheduleLimit  = 1024
 	defaultRegionScheduleLimit  = 12
 	defaultReplicaScheduleLimit = 16
+	defaultTombstoneRetention   = 24 * time.Hour
+	defaultHotRegionThreshold   = 3
+	defaultHotRegionFlowRatio   = 4
+	defaultTolerantSizeRatio    = 1
+	defaultHighSpaceRatio       = 0.8
+	defaultLowSpaceRatio        = 0.9
+	defaultRegionSizeWeight     = 1
+	defaultCapacityWeight       = 1
 )
 
+func (c *ScheduleConfig) validate() error {
+	if err := c.parseThrottleWindows(); err != nil {
+		return errors.Trace(err)
+	}
+	if c.HighSpaceRatio <= 0 || c.HighSpaceRatio >= c.LowSpaceRatio || c.LowSpaceRatio > 1 {
+		return errors.Errorf("high-space-ratio %v and low-space-ratio %v should be 0 < high-space-ratio < low-space-ratio <= 1", c.HighSpaceRatio, c.LowSpaceRatio)
+	}
+	if c.HotRegionFlowRatio < 1 {
+		return errors.Errorf("hot-region-flow-ratio %v should be at least 1", c.HotRegionFlowRatio)
+	}
+	if err := c.StoreScore.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.LabelProperty.validate())
+}
+
+// parseThrottleWindows parses the times of the throttle windows once when
+// the config is validated or loaded, so they are not parsed on every check of
+// the region schedule limit.
+func (c *ScheduleConfig) parseThrottleWindows() error {
+	for i := range c.ThrottleWindows {
+		if err := c.ThrottleWindows[i].parse(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// getRegionScheduleLimit returns the region schedule limit at the time, it is
+// the min limit of RegionScheduleLimit and the windows containing the time.
+func (c *ScheduleConfig) getRegionScheduleLimit(t time.Time) uint64 {
+	limit := c.RegionScheduleLimit
+	for i := range c.ThrottleWindows {
+		if w := &c.ThrottleWindows[i]; w.contains(t) {
+			limit = minUint64(limit, w.RegionScheduleLimit)
+		}
+	}
+	return limit
+}
+
 func (c *ScheduleConfig) adjust() {
 	adjustUint64(&c.MaxSnapshotCount, defaultMaxSnapshotCount)
 	adjustDuration(&c.MaxStoreDownTime, defaultMaxStoreDownTime)
+	adjustUint64(&c.MaxPendingPeerCount, defaultMaxPendingPeerCount)
 	adjustUint64(&c.LeaderScheduleLimit, defaultLeaderScheduleLimit)
 	adjustUint64(&c.RegionScheduleLimit, defaultRegionScheduleLimit)
 	adjustUint64(&c.ReplicaScheduleLimit, defaultReplicaScheduleLimit)
+	adjustDuration(&c.TombstoneStoreRetention, defaultTombstoneRetention)
+	adjustUint64(&c.HotRegionThreshold, defaultHotRegionThreshold)
+	adjustFloat64(&c.HotRegionFlowRatio, defaultHotRegionFlowRatio)
+	adjustString(&c.LeaderSchedulePolicy, LeaderSchedulePolicyCount)
+	adjustFloat64(&c.TolerantSizeRatio, defaultTolerantSizeRatio)
+	adjustFloat64(&c.HighSpaceRatio, defaultHighSpaceRatio)
+	adjustFloat64(&c.LowSpaceRatio, defaultLowSpaceRatio)
+	c.StoreScore.adjust()
 }
 
 // ReplicationConfig is the replication configuration.
@@ -338,20 +725,41 @@ func (c *ReplicationConfig) clone() *ReplicationConfig {
 	}
 }
 
+func (c *ReplicationConfig) validate() error {
+	if c.MaxReplicas == 0 {
+		return errors.New("max-replicas should be larger than 0")
+	}
+	labels := make(map[string]struct{}, len(c.LocationLabels))
+	for _, label := range c.LocationLabels {
+		if label == "" {
+			return errors.New("location label should not be empty")
+		}
+		if _, ok := labels[label]; ok {
+			return errors.Errorf("location label %q is duplicated", label)
+		}
+		labels[label] = struct{}{}
+	}
+	return nil
+}
+
 func (c *ReplicationConfig) adjust() {
 	adjustUint64(&c.MaxReplicas, defaultMaxReplicas)
 }
 
 // scheduleOption is a wrapper to access the configuration safely.
 type scheduleOption struct {
-	v   atomic.Value
-	rep *Replication
+	v          atomic.Value
+	rep        *Replication
+	rules      *placementRules
+	namespaces *namespacesInfo
 }
 
 func newScheduleOption(cfg *Config) *scheduleOption {
 	o := &scheduleOption{}
 	o.store(&cfg.Schedule)
 	o.rep = newReplication(&cfg.Replication)
+	o.rules = newPlacementRules()
+	o.namespaces = newNamespacesInfo()
 	return o
 }
 
@@ -371,6 +779,19 @@ func (o *scheduleOption) GetMaxReplicas() int {
 	return o.rep.GetMaxReplicas()
 }
 
+func (o *scheduleOption) getRules() *placementRules {
+	return o.rules
+}
+
+func (o *scheduleOption) getNamespaces() *namespacesInfo {
+	return o.namespaces
+}
+
+// getRegionMaxReplicas returns max-replicas of the namespace the region is in.
+func (o *scheduleOption) getRegionMaxReplicas(region *RegionInfo) int {
+	return o.namespaces.getMaxReplicas(region, o.rep.GetMaxReplicas())
+}
+
 func (o *scheduleOption) SetMaxReplicas(replicas int) {
 	o.rep.SetMaxReplicas(replicas)
 }
@@ -383,20 +804,71 @@ func (o *scheduleOption) GetMaxStoreDownTime() time.Duration {
 	return o.load().MaxStoreDownTime.Duration
 }
 
+func (o *scheduleOption) GetMaxPendingPeerCount() uint64 {
+	return o.load().MaxPendingPeerCount
+}
+
 func (o *scheduleOption) GetLeaderScheduleLimit() uint64 {
 	return o.load().LeaderScheduleLimit
 }
 
+// GetRegionScheduleLimit returns the region schedule limit, it may be
+// throttled by the throttle windows.
 func (o *scheduleOption) GetRegionScheduleLimit() uint64 {
-	return o.load().RegionScheduleLimit
+	return o.load().getRegionScheduleLimit(time.Now())
 }
 
 func (o *scheduleOption) GetReplicaScheduleLimit() uint64 {
 	return o.load().ReplicaScheduleLimit
 }
 
-func (o *scheduleOption) persist(kv *kv) error {
-	return kv.saveScheduleOption(o)
+func (o *scheduleOption) IsTombstoneStoreGCEnabled() bool {
+	return o.load().EnableTombstoneStoreGC
+}
+
+func (o *scheduleOption) GetTombstoneStoreRetention() time.Duration {
+	return o.load().TombstoneStoreRetention.Duration
+}
+
+func (o *scheduleOption) GetHotRegionThreshold() int {
+	return int(o.load().HotRegionThreshold)
+}
+
+func (o *scheduleOption) GetHotRegionFlowRatio() float64 {
+	return o.load().HotRegionFlowRatio
+}
+
+func (o *scheduleOption) GetLeaderSchedulePolicy() string {
+	return o.load().LeaderSchedulePolicy
+}
+
+func (o *scheduleOption) GetTolerantSizeRatio() float64 {
+	return o.load().TolerantSizeRatio
+}
+
+func (o *scheduleOption) IsDryRunEnabled() bool {
+	return o.load().EnableDryRun
+}
+
+func (o *scheduleOption) IsSchedulingDisabled() bool {
+	return o.load().DisableScheduling
+}
+
+func (o *scheduleOption) GetHighSpaceRatio() float64 {
+	return o.load().HighSpaceRatio
+}
+
+func (o *scheduleOption) GetLowSpaceRatio() float64 {
+	return o.load().LowSpaceRatio
+}
+
+func (o *scheduleOption) GetStoreScore() StoreScoreConfig {
+	return o.load().StoreScore
+}
+
+// CheckLabelProperty checks whether the store labels have the label property.
+func (o *scheduleOption) CheckLabelProperty(typ string, labels []*metapb.StoreLabel) bool {
+	return o.load().LabelProperty.hasProperty(typ, labels)
 }
 
 // ParseUrls parse a string into multiple urls.
@@ -426,10 +898,15 @@ func (c *Config) genEmbedEtcdConfig() (*embed.Config, error) {
 	cfg.ClusterState = c.InitialClusterState
 	cfg.EnablePprof = true
 	cfg.StrictReconfigCheck = !c.disableStrictReconfigCheck
-	cfg.TickMs = uint(c.tickMs)
-	cfg.ElectionMs = uint(c.electionMs)
+	cfg.TickMs = uint(c.TickInterval.Duration / time.Millisecond)
+	cfg.ElectionMs = uint(c.ElectionInterval.Duration / time.Millisecond)
+	// TODO: enable the pre-vote after etcd is updated to 3.3, the embed config
+	// of the vendored etcd has no option for it.
 	cfg.AutoCompactionRetention = c.AutoCompactionRetention
 	cfg.QuotaBackendBytes = int64(c.QuotaBackendBytes)
+	if c.SnapshotCount > 0 {
+		cfg.SnapCount = c.SnapshotCount
+	}
 
 	var err error
 
@@ -475,8 +952,8 @@ func NewTestSingleConfig() *Config {
 	cfg.DataDir, _ = ioutil.TempDir("/tmp", "test_pd")
 	cfg.InitialCluster = fmt.Sprintf("pd=%s", cfg.PeerUrls)
 	cfg.disableStrictReconfigCheck = true
-	cfg.tickMs = 100
-	cfg.electionMs = 1000
+	cfg.TickInterval = typeutil.NewDuration(100 * time.Millisecond)
+	cfg.ElectionInterval = typeutil.NewDuration(time.Second)
 
 	cfg.adjust()
 	return cfg
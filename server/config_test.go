@@ -0,0 +1,303 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/embed"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+var _ = Suite(&testScheduleConfigSuite{})
+
+type testScheduleConfigSuite struct{}
+
+func (s *testScheduleConfigSuite) TestValidate(c *C) {
+	valid := []ThrottleWindow{
+		{StartTime: "09:00", EndTime: "18:00"},
+		{StartTime: "22:30", EndTime: "06:00"},
+	}
+	for _, w := range valid {
+		c.Assert(w.parse(), IsNil)
+	}
+	invalid := []ThrottleWindow{
+		{StartTime: "09:00", EndTime: "09:00"},
+		{StartTime: "9am", EndTime: "18:00"},
+		{StartTime: "09:00", EndTime: "25:00"},
+		{StartTime: "09:00"},
+	}
+	for _, w := range invalid {
+		c.Assert(w.parse(), NotNil)
+	}
+
+	cfg := &ScheduleConfig{ThrottleWindows: valid}
+	cfg.adjust()
+	c.Assert(cfg.validate(), IsNil)
+	cfg.ThrottleWindows = append(cfg.ThrottleWindows, invalid[0])
+	c.Assert(cfg.validate(), NotNil)
+}
+
+func (s *testScheduleConfigSuite) TestHotRegionFlowRatio(c *C) {
+	cfg := &ScheduleConfig{}
+	cfg.adjust()
+	c.Assert(cfg.HotRegionFlowRatio, Equals, float64(defaultHotRegionFlowRatio))
+	cfg.HotRegionFlowRatio = 1
+	c.Assert(cfg.validate(), IsNil)
+	cfg.HotRegionFlowRatio = 0.5
+	c.Assert(cfg.validate(), NotNil)
+}
+
+func (s *testScheduleConfigSuite) TestSpaceRatio(c *C) {
+	cfg := &ScheduleConfig{}
+	cfg.adjust()
+	c.Assert(cfg.validate(), IsNil)
+
+	testCases := []struct {
+		high, low float64
+		valid     bool
+	}{
+		{0.6, 0.8, true},
+		{0.8, 1, true},
+		{0.8, 0.8, false},
+		{0.9, 0.8, false},
+		{0, 0.8, false},
+		{0.8, 1.1, false},
+	}
+	for _, t := range testCases {
+		cfg.HighSpaceRatio, cfg.LowSpaceRatio = t.high, t.low
+		c.Assert(cfg.validate() == nil, Equals, t.valid)
+	}
+}
+
+func (s *testScheduleConfigSuite) TestStoreScore(c *C) {
+	cfg := &ScheduleConfig{}
+	cfg.adjust()
+	c.Assert(cfg.StoreScore, Equals, StoreScoreConfig{RegionSizeWeight: 1, CapacityWeight: 1})
+	c.Assert(cfg.validate(), IsNil)
+
+	testCases := []struct {
+		score StoreScoreConfig
+		valid bool
+	}{
+		{StoreScoreConfig{RegionSizeWeight: 1, LeaderCountWeight: 0.5, CapacityWeight: 0.5, FlowWeight: 10}, true},
+		{StoreScoreConfig{RegionSizeWeight: 0, CapacityWeight: 1}, false},
+		{StoreScoreConfig{RegionSizeWeight: 1, LeaderCountWeight: -1, CapacityWeight: 1}, false},
+		{StoreScoreConfig{RegionSizeWeight: 1, CapacityWeight: 1, FlowWeight: -1}, false},
+		{StoreScoreConfig{RegionSizeWeight: 1, CapacityWeight: 0}, false},
+		{StoreScoreConfig{RegionSizeWeight: 1, CapacityWeight: 1.5}, false},
+	}
+	for _, t := range testCases {
+		cfg.StoreScore = t.score
+		c.Assert(cfg.validate() == nil, Equals, t.valid)
+	}
+}
+
+func (s *testScheduleConfigSuite) TestRegionScheduleLimit(c *C) {
+	cfg := &ScheduleConfig{
+		RegionScheduleLimit: 12,
+		ThrottleWindows: []ThrottleWindow{
+			{StartTime: "09:00", EndTime: "18:00", RegionScheduleLimit: 4},
+			{StartTime: "12:00", EndTime: "13:00", RegionScheduleLimit: 0},
+			{StartTime: "23:00", EndTime: "01:00", RegionScheduleLimit: 20},
+			{StartTime: "02:00", EndTime: "03:00", RegionScheduleLimit: 2},
+		},
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2017, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+	// The windows not parsed contain nothing.
+	c.Assert(cfg.getRegionScheduleLimit(at(9, 0)), Equals, uint64(12))
+	c.Assert(cfg.parseThrottleWindows(), IsNil)
+	testCases := []struct {
+		t     time.Time
+		limit uint64
+	}{
+		{at(8, 59), 12},
+		{at(9, 0), 4},
+		{at(12, 30), 0},
+		{at(17, 59), 4},
+		{at(18, 0), 12},
+		// A window larger than the limit takes no effect.
+		{at(0, 30), 12},
+		{at(2, 0), 2},
+		{at(3, 0), 12},
+	}
+	for _, t := range testCases {
+		c.Assert(cfg.getRegionScheduleLimit(t.t), Equals, t.limit)
+	}
+
+	// The replica schedules are not throttled.
+	_, opt := newTestScheduleConfig()
+	before := opt.GetReplicaScheduleLimit()
+	now := time.Now()
+	opt.load().ThrottleWindows = []ThrottleWindow{{
+		StartTime: now.Add(-time.Hour).Format("15:04"),
+		EndTime:   now.Add(time.Hour).Format("15:04"),
+	}}
+	c.Assert(opt.load().parseThrottleWindows(), IsNil)
+	c.Assert(opt.GetRegionScheduleLimit(), Equals, uint64(0))
+	c.Assert(opt.GetReplicaScheduleLimit(), Equals, before)
+}
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestElectionInterval(c *C) {
+	cfg := NewConfig()
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(cfg.TickInterval.Duration, Equals, defaultTickInterval)
+	c.Assert(cfg.ElectionInterval.Duration, Equals, defaultElectionInterval)
+
+	cfg = NewConfig()
+	cfg.TickInterval = typeutil.NewDuration(time.Second)
+	cfg.ElectionInterval = typeutil.NewDuration(4 * time.Second)
+	c.Assert(cfg.adjust(), NotNil)
+	cfg.ElectionInterval = typeutil.NewDuration(5 * time.Second)
+	c.Assert(cfg.adjust(), IsNil)
+}
+
+func (s *testConfigSuite) TestTsoWindow(c *C) {
+	cfg := NewConfig()
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(cfg.TsoMaxClockJump.Duration, Equals, defaultTsoMaxClockJump)
+
+	cfg = NewConfig()
+	cfg.TsoSaveInterval = typeutil.NewDuration(10 * time.Millisecond)
+	c.Assert(cfg.adjust(), NotNil)
+	cfg.TsoSaveInterval = typeutil.NewDuration(time.Minute)
+	c.Assert(cfg.adjust(), NotNil)
+	cfg.TsoMaxClockJump = typeutil.NewDuration(2 * time.Minute)
+	c.Assert(cfg.adjust(), IsNil)
+}
+
+func (s *testConfigSuite) TestEtcdConfig(c *C) {
+	cfg := NewConfig()
+	c.Assert(cfg.adjust(), IsNil)
+	etcdCfg, err := cfg.genEmbedEtcdConfig()
+	c.Assert(err, IsNil)
+	c.Assert(etcdCfg.SnapCount, Equals, embed.NewConfig().SnapCount)
+	c.Assert(etcdCfg.AutoCompactionRetention, Equals, defaultAutoCompactionRetention)
+
+	cfg.QuotaBackendBytes = 4 * typeutil.ByteSize(1<<30)
+	cfg.AutoCompactionRetention = 2
+	cfg.SnapshotCount = 5000
+	etcdCfg, err = cfg.genEmbedEtcdConfig()
+	c.Assert(err, IsNil)
+	c.Assert(etcdCfg.QuotaBackendBytes, Equals, int64(4<<30))
+	c.Assert(etcdCfg.AutoCompactionRetention, Equals, 2)
+	c.Assert(etcdCfg.SnapCount, Equals, uint64(5000))
+	c.Assert(cfg.Check(), IsNil)
+
+	// The quota is checked at startup.
+	cfg.QuotaBackendBytes = maxQuotaBackendBytes + 1
+	c.Assert(cfg.adjust(), NotNil)
+}
+
+func (s *testConfigSuite) TestPersistConfig(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	cfg := *leader.GetScheduleConfig()
+	cfg.LeaderScheduleLimit = 64
+	c.Assert(leader.SetScheduleConfig(cfg), IsNil)
+	rep := *leader.GetReplicationConfig()
+	rep.MaxReplicas = 5
+	c.Assert(leader.SetReplicationConfig(rep), IsNil)
+
+	// The followers can't change the persisted config.
+	for _, svr := range svrs {
+		if svr != leader {
+			cfg.LeaderScheduleLimit = 128
+			c.Assert(svr.SetScheduleConfig(cfg), NotNil)
+			c.Assert(svr.GetScheduleConfig().LeaderScheduleLimit, Not(Equals), uint64(128))
+		}
+	}
+
+	// The new leader goes on with the persisted config.
+	newLeader := leader
+	for i := 0; i < 10 && newLeader == leader; i++ {
+		c.Assert(newLeader.resignLeader(), IsNil)
+		time.Sleep(time.Second)
+		newLeader = mustWaitLeader(c, svrs)
+	}
+	c.Assert(newLeader, Not(Equals), leader)
+	c.Assert(newLeader.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(64))
+	c.Assert(newLeader.GetReplicationConfig().MaxReplicas, Equals, uint64(5))
+	c.Assert(newLeader.GetConfig().Schedule.LeaderScheduleLimit, Equals, uint64(64))
+}
+
+func (s *testConfigSuite) TestCheck(c *C) {
+	cfg := NewConfig()
+	c.Assert(cfg.Parse([]string{"--config", "../conf/config.toml"}), IsNil)
+	c.Assert(cfg.Check(), IsNil)
+
+	dir, err := ioutil.TempDir("/tmp", "test_pd_config")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "pd.toml")
+	data := `
+client-urls = "127.0.0.1:2379"
+unknown-item = 1
+[replication]
+location-labels = ["zone", "zone"]
+`
+	c.Assert(ioutil.WriteFile(file, []byte(data), os.ModePerm), IsNil)
+	cfg = NewConfig()
+	c.Assert(cfg.Parse([]string{"--config", file}), IsNil)
+	c.Assert(cfg.WarningMsgs, HasLen, 1)
+	err = cfg.Check()
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, ".*unknown config item unknown-item.*")
+	c.Assert(err, ErrorMatches, ".*invalid client-urls.*")
+	c.Assert(err, ErrorMatches, ".*location label \"zone\" is duplicated.*")
+}
+
+func (s *testConfigSuite) TestPersistConfigConcurrently(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			cfg := *svr.GetScheduleConfig()
+			cfg.LeaderScheduleLimit = uint64(i)
+			c.Assert(svr.SetScheduleConfig(cfg), IsNil)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			rep := *svr.GetReplicationConfig()
+			rep.MaxReplicas = uint64(i)
+			c.Assert(svr.SetReplicationConfig(rep), IsNil)
+		}(i)
+	}
+	wg.Wait()
+
+	// The persisted config is the one in effect.
+	cfg := &Config{}
+	isExist, err := svr.kv.loadConfig(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(isExist, IsTrue)
+	c.Assert(cfg.Schedule, DeepEquals, *svr.GetScheduleConfig())
+	c.Assert(cfg.Replication, DeepEquals, *svr.GetReplicationConfig())
+}
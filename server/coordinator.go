@@ -14,14 +14,19 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
 	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
 )
 
 const (
@@ -35,22 +40,37 @@ const (
 	minSlowScheduleInterval   = time.Second * 3
 	scheduleIntervalFactor    = 1.3
 
+	defaultRegionSize             = 1 << 20
 	writeStatLRUMaxLen            = 1000
+	readStatLRUMaxLen             = 1000
 	storeHotRegionsDefaultLen     = 100
 	hotRegionLimitFactor          = 0.75
 	hotRegionScheduleFactor       = 0.9
 	hotRegionMinWriteRate         = 16 * 1024
+	hotRegionMinReadRate          = 128 * 1024
 	regionHeartBeatReportInterval = 60
-	storeHeartBeatReportInterval  = 10
 	minHotRegionReportInterval    = 3
-	hotRegionAntiCount            = 1
+	hotRegionEvictRatio           = 0.5
 	hotRegionScheduleName         = "balance-hot-region-scheduler"
+	hotReadRegionScheduleName     = "balance-hot-read-region-scheduler"
 )
 
+// urgentReplicaScheduleRatio is the ratio of the replica schedule limit
+// reserved for the regions down to one healthy replica.
+const urgentReplicaScheduleRatio = 0.25
+
+// operatorCheckInterval is the interval to cancel the timeout and the stale
+// operators.
+const operatorCheckInterval = 10 * time.Second
+
+// dryRunScheduleInterval is the min schedule interval in dry-run mode. The
+// operators are not dispatched and take no schedule limit, so the schedulers
+// create them again and again without the interval.
+const dryRunScheduleInterval = 3 * time.Second
+
 var (
-	hotRegionLowThreshold = 3
-	errSchedulerExisted   = errors.New("scheduler existed")
-	errSchedulerNotFound  = errors.New("scheduler not found")
+	errSchedulerExisted  = errors.New("scheduler existed")
+	errSchedulerNotFound = errors.New("scheduler not found")
 )
 
 type coordinator struct {
@@ -60,37 +80,65 @@ type coordinator struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	cluster    *clusterInfo
-	opt        *scheduleOption
-	limiter    *scheduleLimiter
-	checker    *replicaChecker
-	operators  map[uint64]Operator
-	schedulers map[string]*scheduleController
+	cluster       *clusterInfo
+	opt           *scheduleOption
+	limiter       *scheduleLimiter
+	checker       *replicaChecker
+	leaderChecker *leaderChecker
+	operators     map[uint64]Operator
+	sources       map[uint64]operatorSource
+	schedulers    map[string]*scheduleController
 
 	histories *lruCache
+	records   *fifoCache
+	dryRuns   *lruCache
+	splits    *fifoCache
 	events    *fifoCache
+	// opEvents traces the lifecycle of the operators, it's shown at
+	// /debug/events of the client URLs.
+	opEvents trace.EventLog
 }
 
 func newCoordinator(cluster *clusterInfo, opt *scheduleOption) *coordinator {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &coordinator{
-		ctx:        ctx,
-		cancel:     cancel,
-		cluster:    cluster,
-		opt:        opt,
-		limiter:    newScheduleLimiter(),
-		checker:    newReplicaChecker(opt, cluster),
-		operators:  make(map[uint64]Operator),
-		schedulers: make(map[string]*scheduleController),
-		histories:  newLRUCache(historiesCacheSize),
-		events:     newFifoCache(eventsCacheSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		cluster:       cluster,
+		opt:           opt,
+		limiter:       newScheduleLimiter(),
+		checker:       newReplicaChecker(opt, cluster),
+		leaderChecker: newLeaderChecker(opt, cluster),
+		operators:     make(map[uint64]Operator),
+		sources:       make(map[uint64]operatorSource),
+		schedulers:    make(map[string]*scheduleController),
+		histories:     newLRUCache(historiesCacheSize),
+		records:       newFifoCache(historiesCacheSize),
+		dryRuns:       newLRUCache(historiesCacheSize),
+		splits:        newFifoCache(historiesCacheSize),
+		events:        newFifoCache(eventsCacheSize),
+		opEvents:      trace.NewEventLog("pd.coordinator", "operators"),
 	}
 }
 
 func (c *coordinator) dispatch(region *RegionInfo) *pdpb.RegionHeartbeatResponse {
+	// The failpoint drops the response, as if it's lost on the way to TiKV.
+	if _, ok := failpoint.Eval("dropOperatorResponse"); ok {
+		return nil
+	}
+
+	// Cancel the stale operator, the checkers may create a new one.
+	c.Lock()
+	if op, ok := c.operators[region.GetId()].(staleOperator); ok && op.isStale(region) {
+		log.Warnf("coordinator: cancel stale operator %v, region epoch %v", op, region.GetRegionEpoch())
+		op.SetState(OperatorStale)
+		c.removeOperatorLocked(op)
+	}
+	c.Unlock()
+
 	// Check existed operator.
 	if op := c.getOperator(region.GetId()); op != nil {
-		res, finished := op.Do(region)
+		res, finished := c.doOperator(op, region)
 		if !finished {
 			collectOperatorCounterMetrics(op)
 			return res
@@ -98,13 +146,36 @@ func (c *coordinator) dispatch(region *RegionInfo) *pdpb.RegionHeartbeatResponse
 		c.removeOperator(op)
 	}
 
-	// Check replica operator.
-	if c.limiter.operatorCount(RegionKind) >= c.opt.GetReplicaScheduleLimit() {
+	if c.opt.IsSchedulingDisabled() {
 		return nil
 	}
-	if op := c.checker.Check(region); op != nil {
-		if c.addOperator(op) {
-			res, _ := op.Do(region)
+
+	// Check replica operator. Operators moving replicas out of offline
+	// stores are limited separately, so they won't be blocked by others.
+	// Some replica schedules are reserved for the regions down to one healthy
+	// replica, so they are repaired first during multi-store failures.
+	urgent := c.checker.isUrgent(region)
+	allowReplica := func(kind ResourceKind) bool {
+		var reserved float64
+		if kind == ReplicaKind && !urgent {
+			reserved = urgentReplicaScheduleRatio
+		}
+		return c.allowReservedSchedule(region, kind, c.opt.GetReplicaScheduleLimit(), reserved)
+	}
+	if allowReplica(ReplicaKind) || allowReplica(PriorityKind) {
+		if op := c.checker.Check(region); op != nil {
+			if allowReplica(op.GetResourceKind()) && c.addOperatorFrom(replicaCheckerSource, op) {
+				res, _ := c.doOperator(op, region)
+				return res
+			}
+			return nil
+		}
+	}
+
+	// Check leader operator.
+	if c.allowSchedule(region, LeaderKind, c.opt.GetLeaderScheduleLimit()) {
+		if op := c.leaderChecker.Check(region); op != nil && c.addOperatorFrom(leaderCheckerSource, op) {
+			res, _ := c.doOperator(op, region)
 			return res
 		}
 	}
@@ -112,6 +183,53 @@ func (c *coordinator) dispatch(region *RegionInfo) *pdpb.RegionHeartbeatResponse
 	return nil
 }
 
+// allowSchedule checks whether an operator of the kind can be added to the
+// region. The operators are limited by the global limit, and by the limit of
+// the namespace of the region if it overrides the one of the kind.
+func (c *coordinator) allowSchedule(region *RegionInfo, kind ResourceKind, globalLimit uint64) bool {
+	return c.allowReservedSchedule(region, kind, globalLimit, 0)
+}
+
+// allowReservedSchedule is like allowSchedule, but the ratio of the limits is
+// reserved for the other operators.
+func (c *coordinator) allowReservedSchedule(region *RegionInfo, kind ResourceKind, globalLimit uint64, reserved float64) bool {
+	reserve := func(limit uint64) uint64 {
+		return limit - uint64(float64(limit)*reserved)
+	}
+	namespace := c.opt.namespaces.getRegionNamespace(region)
+	namespaceLimit := c.opt.namespaces.getScheduleLimit(namespace, kind)
+	return c.limiter.allowSchedule(kind, reserve(globalLimit), namespace, reserve(namespaceLimit))
+}
+
+// getOperatorLimit returns the global limit of the operator created by the
+// scheduler. It's the limit of the scheduler if the operator is of its kind,
+// otherwise the operator is limited by the limit of its own kind, e.g. the
+// leader transfers of the scatter-range scheduler.
+func (c *coordinator) getOperatorLimit(s Scheduler, op Operator) uint64 {
+	kind := op.GetResourceKind()
+	if kind == s.GetResourceKind() {
+		return s.GetResourceLimit()
+	}
+	switch kind {
+	case LeaderKind:
+		return c.opt.GetLeaderScheduleLimit()
+	case RegionKind:
+		return c.opt.GetRegionScheduleLimit()
+	case ReplicaKind, PriorityKind:
+		return c.opt.GetReplicaScheduleLimit()
+	}
+	return s.GetResourceLimit()
+}
+
+// getOperatorNamespace returns the namespace of the region of the operator,
+// the operator is counted in it.
+func (c *coordinator) getOperatorNamespace(op Operator) string {
+	if region := c.cluster.GetRegion(op.GetRegionID()); region != nil {
+		return c.opt.namespaces.getRegionNamespace(region)
+	}
+	return DefaultNamespace
+}
+
 func (c *coordinator) run() {
 	ticker := time.NewTicker(runSchedulerCheckInterval)
 	defer ticker.Stop()
@@ -132,21 +250,108 @@ func (c *coordinator) run() {
 	c.addScheduler(newBalanceLeaderScheduler(c.opt), minScheduleInterval)
 	c.addScheduler(newBalanceRegionScheduler(c.opt), minScheduleInterval)
 	c.addScheduler(newBalanceHotRegionScheduler(c.opt), minSlowScheduleInterval)
+	c.addScheduler(newBalanceHotReadRegionScheduler(c.opt), minSlowScheduleInterval)
+	c.restoreSchedulers()
+
+	c.wg.Add(1)
+	go c.checkOperators()
+}
+
+// checkOperators removes the timeout and the stale operators periodically.
+// The operators are also checked on region heartbeats, but a region may stop
+// sending heartbeats, e.g. its leader store goes down or it's merged.
+func (c *coordinator) checkOperators() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(operatorCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.removeTimeoutOperators()
+			c.removeStaleOperators()
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *coordinator) removeTimeoutOperators() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, op := range c.operators {
+		if t, ok := op.(timeoutOperator); ok && t.isTimeout() {
+			log.Warnf("coordinator: cancel timeout operator %v", op)
+			op.SetState(OperatorTimeOut)
+			c.removeOperatorLocked(op)
+		}
+	}
+}
+
+// removeStaleOperators removes the operators whose regions in the cache have
+// changed unexpectedly.
+func (c *coordinator) removeStaleOperators() {
+	for _, op := range c.getOperators() {
+		s, ok := op.(staleOperator)
+		if !ok {
+			continue
+		}
+		region := c.cluster.GetRegion(op.GetRegionID())
+		if region == nil {
+			continue
+		}
+		c.Lock()
+		// The operator may be removed or replaced in the meantime. It's
+		// checked under the lock, as it's only run under the lock.
+		if c.operators[op.GetRegionID()] == op && s.isStale(region) {
+			log.Warnf("coordinator: cancel stale operator %v, region epoch %v", op, region.GetRegionEpoch())
+			op.SetState(OperatorStale)
+			c.removeOperatorLocked(op)
+		}
+		c.Unlock()
+	}
+}
+
+// restoreSchedulers adds the schedulers persisted by API. A persisted
+// scheduler may be one of the default schedulers, which is already added.
+func (c *coordinator) restoreSchedulers() {
+	if c.cluster.kv == nil {
+		return
+	}
+	cfgs, err := c.cluster.kv.loadSchedulers()
+	if err != nil {
+		log.Errorf("coordinator: failed to load schedulers: %v", err)
+		return
+	}
+	for _, cfg := range cfgs {
+		s, err := newSchedulerFromConfig(c.opt, cfg)
+		if err != nil {
+			log.Errorf("coordinator: failed to restore scheduler %+v: %v", cfg, err)
+			continue
+		}
+		if err := c.addScheduler(s, minScheduleInterval); err != nil && errors.Cause(err) != errSchedulerExisted {
+			log.Errorf("coordinator: failed to restore scheduler %s: %v", s.GetName(), err)
+		}
+	}
 }
 
 func (c *coordinator) stop() {
 	c.cancel()
 	c.wg.Wait()
+	c.opEvents.Finish()
 }
 
+// getHotWriteRegions returns the hot write regions of the stores, they are
+// read from the hot spot cache shared with the schedulers, so they are
+// reported even if the hot region scheduler is removed.
 func (c *coordinator) getHotWriteRegions() *StoreHotRegionInfos {
-	c.RLock()
-	defer c.RUnlock()
-	s, ok := c.schedulers[hotRegionScheduleName]
-	if !ok {
-		return nil
-	}
-	return s.Scheduler.(*balanceHotRegionScheduler).GetStatus()
+	return c.cluster.getHotRegionStoreStats(hotWriteFlow, c.opt.GetHotRegionThreshold())
+}
+
+func (c *coordinator) getHotReadRegions() *StoreHotRegionInfos {
+	return c.cluster.getHotRegionStoreStats(hotReadFlow, c.opt.GetHotRegionThreshold())
 }
 
 func (c *coordinator) getSchedulers() []string {
@@ -176,13 +381,13 @@ func (c *coordinator) collectSchedulerMetrics() {
 }
 
 func (c *coordinator) collectHotSpotMetrics() {
-	c.RLock()
-	defer c.RUnlock()
-	s, ok := c.schedulers[hotRegionScheduleName]
-	if !ok {
-		return
+	for storeID, stat := range c.getHotReadRegions().AsLeader {
+		store := fmt.Sprintf("store_%d", storeID)
+		hotSpotStatusGauge.WithLabelValues(store, "total_read_bytes_as_leader").Set(float64(stat.ReadBytes))
+		hotSpotStatusGauge.WithLabelValues(store, "hot_read_region_as_leader").Set(float64(stat.RegionsCount))
 	}
-	status := s.Scheduler.(*balanceHotRegionScheduler).GetStatus()
+
+	status := c.getHotWriteRegions()
 	for storeID, stat := range status.AsPeer {
 		store := fmt.Sprintf("store_%d", storeID)
 		totalWriteBytes := float64(stat.WrittenBytes)
@@ -238,6 +443,34 @@ func (c *coordinator) removeScheduler(name string) error {
 	return nil
 }
 
+// pauseOrResumeScheduler pauses or resumes the scheduler, a paused scheduler
+// creates no operators. The pause is not persisted, the scheduler runs again
+// after the leader changes.
+func (c *coordinator) pauseOrResumeScheduler(name string, pause bool) error {
+	c.RLock()
+	defer c.RUnlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return errSchedulerNotFound
+	}
+	s.setPaused(pause)
+	return nil
+}
+
+func (c *coordinator) getPausedSchedulers() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	var names []string
+	for name, s := range c.schedulers {
+		if s.isPaused() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func (c *coordinator) runScheduler(s *scheduleController) {
 	defer c.wg.Done()
 	defer s.Cleanup(c.cluster)
@@ -252,8 +485,22 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 			if !s.AllowSchedule() {
 				continue
 			}
-			if op := s.Schedule(c.cluster); op != nil {
-				c.addOperator(op)
+			var cluster Cluster = c.cluster
+			dryRun := c.opt.IsDryRunEnabled()
+			if dryRun {
+				cluster = dryRunCluster{Cluster: c.cluster}
+			}
+			if op := s.Schedule(cluster, c.getOpInfluence()); op != nil {
+				// Check both limits again with the region, the namespace
+				// of the region may be throttled further.
+				if region := c.cluster.GetRegion(op.GetRegionID()); region != nil && !c.allowSchedule(region, op.GetResourceKind(), c.getOperatorLimit(s, op)) {
+					continue
+				}
+				if dryRun {
+					c.addDryRunOperator(s, op)
+				} else {
+					c.addOperatorFrom(s.GetName(), op)
+				}
 			}
 
 		case <-s.Ctx().Done():
@@ -263,7 +510,16 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 	}
 }
 
+// addOperator adds an operator created by the API or an RPC.
 func (c *coordinator) addOperator(op Operator) bool {
+	return c.addOperatorFrom(adminSource, op)
+}
+
+// addOperatorFrom adds an operator created by the source, which is a
+// scheduler or a checker, the operator metrics are collected by the source.
+func (c *coordinator) addOperatorFrom(source string, op Operator) bool {
+	namespace := c.getOperatorNamespace(op)
+
 	c.Lock()
 	defer c.Unlock()
 	regionID := op.GetRegionID()
@@ -278,9 +534,12 @@ func (c *coordinator) addOperator(op Operator) bool {
 	}
 
 	c.histories.add(regionID, op)
-	c.limiter.addOperator(op)
+	c.limiter.addOperator(op, namespace)
 	c.operators[regionID] = op
+	c.sources[regionID] = operatorSource{name: source, namespace: namespace, added: time.Now()}
 	collectOperatorCounterMetrics(op)
+	operatorEventCounter.WithLabelValues(source, op.GetResourceKind().String(), "create").Inc()
+	c.opEvents.Printf("%s adds operator %v", source, op)
 	return true
 }
 
@@ -294,6 +553,15 @@ func isHigherPriorityOperator(new Operator, old Operator) bool {
 	return false
 }
 
+// doOperator runs the operator with the heartbeat of its region. The running
+// operators are only changed under the lock, so they can be checked by the
+// others, e.g. whether it's stale or its influence on the stores.
+func (c *coordinator) doOperator(op Operator, region *RegionInfo) (*pdpb.RegionHeartbeatResponse, bool) {
+	c.Lock()
+	defer c.Unlock()
+	return op.Do(region)
+}
+
 func (c *coordinator) removeOperator(op Operator) {
 	c.Lock()
 	defer c.Unlock()
@@ -302,10 +570,24 @@ func (c *coordinator) removeOperator(op Operator) {
 
 func (c *coordinator) removeOperatorLocked(op Operator) {
 	regionID := op.GetRegionID()
-	c.limiter.removeOperator(op)
+	// The operator may be removed or replaced already, e.g. it's finished and
+	// canceled at the same time.
+	if c.operators[regionID] != op {
+		return
+	}
+	source, ok := c.sources[regionID]
+	c.limiter.removeOperator(op, source.namespace)
 	delete(c.operators, regionID)
+	if ok {
+		delete(c.sources, regionID)
+		kind, state := op.GetResourceKind().String(), op.GetState().String()
+		operatorEventCounter.WithLabelValues(source.name, kind, state).Inc()
+		operatorDuration.WithLabelValues(source.name, kind, state).Observe(time.Since(source.added).Seconds())
+	}
+	c.opEvents.Printf("remove operator %v, state %s", op, op.GetState())
 
 	c.histories.add(regionID, op)
+	c.records.add(regionID, newOperatorRecord(op))
 	collectOperatorCounterMetrics(op)
 }
 
@@ -315,6 +597,19 @@ func (c *coordinator) getOperator(regionID uint64) Operator {
 	return c.operators[regionID]
 }
 
+// getOpInfluence returns the influence of the running operators on stores.
+// The operators run under the lock, so their steps are read under it too.
+func (c *coordinator) getOpInfluence() OpInfluence {
+	c.RLock()
+	defer c.RUnlock()
+
+	operators := make([]Operator, 0, len(c.operators))
+	for _, op := range c.operators {
+		operators = append(operators, op)
+	}
+	return newOpInfluence(operators)
+}
+
 func (c *coordinator) getOperators() []Operator {
 	c.RLock()
 	defer c.RUnlock()
@@ -354,27 +649,122 @@ func (c *coordinator) getHistoriesOfKind(kind ResourceKind) []Operator {
 	return operators
 }
 
+// getOperatorRecords returns the records of the operators removed since the
+// start time, the earliest first.
+func (c *coordinator) getOperatorRecords(start time.Time) []*OperatorRecord {
+	var records []*OperatorRecord
+	for _, elem := range c.records.elems() {
+		record := elem.value.(*OperatorRecord)
+		if !record.FinishTime.Before(start) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// dryRunCluster is the cluster read by the schedulers in dry-run mode, the
+// ids of the new peers are not allocated since the operators are never
+// dispatched.
+type dryRunCluster struct {
+	Cluster
+}
+
+// AllocPeer returns a placeholder peer with no id.
+func (c dryRunCluster) AllocPeer(storeID uint64) (*metapb.Peer, error) {
+	return &metapb.Peer{StoreId: storeID}, nil
+}
+
+// addDryRunOperator records the operator created by the scheduler in dry-run
+// mode instead of dispatching it, only the latest one of each region is kept.
+func (c *coordinator) addDryRunOperator(s *scheduleController, op Operator) {
+	log.Infof("coordinator: [dry-run] %s creates operator %+v", s.GetName(), op)
+	c.dryRuns.add(op.GetRegionID(), newDryRunOperator(s.GetName(), op))
+	s.nextInterval = maxDuration(s.nextInterval, dryRunScheduleInterval)
+}
+
+// getDryRunOperators returns the operators created in dry-run mode, the
+// earliest first.
+func (c *coordinator) getDryRunOperators() []*DryRunOperator {
+	elems := c.dryRuns.elems()
+	ops := make([]*DryRunOperator, 0, len(elems))
+	for i := len(elems) - 1; i >= 0; i-- {
+		ops = append(ops, elems[i].value.(*DryRunOperator))
+	}
+	return ops
+}
+
+func (c *coordinator) addSplitHistory(op *splitOperator) {
+	c.histories.add(op.GetRegionID(), op)
+	c.splits.add(op.GetRegionID(), op)
+}
+
+// getSplitHistories returns the recent splits which origin region overlaps
+// with [startKey, endKey). An empty endKey means +inf.
+func (c *coordinator) getSplitHistories(startKey, endKey []byte) []Operator {
+	var operators []Operator
+	for _, elem := range c.splits.elems() {
+		op := elem.value.(*splitOperator)
+		originEnd := op.Origin.GetEndKey()
+		if len(endKey) > 0 && bytes.Compare(op.Origin.GetStartKey(), endKey) >= 0 {
+			continue
+		}
+		if len(originEnd) > 0 && bytes.Compare(originEnd, startKey) <= 0 {
+			continue
+		}
+		operators = append(operators, op)
+	}
+
+	return operators
+}
+
+// The sources of the operators not created by schedulers.
+const (
+	adminSource          = "admin"
+	replicaCheckerSource = "replica_checker"
+	leaderCheckerSource  = "leader_checker"
+)
+
+// operatorSource records who adds a running operator and when, and the
+// namespace the operator is counted in.
+type operatorSource struct {
+	name      string
+	namespace string
+	added     time.Time
+}
+
 type scheduleLimiter struct {
 	sync.RWMutex
 	counts map[ResourceKind]uint64
+	// namespaceCounts are the counts of the operators of each namespace.
+	namespaceCounts map[string]map[ResourceKind]uint64
 }
 
 func newScheduleLimiter() *scheduleLimiter {
 	return &scheduleLimiter{
-		counts: make(map[ResourceKind]uint64),
+		counts:          make(map[ResourceKind]uint64),
+		namespaceCounts: make(map[string]map[ResourceKind]uint64),
 	}
 }
 
-func (l *scheduleLimiter) addOperator(op Operator) {
+func (l *scheduleLimiter) addOperator(op Operator, namespace string) {
 	l.Lock()
 	defer l.Unlock()
 	l.counts[op.GetResourceKind()]++
+	counts, ok := l.namespaceCounts[namespace]
+	if !ok {
+		counts = make(map[ResourceKind]uint64)
+		l.namespaceCounts[namespace] = counts
+	}
+	counts[op.GetResourceKind()]++
 }
 
-func (l *scheduleLimiter) removeOperator(op Operator) {
+func (l *scheduleLimiter) removeOperator(op Operator, namespace string) {
 	l.Lock()
 	defer l.Unlock()
 	l.counts[op.GetResourceKind()]--
+	if counts, ok := l.namespaceCounts[namespace]; ok && counts[op.GetResourceKind()] > 0 {
+		counts[op.GetResourceKind()]--
+	}
 }
 
 func (l *scheduleLimiter) operatorCount(kind ResourceKind) uint64 {
@@ -383,6 +773,24 @@ func (l *scheduleLimiter) operatorCount(kind ResourceKind) uint64 {
 	return l.counts[kind]
 }
 
+// allowSchedule checks whether an operator of the kind can be added. It must
+// be under both the global limit and the limit of the namespace, 0 namespace
+// limit means the namespace doesn't override the global one.
+func (l *scheduleLimiter) allowSchedule(kind ResourceKind, limit uint64, namespace string, namespaceLimit uint64) bool {
+	l.RLock()
+	defer l.RUnlock()
+	if l.counts[kind] >= limit {
+		return false
+	}
+	return namespaceLimit == 0 || l.namespaceCounts[namespace][kind] < namespaceLimit
+}
+
+func (l *scheduleLimiter) namespaceOperatorCount(namespace string, kind ResourceKind) uint64 {
+	l.RLock()
+	defer l.RUnlock()
+	return l.namespaceCounts[namespace][kind]
+}
+
 type scheduleController struct {
 	Scheduler
 	opt          *scheduleOption
@@ -391,6 +799,8 @@ type scheduleController struct {
 	minInterval  time.Duration
 	ctx          context.Context
 	cancel       context.CancelFunc
+	// paused is 1 if the scheduler is paused.
+	paused int32
 }
 
 func newScheduleController(c *coordinator, s Scheduler, minInterval time.Duration) *scheduleController {
@@ -414,10 +824,10 @@ func (s *scheduleController) Stop() {
 	s.cancel()
 }
 
-func (s *scheduleController) Schedule(cluster *clusterInfo) Operator {
+func (s *scheduleController) Schedule(cluster Cluster, influence OpInfluence) Operator {
 	for i := 0; i < maxScheduleRetries; i++ {
 		// If we have schedule, reset interval to the minimal interval.
-		if op := s.Scheduler.Schedule(cluster); op != nil {
+		if op := s.Scheduler.Schedule(cluster, influence); op != nil {
 			s.nextInterval = s.minInterval
 			return op
 		}
@@ -434,7 +844,25 @@ func (s *scheduleController) GetInterval() time.Duration {
 }
 
 func (s *scheduleController) AllowSchedule() bool {
-	return s.limiter.operatorCount(s.GetResourceKind()) < s.GetResourceLimit()
+	if s.isPaused() || s.opt.IsSchedulingDisabled() {
+		return false
+	}
+	// The region is unknown before scheduling, so only the global limit is
+	// checked here, the namespace of the region is checked once the operator
+	// is created.
+	return s.limiter.allowSchedule(s.GetResourceKind(), s.GetResourceLimit(), DefaultNamespace, 0)
+}
+
+func (s *scheduleController) isPaused() bool {
+	return atomic.LoadInt32(&s.paused) == 1
+}
+
+func (s *scheduleController) setPaused(pause bool) {
+	var paused int32
+	if pause {
+		paused = 1
+	}
+	atomic.StoreInt32(&s.paused, paused)
 }
 
 func collectOperatorCounterMetrics(op Operator) {
@@ -56,6 +56,7 @@ func (s *testCoordinatorSuite) TestBasic(c *C) {
 	co.addOperator(op1)
 	c.Assert(l.operatorCount(op1.GetResourceKind()), Equals, uint64(1))
 	c.Assert(co.getOperator(1).GetRegionID(), Equals, op1.GetRegionID())
+	c.Assert(co.sources[1].name, Equals, adminSource)
 
 	// Region 1 already has an operator, cannot add another one.
 	op2 := newTestOperator(1, RegionKind)
@@ -64,9 +65,36 @@ func (s *testCoordinatorSuite) TestBasic(c *C) {
 
 	// Remove the operator manually, then we can add a new operator.
 	co.removeOperator(op1)
+	c.Assert(co.sources, HasLen, 0)
 	co.addOperator(op2)
 	c.Assert(l.operatorCount(op2.GetResourceKind()), Equals, uint64(1))
 	c.Assert(co.getOperator(1).GetRegionID(), Equals, op2.GetRegionID())
+
+	// Removing the removed operator again doesn't affect the new one.
+	co.removeOperator(op1)
+	c.Assert(l.operatorCount(op1.GetResourceKind()), Equals, uint64(0))
+	c.Assert(l.operatorCount(op2.GetResourceKind()), Equals, uint64(1))
+	c.Assert(co.getOperator(1), Equals, op2)
+}
+
+func (s *testCoordinatorSuite) TestOperatorLimit(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	cfg, opt := newTestScheduleConfig()
+	cfg.LeaderScheduleLimit = 2
+	cfg.RegionScheduleLimit = 3
+	co := newCoordinator(cluster, opt)
+	sr := newScatterRangeScheduler(opt, "t1", []byte("a"), []byte("b"))
+
+	// The leader transfers of the scatter-range scheduler are limited by the
+	// leader schedule limit.
+	region := newRegionInfo(&metapb.Region{Id: 1}, nil)
+	op := newTestOperator(1, LeaderKind)
+	c.Assert(co.getOperatorLimit(sr, newTestOperator(1, RegionKind)), Equals, uint64(3))
+	c.Assert(co.getOperatorLimit(sr, op), Equals, uint64(2))
+	c.Assert(co.allowSchedule(region, LeaderKind, co.getOperatorLimit(sr, op)), IsTrue)
+	co.addOperator(newTestOperator(2, LeaderKind))
+	co.addOperator(newTestOperator(3, LeaderKind))
+	c.Assert(co.allowSchedule(region, LeaderKind, co.getOperatorLimit(sr, op)), IsFalse)
 }
 
 func (s *testCoordinatorSuite) TestDispatch(c *C) {
@@ -95,13 +123,15 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	// Wait for schedule and turn off balance.
 	waitOperator(c, co, 1)
 	checkTransferPeer(c, co.getOperator(1), 4, 1)
+	c.Assert(co.getOperatorSource(1), Equals, "balance-region-scheduler")
 	c.Assert(co.removeScheduler("balance-region-scheduler"), IsNil)
 	waitOperator(c, co, 2)
 	checkTransferLeader(c, co.getOperator(2), 4, 2)
+	c.Assert(co.getOperatorSource(2), Equals, "balance-leader-scheduler")
 	c.Assert(co.removeScheduler("balance-leader-scheduler"), IsNil)
 
 	// Transfer peer.
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 	resp := co.dispatch(region)
 	checkAddPeerResp(c, resp, 1)
 	region.Peers = append(region.Peers, resp.GetChangePeer().GetPeer())
@@ -111,9 +141,10 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	region.RemoveStorePeer(4)
 	cluster.putRegion(region)
 	c.Assert(co.dispatch(region), IsNil)
+	c.Assert(co.getOperatorSource(1), Equals, "")
 
 	// Transfer leader.
-	region = cluster.getRegion(2)
+	region = cluster.GetRegion(2)
 	resp = co.dispatch(region)
 	checkTransferLeaderResp(c, resp, 2)
 	region.Leader = resp.GetTransferLeader().GetPeer()
@@ -139,15 +170,23 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 	tc.addRegionStore(3, 3)
 	tc.addRegionStore(4, 4)
 
-	// Add peer to store 1.
+	// Replica schedules are paused.
+	cfg.ReplicaScheduleLimit = 0
 	tc.addLeaderRegion(1, 2, 3)
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
+	c.Assert(co.dispatch(region), IsNil)
+
+	// Add peer to store 1. The replica operators are not blocked by the
+	// region operators.
+	cfg.ReplicaScheduleLimit = 1
+	co.addOperator(newTestOperator(3, RegionKind))
 	resp := co.dispatch(region)
 	checkAddPeerResp(c, resp, 1)
+	c.Assert(co.getOperator(1).GetResourceKind(), Equals, ReplicaKind)
 	region.Peers = append(region.Peers, resp.GetChangePeer().GetPeer())
 	c.Assert(co.dispatch(region), IsNil)
 
-	// Peer in store 3 is down, remove peer in store 3 and add peer to store 4.
+	// Peer in store 3 is down, add peer to store 4 and remove peer in store 3.
 	tc.setStoreDown(3)
 	downPeer := &pdpb.PeerStats{
 		Peer:        region.GetStorePeer(3),
@@ -155,23 +194,342 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 	}
 	region.DownPeers = append(region.DownPeers, downPeer)
 	resp = co.dispatch(region)
+	checkAddPeerResp(c, resp, 4)
+	region.Peers = append(region.Peers, resp.GetChangePeer().GetPeer())
+	resp = co.dispatch(region)
 	checkRemovePeerResp(c, resp, 3)
 	region.RemoveStorePeer(3)
 	region.DownPeers = nil
-	resp = co.dispatch(region)
-	checkAddPeerResp(c, resp, 4)
-	region.Peers = append(region.Peers, resp.GetChangePeer().GetPeer())
 	c.Assert(co.dispatch(region), IsNil)
 
 	// Remove peer from store 4.
 	tc.addLeaderRegion(2, 1, 2, 3, 4)
-	region = cluster.getRegion(2)
+	region = cluster.GetRegion(2)
 	resp = co.dispatch(region)
 	checkRemovePeerResp(c, resp, 4)
 	region.RemoveStorePeer(4)
 	c.Assert(co.dispatch(region), IsNil)
 }
 
+func (s *testCoordinatorSuite) TestUrgentReplica(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	// Turn off balance.
+	cfg, opt := newTestScheduleConfig()
+	cfg.LeaderScheduleLimit = 0
+	cfg.RegionScheduleLimit = 0
+	cfg.ReplicaScheduleLimit = 4
+	co := newCoordinator(cluster, opt)
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 3)
+	tc.addRegionStore(4, 4)
+	for id := uint64(1); id <= 4; id++ {
+		tc.addLeaderRegion(id, 1, 2)
+	}
+	tc.addLeaderRegion(5, 1)
+
+	// One replica schedule is reserved for the urgent regions.
+	for id := uint64(1); id <= 3; id++ {
+		checkAddPeerResp(c, co.dispatch(cluster.GetRegion(id)), 3)
+	}
+	region := cluster.GetRegion(4)
+	c.Assert(co.dispatch(region), IsNil)
+
+	// The region with a pending peer has only one healthy replica.
+	region.PendingPeers = []*metapb.Peer{region.GetStorePeer(2)}
+	checkAddPeerResp(c, co.dispatch(region), 3)
+	c.Assert(co.dispatch(cluster.GetRegion(5)), IsNil)
+
+	co.removeOperator(co.getOperator(1))
+	checkAddPeerResp(c, co.dispatch(cluster.GetRegion(5)), 2)
+}
+
+func (s *testCoordinatorSuite) TestOfflineReplica(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	// Turn off balance.
+	cfg, opt := newTestScheduleConfig()
+	cfg.LeaderScheduleLimit = 0
+	cfg.RegionScheduleLimit = 0
+	cfg.ReplicaScheduleLimit = 1
+
+	co := newCoordinator(cluster, opt)
+	co.run()
+	defer co.stop()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 3)
+	tc.addRegionStore(4, 4)
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 1, 2, 3)
+
+	// Replica operators reach the limit.
+	co.addOperator(newTestOperator(3, ReplicaKind))
+	c.Assert(co.dispatch(cluster.GetRegion(1)), IsNil)
+
+	// Moving replicas out of the offline store is not blocked.
+	tc.setStoreOffline(3)
+	region := cluster.GetRegion(1)
+	resp := co.dispatch(region)
+	checkAddPeerResp(c, resp, 4)
+	c.Assert(co.getOperator(1).GetResourceKind(), Equals, PriorityKind)
+
+	// But it is limited separately.
+	c.Assert(co.dispatch(cluster.GetRegion(2)), IsNil)
+
+	region.Peers = append(region.Peers, resp.GetChangePeer().GetPeer())
+	resp = co.dispatch(region)
+	checkRemovePeerResp(c, resp, 3)
+	region.RemoveStorePeer(3)
+	c.Assert(co.dispatch(region), IsNil)
+	c.Assert(co.getOperator(1), IsNil)
+
+	checkAddPeerResp(c, co.dispatch(cluster.GetRegion(2)), 4)
+}
+
+func (s *testCoordinatorSuite) TestRemoveTimeoutOperators(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 2)
+	region1, region2 := cluster.GetRegion(1), cluster.GetRegion(2)
+	op1 := newTransferLeader(region1, region1.GetStorePeer(2)).(*regionOperator)
+	op2 := newTransferLeader(region2, region2.GetStorePeer(2)).(*regionOperator)
+	c.Assert(co.addOperator(op1), IsTrue)
+	c.Assert(co.addOperator(op2), IsTrue)
+
+	// Operator 1 is removed without any heartbeat of region 1.
+	op1.Start = op1.Start.Add(-maxLeaderOperatorWaitTime).Add(-time.Second)
+	co.removeTimeoutOperators()
+	c.Assert(co.getOperator(1), IsNil)
+	c.Assert(op1.GetState(), Equals, OperatorTimeOut)
+	c.Assert(co.limiter.operatorCount(LeaderKind), Equals, uint64(1))
+	c.Assert(co.getOperator(2), NotNil)
+}
+
+func (s *testCoordinatorSuite) TestRemoveStaleOperators(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	// Turn off the checkers.
+	cfg, opt := newTestScheduleConfig()
+	cfg.LeaderScheduleLimit = 0
+	cfg.ReplicaScheduleLimit = 0
+	co := newCoordinator(cluster, opt)
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 1)
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 2)
+	setEpoch := func(regionID, confVer, version uint64) *RegionInfo {
+		region := cluster.GetRegion(regionID).clone()
+		region.RegionEpoch = &metapb.RegionEpoch{ConfVer: confVer, Version: version}
+		c.Assert(cluster.putRegion(region), IsNil)
+		return region
+	}
+	region1, region2 := setEpoch(1, 1, 1), setEpoch(2, 1, 1)
+	op1 := newTransferLeader(region1, region1.GetStorePeer(2))
+	op2 := newTransferPeer(region2, region2.GetStorePeer(2), &metapb.Peer{Id: 100, StoreId: 3})
+	c.Assert(co.addOperator(op1), IsTrue)
+	c.Assert(co.addOperator(op2), IsTrue)
+
+	// Region 1 splits without any heartbeat dispatched.
+	setEpoch(1, 1, 2)
+	co.removeStaleOperators()
+	c.Assert(co.getOperator(1), IsNil)
+	c.Assert(op1.GetState(), Equals, OperatorStale)
+	c.Assert(co.limiter.operatorCount(LeaderKind), Equals, uint64(0))
+
+	// The peer added by operator 2 itself doesn't make it stale.
+	setEpoch(2, 2, 1)
+	co.removeStaleOperators()
+	c.Assert(co.getOperator(2), NotNil)
+	region2 = setEpoch(2, 3, 1)
+	c.Assert(co.dispatch(region2), IsNil)
+	c.Assert(co.getOperator(2), IsNil)
+	c.Assert(op2.GetState(), Equals, OperatorStale)
+}
+
+func (s *testCoordinatorSuite) TestOperatorRecords(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 2)
+	region1, region2 := cluster.GetRegion(1), cluster.GetRegion(2)
+	c.Assert(co.addOperator(newTransferLeader(region1, region1.GetStorePeer(2))), IsTrue)
+	c.Assert(co.addOperator(newTransferLeader(region2, region2.GetStorePeer(2))), IsTrue)
+	c.Assert(co.getOperatorRecords(time.Time{}), HasLen, 0)
+
+	// Region 1 transfers leader to store 2.
+	start := time.Now()
+	region1.Leader = region1.GetStorePeer(2)
+	co.dispatch(region1)
+	// The operator of region 2 is canceled.
+	op := co.getOperator(2)
+	op.SetState(OperatorCanceled)
+	co.removeOperator(op)
+
+	records := co.getOperatorRecords(start)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].RegionID, Equals, uint64(1))
+	c.Assert(records[0].Kind, Equals, "leader")
+	c.Assert(records[0].State, Equals, OperatorFinished)
+	c.Assert(records[1].RegionID, Equals, uint64(2))
+	c.Assert(records[1].State, Equals, OperatorCanceled)
+	c.Assert(co.getOperatorRecords(time.Now().Add(time.Second)), HasLen, 0)
+}
+
+func (s *testCoordinatorSuite) TestDryRun(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.EnableDryRun = true
+	co := newCoordinator(cluster, opt)
+	co.run()
+	defer co.stop()
+
+	// The peer of store 4 should be moved to store 1.
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 3)
+	tc.addRegionStore(4, 4)
+	tc.addLeaderRegion(1, 2, 3, 4)
+	id, err := cluster.allocID()
+	c.Assert(err, IsNil)
+
+	var ops []*DryRunOperator
+	for i := 0; i < 20 && len(ops) == 0; i++ {
+		time.Sleep(time.Millisecond * 100)
+		ops = co.getDryRunOperators()
+	}
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID, Equals, uint64(1))
+	c.Assert(ops[0].Kind, Equals, "region")
+	c.Assert(ops[0].Scheduler, Equals, "balance-region-scheduler")
+	checkTransferPeer(c, ops[0].Operator, 4, 1)
+	// No peer id is allocated for the operator.
+	nextID, err := cluster.allocID()
+	c.Assert(err, IsNil)
+	c.Assert(nextID, Equals, id+1)
+	// The operator is not dispatched.
+	c.Assert(co.getOperator(1), IsNil)
+	c.Assert(co.dispatch(cluster.GetRegion(1)), IsNil)
+
+	// The operators are dispatched after dry-run mode is disabled, the
+	// scheduler is slowed down in dry-run mode.
+	cfg.EnableDryRun = false
+	for i := 0; i < 50 && co.getOperator(1) == nil; i++ {
+		time.Sleep(time.Millisecond * 100)
+	}
+	checkTransferPeer(c, co.getOperator(1), 4, 1)
+}
+
+func (s *testCoordinatorSuite) TestDisableScheduling(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.DisableScheduling = true
+	co := newCoordinator(cluster, opt)
+	co.run()
+	defer co.stop()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 3)
+	tc.addRegionStore(4, 4)
+	tc.addLeaderRegion(1, 2, 3)
+	region := cluster.GetRegion(1)
+
+	// Neither the checkers nor the schedulers create operators.
+	c.Assert(co.dispatch(region), IsNil)
+	time.Sleep(time.Millisecond * 500)
+	c.Assert(co.getOperator(1), IsNil)
+	co.RLock()
+	for _, s := range co.schedulers {
+		c.Assert(s.AllowSchedule(), IsFalse)
+	}
+	co.RUnlock()
+
+	// The operators added by the API are still dispatched.
+	c.Assert(co.addOperator(newTransferLeader(region, region.GetStorePeer(3))), IsTrue)
+	checkTransferLeaderResp(c, co.dispatch(region), 3)
+	co.removeOperator(co.getOperator(1))
+
+	// Turn off balance to check the replica checker only.
+	cfg.LeaderScheduleLimit = 0
+	cfg.RegionScheduleLimit = 0
+	cfg.DisableScheduling = false
+	checkAddPeerResp(c, co.dispatch(region), 1)
+}
+
+func (s *testCoordinatorSuite) TestRejectLeader(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	tc.addLabelsLeaderStore(1, 1, map[string]string{"zone": "z1"})
+	tc.addLabelsLeaderStore(2, 0, map[string]string{"zone": "z2"})
+	tc.addLeaderRegion(1, 1, 2)
+	region := cluster.GetRegion(1)
+	c.Assert(co.dispatch(region), IsNil)
+
+	// The leader is moved off the store rejecting leaders.
+	cfg.LabelProperty = LabelPropertyConfig{RejectLeader: {{Key: "zone", Value: "z1"}}}
+	cfg.LeaderScheduleLimit = 0
+	c.Assert(co.dispatch(region), IsNil)
+	cfg.LeaderScheduleLimit = 1
+	checkTransferLeaderResp(c, co.dispatch(region), 2)
+	checkTransferLeader(c, co.getOperator(1), 1, 2)
+}
+
+func (s *testCoordinatorSuite) TestMaxReplicasChange(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.ReplicaScheduleLimit = 1
+	co := newCoordinator(cluster, opt)
+
+	for i := uint64(1); i <= 4; i++ {
+		tc.addRegionStore(i, int(i))
+	}
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 1, 2, 3)
+	region1, region2 := cluster.GetRegion(1), cluster.GetRegion(2)
+	c.Assert(co.dispatch(region1), IsNil)
+
+	// The extra peers are removed after max-replicas is lowered, one region
+	// at a time.
+	opt.SetMaxReplicas(2)
+	checkRemovePeerResp(c, co.dispatch(region1), 3)
+	c.Assert(co.dispatch(region2), IsNil)
+	region1.RemoveStorePeer(3)
+	c.Assert(co.dispatch(region1), IsNil)
+	checkRemovePeerResp(c, co.dispatch(region2), 3)
+	region2.RemoveStorePeer(3)
+	c.Assert(co.dispatch(region2), IsNil)
+
+	// The peers are added back after max-replicas is raised.
+	opt.SetMaxReplicas(3)
+	checkAddPeerResp(c, co.dispatch(region1), 3)
+	c.Assert(co.dispatch(region2), IsNil)
+}
+
 func (s *testCoordinatorSuite) TestPeerState(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
@@ -192,7 +550,7 @@ func (s *testCoordinatorSuite) TestPeerState(c *C) {
 	waitOperator(c, co, 1)
 	checkTransferPeer(c, co.getOperator(1), 4, 1)
 
-	region := cluster.getRegion(1)
+	region := cluster.GetRegion(1)
 
 	// Add new peer.
 	resp := co.dispatch(region)
@@ -211,7 +569,7 @@ func (s *testCoordinatorSuite) TestPeerState(c *C) {
 	resp = co.dispatch(region)
 	checkRemovePeerResp(c, resp, 4)
 	tc.addLeaderRegion(1, 1, 2, 3)
-	region = cluster.getRegion(1)
+	region = cluster.GetRegion(1)
 	c.Assert(co.dispatch(region), IsNil)
 }
 
@@ -241,7 +599,7 @@ func (s *testCoordinatorSuite) TestShouldRun(c *C) {
 	}
 
 	for _, t := range tbl {
-		r := tc.getRegion(t.regionID)
+		r := tc.GetRegion(t.regionID)
 		r.Leader = r.Peers[0]
 		tc.handleRegionHeartbeat(r)
 		c.Assert(co.shouldRun(), Equals, t.shouldRun)
@@ -259,10 +617,11 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	co.run()
 	defer co.stop()
 
-	c.Assert(co.schedulers, HasLen, 3)
+	c.Assert(co.schedulers, HasLen, 4)
 	c.Assert(co.removeScheduler("balance-leader-scheduler"), IsNil)
 	c.Assert(co.removeScheduler("balance-region-scheduler"), IsNil)
 	c.Assert(co.removeScheduler("balance-hot-region-scheduler"), IsNil)
+	c.Assert(co.removeScheduler("balance-hot-read-region-scheduler"), IsNil)
 	c.Assert(co.schedulers, HasLen, 0)
 
 	// Add stores 1,2,3
@@ -285,20 +644,44 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 
 	// Transfer all leaders to store 1.
 	waitOperator(c, co, 2)
-	region2 := cluster.getRegion(2)
+	region2 := cluster.GetRegion(2)
 	checkTransferLeaderResp(c, co.dispatch(region2), 1)
 	region2.Leader = region2.GetStorePeer(1)
 	cluster.putRegion(region2)
 	c.Assert(co.dispatch(region2), IsNil)
 
 	waitOperator(c, co, 3)
-	region3 := cluster.getRegion(3)
+	region3 := cluster.GetRegion(3)
 	checkTransferLeaderResp(c, co.dispatch(region3), 1)
 	region3.Leader = region3.GetStorePeer(1)
 	cluster.putRegion(region3)
 	c.Assert(co.dispatch(region3), IsNil)
 }
 
+func (s *testCoordinatorSuite) TestPauseScheduler(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+	co.run()
+	defer co.stop()
+
+	name := "balance-leader-scheduler"
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+	c.Assert(co.pauseOrResumeScheduler(name, true), IsNil)
+	c.Assert(co.schedulers[name].AllowSchedule(), IsFalse)
+	c.Assert(co.getPausedSchedulers(), DeepEquals, []string{name})
+	c.Assert(co.pauseOrResumeScheduler(name, false), IsNil)
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+	c.Assert(co.getPausedSchedulers(), HasLen, 0)
+	c.Assert(co.pauseOrResumeScheduler("unknown-scheduler", true), NotNil)
+}
+
+func (co *coordinator) getOperatorSource(regionID uint64) string {
+	co.RLock()
+	defer co.RUnlock()
+	return co.sources[regionID].name
+}
+
 func waitOperator(c *C, co *coordinator, regionID uint64) {
 	for i := 0; i < 20; i++ {
 		if co.getOperator(regionID) != nil {
@@ -319,19 +702,19 @@ func (s *testScheduleLimiterSuite) TestOperatorCount(c *C) {
 	c.Assert(l.operatorCount(RegionKind), Equals, uint64(0))
 
 	leaderOP := newTestOperator(1, LeaderKind)
-	l.addOperator(leaderOP)
+	l.addOperator(leaderOP, "")
 	c.Assert(l.operatorCount(LeaderKind), Equals, uint64(1))
-	l.addOperator(leaderOP)
+	l.addOperator(leaderOP, "")
 	c.Assert(l.operatorCount(LeaderKind), Equals, uint64(2))
-	l.removeOperator(leaderOP)
+	l.removeOperator(leaderOP, "")
 	c.Assert(l.operatorCount(LeaderKind), Equals, uint64(1))
 
 	regionOP := newTestOperator(1, RegionKind)
-	l.addOperator(regionOP)
+	l.addOperator(regionOP, "")
 	c.Assert(l.operatorCount(RegionKind), Equals, uint64(1))
-	l.addOperator(regionOP)
+	l.addOperator(regionOP, "")
 	c.Assert(l.operatorCount(RegionKind), Equals, uint64(2))
-	l.removeOperator(regionOP)
+	l.removeOperator(regionOP, "")
 	c.Assert(l.operatorCount(RegionKind), Equals, uint64(1))
 }
 
@@ -348,7 +731,7 @@ func (s *testScheduleControllerSuite) TestController(c *C) {
 
 	for i := minScheduleInterval; sc.GetInterval() != maxScheduleInterval; i = time.Duration(float64(i) * scheduleIntervalFactor) {
 		c.Assert(sc.GetInterval(), Equals, i)
-		c.Assert(sc.Schedule(cluster), IsNil)
+		c.Assert(sc.Schedule(cluster, nil), IsNil)
 	}
 
 	cfg.LeaderScheduleLimit = 1
@@ -402,7 +785,7 @@ func (s *testScheduleControllerSuite) TestInterval(c *C) {
 	for _, n := range idleSeconds {
 		sc.nextInterval = minScheduleInterval
 		for totalSleep := time.Duration(0); totalSleep <= time.Second*time.Duration(n); totalSleep += sc.GetInterval() {
-			c.Assert(sc.Schedule(cluster), IsNil)
+			c.Assert(sc.Schedule(cluster, nil), IsNil)
 		}
 		c.Assert(sc.GetInterval(), Less, time.Second*time.Duration(n/2))
 	}
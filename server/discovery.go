@@ -0,0 +1,59 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/coreos/etcd/discovery"
+	"github.com/coreos/etcd/embed"
+	"github.com/coreos/etcd/pkg/types"
+	"github.com/coreos/etcd/wal"
+	"github.com/juju/errors"
+)
+
+// srvGetCluster is replaced by test.
+var srvGetCluster = discovery.SRVGetCluster
+
+// PrepareDiscoveryCluster resolves the initial cluster from the DNS SRV
+// records of discovery-srv, the records are the same as etcd uses, i.e.
+// _etcd-server-ssl._tcp.<domain> and _etcd-server._tcp.<domain>. The advertise
+// peer urls of the PD must be in the records.
+//
+// The initial cluster is only used to bootstrap the cluster, so nothing is
+// resolved if the PD has data already.
+func PrepareDiscoveryCluster(cfg *Config) error {
+	if cfg.DiscoverySRV == "" {
+		return nil
+	}
+	if wal.Exist(path.Join(cfg.DataDir, "member", "wal")) {
+		cfg.InitialCluster = ""
+		cfg.InitialClusterState = embed.ClusterStateFlagExisting
+		return nil
+	}
+
+	urls, err := types.NewURLs(strings.Split(cfg.AdvertisePeerUrls, ","))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	initialCluster, err := srvGetCluster(cfg.Name, cfg.DiscoverySRV, urls)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("initial cluster %s is resolved from discovery-srv %s", initialCluster, cfg.DiscoverySRV)
+	cfg.InitialCluster = initialCluster
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/coreos/etcd/discovery"
+	"github.com/coreos/etcd/embed"
+	"github.com/coreos/etcd/pkg/types"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testDiscoverySuite{})
+
+type testDiscoverySuite struct{}
+
+func (s *testDiscoverySuite) TearDownTest(c *C) {
+	srvGetCluster = discovery.SRVGetCluster
+}
+
+func (s *testDiscoverySuite) TestDiscoverySRV(c *C) {
+	cfg := NewTestSingleConfig()
+	defer cleanServer(cfg)
+	cfg.InitialCluster = ""
+	cfg.DiscoverySRV = "pd.example.com"
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(cfg.InitialCluster, Equals, "")
+
+	resolved := fmt.Sprintf("pd=%s,pd2=http://10.0.0.2:2380", cfg.AdvertisePeerUrls)
+	srvGetCluster = func(name, dns string, apurls types.URLs) (string, error) {
+		c.Assert(name, Equals, cfg.Name)
+		c.Assert(dns, Equals, cfg.DiscoverySRV)
+		c.Assert(apurls.String(), Equals, cfg.AdvertisePeerUrls)
+		return resolved, nil
+	}
+	c.Assert(PrepareDiscoveryCluster(cfg), IsNil)
+	c.Assert(cfg.InitialCluster, Equals, resolved)
+	c.Assert(cfg.Check(), IsNil)
+
+	// Nothing is resolved after the PD has data.
+	walDir := path.Join(cfg.DataDir, "member", "wal")
+	c.Assert(os.MkdirAll(walDir, privateDirMode), IsNil)
+	c.Assert(ioutil.WriteFile(path.Join(walDir, "0.wal"), nil, privateFileMode), IsNil)
+	srvGetCluster = func(string, string, types.URLs) (string, error) {
+		c.Fatal("unexpected discovery")
+		return "", nil
+	}
+	c.Assert(PrepareDiscoveryCluster(cfg), IsNil)
+	c.Assert(cfg.InitialCluster, Equals, "")
+	c.Assert(cfg.InitialClusterState, Equals, embed.ClusterStateFlagExisting)
+
+	// It can't be used with initial-cluster or join.
+	cfg.InitialCluster = resolved
+	c.Assert(cfg.adjust(), NotNil)
+	cfg.InitialCluster, cfg.Join = "", "http://10.0.0.2:2379"
+	c.Assert(cfg.adjust(), NotNil)
+}
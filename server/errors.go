@@ -0,0 +1,80 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// Error is an error of a type the clients can tell apart without parsing the
+// message. It's returned in the header of the gRPC responses, and the HTTP
+// API maps the type to the status code.
+type Error struct {
+	Type    pdpb.ErrorType
+	Message string
+}
+
+func newError(typ pdpb.ErrorType, format string, args ...interface{}) *Error {
+	return &Error{Type: typ, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorType returns the type of the cause of the error, it's UNKNOWN if the
+// error is not typed.
+func ErrorType(err error) pdpb.ErrorType {
+	if err == nil {
+		return pdpb.ErrorType_OK
+	}
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e.Type
+	}
+	return pdpb.ErrorType_UNKNOWN
+}
+
+// NotFoundError is the error of a store or a region not found. pdpb has no
+// error type for it, so it's returned as a gRPC error, but the HTTP API still
+// tells it apart.
+type NotFoundError struct {
+	Message string
+}
+
+func newNotFoundError(format string, args ...interface{}) *NotFoundError {
+	return &NotFoundError{Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// IsNotFound returns true if the cause of the error is a NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := errors.Cause(err).(*NotFoundError)
+	return ok
+}
+
+// pbError converts the typed error to the error of the response header, it
+// returns nil if the error is not typed.
+func pbError(err error) *pdpb.Error {
+	e, ok := errors.Cause(err).(*Error)
+	if !ok {
+		return nil
+	}
+	return &pdpb.Error{Type: e.Type, Message: e.Message}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const etcdStatusUpdateInterval = 10 * time.Second
+
+// The metrics registered by the embedded etcd.
+const (
+	etcdLeaderChangesMetric    = "etcd_server_leader_changes_seen_total"
+	etcdProposalsFailedMetric  = "etcd_server_proposals_failed_total"
+	etcdProposalsPendingMetric = "etcd_server_proposals_pending"
+	etcdWALFsyncMetric         = "etcd_disk_wal_fsync_duration_seconds"
+)
+
+// EtcdStatus is the health and raft status of the embedded etcd of a member.
+// The WAL fsync duration is the average in seconds since the member starts.
+type EtcdStatus struct {
+	ID               uint64  `json:"id"`
+	Leader           uint64  `json:"leader"`
+	Term             uint64  `json:"term"`
+	Index            uint64  `json:"index"`
+	LeaderChanges    uint64  `json:"leader_changes"`
+	ProposalsFailed  uint64  `json:"proposals_failed"`
+	ProposalsPending uint64  `json:"proposals_pending"`
+	WALFsyncCount    uint64  `json:"wal_fsync_count"`
+	WALFsyncDuration float64 `json:"wal_fsync_duration"`
+	DBSize           int64   `json:"db_size"`
+}
+
+// GetEtcdStatus returns the status of the embedded etcd.
+func (s *Server) GetEtcdStatus() (*EtcdStatus, error) {
+	if s.etcd == nil {
+		return nil, errors.New("etcd is not started")
+	}
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	metrics := make(map[string]*dto.Metric)
+	for _, family := range families {
+		if len(family.GetMetric()) > 0 {
+			metrics[family.GetName()] = family.GetMetric()[0]
+		}
+	}
+
+	etcdServer := s.etcd.Server
+	status := &EtcdStatus{
+		ID:               uint64(etcdServer.ID()),
+		Leader:           etcdServer.Lead(),
+		Term:             etcdServer.Term(),
+		Index:            etcdServer.Index(),
+		LeaderChanges:    uint64(metrics[etcdLeaderChangesMetric].GetCounter().GetValue()),
+		ProposalsFailed:  uint64(metrics[etcdProposalsFailedMetric].GetCounter().GetValue()),
+		ProposalsPending: uint64(metrics[etcdProposalsPendingMetric].GetGauge().GetValue()),
+		DBSize:           etcdServer.Backend().Size(),
+	}
+	if fsync := metrics[etcdWALFsyncMetric].GetHistogram(); fsync.GetSampleCount() > 0 {
+		status.WALFsyncCount = fsync.GetSampleCount()
+		status.WALFsyncDuration = fsync.GetSampleSum() / float64(fsync.GetSampleCount())
+	}
+	return status, nil
+}
+
+func (s *Server) updateEtcdStatusMetrics() {
+	status, err := s.GetEtcdStatus()
+	if err != nil {
+		log.Errorf("get etcd status meet error: %v", err)
+		return
+	}
+	etcdStatusGauge.WithLabelValues("term").Set(float64(status.Term))
+	etcdStatusGauge.WithLabelValues("leader_changes").Set(float64(status.LeaderChanges))
+	etcdStatusGauge.WithLabelValues("proposals_failed").Set(float64(status.ProposalsFailed))
+	etcdStatusGauge.WithLabelValues("proposals_pending").Set(float64(status.ProposalsPending))
+	etcdStatusGauge.WithLabelValues("wal_fsync_duration_seconds").Set(status.WALFsyncDuration)
+	etcdStatusGauge.WithLabelValues("db_size").Set(float64(status.DBSize))
+	s.checkEtcdQuota(status.DBSize)
+}
+
+// etcdStatusLoop updates the etcd status metrics until the server is closed.
+func (s *Server) etcdStatusLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(etcdStatusUpdateInterval)
+	defer ticker.Stop()
+	for {
+		s.updateEtcdStatusMetrics()
+		select {
+		case <-ticker.C:
+		case <-s.client.Ctx().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build failpoint
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/failpoint"
+)
+
+var _ = Suite(&testFailpointSuite{})
+
+type testFailpointSuite struct{}
+
+func (s *testFailpointSuite) TestCampaignLeader(c *C) {
+	c.Assert(failpoint.Enable("campaignLeaderErr", "return"), IsNil)
+	defer failpoint.Disable("campaignLeaderErr")
+
+	svr, cleanup := newTestServer(c)
+	defer cleanup()
+	go svr.Run()
+
+	time.Sleep(time.Second)
+	c.Assert(svr.IsLeader(), IsFalse)
+	failpoint.Disable("campaignLeaderErr")
+	mustWaitLeader(c, []*Server{svr})
+}
+
+func (s *testFailpointSuite) TestTsoClockJump(c *C) {
+	svr, cleanup := newTestServer(c)
+	defer cleanup()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	// The leader stops serving once the clock jumps forward too far, and
+	// campaigns again later.
+	c.Assert(failpoint.Enable("tsoClockJump", "1*return(3600000)"), IsNil)
+	defer failpoint.Disable("tsoClockJump")
+	for i := 0; i < 20 && svr.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(svr.IsLeader(), IsFalse)
+	mustWaitLeader(c, []*Server{svr})
+	_, err := svr.getRespTS(1)
+	c.Assert(err, IsNil)
+}
+
+func (s *testFailpointSuite) TestRegionHeartbeat(c *C) {
+	c.Assert(failpoint.Enable("regionHeartbeatErr", `1*return("injected")`), IsNil)
+	defer failpoint.Disable("regionHeartbeatErr")
+
+	cluster := newClusterInfo(newMockIDAllocator())
+	region := newTestRegions(1, 3)[0]
+	err := cluster.handleRegionHeartbeat(region)
+	c.Assert(err, ErrorMatches, ".*injected.*")
+	c.Assert(cluster.GetRegion(region.GetId()), IsNil)
+
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	c.Assert(cluster.GetRegion(region.GetId()), NotNil)
+}
+
+func (s *testFailpointSuite) TestSaveRegion(c *C) {
+	c.Assert(failpoint.Enable("saveRegionErr", "1*return"), IsNil)
+	defer failpoint.Disable("saveRegionErr")
+
+	server, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	cluster := newClusterInfo(server.idAlloc)
+	cluster.kv = server.kv
+
+	// The region is not cached if it's not saved, so it's saved again by the
+	// next heartbeat.
+	region := newTestRegions(1, 3)[0]
+	c.Assert(cluster.handleRegionHeartbeat(region), NotNil)
+	c.Assert(cluster.GetRegion(region.GetId()), IsNil)
+	ok, err := server.kv.loadRegion(region.GetId(), &metapb.Region{})
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	c.Assert(cluster.GetRegion(region.GetId()), NotNil)
+	checkRegionsKV(c, server.kv, []*RegionInfo{region})
+}
+
+func (s *testFailpointSuite) TestDropOperatorResponse(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 0)
+	tc.addLeaderRegion(1, 1, 2)
+	region := cluster.GetRegion(1)
+	co.addOperator(newTransferLeader(region, region.GetStorePeer(2)))
+
+	// The operator keeps running while its responses are dropped.
+	c.Assert(failpoint.Enable("dropOperatorResponse", "2*return"), IsNil)
+	defer failpoint.Disable("dropOperatorResponse")
+	for i := 0; i < 2; i++ {
+		c.Assert(co.dispatch(region), IsNil)
+		c.Assert(co.getOperator(1), NotNil)
+	}
+	checkTransferLeaderResp(c, co.dispatch(region), 2)
+}
@@ -16,12 +16,12 @@ package server
 // Filter is an interface to filter source and target store.
 type Filter interface {
 	// Return true if the store should not be used as a source store.
-	FilterSource(store *storeInfo) bool
+	FilterSource(store *StoreInfo) bool
 	// Return true if the store should not be used as a target store.
-	FilterTarget(store *storeInfo) bool
+	FilterTarget(store *StoreInfo) bool
 }
 
-func filterSource(store *storeInfo, filters []Filter) bool {
+func filterSource(store *StoreInfo, filters []Filter) bool {
 	for _, filter := range filters {
 		if filter.FilterSource(store) {
 			return true
@@ -30,7 +30,7 @@ func filterSource(store *storeInfo, filters []Filter) bool {
 	return false
 }
 
-func filterTarget(store *storeInfo, filters []Filter) bool {
+func filterTarget(store *StoreInfo, filters []Filter) bool {
 	for _, filter := range filters {
 		if filter.FilterTarget(store) {
 			return true
@@ -51,12 +51,12 @@ func newExcludedFilter(sources, targets map[uint64]struct{}) *excludedFilter {
 	}
 }
 
-func (f *excludedFilter) FilterSource(store *storeInfo) bool {
+func (f *excludedFilter) FilterSource(store *StoreInfo) bool {
 	_, ok := f.sources[store.GetId()]
 	return ok
 }
 
-func (f *excludedFilter) FilterTarget(store *storeInfo) bool {
+func (f *excludedFilter) FilterTarget(store *StoreInfo) bool {
 	_, ok := f.targets[store.GetId()]
 	return ok
 }
@@ -67,11 +67,11 @@ func newBlockFilter() *blockFilter {
 	return &blockFilter{}
 }
 
-func (f *blockFilter) FilterSource(store *storeInfo) bool {
+func (f *blockFilter) FilterSource(store *StoreInfo) bool {
 	return store.isBlocked()
 }
 
-func (f *blockFilter) FilterTarget(store *storeInfo) bool {
+func (f *blockFilter) FilterTarget(store *StoreInfo) bool {
 	return store.isBlocked()
 }
 
@@ -83,105 +83,199 @@ func newCacheFilter(cache *idCache) *cacheFilter {
 	return &cacheFilter{cache: cache}
 }
 
-func (f *cacheFilter) FilterSource(store *storeInfo) bool {
+func (f *cacheFilter) FilterSource(store *StoreInfo) bool {
 	return f.cache.get(store.GetId())
 }
 
-func (f *cacheFilter) FilterTarget(store *storeInfo) bool {
+func (f *cacheFilter) FilterTarget(store *StoreInfo) bool {
 	return false
 }
 
 type stateFilter struct {
-	opt *scheduleOption
+	opt ScheduleOptions
 }
 
-func newStateFilter(opt *scheduleOption) *stateFilter {
+func newStateFilter(opt ScheduleOptions) *stateFilter {
 	return &stateFilter{opt: opt}
 }
 
-func (f *stateFilter) filter(store *storeInfo) bool {
+func (f *stateFilter) filter(store *StoreInfo) bool {
 	return !store.isUp()
 }
 
-func (f *stateFilter) FilterSource(store *storeInfo) bool {
+func (f *stateFilter) FilterSource(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
-func (f *stateFilter) FilterTarget(store *storeInfo) bool {
+func (f *stateFilter) FilterTarget(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
+// rejectLeaderFilter filters the stores rejecting leaders from being the target
+// of leader transfers.
+type rejectLeaderFilter struct {
+	opt ScheduleOptions
+}
+
+func newRejectLeaderFilter(opt ScheduleOptions) *rejectLeaderFilter {
+	return &rejectLeaderFilter{opt: opt}
+}
+
+func (f *rejectLeaderFilter) FilterSource(store *StoreInfo) bool {
+	return false
+}
+
+func (f *rejectLeaderFilter) FilterTarget(store *StoreInfo) bool {
+	return f.opt.CheckLabelProperty(RejectLeader, store.GetLabels())
+}
+
+// specialEngineFilter filters the stores of special engines, only the rules
+// targeting the engines may place replicas to them, so the ordinary schedules
+// neither move peers or leaders to them nor move the peers out of them.
+type specialEngineFilter struct{}
+
+func newSpecialEngineFilter() *specialEngineFilter {
+	return &specialEngineFilter{}
+}
+
+func (f *specialEngineFilter) FilterSource(store *StoreInfo) bool {
+	return store.isSpecialEngine()
+}
+
+func (f *specialEngineFilter) FilterTarget(store *StoreInfo) bool {
+	return store.isSpecialEngine()
+}
+
 type healthFilter struct {
-	opt *scheduleOption
+	opt ScheduleOptions
 }
 
-func newHealthFilter(opt *scheduleOption) *healthFilter {
+func newHealthFilter(opt ScheduleOptions) *healthFilter {
 	return &healthFilter{opt: opt}
 }
 
-func (f *healthFilter) filter(store *storeInfo) bool {
+func (f *healthFilter) filter(store *StoreInfo) bool {
 	if store.status.GetIsBusy() {
 		return true
 	}
 	return store.downTime() > f.opt.GetMaxStoreDownTime()
 }
 
-func (f *healthFilter) FilterSource(store *storeInfo) bool {
+func (f *healthFilter) FilterSource(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
-func (f *healthFilter) FilterTarget(store *storeInfo) bool {
+func (f *healthFilter) FilterTarget(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
+// snapshotCountFilter ensures that we will not schedule a store which is busy
+// sending, receiving or applying snapshots, so a slow store is not buried by
+// concurrent snapshots. The limit of a store may be set by the API.
 type snapshotCountFilter struct {
-	opt *scheduleOption
+	opt ScheduleOptions
 }
 
-func newSnapshotCountFilter(opt *scheduleOption) *snapshotCountFilter {
+func newSnapshotCountFilter(opt ScheduleOptions) *snapshotCountFilter {
 	return &snapshotCountFilter{opt: opt}
 }
 
-func (f *snapshotCountFilter) filter(store *storeInfo) bool {
-	return uint64(store.status.GetSendingSnapCount()) > f.opt.GetMaxSnapshotCount() ||
-		uint64(store.status.GetReceivingSnapCount()) > f.opt.GetMaxSnapshotCount() ||
-		uint64(store.status.GetApplyingSnapCount()) > f.opt.GetMaxSnapshotCount()
+func (f *snapshotCountFilter) filter(store *StoreInfo) bool {
+	limit := store.snapshotLimit(f.opt)
+	return uint64(store.status.GetSendingSnapCount()) > limit ||
+		uint64(store.status.GetReceivingSnapCount()) > limit ||
+		uint64(store.status.GetApplyingSnapCount()) > limit
 }
 
-func (f *snapshotCountFilter) FilterSource(store *storeInfo) bool {
+func (f *snapshotCountFilter) FilterSource(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
-func (f *snapshotCountFilter) FilterTarget(store *storeInfo) bool {
+func (f *snapshotCountFilter) FilterTarget(store *StoreInfo) bool {
 	return f.filter(store)
 }
 
+// pendingPeerCountFilter ensures that we will not add more peers to a store
+// which has too many pending peers catching up.
+type pendingPeerCountFilter struct {
+	opt ScheduleOptions
+}
+
+func newPendingPeerCountFilter(opt ScheduleOptions) *pendingPeerCountFilter {
+	return &pendingPeerCountFilter{opt: opt}
+}
+
+func (f *pendingPeerCountFilter) FilterSource(store *StoreInfo) bool {
+	return false
+}
+
+func (f *pendingPeerCountFilter) FilterTarget(store *StoreInfo) bool {
+	return uint64(store.status.PendingPeerCount) > f.opt.GetMaxPendingPeerCount()
+}
+
 // storageThresholdFilter ensures that we will not use an almost full store as a target.
-type storageThresholdFilter struct{}
+type storageThresholdFilter struct {
+	opt ScheduleOptions
+}
 
-const storageAvailableRatioThreshold = 0.2
+func newStorageThresholdFilter(opt ScheduleOptions) *storageThresholdFilter {
+	return &storageThresholdFilter{opt: opt}
+}
 
-func newStorageThresholdFilter(opt *scheduleOption) *storageThresholdFilter {
-	return &storageThresholdFilter{}
+func (f *storageThresholdFilter) FilterSource(store *StoreInfo) bool {
+	return false
 }
 
-func (f *storageThresholdFilter) FilterSource(store *storeInfo) bool {
+func (f *storageThresholdFilter) FilterTarget(store *StoreInfo) bool {
+	return store.usedRatio() > f.opt.GetHighSpaceRatio()
+}
+
+// leaderStorageThresholdFilter ensures that we will not transfer leaders to an
+// almost full store, so it takes no more load while running out of space. The
+// stores without capacity are not filtered as their space is unknown.
+type leaderStorageThresholdFilter struct {
+	opt ScheduleOptions
+}
+
+func newLeaderStorageThresholdFilter(opt ScheduleOptions) *leaderStorageThresholdFilter {
+	return &leaderStorageThresholdFilter{opt: opt}
+}
+
+func (f *leaderStorageThresholdFilter) FilterSource(store *StoreInfo) bool {
 	return false
 }
 
-func (f *storageThresholdFilter) FilterTarget(store *storeInfo) bool {
-	return store.availableRatio() < storageAvailableRatioThreshold
+func (f *leaderStorageThresholdFilter) FilterTarget(store *StoreInfo) bool {
+	return store.isLowSpace(f.opt.GetHighSpaceRatio())
+}
+
+// lowSpaceFilter keeps only the low space stores as the sources, to move the
+// replicas out of them.
+type lowSpaceFilter struct {
+	opt ScheduleOptions
+}
+
+func newLowSpaceFilter(opt ScheduleOptions) *lowSpaceFilter {
+	return &lowSpaceFilter{opt: opt}
+}
+
+func (f *lowSpaceFilter) FilterSource(store *StoreInfo) bool {
+	return !store.isLowSpace(f.opt.GetLowSpaceRatio())
+}
+
+func (f *lowSpaceFilter) FilterTarget(store *StoreInfo) bool {
+	return false
 }
 
 // distinctScoreFilter ensures that distinct score will not decrease.
 type distinctScoreFilter struct {
 	rep       *Replication
-	stores    []*storeInfo
+	stores    []*StoreInfo
 	safeScore float64
 }
 
-func newDistinctScoreFilter(rep *Replication, stores []*storeInfo, source *storeInfo) *distinctScoreFilter {
-	newStores := make([]*storeInfo, 0, len(stores)-1)
+func newDistinctScoreFilter(rep *Replication, stores []*StoreInfo, source *StoreInfo) *distinctScoreFilter {
+	newStores := make([]*StoreInfo, 0, len(stores)-1)
 	for _, s := range stores {
 		if s.GetId() == source.GetId() {
 			continue
@@ -196,10 +290,10 @@ func newDistinctScoreFilter(rep *Replication, stores []*storeInfo, source *store
 	}
 }
 
-func (f *distinctScoreFilter) FilterSource(store *storeInfo) bool {
+func (f *distinctScoreFilter) FilterSource(store *StoreInfo) bool {
 	return false
 }
 
-func (f *distinctScoreFilter) FilterTarget(store *storeInfo) bool {
+func (f *distinctScoreFilter) FilterTarget(store *StoreInfo) bool {
 	return f.rep.GetDistinctScore(f.stores, store) < f.safeScore
 }
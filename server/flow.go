@@ -0,0 +1,154 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// flowDecayWindow is the time window of the region flow, the weight of a
+// heartbeat decays to 1/e after this time.
+const flowDecayWindow = 5 * regionHeartBeatReportInterval * time.Second
+
+// flowExpireTime is how long the flow of a region is kept without heartbeats,
+// the region is removed or has no leader by then and its flow has decayed.
+const flowExpireTime = 10 * flowDecayWindow
+
+// RegionFlow is the recent flow of a region, in bytes or keys per second.
+type RegionFlow struct {
+	BytesWritten   float64   `json:"bytes_written"`
+	BytesRead      float64   `json:"bytes_read"`
+	KeysWritten    float64   `json:"keys_written"`
+	KeysRead       float64   `json:"keys_read"`
+	LastUpdateTime time.Time `json:"last_update_time"`
+}
+
+// update adds the flow of a heartbeat reported in interval, the previous flow
+// decays by the time since it is updated.
+func (f *RegionFlow) update(region *RegionInfo, interval time.Duration, now time.Time) {
+	seconds := interval.Seconds()
+	decay := math.Exp(-float64(now.Sub(f.LastUpdateTime)) / float64(flowDecayWindow))
+	if f.LastUpdateTime.IsZero() {
+		decay = 0
+	}
+	rate := func(old float64, v uint64) float64 {
+		return old*decay + float64(v)/seconds*(1-decay)
+	}
+	f.BytesWritten = rate(f.BytesWritten, region.WrittenBytes)
+	f.BytesRead = rate(f.BytesRead, region.ReadBytes)
+	f.KeysWritten = rate(f.KeysWritten, region.WrittenKeys)
+	f.KeysRead = rate(f.KeysRead, region.ReadKeys)
+	f.LastUpdateTime = now
+}
+
+// add adds the flow of another region to the sum of the flows.
+func (f *RegionFlow) add(other *RegionFlow, sign float64) {
+	f.BytesWritten += other.BytesWritten * sign
+	f.BytesRead += other.BytesRead * sign
+	f.KeysWritten += other.KeysWritten * sign
+	f.KeysRead += other.KeysRead * sign
+}
+
+// regionFlows keeps the rolling window flows of regions, it's safe for
+// concurrent use.
+type regionFlows struct {
+	sync.RWMutex
+	flows map[uint64]*RegionFlow
+	// total is the sum of the flows of all the regions, it is updated on
+	// every change and recomputed by prune, so the float errors don't
+	// accumulate.
+	total RegionFlow
+}
+
+func newRegionFlows() *regionFlows {
+	return &regionFlows{
+		flows: make(map[uint64]*RegionFlow),
+	}
+}
+
+func (r *regionFlows) update(region *RegionInfo, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	flow, ok := r.flows[region.GetId()]
+	if !ok {
+		flow = &RegionFlow{}
+		r.flows[region.GetId()] = flow
+	}
+
+	interval := now.Sub(flow.LastUpdateTime)
+	if !ok || interval > regionHeartBeatReportInterval*time.Second {
+		interval = regionHeartBeatReportInterval * time.Second
+	}
+	if interval < minHotRegionReportInterval*time.Second {
+		interval = minHotRegionReportInterval * time.Second
+	}
+	r.total.add(flow, -1)
+	flow.update(region, interval, now)
+	r.total.add(flow, 1)
+}
+
+// remove removes the flow of the region, e.g. it's merged.
+func (r *regionFlows) remove(regionID uint64) {
+	r.Lock()
+	defer r.Unlock()
+	if flow, ok := r.flows[regionID]; ok {
+		r.total.add(flow, -1)
+		delete(r.flows, regionID)
+	}
+	if len(r.flows) == 0 {
+		r.total = RegionFlow{}
+	}
+}
+
+// prune removes the flows of the regions which don't report heartbeats for
+// flowExpireTime, e.g. they are removed without being merged. The total is
+// recomputed from the rest of the flows.
+func (r *regionFlows) prune(now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	var total RegionFlow
+	for id, flow := range r.flows {
+		if now.Sub(flow.LastUpdateTime) > flowExpireTime {
+			delete(r.flows, id)
+			continue
+		}
+		total.add(flow, 1)
+	}
+	r.total = total
+}
+
+// getAverage returns the average flow of the regions, it's zero if there is
+// no region.
+func (r *regionFlows) getAverage() RegionFlow {
+	r.RLock()
+	defer r.RUnlock()
+	var avg RegionFlow
+	if len(r.flows) > 0 {
+		avg.add(&r.total, 1/float64(len(r.flows)))
+	}
+	return avg
+}
+
+func (r *regionFlows) get(regionID uint64) *RegionFlow {
+	r.RLock()
+	defer r.RUnlock()
+	flow, ok := r.flows[regionID]
+	if !ok {
+		return nil
+	}
+	f := *flow
+	return &f
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testFlowSuite{})
+
+type testFlowSuite struct{}
+
+func (s *testFlowSuite) TestRegionFlows(c *C) {
+	flows := newRegionFlows()
+	c.Assert(flows.get(1), IsNil)
+
+	region := newTestRegions(2, 3)[1]
+	region.WrittenBytes, region.ReadBytes = 6000, 600
+	region.WrittenKeys, region.ReadKeys = 60, 6
+
+	// The first heartbeat is considered as reported in the heartbeat interval.
+	now := time.Now()
+	flows.update(region, now)
+	flow := flows.get(1)
+	c.Assert(flow.BytesWritten, Equals, float64(100))
+	c.Assert(flow.BytesRead, Equals, float64(10))
+	c.Assert(flow.KeysWritten, Equals, float64(1))
+	c.Assert(flow.KeysRead, Equals, 0.1)
+
+	// The previous flow decays.
+	interval := 30 * time.Second
+	now = now.Add(interval)
+	region.WrittenBytes, region.ReadBytes = 0, 0
+	flows.update(region, now)
+	decay := math.Exp(-float64(interval) / float64(flowDecayWindow))
+	flow = flows.get(1)
+	c.Assert(flow.BytesWritten, Equals, 100*decay)
+	c.Assert(flow.BytesRead, Equals, 10*decay)
+	c.Assert(flow.LastUpdateTime, Equals, now)
+
+	// Converges to the current rate.
+	region.WrittenBytes = 3000
+	for i := 0; i < 100; i++ {
+		now = now.Add(interval)
+		flows.update(region, now)
+	}
+	c.Assert(math.Abs(flows.get(1).BytesWritten-100), Less, 0.01)
+
+	// A clone is returned.
+	flows.get(1).BytesWritten = 0
+	c.Assert(flows.get(1).BytesWritten, Not(Equals), float64(0))
+}
+
+func (s *testFlowSuite) TestAverageFlow(c *C) {
+	flows := newRegionFlows()
+	c.Assert(flows.getAverage().BytesWritten, Equals, float64(0))
+
+	regions := newTestRegions(3, 3)
+	for i, region := range regions {
+		region.WrittenBytes = uint64(i+1) * 6000
+		region.ReadBytes = uint64(i+1) * 600
+		flows.update(region, time.Now())
+	}
+	avg := flows.getAverage()
+	c.Assert(math.Abs(avg.BytesWritten-200), Less, 1e-9)
+	c.Assert(math.Abs(avg.BytesRead-20), Less, 1e-9)
+
+	// The flow of a region is replaced on update.
+	regions[2].WrittenBytes = 0
+	flows.update(regions[2], time.Now().Add(flowDecayWindow*100))
+	c.Assert(math.Abs(flows.getAverage().BytesWritten-100), Less, 1e-9)
+
+	// The removed region is not counted.
+	flows.remove(regions[0].GetId())
+	c.Assert(flows.get(regions[0].GetId()), IsNil)
+	c.Assert(math.Abs(flows.getAverage().BytesWritten-100), Less, 1e-9)
+	flows.remove(regions[1].GetId())
+	flows.remove(regions[2].GetId())
+	c.Assert(flows.getAverage().BytesWritten, Equals, float64(0))
+}
+
+func (s *testFlowSuite) TestPruneFlows(c *C) {
+	flows := newRegionFlows()
+	regions := newTestRegions(2, 3)
+	now := time.Now()
+	flows.update(regions[0], now)
+	flows.update(regions[1], now.Add(flowExpireTime))
+
+	// The region without heartbeats is removed.
+	flows.prune(now.Add(flowExpireTime + time.Second))
+	c.Assert(flows.get(regions[0].GetId()), IsNil)
+	c.Assert(flows.get(regions[1].GetId()), NotNil)
+	flows.prune(now.Add(2*flowExpireTime + time.Second))
+	c.Assert(flows.get(regions[1].GetId()), IsNil)
+}
+
+func (s *testFlowSuite) TestRecomputeTotal(c *C) {
+	flows := newRegionFlows()
+	regions := newTestRegions(2, 3)
+	now := time.Now()
+	for _, region := range regions {
+		region.WrittenBytes = 6000
+		flows.update(region, now)
+	}
+	// The errors of the running total are dropped by prune.
+	flows.total.BytesWritten += 1
+	c.Assert(flows.getAverage().BytesWritten, Not(Equals), float64(100))
+	flows.prune(now)
+	c.Assert(math.Abs(flows.getAverage().BytesWritten-100), Less, 1e-9)
+
+	// The total is reset once all the flows are removed.
+	flows.total.BytesWritten += 1
+	flows.remove(regions[0].GetId())
+	flows.remove(regions[1].GetId())
+	c.Assert(flows.total, Equals, RegionFlow{})
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// forwardedKey is the metadata key of the requests forwarded by a follower.
+// A forwarded request is not forwarded again, so it doesn't loop among the
+// followers while the leader changes.
+const forwardedKey = "pd-forwarded"
+
+// getForwardClient returns the client of the leader and the context to
+// forward the read-only request, the client is nil if the server is leader.
+// It helps the clients which can't reach the leader, but reach the followers.
+func (s *Server) getForwardClient(ctx context.Context) (pdpb.PDClient, context.Context, error) {
+	if s.IsLeader() {
+		return nil, nil, nil
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[forwardedKey]) > 0 {
+		return nil, nil, notLeaderError
+	}
+	conn, err := s.getLeaderConn()
+	if err != nil {
+		// The clients retry the not leader error after the leader is elected.
+		log.Warnf("failed to forward the request to the leader: %v", err)
+		return nil, nil, notLeaderError
+	}
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(forwardedKey, s.Name()))
+	return pdpb.NewPDClient(conn), ctx, nil
+}
+
+// getLeaderConn returns the gRPC connection to the current leader. The
+// connections are cached and shared by all forwarded streams.
+func (s *Server) getLeaderConn() (*grpc.ClientConn, error) {
+	leader, err := s.GetLeader()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if s.isSameLeader(leader) || len(leader.GetClientUrls()) == 0 {
+		// The leader key is not updated yet.
+		return nil, notLeaderError
+	}
+	return s.getOrCreateGRPCConn(leader.GetClientUrls()[0])
+}
+
+func (s *Server) getOrCreateGRPCConn(addr string) (*grpc.ClientConn, error) {
+	s.connMu.RLock()
+	conn, ok := s.connMu.clientConns[addr]
+	s.connMu.RUnlock()
+	if ok {
+		return conn, nil
+	}
+
+	cc, err := grpc.Dial(addr, grpc.WithDialer(func(addr string, d time.Duration) (net.Conn, error) {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// For tests.
+		if u.Scheme == "unix" || u.Scheme == "unixs" {
+			return net.DialTimeout("unix", u.Host, d)
+		}
+		return net.DialTimeout("tcp", u.Host, d)
+	}), grpc.WithInsecure())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if old, ok := s.connMu.clientConns[addr]; ok {
+		cc.Close()
+		return old, nil
+	}
+	s.connMu.clientConns[addr] = cc
+	return cc, nil
+}
+
+func (s *Server) closeGRPCConns() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	for addr, cc := range s.connMu.clientConns {
+		if err := cc.Close(); err != nil {
+			log.Errorf("failed to close grpc conn to %s: %v", addr, err)
+		}
+		delete(s.connMu.clientConns, addr)
+	}
+}
+
+// forwardTso forwards the Tso stream to the leader, it is used when the
+// server is not leader.
+func (s *Server) forwardTso(stream pdpb.PD_TsoServer) error {
+	conn, err := s.getLeaderConn()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	leaderStream, err := pdpb.NewPDClient(conn).Tso(stream.Context())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			request, err := stream.Recv()
+			if err == io.EOF {
+				errCh <- errors.Trace(leaderStream.CloseSend())
+				return
+			}
+			if err != nil {
+				errCh <- errors.Trace(err)
+				return
+			}
+			if err = leaderStream.Send(request); err != nil {
+				errCh <- errors.Trace(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		response, err := leaderStream.Recv()
+		if err == io.EOF {
+			return errors.Trace(<-errCh)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = stream.Send(response); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
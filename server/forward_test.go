@@ -0,0 +1,73 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = Suite(&testForwardSuite{})
+
+type testForwardSuite struct{}
+
+func (s *testForwardSuite) TestForwardReadRequests(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+	leaderClient := mustNewGrpcClient(c, leader.GetAddr())
+	s.bootstrap(c, leader, leaderClient)
+	followerClient := mustNewGrpcClient(c, follower.GetAddr())
+
+	// The read-only requests are forwarded to the leader.
+	header := newRequestHeader(leader.clusterID)
+	storeResp, err := followerClient.GetStore(context.Background(), &pdpb.GetStoreRequest{Header: header, StoreId: 1})
+	c.Assert(err, IsNil)
+	c.Assert(storeResp.GetHeader().GetError(), IsNil)
+	c.Assert(storeResp.GetStore().GetId(), Equals, uint64(1))
+	regionResp, err := followerClient.GetRegion(context.Background(), &pdpb.GetRegionRequest{Header: header, RegionKey: []byte("a")})
+	c.Assert(err, IsNil)
+	c.Assert(regionResp.GetRegion().GetId(), Equals, uint64(2))
+
+	// The others are not.
+	_, err = followerClient.AllocID(context.Background(), &pdpb.AllocIDRequest{Header: header})
+	c.Assert(err, NotNil)
+
+	// The forwarded request is not forwarded again.
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(forwardedKey, "pd"))
+	_, err = followerClient.GetStore(ctx, &pdpb.GetStoreRequest{Header: header, StoreId: 1})
+	c.Assert(err, ErrorMatches, ".*not leader.*")
+}
+
+func (s *testForwardSuite) bootstrap(c *C, leader *Server, client pdpb.PDClient) {
+	req := &pdpb.BootstrapRequest{
+		Header: newRequestHeader(leader.clusterID),
+		Store:  &metapb.Store{Id: 1, Address: "127.0.0.1:0"},
+		Region: &metapb.Region{Id: 2, Peers: []*metapb.Peer{{Id: 3, StoreId: 1}}},
+	}
+	resp, err := client.Bootstrap(context.Background(), req)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError(), IsNil)
+}
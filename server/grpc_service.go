@@ -16,6 +16,7 @@ package server
 import (
 	"fmt"
 	"io"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/juju/errors"
@@ -31,10 +32,16 @@ import (
 var notLeaderError = grpc.Errorf(codes.Unavailable, "not leader")
 
 // GetMembers implements gRPC PDServer.
-func (s *Server) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.GetMembersResponse, error) {
+func (s *Server) GetMembers(ctx context.Context, request *pdpb.GetMembersRequest) (*pdpb.GetMembersResponse, error) {
+	defer s.logSlowRPC(ctx, "GetMembers", request, time.Now())
 	if s.isClosed() {
 		return nil, grpc.Errorf(codes.Unknown, "server not started")
 	}
+	// The clients get the cluster ID by it, so the cluster ID is checked
+	// only if it's set. The followers serve it too.
+	if id := request.GetHeader().GetClusterId(); id != 0 && id != s.clusterID {
+		return nil, s.clusterIDMismatchError(id)
+	}
 	members, err := GetMembers(s.GetClient())
 	if err != nil {
 		return nil, grpc.Errorf(codes.Unknown, err.Error())
@@ -52,37 +59,102 @@ func (s *Server) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.Get
 	}, nil
 }
 
+// maxMergeTsoRequests is the max number of requests on a stream that are
+// served by one timestamp allocation.
+const maxMergeTsoRequests = 16
+
+type tsoRecv struct {
+	request *pdpb.TsoRequest
+	err     error
+}
+
 // Tso implements gRPC PDServer.
 func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
+	if !s.IsLeader() {
+		return s.forwardTso(stream)
+	}
+
+	// Receive in another goroutine, so the requests arrived while the
+	// previous ones are being served can be merged.
+	recvCh := make(chan tsoRecv, maxMergeTsoRequests)
+	go func() {
+		for {
+			request, err := stream.Recv()
+			select {
+			case recvCh <- tsoRecv{request: request, err: err}:
+			case <-stream.Context().Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
 	for {
-		request, err := stream.Recv()
-		if err == io.EOF {
+		recv := <-recvCh
+		requests := make([]*pdpb.TsoRequest, 0, maxMergeTsoRequests)
+		for recv.err == nil {
+			requests = append(requests, recv.request)
+			if len(requests) >= maxMergeTsoRequests || len(recvCh) == 0 {
+				break
+			}
+			recv = <-recvCh
+		}
+
+		if len(requests) > 0 {
+			if err := s.handleTsoRequests(stream, requests); err != nil {
+				return errors.Trace(err)
+			}
+		}
+
+		if recv.err == io.EOF {
 			return nil
 		}
-		if err != nil {
-			return errors.Trace(err)
+		if recv.err != nil {
+			return errors.Trace(recv.err)
 		}
-		if err = s.validateRequest(request.GetHeader()); err != nil {
+	}
+}
+
+// handleTsoRequests allocates the timestamps of all requests at once, each
+// request gets a continuous range and its response carries the last one.
+func (s *Server) handleTsoRequests(stream pdpb.PD_TsoServer, requests []*pdpb.TsoRequest) error {
+	defer s.logSlowRPC(stream.Context(), "Tso", nil, time.Now())
+	var count uint32
+	for _, request := range requests {
+		if err := s.validateRequest(request.GetHeader()); err != nil {
 			return errors.Trace(err)
 		}
-		count := request.GetCount()
-		ts, err := s.getRespTS(count)
-		if err != nil {
-			return grpc.Errorf(codes.Unknown, err.Error())
-		}
+		count += request.GetCount()
+	}
+
+	ts, err := s.getRespTS(count)
+	if err != nil {
+		return grpc.Errorf(codes.Unknown, err.Error())
+	}
+
+	logical := ts.GetLogical() - int64(count)
+	for _, request := range requests {
+		logical += int64(request.GetCount())
 		response := &pdpb.TsoResponse{
-			Header:    s.header(),
-			Timestamp: &ts,
-			Count:     count,
+			Header: s.header(),
+			Timestamp: &pdpb.Timestamp{
+				Physical: ts.GetPhysical(),
+				Logical:  logical,
+			},
+			Count: request.GetCount(),
 		}
 		if err := stream.Send(response); err != nil {
 			return errors.Trace(err)
 		}
 	}
+	return nil
 }
 
 // Bootstrap implements gRPC PDServer.
 func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest) (*pdpb.BootstrapResponse, error) {
+	defer s.logSlowRPC(ctx, "Bootstrap", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -97,8 +169,11 @@ func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest)
 			Header: s.errorHeader(err),
 		}, nil
 	}
-	if _, err := s.bootstrapCluster(request); err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+	_, err := s.bootstrapCluster(request)
+	traceRPC(ctx, "bootstrap cluster")
+	if err != nil {
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.BootstrapResponse{Header: header}, err
 	}
 
 	return &pdpb.BootstrapResponse{
@@ -108,6 +183,7 @@ func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest)
 
 // IsBootstrapped implements gRPC PDServer.
 func (s *Server) IsBootstrapped(ctx context.Context, request *pdpb.IsBootstrappedRequest) (*pdpb.IsBootstrappedResponse, error) {
+	defer s.logSlowRPC(ctx, "IsBootstrapped", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -121,12 +197,14 @@ func (s *Server) IsBootstrapped(ctx context.Context, request *pdpb.IsBootstrappe
 
 // AllocID implements gRPC PDServer.
 func (s *Server) AllocID(ctx context.Context, request *pdpb.AllocIDRequest) (*pdpb.AllocIDResponse, error) {
+	defer s.logSlowRPC(ctx, "AllocID", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
 
 	// We can use an allocator for all types ID allocation.
 	id, err := s.idAlloc.Alloc()
+	traceRPC(ctx, "alloc id %d", id)
 	if err != nil {
 		return nil, grpc.Errorf(codes.Unknown, err.Error())
 	}
@@ -139,6 +217,15 @@ func (s *Server) AllocID(ctx context.Context, request *pdpb.AllocIDRequest) (*pd
 
 // GetStore implements gRPC PDServer.
 func (s *Server) GetStore(ctx context.Context, request *pdpb.GetStoreRequest) (*pdpb.GetStoreResponse, error) {
+	defer s.logSlowRPC(ctx, "GetStore", request, time.Now())
+	forwardCli, forwardCtx, err := s.getForwardClient(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if forwardCli != nil {
+		traceRPC(ctx, "forward to leader")
+		return forwardCli.GetStore(forwardCtx, request)
+	}
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -148,9 +235,12 @@ func (s *Server) GetStore(ctx context.Context, request *pdpb.GetStoreRequest) (*
 		return &pdpb.GetStoreResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 
+	traceRPC(ctx, "get raft cluster")
 	store, _, err := cluster.GetStore(request.GetStoreId())
+	traceRPC(ctx, "get store %d", request.GetStoreId())
 	if err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.GetStoreResponse{Header: header}, err
 	}
 	return &pdpb.GetStoreResponse{
 		Header: s.header(),
@@ -165,10 +255,7 @@ func checkStore2(cluster *RaftCluster, storeID uint64) *pdpb.Error {
 	store, _, err := cluster.GetStore(storeID)
 	if err == nil && store != nil {
 		if store.GetState() == metapb.StoreState_Tombstone {
-			return &pdpb.Error{
-				Type:    pdpb.ErrorType_STORE_TOMBSTONE,
-				Message: "store is tombstone",
-			}
+			return pbError(errStoreTombstone(storeID))
 		}
 	}
 	return nil
@@ -176,6 +263,7 @@ func checkStore2(cluster *RaftCluster, storeID uint64) *pdpb.Error {
 
 // PutStore implements gRPC PDServer.
 func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*pdpb.PutStoreResponse, error) {
+	defer s.logSlowRPC(ctx, "PutStore", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -192,8 +280,12 @@ func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*
 		}, nil
 	}
 
-	if err := cluster.putStore(store); err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+	traceRPC(ctx, "check store %d", store.GetId())
+	err := cluster.putStore(store)
+	traceRPC(ctx, "put store %d", store.GetId())
+	if err != nil {
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.PutStoreResponse{Header: header}, err
 	}
 
 	log.Infof("put store ok - %v", store)
@@ -205,6 +297,7 @@ func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*
 
 // StoreHeartbeat implements gRPC PDServer.
 func (s *Server) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbeatRequest) (*pdpb.StoreHeartbeatResponse, error) {
+	defer s.logSlowRPC(ctx, "StoreHeartbeat", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -217,16 +310,26 @@ func (s *Server) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbea
 		return &pdpb.StoreHeartbeatResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 
+	store := storeLabel(request.GetStats().GetStoreId())
+	storeHeartbeatCounter.WithLabelValues(store, heartbeatReport).Inc()
 	if pberr := checkStore2(cluster, request.GetStats().GetStoreId()); pberr != nil {
+		storeHeartbeatCounter.WithLabelValues(store, heartbeatError).Inc()
 		return &pdpb.StoreHeartbeatResponse{
 			Header: s.errorHeader(pberr),
 		}, nil
 	}
 
+	traceRPC(ctx, "check store %d", request.GetStats().GetStoreId())
+	start := time.Now()
 	err := cluster.cachedCluster.handleStoreHeartbeat(request.Stats)
+	traceRPC(ctx, "handle store heartbeat")
+	storeHeartbeatDuration.WithLabelValues(store).Observe(time.Since(start).Seconds())
 	if err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+		storeHeartbeatCounter.WithLabelValues(store, heartbeatError).Inc()
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.StoreHeartbeatResponse{Header: header}, err
 	}
+	storeHeartbeatCounter.WithLabelValues(store, heartbeatOK).Inc()
 
 	return &pdpb.StoreHeartbeatResponse{
 		Header: s.header(),
@@ -235,14 +338,23 @@ func (s *Server) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbea
 
 // RegionHeartbeat implements gRPC PDServer.
 func (s *Server) RegionHeartbeat(server pdpb.PD_RegionHeartbeatServer) error {
+	stream := newHeartbeatStream(server)
+	defer s.hbStreams.unbindStream(stream)
+	defer stream.close()
+
 	for {
-		request, err := server.Recv()
+		request, err := stream.Recv()
 		if err == io.EOF {
 			return nil
 		}
 		if err != nil {
 			return errors.Trace(err)
 		}
+		// The responses are sent by the region heartbeat workers, close the
+		// stream if any of them fails.
+		if err = stream.getSendError(); err != nil {
+			return errors.Trace(err)
+		}
 
 		if err = s.validateRequest(request.GetHeader()); err != nil {
 			// TODO: How to close this stream?
@@ -252,7 +364,7 @@ func (s *Server) RegionHeartbeat(server pdpb.PD_RegionHeartbeatServer) error {
 		cluster := s.GetRaftCluster()
 		if cluster == nil {
 			msg := "cluster is not bootstrapped"
-			err = sendErrorRegionHeartbeatResponse(server, s.clusterID, pdpb.ErrorType_NOT_BOOTSTRAPPED, msg)
+			err = s.sendErrorRegionHeartbeatResponse(stream, pdpb.ErrorType_NOT_BOOTSTRAPPED, msg)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -263,9 +375,12 @@ func (s *Server) RegionHeartbeat(server pdpb.PD_RegionHeartbeatServer) error {
 		region.DownPeers = request.GetDownPeers()
 		region.PendingPeers = request.GetPendingPeers()
 		region.WrittenBytes = request.GetBytesWritten()
+		region.ReadBytes = request.GetBytesRead()
+		region.WrittenKeys = request.GetKeysWritten()
+		region.ReadKeys = request.GetKeysRead()
 		if region.GetId() == 0 {
 			msg := fmt.Sprintf("invalid request region, %v", request)
-			err = sendErrorRegionHeartbeatResponse(server, s.clusterID, pdpb.ErrorType_UNKNOWN, msg)
+			err = s.sendErrorRegionHeartbeatResponse(stream, pdpb.ErrorType_UNKNOWN, msg)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -273,50 +388,38 @@ func (s *Server) RegionHeartbeat(server pdpb.PD_RegionHeartbeatServer) error {
 		}
 		if region.Leader == nil {
 			msg := fmt.Sprintf("invalid request leader, %v", request)
-			err = sendErrorRegionHeartbeatResponse(server, s.clusterID, pdpb.ErrorType_UNKNOWN, msg)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			continue
-		}
-
-		err = cluster.cachedCluster.handleRegionHeartbeat(region)
-		if err != nil {
-			msg := errors.Trace(err).Error()
-			err = sendErrorRegionHeartbeatResponse(server, s.clusterID, pdpb.ErrorType_UNKNOWN, msg)
+			err = s.sendErrorRegionHeartbeatResponse(stream, pdpb.ErrorType_UNKNOWN, msg)
 			if err != nil {
 				return errors.Trace(err)
 			}
 			continue
 		}
 
-		var resp *pdpb.RegionHeartbeatResponse
-		resp, err = cluster.handleRegionHeartbeat(region)
-		if err != nil {
-			msg := errors.Trace(err).Error()
-			err = sendErrorRegionHeartbeatResponse(server, s.clusterID, pdpb.ErrorType_UNKNOWN, msg)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			continue
-		}
-		if resp == nil {
-			continue
-		}
+		storeID := region.Leader.GetStoreId()
+		store := storeLabel(storeID)
+		s.hbStreams.bindStream(storeID, stream)
+		regionHeartbeatCounter.WithLabelValues(store, heartbeatReport).Inc()
 
-		resp.Header = s.header()
-		resp.RegionId = request.Region.Id
-		resp.RegionEpoch = request.Region.RegionEpoch
-		resp.TargetPeer = request.Leader
-
-		if err := server.Send(resp); err != nil {
-			return errors.Trace(err)
-		}
+		cluster.dispatchRegionHeartbeat(&regionHeartbeatTask{
+			stream:  stream,
+			request: request,
+			region:  region,
+			start:   time.Now(),
+		})
 	}
 }
 
 // GetRegion implements gRPC PDServer.
 func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest) (*pdpb.GetRegionResponse, error) {
+	defer s.logSlowRPC(ctx, "GetRegion", request, time.Now())
+	forwardCli, forwardCtx, err := s.getForwardClient(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if forwardCli != nil {
+		traceRPC(ctx, "forward to leader")
+		return forwardCli.GetRegion(forwardCtx, request)
+	}
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -325,7 +428,9 @@ func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest)
 	if cluster == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
+	traceRPC(ctx, "get raft cluster")
 	region, leader := cluster.GetRegionByKey(request.GetRegionKey())
+	traceRPC(ctx, "get region %d", region.GetId())
 	return &pdpb.GetRegionResponse{
 		Header: s.header(),
 		Region: region,
@@ -335,6 +440,15 @@ func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest)
 
 // GetRegionByID implements gRPC PDServer.
 func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDRequest) (*pdpb.GetRegionResponse, error) {
+	defer s.logSlowRPC(ctx, "GetRegionByID", request, time.Now())
+	forwardCli, forwardCtx, err := s.getForwardClient(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if forwardCli != nil {
+		traceRPC(ctx, "forward to leader")
+		return forwardCli.GetRegionByID(forwardCtx, request)
+	}
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -343,8 +457,10 @@ func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDR
 	if cluster == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
+	traceRPC(ctx, "get raft cluster")
 	id := request.GetRegionId()
 	region, leader := cluster.GetRegionByID(id)
+	traceRPC(ctx, "get region %d", id)
 	return &pdpb.GetRegionResponse{
 		Header: s.header(),
 		Region: region,
@@ -354,6 +470,7 @@ func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDR
 
 // AskSplit implements gRPC PDServer.
 func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*pdpb.AskSplitResponse, error) {
+	defer s.logSlowRPC(ctx, "AskSplit", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -369,8 +486,10 @@ func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*
 		Region: request.Region,
 	}
 	split, err := cluster.handleAskSplit(req)
+	traceRPC(ctx, "handle ask split")
 	if err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.AskSplitResponse{Header: header}, err
 	}
 
 	return &pdpb.AskSplitResponse{
@@ -382,6 +501,7 @@ func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*
 
 // ReportSplit implements gRPC PDServer.
 func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitRequest) (*pdpb.ReportSplitResponse, error) {
+	defer s.logSlowRPC(ctx, "ReportSplit", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -391,8 +511,10 @@ func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitReque
 		return &pdpb.ReportSplitResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 	_, err := cluster.handleReportSplit(request)
+	traceRPC(ctx, "handle report split")
 	if err != nil {
-		return nil, grpc.Errorf(codes.Unknown, err.Error())
+		header, err := s.typedErrorHeader(err)
+		return &pdpb.ReportSplitResponse{Header: header}, err
 	}
 
 	return &pdpb.ReportSplitResponse{
@@ -402,6 +524,7 @@ func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitReque
 
 // GetClusterConfig implements gRPC PDServer.
 func (s *Server) GetClusterConfig(ctx context.Context, request *pdpb.GetClusterConfigRequest) (*pdpb.GetClusterConfigResponse, error) {
+	defer s.logSlowRPC(ctx, "GetClusterConfig", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -418,6 +541,7 @@ func (s *Server) GetClusterConfig(ctx context.Context, request *pdpb.GetClusterC
 
 // PutClusterConfig implements gRPC PDServer.
 func (s *Server) PutClusterConfig(ctx context.Context, request *pdpb.PutClusterConfigRequest) (*pdpb.PutClusterConfigResponse, error) {
+	defer s.logSlowRPC(ctx, "PutClusterConfig", request, time.Now())
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -427,7 +551,9 @@ func (s *Server) PutClusterConfig(ctx context.Context, request *pdpb.PutClusterC
 		return &pdpb.PutClusterConfigResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 	conf := request.GetCluster()
-	if err := cluster.putConfig(conf); err != nil {
+	err := cluster.putConfig(conf)
+	traceRPC(ctx, "put cluster config")
+	if err != nil {
 		return nil, grpc.Errorf(codes.Unknown, err.Error())
 	}
 
@@ -445,11 +571,15 @@ func (s *Server) validateRequest(header *pdpb.RequestHeader) error {
 		return notLeaderError
 	}
 	if header.GetClusterId() != s.clusterID {
-		return grpc.Errorf(codes.FailedPrecondition, "mismatch cluster id, need %d but got %d", s.clusterID, header.GetClusterId())
+		return s.clusterIDMismatchError(header.GetClusterId())
 	}
 	return nil
 }
 
+func (s *Server) clusterIDMismatchError(id uint64) error {
+	return grpc.Errorf(codes.FailedPrecondition, "mismatch cluster id, need %d but got %d", s.clusterID, id)
+}
+
 func (s *Server) header() *pdpb.ResponseHeader {
 	return &pdpb.ResponseHeader{ClusterId: s.clusterID}
 }
@@ -462,23 +592,24 @@ func (s *Server) errorHeader(err *pdpb.Error) *pdpb.ResponseHeader {
 }
 
 func (s *Server) notBootstrappedHeader() *pdpb.ResponseHeader {
-	return s.errorHeader(&pdpb.Error{
-		Type:    pdpb.ErrorType_NOT_BOOTSTRAPPED,
-		Message: "cluster is not bootstrapped",
-	})
+	return s.errorHeader(pbError(ErrNotBootstrapped))
+}
+
+// typedErrorHeader returns the header of the error if it's typed, the error
+// is returned as a gRPC error otherwise.
+func (s *Server) typedErrorHeader(err error) (*pdpb.ResponseHeader, error) {
+	if pberr := pbError(err); pberr != nil {
+		return s.errorHeader(pberr), nil
+	}
+	return nil, grpc.Errorf(codes.Unknown, err.Error())
 }
 
-func sendErrorRegionHeartbeatResponse(server pdpb.PD_RegionHeartbeatServer, clusterID uint64, ty pdpb.ErrorType, msg string) error {
-	pberr := &pdpb.Error{
+func (s *Server) sendErrorRegionHeartbeatResponse(stream *heartbeatStream, ty pdpb.ErrorType, msg string) error {
+	resp := s.newRegionHeartbeatResponse(&pdpb.Error{
 		Type:    ty,
 		Message: msg,
-	}
-	resp := &pdpb.RegionHeartbeatResponse{}
-	resp.Header = &pdpb.ResponseHeader{
-		ClusterId: clusterID,
-		Error:     pberr,
-	}
-	if err := server.Send(resp); err != nil {
+	})
+	if err := stream.Send(resp); err != nil {
 		return errors.Trace(err)
 	}
 	return nil
@@ -13,10 +13,16 @@
 
 package server
 
-import "github.com/juju/errors"
+import (
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
 
 var (
-	errNotBootstrapped  = errors.New("TiKV cluster not bootstrapped, please start TiKV first")
+	errNotBootstrapped  = newError(pdpb.ErrorType_NOT_BOOTSTRAPPED, "TiKV cluster not bootstrapped, please start TiKV first")
 	errOperatorNotFound = errors.New("operator not found")
 )
 
@@ -47,6 +53,34 @@ func (h *Handler) GetSchedulers() ([]string, error) {
 	return c.getSchedulers(), nil
 }
 
+// GetPausedSchedulers returns the names of the paused schedulers.
+func (h *Handler) GetPausedSchedulers() ([]string, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getPausedSchedulers(), nil
+}
+
+// PauseScheduler pauses the scheduler until it is resumed or the leader
+// changes.
+func (h *Handler) PauseScheduler(name string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.pauseOrResumeScheduler(name, true))
+}
+
+// ResumeScheduler resumes the paused scheduler.
+func (h *Handler) ResumeScheduler(name string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.pauseOrResumeScheduler(name, false))
+}
+
 // GetHotWriteRegions gets all hot regions status
 func (h *Handler) GetHotWriteRegions() *StoreHotRegionInfos {
 	c, err := h.getCoordinator()
@@ -56,12 +90,68 @@ func (h *Handler) GetHotWriteRegions() *StoreHotRegionInfos {
 	return c.getHotWriteRegions()
 }
 
+// GetHotReadRegions gets all read hot regions status
+func (h *Handler) GetHotReadRegions() *StoreHotRegionInfos {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil
+	}
+	return c.getHotReadRegions()
+}
+
+// GetRegionFlow gets the recent flow of a region.
+func (h *Handler) GetRegionFlow(regionID uint64) (*RegionFlow, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(errNotBootstrapped)
+	}
+	flow := cluster.cachedCluster.getRegionFlow(regionID)
+	if flow == nil {
+		return nil, errors.Errorf("flow of region %d not found", regionID)
+	}
+	return flow, nil
+}
+
+// GetHeatmap gets the write or read flow heatmap of the key space.
+func (h *Handler) GetHeatmap(kind string) (*Heatmap, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(errNotBootstrapped)
+	}
+	switch kind {
+	case "write":
+		return cluster.heatmap.getHeatmap(hotWriteFlow, heatmapMaxRows), nil
+	case "read":
+		return cluster.heatmap.getHeatmap(hotReadFlow, heatmapMaxRows), nil
+	}
+	return nil, errors.Errorf("unknown heatmap type %q", kind)
+}
+
+// GetStatsTargets returns the names of the stats series.
+func (h *Handler) GetStatsTargets() ([]string, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(errNotBootstrapped)
+	}
+	return cluster.stats.targets(), nil
+}
+
+// GetStatsSeries returns the stats series of the targets in the time range
+// [from, to], the recent 2 hours are kept.
+func (h *Handler) GetStatsSeries(targets []string, from, to time.Time) ([]*StatsSeries, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(errNotBootstrapped)
+	}
+	return cluster.stats.query(targets, from, to), nil
+}
+
 // GetHotWriteStores gets all hot write stores status
 func (h *Handler) GetHotWriteStores() map[uint64]uint64 {
 	return h.s.cluster.cachedCluster.getStoresWriteStat()
 }
 
-// AddScheduler adds a scheduler.
+// AddScheduler adds a scheduler, it is not persisted.
 func (h *Handler) AddScheduler(s Scheduler) error {
 	c, err := h.getCoordinator()
 	if err != nil {
@@ -76,32 +166,58 @@ func (h *Handler) RemoveScheduler(name string) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return errors.Trace(c.removeScheduler(name))
+	if err := c.removeScheduler(name); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(h.s.kv.deleteScheduler(name))
+}
+
+// AddSchedulerByType creates a scheduler of the registered type with the
+// arguments, adds it and persists its config, so it is restored after PD
+// restarts or the leader changes.
+func (h *Handler) AddSchedulerByType(typ string, args ...string) error {
+	cfg := &schedulerConfig{Type: typ, Args: args}
+	s, err := newSchedulerFromConfig(h.opt, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := h.AddScheduler(s); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(h.s.kv.saveScheduler(s.GetName(), cfg))
 }
 
 // AddBalanceLeaderScheduler adds a balance-leader-scheduler.
 func (h *Handler) AddBalanceLeaderScheduler() error {
-	return h.AddScheduler(newBalanceLeaderScheduler(h.opt))
+	return h.AddSchedulerByType("balance-leader-scheduler")
 }
 
 // AddGrantLeaderScheduler adds a grant-leader-scheduler.
 func (h *Handler) AddGrantLeaderScheduler(storeID uint64) error {
-	return h.AddScheduler(newGrantLeaderScheduler(h.opt, storeID))
+	return h.AddSchedulerByType(grantLeaderSchedulerType, strconv.FormatUint(storeID, 10))
 }
 
 // AddEvictLeaderScheduler adds an evict-leader-scheduler.
 func (h *Handler) AddEvictLeaderScheduler(storeID uint64) error {
-	return h.AddScheduler(newEvictLeaderScheduler(h.opt, storeID))
+	return h.AddSchedulerByType(evictLeaderSchedulerType, strconv.FormatUint(storeID, 10))
+}
+
+// AddScatterRangeScheduler adds a scatter-range-scheduler for the
+// hex encoded key range.
+func (h *Handler) AddScatterRangeScheduler(rangeName, startKey, endKey string) error {
+	return h.AddSchedulerByType(scatterRangeSchedulerType, rangeName, startKey, endKey)
 }
 
-// AddShuffleLeaderScheduler adds a shuffle-leader-scheduler.
-func (h *Handler) AddShuffleLeaderScheduler() error {
-	return h.AddScheduler(newShuffleLeaderScheduler(h.opt))
+// AddShuffleLeaderScheduler adds a shuffle-leader-scheduler, the limit is the
+// max coexist shuffle operators, 0 means the leader schedule limit.
+func (h *Handler) AddShuffleLeaderScheduler(limit uint64) error {
+	return h.AddSchedulerByType("shuffle-leader-scheduler", strconv.FormatUint(limit, 10))
 }
 
-// AddShuffleRegionScheduler adds a shuffle-region-scheduler.
-func (h *Handler) AddShuffleRegionScheduler() error {
-	return h.AddScheduler(newShuffleRegionScheduler(h.opt))
+// AddShuffleRegionScheduler adds a shuffle-region-scheduler, the limit is the
+// max coexist shuffle operators, 0 means the region schedule limit.
+func (h *Handler) AddShuffleRegionScheduler(limit uint64) error {
+	return h.AddSchedulerByType("shuffle-region-scheduler", strconv.FormatUint(limit, 10))
 }
 
 // GetOperator returns the region operator.
@@ -131,6 +247,7 @@ func (h *Handler) RemoveOperator(regionID uint64) error {
 		return errOperatorNotFound
 	}
 
+	op.SetState(OperatorCanceled)
 	c.removeOperator(op)
 	return nil
 }
@@ -159,6 +276,11 @@ func (h *Handler) GetRegionOperators() ([]Operator, error) {
 	return h.GetOperatorsOfKind(RegionKind)
 }
 
+// GetReplicaOperators returns the running replica operators.
+func (h *Handler) GetReplicaOperators() ([]Operator, error) {
+	return h.GetOperatorsOfKind(ReplicaKind)
+}
+
 // GetOperatorsOfKind returns the running operators of the kind.
 func (h *Handler) GetOperatorsOfKind(kind ResourceKind) ([]Operator, error) {
 	ops, err := h.GetOperators()
@@ -183,6 +305,26 @@ func (h *Handler) GetHistoryOperators() ([]Operator, error) {
 	return c.getHistories(), nil
 }
 
+// GetOperatorRecords returns the records of the operators finished or canceled
+// since the start time.
+func (h *Handler) GetOperatorRecords(start time.Time) ([]*OperatorRecord, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getOperatorRecords(start), nil
+}
+
+// GetDryRunOperators returns the operators created by the schedulers in dry-run
+// mode.
+func (h *Handler) GetDryRunOperators() ([]*DryRunOperator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getDryRunOperators(), nil
+}
+
 // GetHistoryOperatorsOfKind returns history operators by Kind
 func (h *Handler) GetHistoryOperatorsOfKind(kind ResourceKind) ([]Operator, error) {
 	c, err := h.getCoordinator()
@@ -192,6 +334,15 @@ func (h *Handler) GetHistoryOperatorsOfKind(kind ResourceKind) ([]Operator, erro
 	return c.getHistoriesOfKind(kind), nil
 }
 
+// GetSplitHistories returns the recent splits in the key range [startKey, endKey).
+func (h *Handler) GetSplitHistories(startKey, endKey []byte) ([]Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getSplitHistories(startKey, endKey), nil
+}
+
 // AddTransferLeaderOperator adds an operator to transfer leader to the store.
 func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) error {
 	c, err := h.getCoordinator()
@@ -199,7 +350,7 @@ func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) err
 		return errors.Trace(err)
 	}
 
-	region := c.cluster.getRegion(regionID)
+	region := c.cluster.GetRegion(regionID)
 	if region == nil {
 		return errRegionNotFound(regionID)
 	}
@@ -220,7 +371,7 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 		return errors.Trace(err)
 	}
 
-	region := c.cluster.getRegion(regionID)
+	region := c.cluster.GetRegion(regionID)
 	if region == nil {
 		return errRegionNotFound(regionID)
 	}
@@ -229,13 +380,13 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 
 	// Add missing peers.
 	for id := range storeIDs {
-		if c.cluster.getStore(id) == nil {
+		if c.cluster.GetStore(id) == nil {
 			return errStoreNotFound(id)
 		}
 		if region.GetStorePeer(id) != nil {
 			continue
 		}
-		peer, err := c.cluster.allocPeer(id)
+		peer, err := c.cluster.AllocPeer(id)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -261,7 +412,7 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 		return errors.Trace(err)
 	}
 
-	region := c.cluster.getRegion(regionID)
+	region := c.cluster.GetRegion(regionID)
 	if region == nil {
 		return errRegionNotFound(regionID)
 	}
@@ -271,10 +422,10 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 		return errors.Errorf("region has no peer in store %v", fromStoreID)
 	}
 
-	if c.cluster.getStore(toStoreID) == nil {
+	if c.cluster.GetStore(toStoreID) == nil {
 		return errStoreNotFound(toStoreID)
 	}
-	newPeer, err := c.cluster.allocPeer(toStoreID)
+	newPeer, err := c.cluster.AllocPeer(toStoreID)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -284,3 +435,54 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 	c.addOperator(newAdminOperator(region, addPeer, removePeer))
 	return nil
 }
+
+// AddAddPeerOperator adds an operator to add a peer of the region on the store.
+func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	region := c.cluster.GetRegion(regionID)
+	if region == nil {
+		return errRegionNotFound(regionID)
+	}
+	if region.GetStorePeer(toStoreID) != nil {
+		return errors.Errorf("region already has peer in store %v", toStoreID)
+	}
+	if c.cluster.GetStore(toStoreID) == nil {
+		return errStoreNotFound(toStoreID)
+	}
+	newPeer, err := c.cluster.AllocPeer(toStoreID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	c.addOperator(newAdminOperator(region, newAddPeerOperator(regionID, newPeer)))
+	return nil
+}
+
+// AddRemovePeerOperator adds an operator to remove the peer of the region on
+// the store, the leadership is transferred first if the peer is leader.
+func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	region := c.cluster.GetRegion(regionID)
+	if region == nil {
+		return errRegionNotFound(regionID)
+	}
+	oldPeer := region.GetStorePeer(fromStoreID)
+	if oldPeer == nil {
+		return errors.Errorf("region has no peer in store %v", fromStoreID)
+	}
+	ops := removePeerOps(region, oldPeer)
+	if ops == nil {
+		return errors.New("no follower to transfer leader to")
+	}
+
+	c.addOperator(newAdminOperator(region, ops...))
+	return nil
+}
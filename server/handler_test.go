@@ -0,0 +1,58 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHandlerSuite{})
+
+type testHandlerSuite struct {
+	testClusterBaseSuite
+}
+
+func (s *testHandlerSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = newTestServer(c)
+	s.client = s.svr.client
+	go s.svr.Run()
+	mustWaitLeader(c, []*Server{s.svr})
+	s.grpcPDClient = mustNewGrpcClient(c, s.svr.GetAddr())
+	s.bootstrapCluster(c, s.svr.clusterID, "127.0.0.1:0")
+}
+
+func (s *testHandlerSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testHandlerSuite) TestAddOperators(c *C) {
+	cluster := s.svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	h := s.svr.GetHandler()
+	store := s.newStore(c, 0, "127.0.0.1:1")
+	c.Assert(cluster.putStore(store), IsNil)
+
+	region := cluster.GetRegions()[0]
+	leader := region.GetPeers()[0]
+	c.Assert(cluster.cachedCluster.handleRegionHeartbeat(newRegionInfo(region, leader)), IsNil)
+	regionID := region.GetId()
+
+	c.Assert(h.AddAddPeerOperator(regionID, leader.GetStoreId()), NotNil)
+	c.Assert(h.AddAddPeerOperator(regionID, store.GetId()), IsNil)
+	c.Assert(h.RemoveOperator(regionID), IsNil)
+
+	c.Assert(h.AddRemovePeerOperator(regionID, store.GetId()), NotNil)
+	// The leader can't be removed without followers.
+	c.Assert(h.AddRemovePeerOperator(regionID, leader.GetStoreId()), NotNil)
+}
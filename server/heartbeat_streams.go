@@ -0,0 +1,159 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// heartbeatStream wraps a region heartbeat stream so that it can be sent to
+// concurrently.
+type heartbeatStream struct {
+	pdpb.PD_RegionHeartbeatServer
+	sendLock sync.Mutex
+	// storeID is the store the stream is bound to last, it is protected by
+	// the lock of heartbeatStreams.
+	storeID uint64
+	// sendErr keeps the first error of sending the responses by the region
+	// heartbeat workers, the stream is closed once it's received.
+	sendErr chan error
+	// closed is set once the RegionHeartbeat handler exits, it is protected
+	// by sendLock.
+	closed bool
+}
+
+func newHeartbeatStream(stream pdpb.PD_RegionHeartbeatServer) *heartbeatStream {
+	return &heartbeatStream{
+		PD_RegionHeartbeatServer: stream,
+		sendErr:                  make(chan error, 1),
+	}
+}
+
+// setSendError records the error of sending a response, only the first one
+// is kept.
+func (s *heartbeatStream) setSendError(err error) {
+	select {
+	case s.sendErr <- err:
+	default:
+	}
+}
+
+// getSendError returns the error of sending a response, nil if there is none.
+func (s *heartbeatStream) getSendError() error {
+	select {
+	case err := <-s.sendErr:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Send sends a response to the stream. The response is dropped if the stream
+// is closed, the workers may still respond to the heartbeats queued before.
+func (s *heartbeatStream) Send(resp *pdpb.RegionHeartbeatResponse) error {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.PD_RegionHeartbeatServer.Send(resp)
+}
+
+// close marks the stream closed, it is called when the RegionHeartbeat
+// handler exits since gRPC doesn't allow sending to the stream after that.
+func (s *heartbeatStream) close() {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+	s.closed = true
+}
+
+// heartbeatStreams keeps the region heartbeat stream of each store, so PD
+// can push messages to a store besides responding to its heartbeats.
+type heartbeatStreams struct {
+	sync.RWMutex
+	streams map[uint64]*heartbeatStream
+}
+
+func newHeartbeatStreams() *heartbeatStreams {
+	return &heartbeatStreams{
+		streams: make(map[uint64]*heartbeatStream),
+	}
+}
+
+// bindStream binds the stream to the store, the previous stream of the
+// store is replaced.
+func (s *heartbeatStreams) bindStream(storeID uint64, stream *heartbeatStream) {
+	s.Lock()
+	defer s.Unlock()
+	if stream.storeID != storeID {
+		if stream.storeID != 0 {
+			regionHeartbeatStreamGauge.WithLabelValues(storeLabel(stream.storeID)).Dec()
+		}
+		regionHeartbeatStreamGauge.WithLabelValues(storeLabel(storeID)).Inc()
+		stream.storeID = storeID
+	}
+	s.streams[storeID] = stream
+}
+
+// unbindStream removes the stream from all stores bound to it, it is called
+// once the stream is closed.
+func (s *heartbeatStreams) unbindStream(stream *heartbeatStream) {
+	s.Lock()
+	defer s.Unlock()
+	if stream.storeID != 0 {
+		regionHeartbeatStreamGauge.WithLabelValues(storeLabel(stream.storeID)).Dec()
+		stream.storeID = 0
+	}
+	for storeID, st := range s.streams {
+		if st == stream {
+			delete(s.streams, storeID)
+		}
+	}
+}
+
+func (s *heartbeatStreams) getStream(storeID uint64) *heartbeatStream {
+	s.RLock()
+	defer s.RUnlock()
+	return s.streams[storeID]
+}
+
+// sendMsg sends the message to the stream of the store.
+func (s *heartbeatStreams) sendMsg(storeID uint64, msg *pdpb.RegionHeartbeatResponse) error {
+	stream := s.getStream(storeID)
+	if stream == nil {
+		return errors.Errorf("heartbeat stream of store %d not found", storeID)
+	}
+	return errors.Trace(stream.Send(msg))
+}
+
+func (s *Server) newRegionHeartbeatResponse(pberr *pdpb.Error) *pdpb.RegionHeartbeatResponse {
+	return &pdpb.RegionHeartbeatResponse{
+		Header: &pdpb.ResponseHeader{
+			ClusterId: s.clusterID,
+			Error:     pberr,
+		},
+	}
+}
+
+// sendStoreError pushes an error to the region heartbeat stream of the store.
+func (s *Server) sendStoreError(storeID uint64, ty pdpb.ErrorType, msg string) error {
+	resp := s.newRegionHeartbeatResponse(&pdpb.Error{
+		Type:    ty,
+		Message: msg,
+	})
+	return errors.Trace(s.hbStreams.sendMsg(storeID, resp))
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var _ = Suite(&testHeartbeatStreamsSuite{})
+
+type testHeartbeatStreamsSuite struct{}
+
+func getStreamGauge(c *C, storeID uint64) float64 {
+	var m dto.Metric
+	c.Assert(regionHeartbeatStreamGauge.WithLabelValues(storeLabel(storeID)).Write(&m), IsNil)
+	return m.GetGauge().GetValue()
+}
+
+func (s *testHeartbeatStreamsSuite) TestStreamMetrics(c *C) {
+	hbStreams := newHeartbeatStreams()
+	stream1, stream2 := newHeartbeatStream(nil), newHeartbeatStream(nil)
+	base := getStreamGauge(c, 1)
+
+	hbStreams.bindStream(1, stream1)
+	hbStreams.bindStream(1, stream1)
+	c.Assert(getStreamGauge(c, 1), Equals, base+1)
+	// The replaced stream is counted until it is closed.
+	hbStreams.bindStream(1, stream2)
+	c.Assert(getStreamGauge(c, 1), Equals, base+2)
+	c.Assert(hbStreams.getStream(1), Equals, stream2)
+
+	hbStreams.unbindStream(stream1)
+	c.Assert(getStreamGauge(c, 1), Equals, base+1)
+	c.Assert(hbStreams.getStream(1), Equals, stream2)
+	hbStreams.unbindStream(stream2)
+	c.Assert(getStreamGauge(c, 1), Equals, base)
+	c.Assert(hbStreams.getStream(1), IsNil)
+}
+
+func (s *testHeartbeatStreamsSuite) TestSendError(c *C) {
+	stream := newHeartbeatStream(nil)
+	c.Assert(stream.getSendError(), IsNil)
+
+	// Only the first error is kept.
+	stream.setSendError(errors.New("error 1"))
+	stream.setSendError(errors.New("error 2"))
+	c.Assert(stream.getSendError(), ErrorMatches, "error 1")
+	c.Assert(stream.getSendError(), IsNil)
+}
+
+type mockHeartbeatServer struct {
+	pdpb.PD_RegionHeartbeatServer
+	sent int
+}
+
+func (s *mockHeartbeatServer) Send(resp *pdpb.RegionHeartbeatResponse) error {
+	s.sent++
+	return nil
+}
+
+func (s *testHeartbeatStreamsSuite) TestSendClosed(c *C) {
+	server := &mockHeartbeatServer{}
+	stream := newHeartbeatStream(server)
+	c.Assert(stream.Send(&pdpb.RegionHeartbeatResponse{}), IsNil)
+	c.Assert(server.sent, Equals, 1)
+
+	// The responses to a closed stream are dropped.
+	stream.close()
+	c.Assert(stream.Send(&pdpb.RegionHeartbeatResponse{}), IsNil)
+	c.Assert(server.sent, Equals, 1)
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+const (
+	// The region heartbeats are sharded by the region IDs to the workers, the
+	// queue of a worker blocks the streams once it's full.
+	regionHeartbeatWorkers   = 16
+	regionHeartbeatQueueSize = 256
+)
+
+// regionHeartbeatTask is a region heartbeat received from a stream.
+type regionHeartbeatTask struct {
+	stream  *heartbeatStream
+	request *pdpb.RegionHeartbeatRequest
+	region  *RegionInfo
+	start   time.Time
+}
+
+// dispatchRegionHeartbeat queues the heartbeat to the worker of its region,
+// so the heartbeats of a region are handled in order, while the heartbeats of
+// different regions are handled concurrently. The heartbeat is dropped if the
+// cluster is stopped.
+func (c *RaftCluster) dispatchRegionHeartbeat(task *regionHeartbeatTask) {
+	queue := c.hbQueues[task.region.GetId()%uint64(len(c.hbQueues))]
+	select {
+	case queue <- task:
+	case <-c.quit:
+	}
+}
+
+func (c *RaftCluster) runRegionHeartbeatWorker(queue <-chan *regionHeartbeatTask) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.quit:
+			return
+		case task := <-queue:
+			if err := c.s.handleRegionHeartbeatTask(c, task); err != nil {
+				log.Warnf("[region %d] send region heartbeat response meet error: %v", task.region.GetId(), err)
+				task.stream.setSendError(err)
+			}
+		}
+	}
+}
+
+// handleRegionHeartbeatTask handles the heartbeat and responds to its stream,
+// it returns the error of sending the response.
+func (s *Server) handleRegionHeartbeatTask(cluster *RaftCluster, task *regionHeartbeatTask) error {
+	region, stream := task.region, task.stream
+	storeID := region.Leader.GetStoreId()
+	store := storeLabel(storeID)
+
+	if err := cluster.cachedCluster.handleRegionHeartbeat(region); err != nil {
+		regionHeartbeatCounter.WithLabelValues(store, heartbeatError).Inc()
+		return s.sendErrorRegionHeartbeatResponse(stream, pdpb.ErrorType_UNKNOWN, errors.Trace(err).Error())
+	}
+
+	resp, err := cluster.handleRegionHeartbeat(region)
+	if err != nil {
+		regionHeartbeatCounter.WithLabelValues(store, heartbeatError).Inc()
+		return s.sendErrorRegionHeartbeatResponse(stream, pdpb.ErrorType_UNKNOWN, errors.Trace(err).Error())
+	}
+	regionHeartbeatCounter.WithLabelValues(store, heartbeatOK).Inc()
+	cost := time.Since(task.start)
+	regionHeartbeatDuration.WithLabelValues(store).Observe(cost.Seconds())
+	s.logSlowRPC(stream.Context(), "RegionHeartbeat", task.request, task.start)
+	if resp == nil {
+		return nil
+	}
+
+	resp.Header = s.header()
+	resp.RegionId = task.request.Region.Id
+	resp.RegionEpoch = task.request.Region.RegionEpoch
+	resp.TargetPeer = task.request.Leader
+	return errors.Trace(stream.Send(resp))
+}
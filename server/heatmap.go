@@ -0,0 +1,211 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// heatmapInterval is the time span of a heatmap column.
+	heatmapInterval = time.Minute
+	// heatmapMaxColumns is the number of recent columns kept, 2 hours by default.
+	heatmapMaxColumns = 120
+	// heatmapMaxRows is the max number of key ranges a heatmap is split into.
+	heatmapMaxRows = 256
+	// heatmapMaxColumnRanges is the max number of key ranges kept in a
+	// column, the adjacent regions are aggregated beyond it, so the memory
+	// doesn't grow with the number of regions.
+	heatmapMaxColumnRanges = 4 * heatmapMaxRows
+)
+
+// Heatmap is the flow of the key space over time. Values[i][j] is the flow in
+// bytes per second of the key range [Keys[j], Keys[j+1]) at Times[i]. The
+// keys are hex encoded, the last key is empty for the end of the key space.
+type Heatmap struct {
+	Times  []time.Time `json:"times"`
+	Keys   []string    `json:"keys"`
+	Values [][]float64 `json:"values"`
+}
+
+// keyRangeFlow is the flow of a region at the time of a heatmap column.
+type keyRangeFlow struct {
+	startKey     []byte
+	endKey       []byte
+	bytesWritten float64
+	bytesRead    float64
+}
+
+func (f *keyRangeFlow) getFlow(kind hotFlowKind) float64 {
+	if kind == hotReadFlow {
+		return f.bytesRead
+	}
+	return f.bytesWritten
+}
+
+// heatmapColumn is a snapshot of the flows of all regions, sorted by the
+// start keys.
+type heatmapColumn struct {
+	time  time.Time
+	flows []*keyRangeFlow
+}
+
+type keyRangeFlowsByKey []*keyRangeFlow
+
+func (f keyRangeFlowsByKey) Len() int      { return len(f) }
+func (f keyRangeFlowsByKey) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f keyRangeFlowsByKey) Less(i, j int) bool {
+	return bytes.Compare(f[i].startKey, f[j].startKey) < 0
+}
+
+// newHeatmapColumn takes a snapshot of the recent flows of the regions.
+func (c *clusterInfo) newHeatmapColumn(now time.Time) *heatmapColumn {
+	column := &heatmapColumn{time: now}
+	for _, region := range c.snapshotRegions() {
+		f := &keyRangeFlow{
+			startKey: region.GetStartKey(),
+			endKey:   region.GetEndKey(),
+		}
+		if flow := c.flows.get(region.GetId()); flow != nil {
+			f.bytesWritten = flow.BytesWritten
+			f.bytesRead = flow.BytesRead
+		}
+		column.flows = append(column.flows, f)
+	}
+	sort.Sort(keyRangeFlowsByKey(column.flows))
+	column.flows = mergeKeyRangeFlows(column.flows, heatmapMaxColumnRanges)
+	return column
+}
+
+// mergeKeyRangeFlows merges the adjacent sorted flows into at most maxRanges
+// key ranges, the flow of a merged range is the sum of the flows.
+func mergeKeyRangeFlows(flows []*keyRangeFlow, maxRanges int) []*keyRangeFlow {
+	if len(flows) <= maxRanges {
+		return flows
+	}
+	step := (len(flows) + maxRanges - 1) / maxRanges
+	merged := make([]*keyRangeFlow, 0, maxRanges)
+	for i := 0; i < len(flows); i += step {
+		end := i + step
+		if end > len(flows) {
+			end = len(flows)
+		}
+		f := &keyRangeFlow{startKey: flows[i].startKey}
+		for _, o := range flows[i:end] {
+			f.endKey = o.endKey
+			f.bytesWritten += o.bytesWritten
+			f.bytesRead += o.bytesRead
+		}
+		merged = append(merged, f)
+	}
+	return merged
+}
+
+// heatmapColumns keeps the recent heatmap columns, the oldest ones are
+// dropped once there are more than maxColumns.
+type heatmapColumns struct {
+	sync.RWMutex
+	columns    []*heatmapColumn
+	maxColumns int
+}
+
+func newHeatmapColumns(maxColumns int) *heatmapColumns {
+	return &heatmapColumns{
+		maxColumns: maxColumns,
+	}
+}
+
+func (h *heatmapColumns) add(column *heatmapColumn) {
+	h.Lock()
+	defer h.Unlock()
+	h.columns = append(h.columns, column)
+	if len(h.columns) > h.maxColumns {
+		h.columns = append([]*heatmapColumn(nil), h.columns[len(h.columns)-h.maxColumns:]...)
+	}
+}
+
+// getHeatmap builds the heatmap of the flow kind. The rows are split by the
+// start keys of the regions in all columns, if there are more than maxRows,
+// adjacent rows are merged. The flow of a region is shared evenly by the rows
+// it covers.
+func (h *heatmapColumns) getHeatmap(kind hotFlowKind, maxRows int) *Heatmap {
+	h.RLock()
+	defer h.RUnlock()
+
+	keys := heatmapRowKeys(h.columns, maxRows)
+	heatmap := &Heatmap{
+		Times:  make([]time.Time, 0, len(h.columns)),
+		Keys:   make([]string, 0, len(keys)+1),
+		Values: make([][]float64, 0, len(h.columns)),
+	}
+	for _, key := range keys {
+		heatmap.Keys = append(heatmap.Keys, hex.EncodeToString(key))
+	}
+	if len(keys) > 0 {
+		heatmap.Keys = append(heatmap.Keys, "")
+	}
+
+	for _, column := range h.columns {
+		values := make([]float64, len(keys))
+		for _, f := range column.flows {
+			// The row of the start key is the last one starting not after it.
+			first := sort.Search(len(keys), func(i int) bool {
+				return bytes.Compare(keys[i], f.startKey) > 0
+			}) - 1
+			last := first
+			for last+1 < len(keys) && (len(f.endKey) == 0 || bytes.Compare(keys[last+1], f.endKey) < 0) {
+				last++
+			}
+			share := f.getFlow(kind) / float64(last-first+1)
+			for i := first; i <= last; i++ {
+				values[i] += share
+			}
+		}
+		heatmap.Times = append(heatmap.Times, column.time)
+		heatmap.Values = append(heatmap.Values, values)
+	}
+	return heatmap
+}
+
+// heatmapRowKeys returns the sorted start keys of the rows, the first one is
+// the start of the key space if there are any columns.
+func heatmapRowKeys(columns []*heatmapColumn, maxRows int) [][]byte {
+	if len(columns) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{})
+	for _, column := range columns {
+		for _, f := range column.flows {
+			set[string(f.startKey)] = struct{}{}
+		}
+	}
+	delete(set, "")
+	keys := make([]string, 0, len(set)+1)
+	keys = append(keys, "")
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	step := (len(keys) + maxRows - 1) / maxRows
+	rows := make([][]byte, 0, maxRows)
+	for i := 0; i < len(keys); i += step {
+		rows = append(rows, []byte(keys[i]))
+	}
+	return rows
+}
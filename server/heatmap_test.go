@@ -0,0 +1,99 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/hex"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHeatmapSuite{})
+
+type testHeatmapSuite struct{}
+
+func newTestHeatmapColumn(t time.Time, keys []string, written, read []float64) *heatmapColumn {
+	column := &heatmapColumn{time: t}
+	for i := 0; i < len(keys)-1; i++ {
+		column.flows = append(column.flows, &keyRangeFlow{
+			startKey:     []byte(keys[i]),
+			endKey:       []byte(keys[i+1]),
+			bytesWritten: written[i],
+			bytesRead:    read[i],
+		})
+	}
+	return column
+}
+
+func (s *testHeatmapSuite) TestHeatmap(c *C) {
+	h := newHeatmapColumns(2)
+	heatmap := h.getHeatmap(hotWriteFlow, heatmapMaxRows)
+	c.Assert(heatmap.Times, HasLen, 0)
+	c.Assert(heatmap.Keys, HasLen, 0)
+
+	t := time.Now()
+	h.add(newTestHeatmapColumn(t, []string{"", "c", ""}, []float64{1, 2}, []float64{3, 4}))
+	// Region [a, c) is split at b in the second column.
+	h.add(newTestHeatmapColumn(t.Add(time.Minute), []string{"", "b", "c", ""}, []float64{2, 4, 6}, []float64{0, 0, 0}))
+
+	heatmap = h.getHeatmap(hotWriteFlow, heatmapMaxRows)
+	c.Assert(heatmap.Times, HasLen, 2)
+	c.Assert(heatmap.Keys, DeepEquals, []string{"", hex.EncodeToString([]byte("b")), hex.EncodeToString([]byte("c")), ""})
+	c.Assert(heatmap.Values, DeepEquals, [][]float64{{0.5, 0.5, 2}, {2, 4, 6}})
+	heatmap = h.getHeatmap(hotReadFlow, heatmapMaxRows)
+	c.Assert(heatmap.Values, DeepEquals, [][]float64{{1.5, 1.5, 4}, {0, 0, 0}})
+
+	// The rows are merged.
+	heatmap = h.getHeatmap(hotWriteFlow, 2)
+	c.Assert(heatmap.Keys, DeepEquals, []string{"", hex.EncodeToString([]byte("c")), ""})
+	c.Assert(heatmap.Values, DeepEquals, [][]float64{{1, 2}, {6, 6}})
+
+	// The oldest column is dropped.
+	h.add(newTestHeatmapColumn(t.Add(2*time.Minute), []string{"", ""}, []float64{1}, []float64{1}))
+	heatmap = h.getHeatmap(hotWriteFlow, heatmapMaxRows)
+	c.Assert(heatmap.Times, DeepEquals, []time.Time{t.Add(time.Minute), t.Add(2 * time.Minute)})
+	c.Assert(heatmap.Values[1], DeepEquals, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3})
+}
+
+func (s *testHeatmapSuite) TestHeatmapColumn(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addRegionStore(1, 0)
+	tc.addLeaderRegion(1, 1)
+	region := cluster.GetRegion(1)
+	region.WrittenBytes = 1024 * regionHeartBeatReportInterval
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+
+	column := cluster.newHeatmapColumn(time.Now())
+	c.Assert(column.flows, HasLen, 1)
+	c.Assert(column.flows[0].bytesWritten, Equals, float64(1024))
+}
+
+func (s *testHeatmapSuite) TestMergeKeyRangeFlows(c *C) {
+	column := newTestHeatmapColumn(time.Now(), []string{"", "b", "c", "d", "e", ""}, []float64{1, 2, 3, 4, 5}, []float64{5, 4, 3, 2, 1})
+	c.Assert(mergeKeyRangeFlows(column.flows, 5), HasLen, 5)
+
+	// The adjacent ranges are merged and their flows are summed.
+	flows := mergeKeyRangeFlows(column.flows, 2)
+	c.Assert(flows, HasLen, 2)
+	c.Assert(string(flows[0].startKey), Equals, "")
+	c.Assert(string(flows[0].endKey), Equals, "d")
+	c.Assert(flows[0].bytesWritten, Equals, float64(6))
+	c.Assert(flows[0].bytesRead, Equals, float64(12))
+	c.Assert(string(flows[1].startKey), Equals, "d")
+	c.Assert(string(flows[1].endKey), Equals, "")
+	c.Assert(flows[1].bytesWritten, Equals, float64(9))
+	c.Assert(flows[1].bytesRead, Equals, float64(3))
+}
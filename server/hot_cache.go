@@ -0,0 +1,160 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"time"
+)
+
+// hotFlowKind is the kind of the flow a region is hot in.
+type hotFlowKind int
+
+const (
+	hotWriteFlow hotFlowKind = iota
+	hotReadFlow
+)
+
+// hotSpotCache keeps the hot regions with their decayed flows. A region is
+// promoted into the cache once its flow reaches the hot threshold, its hot
+// degree grows while its decayed flow stays over the threshold and falls
+// otherwise. It is evicted after its hot degree falls to 0 or its decayed flow
+// drops below hotRegionEvictRatio of the threshold, so a few quiet heartbeats
+// don't cool a hot region down at once.
+type hotSpotCache struct {
+	writeFlow *lruCache
+	readFlow  *lruCache
+}
+
+func newHotSpotCache() *hotSpotCache {
+	return &hotSpotCache{
+		writeFlow: newLRUCache(writeStatLRUMaxLen),
+		readFlow:  newLRUCache(readStatLRUMaxLen),
+	}
+}
+
+func (h *hotSpotCache) getCache(kind hotFlowKind) *lruCache {
+	if kind == hotReadFlow {
+		return h.readFlow
+	}
+	return h.writeFlow
+}
+
+// getRegionStat returns the stat of the region, nil if it isn't hot.
+func (h *hotSpotCache) getRegionStat(kind hotFlowKind, regionID uint64) *RegionStat {
+	if v, ok := h.getCache(kind).peek(regionID); ok {
+		stat := *v.(*RegionStat)
+		return &stat
+	}
+	return nil
+}
+
+// update adds the flow of a region heartbeat in bytes per second.
+func (h *hotSpotCache) update(kind hotFlowKind, region *RegionInfo, bytesPerSec, threshold uint64, now time.Time) {
+	cache := h.getCache(kind)
+	key := region.GetId()
+	stat := &RegionStat{
+		RegionID:       key,
+		LastUpdateTime: now,
+		StoreID:        region.Leader.GetStoreId(),
+		version:        region.GetRegionEpoch().GetVersion(),
+	}
+	if v, ok := cache.peek(key); ok {
+		old := v.(*RegionStat)
+		decay := math.Exp(-float64(now.Sub(old.LastUpdateTime)) / float64(flowDecayWindow))
+		stat.flow = old.flow*decay + float64(bytesPerSec)*(1-decay)
+		stat.HotDegree = old.HotDegree
+	} else {
+		if bytesPerSec < threshold {
+			return
+		}
+		stat.flow = float64(bytesPerSec)
+	}
+
+	if stat.flow >= float64(threshold) {
+		stat.HotDegree++
+	} else {
+		stat.HotDegree--
+		if stat.HotDegree <= 0 || stat.flow < float64(threshold)*hotRegionEvictRatio {
+			cache.remove(key)
+			return
+		}
+	}
+
+	if kind == hotReadFlow {
+		stat.ReadBytes = uint64(stat.flow)
+	} else {
+		stat.WrittenBytes = uint64(stat.flow)
+	}
+	cache.add(key, stat)
+}
+
+// regionStats returns the hot regions with hot degree not less than
+// minHotDegree.
+func (h *hotSpotCache) regionStats(kind hotFlowKind, minHotDegree int) []*RegionStat {
+	var stats []*RegionStat
+	for _, item := range h.getCache(kind).elems() {
+		if stat := item.value.(*RegionStat); stat.HotDegree >= minHotDegree {
+			clone := *stat
+			stats = append(stats, &clone)
+		}
+	}
+	return stats
+}
+
+// getHotRegionStoreStats groups the hot regions by the stores of their peers
+// and leaders. The read flow is only served by the leaders, so the read hot
+// regions are grouped by the leaders only.
+func (c *clusterInfo) getHotRegionStoreStats(kind hotFlowKind, minHotDegree int) *StoreHotRegionInfos {
+	infos := &StoreHotRegionInfos{
+		AsPeer:   make(map[uint64]*HotRegionsStat),
+		AsLeader: make(map[uint64]*HotRegionsStat),
+	}
+	get := func(stats map[uint64]*HotRegionsStat, storeID uint64) *HotRegionsStat {
+		s, ok := stats[storeID]
+		if !ok {
+			s = &HotRegionsStat{
+				RegionsStat: make(RegionsStat, 0, storeHotRegionsDefaultLen),
+			}
+			stats[storeID] = s
+		}
+		return s
+	}
+	add := func(stats map[uint64]*HotRegionsStat, storeID uint64, r *RegionStat) {
+		s := get(stats, storeID)
+		stat := *r
+		stat.StoreID = storeID
+		s.WrittenBytes += r.WrittenBytes
+		s.ReadBytes += r.ReadBytes
+		s.RegionsCount++
+		s.RegionsStat = append(s.RegionsStat, stat)
+	}
+
+	for _, r := range c.hotCache.regionStats(kind, minHotDegree) {
+		region := c.GetRegion(r.RegionID)
+		if region == nil {
+			continue
+		}
+		add(infos.AsLeader, region.Leader.GetStoreId(), r)
+		if kind != hotWriteFlow {
+			continue
+		}
+		// The stores of the followers are the candidates of the hot leaders.
+		for storeID := range region.GetStoreIds() {
+			add(infos.AsPeer, storeID, r)
+			get(infos.AsLeader, storeID)
+		}
+	}
+	return infos
+}
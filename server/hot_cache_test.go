@@ -0,0 +1,91 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testHotSpotCacheSuite{})
+
+type testHotSpotCacheSuite struct{}
+
+func (s *testHotSpotCacheSuite) TestUpdate(c *C) {
+	h := newHotSpotCache()
+	peer := &metapb.Peer{Id: 11, StoreId: 1}
+	region := newRegionInfo(&metapb.Region{Id: 1, Peers: []*metapb.Peer{peer}}, peer)
+	now := time.Now()
+
+	// The region is not promoted until its flow reaches the threshold.
+	h.update(hotWriteFlow, region, 50, 100, now)
+	c.Assert(h.getRegionStat(hotWriteFlow, 1), IsNil)
+	h.update(hotWriteFlow, region, 200, 100, now)
+	stat := h.getRegionStat(hotWriteFlow, 1)
+	c.Assert(stat.HotDegree, Equals, 1)
+	c.Assert(stat.WrittenBytes, Equals, uint64(200))
+	c.Assert(h.getRegionStat(hotReadFlow, 1), IsNil)
+
+	now = now.Add(flowDecayWindow)
+	h.update(hotWriteFlow, region, 200, 100, now)
+	c.Assert(h.getRegionStat(hotWriteFlow, 1).HotDegree, Equals, 2)
+	c.Assert(h.regionStats(hotWriteFlow, 2), HasLen, 1)
+	c.Assert(h.regionStats(hotWriteFlow, 3), HasLen, 0)
+
+	// The decayed flow is 200/e, it is cooling down but still kept.
+	now = now.Add(flowDecayWindow)
+	h.update(hotWriteFlow, region, 0, 100, now)
+	stat = h.getRegionStat(hotWriteFlow, 1)
+	c.Assert(stat.HotDegree, Equals, 1)
+	c.Assert(stat.WrittenBytes, Equals, uint64(73))
+
+	// The decayed flow drops below the eviction ratio of the threshold.
+	now = now.Add(flowDecayWindow)
+	h.update(hotWriteFlow, region, 0, 100, now)
+	c.Assert(h.getRegionStat(hotWriteFlow, 1), IsNil)
+
+	// The region is evicted once its hot degree falls to 0.
+	h.update(hotReadFlow, region, 200, 100, now)
+	c.Assert(h.getRegionStat(hotReadFlow, 1).ReadBytes, Equals, uint64(200))
+	h.update(hotReadFlow, region, 0, 100, now.Add(flowDecayWindow))
+	c.Assert(h.getRegionStat(hotReadFlow, 1), IsNil)
+}
+
+func (s *testHotSpotCacheSuite) TestHotRegionStoreStats(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 2, 1, 3)
+	now := time.Now()
+	cluster.hotCache.update(hotWriteFlow, cluster.GetRegion(1), 200, 100, now)
+	cluster.hotCache.update(hotReadFlow, cluster.GetRegion(2), 300, 100, now)
+
+	stats := cluster.getHotRegionStoreStats(hotWriteFlow, 1)
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		c.Assert(stats.AsPeer[storeID].RegionsCount, Equals, 1)
+		c.Assert(stats.AsPeer[storeID].WrittenBytes, Equals, uint64(200))
+	}
+	c.Assert(stats.AsLeader[1].RegionsCount, Equals, 1)
+	c.Assert(stats.AsLeader[1].RegionsStat[0].StoreID, Equals, uint64(1))
+	c.Assert(stats.AsLeader[2].RegionsCount, Equals, 0)
+	c.Assert(cluster.getHotRegionStoreStats(hotWriteFlow, 2).AsPeer, HasLen, 0)
+
+	// The read hot regions are grouped by the leaders only.
+	stats = cluster.getHotRegionStoreStats(hotReadFlow, 1)
+	c.Assert(stats.AsPeer, HasLen, 0)
+	c.Assert(stats.AsLeader, HasLen, 1)
+	c.Assert(stats.AsLeader[2].ReadBytes, Equals, uint64(300))
+}
@@ -24,6 +24,9 @@ import (
 
 const (
 	allocStep = uint64(1000)
+	// Prepare the next window in background if the current one has no more
+	// than allocPrepareThreshold ids left.
+	allocPrepareThreshold = allocStep / 4
 )
 
 // IDAllocator is the allocator to generate unique ID.
@@ -36,6 +39,13 @@ type idAllocator struct {
 	base uint64
 	end  uint64
 
+	// nextEnd is the end of the window prepared in background, 0 if there
+	// is no prepared window.
+	nextEnd uint64
+	// preparing is closed when the preparing window is ready, nil if there
+	// is no window being prepared.
+	preparing chan struct{}
+
 	s *Server
 }
 
@@ -43,7 +53,21 @@ func (alloc *idAllocator) Alloc() (uint64, error) {
 	alloc.mu.Lock()
 	defer alloc.mu.Unlock()
 
-	if alloc.base == alloc.end {
+	for alloc.base == alloc.end {
+		if alloc.nextEnd != 0 {
+			alloc.end, alloc.nextEnd = alloc.nextEnd, 0
+			alloc.base = alloc.end - allocStep
+			break
+		}
+
+		if ch := alloc.preparing; ch != nil {
+			// Wait for the window being prepared instead of racing with it.
+			alloc.mu.Unlock()
+			<-ch
+			alloc.mu.Lock()
+			continue
+		}
+
 		end, err := alloc.generate()
 		if err != nil {
 			return 0, errors.Trace(err)
@@ -55,10 +79,90 @@ func (alloc *idAllocator) Alloc() (uint64, error) {
 
 	alloc.base++
 
+	if alloc.end-alloc.base <= allocPrepareThreshold && alloc.nextEnd == 0 && alloc.preparing == nil {
+		alloc.prepare()
+	}
+
 	return alloc.base, nil
 }
 
+// prepare generates the next window in background, it must be called with
+// the lock held.
+func (alloc *idAllocator) prepare() {
+	ch := make(chan struct{})
+	alloc.preparing = ch
+
+	go func() {
+		end, err := alloc.generate()
+
+		alloc.mu.Lock()
+		if err != nil {
+			log.Errorf("idAllocator prepares next window failed: %v", err)
+		} else {
+			alloc.nextEnd = end
+		}
+		alloc.preparing = nil
+		alloc.mu.Unlock()
+
+		close(ch)
+	}()
+}
+
 func (alloc *idAllocator) generate() (uint64, error) {
+	end, err := alloc.updateEnd(func(end uint64) uint64 { return end + allocStep })
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	log.Infof("idAllocator allocates a new id: %d", end)
+	return end, nil
+}
+
+// loadEnd returns the persisted end of the windows, no allocated id is
+// larger than it.
+func (alloc *idAllocator) loadEnd() (uint64, error) {
+	value, err := getValue(alloc.s.client, alloc.s.getAllocIDPath())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if value == nil {
+		return 0, nil
+	}
+	end, err := bytesToUint64(value)
+	return end, errors.Trace(err)
+}
+
+// rebase makes the ids allocated later larger than id, so the ids restored
+// from a backup are not allocated again. The current windows are dropped.
+func (alloc *idAllocator) rebase(id uint64) error {
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+
+	for alloc.preparing != nil {
+		ch := alloc.preparing
+		alloc.mu.Unlock()
+		<-ch
+		alloc.mu.Lock()
+	}
+	if alloc.base >= id {
+		return nil
+	}
+
+	end, err := alloc.updateEnd(func(end uint64) uint64 {
+		if end < id {
+			return id
+		}
+		return end
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	alloc.base, alloc.end, alloc.nextEnd = 0, 0, 0
+	log.Infof("idAllocator is rebased to %d", end)
+	return nil
+}
+
+// updateEnd persists the new end of the windows computed from the old one.
+func (alloc *idAllocator) updateEnd(f func(end uint64) uint64) (uint64, error) {
 	key := alloc.s.getAllocIDPath()
 	value, err := getValue(alloc.s.client, key)
 	if err != nil {
@@ -83,8 +187,11 @@ func (alloc *idAllocator) generate() (uint64, error) {
 		cmp = clientv3.Compare(clientv3.Value(key), "=", string(value))
 	}
 
-	end += allocStep
-	value = uint64ToBytes(end)
+	newEnd := f(end)
+	if value != nil && newEnd == end {
+		return end, nil
+	}
+	value = uint64ToBytes(newEnd)
 	resp, err := alloc.s.leaderTxn(cmp).Then(clientv3.OpPut(key, string(value))).Commit()
 	if err != nil {
 		return 0, errors.Trace(err)
@@ -92,9 +199,7 @@ func (alloc *idAllocator) generate() (uint64, error) {
 	if !resp.Succeeded {
 		return 0, errors.New("generate id failed, we may not leader")
 	}
-
-	log.Infof("idAllocator allocates a new id: %d", end)
-	return end, nil
+	return newEnd, nil
 }
 
 func (s *Server) getAllocIDPath() string {
@@ -94,3 +94,38 @@ func (s *testAllocIDSuite) TestCommand(c *C) {
 		last = resp.GetId()
 	}
 }
+
+func (s *testAllocIDSuite) TestPrepareNextWindow(c *C) {
+	// Alloc until the next window is being prepared.
+	for i := uint64(0); i <= allocStep; i++ {
+		_, err := s.alloc.Alloc()
+		c.Assert(err, IsNil)
+
+		s.alloc.mu.Lock()
+		ch, nextEnd := s.alloc.preparing, s.alloc.nextEnd
+		s.alloc.mu.Unlock()
+		if ch != nil {
+			<-ch
+			break
+		}
+		if nextEnd != 0 {
+			break
+		}
+	}
+
+	s.alloc.mu.Lock()
+	end, nextEnd := s.alloc.end, s.alloc.nextEnd
+	left := end - s.alloc.base
+	s.alloc.mu.Unlock()
+	c.Assert(left, LessEqual, allocPrepareThreshold)
+	c.Assert(nextEnd, Equals, end+allocStep)
+
+	// Switch to the prepared window after the current one is used up.
+	var last uint64
+	for i := uint64(0); i < left+1; i++ {
+		id, err := s.alloc.Alloc()
+		c.Assert(err, IsNil)
+		last = id
+	}
+	c.Assert(last, Equals, end+1)
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import "github.com/pingcap/kvproto/pkg/pdpb"
+
+// storeInfluence is the pending change of the resources of a store, made by
+// the operator steps which are not finished yet.
+type storeInfluence struct {
+	regionSize  int64
+	regionCount int64
+	leaderSize  int64
+	leaderCount int64
+}
+
+// OpInfluence is the store influences of the running operators. The
+// schedulers add it to the store status, so they won't pick the same store
+// again and again before the earlier operators land. A nil OpInfluence has no
+// influence.
+type OpInfluence map[uint64]*storeInfluence
+
+func newOpInfluence(ops []Operator) OpInfluence {
+	m := make(OpInfluence)
+	for _, op := range ops {
+		m.addOperator(op)
+	}
+	return m
+}
+
+func (m OpInfluence) getStoreInfluence(storeID uint64) *storeInfluence {
+	s, ok := m[storeID]
+	if !ok {
+		s = &storeInfluence{}
+		m[storeID] = s
+	}
+	return s
+}
+
+func (m OpInfluence) addOperator(op Operator) {
+	var (
+		region *RegionInfo
+		steps  []Operator
+	)
+	switch o := op.(type) {
+	case *regionOperator:
+		region, steps = o.Region, o.Ops
+	case *adminOperator:
+		region, steps = o.Region, o.Ops
+	default:
+		return
+	}
+
+	size := int64(region.regionSize())
+	for _, step := range steps {
+		if step.GetState() == OperatorFinished {
+			continue
+		}
+		switch s := step.(type) {
+		case *changePeerOperator:
+			peer := s.ChangePeer.GetPeer()
+			store := m.getStoreInfluence(peer.GetStoreId())
+			switch s.ChangePeer.GetChangeType() {
+			case pdpb.ConfChangeType_AddNode:
+				store.regionSize += size
+				store.regionCount++
+			case pdpb.ConfChangeType_RemoveNode:
+				store.regionSize -= size
+				store.regionCount--
+			}
+		case *transferLeaderOperator:
+			from := m.getStoreInfluence(s.OldLeader.GetStoreId())
+			from.leaderSize -= size
+			from.leaderCount--
+			to := m.getStoreInfluence(s.NewLeader.GetStoreId())
+			to.leaderSize += size
+			to.leaderCount++
+		}
+	}
+}
+
+// apply adds the influence to the status of the store. The store is modified
+// in place, so it must be a clone from the cluster.
+func (m OpInfluence) apply(store *StoreInfo) *StoreInfo {
+	if store == nil {
+		return nil
+	}
+	s, ok := m[store.GetId()]
+	if !ok {
+		return store
+	}
+	status := store.status
+	status.LeaderCount = int(addInfluence(uint64(status.LeaderCount), s.leaderCount))
+	status.LeaderSize = addInfluence(status.LeaderSize, s.leaderSize)
+	status.RegionCount = int(addInfluence(uint64(status.RegionCount), s.regionCount))
+	status.RegionSize = addInfluence(status.RegionSize, s.regionSize)
+	return store
+}
+
+func (m OpInfluence) applyStores(stores []*StoreInfo) []*StoreInfo {
+	for _, store := range stores {
+		m.apply(store)
+	}
+	return stores
+}
+
+func addInfluence(v uint64, delta int64) uint64 {
+	if delta < 0 && uint64(-delta) > v {
+		return 0
+	}
+	return uint64(int64(v) + delta)
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testInfluenceSuite{})
+
+type testInfluenceSuite struct{}
+
+func (s *testInfluenceSuite) TestOpInfluence(c *C) {
+	peers := []*metapb.Peer{
+		{Id: 11, StoreId: 1},
+		{Id: 12, StoreId: 2},
+		{Id: 13, StoreId: 3},
+	}
+	region := newRegionInfo(&metapb.Region{Id: 1, Peers: peers}, peers[0])
+	region.ApproximateSize = 10
+	newPeer := &metapb.Peer{Id: 14, StoreId: 4}
+
+	ops := []Operator{
+		// Move the peer from store 2 to store 4.
+		newRegionOperator(region, RegionKind,
+			newAddPeerOperator(1, newPeer),
+			newRemovePeerOperator(1, peers[1]),
+		),
+		newRegionOperator(region, LeaderKind, newTransferLeaderOperator(1, peers[0], peers[1])),
+	}
+	m := newOpInfluence(ops)
+	c.Assert(*m[1], Equals, storeInfluence{leaderSize: -10, leaderCount: -1})
+	c.Assert(*m[2], Equals, storeInfluence{regionSize: -10, regionCount: -1, leaderSize: 10, leaderCount: 1})
+	c.Assert(*m[4], Equals, storeInfluence{regionSize: 10, regionCount: 1})
+
+	// The finished steps have no influence.
+	ops[0].(*regionOperator).Ops[0].SetState(OperatorFinished)
+	m = newOpInfluence(ops)
+	c.Assert(m[4], IsNil)
+
+	store := newStoreInfo(&metapb.Store{Id: 2})
+	store.status.LeaderCount = 0
+	store.status.RegionCount = 5
+	store.status.RegionSize = 5
+	m.apply(store)
+	c.Assert(store.status.LeaderCount, Equals, 1)
+	c.Assert(store.status.LeaderSize, Equals, uint64(10))
+	c.Assert(store.status.RegionCount, Equals, 4)
+	// The size is never negative.
+	c.Assert(store.status.RegionSize, Equals, uint64(0))
+
+	// A nil influence has no influence.
+	var empty OpInfluence
+	c.Assert(empty.apply(store).status.LeaderCount, Equals, 1)
+}
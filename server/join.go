@@ -15,6 +15,9 @@ package server
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
 	"strings"
 
 	"github.com/coreos/etcd/clientv3"
@@ -24,6 +27,15 @@ import (
 	"github.com/pingcap/pd/pkg/etcdutil"
 )
 
+const (
+	// joinFileName is the file in the data directory to save the
+	// initial-cluster generated by join.
+	joinFileName = "join"
+
+	privateFileMode = 0600
+	privateDirMode  = 0700
+)
+
 // TODO: support HTTPS
 func genClientV3Config(cfg *Config) clientv3.Config {
 	endpoints := strings.Split(cfg.Join, ",")
@@ -70,20 +82,26 @@ func genClientV3Config(cfg *Config) clientv3.Config {
 //      What join does: return "" (as etcd will read data directory and find
 //                      that the PD itself has been removed, so an empty string
 //                      is fine.)
+//
+// The initial-cluster generated after MemberAdd is saved in the join file of
+// the data directory, so a PD which fails before etcd creates its data can
+// restart with the same initial-cluster instead of adding itself again.
 func PrepareJoinCluster(cfg *Config) error {
 	// - A PD tries to join itself.
 	if cfg.Join == "" {
 		return nil
 	}
 
-	if cfg.Join == cfg.AdvertiseClientUrls {
+	if isJoinSelf(cfg) {
 		return errors.New("join self is forbidden")
 	}
 
 	// Cases with data directory.
 
 	initialCluster := ""
-	if wal.Exist(cfg.DataDir) {
+	// The data directory has other files like the join file, so check the wal
+	// directory of etcd in it.
+	if wal.Exist(path.Join(cfg.DataDir, "member", "wal")) {
 		cfg.InitialCluster = initialCluster
 		cfg.InitialClusterState = embed.ClusterStateFlagExisting
 		return nil
@@ -91,6 +109,16 @@ func PrepareJoinCluster(cfg *Config) error {
 
 	// Below are cases without data directory.
 
+	// - A PD has added itself but failed before etcd starts.
+	joinPath := path.Join(cfg.DataDir, joinFileName)
+	if s, err := ioutil.ReadFile(joinPath); err == nil {
+		cfg.InitialCluster = strings.TrimSpace(string(s))
+		cfg.InitialClusterState = embed.ClusterStateFlagExisting
+		return nil
+	} else if !os.IsNotExist(err) {
+		return errors.Trace(err)
+	}
+
 	client, err := clientv3.New(genClientV3Config(cfg))
 	if err != nil {
 		return errors.Trace(err)
@@ -116,7 +144,7 @@ func PrepareJoinCluster(cfg *Config) error {
 
 	// - A new PD joins an existing cluster.
 	// - A deleted PD joins to previous cluster.
-	addResp, err := etcdutil.AddEtcdMember(client, []string{cfg.AdvertisePeerUrls})
+	addResp, err := etcdutil.AddEtcdMember(client, strings.Split(cfg.AdvertisePeerUrls, ","))
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -139,5 +167,24 @@ func PrepareJoinCluster(cfg *Config) error {
 	initialCluster = strings.Join(pds, ",")
 	cfg.InitialCluster = initialCluster
 	cfg.InitialClusterState = embed.ClusterStateFlagExisting
-	return nil
+
+	if err = os.MkdirAll(cfg.DataDir, privateDirMode); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(joinPath, []byte(initialCluster), privateFileMode))
+}
+
+// isJoinSelf returns whether the PD joins itself, by the advertise client urls
+// or the listen ones which may differ behind a NAT.
+func isJoinSelf(cfg *Config) bool {
+	self := make(map[string]struct{})
+	for _, u := range strings.Split(cfg.AdvertiseClientUrls+","+cfg.ClientUrls, ",") {
+		self[strings.TrimSuffix(strings.TrimSpace(u), "/")] = struct{}{}
+	}
+	for _, u := range strings.Split(cfg.Join, ",") {
+		if _, ok := self[strings.TrimSuffix(strings.TrimSpace(u), "/")]; ok {
+			return true
+		}
+	}
+	return false
 }
@@ -221,6 +221,15 @@ func (s *testJoinServerSuite) TestPDJoinsItself(c *C) {
 
 	_, err := startPdWith(cfg)
 	c.Assert(err, NotNil)
+
+	// It joins itself by the listen urls which differ from the advertise ones.
+	cfg = newTestMultiJoinConfig(1)[0]
+	defer cleanServer(cfg)
+	cfg.AdvertiseClientUrls = "http://pd.invalid:2379,http://pd2.invalid:2379"
+	cfg.Join = "http://pd.invalid:2379/"
+	c.Assert(PrepareJoinCluster(cfg), NotNil)
+	cfg.Join = "http://127.0.0.1:2379," + cfg.ClientUrls
+	c.Assert(PrepareJoinCluster(cfg), NotNil)
 }
 
 // A failed PD re-joins the previous cluster.
@@ -239,6 +248,28 @@ func (s *testJoinServerSuite) TestFailedPDJoinsPreviousCluster(c *C) {
 	c.Assert(err, NotNil)
 }
 
+// A PD fails after it is added to the cluster but before etcd starts.
+func (s *testJoinServerSuite) TestPDRestartsBeforeEtcdStarts(c *C) {
+	cfgs, svrs, clean := mustNewJoinCluster(c, 2)
+	defer clean()
+
+	cfg := newTestMultiJoinConfig(1)[0]
+	cfg.Name = "pd3"
+	cfg.Join = cfgs[1].ClientUrls
+	defer cleanServer(cfg)
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(PrepareJoinCluster(cfg), IsNil)
+	initialCluster := cfg.InitialCluster
+
+	// The initial-cluster is loaded from the join file.
+	cfg.InitialCluster = ""
+	svr, err := startPdWith(cfg)
+	c.Assert(err, IsNil)
+	defer svr.Close()
+	c.Assert(cfg.InitialCluster, Equals, initialCluster)
+	c.Assert(waitMembers(append(svrs, svr), 3), IsNil)
+}
+
 // A failed PD tries to join the previous cluster but it has been deleted
 // during its downtime.
 func (s *testJoinServerSuite) TestFailedAndDeletedPDJoinsPreviousCluster(c *C) {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"math"
 	"path"
+	"strconv"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -25,6 +26,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/failpoint"
 	"golang.org/x/net/context"
 )
 
@@ -32,6 +34,8 @@ const (
 	kvRangeLimit      = 100000
 	kvRequestTimeout  = time.Second * 10
 	kvSlowRequestTime = time.Second * 1
+	// maxKVOpsPerTxn is the max number of operations in one etcd txn.
+	maxKVOpsPerTxn = 64
 )
 
 var (
@@ -44,6 +48,13 @@ type kv struct {
 	client      *clientv3.Client
 	clusterPath string
 	configPath  string
+	rulesPath   string
+	nsPath      string
+	schedPath   string
+	eventsPath  string
+	// regionKV is the local storage of regions, regions are saved to it
+	// instead of etcd if it is not nil.
+	regionKV *regionKV
 }
 
 func newKV(s *Server) *kv {
@@ -52,6 +63,10 @@ func newKV(s *Server) *kv {
 		client:      s.client,
 		clusterPath: path.Join(s.rootPath, "raft"),
 		configPath:  path.Join(s.rootPath, "config"),
+		rulesPath:   path.Join(s.rootPath, "rules"),
+		nsPath:      path.Join(s.rootPath, "namespaces"),
+		schedPath:   path.Join(s.rootPath, "schedulers"),
+		eventsPath:  path.Join(s.rootPath, "events"),
 	}
 }
 
@@ -61,10 +76,30 @@ func (kv *kv) storePath(storeID uint64) string {
 	return path.Join(kv.clusterPath, "s", fmt.Sprintf("%020d", storeID))
 }
 
+func (kv *kv) storeSettingsPath(storeID uint64) string {
+	return path.Join(kv.clusterPath, "store_settings", fmt.Sprintf("%020d", storeID))
+}
+
 func (kv *kv) regionPath(regionID uint64) string {
 	return path.Join(kv.clusterPath, "r", fmt.Sprintf("%020d", regionID))
 }
 
+func (kv *kv) rulePath(ruleID string) string {
+	return path.Join(kv.rulesPath, ruleID)
+}
+
+func (kv *kv) namespacePath(name string) string {
+	return path.Join(kv.nsPath, name)
+}
+
+func (kv *kv) schedulerPath(name string) string {
+	return path.Join(kv.schedPath, name)
+}
+
+func (kv *kv) clusterEventPath(id uint64) string {
+	return path.Join(kv.eventsPath, fmt.Sprintf("%020d", id))
+}
+
 func (kv *kv) clusterStatePath(option string) string {
 	return path.Join(kv.clusterPath, "status", option)
 }
@@ -96,14 +131,142 @@ func (kv *kv) saveStore(store *metapb.Store) error {
 	return kv.saveProto(kv.storePath(store.GetId()), store)
 }
 
+func (kv *kv) deleteStore(store *metapb.Store) error {
+	if err := kv.delete(kv.storePath(store.GetId())); err != nil {
+		return errors.Trace(err)
+	}
+	return kv.delete(kv.storeSettingsPath(store.GetId()))
+}
+
+func (kv *kv) saveStoreSettings(storeID uint64, settings StoreSettings) error {
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.storeSettingsPath(storeID), string(value))
+}
+
+// loadStoreSettings loads the settings of the loaded stores.
+func (kv *kv) loadStoreSettings(stores *storesInfo) error {
+	resp, err := kvGet(kv.client, path.Dir(kv.storeSettingsPath(0))+"/", clientv3.WithPrefix())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, item := range resp.Kvs {
+		storeID, err := strconv.ParseUint(path.Base(string(item.Key)), 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		store := stores.getStore(storeID)
+		if store == nil {
+			continue
+		}
+		settings := newStoreSettings()
+		if err := json.Unmarshal(item.Value, &settings); err != nil {
+			return errors.Trace(err)
+		}
+		store.status.Settings = settings
+		stores.setStore(store)
+	}
+	return nil
+}
+
 func (kv *kv) loadRegion(regionID uint64, region *metapb.Region) (bool, error) {
+	if kv.regionKV != nil {
+		ok, err := kv.regionKV.loadRegion(regionID, region)
+		if err != nil || ok {
+			return ok, errors.Trace(err)
+		}
+	}
 	return kv.loadProto(kv.regionPath(regionID), region)
 }
 
 func (kv *kv) saveRegion(region *metapb.Region) error {
+	if _, ok := failpoint.Eval("saveRegionErr"); ok {
+		return errors.Errorf("failpoint: save region %d failed", region.GetId())
+	}
+	if kv.regionKV != nil {
+		return kv.regionKV.saveRegion(region)
+	}
 	return kv.saveProto(kv.regionPath(region.GetId()), region)
 }
 
+func (kv *kv) deleteRegion(region *metapb.Region) error {
+	if kv.regionKV != nil {
+		if err := kv.regionKV.deleteRegion(region); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return kv.delete(kv.regionPath(region.GetId()))
+}
+
+// syncRegions saves the regions to etcd, it is used to sync the regions in
+// the local storage.
+func (kv *kv) syncRegions(regions []*metapb.Region) error {
+	ops := make([]clientv3.Op, 0, len(regions))
+	for _, region := range regions {
+		value, err := proto.Marshal(region)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ops = append(ops, clientv3.OpPut(kv.regionPath(region.GetId()), string(value)))
+	}
+	return errors.Trace(kv.commitOps(ops))
+}
+
+// flushRegions saves and deletes the regions in batches. Like saveRegion and
+// deleteRegion, the saved regions go to the local storage if it is used, and
+// the deleted regions are deleted from both.
+func (kv *kv) flushRegions(saves, deletes []*metapb.Region) error {
+	if _, ok := failpoint.Eval("saveRegionErr"); ok {
+		return errors.Errorf("failpoint: flush %d regions failed", len(saves))
+	}
+	var ops []clientv3.Op
+	if kv.regionKV != nil {
+		if err := kv.regionKV.flushRegions(saves, deletes); err != nil {
+			return errors.Trace(err)
+		}
+	} else {
+		for _, region := range saves {
+			value, err := proto.Marshal(region)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			ops = append(ops, clientv3.OpPut(kv.regionPath(region.GetId()), string(value)))
+		}
+	}
+	for _, region := range deletes {
+		ops = append(ops, clientv3.OpDelete(kv.regionPath(region.GetId())))
+	}
+	return errors.Trace(kv.commitOps(ops))
+}
+
+// commitOps commits the ops in transactions of maxKVOpsPerTxn ops at most.
+func (kv *kv) commitOps(ops []clientv3.Op) error {
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > maxKVOpsPerTxn {
+			n = maxKVOpsPerTxn
+		}
+		resp, err := kv.txn().Then(ops[:n]...).Commit()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !resp.Succeeded {
+			return errors.Trace(errTxnFailed)
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+func (kv *kv) close() error {
+	if kv.regionKV != nil {
+		return kv.regionKV.close()
+	}
+	return nil
+}
+
 func (kv *kv) loadScheduleOption(opt *scheduleOption) (bool, error) {
 	cfg := &Config{}
 	cfg.Schedule = *opt.load()
@@ -115,6 +278,9 @@ func (kv *kv) loadScheduleOption(opt *scheduleOption) (bool, error) {
 	if !isExist {
 		return false, nil
 	}
+	if err = cfg.Schedule.parseThrottleWindows(); err != nil {
+		return false, errors.Trace(err)
+	}
 	opt.store(&cfg.Schedule)
 	opt.rep.store(&cfg.Replication)
 	return true, nil
@@ -150,6 +316,161 @@ func (kv *kv) loadConfig(cfg *Config) (bool, error) {
 	return true, nil
 }
 
+func (kv *kv) saveRule(rule *Rule) error {
+	value, err := json.Marshal(rule)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.rulePath(rule.ID), string(value))
+}
+
+func (kv *kv) deleteRule(ruleID string) error {
+	return kv.delete(kv.rulePath(ruleID))
+}
+
+// loadRules replaces the placement rules with the rules in etcd.
+func (kv *kv) loadRules(rules *placementRules) error {
+	resp, err := kvGet(kv.client, kv.rulesPath+"/", clientv3.WithPrefix())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	loaded := newPlacementRules()
+	for _, item := range resp.Kvs {
+		rule := &Rule{}
+		if err := json.Unmarshal(item.Value, rule); err != nil {
+			return errors.Trace(err)
+		}
+		if err := rule.adjust(); err != nil {
+			return errors.Trace(err)
+		}
+		loaded.setRule(rule)
+	}
+
+	rules.Lock()
+	defer rules.Unlock()
+	rules.rules = loaded.rules
+	return nil
+}
+
+func (kv *kv) saveNamespace(ns *Namespace) error {
+	value, err := json.Marshal(ns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.namespacePath(ns.Name), string(value))
+}
+
+func (kv *kv) deleteNamespace(name string) error {
+	return kv.delete(kv.namespacePath(name))
+}
+
+// loadNamespaces replaces the namespaces with the namespaces in etcd.
+func (kv *kv) loadNamespaces(namespaces *namespacesInfo) error {
+	resp, err := kvGet(kv.client, kv.nsPath+"/", clientv3.WithPrefix())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	loaded := newNamespacesInfo()
+	for _, item := range resp.Kvs {
+		ns := &Namespace{}
+		if err := json.Unmarshal(item.Value, ns); err != nil {
+			return errors.Trace(err)
+		}
+		loaded.setNamespace(ns)
+	}
+
+	namespaces.Lock()
+	defer namespaces.Unlock()
+	namespaces.namespaces = loaded.namespaces
+	namespaces.tables = loaded.tables
+	namespaces.stores = loaded.stores
+	return nil
+}
+
+func (kv *kv) saveScheduler(name string, cfg *schedulerConfig) error {
+	value, err := json.Marshal(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.schedulerPath(name), string(value))
+}
+
+func (kv *kv) deleteScheduler(name string) error {
+	return kv.delete(kv.schedulerPath(name))
+}
+
+func (kv *kv) loadSchedulers() ([]*schedulerConfig, error) {
+	resp, err := kvGet(kv.client, kv.schedPath+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	cfgs := make([]*schedulerConfig, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		cfg := &legacySchedulerConfig{}
+		if err := json.Unmarshal(item.Value, cfg); err != nil {
+			return nil, errors.Trace(err)
+		}
+		cfgs = append(cfgs, cfg.upgrade())
+	}
+	return cfgs, nil
+}
+
+func (kv *kv) saveClusterEvent(evt *ClusterEvent) error {
+	value, err := json.Marshal(evt)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.clusterEventPath(evt.ID), string(value))
+}
+
+// loadClusterEvents loads at most limit events with ids in [startID, endID).
+func (kv *kv) loadClusterEvents(startID, endID uint64, limit int64) ([]*ClusterEvent, error) {
+	resp, err := kvGet(kv.client, kv.clusterEventPath(startID), clientv3.WithRange(kv.clusterEventPath(endID)), clientv3.WithLimit(limit))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	evts := make([]*ClusterEvent, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		evt := &ClusterEvent{}
+		if err := json.Unmarshal(item.Value, evt); err != nil {
+			return nil, errors.Trace(err)
+		}
+		evts = append(evts, evt)
+	}
+	return evts, nil
+}
+
+// trimClusterEvents deletes the oldest events if there are more than max.
+func (kv *kv) trimClusterEvents(max int64) error {
+	prefix := kv.eventsPath + "/"
+	resp, err := kvGet(kv.client, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if resp.Count <= max {
+		return nil
+	}
+	resp, err = kvGet(kv.client, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithLimit(resp.Count-max))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+	last := string(resp.Kvs[len(resp.Kvs)-1].Key)
+	txnResp, err := kv.txn().Then(clientv3.OpDelete(prefix, clientv3.WithRange(last+"\x00"))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !txnResp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
 func (kv *kv) loadStores(stores *storesInfo, rangeLimit int64) error {
 	nextID := uint64(0)
 	endStore := kv.storePath(math.MaxUint64)
@@ -203,9 +524,14 @@ func (kv *kv) loadRegions(regions *regionsInfo, rangeLimit int64) error {
 		}
 
 		if len(resp.Kvs) < int(rangeLimit) {
-			return nil
+			break
 		}
 	}
+
+	if kv.regionKV != nil {
+		return kv.regionKV.loadRegions(regions)
+	}
+	return nil
 }
 
 func (kv *kv) loadProto(key string, msg proto.Message) (bool, error) {
@@ -251,6 +577,17 @@ func (kv *kv) save(key, value string) error {
 	return nil
 }
 
+func (kv *kv) delete(key string) error {
+	resp, err := kv.txn().Then(clientv3.OpDelete(key)).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
 func kvGet(c *clientv3.Client, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
 	ctx, cancel := context.WithTimeout(c.Ctx(), kvRequestTimeout)
 	defer cancel()
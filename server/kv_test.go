@@ -15,6 +15,10 @@ package server
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -133,3 +137,281 @@ func (s *testKVSuite) TestLoadRegions(c *C) {
 		c.Assert(region, DeepEquals, regions[region.GetId()])
 	}
 }
+
+func (s *testKVSuite) TestLoadRules(c *C) {
+	kv := newKV(s.server)
+	rules := newPlacementRules()
+	rules.setRule(newTestRule("stale", "", "", 1))
+
+	c.Assert(kv.saveRule(newTestRule("r1", "", "", 3)), IsNil)
+	c.Assert(kv.saveRule(newTestRule("r2", "7480", "", 1)), IsNil)
+	c.Assert(kv.saveRule(newTestRule("r3", "", "", 1)), IsNil)
+	c.Assert(kv.deleteRule("r3"), IsNil)
+	c.Assert(kv.loadRules(rules), IsNil)
+
+	loaded := rules.getRules()
+	c.Assert(loaded, HasLen, 2)
+	c.Assert(loaded[0].ID, Equals, "r1")
+	c.Assert(loaded[1].ID, Equals, "r2")
+	c.Assert(loaded[1].startKey, DeepEquals, []byte("\x74\x80"))
+}
+
+func (s *testKVSuite) TestLoadNamespaces(c *C) {
+	kv := newKV(s.server)
+	namespaces := newNamespacesInfo()
+
+	c.Assert(kv.saveNamespace(&Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1}}), IsNil)
+	c.Assert(kv.saveNamespace(&Namespace{Name: "ns2", TableIDs: []int64{2}}), IsNil)
+	c.Assert(kv.deleteNamespace("ns2"), IsNil)
+	c.Assert(kv.loadNamespaces(namespaces), IsNil)
+
+	loaded := namespaces.getNamespaces()
+	c.Assert(loaded, HasLen, 1)
+	c.Assert(loaded[0], DeepEquals, &Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1}})
+	c.Assert(namespaces.getStoreNamespace(newStoreInfo(&metapb.Store{Id: 1})), Equals, "ns1")
+}
+
+func (s *testKVSuite) TestLoadSchedulers(c *C) {
+	kv := newKV(s.server)
+
+	c.Assert(kv.saveScheduler("evict-leader-scheduler-1", &schedulerConfig{Type: evictLeaderSchedulerType, Args: []string{"1"}}), IsNil)
+	c.Assert(kv.saveScheduler("evict-leader-scheduler-2", &schedulerConfig{Type: evictLeaderSchedulerType, Args: []string{"2"}}), IsNil)
+	c.Assert(kv.deleteScheduler("evict-leader-scheduler-2"), IsNil)
+	c.Assert(kv.saveScheduler("shuffle-leader-scheduler", &schedulerConfig{Type: "shuffle-leader-scheduler", Args: []string{"2"}}), IsNil)
+	c.Assert(kv.saveScheduler("balance-leader-scheduler", &schedulerConfig{Type: "balance-leader-scheduler"}), IsNil)
+	// The configs persisted before the arguments are upgraded.
+	c.Assert(kv.save(kv.schedulerPath("grant-leader-scheduler-1"), `{"type":"grant-leader-scheduler","store_id":1}`), IsNil)
+	c.Assert(kv.save(kv.schedulerPath("scatter-range-scheduler-t1"), `{"type":"scatter-range-scheduler","range_name":"t1","start_key":"7480","end_key":"7490"}`), IsNil)
+	cfgs, err := kv.loadSchedulers()
+	c.Assert(err, IsNil)
+	c.Assert(cfgs, DeepEquals, []*schedulerConfig{
+		{Type: "balance-leader-scheduler"},
+		{Type: evictLeaderSchedulerType, Args: []string{"1"}},
+		{Type: grantLeaderSchedulerType, Args: []string{"1"}},
+		{Type: scatterRangeSchedulerType, Args: []string{"t1", "7480", "7490"}},
+		{Type: "shuffle-leader-scheduler", Args: []string{"2"}},
+	})
+	c.Assert(kv.deleteScheduler("grant-leader-scheduler-1"), IsNil)
+	c.Assert(kv.deleteScheduler("scatter-range-scheduler-t1"), IsNil)
+
+	// The coordinator restores the persisted schedulers.
+	cluster := newClusterInfo(newMockIDAllocator())
+	cluster.kv = kv
+	tc := newTestClusterInfo(cluster)
+	tc.addLeaderStore(1, 1)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+	// The default schedulers are added before restoring.
+	c.Assert(co.addScheduler(newBalanceLeaderScheduler(opt), minScheduleInterval), IsNil)
+	co.restoreSchedulers()
+	defer co.stop()
+	c.Assert(co.schedulers, HasLen, 3)
+	c.Assert(co.schedulers, HasKey, "evict-leader-scheduler-1")
+	c.Assert(cluster.GetStore(1).isBlocked(), IsTrue)
+	c.Assert(co.schedulers["shuffle-leader-scheduler"].GetResourceLimit(), Equals, uint64(2))
+}
+
+func (s *testKVSuite) TestRegionKV(c *C) {
+	kv := newKV(s.server)
+	dir, err := ioutil.TempDir("", "region_kv")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	kv.regionKV, err = newRegionKV(path.Join(dir, "region-meta"))
+	c.Assert(err, IsNil)
+	defer kv.close()
+
+	newRegion := func(id uint64, start, end string, version uint64) *metapb.Region {
+		return &metapb.Region{
+			Id:          id,
+			StartKey:    []byte(start),
+			EndKey:      []byte(end),
+			RegionEpoch: &metapb.RegionEpoch{Version: version},
+		}
+	}
+
+	// Region 1 and 4 are in etcd.
+	c.Assert(kv.syncRegions([]*metapb.Region{newRegion(1, "", "m", 1), newRegion(4, "m", "", 3)}), IsNil)
+	// Region 1 is split into 1 and 2, region 3 is older than region 4.
+	regions := []*metapb.Region{newRegion(1, "", "g", 2), newRegion(2, "g", "m", 2), newRegion(3, "m", "", 2)}
+	for _, region := range regions {
+		c.Assert(kv.saveRegion(region), IsNil)
+	}
+
+	region := &metapb.Region{}
+	ok, err := kv.loadRegion(2, region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	c.Assert(region, DeepEquals, regions[1])
+	// Not saved to etcd.
+	ok, err = kv.loadProto(kv.regionPath(2), region)
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+
+	cache := newRegionsInfo()
+	c.Assert(kv.loadRegions(cache, 3), IsNil)
+	c.Assert(cache.getRegionCount(), Equals, 3)
+	c.Assert(cache.getRegion(1).Region, DeepEquals, regions[0])
+	c.Assert(cache.getRegion(2).Region, DeepEquals, regions[1])
+	c.Assert(cache.getRegion(3), IsNil)
+	c.Assert(cache.getRegion(4).GetRegionEpoch().GetVersion(), Equals, uint64(3))
+	c.Assert(cache.searchRegion([]byte("h")).GetId(), Equals, uint64(2))
+
+	// Sync to etcd.
+	c.Assert(kv.syncRegions(cache.getMetaRegions()), IsNil)
+	ok, err = kv.loadProto(kv.regionPath(2), region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	c.Assert(region, DeepEquals, regions[1])
+}
+
+func (s *testKVSuite) TestRegionBuffer(c *C) {
+	kv := newKV(s.server)
+	buffer := newRegionBuffer(kv)
+	regions := mustSaveRegions(c, kv, 3)
+
+	// The changes are not saved until they are flushed, a later change of a
+	// region replaces the buffered one.
+	buffer.deleteRegion(regions[0])
+	buffer.saveRegion(&metapb.Region{Id: 1, RegionEpoch: &metapb.RegionEpoch{Version: 1}})
+	buffer.saveRegion(&metapb.Region{Id: 1, RegionEpoch: &metapb.RegionEpoch{Version: 2}})
+	buffer.saveRegion(&metapb.Region{Id: 3})
+	region := &metapb.Region{}
+	ok, err := kv.loadRegion(0, region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	ok, err = kv.loadRegion(3, region)
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+
+	c.Assert(buffer.flush(), IsNil)
+	c.Assert(buffer.changes, HasLen, 0)
+	ok, err = kv.loadRegion(0, region)
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+	ok, err = kv.loadRegion(1, region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	c.Assert(region.GetRegionEpoch().GetVersion(), Equals, uint64(2))
+	ok, err = kv.loadRegion(3, region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+
+	// The buffer is flushed once it's full.
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		buffer.run(time.Hour, quit)
+		close(done)
+	}()
+	for i := 0; i < regionFlushSize; i++ {
+		buffer.saveRegion(&metapb.Region{Id: uint64(i + 10)})
+	}
+	for i := 0; i < 100; i++ {
+		if ok, _ = kv.loadRegion(10, region); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(ok, IsTrue)
+	// The remaining changes are flushed when it quits.
+	buffer.saveRegion(&metapb.Region{Id: 5})
+	close(quit)
+	<-done
+	ok, err = kv.loadRegion(5, region)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+}
+
+func (s *testKVSuite) TestRegionBufferClone(c *C) {
+	kv := newKV(s.server)
+	buffer := newRegionBuffer(kv)
+
+	// The buffered region is a clone, the cached one may be changed while
+	// it's flushed.
+	region := &metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("b")}
+	buffer.saveRegion(region)
+	done := make(chan error, 1)
+	go func() {
+		done <- buffer.flush()
+	}()
+	region.StartKey = []byte("aa")
+	c.Assert(<-done, IsNil)
+	loaded := &metapb.Region{}
+	ok, err := kv.loadRegion(1, loaded)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.GetStartKey(), DeepEquals, []byte("a"))
+}
+
+func (s *testKVSuite) TestRegionBufferWrite(c *C) {
+	kv := newKV(s.server)
+	cluster := newClusterInfo(newMockIDAllocator())
+	cluster.kv = kv
+	cluster.regionBuffer = newRegionBuffer(kv)
+	loadVersions := func(region *RegionInfo) (uint64, uint64) {
+		loaded := &metapb.Region{}
+		ok, err := kv.loadRegion(region.GetId(), loaded)
+		c.Assert(ok, IsTrue)
+		c.Assert(err, IsNil)
+		return loaded.GetRegionEpoch().GetVersion(), loaded.GetRegionEpoch().GetConfVer()
+	}
+
+	// A new region is written at once.
+	region := newTestRegions(1, 3)[0]
+	region.RegionEpoch = &metapb.RegionEpoch{Version: 1, ConfVer: 1}
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	version, confVer := loadVersions(region)
+	c.Assert(version, Equals, uint64(1))
+	c.Assert(confVer, Equals, uint64(1))
+
+	// The conf change is buffered.
+	region = region.clone()
+	region.RegionEpoch = &metapb.RegionEpoch{Version: 1, ConfVer: 2}
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	_, confVer = loadVersions(region)
+	c.Assert(confVer, Equals, uint64(1))
+	c.Assert(cluster.regionBuffer.changes, HasLen, 1)
+
+	// The split is written at once, and it replaces the buffered change.
+	region = region.clone()
+	region.RegionEpoch = &metapb.RegionEpoch{Version: 2, ConfVer: 2}
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	version, confVer = loadVersions(region)
+	c.Assert(version, Equals, uint64(2))
+	c.Assert(confVer, Equals, uint64(2))
+	c.Assert(cluster.regionBuffer.changes, HasLen, 0)
+}
+
+func (s *testKVSuite) TestReconcileRegions(c *C) {
+	newRegion := func(id uint64, start, end string, version uint64) *RegionInfo {
+		return newRegionInfo(&metapb.Region{
+			Id:          id,
+			StartKey:    []byte(start),
+			EndKey:      []byte(end),
+			RegionEpoch: &metapb.RegionEpoch{Version: version},
+		}, nil)
+	}
+
+	// Region 1 is split into 1 and 2, but the leader crashes before region 1
+	// is saved. Region 4 is merged into region 3, but it's not deleted.
+	regions := newRegionsInfo()
+	for _, region := range []*RegionInfo{
+		newRegion(2, "g", "m", 2),
+		newRegion(3, "m", "", 3),
+		newRegion(4, "t", "", 2),
+		newRegion(1, "", "m", 1),
+	} {
+		regions.setRegion(region)
+	}
+	stale := reconcileRegions(regions)
+	c.Assert(stale, HasLen, 2)
+	c.Assert(regions.getRegionCount(), Equals, 2)
+	c.Assert(regions.getRegion(1), IsNil)
+	c.Assert(regions.getRegion(4), IsNil)
+	c.Assert(regions.searchRegion([]byte("h")).GetId(), Equals, uint64(2))
+	c.Assert(regions.searchRegion([]byte("u")).GetId(), Equals, uint64(3))
+
+	// Nothing is removed if the regions don't overlap.
+	c.Assert(reconcileRegions(regions), HasLen, 0)
+	c.Assert(regions.getRegionCount(), Equals, 2)
+}
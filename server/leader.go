@@ -25,6 +25,7 @@ import (
 	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
 	"golang.org/x/net/context"
 )
 
@@ -37,6 +38,11 @@ func (s *Server) IsLeader() bool {
 	return atomic.LoadInt64(&s.isLeaderValue) == 1
 }
 
+// getLeaderTerm returns the term of the leader the server has campaigned.
+func (s *Server) getLeaderTerm() uint64 {
+	return atomic.LoadUint64(&s.leaderTerm)
+}
+
 func (s *Server) enableLeader(b bool) {
 	value := int64(0)
 	if b {
@@ -82,8 +88,17 @@ func (s *Server) leaderLoop() {
 			}
 		}
 
+		if s.waitLeaderTransfer() || s.waitHigherPriorityMembers() {
+			continue
+		}
 		if err = s.campaignLeader(); err != nil {
 			log.Errorf("campaign leader err %s", errors.ErrorStack(err))
+			if errors.Cause(err) == errClockJump {
+				select {
+				case <-time.After(clockJumpRetryDelay):
+				case <-s.client.Ctx().Done():
+				}
+			}
 		}
 	}
 }
@@ -144,6 +159,9 @@ func (s *Server) marshalLeader() string {
 
 func (s *Server) campaignLeader() error {
 	log.Debugf("begin to campaign leader %s", s.Name())
+	if _, ok := failpoint.Eval("campaignLeaderErr"); ok {
+		return errors.New("failpoint: campaign leader failed")
+	}
 
 	lessor := clientv3.NewLease(s.client)
 	defer lessor.Close()
@@ -173,6 +191,7 @@ func (s *Server) campaignLeader() error {
 	if !resp.Succeeded {
 		return errors.New("campaign leader failed, other server may campaign ok")
 	}
+	atomic.StoreUint64(&s.leaderTerm, uint64(resp.Header.Revision))
 
 	// Make the leader keepalived.
 	ctx, cancel = context.WithCancel(s.client.Ctx())
@@ -207,21 +226,49 @@ func (s *Server) campaignLeader() error {
 	defer s.enableLeader(false)
 
 	log.Infof("PD cluster leader %s is ready to serve", s.Name())
+	s.notifyAlert(alertLeaderChange, 0, "%s becomes the leader", s.Name())
+	s.recordClusterEvent(clusterEventLeader, "%s is elected as the leader, term %d", s.Name(), s.getLeaderTerm())
+
+	// Drop the notification for the former term.
+	select {
+	case <-s.resignCh:
+	default:
+	}
 
 	tsTicker := time.NewTicker(updateTimestampStep)
 	defer tsTicker.Stop()
-
+	priorityTicker := time.NewTicker(s.cfg.leaderPriorityCheckInterval)
+	defer priorityTicker.Stop()
+
+	// The lease may have expired if it is not renewed in time, then another
+	// server may be the leader already, so stop serving at once instead of
+	// waiting for the keep alive channel to be closed. The TTL granted by etcd
+	// may be longer than the configured lease.
+	leaseExpire := start.Add(time.Duration(leaseResp.TTL) * time.Second)
 	for {
 		select {
-		case _, ok := <-ch:
+		case keepAliveResp, ok := <-ch:
 			if !ok {
 				log.Info("keep alive channel is closed")
 				return nil
 			}
+			leaseExpire = time.Now().Add(time.Duration(keepAliveResp.TTL) * time.Second)
 		case <-tsTicker.C:
+			// The renewals may be queued while the leader is busy, the
+			// lease is expired only if there is no one left.
+			if len(ch) == 0 && time.Now().After(leaseExpire) {
+				return errors.New("leader lease is expired")
+			}
 			if err = s.updateTimestamp(); err != nil {
 				return errors.Trace(err)
 			}
+		case <-priorityTicker.C:
+			if s.checkLeaderPriority() {
+				return nil
+			}
+		case <-s.resignCh:
+			log.Infof("%s is resigned from the leader", s.Name())
+			return nil
 		case <-ctx.Done():
 			return errors.New("server closed")
 		}
@@ -278,6 +325,14 @@ func (s *Server) leaderCmp() clientv3.Cmp {
 }
 
 func (s *Server) reloadScheduleOption() error {
+	if err := s.kv.loadRules(s.scheduleOpt.rules); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.kv.loadNamespaces(s.scheduleOpt.namespaces); err != nil {
+		return errors.Trace(err)
+	}
+	// The persisted config is set by the API or the former leaders, it
+	// overrides the config file.
 	isExist, err := s.kv.loadScheduleOption(s.scheduleOpt)
 	if err != nil {
 		return errors.Trace(err)
@@ -0,0 +1,58 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+// leaderChecker transfers the leaders out of the stores rejecting leaders,
+// the balancers only avoid transferring leaders to them.
+type leaderChecker struct {
+	opt      *scheduleOption
+	cluster  *clusterInfo
+	selector Selector
+}
+
+func newLeaderChecker(opt *scheduleOption, cluster *clusterInfo) *leaderChecker {
+	var filters []Filter
+	filters = append(filters, newStateFilter(opt))
+	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
+	filters = append(filters, newRejectLeaderFilter(opt))
+	filters = append(filters, newLeaderStorageThresholdFilter(opt))
+
+	return &leaderChecker{
+		opt:      opt,
+		cluster:  cluster,
+		selector: newBalanceSelector(LeaderKind, opt, filters),
+	}
+}
+
+// Check returns an operator to transfer the leader to the follower with the
+// least leaders if the leader store of the region rejects leaders.
+func (l *leaderChecker) Check(region *RegionInfo) Operator {
+	if region.Leader == nil || !isHealthyRegion(region) {
+		return nil
+	}
+	store := l.cluster.GetStore(region.Leader.GetStoreId())
+	if store == nil || !l.opt.CheckLabelProperty(RejectLeader, store.GetLabels()) {
+		return nil
+	}
+	target := l.selector.SelectTarget(l.cluster.GetFollowerStores(region))
+	if target == nil {
+		return nil
+	}
+	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
+}
+
+func isHealthyRegion(region *RegionInfo) bool {
+	return len(region.DownPeers) == 0 && len(region.PendingPeers) == 0
+}
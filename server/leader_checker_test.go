@@ -0,0 +1,60 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testLeaderCheckerSuite{})
+
+type testLeaderCheckerSuite struct{}
+
+func (s *testLeaderCheckerSuite) TestRejectLeader(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	cfg, opt := newTestScheduleConfig()
+	lc := newLeaderChecker(opt, cluster)
+
+	tc.addLabelsLeaderStore(1, 1, map[string]string{"zone": "z1"})
+	tc.addLabelsLeaderStore(2, 2, map[string]string{"zone": "z2"})
+	tc.addLabelsLeaderStore(3, 1, map[string]string{"zone": "z3"})
+	tc.addLeaderRegion(1, 1, 2, 3)
+	region := cluster.GetRegion(1)
+	c.Assert(lc.Check(region), IsNil)
+
+	// The leader is transferred to the follower with the least leaders.
+	cfg.LabelProperty = LabelPropertyConfig{
+		RejectLeader: {{Key: "zone", Value: "z1"}, {Key: "zone", Value: "z4"}},
+	}
+	checkTransferLeader(c, lc.Check(region), 1, 3)
+	tc.setStoreDown(3)
+	checkTransferLeader(c, lc.Check(region), 1, 2)
+	tc.setStoreUp(3)
+
+	// The followers rejecting leaders are not the targets.
+	cfg.LabelProperty[RejectLeader] = append(cfg.LabelProperty[RejectLeader], StoreLabel{Key: "zone", Value: "z3"})
+	checkTransferLeader(c, lc.Check(region), 1, 2)
+	cfg.LabelProperty[RejectLeader] = append(cfg.LabelProperty[RejectLeader], StoreLabel{Key: "zone", Value: "z2"})
+	c.Assert(lc.Check(region), IsNil)
+	cfg.LabelProperty[RejectLeader] = cfg.LabelProperty[RejectLeader][:2]
+
+	// The unhealthy regions are not checked.
+	region.PendingPeers = region.GetPeers()[1:2]
+	c.Assert(lc.Check(region), IsNil)
+	region.PendingPeers = nil
+	region.DownPeers = []*pdpb.PeerStats{{Peer: region.GetPeers()[2]}}
+	c.Assert(lc.Check(region), IsNil)
+}
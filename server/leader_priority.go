@@ -0,0 +1,160 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultLeaderPriorityCheckInterval = time.Minute
+	memberHealthCheckTimeout           = 3 * time.Second
+)
+
+func (s *Server) getMemberLeaderPriorityPath(id uint64) string {
+	return path.Join(s.rootPath, "member", fmt.Sprintf("%d", id), "leader_priority")
+}
+
+// SetMemberLeaderPriority sets the leader priority of a member. The leader
+// hands the leadership over to a healthy member with higher priority, so the
+// leadership returns to the preferred member after it recovers. The priority
+// of a member is 0 by default.
+func (s *Server) SetMemberLeaderPriority(id uint64, priority int) error {
+	key := s.getMemberLeaderPriorityPath(id)
+	resp, err := s.leaderTxn().Then(clientv3.OpPut(key, strconv.Itoa(priority))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("save leader priority failed, maybe we are not leader")
+	}
+	log.Infof("leader priority of member %d is set to %d", id, priority)
+	return nil
+}
+
+// DeleteMemberLeaderPriority resets the leader priority of a member to 0.
+func (s *Server) DeleteMemberLeaderPriority(id uint64) error {
+	key := s.getMemberLeaderPriorityPath(id)
+	resp, err := s.leaderTxn().Then(clientv3.OpDelete(key)).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("delete leader priority failed, maybe we are not leader")
+	}
+	return nil
+}
+
+// GetMemberLeaderPriority returns the leader priority of a member.
+func (s *Server) GetMemberLeaderPriority(id uint64) (int, error) {
+	value, err := getValue(s.client, s.getMemberLeaderPriorityPath(id))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if value == nil {
+		return 0, nil
+	}
+	priority, err := strconv.Atoi(string(value))
+	return priority, errors.Trace(err)
+}
+
+// getHigherPriorityMembers returns the other members with higher leader
+// priority than the server.
+func (s *Server) getHigherPriorityMembers() ([]*pdpb.Member, error) {
+	priority, err := s.GetMemberLeaderPriority(s.ID())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	members, err := GetMembers(s.client)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var higher []*pdpb.Member
+	for _, m := range members {
+		if m.GetMemberId() == s.ID() {
+			continue
+		}
+		p, err := s.GetMemberLeaderPriority(m.GetMemberId())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if p > priority {
+			higher = append(higher, m)
+		}
+	}
+	return higher, nil
+}
+
+// isMemberHealthy checks whether the member serves gRPC requests.
+func (s *Server) isMemberHealthy(member *pdpb.Member) bool {
+	if len(member.GetClientUrls()) == 0 {
+		return false
+	}
+	conn, err := s.getOrCreateGRPCConn(member.GetClientUrls()[0])
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), memberHealthCheckTimeout)
+	defer cancel()
+	_, err = pdpb.NewPDClient(conn).GetMembers(ctx, &pdpb.GetMembersRequest{Header: &pdpb.RequestHeader{ClusterId: s.clusterID}})
+	return err == nil
+}
+
+// checkLeaderPriority resigns the leadership if there is a healthy member
+// with higher leader priority, it returns true if the leader is resigned.
+func (s *Server) checkLeaderPriority() bool {
+	members, err := s.getHigherPriorityMembers()
+	if err != nil {
+		log.Errorf("get leader priorities meet error: %v", err)
+		return false
+	}
+	for _, m := range members {
+		if !s.isMemberHealthy(m) {
+			continue
+		}
+		log.Infof("member %s has higher leader priority, resign leader", m.GetName())
+		if err = s.resignLeader(); err != nil {
+			log.Errorf("resign leader meet error: %v", err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// waitHigherPriorityMembers gives the members with higher leader priority a
+// lease to campaign first, it returns true if a leader is elected in the
+// meantime.
+func (s *Server) waitHigherPriorityMembers() bool {
+	members, err := s.getHigherPriorityMembers()
+	if err != nil || len(members) == 0 {
+		return false
+	}
+	select {
+	case <-time.After(time.Duration(s.cfg.LeaderLease) * time.Second):
+	case <-s.client.Ctx().Done():
+		return false
+	}
+	leader, err := getLeader(s.client, s.getLeaderPath())
+	return err == nil && leader != nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLeaderPrioritySuite{})
+
+type testLeaderPrioritySuite struct{}
+
+func (s *testLeaderPrioritySuite) TestLeaderPriority(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.leaderPriorityCheckInterval = 100 * time.Millisecond
+	}
+	svrs, cleanup := newTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	priority, err := leader.GetMemberLeaderPriority(follower.ID())
+	c.Assert(err, IsNil)
+	c.Assert(priority, Equals, 0)
+	c.Assert(leader.SetMemberLeaderPriority(follower.ID(), 10), IsNil)
+	priority, err = follower.GetMemberLeaderPriority(follower.ID())
+	c.Assert(err, IsNil)
+	c.Assert(priority, Equals, 10)
+
+	// The leadership is handed over to the member with higher priority.
+	for i := 0; i < 100 && !follower.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(follower.IsLeader(), IsTrue)
+	c.Assert(leader.IsLeader(), IsFalse)
+
+	// Only the leader can set the priorities.
+	c.Assert(leader.SetMemberLeaderPriority(follower.ID(), 0), NotNil)
+	c.Assert(follower.DeleteMemberLeaderPriority(follower.ID()), IsNil)
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+// leaderTransfer is saved with a lease when the leader resigns, the members
+// except the one it is transferred to wait the lease before campaigning. A
+// resigned leader without the target waits the lease itself, so the other
+// members campaign first.
+type leaderTransfer struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+func (s *Server) getLeaderTransferPath() string {
+	return path.Join(s.rootPath, "leader_transfer")
+}
+
+// ResignLeader resigns the leadership. If nextLeader is not empty, the
+// leadership is transferred to the member with the name, but it may be handed
+// over again to the members with higher leader priority later.
+func (s *Server) ResignLeader(nextLeader string) error {
+	if !s.IsLeader() {
+		return errors.New("resign leader failed, we are not leader")
+	}
+	transfer := &leaderTransfer{From: s.ID()}
+	if nextLeader != "" {
+		next, err := s.getMemberByName(nextLeader)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if next.GetMemberId() == s.ID() {
+			return errors.Errorf("%s is the leader already", nextLeader)
+		}
+		if !s.isMemberHealthy(next) {
+			return errors.Errorf("member %s is not healthy", nextLeader)
+		}
+		transfer.To = next.GetMemberId()
+	}
+	value, err := json.Marshal(transfer)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), requestTimeout)
+	leaseResp, err := s.client.Grant(ctx, s.cfg.LeaderLease)
+	cancel()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp, err := s.leaderTxn().
+		Then(clientv3.OpPut(s.getLeaderTransferPath(), string(value), clientv3.WithLease(leaseResp.ID)),
+			clientv3.OpDelete(s.getLeaderPath())).
+		Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("resign leader failed, we are not leader already")
+	}
+	log.Infof("%s resigns the leader, next leader: %q", s.Name(), nextLeader)
+
+	select {
+	case s.resignCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *Server) getMemberByName(name string) (*pdpb.Member, error) {
+	members, err := GetMembers(s.client)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, m := range members {
+		if m.GetName() == name {
+			return m, nil
+		}
+	}
+	return nil, errors.Errorf("member %s not found", name)
+}
+
+// waitLeaderTransfer waits the lease of the leader transfer if the server
+// should not campaign first, it returns true if a leader is elected in the
+// meantime.
+func (s *Server) waitLeaderTransfer() bool {
+	value, err := getValue(s.client, s.getLeaderTransferPath())
+	if err != nil || value == nil {
+		return false
+	}
+	transfer := &leaderTransfer{}
+	if err = json.Unmarshal(value, transfer); err != nil {
+		log.Errorf("unmarshal leader transfer meet error: %v", err)
+		return false
+	}
+	if transfer.To == s.ID() || (transfer.To == 0 && transfer.From != s.ID()) {
+		return false
+	}
+	select {
+	case <-time.After(time.Duration(s.cfg.LeaderLease) * time.Second):
+	case <-s.client.Ctx().Done():
+		return false
+	}
+	leader, err := getLeader(s.client, s.getLeaderPath())
+	return err == nil && leader != nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLeaderTransferSuite{})
+
+type testLeaderTransferSuite struct{}
+
+func waitNewLeader(c *C, svrs []*Server, old *Server) *Server {
+	for i := 0; i < 100; i++ {
+		for _, svr := range svrs {
+			if svr != old && svr.IsLeader() {
+				return svr
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Fatal("no new leader")
+	return nil
+}
+
+func (s *testLeaderTransferSuite) TestResignLeader(c *C) {
+	svrs, cleanup := newTestServersWithCfgs(c, NewTestMultiConfig(3))
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	c.Assert(follower.ResignLeader(""), NotNil)
+	c.Assert(leader.ResignLeader("unknown"), NotNil)
+	c.Assert(leader.ResignLeader(leader.Name()), NotNil)
+
+	// The leadership is transferred to the follower.
+	c.Assert(leader.ResignLeader(follower.Name()), IsNil)
+	c.Assert(waitNewLeader(c, svrs, leader), Equals, follower)
+	c.Assert(leader.IsLeader(), IsFalse)
+
+	// The resigned leader doesn't campaign first.
+	c.Assert(follower.ResignLeader(""), IsNil)
+	c.Assert(waitNewLeader(c, svrs, follower), Not(Equals), follower)
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+// MemberInfo is the info of the binary of a member. Each member saves its own
+// info when it starts, so the info of all members can be got from any one.
+type MemberInfo struct {
+	DeployPath     string `json:"deploy_path"`
+	BinaryVersion  string `json:"binary_version"`
+	GitHash        string `json:"git_hash"`
+	StartTimestamp int64  `json:"start_timestamp"`
+}
+
+// Member is a member with the info of its binary. The info is not a part of
+// pdpb.Member, so it's only shown by the HTTP API.
+type Member struct {
+	*pdpb.Member
+	MemberInfo
+}
+
+func (s *Server) getMemberInfoPath(id uint64) string {
+	return path.Join(s.rootPath, "member", fmt.Sprintf("%d", id), "info")
+}
+
+// saveMemberInfo saves the info of the server. It's not saved by the leader
+// transaction as every member saves its own info.
+func (s *Server) saveMemberInfo() error {
+	info := MemberInfo{
+		BinaryVersion:  PDReleaseVersion,
+		GitHash:        PDGitHash,
+		StartTimestamp: s.startTimestamp,
+	}
+	if binary, err := filepath.Abs(os.Args[0]); err == nil {
+		info.DeployPath = filepath.Dir(binary)
+	}
+	value, err := json.Marshal(info)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), requestTimeout)
+	defer cancel()
+	_, err = s.client.Put(ctx, s.getMemberInfoPath(s.ID()), string(value))
+	return errors.Trace(err)
+}
+
+// DeleteMemberInfo deletes the info of a member, it's called once the member
+// is removed.
+func (s *Server) DeleteMemberInfo(id uint64) error {
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), requestTimeout)
+	defer cancel()
+	_, err := s.client.Delete(ctx, s.getMemberInfoPath(id))
+	return errors.Trace(err)
+}
+
+// GetMemberList returns the members with the info of their binaries, so the
+// version skew of the members can be found, e.g. after a rolling upgrade. The
+// info is empty if the member has not saved it, e.g. it runs an old version.
+func (s *Server) GetMemberList() ([]*Member, error) {
+	pbMembers, err := GetMembers(s.client)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	members := make([]*Member, 0, len(pbMembers))
+	for _, pbMember := range pbMembers {
+		m := &Member{Member: pbMember}
+		members = append(members, m)
+		value, err := getValue(s.client, s.getMemberInfoPath(m.GetMemberId()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if value == nil {
+			continue
+		}
+		// A corrupt info doesn't fail the whole list, it's overwritten once
+		// the member restarts.
+		var info MemberInfo
+		if err = json.Unmarshal(value, &info); err != nil {
+			log.Warnf("member %d has corrupt info %q: %v", m.GetMemberId(), value, err)
+			continue
+		}
+		m.MemberInfo = info
+	}
+	return members, nil
+}
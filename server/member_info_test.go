@@ -0,0 +1,50 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testMemberInfoSuite{})
+
+type testMemberInfoSuite struct{}
+
+func (s *testMemberInfoSuite) TestMemberInfo(c *C) {
+	svr, cleanup := newTestServer(c)
+	defer cleanup()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	members, err := svr.GetMemberList()
+	c.Assert(err, IsNil)
+	c.Assert(members, HasLen, 1)
+	c.Assert(members[0].GitHash, Equals, PDGitHash)
+	c.Assert(members[0].StartTimestamp, Equals, svr.startTimestamp)
+
+	// The corrupt info is skipped.
+	_, err = svr.client.Put(context.Background(), svr.getMemberInfoPath(svr.ID()), "corrupt")
+	c.Assert(err, IsNil)
+	members, err = svr.GetMemberList()
+	c.Assert(err, IsNil)
+	c.Assert(members, HasLen, 1)
+	c.Assert(members[0].StartTimestamp, Equals, int64(0))
+
+	c.Assert(svr.saveMemberInfo(), IsNil)
+	c.Assert(svr.DeleteMemberInfo(svr.ID()), IsNil)
+	value, err := getValue(svr.client, svr.getMemberInfoPath(svr.ID()))
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+}
@@ -13,7 +13,11 @@
 
 package server
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 var (
 	txnCounter = prometheus.NewCounterVec(
@@ -41,6 +45,32 @@ var (
 			Help:      "Counter of schedule operators.",
 		}, []string{"type", "state"})
 
+	operatorEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_events_total",
+			Help:      "Counter of the operators created and ended by the schedulers and checkers, an ended operator is counted by its state, e.g. timeout or stale.",
+		}, []string{"source", "kind", "event"})
+
+	operatorDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_duration_seconds",
+			Help:      "Bucketed histogram of the time (s) from an operator is added to it ends.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		}, []string{"source", "kind", "state"})
+
+	operatorStepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_step_duration_seconds",
+			Help:      "Bucketed histogram of the time (s) an operator step takes.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		}, []string{"type"})
+
 	clusterStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -57,6 +87,14 @@ var (
 			Help:      "Counter of system time jumps backward.",
 		})
 
+	tsoClockJumpCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "clock_jump_total",
+			Help:      "Counter of the clock jumps which stop serving the TSO.",
+		}, []string{"type"})
+
 	schedulerStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -72,14 +110,132 @@ var (
 			Name:      "status",
 			Help:      "Status of the hotspot.",
 		}, []string{"store", "type"})
+
+	regionHeartbeatCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "region_heartbeat_total",
+			Help:      "Counter of the region heartbeats received from the stores.",
+		}, []string{"store", "status"})
+
+	regionHeartbeatDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "region_heartbeat_duration_seconds",
+			Help:      "Bucketed histogram of the time (s) handling a region heartbeat takes.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, []string{"store"})
+
+	regionHeartbeatUpdateCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_heartbeat_updates_total",
+			Help:      "Counter of the region heartbeats by how the region is updated.",
+		}, []string{"type"})
+
+	regionFlushCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_flushed_total",
+			Help:      "Counter of the region meta changes flushed to the storage.",
+		})
+
+	regionFlushFailedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_flush_failed_total",
+			Help:      "Counter of the failed flushes of the region meta changes.",
+		})
+
+	storeHeartbeatCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "store_heartbeat_total",
+			Help:      "Counter of the store heartbeats received from the stores.",
+		}, []string{"store", "status"})
+
+	storeHeartbeatDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "store_heartbeat_duration_seconds",
+			Help:      "Bucketed histogram of the time (s) handling a store heartbeat takes.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, []string{"store"})
+
+	regionHeartbeatStreamGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "region_heartbeat_streams",
+			Help:      "Number of the region heartbeat streams of the stores.",
+		}, []string{"store"})
+
+	etcdStatusGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "etcd_status",
+			Help:      "Status of the embedded etcd.",
+		}, []string{"type"})
+)
+
+// The status labels of the heartbeat counters.
+const (
+	heartbeatReport = "report"
+	heartbeatOK     = "ok"
+	heartbeatError  = "error"
 )
 
+func storeLabel(storeID uint64) string {
+	return fmt.Sprintf("store_%d", storeID)
+}
+
+// clearStoreMetrics removes the metrics of a removed store.
+func clearStoreMetrics(storeID uint64) {
+	store := storeLabel(storeID)
+	for _, typ := range []string{
+		"total_written_bytes_as_peer",
+		"hot_write_region_as_peer",
+		"total_written_bytes_as_leader",
+		"hot_write_region_as_leader",
+	} {
+		hotSpotStatusGauge.DeleteLabelValues(store, typ)
+	}
+	for _, status := range []string{heartbeatReport, heartbeatOK, heartbeatError} {
+		regionHeartbeatCounter.DeleteLabelValues(store, status)
+		storeHeartbeatCounter.DeleteLabelValues(store, status)
+	}
+	regionHeartbeatDuration.DeleteLabelValues(store)
+	storeHeartbeatDuration.DeleteLabelValues(store)
+	regionHeartbeatStreamGauge.DeleteLabelValues(store)
+}
+
 func init() {
 	prometheus.MustRegister(txnCounter)
 	prometheus.MustRegister(txnDuration)
 	prometheus.MustRegister(operatorCounter)
+	prometheus.MustRegister(operatorEventCounter)
+	prometheus.MustRegister(operatorDuration)
+	prometheus.MustRegister(operatorStepDuration)
 	prometheus.MustRegister(clusterStatusGauge)
 	prometheus.MustRegister(timeJumpBackCounter)
+	prometheus.MustRegister(tsoClockJumpCounter)
 	prometheus.MustRegister(schedulerStatusGauge)
 	prometheus.MustRegister(hotSpotStatusGauge)
+	prometheus.MustRegister(regionHeartbeatCounter)
+	prometheus.MustRegister(regionHeartbeatDuration)
+	prometheus.MustRegister(regionHeartbeatUpdateCounter)
+	prometheus.MustRegister(regionFlushCounter)
+	prometheus.MustRegister(regionFlushFailedCounter)
+	prometheus.MustRegister(storeHeartbeatCounter)
+	prometheus.MustRegister(storeHeartbeatDuration)
+	prometheus.MustRegister(regionHeartbeatStreamGauge)
+	prometheus.MustRegister(etcdStatusGauge)
 }
@@ -0,0 +1,323 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/codec"
+)
+
+// DefaultNamespace is the namespace of the tables and stores not bound to any
+// namespace.
+const DefaultNamespace = "global"
+
+// Namespace binds the regions of some tables to a set of stores, the regions
+// of the tables are only scheduled to the stores of the namespace, and the
+// stores only hold the regions of the namespace.
+//
+// MaxReplicas and the schedule limits override the global config for the
+// regions of the namespace, 0 means the global one is used. The operators of
+// a namespace overriding a schedule limit are limited by their own count, so
+// a busy namespace is throttled independently of the others.
+type Namespace struct {
+	Name     string   `json:"name"`
+	TableIDs []int64  `json:"table_ids,omitempty"`
+	StoreIDs []uint64 `json:"store_ids,omitempty"`
+
+	MaxReplicas          uint64 `json:"max_replicas,omitempty"`
+	LeaderScheduleLimit  uint64 `json:"leader_schedule_limit,omitempty"`
+	RegionScheduleLimit  uint64 `json:"region_schedule_limit,omitempty"`
+	ReplicaScheduleLimit uint64 `json:"replica_schedule_limit,omitempty"`
+}
+
+func (n *Namespace) clone() *Namespace {
+	return &Namespace{
+		Name:                 n.Name,
+		TableIDs:             append([]int64(nil), n.TableIDs...),
+		StoreIDs:             append([]uint64(nil), n.StoreIDs...),
+		MaxReplicas:          n.MaxReplicas,
+		LeaderScheduleLimit:  n.LeaderScheduleLimit,
+		RegionScheduleLimit:  n.RegionScheduleLimit,
+		ReplicaScheduleLimit: n.ReplicaScheduleLimit,
+	}
+}
+
+// getScheduleLimit returns the schedule limit of the operators of the kind, 0
+// means the global one is used.
+func (n *Namespace) getScheduleLimit(kind ResourceKind) uint64 {
+	switch kind {
+	case LeaderKind:
+		return n.LeaderScheduleLimit
+	case RegionKind:
+		return n.RegionScheduleLimit
+	case ReplicaKind, PriorityKind:
+		return n.ReplicaScheduleLimit
+	}
+	return 0
+}
+
+// namespacesInfo classifies the regions and stores into namespaces, the
+// namespaces are persisted by kv.
+type namespacesInfo struct {
+	sync.RWMutex
+	namespaces map[string]*Namespace
+	tables     map[int64]string
+	stores     map[uint64]string
+}
+
+func newNamespacesInfo() *namespacesInfo {
+	return &namespacesInfo{
+		namespaces: make(map[string]*Namespace),
+		tables:     make(map[int64]string),
+		stores:     make(map[uint64]string),
+	}
+}
+
+// getNamespaces returns all namespaces sorted by name.
+func (n *namespacesInfo) getNamespaces() []*Namespace {
+	n.RLock()
+	defer n.RUnlock()
+	namespaces := make([]*Namespace, 0, len(n.namespaces))
+	for _, ns := range n.namespaces {
+		namespaces = append(namespaces, ns.clone())
+	}
+	sort.Sort(namespacesByName(namespaces))
+	return namespaces
+}
+
+// getNamespaceNames returns the names of all namespaces, including the
+// default one.
+func (n *namespacesInfo) getNamespaceNames() []string {
+	n.RLock()
+	defer n.RUnlock()
+	names := make([]string, 0, len(n.namespaces)+1)
+	names = append(names, DefaultNamespace)
+	for name := range n.namespaces {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (n *namespacesInfo) getNamespace(name string) *Namespace {
+	n.RLock()
+	defer n.RUnlock()
+	if ns, ok := n.namespaces[name]; ok {
+		return ns.clone()
+	}
+	return nil
+}
+
+// checkNamespace checks that the namespace is valid, and its tables and
+// stores are not in other namespaces.
+func (n *namespacesInfo) checkNamespace(ns *Namespace) error {
+	if ns.Name == "" || ns.Name == DefaultNamespace || strings.Contains(ns.Name, "/") {
+		return errors.Errorf("invalid namespace name %q", ns.Name)
+	}
+	n.RLock()
+	defer n.RUnlock()
+	for _, id := range ns.TableIDs {
+		if name, ok := n.tables[id]; ok && name != ns.Name {
+			return errors.Errorf("table %d is in namespace %s", id, name)
+		}
+	}
+	for _, id := range ns.StoreIDs {
+		if name, ok := n.stores[id]; ok && name != ns.Name {
+			return errors.Errorf("store %d is in namespace %s", id, name)
+		}
+	}
+	return nil
+}
+
+// setNamespace adds or replaces a namespace, the namespace must be checked.
+func (n *namespacesInfo) setNamespace(ns *Namespace) {
+	n.Lock()
+	defer n.Unlock()
+	n.deleteNamespaceLocked(ns.Name)
+	ns = ns.clone()
+	n.namespaces[ns.Name] = ns
+	for _, id := range ns.TableIDs {
+		n.tables[id] = ns.Name
+	}
+	for _, id := range ns.StoreIDs {
+		n.stores[id] = ns.Name
+	}
+}
+
+func (n *namespacesInfo) deleteNamespace(name string) {
+	n.Lock()
+	defer n.Unlock()
+	n.deleteNamespaceLocked(name)
+}
+
+func (n *namespacesInfo) deleteNamespaceLocked(name string) {
+	ns, ok := n.namespaces[name]
+	if !ok {
+		return
+	}
+	for _, id := range ns.TableIDs {
+		delete(n.tables, id)
+	}
+	for _, id := range ns.StoreIDs {
+		delete(n.stores, id)
+	}
+	delete(n.namespaces, name)
+}
+
+// getRegionNamespace returns the namespace of the table the region starts in.
+func (n *namespacesInfo) getRegionNamespace(region *RegionInfo) string {
+	n.RLock()
+	defer n.RUnlock()
+	return n.getRegionNamespaceLocked(region)
+}
+
+func (n *namespacesInfo) getRegionNamespaceLocked(region *RegionInfo) string {
+	if len(n.tables) == 0 {
+		return DefaultNamespace
+	}
+	tableID := codec.DecodeTableID(region.GetStartKey())
+	if name, ok := n.tables[tableID]; ok {
+		return name
+	}
+	return DefaultNamespace
+}
+
+// getMaxReplicas returns max-replicas of the namespace of the region, or the
+// global one if the namespace doesn't override it.
+func (n *namespacesInfo) getMaxReplicas(region *RegionInfo, global int) int {
+	n.RLock()
+	defer n.RUnlock()
+	if ns, ok := n.namespaces[n.getRegionNamespaceLocked(region)]; ok && ns.MaxReplicas > 0 {
+		return int(ns.MaxReplicas)
+	}
+	return global
+}
+
+// getScheduleLimit returns the schedule limit of the kind of the namespace, 0
+// means the namespace doesn't override the global one.
+func (n *namespacesInfo) getScheduleLimit(name string, kind ResourceKind) uint64 {
+	n.RLock()
+	defer n.RUnlock()
+	if ns, ok := n.namespaces[name]; ok {
+		return ns.getScheduleLimit(kind)
+	}
+	return 0
+}
+
+func (n *namespacesInfo) getStoreNamespace(store *StoreInfo) string {
+	n.RLock()
+	defer n.RUnlock()
+	if name, ok := n.stores[store.GetId()]; ok {
+		return name
+	}
+	return DefaultNamespace
+}
+
+type namespacesByName []*Namespace
+
+func (n namespacesByName) Len() int           { return len(n) }
+func (n namespacesByName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n namespacesByName) Less(i, j int) bool { return n[i].Name < n[j].Name }
+
+// namespaceFilter filters the stores not in the namespace.
+type namespaceFilter struct {
+	namespaces *namespacesInfo
+	namespace  string
+}
+
+func newNamespaceFilter(namespaces *namespacesInfo, namespace string) *namespaceFilter {
+	return &namespaceFilter{
+		namespaces: namespaces,
+		namespace:  namespace,
+	}
+}
+
+func (f *namespaceFilter) FilterSource(store *StoreInfo) bool {
+	return f.namespaces.getStoreNamespace(store) != f.namespace
+}
+
+func (f *namespaceFilter) FilterTarget(store *StoreInfo) bool {
+	return f.namespaces.getStoreNamespace(store) != f.namespace
+}
+
+// newRegionNamespaceFilter filters the stores not in the namespace of the region.
+func newRegionNamespaceFilter(opt ScheduleOptions, region *RegionInfo) *namespaceFilter {
+	return newNamespaceFilter(opt.getNamespaces(), opt.getNamespaces().getRegionNamespace(region))
+}
+
+// namespaceCluster is the part of the cluster in a namespace, it only has the
+// stores of the namespace, and the regions of the namespace are selected from
+// them, so the balance schedulers balance the stores with the others in the
+// same namespace.
+type namespaceCluster struct {
+	Cluster
+	namespaces *namespacesInfo
+	namespace  string
+}
+
+// getNamespaceClusters splits the cluster by the namespaces in a random
+// order, so no namespace is always scheduled first. The cluster is not split
+// if there is only the default namespace.
+func getNamespaceClusters(cluster Cluster, namespaces *namespacesInfo) []Cluster {
+	names := namespaces.getNamespaceNames()
+	if len(names) == 1 {
+		return []Cluster{cluster}
+	}
+	clusters := make([]Cluster, 0, len(names))
+	for _, i := range rand.Perm(len(names)) {
+		clusters = append(clusters, &namespaceCluster{
+			Cluster:    cluster,
+			namespaces: namespaces,
+			namespace:  names[i],
+		})
+	}
+	return clusters
+}
+
+func (c *namespaceCluster) filterStores(stores []*StoreInfo) []*StoreInfo {
+	filtered := make([]*StoreInfo, 0, len(stores))
+	for _, s := range stores {
+		if c.namespaces.getStoreNamespace(s) == c.namespace {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func (c *namespaceCluster) checkRegion(region *RegionInfo) *RegionInfo {
+	if region == nil || c.namespaces.getRegionNamespace(region) != c.namespace {
+		return nil
+	}
+	return region
+}
+
+func (c *namespaceCluster) GetStores() []*StoreInfo {
+	return c.filterStores(c.Cluster.GetStores())
+}
+
+func (c *namespaceCluster) GetFollowerStores(region *RegionInfo) []*StoreInfo {
+	return c.filterStores(c.Cluster.GetFollowerStores(region))
+}
+
+func (c *namespaceCluster) RandLeaderRegion(storeID uint64) *RegionInfo {
+	return c.checkRegion(c.Cluster.RandLeaderRegion(storeID))
+}
+
+func (c *namespaceCluster) RandFollowerRegion(storeID uint64) *RegionInfo {
+	return c.checkRegion(c.Cluster.RandFollowerRegion(storeID))
+}
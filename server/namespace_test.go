@@ -0,0 +1,145 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/codec"
+)
+
+var _ = Suite(&testNamespaceSuite{})
+
+type testNamespaceSuite struct{}
+
+func newTableRegion(regionID uint64, tableID int64, storeIDs ...uint64) *RegionInfo {
+	region := &metapb.Region{
+		Id:       regionID,
+		StartKey: codec.GenerateTableKey(tableID),
+		EndKey:   codec.GenerateTableKey(tableID + 1),
+	}
+	for i, storeID := range storeIDs {
+		region.Peers = append(region.Peers, &metapb.Peer{Id: regionID*100 + uint64(i), StoreId: storeID})
+	}
+	return newRegionInfo(region, region.Peers[0])
+}
+
+func (s *testNamespaceSuite) TestClassify(c *C) {
+	namespaces := newNamespacesInfo()
+	ns1 := &Namespace{Name: "ns1", TableIDs: []int64{1, 2}, StoreIDs: []uint64{1}}
+	c.Assert(namespaces.checkNamespace(ns1), IsNil)
+	namespaces.setNamespace(ns1)
+
+	c.Assert(namespaces.getRegionNamespace(newTableRegion(1, 1, 1)), Equals, "ns1")
+	c.Assert(namespaces.getRegionNamespace(newTableRegion(2, 3, 1)), Equals, DefaultNamespace)
+	c.Assert(namespaces.getStoreNamespace(newStoreInfo(&metapb.Store{Id: 1})), Equals, "ns1")
+	c.Assert(namespaces.getStoreNamespace(newStoreInfo(&metapb.Store{Id: 2})), Equals, DefaultNamespace)
+
+	// Tables and stores can't be in two namespaces.
+	c.Assert(namespaces.checkNamespace(&Namespace{Name: "ns2", TableIDs: []int64{2}}), NotNil)
+	c.Assert(namespaces.checkNamespace(&Namespace{Name: "ns2", StoreIDs: []uint64{1}}), NotNil)
+	c.Assert(namespaces.checkNamespace(&Namespace{Name: DefaultNamespace}), NotNil)
+	c.Assert(namespaces.checkNamespace(&Namespace{Name: ""}), NotNil)
+
+	// Replace the namespace.
+	ns1 = &Namespace{Name: "ns1", TableIDs: []int64{2}, StoreIDs: []uint64{2}}
+	c.Assert(namespaces.checkNamespace(ns1), IsNil)
+	namespaces.setNamespace(ns1)
+	c.Assert(namespaces.getRegionNamespace(newTableRegion(1, 1, 1)), Equals, DefaultNamespace)
+	c.Assert(namespaces.getStoreNamespace(newStoreInfo(&metapb.Store{Id: 1})), Equals, DefaultNamespace)
+	c.Assert(namespaces.getNamespaces(), HasLen, 1)
+
+	namespaces.deleteNamespace("ns1")
+	c.Assert(namespaces.getRegionNamespace(newTableRegion(2, 2, 1)), Equals, DefaultNamespace)
+	c.Assert(namespaces.getNamespace("ns1"), IsNil)
+}
+
+func (s *testNamespaceSuite) TestOverride(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3)
+	rc := newReplicaChecker(opt, cluster)
+
+	for id := uint64(1); id <= 5; id++ {
+		tc.addRegionStore(id, int(id))
+	}
+	ns1 := &Namespace{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1, 2, 3, 4, 5}, MaxReplicas: 5, LeaderScheduleLimit: 8, ReplicaScheduleLimit: 16}
+	c.Assert(opt.namespaces.checkNamespace(ns1), IsNil)
+	opt.namespaces.setNamespace(ns1)
+
+	region := newTableRegion(1, 1, 1, 2, 3)
+	c.Assert(cluster.putRegion(region), IsNil)
+	c.Assert(opt.getRegionMaxReplicas(region), Equals, 5)
+	c.Assert(opt.namespaces.getScheduleLimit("ns1", ReplicaKind), Equals, uint64(16))
+	c.Assert(opt.namespaces.getScheduleLimit("ns1", PriorityKind), Equals, uint64(16))
+	c.Assert(opt.namespaces.getScheduleLimit("ns1", LeaderKind), Equals, uint64(8))
+	c.Assert(opt.namespaces.getScheduleLimit("ns1", RegionKind), Equals, uint64(0))
+	checkAddPeer(c, rc.Check(region), 4)
+
+	// The other regions use the global config.
+	other := newTableRegion(2, 2, 1, 2, 3)
+	c.Assert(opt.getRegionMaxReplicas(other), Equals, 3)
+	c.Assert(opt.namespaces.getRegionNamespace(other), Equals, DefaultNamespace)
+	c.Assert(opt.namespaces.getScheduleLimit(DefaultNamespace, ReplicaKind), Equals, uint64(0))
+
+	ns1.MaxReplicas = 0
+	opt.namespaces.setNamespace(ns1)
+	c.Assert(opt.getRegionMaxReplicas(region), Equals, 3)
+	c.Assert(rc.Check(region), IsNil)
+}
+
+func (s *testNamespaceSuite) TestScheduleLimit(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	cfg, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3)
+	cfg.LeaderScheduleLimit = 0
+	cfg.RegionScheduleLimit = 0
+	co := newCoordinator(cluster, opt)
+
+	for id := uint64(1); id <= 6; id++ {
+		tc.addRegionStore(id, int(id))
+	}
+	ns1 := &Namespace{Name: "ns1", TableIDs: []int64{1, 2}, StoreIDs: []uint64{1, 2, 3}, ReplicaScheduleLimit: 1}
+	c.Assert(opt.namespaces.checkNamespace(ns1), IsNil)
+	opt.namespaces.setNamespace(ns1)
+	regions := []*RegionInfo{
+		newTableRegion(1, 1, 1, 2),
+		newTableRegion(2, 2, 1, 2),
+		newTableRegion(3, 3, 4, 5),
+	}
+	for _, region := range regions {
+		c.Assert(cluster.putRegion(region), IsNil)
+	}
+
+	// Only one operator of ns1 runs, the others are not blocked.
+	checkAddPeerResp(c, co.dispatch(regions[0]), 3)
+	c.Assert(co.dispatch(regions[1]), IsNil)
+	checkAddPeerResp(c, co.dispatch(regions[2]), 6)
+	c.Assert(co.limiter.namespaceOperatorCount("ns1", ReplicaKind), Equals, uint64(1))
+	c.Assert(co.limiter.namespaceOperatorCount(DefaultNamespace, ReplicaKind), Equals, uint64(1))
+
+	co.removeOperator(co.getOperator(1))
+	c.Assert(co.limiter.namespaceOperatorCount("ns1", ReplicaKind), Equals, uint64(0))
+	checkAddPeerResp(c, co.dispatch(regions[1]), 3)
+
+	// The global limit is enforced even if the namespace overrides it.
+	ns1.ReplicaScheduleLimit = 8
+	opt.namespaces.setNamespace(ns1)
+	cfg.ReplicaScheduleLimit = 2
+	c.Assert(co.dispatch(regions[0]), IsNil)
+	cfg.ReplicaScheduleLimit = 4
+	checkAddPeerResp(c, co.dispatch(regions[0]), 3)
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/coreos/etcd/etcdserver"
+	"github.com/juju/errors"
+	"golang.org/x/net/context"
+)
+
+// The types of the alerts.
+const (
+	alertStoreDown      = "store_down"
+	alertLeaderChange   = "leader_change"
+	alertRegionNoLeader = "region_no_leader"
+	alertEtcdQuota      = "etcd_quota"
+	alertClockJump      = "clock_jump"
+)
+
+const (
+	// The alerts are dropped if there are more pending ones.
+	maxPendingAlerts = 1024
+	webhookTimeout   = 3 * time.Second
+	// etcdQuotaAlertRatio is the ratio of the db size to the quota of the
+	// embedded etcd beyond which the quota is nearing.
+	etcdQuotaAlertRatio = 0.8
+)
+
+// Alert is the JSON payload posted to the webhooks on a critical event.
+type Alert struct {
+	Type      string    `json:"type"`
+	ClusterID uint64    `json:"cluster_id"`
+	Member    string    `json:"member"`
+	StoreID   uint64    `json:"store_id,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// notifier posts the alerts to the webhooks in the background, so the events
+// are not blocked by a slow webhook.
+type notifier struct {
+	urls   []string
+	client *http.Client
+	alerts chan *Alert
+}
+
+func newNotifier(urls []string) *notifier {
+	return &notifier{
+		urls:   urls,
+		client: &http.Client{Timeout: webhookTimeout},
+		alerts: make(chan *Alert, maxPendingAlerts),
+	}
+}
+
+func (n *notifier) notify(alert *Alert) {
+	if len(n.urls) == 0 {
+		return
+	}
+	select {
+	case n.alerts <- alert:
+	default:
+		log.Warnf("too many pending alerts, drop alert %+v", alert)
+	}
+}
+
+func (n *notifier) run(ctx context.Context) {
+	for {
+		select {
+		case alert := <-n.alerts:
+			for _, url := range n.urls {
+				if err := n.post(url, alert); err != nil {
+					log.Errorf("post alert %+v to %s meet error: %v", alert, url, err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *notifier) post(url string, alert *Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp, err := n.client.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("webhook responds %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyAlert posts an alert to the webhooks configured.
+func (s *Server) notifyAlert(typ string, storeID uint64, format string, args ...interface{}) {
+	alert := &Alert{
+		Type:      typ,
+		ClusterID: s.clusterID,
+		Member:    s.Name(),
+		StoreID:   storeID,
+		Message:   fmt.Sprintf(format, args...),
+		Time:      time.Now(),
+	}
+	log.Warnf("alert %s: %s", typ, alert.Message)
+	s.notifier.notify(alert)
+}
+
+func (s *Server) notifyLoop() {
+	defer s.wg.Done()
+	s.notifier.run(s.client.Ctx())
+}
+
+// checkEtcdQuota alerts once the db size of the embedded etcd is nearing the
+// quota, the alert is sent again after the size falls below.
+func (s *Server) checkEtcdQuota(dbSize int64) {
+	quota := int64(s.cfg.QuotaBackendBytes)
+	if quota == 0 {
+		quota = etcdserver.DefaultQuotaBytes
+	}
+	nearing := float64(dbSize) >= float64(quota)*etcdQuotaAlertRatio
+	if nearing && !s.etcdQuotaAlerted {
+		s.notifyAlert(alertEtcdQuota, 0, "etcd db size %d is nearing the quota %d", dbSize, quota)
+	}
+	s.etcdQuotaAlerted = nearing
+}
+
+// checkDownStores alerts once a store is down. The regions still led by the
+// store in the cache are not reported by new leaders, they may have lost the
+// quorum, so they are alerted as well.
+func (c *RaftCluster) checkDownStores() {
+	cluster := c.cachedCluster
+	maxDownTime := c.coordinator.opt.GetMaxStoreDownTime()
+	for _, store := range cluster.GetStores() {
+		storeID := store.GetId()
+		if store.isTombstone() || store.downTime() < maxDownTime {
+			if _, ok := c.downStores[storeID]; ok && !store.isTombstone() {
+				c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is up again", storeID, store.GetAddress())
+			}
+			delete(c.downStores, storeID)
+			continue
+		}
+		if _, ok := c.downStores[storeID]; ok {
+			continue
+		}
+		c.downStores[storeID] = struct{}{}
+		c.s.notifyAlert(alertStoreDown, storeID, "store %d at %s is down for %v", storeID, store.GetAddress(), store.downTime())
+		c.s.recordClusterEvent(clusterEventStoreState, "store %d at %s is down", storeID, store.GetAddress())
+		if count := cluster.getStoreLeaderCount(storeID); count > 0 {
+			c.s.notifyAlert(alertRegionNoLeader, storeID, "%d regions may have no leader, their leaders are on the down store %d", count, storeID)
+		}
+	}
+	for storeID := range c.downStores {
+		if cluster.GetStore(storeID) == nil {
+			delete(c.downStores, storeID)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/coreos/etcd/etcdserver"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testNotifierSuite{})
+
+type testNotifierSuite struct {
+	testClusterBaseSuite
+	alerts  chan *Alert
+	webhook *httptest.Server
+}
+
+func (s *testNotifierSuite) SetUpTest(c *C) {
+	s.alerts = make(chan *Alert, 16)
+	s.webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alert := &Alert{}
+		if err := json.NewDecoder(r.Body).Decode(alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.alerts <- alert
+	}))
+}
+
+func (s *testNotifierSuite) TearDownTest(c *C) {
+	s.webhook.Close()
+}
+
+func (s *testNotifierSuite) mustGetAlert(c *C) *Alert {
+	select {
+	case alert := <-s.alerts:
+		return alert
+	case <-time.After(5 * time.Second):
+		c.Fatal("no alert is posted")
+	}
+	return nil
+}
+
+func (s *testNotifierSuite) TestNotifier(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := newNotifier([]string{s.webhook.URL})
+	go n.run(ctx)
+	n.notify(&Alert{Type: alertStoreDown, StoreID: 1, Message: "store 1 is down"})
+	alert := s.mustGetAlert(c)
+	c.Assert(alert.Type, Equals, alertStoreDown)
+	c.Assert(alert.StoreID, Equals, uint64(1))
+	c.Assert(alert.Message, Equals, "store 1 is down")
+
+	// The alerts are not kept without webhooks.
+	n = newNotifier(nil)
+	n.notify(&Alert{Type: alertStoreDown})
+	c.Assert(n.alerts, HasLen, 0)
+}
+
+func (s *testNotifierSuite) TestAlerts(c *C) {
+	cfg := NewTestSingleConfig()
+	cfg.Alert.WebhookURLs = []string{s.webhook.URL}
+	svrs, cleanup := newTestServersWithCfgs(c, []*Config{cfg})
+	defer cleanup()
+	s.svr = svrs[0]
+	s.grpcPDClient = mustNewGrpcClient(c, s.svr.GetAddr())
+
+	alert := s.mustGetAlert(c)
+	c.Assert(alert.Type, Equals, alertLeaderChange)
+	c.Assert(alert.Member, Equals, s.svr.Name())
+
+	// The store of the bootstrap request never sends heartbeats.
+	s.bootstrapCluster(c, s.svr.clusterID, "127.0.0.1:0")
+	cluster := s.svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	region := cluster.GetRegions()[0]
+	err := cluster.cachedCluster.handleRegionHeartbeat(newRegionInfo(region, region.GetPeers()[0]))
+	c.Assert(err, IsNil)
+	storeID := region.GetPeers()[0].GetStoreId()
+
+	cluster.checkDownStores()
+	alert = s.mustGetAlert(c)
+	c.Assert(alert.Type, Equals, alertStoreDown)
+	c.Assert(alert.ClusterID, Equals, s.svr.clusterID)
+	c.Assert(alert.StoreID, Equals, storeID)
+	alert = s.mustGetAlert(c)
+	c.Assert(alert.Type, Equals, alertRegionNoLeader)
+	c.Assert(alert.StoreID, Equals, storeID)
+
+	// A down store is alerted only once.
+	cluster.checkDownStores()
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(s.alerts, HasLen, 0)
+
+	s.svr.checkEtcdQuota(etcdserver.DefaultQuotaBytes)
+	alert = s.mustGetAlert(c)
+	c.Assert(alert.Type, Equals, alertEtcdQuota)
+}
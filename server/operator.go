@@ -16,6 +16,7 @@ package server
 import (
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -24,7 +25,21 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
-const maxOperatorWaitTime = 5 * time.Minute
+const (
+	maxOperatorWaitTime = 5 * time.Minute
+	// maxLeaderOperatorWaitTime is the timeout of the leader operators, a
+	// leader transfer is expected to be done in seconds.
+	maxLeaderOperatorWaitTime = 30 * time.Second
+)
+
+// operatorWaitTime returns the max time an operator of the kind can run
+// before it is canceled.
+func operatorWaitTime(kind ResourceKind) time.Duration {
+	if kind == LeaderKind {
+		return maxLeaderOperatorWaitTime
+	}
+	return maxOperatorWaitTime
+}
 
 // ResourceKind distinguishes different kinds of resources.
 type ResourceKind int
@@ -42,6 +57,9 @@ const (
 	PriorityKind
 	// OtherKind indicates the other kind resource
 	OtherKind
+	// ReplicaKind indicates the replica kind resource, which is used by the
+	// operators repairing the replicas of regions
+	ReplicaKind
 )
 
 var resourceKindToName = map[ResourceKind]string{
@@ -51,6 +69,7 @@ var resourceKindToName = map[ResourceKind]string{
 	3: "region",
 	4: "priority",
 	5: "other",
+	6: "replica",
 }
 
 var resourceNameToValue = map[string]ResourceKind{
@@ -60,6 +79,7 @@ var resourceNameToValue = map[string]ResourceKind{
 	"region":   RegionKind,
 	"priority": PriorityKind,
 	"other":    OtherKind,
+	"replica":  ReplicaKind,
 }
 
 func (k ResourceKind) String() string {
@@ -80,7 +100,7 @@ func ParseResourceKind(name string) ResourceKind {
 }
 
 // OperatorState indicates state of the operator
-type OperatorState int
+type OperatorState int32
 
 const (
 	// OperatorUnKnownState indicates the unknown state
@@ -95,6 +115,11 @@ const (
 	OperatorTimeOut
 	// OperatorReplaced indicates this operator replaced by more priority operator
 	OperatorReplaced
+	// OperatorCanceled indicates this operator is removed by admin
+	OperatorCanceled
+	// OperatorStale indicates this operator is removed as its region has
+	// changed unexpectedly, e.g. split or merged
+	OperatorStale
 )
 
 var operatorStateToName = map[OperatorState]string{
@@ -104,6 +129,8 @@ var operatorStateToName = map[OperatorState]string{
 	3: "finished",
 	4: "timeout",
 	5: "replaced",
+	6: "canceled",
+	7: "stale",
 }
 
 var operatorStateNameToValue = map[string]OperatorState{
@@ -113,6 +140,8 @@ var operatorStateNameToValue = map[string]OperatorState{
 	"finished": OperatorFinished,
 	"timeout":  OperatorTimeOut,
 	"replaced": OperatorReplaced,
+	"canceled": OperatorCanceled,
+	"stale":    OperatorStale,
 }
 
 func (o OperatorState) String() string {
@@ -153,6 +182,20 @@ type Operator interface {
 	Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse, bool)
 }
 
+// timeoutOperator is an operator which is canceled if it runs too long, even
+// if its region stops sending heartbeats.
+type timeoutOperator interface {
+	Operator
+	isTimeout() bool
+}
+
+// staleOperator is an operator which is canceled once its region changes in
+// a way it doesn't expect, as it can't go on.
+type staleOperator interface {
+	Operator
+	isStale(region *RegionInfo) bool
+}
+
 type adminOperator struct {
 	Name   string        `json:"name"`
 	Region *RegionInfo   `json:"region"`
@@ -184,21 +227,47 @@ func (op *adminOperator) GetResourceKind() ResourceKind {
 }
 
 func (op *adminOperator) GetState() OperatorState {
-	return op.State
+	return OperatorState(atomic.LoadInt32((*int32)(&op.State)))
 }
 
+// SetState changes the state of the operator. It's called by the API while
+// the operator is running, so the state is changed atomically.
 func (op *adminOperator) SetState(state OperatorState) {
-	op.State = state
+	if !op.updateState(state) {
+		return
+	}
 	for _, o := range op.Ops {
 		o.SetState(state)
 	}
+}
 
+// updateState changes the state unless the operator is ended, e.g. it's
+// canceled by the API while it's running.
+func (op *adminOperator) updateState(state OperatorState) bool {
+	for {
+		old := op.GetState()
+		if old != OperatorWaiting && old != OperatorRunning && old != OperatorFinished {
+			return false
+		}
+		if old == OperatorFinished && state == OperatorRunning {
+			return false
+		}
+		if atomic.CompareAndSwapInt32((*int32)(&op.State), int32(old), int32(state)) {
+			return true
+		}
+	}
 }
 
 func (op *adminOperator) GetName() string {
 	return op.Name
 }
 
+// isTimeout checks whether the operator runs too long. The admin operator
+// never ends by itself, it's removed once it times out.
+func (op *adminOperator) isTimeout() bool {
+	return time.Since(op.Start) > operatorWaitTime(AdminKind)
+}
+
 func (op *adminOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse, bool) {
 	// Update region.
 	op.Region = region.clone()
@@ -206,13 +275,13 @@ func (op *adminOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse,
 	// Do all operators in order.
 	for i := 0; i < len(op.Ops); i++ {
 		if res, finished := op.Ops[i].Do(region); !finished {
-			op.State = OperatorRunning
+			op.updateState(OperatorRunning)
 			return res, false
 		}
 	}
 
 	// Admin operator never ends, remove it from the API.
-	op.State = OperatorFinished
+	op.updateState(OperatorFinished)
 	return nil, false
 }
 
@@ -225,6 +294,11 @@ type regionOperator struct {
 	Ops    []Operator    `json:"ops"`
 	Kind   ResourceKind  `json:"kind"`
 	State  OperatorState `json:"state"`
+	// Epoch is the region epoch when the operator is created.
+	Epoch *metapb.RegionEpoch `json:"epoch"`
+
+	// stepStart is the time the current step starts.
+	stepStart time.Time
 }
 
 func newRegionOperator(region *RegionInfo, kind ResourceKind, ops ...Operator) *regionOperator {
@@ -240,6 +314,7 @@ func newRegionOperator(region *RegionInfo, kind ResourceKind, ops ...Operator) *
 		Ops:    ops,
 		Kind:   kind,
 		State:  OperatorWaiting,
+		Epoch:  region.GetRegionEpoch(),
 	}
 }
 
@@ -273,8 +348,30 @@ func (op *regionOperator) GetName() string {
 	return op.Name
 }
 
+func (op *regionOperator) isTimeout() bool {
+	return time.Since(op.Start) > operatorWaitTime(op.Kind)
+}
+
+// isStale checks whether the region is split or merged, or its peers are
+// changed by others since the operator is created. Each peer change step of
+// the operator increases the conf version once, and the running step may have
+// been applied before its heartbeat is dispatched.
+func (op *regionOperator) isStale(region *RegionInfo) bool {
+	if op.Epoch == nil {
+		return false
+	}
+	var confChanges uint64
+	for i, step := range op.Ops {
+		if _, ok := step.(*changePeerOperator); ok && i <= op.Index {
+			confChanges++
+		}
+	}
+	epoch := region.GetRegionEpoch()
+	return epoch.GetVersion() > op.Epoch.GetVersion() || epoch.GetConfVer() > op.Epoch.GetConfVer()+confChanges
+}
+
 func (op *regionOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse, bool) {
-	if time.Since(op.Start) > maxOperatorWaitTime {
+	if op.isTimeout() {
 		log.Errorf("[region %d] Operator timeout:%s", region.GetId(), op)
 		op.State = OperatorTimeOut
 		return nil, true
@@ -285,10 +382,15 @@ func (op *regionOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse,
 
 	// If an operator is not finished, do it.
 	for ; op.Index < len(op.Ops); op.Index++ {
+		if op.stepStart.IsZero() {
+			op.stepStart = time.Now()
+		}
 		if res, finished := op.Ops[op.Index].Do(region); !finished {
 			op.State = OperatorRunning
 			return res, false
 		}
+		operatorStepDuration.WithLabelValues(op.Ops[op.Index].GetName()).Observe(time.Since(op.stepStart).Seconds())
+		op.stepStart = time.Time{}
 	}
 
 	op.End = time.Now()
@@ -448,3 +550,43 @@ func (op *transferLeaderOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatR
 	}
 	return res, false
 }
+
+// OperatorRecord is the outcome of an operator removed from the coordinator,
+// the state is finished, timeout, replaced, canceled or stale.
+type OperatorRecord struct {
+	RegionID   uint64        `json:"region_id"`
+	Kind       string        `json:"kind"`
+	State      OperatorState `json:"state"`
+	FinishTime time.Time     `json:"finish_time"`
+	Operator   Operator      `json:"operator"`
+}
+
+// DryRunOperator is an operator created by a scheduler in dry-run mode, it is
+// only reported and never dispatched.
+type DryRunOperator struct {
+	RegionID   uint64    `json:"region_id"`
+	Kind       string    `json:"kind"`
+	Scheduler  string    `json:"scheduler"`
+	CreateTime time.Time `json:"create_time"`
+	Operator   Operator  `json:"operator"`
+}
+
+func newDryRunOperator(scheduler string, op Operator) *DryRunOperator {
+	return &DryRunOperator{
+		RegionID:   op.GetRegionID(),
+		Kind:       op.GetResourceKind().String(),
+		Scheduler:  scheduler,
+		CreateTime: time.Now(),
+		Operator:   op,
+	}
+}
+
+func newOperatorRecord(op Operator) *OperatorRecord {
+	return &OperatorRecord{
+		RegionID:   op.GetRegionID(),
+		Kind:       op.GetResourceKind().String(),
+		State:      op.GetState(),
+		FinishTime: time.Now(),
+		Operator:   op,
+	}
+}
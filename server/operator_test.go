@@ -36,6 +36,7 @@ func (s *testResouceKindSuite) TestString(c *C) {
 		{RegionKind, "region"},
 		{PriorityKind, "priority"},
 		{OtherKind, "other"},
+		{ReplicaKind, "replica"},
 		{ResourceKind(404), "unknown"},
 	}
 	for _, t := range tbl {
@@ -54,6 +55,7 @@ func (s *testResouceKindSuite) TestParseResouceKind(c *C) {
 		{"region", RegionKind},
 		{"priority", PriorityKind},
 		{"other", OtherKind},
+		{"replica", ReplicaKind},
 		{"test", UnKnownKind},
 	}
 	for _, t := range tbl {
@@ -76,6 +78,8 @@ func (o *testOperatorSuite) TestOperatorStateString(c *C) {
 		{OperatorFinished, "finished"},
 		{OperatorTimeOut, "timeout"},
 		{OperatorReplaced, "replaced"},
+		{OperatorCanceled, "canceled"},
+		{OperatorStale, "stale"},
 		{OperatorState(404), "unknown"},
 	}
 	for _, t := range tbl {
@@ -94,6 +98,8 @@ func (o *testOperatorSuite) TestOperatorStateMarshal(c *C) {
 		{OperatorFinished, OperatorFinished},
 		{OperatorTimeOut, OperatorTimeOut},
 		{OperatorReplaced, OperatorReplaced},
+		{OperatorCanceled, OperatorCanceled},
+		{OperatorStale, OperatorStale},
 		{OperatorState(404), OperatorUnKnownState},
 	}
 	for _, t := range tbl {
@@ -153,7 +159,7 @@ func (o *testOperatorSuite) TestOperatorState(c *C) {
 	waitOperator(c, co, 1)
 	op := co.getOperator(1)
 	c.Assert(op.GetState(), Equals, OperatorWaiting)
-	regionInfo := tc.getRegion(1)
+	regionInfo := tc.GetRegion(1)
 
 	// Do Operator, Operator start running. doRegionHeartbeatRequest will add one peer in store 1
 	c.Assert(regionInfo, NotNil)
@@ -200,3 +206,56 @@ func (o *testOperatorSuite) TestOperatorState(c *C) {
 	c.Assert(op.GetState(), Equals, OperatorTimeOut)
 
 }
+
+func (o *testOperatorSuite) TestOperatorTimeout(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addLeaderRegion(1, 1, 2)
+	region := cluster.GetRegion(1)
+
+	// Leader operators time out earlier than region operators.
+	start := time.Now().Add(-maxLeaderOperatorWaitTime).Add(-time.Second)
+	leaderOp := newTransferLeader(region, region.GetStorePeer(2)).(*regionOperator)
+	leaderOp.Start = start
+	c.Assert(leaderOp.isTimeout(), IsTrue)
+	regionOp := newRemovePeer(region, region.GetStorePeer(2)).(*regionOperator)
+	regionOp.Start = start
+	c.Assert(regionOp.isTimeout(), IsFalse)
+	regionOp.Start = time.Now().Add(-maxOperatorWaitTime).Add(-time.Second)
+	c.Assert(regionOp.isTimeout(), IsTrue)
+
+	// The admin operators time out too.
+	adminOp := newAdminOperator(region, newTransferLeaderOperator(1, region.GetStorePeer(1), region.GetStorePeer(2)))
+	c.Assert(adminOp.isTimeout(), IsFalse)
+	adminOp.Start = time.Now().Add(-maxOperatorWaitTime).Add(-time.Second)
+	c.Assert(adminOp.isTimeout(), IsTrue)
+}
+
+func (o *testOperatorSuite) TestAdminOperatorState(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addLeaderRegion(1, 1, 2)
+	region := cluster.GetRegion(1)
+
+	op := newAdminOperator(region, newTransferLeaderOperator(1, region.GetStorePeer(1), region.GetStorePeer(2)))
+	c.Assert(op.GetState(), Equals, OperatorWaiting)
+	_, finished := op.Do(region)
+	c.Assert(finished, IsFalse)
+	c.Assert(op.GetState(), Equals, OperatorRunning)
+
+	// The canceled operator isn't running again.
+	op.SetState(OperatorCanceled)
+	op.Do(region)
+	c.Assert(op.GetState(), Equals, OperatorCanceled)
+	op.SetState(OperatorTimeOut)
+	c.Assert(op.GetState(), Equals, OperatorCanceled)
+
+	// The finished operator can still be canceled.
+	op = newAdminOperator(region, newTransferLeaderOperator(1, region.GetStorePeer(2), region.GetStorePeer(1)))
+	op.Do(region)
+	c.Assert(op.GetState(), Equals, OperatorFinished)
+	op.Do(region)
+	c.Assert(op.GetState(), Equals, OperatorFinished)
+	op.SetState(OperatorCanceled)
+	c.Assert(op.GetState(), Equals, OperatorCanceled)
+}
@@ -0,0 +1,288 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// Rule roles. Only voters are supported, the non-voting roles need raft
+// learners, which kvproto doesn't have yet.
+const (
+	RuleRoleVoter = "voter"
+)
+
+// Label constraint operators.
+const (
+	LabelConstraintIn    = "in"
+	LabelConstraintNotIn = "notIn"
+)
+
+// LabelConstraint requires the label of a store to be in or not in the
+// values. A store without the label is treated as not in the values.
+type LabelConstraint struct {
+	Key    string   `json:"key"`
+	Op     string   `json:"op"`
+	Values []string `json:"values"`
+}
+
+func (c *LabelConstraint) matchStore(store *StoreInfo) bool {
+	value := store.getLabelValue(c.Key)
+	in := false
+	for _, v := range c.Values {
+		if v == value {
+			in = true
+			break
+		}
+	}
+	if c.Op == LabelConstraintNotIn {
+		return !in
+	}
+	return in
+}
+
+// Rule is a placement rule, it places Count replicas of the regions in the
+// key range to the stores matching the label constraints. The keys are hex
+// encoded, an empty end key means the end of the key space.
+//
+// A region is placed by rules only if the whole region is in the key ranges
+// of the rules, regions across the boundaries of a rule are placed by
+// max-replicas until they are split.
+type Rule struct {
+	ID               string            `json:"id"`
+	StartKey         string            `json:"start_key"`
+	EndKey           string            `json:"end_key"`
+	Role             string            `json:"role"`
+	Count            int               `json:"count"`
+	LabelConstraints []LabelConstraint `json:"label_constraints,omitempty"`
+
+	startKey []byte
+	endKey   []byte
+}
+
+// adjust validates the rule and decodes the keys.
+func (r *Rule) adjust() error {
+	if r.ID == "" || strings.Contains(r.ID, "/") {
+		return errors.Errorf("invalid rule id %q", r.ID)
+	}
+	if r.Role == "" {
+		r.Role = RuleRoleVoter
+	}
+	if r.Role != RuleRoleVoter {
+		return errors.Errorf("unsupported rule role %q", r.Role)
+	}
+	if r.Count <= 0 {
+		return errors.Errorf("invalid rule count %d", r.Count)
+	}
+	var err error
+	if r.startKey, err = hex.DecodeString(r.StartKey); err != nil {
+		return errors.Errorf("invalid start key %q", r.StartKey)
+	}
+	if r.endKey, err = hex.DecodeString(r.EndKey); err != nil {
+		return errors.Errorf("invalid end key %q", r.EndKey)
+	}
+	if len(r.endKey) > 0 && bytes.Compare(r.startKey, r.endKey) >= 0 {
+		return errors.New("start key must be less than end key")
+	}
+	for _, c := range r.LabelConstraints {
+		if c.Key == "" {
+			return errors.New("label constraint key is empty")
+		}
+		if c.Op != LabelConstraintIn && c.Op != LabelConstraintNotIn {
+			return errors.Errorf("unsupported label constraint op %q", c.Op)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) clone() *Rule {
+	rule := *r
+	rule.LabelConstraints = append([]LabelConstraint(nil), r.LabelConstraints...)
+	return &rule
+}
+
+// containsRegion checks whether the whole region is in the key range.
+func (r *Rule) containsRegion(region *RegionInfo) bool {
+	if bytes.Compare(region.GetStartKey(), r.startKey) < 0 {
+		return false
+	}
+	if len(r.endKey) == 0 {
+		return true
+	}
+	return len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), r.endKey) <= 0
+}
+
+// matchStore checks whether the store matches the label constraints. The
+// stores of special engines only match the rules targeting the engines.
+func (r *Rule) matchStore(store *StoreInfo) bool {
+	if store.isSpecialEngine() && !r.targetsEngine() {
+		return false
+	}
+	for i := range r.LabelConstraints {
+		if !r.LabelConstraints[i].matchStore(store) {
+			return false
+		}
+	}
+	return true
+}
+
+// targetsEngine checks whether the rule explicitly places the replicas to
+// some engines.
+func (r *Rule) targetsEngine() bool {
+	for _, c := range r.LabelConstraints {
+		if c.Key == EngineLabelKey && c.Op == LabelConstraintIn {
+			return true
+		}
+	}
+	return false
+}
+
+// placementRules keeps the placement rules in memory, the rules are
+// persisted by kv.
+type placementRules struct {
+	sync.RWMutex
+	rules map[string]*Rule
+}
+
+func newPlacementRules() *placementRules {
+	return &placementRules{
+		rules: make(map[string]*Rule),
+	}
+}
+
+func (p *placementRules) getRule(id string) *Rule {
+	p.RLock()
+	defer p.RUnlock()
+	if rule, ok := p.rules[id]; ok {
+		return rule.clone()
+	}
+	return nil
+}
+
+// getRules returns all rules sorted by id.
+func (p *placementRules) getRules() []*Rule {
+	p.RLock()
+	defer p.RUnlock()
+	rules := make([]*Rule, 0, len(p.rules))
+	for _, rule := range p.rules {
+		rules = append(rules, rule.clone())
+	}
+	sort.Sort(rulesByID(rules))
+	return rules
+}
+
+// setRule adds or replaces a rule, the rule must be adjusted.
+func (p *placementRules) setRule(rule *Rule) {
+	p.Lock()
+	defer p.Unlock()
+	p.rules[rule.ID] = rule.clone()
+}
+
+func (p *placementRules) deleteRule(id string) {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.rules, id)
+}
+
+// getRegionRules returns the rules containing the region, sorted by id. It
+// returns nil if none of the rules places voters, so that the voters of the
+// region are placed by max-replicas instead of being removed as extra peers.
+func (p *placementRules) getRegionRules(region *RegionInfo) []*Rule {
+	p.RLock()
+	defer p.RUnlock()
+	var rules []*Rule
+	hasVoter := false
+	for _, rule := range p.rules {
+		if rule.containsRegion(region) {
+			rules = append(rules, rule)
+			hasVoter = hasVoter || rule.Role == RuleRoleVoter
+		}
+	}
+	if !hasVoter {
+		return nil
+	}
+	sort.Sort(rulesByID(rules))
+	return rules
+}
+
+type rulesByID []*Rule
+
+func (r rulesByID) Len() int           { return len(r) }
+func (r rulesByID) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r rulesByID) Less(i, j int) bool { return r[i].ID < r[j].ID }
+
+// ruleFit is the result of fitting the peers of a region to rules.
+type ruleFit struct {
+	// peerRules is the rule each fitted peer belongs to.
+	peerRules map[uint64]*Rule
+	// unfilled is the first rule without enough peers.
+	unfilled *Rule
+	// extra is the peers not needed by any rule.
+	extra []*metapb.Peer
+}
+
+// fitRules assigns the peers to the rules in order, each rule takes the
+// unassigned peers on the matching stores until it has enough peers.
+func fitRules(cluster Cluster, region *RegionInfo, rules []*Rule) *ruleFit {
+	fit := &ruleFit{peerRules: make(map[uint64]*Rule)}
+	for _, rule := range rules {
+		count := 0
+		for _, peer := range region.GetPeers() {
+			if count >= rule.Count {
+				break
+			}
+			if _, ok := fit.peerRules[peer.GetId()]; ok {
+				continue
+			}
+			store := cluster.GetStore(peer.GetStoreId())
+			if store != nil && rule.matchStore(store) {
+				fit.peerRules[peer.GetId()] = rule
+				count++
+			}
+		}
+		if count < rule.Count && fit.unfilled == nil {
+			fit.unfilled = rule
+		}
+	}
+	for _, peer := range region.GetPeers() {
+		if _, ok := fit.peerRules[peer.GetId()]; !ok {
+			fit.extra = append(fit.extra, peer)
+		}
+	}
+	return fit
+}
+
+// ruleFilter filters the stores not matching the rule.
+type ruleFilter struct {
+	rule *Rule
+}
+
+func newRuleFilter(rule *Rule) *ruleFilter {
+	return &ruleFilter{rule: rule}
+}
+
+func (f *ruleFilter) FilterSource(store *StoreInfo) bool {
+	return false
+}
+
+func (f *ruleFilter) FilterTarget(store *StoreInfo) bool {
+	return !f.rule.matchStore(store)
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testPlacementSuite{})
+
+type testPlacementSuite struct{}
+
+func newTestRule(id, startKey, endKey string, count int, constraints ...LabelConstraint) *Rule {
+	rule := &Rule{
+		ID:               id,
+		StartKey:         startKey,
+		EndKey:           endKey,
+		Count:            count,
+		LabelConstraints: constraints,
+	}
+	if err := rule.adjust(); err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func (s *testPlacementSuite) TestRegionRules(c *C) {
+	rules := newPlacementRules()
+	rules.setRule(newTestRule("all", "", "", 3))
+	rules.setRule(newTestRule("t1", "7480", "7490", 2))
+	rules.setRule(newTestRule("t2", "7490", "", 1))
+
+	newRegion := func(start, end string) *RegionInfo {
+		return newRegionInfo(&metapb.Region{StartKey: []byte(start), EndKey: []byte(end)}, nil)
+	}
+	getIDs := func(region *RegionInfo) []string {
+		var ids []string
+		for _, rule := range rules.getRegionRules(region) {
+			ids = append(ids, rule.ID)
+		}
+		return ids
+	}
+
+	c.Assert(getIDs(newRegion("", "")), DeepEquals, []string{"all"})
+	c.Assert(getIDs(newRegion("\x74\x80", "\x74\x81")), DeepEquals, []string{"all", "t1"})
+	c.Assert(getIDs(newRegion("\x74\x80", "\x74\x90")), DeepEquals, []string{"all", "t1"})
+	c.Assert(getIDs(newRegion("\x74\x85", "\x74\x95")), DeepEquals, []string{"all"})
+	c.Assert(getIDs(newRegion("\x74\x95", "")), DeepEquals, []string{"all", "t2"})
+
+	rules.deleteRule("all")
+	c.Assert(getIDs(newRegion("", "")), HasLen, 0)
+	c.Assert(rules.getRules(), HasLen, 2)
+	c.Assert(rules.getRule("all"), IsNil)
+
+	// The region is placed by max-replicas if no rule places its voters.
+	learner := newTestRule("learner", "", "", 1)
+	learner.Role = "learner"
+	rules.setRule(learner)
+	c.Assert(getIDs(newRegion("", "")), HasLen, 0)
+	c.Assert(getIDs(newRegion("\x74\x95", "")), DeepEquals, []string{"learner", "t2"})
+	c.Assert((&Rule{ID: "learner", Role: "learner", Count: 1}).adjust(), NotNil)
+}
+
+func (s *testPlacementSuite) TestFitRules(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addLabelsStore(1, 1, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(2, 1, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(3, 1, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(4, 1, map[string]string{})
+	tc.addLeaderRegion(1, 1, 2, 3, 4)
+	region := cluster.GetRegion(1)
+
+	z1 := LabelConstraint{Key: "zone", Op: LabelConstraintIn, Values: []string{"z1"}}
+	notZ1 := LabelConstraint{Key: "zone", Op: LabelConstraintNotIn, Values: []string{"z1"}}
+
+	fit := fitRules(cluster, region, []*Rule{newTestRule("a", "", "", 2, z1), newTestRule("b", "", "", 2, notZ1)})
+	c.Assert(fit.unfilled, IsNil)
+	c.Assert(fit.extra, HasLen, 0)
+
+	fit = fitRules(cluster, region, []*Rule{newTestRule("a", "", "", 3, z1)})
+	c.Assert(fit.unfilled.ID, Equals, "a")
+	c.Assert(fit.extra, HasLen, 2)
+	c.Assert(fit.extra[0].GetStoreId(), Equals, uint64(3))
+	c.Assert(fit.extra[1].GetStoreId(), Equals, uint64(4))
+}
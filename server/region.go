@@ -28,15 +28,39 @@ type RegionInfo struct {
 	DownPeers    []*pdpb.PeerStats
 	PendingPeers []*metapb.Peer
 	WrittenBytes uint64
+	ReadBytes    uint64
+	WrittenKeys  uint64
+	ReadKeys     uint64
+	// Approximate size and keys of the region, 0 means unknown. The region
+	// heartbeat doesn't report them until kvproto has the fields.
+	ApproximateSize uint64
+	ApproximateKeys uint64
 }
 
 func newRegionInfo(region *metapb.Region, leader *metapb.Peer) *RegionInfo {
 	return &RegionInfo{
 		Region: region,
-		Leader: leader,
+		Leader: internPeer(region, leader),
 	}
 }
 
+// internPeer returns the same peer in the region if exists, so the leader
+// shares memory with the region peers.
+func internPeer(region *metapb.Region, peer *metapb.Peer) *metapb.Peer {
+	if peer == nil {
+		return nil
+	}
+	for _, p := range region.GetPeers() {
+		if p == peer {
+			return p
+		}
+		if p.GetId() == peer.GetId() && p.GetStoreId() == peer.GetStoreId() {
+			return p
+		}
+	}
+	return peer
+}
+
 func (r *RegionInfo) clone() *RegionInfo {
 	downPeers := make([]*pdpb.PeerStats, 0, len(r.DownPeers))
 	for _, peer := range r.DownPeers {
@@ -46,15 +70,37 @@ func (r *RegionInfo) clone() *RegionInfo {
 	for _, peer := range r.PendingPeers {
 		pendingPeers = append(pendingPeers, proto.Clone(peer).(*metapb.Peer))
 	}
+	region := proto.Clone(r.Region).(*metapb.Region)
+	leader := internPeer(region, r.Leader)
+	if leader != nil && leader == r.Leader {
+		// The leader is not in the region.
+		leader = proto.Clone(leader).(*metapb.Peer)
+	}
 	return &RegionInfo{
-		Region:       proto.Clone(r.Region).(*metapb.Region),
-		Leader:       proto.Clone(r.Leader).(*metapb.Peer),
+		Region:       region,
+		Leader:       leader,
 		DownPeers:    downPeers,
 		PendingPeers: pendingPeers,
 		WrittenBytes: r.WrittenBytes,
+		ReadBytes:    r.ReadBytes,
+		WrittenKeys:  r.WrittenKeys,
+		ReadKeys:     r.ReadKeys,
+
+		ApproximateSize: r.ApproximateSize,
+		ApproximateKeys: r.ApproximateKeys,
 	}
 }
 
+// regionSize returns the approximate size of the region, a region without
+// reported size is treated as defaultRegionSize, so stores are balanced by
+// region count before the sizes are reported.
+func (r *RegionInfo) regionSize() uint64 {
+	if r.ApproximateSize == 0 {
+		return defaultRegionSize
+	}
+	return r.ApproximateSize
+}
+
 // GetPeer return the peer with specified peer id
 func (r *RegionInfo) GetPeer(peerID uint64) *metapb.Peer {
 	for _, peer := range r.GetPeers() {
@@ -179,11 +225,18 @@ func (t *regionTree) length() int {
 // It finds and deletes all the overlapped regions first, and then
 // insert the region.
 func (t *regionTree) update(region *metapb.Region) {
-	item := &regionItem{region: region}
+	for _, item := range t.getOverlaps(region) {
+		t.tree.Delete(item)
+	}
+
+	t.tree.ReplaceOrInsert(&regionItem{region: region})
+}
 
+// getOverlaps returns the items whose ranges are overlapped with the region.
+func (t *regionTree) getOverlaps(region *metapb.Region) []*regionItem {
 	result := t.find(region)
 	if result == nil {
-		result = item
+		result = &regionItem{region: region}
 	}
 
 	var overlaps []*regionItem
@@ -195,12 +248,7 @@ func (t *regionTree) update(region *metapb.Region) {
 		overlaps = append(overlaps, over)
 		return true
 	})
-
-	for _, item := range overlaps {
-		t.tree.Delete(item)
-	}
-
-	t.tree.ReplaceOrInsert(item)
+	return overlaps
 }
 
 // remove removes a region if the region is in the tree.
@@ -225,6 +273,41 @@ func (t *regionTree) search(regionKey []byte) *metapb.Region {
 	return result.region
 }
 
+// scanRange scans the regions in the ascending order of start keys, from the
+// one that contains the startKey, until f returns false.
+func (t *regionTree) scanRange(startKey []byte, f func(*metapb.Region) bool) {
+	region := &metapb.Region{StartKey: startKey}
+	pivot := t.find(region)
+	if pivot == nil {
+		pivot = &regionItem{region: region}
+	}
+	t.tree.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		return f(i.(*regionItem).region)
+	})
+}
+
+// getAdjacentRegions returns the regions right before and after the region
+// in the key order, nil if not exist.
+func (t *regionTree) getAdjacentRegions(region *metapb.Region) (*metapb.Region, *metapb.Region) {
+	item := &regionItem{region: region}
+	var prev, next *metapb.Region
+	t.tree.AscendGreaterOrEqual(item, func(i btree.Item) bool {
+		if bytes.Equal(region.StartKey, i.(*regionItem).region.StartKey) {
+			return true
+		}
+		prev = i.(*regionItem).region
+		return false
+	})
+	t.tree.DescendLessOrEqual(item, func(i btree.Item) bool {
+		if bytes.Equal(region.StartKey, i.(*regionItem).region.StartKey) {
+			return true
+		}
+		next = i.(*regionItem).region
+		return false
+	})
+	return prev, next
+}
+
 // This is a helper function to find an item.
 func (t *regionTree) find(region *metapb.Region) *regionItem {
 	item := &regionItem{region: region}
@@ -241,3 +324,14 @@ func (t *regionTree) find(region *metapb.Region) *regionItem {
 
 	return result
 }
+
+// containsRegion checks whether the range of the region contains the other one.
+func containsRegion(region, other *metapb.Region) bool {
+	if bytes.Compare(region.GetStartKey(), other.GetStartKey()) > 0 {
+		return false
+	}
+	if len(region.GetEndKey()) == 0 {
+		return true
+	}
+	return len(other.GetEndKey()) > 0 && bytes.Compare(other.GetEndKey(), region.GetEndKey()) <= 0
+}
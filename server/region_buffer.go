@@ -0,0 +1,222 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+const (
+	// The buffered region metas are flushed every regionFlushInterval, or
+	// once regionFlushSize regions are buffered.
+	regionFlushInterval = 100 * time.Millisecond
+	regionFlushSize     = 1024
+)
+
+// regionChange is a buffered change of a region meta.
+type regionChange struct {
+	region  *metapb.Region
+	deleted bool
+}
+
+// regionBuffer buffers the changes of the region metas and flushes them to
+// the kv in batches, so the region heartbeats don't wait for the storage. A
+// later change of a region replaces the buffered one. The changes not flushed
+// are lost if the leader crashes, the regions loaded by the next leader are
+// reconciled, and the heartbeats update them again. The splits and merges are
+// written at once by write, so the storage doesn't keep the regions overlapped
+// by each other for long.
+type regionBuffer struct {
+	sync.Mutex
+	kv      *kv
+	changes map[uint64]*regionChange
+	// full is notified once regionFlushSize regions are buffered.
+	full chan struct{}
+	// flushLock serializes the flushes, so the changes of a region are
+	// flushed in order.
+	flushLock sync.Mutex
+}
+
+func newRegionBuffer(kv *kv) *regionBuffer {
+	return &regionBuffer{
+		kv:      kv,
+		changes: make(map[uint64]*regionChange),
+		full:    make(chan struct{}, 1),
+	}
+}
+
+func (b *regionBuffer) saveRegion(region *metapb.Region) {
+	b.add(region, false)
+}
+
+func (b *regionBuffer) deleteRegion(region *metapb.Region) {
+	b.add(region, true)
+}
+
+// add buffers a clone of the region. The cached region is changed under the
+// lock of the cluster, e.g. its keys are shared with the adjacent regions, but
+// the buffer is flushed without the lock.
+func (b *regionBuffer) add(region *metapb.Region, deleted bool) {
+	change := &regionChange{
+		region:  proto.Clone(region).(*metapb.Region),
+		deleted: deleted,
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.changes[change.region.GetId()] = change
+	if len(b.changes) >= regionFlushSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush flushes the buffered changes. The changes are buffered again if the
+// flush fails, unless they are replaced by later changes.
+func (b *regionBuffer) flush() error {
+	b.flushLock.Lock()
+	defer b.flushLock.Unlock()
+
+	b.Lock()
+	changes := b.changes
+	b.changes = make(map[uint64]*regionChange)
+	b.Unlock()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var saves, deletes []*metapb.Region
+	for _, change := range changes {
+		if change.deleted {
+			deletes = append(deletes, change.region)
+		} else {
+			saves = append(saves, change.region)
+		}
+	}
+	if err := b.kv.flushRegions(saves, deletes); err != nil {
+		regionFlushFailedCounter.Inc()
+		b.Lock()
+		for id, change := range changes {
+			if _, ok := b.changes[id]; !ok {
+				b.changes[id] = change
+			}
+		}
+		b.Unlock()
+		return errors.Trace(err)
+	}
+	regionFlushCounter.Add(float64(len(changes)))
+	return nil
+}
+
+// write writes the changes to the kv at once, and drops the buffered changes
+// of the regions, which are older. The caller holds the lock of the cluster,
+// so the regions are not changed or buffered during the write.
+func (b *regionBuffer) write(saves, deletes []*metapb.Region) error {
+	b.flushLock.Lock()
+	defer b.flushLock.Unlock()
+
+	if err := b.kv.flushRegions(saves, deletes); err != nil {
+		regionFlushFailedCounter.Inc()
+		return errors.Trace(err)
+	}
+	b.Lock()
+	for _, region := range saves {
+		delete(b.changes, region.GetId())
+	}
+	for _, region := range deletes {
+		delete(b.changes, region.GetId())
+	}
+	b.Unlock()
+	regionFlushCounter.Add(float64(len(saves) + len(deletes)))
+	return nil
+}
+
+// run flushes the changes every interval or once the buffer is full, until
+// quit is closed, then it flushes the remaining changes.
+func (b *regionBuffer) run(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			if err := b.flush(); err != nil {
+				log.Errorf("flush regions meet error: %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+		if err := b.flush(); err != nil {
+			log.Errorf("flush regions meet error: %v", err)
+		}
+	}
+}
+
+// reconcileRegions removes the regions covered by newer regions from the
+// loaded regions, and returns them. They may be left in the storage if the
+// previous leader crashed before flushing the changes of a split or a merge.
+func reconcileRegions(regions *regionsInfo) []*RegionInfo {
+	items := regions.getRegions()
+	// Check the newer regions first, and the regions with the same version
+	// are kept.
+	sort.Sort(regionsByVersionDesc(items))
+	tree := newRegionTree()
+	var kept, stale []*RegionInfo
+	for _, item := range items {
+		isStale := false
+		for _, overlap := range tree.getOverlaps(item.Region) {
+			if isRegionOlder(item.Region, overlap.region) {
+				isStale = true
+				break
+			}
+		}
+		if isStale {
+			stale = append(stale, item)
+			continue
+		}
+		tree.update(item.Region)
+		kept = append(kept, item)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	for _, item := range stale {
+		regions.removeRegion(item)
+	}
+	// A stale region may replace the newer ones in the tree when it's loaded,
+	// add them back from the older to the newer.
+	for i := len(kept) - 1; i >= 0; i-- {
+		regions.setRegion(kept[i])
+	}
+	return stale
+}
+
+type regionsByVersionDesc []*RegionInfo
+
+func (r regionsByVersionDesc) Len() int      { return len(r) }
+func (r regionsByVersionDesc) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r regionsByVersionDesc) Less(i, j int) bool {
+	return r[i].GetRegionEpoch().GetVersion() > r[j].GetRegionEpoch().GetVersion()
+}
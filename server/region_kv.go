@@ -0,0 +1,158 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+const (
+	regionKVOpenTimeout = time.Second
+	// regionKVSyncInterval is the interval to save all regions in the local
+	// storage to etcd.
+	regionKVSyncInterval = 5 * time.Minute
+)
+
+var regionKVBucket = []byte("regions")
+
+// regionKV saves region metas in a local bolt db, to avoid writing etcd
+// on every region change.
+type regionKV struct {
+	db *bolt.DB
+}
+
+func newRegionKV(path string) (*regionKV, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: regionKVOpenTimeout})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(regionKVBucket)
+		return errors.Trace(err)
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Trace(err)
+	}
+	return &regionKV{db: db}, nil
+}
+
+func (kv *regionKV) saveRegion(region *metapb.Region) error {
+	value, err := region.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(regionKVBucket).Put(uint64ToBytes(region.GetId()), value)
+	})
+	return errors.Trace(err)
+}
+
+func (kv *regionKV) deleteRegion(region *metapb.Region) error {
+	err := kv.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(regionKVBucket).Delete(uint64ToBytes(region.GetId()))
+	})
+	return errors.Trace(err)
+}
+
+// flushRegions saves and deletes the regions in one transaction.
+func (kv *regionKV) flushRegions(saves, deletes []*metapb.Region) error {
+	values := make([][]byte, 0, len(saves))
+	for _, region := range saves {
+		value, err := region.Marshal()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		values = append(values, value)
+	}
+	err := kv.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(regionKVBucket)
+		for i, region := range saves {
+			if err := bucket.Put(uint64ToBytes(region.GetId()), values[i]); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		for _, region := range deletes {
+			if err := bucket.Delete(uint64ToBytes(region.GetId())); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	})
+	return errors.Trace(err)
+}
+
+func (kv *regionKV) loadRegion(regionID uint64, region *metapb.Region) (bool, error) {
+	var value []byte
+	err := kv.db.View(func(tx *bolt.Tx) error {
+		// The value is only valid in the transaction.
+		if v := tx.Bucket(regionKVBucket).Get(uint64ToBytes(regionID)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if value == nil {
+		return false, nil
+	}
+	return true, errors.Trace(region.Unmarshal(value))
+}
+
+// loadRegions loads the regions into the cache. The regions in the cache are
+// loaded from etcd, a local region replaces them only if it is not older.
+func (kv *regionKV) loadRegions(regions *regionsInfo) error {
+	err := kv.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(regionKVBucket).ForEach(func(k, v []byte) error {
+			region := &metapb.Region{}
+			if err := region.Unmarshal(v); err != nil {
+				return errors.Trace(err)
+			}
+
+			overlaps := regions.getOverlaps(region)
+			if origin := regions.getRegion(region.GetId()); origin != nil {
+				overlaps = append(overlaps, origin)
+			}
+			for _, item := range overlaps {
+				if isRegionOlder(region, item.Region) {
+					return nil
+				}
+			}
+			for _, item := range overlaps {
+				regions.removeRegion(item)
+			}
+			regions.setRegion(newRegionInfo(region, nil))
+			return nil
+		})
+	})
+	return errors.Trace(err)
+}
+
+func (kv *regionKV) close() error {
+	return errors.Trace(kv.db.Close())
+}
+
+// isRegionOlder checks whether the region epoch is older than the other one.
+func isRegionOlder(region, other *metapb.Region) bool {
+	r, o := region.GetRegionEpoch(), other.GetRegionEpoch()
+	if region.GetId() == other.GetId() {
+		return r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer()
+	}
+	return r.GetVersion() < o.GetVersion()
+}
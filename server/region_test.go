@@ -15,6 +15,7 @@ package server
 
 import (
 	"math"
+	"testing"
 
 	"github.com/gogo/protobuf/proto"
 	. "github.com/pingcap/check"
@@ -48,6 +49,10 @@ func (s *testRegionSuite) TestRegionInfo(c *C) {
 
 	r := info.clone()
 	c.Assert(r, DeepEquals, info)
+	// The leader is shared with the peers.
+	c.Assert(info.Leader, Equals, peers[0])
+	c.Assert(r.Leader, Equals, r.Peers[0])
+	c.Assert(newRegionInfo(region, proto.Clone(peers[1]).(*metapb.Peer)).Leader, Equals, peers[1])
 
 	for i := uint64(0); i < n; i++ {
 		c.Assert(r.GetPeer(i), Equals, r.Peers[i])
@@ -167,6 +172,43 @@ func (s *testRegionSuite) TestRegionTree(c *C) {
 	c.Assert(tree.search([]byte("e")), Equals, regionE)
 }
 
+func (s *testRegionSuite) TestRegionTreeScan(c *C) {
+	tree := newRegionTree()
+	regionA := newRegion([]byte("a"), []byte("b"))
+	regionB := newRegion([]byte("b"), []byte("c"))
+	regionD := newRegion([]byte("d"), []byte{})
+	tree.update(regionA)
+	tree.update(regionB)
+	tree.update(regionD)
+
+	scan := func(startKey string, limit int) []*metapb.Region {
+		var regions []*metapb.Region
+		tree.scanRange([]byte(startKey), func(region *metapb.Region) bool {
+			if len(regions) >= limit {
+				return false
+			}
+			regions = append(regions, region)
+			return true
+		})
+		return regions
+	}
+	c.Assert(scan("", 4), DeepEquals, []*metapb.Region{regionA, regionB, regionD})
+	c.Assert(scan("a", 2), DeepEquals, []*metapb.Region{regionA, regionB})
+	c.Assert(scan("bb", 4), DeepEquals, []*metapb.Region{regionB, regionD})
+	c.Assert(scan("c", 4), DeepEquals, []*metapb.Region{regionD})
+	c.Assert(scan("z", 4), DeepEquals, []*metapb.Region{regionD})
+
+	prev, next := tree.getAdjacentRegions(regionA)
+	c.Assert(prev, IsNil)
+	c.Assert(next, Equals, regionB)
+	prev, next = tree.getAdjacentRegions(regionB)
+	c.Assert(prev, Equals, regionA)
+	c.Assert(next, Equals, regionD)
+	prev, next = tree.getAdjacentRegions(regionD)
+	c.Assert(prev, Equals, regionB)
+	c.Assert(next, IsNil)
+}
+
 func splitRegions(regions []*metapb.Region) []*metapb.Region {
 	results := make([]*metapb.Region, 0, len(regions)*2)
 	for _, region := range regions {
@@ -268,3 +310,23 @@ func newRegion(start, end []byte) *metapb.Region {
 func newRegionItem(start, end []byte) *regionItem {
 	return &regionItem{region: newRegion(start, end)}
 }
+
+func BenchmarkRegionInfoClone(b *testing.B) {
+	region := newTestRegions(1, 3)[0]
+	region.DownPeers = []*pdpb.PeerStats{{Peer: region.Peers[1]}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		region.clone()
+	}
+}
+
+func BenchmarkRegionTreeUpdate(b *testing.B) {
+	tree := newRegionTree()
+	regions := newBenchmarkRegions(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, region := range regions {
+		tree.update(region.Region)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/logutil"
+)
+
+// reloadSkippedFields are the config fields which are not from the config
+// file, or are applied by ReloadConfig.
+var reloadSkippedFields = map[string]struct{}{
+	"FlagSet":            {},
+	"Version":            {},
+	"WarningMsgs":        {},
+	"LogFileDeprecated":  {},
+	"LogLevelDeprecated": {},
+	"Schedule":           {},
+	"Replication":        {},
+}
+
+// ReloadConfig reloads the config file. The log level, schedule and
+// replication config are applied at once, the changes of the other items are
+// logged and ignored, they take effect after restart. The command line
+// options still take precedence over the config file. Only the schedule and
+// replication items changed in the file since it was loaded last time are
+// applied, so the ones set by the API are kept unless they are changed in the
+// file too.
+func (s *Server) ReloadConfig() error {
+	if s.cfg.configFile == "" {
+		return errors.New("no config file to reload")
+	}
+	cfg := NewConfig()
+	if err := cfg.configFromFile(s.cfg.configFile); err != nil {
+		return errors.Trace(err)
+	}
+	if s.cfg.FlagSet != nil {
+		var err error
+		s.cfg.FlagSet.Visit(func(f *flag.Flag) {
+			if err == nil {
+				err = cfg.FlagSet.Set(f.Name, f.Value.String())
+			}
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := cfg.adjust(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := cfg.Replication.validate(); err != nil {
+		return errors.Trace(err)
+	}
+
+	s.updateLogLevel(cfg.Log.Level)
+
+	s.cfgLock.Lock()
+	defer s.cfgLock.Unlock()
+	if err := s.applyFileChanges(s.cfg, cfg); err != nil {
+		return errors.Trace(err)
+	}
+	s.cfg.Schedule, s.cfg.Replication = cfg.Schedule, cfg.Replication
+
+	if ignored := restartRequiredChanges(s.cfg, cfg); len(ignored) > 0 {
+		log.Warnf("config %s changed, restart to apply them", strings.Join(ignored, ", "))
+	}
+	return nil
+}
+
+// SetLogLevel changes the log level of the server at runtime. The level is
+// not persisted, the one in the config file takes effect after restart.
+func (s *Server) SetLogLevel(level string) error {
+	if err := logutil.CheckLevel(level); err != nil {
+		return errors.Trace(err)
+	}
+	s.updateLogLevel(level)
+	return nil
+}
+
+func (s *Server) updateLogLevel(level string) {
+	s.cfgLock.Lock()
+	defer s.cfgLock.Unlock()
+	if level == s.cfg.Log.Level {
+		return
+	}
+	logutil.SetLevel(level)
+	log.Infof("log level is updated: %s, old: %s", level, s.cfg.Log.Level)
+	s.cfg.Log.Level = level
+}
+
+// applyFileChanges applies the schedule and replication items changed from
+// oldFile to newFile, they are persisted together in one write.
+func (s *Server) applyFileChanges(oldFile, newFile *Config) error {
+	s.scheduleConfigLock.Lock()
+	defer s.scheduleConfigLock.Unlock()
+	schedule, replication := *s.scheduleOpt.load(), *s.scheduleOpt.rep.load()
+	mergeFileChanges(&schedule, &oldFile.Schedule, &newFile.Schedule)
+	mergeFileChanges(&replication, &oldFile.Replication, &newFile.Replication)
+	if reflect.DeepEqual(schedule, *s.scheduleOpt.load()) && reflect.DeepEqual(replication, *s.scheduleOpt.rep.load()) {
+		return nil
+	}
+	if err := schedule.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := replication.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(s.setScheduleConfigLocked(schedule, replication))
+}
+
+// mergeFileChanges sets the fields of cfg to the ones of newFile which are
+// changed from oldFile, cfg, oldFile and newFile are pointers to the same type
+// of struct.
+func mergeFileChanges(cfg, oldFile, newFile interface{}) {
+	c := reflect.ValueOf(cfg).Elem()
+	o, n := reflect.ValueOf(oldFile).Elem(), reflect.ValueOf(newFile).Elem()
+	for i := 0; i < c.NumField(); i++ {
+		if !reflect.DeepEqual(o.Field(i).Interface(), n.Field(i).Interface()) {
+			c.Field(i).Set(n.Field(i))
+		}
+	}
+}
+
+// restartRequiredChanges returns the toml names of the changed config items
+// which can't be applied at runtime.
+func restartRequiredChanges(old, cfg *Config) []string {
+	var changed []string
+	o, n := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	t := o.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, ok := reloadSkippedFields[field.Name]; ok {
+			continue
+		}
+		ov, nv := o.Field(i).Interface(), n.Field(i).Interface()
+		if field.Name == "Log" {
+			// The log level is applied already.
+			oldLog, newLog := old.Log, cfg.Log
+			oldLog.Level = newLog.Level
+			ov, nv = oldLog, newLog
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			changed = append(changed, strings.Split(field.Tag.Get("toml"), ",")[0])
+		}
+	}
+	return changed
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+var _ = Suite(&testReloadSuite{})
+
+type testReloadSuite struct{}
+
+func (s *testReloadSuite) TestReloadConfig(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	defer logutil.SetLevel(svr.cfg.Log.Level)
+
+	c.Assert(svr.ReloadConfig(), NotNil)
+
+	svr.cfg.configFile = path.Join(svr.cfg.DataDir, "pd.toml")
+	data := `
+name = "pd"
+[log]
+level = "warn"
+[schedule]
+leader-schedule-limit = 16
+[replication]
+max-replicas = 5
+`
+	c.Assert(ioutil.WriteFile(svr.cfg.configFile, []byte(data), os.ModePerm), IsNil)
+	c.Assert(svr.ReloadConfig(), IsNil)
+	c.Assert(svr.cfg.Log.Level, Equals, "warn")
+	c.Assert(svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(16))
+	c.Assert(svr.GetReplicationConfig().MaxReplicas, Equals, uint64(5))
+
+	// The config set by the API is kept if it isn't changed in the file.
+	cfg := *svr.GetScheduleConfig()
+	cfg.RegionScheduleLimit = 7
+	cfg.LeaderScheduleLimit = 8
+	c.Assert(svr.SetScheduleConfig(cfg), IsNil)
+	rep := *svr.GetReplicationConfig()
+	rep.LocationLabels = typeutil.StringSlice{"zone"}
+	c.Assert(svr.SetReplicationConfig(rep), IsNil)
+	c.Assert(svr.ReloadConfig(), IsNil)
+	c.Assert(svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(8))
+	c.Assert(svr.GetScheduleConfig().RegionScheduleLimit, Equals, uint64(7))
+	c.Assert(svr.GetReplicationConfig().LocationLabels, DeepEquals, typeutil.StringSlice{"zone"})
+
+	data = strings.Replace(data, "leader-schedule-limit = 16", "leader-schedule-limit = 32", 1)
+	c.Assert(ioutil.WriteFile(svr.cfg.configFile, []byte(data), os.ModePerm), IsNil)
+	c.Assert(svr.ReloadConfig(), IsNil)
+	c.Assert(svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(32))
+	c.Assert(svr.GetScheduleConfig().RegionScheduleLimit, Equals, uint64(7))
+	c.Assert(svr.GetReplicationConfig().MaxReplicas, Equals, uint64(5))
+	c.Assert(svr.GetReplicationConfig().LocationLabels, DeepEquals, typeutil.StringSlice{"zone"})
+
+	c.Assert(ioutil.WriteFile(svr.cfg.configFile, []byte("[schedule\n"), os.ModePerm), IsNil)
+	c.Assert(svr.ReloadConfig(), NotNil)
+	c.Assert(svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(32))
+}
+
+func (s *testReloadSuite) TestRestartRequiredChanges(c *C) {
+	old := NewTestSingleConfig()
+	defer cleanServer(old)
+	cfg := *old
+	c.Assert(restartRequiredChanges(old, &cfg), HasLen, 0)
+
+	cfg.Log.Level = "error"
+	cfg.Schedule.LeaderScheduleLimit++
+	c.Assert(restartRequiredChanges(old, &cfg), HasLen, 0)
+
+	cfg.Name = "pd1"
+	cfg.LeaderLease++
+	c.Assert(restartRequiredChanges(old, &cfg), DeepEquals, []string{"name", "lease"})
+}
+
+func (s *testReloadSuite) TestSetLogLevelConcurrently(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	defer logutil.SetLevel(svr.cfg.Log.Level)
+
+	var wg sync.WaitGroup
+	for _, level := range []string{"debug", "warn"} {
+		wg.Add(2)
+		go func(level string) {
+			defer wg.Done()
+			c.Assert(svr.SetLogLevel(level), IsNil)
+		}(level)
+		go func() {
+			defer wg.Done()
+			svr.GetConfig()
+		}()
+	}
+	wg.Wait()
+	c.Assert(svr.GetConfig().Log.Level, Matches, "debug|warn")
+	c.Assert(svr.SetLogLevel("unknown"), NotNil)
+}
@@ -0,0 +1,357 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var updateReplay = flag.Bool("update-replay", false, "update the operators of the replay cases by the replayed ones")
+
+const (
+	replayCasesPattern = "testdata/replay/*.json"
+	// defaultReplayRounds is the rounds to replay at most if it's not set by
+	// the case.
+	defaultReplayRounds = 100
+	// replayStoreCapacity is the capacity of the stores if it's not set by
+	// the case.
+	replayStoreCapacity = 1 << 40
+	replaySeed          = 1
+)
+
+// replayCase is a recorded snapshot of the cluster with the schedulers, and
+// the operators expected to be created by replaying it.
+type replayCase struct {
+	// The schedulers with their arguments, e.g. "evict-leader-scheduler 1".
+	Schedulers []string `json:"schedulers"`
+	// The configs overriding the default ones.
+	Schedule    json.RawMessage `json:"schedule,omitempty"`
+	Replication json.RawMessage `json:"replication,omitempty"`
+	Rounds      int             `json:"rounds,omitempty"`
+	Stores      []*replayStore  `json:"stores"`
+	Regions     []*replayRegion `json:"regions"`
+	Operators   []string        `json:"operators"`
+}
+
+type replayStore struct {
+	ID           uint64            `json:"id"`
+	State        string            `json:"state,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Capacity     uint64            `json:"capacity,omitempty"`
+	BytesWritten uint64            `json:"bytes-written,omitempty"`
+}
+
+// replayRegion is a region with its peers on the stores, the first store is
+// the leader.
+type replayRegion struct {
+	ID           uint64   `json:"id"`
+	Stores       []uint64 `json:"stores"`
+	Size         uint64   `json:"size,omitempty"`
+	BytesWritten uint64   `json:"bytes-written,omitempty"`
+	BytesRead    uint64   `json:"bytes-read,omitempty"`
+}
+
+type replayRegionsByID []*replayRegion
+
+func (r replayRegionsByID) Len() int           { return len(r) }
+func (r replayRegionsByID) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r replayRegionsByID) Less(i, j int) bool { return r[i].ID < r[j].ID }
+
+// lockedRand is a seeded rand safe for concurrent use.
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func (r *lockedRand) Intn(n int) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.r.Intn(n)
+}
+
+// replayCluster returns the stores sorted by ID, so the stores with the same
+// score are always selected in the same order by the schedulers.
+type replayCluster struct {
+	*clusterInfo
+}
+
+func (c replayCluster) GetStores() []*StoreInfo {
+	return sortStoresByID(c.clusterInfo.GetStores())
+}
+
+func (c replayCluster) GetRegionStores(region *RegionInfo) []*StoreInfo {
+	return sortStoresByID(c.clusterInfo.GetRegionStores(region))
+}
+
+func (c replayCluster) GetFollowerStores(region *RegionInfo) []*StoreInfo {
+	return sortStoresByID(c.clusterInfo.GetFollowerStores(region))
+}
+
+type storesByID []*StoreInfo
+
+func (s storesByID) Len() int           { return len(s) }
+func (s storesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s storesByID) Less(i, j int) bool { return s[i].GetId() < s[j].GetId() }
+
+func sortStoresByID(stores []*StoreInfo) []*StoreInfo {
+	sort.Sort(storesByID(stores))
+	return stores
+}
+
+// scheduleReplayer replays a case without running the coordinator. It runs
+// the schedulers and dispatches the regions in turn, and applies the
+// responses to the regions in place of TiKV, so the replay is deterministic.
+// The flows are reported by the region heartbeats, but the hot region
+// schedulers are not replayed deterministically yet, they break the ties of
+// the stores by the map order.
+type scheduleReplayer struct {
+	c          *C
+	cluster    *clusterInfo
+	sorted     replayCluster
+	co         *coordinator
+	schedulers []*scheduleController
+	flows      map[uint64]*replayRegion
+	operators  []string
+}
+
+func newScheduleReplayer(c *C, rc *replayCase) *scheduleReplayer {
+	cfg := NewConfig()
+	cfg.adjust()
+	if rc.Schedule != nil {
+		c.Assert(json.Unmarshal(rc.Schedule, &cfg.Schedule), IsNil)
+	}
+	if rc.Replication != nil {
+		c.Assert(json.Unmarshal(rc.Replication, &cfg.Replication), IsNil)
+	}
+	opt := newScheduleOption(cfg)
+	cluster := newClusterInfo(newMockIDAllocator())
+	// The random regions are picked under the read lock of the cluster.
+	cluster.regions.randIntn = newLockedRand(replaySeed).Intn
+	r := &scheduleReplayer{
+		c:       c,
+		cluster: cluster,
+		sorted:  replayCluster{clusterInfo: cluster},
+		co:      newCoordinator(cluster, opt),
+		flows:   make(map[uint64]*replayRegion),
+	}
+	r.co.checker = newReplicaChecker(opt, r.sorted)
+
+	for _, args := range rc.Schedulers {
+		fields := strings.Fields(args)
+		s, err := CreateScheduler(fields[0], opt, fields[1:]...)
+		c.Assert(err, IsNil)
+		c.Assert(s.Prepare(r.sorted), IsNil)
+		r.schedulers = append(r.schedulers, newScheduleController(r.co, s, minScheduleInterval))
+	}
+
+	sizes := make(map[uint64]uint64)
+	for _, region := range rc.Regions {
+		for _, id := range region.Stores {
+			sizes[id] += region.Size
+		}
+	}
+	for _, s := range rc.Stores {
+		store := &metapb.Store{Id: s.ID, Address: fmt.Sprintf("mock://tikv-%d", s.ID)}
+		if s.State != "" {
+			state, ok := metapb.StoreState_value[s.State]
+			c.Assert(ok, IsTrue, Commentf("unknown store state %s", s.State))
+			store.State = metapb.StoreState(state)
+		}
+		for k, v := range s.Labels {
+			store.Labels = append(store.Labels, &metapb.StoreLabel{Key: k, Value: v})
+		}
+		c.Assert(cluster.putStore(newStoreInfo(store)), IsNil)
+
+		capacity := s.Capacity
+		if capacity == 0 {
+			capacity = replayStoreCapacity
+		}
+		c.Assert(cluster.handleStoreHeartbeat(&pdpb.StoreStats{
+			StoreId:      s.ID,
+			Capacity:     capacity,
+			Available:    capacity - sizes[s.ID],
+			UsedSize:     sizes[s.ID],
+			BytesWritten: s.BytesWritten,
+		}), IsNil)
+	}
+
+	sort.Sort(replayRegionsByID(rc.Regions))
+	for i, region := range rc.Regions {
+		meta := &metapb.Region{
+			Id:          region.ID,
+			StartKey:    []byte(fmt.Sprintf("%08d", region.ID)),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		if i+1 < len(rc.Regions) {
+			meta.EndKey = []byte(fmt.Sprintf("%08d", rc.Regions[i+1].ID))
+		}
+		for _, id := range region.Stores {
+			peer, err := cluster.AllocPeer(id)
+			c.Assert(err, IsNil)
+			meta.Peers = append(meta.Peers, peer)
+		}
+		c.Assert(meta.Peers, Not(HasLen), 0)
+		info := newRegionInfo(meta, meta.Peers[0])
+		info.ApproximateSize = region.Size
+		r.flows[region.ID] = region
+		r.heartbeat(info)
+	}
+	return r
+}
+
+// heartbeat reports the region with its flow as TiKV does.
+func (r *scheduleReplayer) heartbeat(region *RegionInfo) {
+	flow := r.flows[region.GetId()]
+	region.WrittenBytes, region.ReadBytes = flow.BytesWritten, flow.BytesRead
+	r.c.Assert(r.cluster.handleRegionHeartbeat(region), IsNil)
+}
+
+// run replays the rounds until no operator is created in a round.
+func (r *scheduleReplayer) run(rounds int) {
+	for i := 0; i < rounds; i++ {
+		count := len(r.operators)
+		r.schedule()
+		r.dispatchRegions()
+		if len(r.operators) == count && len(r.co.getOperators()) == 0 {
+			return
+		}
+	}
+	r.c.Fatalf("the replay is not finished in %d rounds, the operators: %v", rounds, r.operators)
+}
+
+func (r *scheduleReplayer) schedule() {
+	for _, s := range r.schedulers {
+		if !s.AllowSchedule() {
+			continue
+		}
+		if op := s.Schedule(r.sorted, r.co.getOpInfluence()); op != nil && r.co.addOperatorFrom(s.GetName(), op) {
+			r.record(s.GetName(), op)
+		}
+	}
+}
+
+// dispatchRegions dispatches the regions by ID order, a region is dispatched
+// and changed by the responses until its operator is finished.
+func (r *scheduleReplayer) dispatchRegions() {
+	regions := r.cluster.getRegions()
+	sort.Sort(regionsByID(regions))
+	for _, region := range regions {
+		region = r.cluster.GetRegion(region.GetId())
+		for i := 0; ; i++ {
+			r.c.Assert(i < 10, IsTrue, Commentf("region %d is not finished: %v", region.GetId(), r.co.getOperator(region.GetId())))
+			old := r.co.getOperator(region.GetId())
+			resp := r.co.dispatch(region)
+			if op := r.co.getOperator(region.GetId()); op != nil && op != old {
+				r.record(r.co.getOperatorSource(region.GetId()), op)
+			}
+			if resp == nil {
+				break
+			}
+			region = r.apply(region, resp)
+			r.heartbeat(region)
+		}
+	}
+}
+
+// apply changes the region by the response as TiKV does.
+func (r *scheduleReplayer) apply(region *RegionInfo, resp *pdpb.RegionHeartbeatResponse) *RegionInfo {
+	region = region.clone()
+	if change := resp.GetChangePeer(); change != nil {
+		peer := change.GetPeer()
+		switch change.GetChangeType() {
+		case pdpb.ConfChangeType_AddNode:
+			region.Peers = append(region.Peers, peer)
+		case pdpb.ConfChangeType_RemoveNode:
+			r.c.Assert(peer.GetId(), Not(Equals), region.Leader.GetId())
+			region.RemoveStorePeer(peer.GetStoreId())
+		}
+		region.RegionEpoch.ConfVer++
+	} else if transfer := resp.GetTransferLeader(); transfer != nil {
+		region.Leader = region.GetPeer(transfer.GetPeer().GetId())
+		r.c.Assert(region.Leader, NotNil)
+	} else {
+		r.c.Fatalf("unsupported response %v", resp)
+	}
+	return region
+}
+
+func (r *scheduleReplayer) record(source string, op Operator) {
+	r.operators = append(r.operators, fmt.Sprintf("%s region %d: %s", source, op.GetRegionID(), describeOperator(op)))
+}
+
+// describeOperator describes the steps of the operator by the stores.
+func describeOperator(op Operator) string {
+	switch o := op.(type) {
+	case *regionOperator:
+		steps := make([]string, 0, len(o.Ops))
+		for _, step := range o.Ops {
+			steps = append(steps, describeOperator(step))
+		}
+		return strings.Join(steps, ", ")
+	case *changePeerOperator:
+		return fmt.Sprintf("%s %d", o.Name, o.ChangePeer.GetPeer().GetStoreId())
+	case *transferLeaderOperator:
+		return fmt.Sprintf("%s %d->%d", o.Name, o.OldLeader.GetStoreId(), o.NewLeader.GetStoreId())
+	}
+	return op.GetName()
+}
+
+var _ = Suite(&testReplaySuite{})
+
+type testReplaySuite struct{}
+
+func (s *testReplaySuite) TestReplay(c *C) {
+	files, err := filepath.Glob(replayCasesPattern)
+	c.Assert(err, IsNil)
+	c.Assert(files, Not(HasLen), 0)
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		c.Assert(err, IsNil)
+		rc := &replayCase{}
+		c.Assert(json.Unmarshal(data, rc), IsNil, Commentf("%s", file))
+
+		r := newScheduleReplayer(c, rc)
+		rounds := rc.Rounds
+		if rounds == 0 {
+			rounds = defaultReplayRounds
+		}
+		r.run(rounds)
+
+		if *updateReplay {
+			rc.Operators = r.operators
+			data, err = json.MarshalIndent(rc, "", "  ")
+			c.Assert(err, IsNil)
+			c.Assert(ioutil.WriteFile(file, append(data, '\n'), 0644), IsNil)
+			continue
+		}
+		c.Assert(r.operators, DeepEquals, rc.Operators, Commentf("%s", file))
+	}
+}
@@ -59,30 +59,22 @@ func (r *Replication) GetLocationLabels() []string {
 
 // GetDistinctScore returns the score that the other is distinct from the stores.
 // A higher score means the other store is more different from the existed stores.
-func (r *Replication) GetDistinctScore(stores []*storeInfo, other *storeInfo) float64 {
+// The location labels are a hierarchy, if the other store is different from a
+// store at a level, it is different at all the lower levels too, and the score
+// of a level outweighs all the lower levels, so the isolation at the highest
+// level is maximized first.
+func (r *Replication) GetDistinctScore(stores []*StoreInfo, other *StoreInfo) float64 {
 	score := float64(0)
 	locationLabels := r.GetLocationLabels()
 
-	for i := range locationLabels {
-		keys := locationLabels[0 : i+1]
-		level := len(locationLabels) - i - 1
-		levelScore := math.Pow(replicaBaseScore, float64(level))
-
-		for _, s := range stores {
-			if s.GetId() == other.GetId() {
-				continue
-			}
-			id1 := s.getLocationID(keys)
-			if len(id1) == 0 {
-				return 0
-			}
-			id2 := other.getLocationID(keys)
-			if len(id2) == 0 {
-				return 0
-			}
-			if id1 != id2 {
-				score += levelScore
-			}
+	for _, s := range stores {
+		if s.GetId() == other.GetId() {
+			continue
+		}
+		diffLevel := s.getLocationDiffLevel(other, locationLabels)
+		for i := diffLevel; i < len(locationLabels); i++ {
+			level := len(locationLabels) - i - 1
+			score += math.Pow(replicaBaseScore, float64(level))
 		}
 	}
 
@@ -93,7 +85,7 @@ func (r *Replication) GetDistinctScore(stores []*storeInfo, other *storeInfo) fl
 // Returns 0 if store A is as good as store B.
 // Returns 1 if store A is better than store B.
 // Returns -1 if store B is better than store A.
-func compareStoreScore(storeA *storeInfo, scoreA float64, storeB *storeInfo, scoreB float64) int {
+func compareStoreScore(storeA *StoreInfo, scoreA float64, storeB *StoreInfo, scoreB float64, weights StoreScoreConfig) int {
 	// The store with higher score is better.
 	if scoreA > scoreB {
 		return 1
@@ -102,10 +94,10 @@ func compareStoreScore(storeA *storeInfo, scoreA float64, storeB *storeInfo, sco
 		return -1
 	}
 	// The store with lower region score is better.
-	if storeA.regionScore() < storeB.regionScore() {
+	if storeA.regionScore(weights) < storeB.regionScore(weights) {
 		return 1
 	}
-	if storeA.regionScore() > storeB.regionScore() {
+	if storeA.regionScore(weights) > storeB.regionScore(weights) {
 		return -1
 	}
 	return 0
@@ -36,7 +36,7 @@ func (s *testReplicationSuite) TestDistinctScore(c *C) {
 	racks := []string{"r1", "r2", "r3"}
 	hosts := []string{"h1", "h2", "h3"}
 
-	var stores []*storeInfo
+	var stores []*StoreInfo
 	for i, zone := range zones {
 		for j, rack := range racks {
 			for k, host := range hosts {
@@ -47,7 +47,7 @@ func (s *testReplicationSuite) TestDistinctScore(c *C) {
 					"host": host,
 				}
 				tc.addLabelsStore(storeID, 1, labels)
-				store := cluster.getStore(storeID)
+				store := cluster.GetStore(storeID)
 				stores = append(stores, store)
 
 				// Number of stores with different zones.
@@ -63,8 +63,25 @@ func (s *testReplicationSuite) TestDistinctScore(c *C) {
 	}
 
 	tc.addLabelsStore(100, 1, map[string]string{})
-	store := cluster.getStore(100)
+	store := cluster.GetStore(100)
 	c.Assert(rep.GetDistinctScore(stores, store), Equals, float64(0))
+
+	// The labels of each level are compared separately.
+	tc.addLabelsStore(101, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
+	tc.addLabelsStore(102, 1, map[string]string{"zone": "z1r", "rack": "1", "host": "h1"})
+	stores = []*StoreInfo{cluster.GetStore(101)}
+	score := (replicaBaseScore+1)*replicaBaseScore + 1
+	c.Assert(rep.GetDistinctScore(stores, cluster.GetStore(102)), Equals, float64(score))
+
+	// The missing labels are treated as the same location, the other levels
+	// still count.
+	tc.addLabelsStore(103, 1, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(104, 1, map[string]string{"zone": "z1", "host": "h2"})
+	score = (replicaBaseScore+1)*replicaBaseScore + 1
+	c.Assert(rep.GetDistinctScore(stores, cluster.GetStore(103)), Equals, float64(score))
+	c.Assert(rep.GetDistinctScore(stores, cluster.GetStore(104)), Equals, float64(1))
+	stores = append(stores, cluster.GetStore(100))
+	c.Assert(rep.GetDistinctScore(stores, cluster.GetStore(103)), Equals, float64(score))
 }
 
 func (s *testReplicationSuite) TestCompareStoreScore(c *C) {
@@ -75,15 +92,17 @@ func (s *testReplicationSuite) TestCompareStoreScore(c *C) {
 	tc.addRegionStore(2, 1)
 	tc.addRegionStore(3, 3)
 
-	store1 := cluster.getStore(1)
-	store2 := cluster.getStore(2)
-	store3 := cluster.getStore(3)
+	store1 := cluster.GetStore(1)
+	store2 := cluster.GetStore(2)
+	store3 := cluster.GetStore(3)
+	_, opt := newTestScheduleConfig()
+	weights := opt.GetStoreScore()
 
-	c.Assert(compareStoreScore(store1, 2, store2, 1), Equals, 1)
-	c.Assert(compareStoreScore(store1, 1, store2, 1), Equals, 0)
-	c.Assert(compareStoreScore(store1, 1, store2, 2), Equals, -1)
+	c.Assert(compareStoreScore(store1, 2, store2, 1, weights), Equals, 1)
+	c.Assert(compareStoreScore(store1, 1, store2, 1, weights), Equals, 0)
+	c.Assert(compareStoreScore(store1, 1, store2, 2, weights), Equals, -1)
 
-	c.Assert(compareStoreScore(store1, 2, store3, 1), Equals, 1)
-	c.Assert(compareStoreScore(store1, 1, store3, 1), Equals, 1)
-	c.Assert(compareStoreScore(store1, 1, store3, 2), Equals, -1)
+	c.Assert(compareStoreScore(store1, 2, store3, 1, weights), Equals, 1)
+	c.Assert(compareStoreScore(store1, 1, store3, 1, weights), Equals, 1)
+	c.Assert(compareStoreScore(store1, 1, store3, 2, weights), Equals, -1)
 }
@@ -0,0 +1,261 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// scatterRangeScanLimit is the number of regions scanned in a batch.
+const scatterRangeScanLimit = 1024
+
+func init() {
+	// The args are the range name and the hex encoded start and end keys.
+	RegisterScheduler(scatterRangeSchedulerType, func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		if len(args) != 3 {
+			return nil, errors.New("should specify the range name, start key and end key")
+		}
+		rangeName := args[0]
+		if rangeName == "" || strings.Contains(rangeName, "/") {
+			return nil, errors.Errorf("invalid range name %q", rangeName)
+		}
+		startKey, err := hex.DecodeString(args[1])
+		if err != nil {
+			return nil, errors.Errorf("invalid start key %q", args[1])
+		}
+		endKey, err := hex.DecodeString(args[2])
+		if err != nil {
+			return nil, errors.Errorf("invalid end key %q", args[2])
+		}
+		if len(endKey) > 0 && bytes.Compare(startKey, endKey) >= 0 {
+			return nil, errors.New("start key must be less than end key")
+		}
+		return newScatterRangeScheduler(opt, rangeName, startKey, endKey), nil
+	})
+}
+
+// scatterRangeScheduler keeps the leaders and peers of the regions in a key
+// range evenly distributed across stores. The regions are balanced only
+// against each other, so a hot table stays spread out even if the stores are
+// balanced as a whole. It's a RegionKind scheduler, but the leader transfers
+// are LeaderKind operators limited by the leader schedule limit.
+type scatterRangeScheduler struct {
+	opt           ScheduleOptions
+	name          string
+	startKey      []byte
+	endKey        []byte
+	filters       []Filter
+	targetFilters []Filter
+}
+
+func newScatterRangeScheduler(opt ScheduleOptions, rangeName string, startKey, endKey []byte) *scatterRangeScheduler {
+	var filters []Filter
+	filters = append(filters, newStateFilter(opt))
+	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
+
+	var targetFilters []Filter
+	targetFilters = append(targetFilters, newSnapshotCountFilter(opt))
+	targetFilters = append(targetFilters, newPendingPeerCountFilter(opt))
+	targetFilters = append(targetFilters, newStorageThresholdFilter(opt))
+
+	return &scatterRangeScheduler{
+		opt:           opt,
+		name:          fmt.Sprintf("%s-%s", scatterRangeSchedulerType, rangeName),
+		startKey:      startKey,
+		endKey:        endKey,
+		filters:       filters,
+		targetFilters: targetFilters,
+	}
+}
+
+func (s *scatterRangeScheduler) GetName() string {
+	return s.name
+}
+
+func (s *scatterRangeScheduler) GetResourceKind() ResourceKind {
+	return RegionKind
+}
+
+func (s *scatterRangeScheduler) GetResourceLimit() uint64 {
+	return s.opt.GetRegionScheduleLimit()
+}
+
+func (s *scatterRangeScheduler) Prepare(cluster Cluster) error { return nil }
+
+func (s *scatterRangeScheduler) Cleanup(cluster Cluster) {}
+
+func (s *scatterRangeScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	regions := s.getRangeRegions(cluster)
+	if len(regions) == 0 {
+		return nil
+	}
+
+	leaderCounts := make(map[uint64]int)
+	peerCounts := make(map[uint64]int)
+	for _, store := range cluster.GetStores() {
+		if !filterSource(store, s.filters) {
+			leaderCounts[store.GetId()] = 0
+			peerCounts[store.GetId()] = 0
+		}
+	}
+	for _, region := range regions {
+		if _, ok := leaderCounts[region.Leader.GetStoreId()]; ok {
+			leaderCounts[region.Leader.GetStoreId()]++
+		}
+		for _, peer := range region.GetPeers() {
+			if _, ok := peerCounts[peer.GetStoreId()]; ok {
+				peerCounts[peer.GetStoreId()]++
+			}
+		}
+	}
+
+	// Scattering the leaders is paused if the leader schedule limit is 0.
+	if s.opt.GetLeaderScheduleLimit() > 0 {
+		if op := s.scatterLeader(cluster, regions, leaderCounts); op != nil {
+			return op
+		}
+	}
+	return s.scatterPeer(cluster, regions, peerCounts)
+}
+
+// getRangeRegions returns the regions overlapping the key range.
+func (s *scatterRangeScheduler) getRangeRegions(cluster Cluster) []*RegionInfo {
+	var regions []*RegionInfo
+	key := s.startKey
+	for {
+		batch := cluster.ScanRegions(key, s.endKey, scatterRangeScanLimit)
+		regions = append(regions, batch...)
+		if len(batch) < scatterRangeScanLimit {
+			return regions
+		}
+		key = batch[len(batch)-1].GetEndKey()
+		if len(key) == 0 {
+			return regions
+		}
+	}
+}
+
+// scatterLeader transfers a leader from the store with the most leaders in
+// the range to a follower store with fewer leaders.
+func (s *scatterRangeScheduler) scatterLeader(cluster Cluster, regions []*RegionInfo, counts map[uint64]int) Operator {
+	source := maxCountStore(counts)
+	if source == 0 || counts[source]-minCount(counts) <= 1 {
+		return nil
+	}
+
+	for _, i := range rand.Perm(len(regions)) {
+		region := regions[i]
+		if region.Leader.GetStoreId() != source || len(region.DownPeers) != 0 || len(region.PendingPeers) != 0 {
+			continue
+		}
+		var target *metapb.Peer
+		for storeID, peer := range region.GetFollowers() {
+			count, ok := counts[storeID]
+			if !ok || count+1 >= counts[source] {
+				continue
+			}
+			if target == nil || count < counts[target.GetStoreId()] {
+				target = peer
+			}
+		}
+		if target != nil {
+			return newTransferLeader(region, target)
+		}
+	}
+	return nil
+}
+
+// scatterPeer moves a peer from the store with the most peers in the range to
+// a store with fewer peers.
+func (s *scatterRangeScheduler) scatterPeer(cluster Cluster, regions []*RegionInfo, counts map[uint64]int) Operator {
+	source := maxCountStore(counts)
+	if source == 0 || counts[source]-minCount(counts) <= 1 {
+		return nil
+	}
+
+	checker := newReplicaChecker(s.opt, cluster)
+	for _, i := range rand.Perm(len(regions)) {
+		region := regions[i]
+		oldPeer := region.GetStorePeer(source)
+		if oldPeer == nil || len(region.DownPeers) != 0 || len(region.PendingPeers) != 0 {
+			continue
+		}
+		if len(region.GetPeers()) != checker.getMaxReplicas(region) {
+			continue
+		}
+
+		stores := cluster.GetRegionStores(region)
+		filters := append([]Filter(nil), s.targetFilters...)
+		filters = append(filters, checker.placementFilters(region, oldPeer)...)
+		filters = append(filters,
+			newExcludedFilter(nil, region.GetStoreIds()),
+			newDistinctScoreFilter(s.opt.GetReplication(), stores, cluster.GetStore(source)),
+			newRegionNamespaceFilter(s.opt, region),
+		)
+		var target *StoreInfo
+		for _, store := range cluster.GetStores() {
+			count, ok := counts[store.GetId()]
+			if !ok || count+1 >= counts[source] || filterTarget(store, filters) {
+				continue
+			}
+			if target == nil || count < counts[target.GetId()] {
+				target = store
+			}
+		}
+		if target == nil {
+			continue
+		}
+
+		newPeer, err := cluster.AllocPeer(target.GetId())
+		if err != nil {
+			log.Errorf("failed to allocate peer: %v", err)
+			return nil
+		}
+		return newTransferPeer(region, oldPeer, newPeer)
+	}
+	return nil
+}
+
+// maxCountStore returns the store with the max count, 0 if counts is empty.
+func maxCountStore(counts map[uint64]int) uint64 {
+	var (
+		storeID  uint64
+		maxCount = -1
+	)
+	for id, count := range counts {
+		if count > maxCount || (count == maxCount && id < storeID) {
+			storeID, maxCount = id, count
+		}
+	}
+	return storeID
+}
+
+func minCount(counts map[uint64]int) int {
+	min := -1
+	for _, count := range counts {
+		if min < 0 || count < min {
+			min = count
+		}
+	}
+	return min
+}
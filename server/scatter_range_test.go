@@ -0,0 +1,103 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testScatterRangeSuite{})
+
+type testScatterRangeSuite struct{}
+
+func (s *testScatterRangeSuite) TestScatterRange(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	sr := newScatterRangeScheduler(opt, "t1", []byte("b"), []byte("e"))
+	c.Assert(sr.GetName(), Equals, "scatter-range-scheduler-t1")
+	c.Assert(sr.Schedule(cluster, nil), IsNil)
+
+	for i := uint64(1); i <= 4; i++ {
+		tc.addRegionStore(i, 0)
+	}
+	// Regions [b, c), [c, d), [d, e) are in the range, all of them are in
+	// stores 1, 2, 3 with leaders in store 1.
+	keys := []string{"", "b", "c", "d", "e", ""}
+	for i := 0; i < len(keys)-1; i++ {
+		region := &metapb.Region{
+			Id:       uint64(i + 1),
+			StartKey: []byte(keys[i]),
+			EndKey:   []byte(keys[i+1]),
+		}
+		for storeID := uint64(1); storeID <= 3; storeID++ {
+			peer, _ := tc.AllocPeer(storeID)
+			region.Peers = append(region.Peers, peer)
+		}
+		tc.putRegion(newRegionInfo(region, region.Peers[0]))
+	}
+	c.Assert(sr.getRangeRegions(cluster), HasLen, 3)
+
+	// The leaders are scattered first, unless it's paused by the leader
+	// schedule limit.
+	cfg.LeaderScheduleLimit = 0
+	op := sr.Schedule(cluster, nil)
+	c.Assert(op, NotNil)
+	c.Assert(op.GetResourceKind(), Equals, RegionKind)
+	cfg.LeaderScheduleLimit = defaultLeaderScheduleLimit
+	op = sr.Schedule(cluster, nil)
+	checkTransferLeaderFrom(c, op, 1)
+	c.Assert(op.GetResourceKind(), Equals, LeaderKind)
+
+	for i := 0; i < 100; i++ {
+		op := sr.Schedule(cluster, nil)
+		if op == nil {
+			break
+		}
+		region := cluster.GetRegion(op.GetRegionID())
+		for j := 0; j < 10; j++ {
+			res, finished := op.Do(region)
+			if finished {
+				break
+			}
+			doRegionHeartbeatResponse(region, res)
+		}
+		tc.putRegion(region)
+	}
+	c.Assert(sr.Schedule(cluster, nil), IsNil)
+
+	leaderCounts := make(map[uint64]int)
+	peerCounts := make(map[uint64]int)
+	for _, region := range sr.getRangeRegions(cluster) {
+		leaderCounts[region.Leader.GetStoreId()]++
+		for _, peer := range region.GetPeers() {
+			peerCounts[peer.GetStoreId()]++
+		}
+	}
+	for _, count := range leaderCounts {
+		c.Assert(count, Equals, 1)
+	}
+	for storeID := uint64(1); storeID <= 4; storeID++ {
+		c.Assert(peerCounts[storeID], Greater, 1)
+	}
+
+	// The regions out of the range are not scheduled.
+	for _, id := range []uint64{1, 5} {
+		region := cluster.GetRegion(id)
+		c.Assert(region.Leader.GetStoreId(), Equals, uint64(1))
+		c.Assert(region.GetStorePeer(4), IsNil)
+	}
+}
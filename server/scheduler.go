@@ -16,33 +16,223 @@ package server
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 )
 
+// Cluster is the cluster state read by the schedulers. The schedulers
+// registered by other packages read it by the exported methods.
+type Cluster interface {
+	GetStore(storeID uint64) *StoreInfo
+	GetStores() []*StoreInfo
+	GetRegion(regionID uint64) *RegionInfo
+	GetRegionStores(region *RegionInfo) []*StoreInfo
+	GetFollowerStores(region *RegionInfo) []*StoreInfo
+	GetLeaderStore(region *RegionInfo) *StoreInfo
+	GetAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo)
+	ScanRegions(startKey, endKey []byte, limit int) []*RegionInfo
+	RandomRegion() *RegionInfo
+	RandLeaderRegion(storeID uint64) *RegionInfo
+	RandFollowerRegion(storeID uint64) *RegionInfo
+	getHotRegionStoreStats(kind hotFlowKind, minHotDegree int) *StoreHotRegionInfos
+	AllocPeer(storeID uint64) (*metapb.Peer, error)
+	BlockStore(storeID uint64) error
+	UnblockStore(storeID uint64)
+}
+
 // Scheduler is an interface to schedule resources.
 type Scheduler interface {
 	GetName() string
 	GetResourceKind() ResourceKind
 	GetResourceLimit() uint64
-	Prepare(cluster *clusterInfo) error
-	Cleanup(cluster *clusterInfo)
-	Schedule(cluster *clusterInfo) Operator
+	Prepare(cluster Cluster) error
+	Cleanup(cluster Cluster)
+	Schedule(cluster Cluster, influence OpInfluence) Operator
+}
+
+// ScheduleOptions are the schedule options read by the schedulers.
+type ScheduleOptions interface {
+	GetReplication() *Replication
+	GetMaxSnapshotCount() uint64
+	GetMaxStoreDownTime() time.Duration
+	GetMaxPendingPeerCount() uint64
+	GetLeaderScheduleLimit() uint64
+	GetRegionScheduleLimit() uint64
+	GetHotRegionThreshold() int
+	GetLeaderSchedulePolicy() string
+	GetTolerantSizeRatio() float64
+	GetHighSpaceRatio() float64
+	GetLowSpaceRatio() float64
+	GetStoreScore() StoreScoreConfig
+	CheckLabelProperty(typ string, labels []*metapb.StoreLabel) bool
+
+	getRules() *placementRules
+	getNamespaces() *namespacesInfo
+	getRegionMaxReplicas(region *RegionInfo) int
+}
+
+// CreateSchedulerFunc creates a scheduler from the arguments, the arguments
+// are the same as the ones persisted in the scheduler config.
+type CreateSchedulerFunc func(opt ScheduleOptions, args []string) (Scheduler, error)
+
+// schedulerMap is the registered scheduler creators by their types.
+var schedulerMap = struct {
+	sync.RWMutex
+	m map[string]CreateSchedulerFunc
+}{m: make(map[string]CreateSchedulerFunc)}
+
+// RegisterScheduler binds a scheduler type with its creator, so the scheduler
+// can be added by API and restored from the persisted config. It should be
+// called in init(), and panics if the type is registered twice.
+func RegisterScheduler(typ string, createFn CreateSchedulerFunc) {
+	schedulerMap.Lock()
+	defer schedulerMap.Unlock()
+	if _, ok := schedulerMap.m[typ]; ok {
+		panic(fmt.Sprintf("duplicated scheduler type %q", typ))
+	}
+	schedulerMap.m[typ] = createFn
+}
+
+func getCreateSchedulerFunc(typ string) (CreateSchedulerFunc, bool) {
+	schedulerMap.RLock()
+	defer schedulerMap.RUnlock()
+	createFn, ok := schedulerMap.m[typ]
+	return createFn, ok
+}
+
+// IsSchedulerRegistered checks whether the scheduler type is registered.
+func IsSchedulerRegistered(typ string) bool {
+	_, ok := getCreateSchedulerFunc(typ)
+	return ok
+}
+
+// CreateScheduler creates a scheduler of the registered type.
+func CreateScheduler(typ string, opt ScheduleOptions, args ...string) (Scheduler, error) {
+	createFn, ok := getCreateSchedulerFunc(typ)
+	if !ok {
+		return nil, errors.Errorf("unknown scheduler type %q", typ)
+	}
+	s, err := createFn(opt, args)
+	return s, errors.Trace(err)
+}
+
+// Types of the schedulers whose names are different from their types.
+const (
+	evictLeaderSchedulerType  = "evict-leader-scheduler"
+	grantLeaderSchedulerType  = "grant-leader-scheduler"
+	scatterRangeSchedulerType = "scatter-range-scheduler"
+)
+
+// schedulerConfig is the persisted config of a scheduler added at runtime, the
+// scheduler is restored from it after PD restarts or the leader changes.
+type schedulerConfig struct {
+	Type string   `json:"type"`
+	Args []string `json:"args,omitempty"`
+}
+
+// legacySchedulerConfig is the scheduler config persisted before the
+// schedulers are created by arguments, the keys are hex encoded.
+type legacySchedulerConfig struct {
+	schedulerConfig
+	StoreID   uint64 `json:"store_id,omitempty"`
+	RangeName string `json:"range_name,omitempty"`
+	StartKey  string `json:"start_key,omitempty"`
+	EndKey    string `json:"end_key,omitempty"`
+}
+
+// upgrade converts the legacy fields to the arguments of the scheduler.
+func (cfg *legacySchedulerConfig) upgrade() *schedulerConfig {
+	if len(cfg.Args) > 0 {
+		return &cfg.schedulerConfig
+	}
+	switch {
+	case cfg.StoreID != 0:
+		cfg.Args = []string{strconv.FormatUint(cfg.StoreID, 10)}
+	case cfg.RangeName != "":
+		cfg.Args = []string{cfg.RangeName, cfg.StartKey, cfg.EndKey}
+	}
+	return &cfg.schedulerConfig
+}
+
+func newSchedulerFromConfig(opt ScheduleOptions, cfg *schedulerConfig) (Scheduler, error) {
+	return CreateScheduler(cfg.Type, opt, cfg.Args...)
+}
+
+func init() {
+	RegisterScheduler(grantLeaderSchedulerType, func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		storeID, err := parseStoreIDArgs(args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return newGrantLeaderScheduler(opt, storeID), nil
+	})
+	RegisterScheduler(evictLeaderSchedulerType, func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		storeID, err := parseStoreIDArgs(args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return newEvictLeaderScheduler(opt, storeID), nil
+	})
+	RegisterScheduler("shuffle-leader-scheduler", func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		limit, err := parseLimitArgs(args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return newShuffleLeaderScheduler(opt, limit), nil
+	})
+	RegisterScheduler("shuffle-region-scheduler", func(opt ScheduleOptions, args []string) (Scheduler, error) {
+		limit, err := parseLimitArgs(args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return newShuffleRegionScheduler(opt, limit), nil
+	})
+}
+
+// parseStoreIDArgs parses the store id of the schedulers for a store.
+func parseStoreIDArgs(args []string) (uint64, error) {
+	if len(args) != 1 {
+		return 0, errors.New("should specify the store id")
+	}
+	storeID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid store id %q", args[0])
+	}
+	return storeID, nil
 }
 
-// grantLeaderScheduler transfers all leaders to peers in the store.
+// parseLimitArgs parses the optional limit of the shuffle schedulers.
+func parseLimitArgs(args []string) (uint64, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	if len(args) > 1 {
+		return 0, errors.New("too many arguments")
+	}
+	limit, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid limit %q", args[0])
+	}
+	return limit, nil
+}
+
+// grantLeaderScheduler transfers all leaders to peers in the store, and blocks
+// the store from balance until it is removed.
 type grantLeaderScheduler struct {
-	opt     *scheduleOption
+	opt     ScheduleOptions
 	name    string
 	storeID uint64
 }
 
-func newGrantLeaderScheduler(opt *scheduleOption, storeID uint64) *grantLeaderScheduler {
+func newGrantLeaderScheduler(opt ScheduleOptions, storeID uint64) *grantLeaderScheduler {
 	return &grantLeaderScheduler{
 		opt:     opt,
-		name:    fmt.Sprintf("grant-leader-scheduler-%d", storeID),
+		name:    fmt.Sprintf("%s-%d", grantLeaderSchedulerType, storeID),
 		storeID: storeID,
 	}
 }
@@ -59,37 +249,45 @@ func (s *grantLeaderScheduler) GetResourceLimit() uint64 {
 	return s.opt.GetLeaderScheduleLimit()
 }
 
-func (s *grantLeaderScheduler) Prepare(cluster *clusterInfo) error {
-	return errors.Trace(cluster.blockStore(s.storeID))
+func (s *grantLeaderScheduler) Prepare(cluster Cluster) error {
+	return errors.Trace(cluster.BlockStore(s.storeID))
 }
 
-func (s *grantLeaderScheduler) Cleanup(cluster *clusterInfo) {
-	cluster.unblockStore(s.storeID)
+func (s *grantLeaderScheduler) Cleanup(cluster Cluster) {
+	cluster.UnblockStore(s.storeID)
 }
 
-func (s *grantLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
-	region := cluster.randFollowerRegion(s.storeID)
+func (s *grantLeaderScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	region := cluster.RandFollowerRegion(s.storeID)
 	if region == nil {
 		return nil
 	}
-	return newTransferLeader(region, region.GetStorePeer(s.storeID))
+	peer := region.GetStorePeer(s.storeID)
+	// A down or pending peer may be behind the leader, don't let it take over.
+	if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+		return nil
+	}
+	return newTransferLeader(region, peer)
 }
 
+// evictLeaderScheduler keeps transferring the leaders out of the store until
+// it is removed, and blocks the store from balance.
 type evictLeaderScheduler struct {
-	opt      *scheduleOption
+	opt      ScheduleOptions
 	name     string
 	storeID  uint64
 	selector Selector
 }
 
-func newEvictLeaderScheduler(opt *scheduleOption, storeID uint64) *evictLeaderScheduler {
+func newEvictLeaderScheduler(opt ScheduleOptions, storeID uint64) *evictLeaderScheduler {
 	var filters []Filter
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
 
 	return &evictLeaderScheduler{
 		opt:      opt,
-		name:     fmt.Sprintf("evict-leader-scheduler-%d", storeID),
+		name:     fmt.Sprintf("%s-%d", evictLeaderSchedulerType, storeID),
 		storeID:  storeID,
 		selector: newRandomSelector(filters),
 	}
@@ -107,39 +305,46 @@ func (s *evictLeaderScheduler) GetResourceLimit() uint64 {
 	return s.opt.GetLeaderScheduleLimit()
 }
 
-func (s *evictLeaderScheduler) Prepare(cluster *clusterInfo) error {
-	return errors.Trace(cluster.blockStore(s.storeID))
+func (s *evictLeaderScheduler) Prepare(cluster Cluster) error {
+	return errors.Trace(cluster.BlockStore(s.storeID))
 }
 
-func (s *evictLeaderScheduler) Cleanup(cluster *clusterInfo) {
-	cluster.unblockStore(s.storeID)
+func (s *evictLeaderScheduler) Cleanup(cluster Cluster) {
+	cluster.UnblockStore(s.storeID)
 }
 
-func (s *evictLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
-	region := cluster.randLeaderRegion(s.storeID)
+func (s *evictLeaderScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	region := cluster.RandLeaderRegion(s.storeID)
 	if region == nil {
 		return nil
 	}
-	target := s.selector.SelectTarget(cluster.getFollowerStores(region))
+	target := s.selector.SelectTarget(cluster.GetFollowerStores(region))
 	if target == nil {
 		return nil
 	}
 	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
 }
 
+// shuffleLeaderScheduler randomly swaps leaders between stores, it is used to
+// exercise the scheduling in tests. The limit controls the rate of the
+// shuffle, 0 means the leader schedule limit.
 type shuffleLeaderScheduler struct {
-	opt      *scheduleOption
+	opt      ScheduleOptions
+	limit    uint64
 	selector Selector
 	selected *metapb.Peer
 }
 
-func newShuffleLeaderScheduler(opt *scheduleOption) *shuffleLeaderScheduler {
+func newShuffleLeaderScheduler(opt ScheduleOptions, limit uint64) *shuffleLeaderScheduler {
 	var filters []Filter
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
+	filters = append(filters, newRejectLeaderFilter(opt))
 
 	return &shuffleLeaderScheduler{
 		opt:      opt,
+		limit:    limit,
 		selector: newRandomSelector(filters),
 	}
 }
@@ -153,14 +358,14 @@ func (s *shuffleLeaderScheduler) GetResourceKind() ResourceKind {
 }
 
 func (s *shuffleLeaderScheduler) GetResourceLimit() uint64 {
-	return s.opt.GetLeaderScheduleLimit()
+	return shuffleLimit(s.limit, s.opt.GetLeaderScheduleLimit())
 }
 
-func (s *shuffleLeaderScheduler) Prepare(cluster *clusterInfo) error { return nil }
+func (s *shuffleLeaderScheduler) Prepare(cluster Cluster) error { return nil }
 
-func (s *shuffleLeaderScheduler) Cleanup(cluster *clusterInfo) {}
+func (s *shuffleLeaderScheduler) Cleanup(cluster Cluster) {}
 
-func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
+func (s *shuffleLeaderScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
 	// We shuffle leaders between stores:
 	// 1. select a store randomly.
 	// 2. transfer a leader from the store to another store.
@@ -169,7 +374,7 @@ func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 
 	// Select a store and transfer a leader from it.
 	if s.selected == nil {
-		region, newLeader := scheduleTransferLeader(cluster, s.selector)
+		region, newLeader := scheduleTransferLeader(cluster, s.selector, s.opt.GetLeaderSchedulePolicy(), influence)
 		if region == nil {
 			return nil
 		}
@@ -183,25 +388,36 @@ func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	s.selected = nil
 
 	// Transfer a leader to the selected store.
-	region := cluster.randFollowerRegion(storeID)
+	if store := cluster.GetStore(storeID); store == nil || s.opt.CheckLabelProperty(RejectLeader, store.GetLabels()) {
+		return nil
+	}
+	region := cluster.RandFollowerRegion(storeID)
 	if region == nil {
 		return nil
 	}
 	return newTransferLeader(region, region.GetStorePeer(storeID))
 }
 
+// shuffleRegionScheduler randomly moves peers between stores, it is used to
+// exercise the scheduling in tests. The limit controls the rate of the
+// shuffle, 0 means the region schedule limit.
 type shuffleRegionScheduler struct {
-	opt      *scheduleOption
+	opt      ScheduleOptions
+	limit    uint64
 	selector Selector
 }
 
-func newShuffleRegionScheduler(opt *scheduleOption) *shuffleRegionScheduler {
+func newShuffleRegionScheduler(opt ScheduleOptions, limit uint64) *shuffleRegionScheduler {
 	var filters []Filter
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSpecialEngineFilter())
+	filters = append(filters, newSnapshotCountFilter(opt))
+	filters = append(filters, newPendingPeerCountFilter(opt))
 
 	return &shuffleRegionScheduler{
 		opt:      opt,
+		limit:    limit,
 		selector: newRandomSelector(filters),
 	}
 }
@@ -215,21 +431,22 @@ func (s *shuffleRegionScheduler) GetResourceKind() ResourceKind {
 }
 
 func (s *shuffleRegionScheduler) GetResourceLimit() uint64 {
-	return s.opt.GetRegionScheduleLimit()
+	return shuffleLimit(s.limit, s.opt.GetRegionScheduleLimit())
 }
 
-func (s *shuffleRegionScheduler) Prepare(cluster *clusterInfo) error { return nil }
+func (s *shuffleRegionScheduler) Prepare(cluster Cluster) error { return nil }
 
-func (s *shuffleRegionScheduler) Cleanup(cluster *clusterInfo) {}
+func (s *shuffleRegionScheduler) Cleanup(cluster Cluster) {}
 
-func (s *shuffleRegionScheduler) Schedule(cluster *clusterInfo) Operator {
-	region, oldPeer := scheduleRemovePeer(cluster, s.selector)
+func (s *shuffleRegionScheduler) Schedule(cluster Cluster, influence OpInfluence) Operator {
+	region, oldPeer := scheduleRemovePeer(cluster, s.selector, influence)
 	if region == nil {
 		return nil
 	}
 
 	excludedFilter := newExcludedFilter(nil, region.GetStoreIds())
-	newPeer := scheduleAddPeer(cluster, s.selector, excludedFilter)
+	namespaceFilter := newRegionNamespaceFilter(s.opt, region)
+	newPeer := scheduleAddPeer(cluster, s.selector, excludedFilter, namespaceFilter)
 	if newPeer == nil {
 		return nil
 	}
@@ -237,24 +454,61 @@ func (s *shuffleRegionScheduler) Schedule(cluster *clusterInfo) Operator {
 	return newTransferPeer(region, oldPeer, newPeer)
 }
 
+// shuffleLimit returns the limit of a shuffle scheduler, it never exceeds the
+// schedule limit.
+func shuffleLimit(limit, scheduleLimit uint64) uint64 {
+	if limit == 0 {
+		return scheduleLimit
+	}
+	return minUint64(limit, scheduleLimit)
+}
+
 func newAddPeer(region *RegionInfo, peer *metapb.Peer) Operator {
 	addPeer := newAddPeerOperator(region.GetId(), peer)
 	return newRegionOperator(region, RegionKind, addPeer)
 }
 
 func newRemovePeer(region *RegionInfo, peer *metapb.Peer) Operator {
+	ops := removePeerOps(region, peer)
+	if ops == nil {
+		return nil
+	}
+	return newRegionOperator(region, RegionKind, ops...)
+}
+
+func newPriorityRemovePeer(region *RegionInfo, peer *metapb.Peer) Operator {
+	ops := removePeerOps(region, peer)
+	if ops == nil {
+		return nil
+	}
+	return newRegionOperator(region, PriorityKind, ops...)
+}
+
+// removePeerOps returns the operators to remove the peer, the leadership is
+// transferred to a follower first if the peer is leader.
+func removePeerOps(region *RegionInfo, peer *metapb.Peer) []Operator {
 	removePeer := newRemovePeerOperator(region.GetId(), peer)
 	if region.Leader != nil && region.Leader.GetId() == peer.GetId() {
 		if follower := region.GetFollower(); follower != nil {
 			transferLeader := newTransferLeaderOperator(region.GetId(), region.Leader, follower)
-			return newRegionOperator(region, RegionKind, transferLeader, removePeer)
+			return []Operator{transferLeader, removePeer}
 		}
 		return nil
 	}
-	return newRegionOperator(region, RegionKind, removePeer)
+	return []Operator{removePeer}
 }
 
 func newTransferPeer(region *RegionInfo, oldPeer, newPeer *metapb.Peer) Operator {
+	return newRegionOperator(region, RegionKind, transferPeerOps(region, oldPeer, newPeer)...)
+}
+
+func newPriorityTransferPeer(region *RegionInfo, oldPeer, newPeer *metapb.Peer) Operator {
+	return newRegionOperator(region, PriorityKind, transferPeerOps(region, oldPeer, newPeer)...)
+}
+
+// transferPeerOps returns the operators to move the old peer to the new
+// peer, the leadership is transferred first if the old peer is leader.
+func transferPeerOps(region *RegionInfo, oldPeer, newPeer *metapb.Peer) []Operator {
 	addPeer := newAddPeerOperator(region.GetId(), newPeer)
 	removePeer := newRemovePeerOperator(region.GetId(), oldPeer)
 	if region.Leader != nil && region.Leader.GetId() == oldPeer.GetId() {
@@ -263,15 +517,9 @@ func newTransferPeer(region *RegionInfo, oldPeer, newPeer *metapb.Peer) Operator
 			newLeader = follower
 		}
 		transferLeader := newTransferLeaderOperator(region.GetId(), region.Leader, newLeader)
-		return newRegionOperator(region, RegionKind, addPeer, transferLeader, removePeer)
+		return []Operator{addPeer, transferLeader, removePeer}
 	}
-	return newRegionOperator(region, RegionKind, addPeer, removePeer)
-}
-
-func newPriorityTransferPeer(region *RegionInfo, oldPeer, newPeer *metapb.Peer) Operator {
-	addPeer := newAddPeerOperator(region.GetId(), newPeer)
-	removePeer := newRemovePeerOperator(region.GetId(), oldPeer)
-	return newRegionOperator(region, PriorityKind, addPeer, removePeer)
+	return []Operator{addPeer, removePeer}
 }
 
 func newPriorityTransferLeader(region *RegionInfo, newLeader *metapb.Peer) Operator {
@@ -285,15 +533,15 @@ func newTransferLeader(region *RegionInfo, newLeader *metapb.Peer) Operator {
 }
 
 // scheduleAddPeer schedules a new peer.
-func scheduleAddPeer(cluster *clusterInfo, s Selector, filters ...Filter) *metapb.Peer {
-	stores := cluster.getStores()
+func scheduleAddPeer(cluster Cluster, s Selector, filters ...Filter) *metapb.Peer {
+	stores := cluster.GetStores()
 
 	target := s.SelectTarget(stores, filters...)
 	if target == nil {
 		return nil
 	}
 
-	newPeer, err := cluster.allocPeer(target.GetId())
+	newPeer, err := cluster.AllocPeer(target.GetId())
 	if err != nil {
 		log.Errorf("failed to allocate peer: %v", err)
 		return nil
@@ -303,17 +551,17 @@ func scheduleAddPeer(cluster *clusterInfo, s Selector, filters ...Filter) *metap
 }
 
 // scheduleRemovePeer schedules a region to remove the peer.
-func scheduleRemovePeer(cluster *clusterInfo, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
-	stores := cluster.getStores()
+func scheduleRemovePeer(cluster Cluster, s Selector, influence OpInfluence, filters ...Filter) (*RegionInfo, *metapb.Peer) {
+	stores := influence.applyStores(cluster.GetStores())
 
 	source := s.SelectSource(stores, filters...)
 	if source == nil {
 		return nil, nil
 	}
 
-	region := cluster.randFollowerRegion(source.GetId())
+	region := cluster.RandFollowerRegion(source.GetId())
 	if region == nil {
-		region = cluster.randLeaderRegion(source.GetId())
+		region = cluster.RandLeaderRegion(source.GetId())
 	}
 	if region == nil {
 		return nil, nil
@@ -323,15 +571,15 @@ func scheduleRemovePeer(cluster *clusterInfo, s Selector, filters ...Filter) (*R
 }
 
 // scheduleTransferLeader schedules a region to transfer leader to the peer.
-func scheduleTransferLeader(cluster *clusterInfo, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
-	stores := cluster.getStores()
+func scheduleTransferLeader(cluster Cluster, s Selector, leaderPolicy string, influence OpInfluence, filters ...Filter) (*RegionInfo, *metapb.Peer) {
+	stores := influence.applyStores(cluster.GetStores())
 	if len(stores) == 0 {
 		return nil, nil
 	}
 
 	var averageLeader float64
 	for _, s := range stores {
-		averageLeader += float64(s.leaderScore()) / float64(len(stores))
+		averageLeader += s.leaderScore(leaderPolicy) / float64(len(stores))
 	}
 
 	mostLeaderStore := s.SelectSource(stores, filters...)
@@ -339,10 +587,10 @@ func scheduleTransferLeader(cluster *clusterInfo, s Selector, filters ...Filter)
 
 	var mostLeaderDistance, leastLeaderDistance float64
 	if mostLeaderStore != nil {
-		mostLeaderDistance = math.Abs(mostLeaderStore.leaderScore() - averageLeader)
+		mostLeaderDistance = math.Abs(mostLeaderStore.leaderScore(leaderPolicy) - averageLeader)
 	}
 	if leastLeaderStore != nil {
-		leastLeaderDistance = math.Abs(leastLeaderStore.leaderScore() - averageLeader)
+		leastLeaderDistance = math.Abs(leastLeaderStore.leaderScore(leaderPolicy) - averageLeader)
 	}
 	if mostLeaderDistance == 0 && leastLeaderDistance == 0 {
 		return nil, nil
@@ -350,11 +598,11 @@ func scheduleTransferLeader(cluster *clusterInfo, s Selector, filters ...Filter)
 
 	if mostLeaderDistance > leastLeaderDistance {
 		// Transfer a leader out of mostLeaderStore.
-		region := cluster.randLeaderRegion(mostLeaderStore.GetId())
+		region := cluster.RandLeaderRegion(mostLeaderStore.GetId())
 		if region == nil {
 			return nil, nil
 		}
-		targetStores := cluster.getFollowerStores(region)
+		targetStores := influence.applyStores(cluster.GetFollowerStores(region))
 		target := s.SelectTarget(targetStores)
 		if target == nil {
 			return nil, nil
@@ -364,7 +612,7 @@ func scheduleTransferLeader(cluster *clusterInfo, s Selector, filters ...Filter)
 	}
 
 	// Transfer a leader into leastLeaderStore.
-	region := cluster.randFollowerRegion(leastLeaderStore.GetId())
+	region := cluster.RandFollowerRegion(leastLeaderStore.GetId())
 	if region == nil {
 		return nil, nil
 	}
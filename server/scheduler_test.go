@@ -13,7 +13,10 @@
 
 package server
 
-import . "github.com/pingcap/check"
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
 
 var _ = Suite(&testShuffleLeaderSuite{})
 
@@ -24,8 +27,10 @@ func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
 	tc := newTestClusterInfo(cluster)
 
 	_, opt := newTestScheduleConfig()
-	sl := newShuffleLeaderScheduler(opt)
-	c.Assert(sl.Schedule(cluster), IsNil)
+	sl := newShuffleLeaderScheduler(opt, 0)
+	c.Assert(sl.Schedule(cluster, nil), IsNil)
+	c.Assert(sl.GetResourceLimit(), Equals, opt.GetLeaderScheduleLimit())
+	c.Assert(newShuffleLeaderScheduler(opt, 2).GetResourceLimit(), Equals, uint64(2))
 
 	// Add stores 1,2,3,4
 	tc.addLeaderStore(1, 6)
@@ -43,13 +48,156 @@ func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
 	tc.addLeaderRegion(4, 1, 2, 3, 4)
 
 	for i := 0; i < 4; i++ {
-		bop := sl.Schedule(cluster)
+		bop := sl.Schedule(cluster, nil)
 		op := bop.(*regionOperator).Ops[0].(*transferLeaderOperator)
 
 		sourceID := op.OldLeader.GetStoreId()
 
-		bop = sl.Schedule(cluster)
+		bop = sl.Schedule(cluster, nil)
 		op = bop.(*regionOperator).Ops[0].(*transferLeaderOperator)
 		c.Assert(op.NewLeader.GetStoreId(), Equals, sourceID)
 	}
 }
+
+var _ = Suite(&testShuffleRegionSuite{})
+
+type testShuffleRegionSuite struct{}
+
+func (s *testShuffleRegionSuite) TestShuffle(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	sr := newShuffleRegionScheduler(opt, 1)
+	c.Assert(sr.GetResourceLimit(), Equals, uint64(1))
+	c.Assert(sr.Schedule(cluster, nil), IsNil)
+
+	// Add stores 1,2,3,4
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 2)
+	tc.addRegionStore(3, 2)
+	tc.addRegionStore(4, 1)
+	// Add region 1 with peers in stores 1,2,3 and region 2 with peers in
+	// stores 2,3,4, so every store has a peer to move out, and the only
+	// target of a region is the store without it.
+	keys := []string{"", "a", ""}
+	missing := map[uint64]uint64{1: 4, 2: 1}
+	for i, storeIDs := range [][]uint64{{1, 2, 3}, {2, 3, 4}} {
+		region := &metapb.Region{
+			Id:       uint64(i + 1),
+			StartKey: []byte(keys[i]),
+			EndKey:   []byte(keys[i+1]),
+		}
+		for _, storeID := range storeIDs {
+			peer, _ := tc.AllocPeer(storeID)
+			region.Peers = append(region.Peers, peer)
+		}
+		tc.putRegion(newRegionInfo(region, region.Peers[0]))
+	}
+
+	// The source store is selected randomly, but the target is determined
+	// by the region.
+	for i := 0; i < 10; i++ {
+		op := sr.Schedule(cluster, nil)
+		c.Assert(op, NotNil)
+		rop := op.(*regionOperator)
+		c.Assert(rop.Ops[0].(*changePeerOperator).ChangePeer.GetPeer().GetStoreId(), Equals, missing[rop.GetRegionID()])
+	}
+}
+
+var _ = Suite(&testLeaderSchedulerSuite{})
+
+type testLeaderSchedulerSuite struct{}
+
+func (s *testLeaderSchedulerSuite) TestGrantLeader(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	gl := newGrantLeaderScheduler(opt, 1)
+	c.Assert(gl.GetName(), Equals, "grant-leader-scheduler-1")
+
+	// Add stores 1,2,3
+	tc.addLeaderStore(1, 0)
+	tc.addLeaderStore(2, 1)
+	tc.addLeaderStore(3, 0)
+	// Add region 1 with leader in store 2 and followers in stores 1,3
+	tc.addLeaderRegion(1, 2, 1, 3)
+	checkTransferLeader(c, gl.Schedule(cluster, nil), 2, 1)
+
+	// The peer in store 1 is pending.
+	region := cluster.GetRegion(1)
+	region.PendingPeers = []*metapb.Peer{region.GetStorePeer(1)}
+	cluster.putRegion(region)
+	c.Assert(gl.Schedule(cluster, nil), IsNil)
+
+	// All leaders are in store 1.
+	tc.addLeaderRegion(1, 1, 2, 3)
+	c.Assert(gl.Schedule(cluster, nil), IsNil)
+}
+
+func (s *testLeaderSchedulerSuite) TestEvictLeader(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	el := newEvictLeaderScheduler(opt, 1)
+	c.Assert(el.GetName(), Equals, "evict-leader-scheduler-1")
+
+	// Add stores 1,2,3
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 0)
+	tc.addLeaderStore(3, 0)
+	// Add region 1 with leader in store 1 and followers in stores 2,3
+	tc.addLeaderRegion(1, 1, 2, 3)
+	checkTransferLeaderFrom(c, el.Schedule(cluster, nil), 1)
+
+	// No leader in store 1.
+	tc.addLeaderRegion(1, 2, 1, 3)
+	c.Assert(el.Schedule(cluster, nil), IsNil)
+}
+
+func (s *testLeaderSchedulerSuite) TestSchedulerConfig(c *C) {
+	_, opt := newTestScheduleConfig()
+	for _, t := range []string{evictLeaderSchedulerType, grantLeaderSchedulerType} {
+		scheduler, err := newSchedulerFromConfig(opt, &schedulerConfig{Type: t, Args: []string{"1"}})
+		c.Assert(err, IsNil)
+		c.Assert(scheduler.GetName(), Equals, t+"-1")
+		_, err = CreateScheduler(t, opt)
+		c.Assert(err, NotNil)
+		_, err = CreateScheduler(t, opt, "x")
+		c.Assert(err, NotNil)
+	}
+	_, err := newSchedulerFromConfig(opt, &schedulerConfig{Type: "unknown"})
+	c.Assert(err, NotNil)
+
+	scheduler, err := CreateScheduler(scatterRangeSchedulerType, opt, "t1", "61", "62")
+	c.Assert(err, IsNil)
+	c.Assert(scheduler.GetName(), Equals, "scatter-range-scheduler-t1")
+	_, err = CreateScheduler(scatterRangeSchedulerType, opt, "t1", "62", "61")
+	c.Assert(err, NotNil)
+	_, err = CreateScheduler(scatterRangeSchedulerType, opt, "t1", "x", "")
+	c.Assert(err, NotNil)
+	_, err = CreateScheduler(scatterRangeSchedulerType, opt, "t1")
+	c.Assert(err, NotNil)
+
+	// The schedulers without arguments are registered with their names.
+	for _, name := range []string{"balance-leader-scheduler", "balance-region-scheduler", hotRegionScheduleName, hotReadRegionScheduleName} {
+		scheduler, err = CreateScheduler(name, opt)
+		c.Assert(err, IsNil)
+		c.Assert(scheduler.GetName(), Equals, name)
+	}
+	for _, name := range []string{"shuffle-leader-scheduler", "shuffle-region-scheduler"} {
+		scheduler, err = CreateScheduler(name, opt, "1")
+		c.Assert(err, IsNil)
+		c.Assert(scheduler.GetName(), Equals, name)
+		c.Assert(scheduler.GetResourceLimit(), Equals, uint64(1))
+		_, err = CreateScheduler(name, opt, "1", "2")
+		c.Assert(err, NotNil)
+	}
+
+	// A type can't be registered twice.
+	c.Assert(func() {
+		RegisterScheduler(evictLeaderSchedulerType, nil)
+	}, PanicMatches, ".*duplicated.*")
+}
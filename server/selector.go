@@ -17,46 +17,52 @@ import "math/rand"
 
 // Selector is an interface to select source and target store to schedule.
 type Selector interface {
-	SelectSource(stores []*storeInfo, filters ...Filter) *storeInfo
-	SelectTarget(stores []*storeInfo, filters ...Filter) *storeInfo
+	SelectSource(stores []*StoreInfo, filters ...Filter) *StoreInfo
+	SelectTarget(stores []*StoreInfo, filters ...Filter) *StoreInfo
 }
 
 type balanceSelector struct {
 	kind    ResourceKind
+	opt     ScheduleOptions
 	filters []Filter
 }
 
-func newBalanceSelector(kind ResourceKind, filters []Filter) *balanceSelector {
+func newBalanceSelector(kind ResourceKind, opt ScheduleOptions, filters []Filter) *balanceSelector {
 	return &balanceSelector{
 		kind:    kind,
+		opt:     opt,
 		filters: filters,
 	}
 }
 
-func (s *balanceSelector) SelectSource(stores []*storeInfo, filters ...Filter) *storeInfo {
+func (s *balanceSelector) score(store *StoreInfo) float64 {
+	return store.resourceScore(s.kind, s.opt.GetLeaderSchedulePolicy(), s.opt.GetStoreScore())
+}
+
+func (s *balanceSelector) SelectSource(stores []*StoreInfo, filters ...Filter) *StoreInfo {
 	filters = append(filters, s.filters...)
 
-	var result *storeInfo
+	var result *StoreInfo
 	for _, store := range stores {
 		if filterSource(store, filters) {
 			continue
 		}
-		if result == nil || result.resourceScore(s.kind) < store.resourceScore(s.kind) {
+		if result == nil || s.score(result) < s.score(store) {
 			result = store
 		}
 	}
 	return result
 }
 
-func (s *balanceSelector) SelectTarget(stores []*storeInfo, filters ...Filter) *storeInfo {
+func (s *balanceSelector) SelectTarget(stores []*StoreInfo, filters ...Filter) *StoreInfo {
 	filters = append(filters, s.filters...)
 
-	var result *storeInfo
+	var result *StoreInfo
 	for _, store := range stores {
 		if filterTarget(store, filters) {
 			continue
 		}
-		if result == nil || result.resourceScore(s.kind) > store.resourceScore(s.kind) {
+		if result == nil || s.score(result) > s.score(store) {
 			result = store
 		}
 	}
@@ -71,17 +77,17 @@ func newRandomSelector(filters []Filter) *randomSelector {
 	return &randomSelector{filters: filters}
 }
 
-func (s *randomSelector) Select(stores []*storeInfo) *storeInfo {
+func (s *randomSelector) Select(stores []*StoreInfo) *StoreInfo {
 	if len(stores) == 0 {
 		return nil
 	}
 	return stores[rand.Int()%len(stores)]
 }
 
-func (s *randomSelector) SelectSource(stores []*storeInfo, filters ...Filter) *storeInfo {
+func (s *randomSelector) SelectSource(stores []*StoreInfo, filters ...Filter) *StoreInfo {
 	filters = append(filters, s.filters...)
 
-	var candidates []*storeInfo
+	var candidates []*StoreInfo
 	for _, store := range stores {
 		if filterSource(store, filters) {
 			continue
@@ -91,10 +97,10 @@ func (s *randomSelector) SelectSource(stores []*storeInfo, filters ...Filter) *s
 	return s.Select(candidates)
 }
 
-func (s *randomSelector) SelectTarget(stores []*storeInfo, filters ...Filter) *storeInfo {
+func (s *randomSelector) SelectTarget(stores []*StoreInfo, filters ...Filter) *StoreInfo {
 	filters = append(filters, s.filters...)
 
-	var candidates []*storeInfo
+	var candidates []*StoreInfo
 	for _, store := range stores {
 		if filterTarget(store, filters) {
 			continue
@@ -57,9 +57,14 @@ type Server struct {
 	rootPath string
 
 	isLeaderValue int64
+	// leaderTerm is the revision of the leader key, it increases when the
+	// leader changes.
+	leaderTerm uint64
 	// leader value saved in etcd leader key.
 	// Every write will use this to check leader validation.
 	leaderValue string
+	// resignCh notifies the leader to stop serving after it is resigned.
+	resignCh chan struct{}
 
 	wg sync.WaitGroup
 
@@ -68,6 +73,16 @@ type Server struct {
 	// for tso
 	ts            atomic.Value
 	lastSavedTime time.Time
+	// for namespace operation, a namespace is checked against the others
+	// before it's saved.
+	namespaceLock sync.Mutex
+	// for the items of cfg changed at runtime, i.e. the log level and the
+	// schedule and replication config loaded from the config file. The ones in
+	// effect are kept by scheduleOpt.
+	cfgLock sync.RWMutex
+	// for setting the schedule and replication config, which are persisted
+	// together.
+	scheduleConfigLock sync.Mutex
 
 	// for id allocator, we can use one allocator for
 	// store, region and peer, because we just need
@@ -86,7 +101,27 @@ type Server struct {
 
 	msgID uint64
 
+	// for pushing messages to stores.
+	hbStreams *heartbeatStreams
+
+	slowLog *slowLogger
+
+	// for posting alerts to the webhooks.
+	notifier *notifier
+	// clusterEvents are the recorded events to be saved.
+	clusterEvents chan *ClusterEvent
+	// etcdQuotaAlerted is only accessed by etcdStatusLoop.
+	etcdQuotaAlerted bool
+
 	id uint64
+	// startTimestamp is when the server is created in seconds.
+	startTimestamp int64
+
+	// for forwarding requests to leader.
+	connMu struct {
+		sync.RWMutex
+		clientConns map[string]*grpc.ClientConn
+	}
 }
 
 // NewServer creates the pd server with given configuration.
@@ -110,12 +145,18 @@ func CreateServer(cfg *Config) *Server {
 	rand.Seed(time.Now().UnixNano())
 
 	s := &Server{
-		cfg:           cfg,
-		scheduleOpt:   newScheduleOption(cfg),
-		isLeaderValue: 0,
-		closed:        1,
+		cfg:            cfg,
+		scheduleOpt:    newScheduleOption(cfg),
+		isLeaderValue:  0,
+		closed:         1,
+		resignCh:       make(chan struct{}, 1),
+		startTimestamp: time.Now().Unix(),
 	}
 
+	s.connMu.clientConns = make(map[string]*grpc.ClientConn)
+	s.hbStreams = newHeartbeatStreams()
+	s.notifier = newNotifier(cfg.Alert.WebhookURLs)
+	s.clusterEvents = make(chan *ClusterEvent, maxPendingClusterEvents)
 	s.handler = newHandler(s)
 	return s
 }
@@ -126,6 +167,9 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if s.slowLog, err = newSlowLogger(s.cfg); err != nil {
+		return errors.Trace(err)
+	}
 	if apiHandler != nil {
 		etcdCfg.UserHandlers = map[string]http.Handler{
 			pdAPIPrefix: apiHandler,
@@ -149,7 +193,9 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 		return errors.Trace(err)
 	}
 
-	endpoints := []string{etcdCfg.ACUrls[0].String()}
+	// Connect the embedded etcd by the listen url, the advertise one may be an
+	// address outside of a NAT which is not reachable from here.
+	endpoints := []string{getLocalEndpoint(etcdCfg.LCUrls[0])}
 
 	log.Infof("create etcd v3 client with endpoints %v", endpoints)
 	client, err := clientv3.New(clientv3.Config{
@@ -196,7 +242,17 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 	s.rootPath = path.Join(pdRootPath, strconv.FormatUint(s.clusterID, 10))
 	s.idAlloc = &idAllocator{s: s}
 	s.kv = newKV(s)
+	if s.cfg.UseRegionStorage {
+		regionKV, err := newRegionKV(path.Join(s.cfg.DataDir, "region-meta"))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.kv.regionKV = regionKV
+	}
 	s.cluster = newRaftCluster(s, s.clusterID)
+	if err = s.saveMemberInfo(); err != nil {
+		log.Errorf("save member info failed: %v", err)
+	}
 
 	// Server has started.
 	atomic.StoreInt64(&s.closed, 0)
@@ -244,7 +300,18 @@ func (s *Server) Close() {
 
 	log.Info("closing server")
 
+	// Stop serving as the leader, which stops the TSO too, then resign the
+	// leadership, so the other servers can campaign at once instead of
+	// waiting for the leader lease to expire.
+	isLeader := s.IsLeader()
 	s.enableLeader(false)
+	if isLeader {
+		if err := s.resignLeader(); err != nil {
+			log.Errorf("resign leader meet error: %v", err)
+		}
+	}
+
+	s.closeGRPCConns()
 
 	if s.client != nil {
 		s.client.Close()
@@ -256,6 +323,16 @@ func (s *Server) Close() {
 
 	s.wg.Wait()
 
+	if s.kv != nil {
+		if err := s.kv.close(); err != nil {
+			log.Errorf("close kv meet error: %v", err)
+		}
+	}
+
+	if err := s.slowLog.close(); err != nil {
+		log.Errorf("close slow log meet error: %v", err)
+	}
+
 	log.Info("close server")
 }
 
@@ -264,12 +341,40 @@ func (s *Server) isClosed() bool {
 	return atomic.LoadInt64(&s.closed) == 1
 }
 
+// IsLive returns whether the server is running.
+func (s *Server) IsLive() bool {
+	return !s.isClosed()
+}
+
+// CheckReady checks whether the server can serve the requests or redirect
+// them to the leader. The leader is read by quorum, so a member which can't
+// reach the quorum or hasn't caught up with it is not ready.
+func (s *Server) CheckReady() error {
+	leader, err := s.GetLeader()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if leader.GetMemberId() == s.ID() && !s.IsLeader() {
+		return errors.New("leader is not ready to serve")
+	}
+	return nil
+}
+
 // Run runs the pd server.
 func (s *Server) Run() {
 	// We use "127.0.0.1:0" for test and will set correct listening
 	// address before run, so we set leader value here.
 	s.leaderValue = s.marshalLeader()
 
+	s.wg.Add(1)
+	go s.etcdStatusLoop()
+
+	s.wg.Add(1)
+	go s.notifyLoop()
+
+	s.wg.Add(1)
+	go s.clusterEventLoop()
+
 	s.wg.Add(1)
 	s.leaderLoop()
 }
@@ -287,6 +287,21 @@ func (s *testServerSuite) TestUpdateAdvertiseUrls(c *C) {
 	}
 }
 
+func (s *testServerSuite) TestAdvertiseClientUrlsOutsideNAT(c *C) {
+	cfg := NewTestSingleConfig()
+	// The advertise address is not reachable from the server itself.
+	cfg.AdvertiseClientUrls = "http://pd.invalid:2379"
+	svr, err := NewServer(cfg)
+	c.Assert(err, IsNil)
+	defer func() {
+		svr.Close()
+		cleanServer(cfg)
+	}()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+	c.Assert(svr.GetAddr(), Equals, cfg.AdvertiseClientUrls)
+}
+
 func (s *testServerSuite) TestCheckClusterID(c *C) {
 	cfgs := NewTestMultiConfig(2)
 	for i, cfg := range cfgs {
@@ -0,0 +1,108 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/logutil"
+	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
+	"google.golang.org/grpc/peer"
+)
+
+// maxSlowLogParamsLen is the max length of the parameters summary in a slow
+// log entry, the rest is cut off.
+const maxSlowLogParamsLen = 256
+
+// slowLogger logs the requests taking longer than the threshold.
+type slowLogger struct {
+	threshold time.Duration
+	logger    *log.Logger
+	// closer closes the slow log file, it is nil if the slow requests are
+	// logged to the main log.
+	closer io.Closer
+}
+
+func newSlowLogger(cfg *Config) (*slowLogger, error) {
+	l := &slowLogger{
+		threshold: cfg.SlowLog.Threshold.Duration,
+		logger:    log.StandardLogger(),
+	}
+	if l.threshold > 0 && cfg.SlowLog.File.Filename != "" {
+		logger, closer, err := logutil.NewFileLogger(&cfg.Log, &cfg.SlowLog.File)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		l.logger, l.closer = logger, closer
+	}
+	return l, nil
+}
+
+func (l *slowLogger) close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return errors.Trace(l.closer.Close())
+}
+
+func (l *slowLogger) isSlow(cost time.Duration) bool {
+	return l != nil && l.threshold > 0 && cost >= l.threshold
+}
+
+func (l *slowLogger) log(kind, method, caller, params string, cost time.Duration) {
+	if len(params) > maxSlowLogParamsLen {
+		params = params[:maxSlowLogParamsLen] + "..."
+	}
+	l.logger.Warnf("[slow %s] %s cost %v, caller %s, params %s", kind, method, cost, caller, params)
+}
+
+// LogSlowRequest logs the request if it takes longer than the slow log
+// threshold, the kind is grpc or http.
+func (s *Server) LogSlowRequest(kind, method, caller, params string, cost time.Duration) {
+	if s.slowLog.isSlow(cost) {
+		s.slowLog.log(kind, method, caller, params, cost)
+	}
+}
+
+// logSlowRPC logs the gRPC request started at the time if it is slow, the
+// request is only formatted when it is logged.
+func (s *Server) logSlowRPC(ctx context.Context, method string, request fmt.Stringer, start time.Time) {
+	cost := time.Since(start)
+	if !s.slowLog.isSlow(cost) {
+		return
+	}
+	var caller, params string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		caller = p.Addr.String()
+	}
+	if request != nil {
+		params = request.String()
+	}
+	s.slowLog.log("grpc", method, caller, params, cost)
+}
+
+// traceRPC adds an event to the trace of the gRPC request. The traces are
+// shown at /debug/requests of the client URLs with the time between the
+// events, so a slow request can be tracked down to the step taking the time.
+// The arguments are formatted when the trace is shown, they must not change.
+func traceRPC(ctx context.Context, format string, args ...interface{}) {
+	if tr, ok := trace.FromContext(ctx); ok {
+		tr.LazyPrintf(format, args...)
+	}
+}
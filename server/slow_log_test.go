@@ -0,0 +1,87 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
+	"google.golang.org/grpc/peer"
+)
+
+var _ = Suite(&testSlowLogSuite{})
+
+type testSlowLogSuite struct{}
+
+func (s *testSlowLogSuite) TestSlowLog(c *C) {
+	dir, err := ioutil.TempDir("", "test_slow_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.SlowLog.Threshold.Duration = time.Second
+	cfg.SlowLog.File.Filename = path.Join(dir, "slow.log")
+	slowLog, err := newSlowLogger(cfg)
+	c.Assert(err, IsNil)
+	svr := &Server{slowLog: slowLog}
+
+	svr.LogSlowRequest("http", "GET /pd/api/v1/stores", "127.0.0.1:1234", "", time.Millisecond)
+	svr.LogSlowRequest("http", "GET /pd/api/v1/regions", "127.0.0.1:1234", strings.Repeat("a", 1000), 2*time.Second)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 5678}})
+	svr.logSlowRPC(ctx, "GetStore", &pdpb.GetStoreRequest{StoreId: 1}, time.Now().Add(-2*time.Second))
+
+	data, err := ioutil.ReadFile(cfg.SlowLog.File.Filename)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(strings.Contains(lines[0], "[slow http] GET /pd/api/v1/regions"), IsTrue)
+	c.Assert(strings.Contains(lines[0], strings.Repeat("a", maxSlowLogParamsLen)+"..."), IsTrue)
+	c.Assert(strings.Contains(lines[0], strings.Repeat("a", maxSlowLogParamsLen+1)), IsFalse)
+	c.Assert(strings.Contains(lines[1], "[slow grpc] GetStore"), IsTrue)
+	c.Assert(strings.Contains(lines[1], "caller 127.0.0.2:5678"), IsTrue)
+	c.Assert(strings.Contains(lines[1], "store_id:1"), IsTrue)
+	c.Assert(svr.slowLog.close(), IsNil)
+
+	// The slow log is disabled without the threshold.
+	svr.slowLog.threshold = 0
+	c.Assert(svr.slowLog.isSlow(time.Hour), IsFalse)
+	svr.slowLog = nil
+	c.Assert(svr.slowLog.isSlow(time.Hour), IsFalse)
+	c.Assert(svr.slowLog.close(), IsNil)
+}
+
+func (s *testSlowLogSuite) TestTraceRPC(c *C) {
+	// The requests not traced are ignored.
+	traceRPC(context.Background(), "get region %d", 1)
+
+	tr := trace.New("pd.test", "GetRegion")
+	traceRPC(trace.NewContext(context.Background(), tr), "get region %d", 2)
+	tr.Finish()
+
+	req, err := http.NewRequest("GET", "/debug/requests?fam=pd.test&b=0&exp=1", nil)
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	trace.Render(&buf, req, true)
+	c.Assert(strings.Contains(buf.String(), "get region 2"), IsTrue)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsMaxSamples is the number of recent stats samples kept, they are taken
+// by the background jobs every minute, so it is 2 hours by default.
+const statsMaxSamples = 120
+
+// statsRegionTargets are the cluster metrics which are served as the region
+// series.
+var statsRegionTargets = map[string]string{
+	"region_count":               "region.count",
+	"miss_peer_region_count":     "region.miss_peer_count",
+	"extra_peer_region_count":    "region.extra_peer_count",
+	"store_offline_region_count": "region.offline_count",
+}
+
+// StatsSeries is the series of a stats target in the format of the Grafana
+// SimpleJSON datasource, a datapoint is a value and its unix time in ms.
+type StatsSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// statsSample is a snapshot of the cluster, region and store statistics.
+type statsSample struct {
+	time   time.Time
+	values map[string]float64
+}
+
+func newStatsSample(t time.Time) *statsSample {
+	return &statsSample{
+		time:   t,
+		values: make(map[string]float64),
+	}
+}
+
+// addClusterMetrics adds the cluster metrics, the region ones are named by
+// statsRegionTargets.
+func (s *statsSample) addClusterMetrics(metrics map[string]float64) {
+	for label, value := range metrics {
+		if target, ok := statsRegionTargets[label]; ok {
+			s.values[target] = value
+		} else {
+			s.values["cluster."+label] = value
+		}
+	}
+}
+
+func (s *statsSample) addStore(store *StoreInfo) {
+	prefix := fmt.Sprintf("store.%d.", store.GetId())
+	s.values[prefix+"region_count"] = float64(store.regionCount())
+	s.values[prefix+"leader_count"] = float64(store.leaderCount())
+	s.values[prefix+"region_size"] = float64(store.regionSize())
+	s.values[prefix+"capacity"] = float64(store.status.GetCapacity())
+	s.values[prefix+"available"] = float64(store.status.GetAvailable())
+}
+
+// statsSeries keeps the recent stats samples, the oldest ones are dropped
+// once there are more than maxSamples.
+type statsSeries struct {
+	sync.RWMutex
+	samples    []*statsSample
+	maxSamples int
+}
+
+func newStatsSeries(maxSamples int) *statsSeries {
+	return &statsSeries{
+		maxSamples: maxSamples,
+	}
+}
+
+func (s *statsSeries) add(sample *statsSample) {
+	s.Lock()
+	defer s.Unlock()
+	s.samples = append(s.samples, sample)
+	if len(s.samples) > s.maxSamples {
+		s.samples = append([]*statsSample(nil), s.samples[len(s.samples)-s.maxSamples:]...)
+	}
+}
+
+// targets returns the sorted names of all series.
+func (s *statsSeries) targets() []string {
+	s.RLock()
+	defer s.RUnlock()
+	set := make(map[string]struct{})
+	for _, sample := range s.samples {
+		for target := range sample.values {
+			set[target] = struct{}{}
+		}
+	}
+	targets := make([]string, 0, len(set))
+	for target := range set {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// query returns the series of the targets in the time range [from, to].
+func (s *statsSeries) query(targets []string, from, to time.Time) []*StatsSeries {
+	s.RLock()
+	defer s.RUnlock()
+	result := make([]*StatsSeries, 0, len(targets))
+	for _, target := range targets {
+		series := &StatsSeries{
+			Target:     target,
+			Datapoints: make([][2]float64, 0, len(s.samples)),
+		}
+		for _, sample := range s.samples {
+			if sample.time.Before(from) || sample.time.After(to) {
+				continue
+			}
+			if value, ok := sample.values[target]; ok {
+				ms := sample.time.UnixNano() / int64(time.Millisecond)
+				series.Datapoints = append(series.Datapoints, [2]float64{value, float64(ms)})
+			}
+		}
+		result = append(result, series)
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testStatsSeriesSuite{})
+
+type testStatsSeriesSuite struct{}
+
+func (s *testStatsSeriesSuite) TestStatsSeries(c *C) {
+	series := newStatsSeries(2)
+	c.Assert(series.targets(), HasLen, 0)
+
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	tc.addLeaderStore(1, 2)
+
+	t := time.Unix(1500000000, 0)
+	sample := newStatsSample(t)
+	sample.addClusterMetrics(map[string]float64{"store_up_count": 1, "region_count": 2})
+	sample.addStore(cluster.GetStore(1))
+	series.add(sample)
+	c.Assert(series.targets(), DeepEquals, []string{
+		"cluster.store_up_count",
+		"region.count",
+		"store.1.available",
+		"store.1.capacity",
+		"store.1.leader_count",
+		"store.1.region_count",
+		"store.1.region_size",
+	})
+
+	sample = newStatsSample(t.Add(time.Minute))
+	sample.addClusterMetrics(map[string]float64{"region_count": 3})
+	series.add(sample)
+	result := series.query([]string{"region.count", "store.1.leader_count", "unknown"}, t, t.Add(time.Hour))
+	c.Assert(result, HasLen, 3)
+	ms := float64(t.Unix() * 1000)
+	c.Assert(result[0].Datapoints, DeepEquals, [][2]float64{{2, ms}, {3, ms + 60000}})
+	c.Assert(result[1].Datapoints, DeepEquals, [][2]float64{{2, ms}})
+	c.Assert(result[2].Datapoints, HasLen, 0)
+	result = series.query([]string{"region.count"}, t.Add(time.Second), t.Add(time.Hour))
+	c.Assert(result[0].Datapoints, DeepEquals, [][2]float64{{3, ms + 60000}})
+
+	// The oldest sample is dropped.
+	series.add(newStatsSample(t.Add(2 * time.Minute)))
+	result = series.query([]string{"region.count"}, t, t.Add(time.Hour))
+	c.Assert(result[0].Datapoints, DeepEquals, [][2]float64{{3, ms + 60000}})
+	c.Assert(series.targets(), DeepEquals, []string{"region.count"})
+}
@@ -14,93 +14,147 @@
 package server
 
 import (
+	"math"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
-// storeInfo contains information about a store.
+// StoreInfo contains information about a store.
 // TODO: Export this to API directly.
-type storeInfo struct {
+type StoreInfo struct {
 	*metapb.Store
 	status *StoreStatus
 }
 
-func newStoreInfo(store *metapb.Store) *storeInfo {
-	return &storeInfo{
+func newStoreInfo(store *metapb.Store) *StoreInfo {
+	return &StoreInfo{
 		Store:  store,
 		status: newStoreStatus(),
 	}
 }
 
-func (s *storeInfo) clone() *storeInfo {
-	return &storeInfo{
+func (s *StoreInfo) clone() *StoreInfo {
+	return &StoreInfo{
 		Store:  proto.Clone(s.Store).(*metapb.Store),
 		status: s.status.clone(),
 	}
 }
 
-func (s *storeInfo) block() {
+func (s *StoreInfo) block() {
 	s.status.blocked = true
 }
 
-func (s *storeInfo) unblock() {
+func (s *StoreInfo) unblock() {
 	s.status.blocked = false
 }
 
-func (s *storeInfo) isBlocked() bool {
+func (s *StoreInfo) isBlocked() bool {
 	return s.status.blocked
 }
 
-func (s *storeInfo) isUp() bool {
+func (s *StoreInfo) isUp() bool {
 	return s.GetState() == metapb.StoreState_Up
 }
 
-func (s *storeInfo) isOffline() bool {
+func (s *StoreInfo) isOffline() bool {
 	return s.GetState() == metapb.StoreState_Offline
 }
 
-func (s *storeInfo) isTombstone() bool {
+func (s *StoreInfo) isTombstone() bool {
 	return s.GetState() == metapb.StoreState_Tombstone
 }
 
-func (s *storeInfo) downTime() time.Duration {
+// tombstoneTime returns how long the store has been tombstone.
+func (s *StoreInfo) tombstoneTime() time.Duration {
+	return time.Since(s.status.tombstoneTS)
+}
+
+func (s *StoreInfo) downTime() time.Duration {
 	return time.Since(s.status.LastHeartbeatTS)
 }
 
-func (s *storeInfo) leaderCount() uint64 {
+func (s *StoreInfo) leaderCount() uint64 {
 	return uint64(s.status.LeaderCount)
 }
 
-func (s *storeInfo) leaderScore() float64 {
-	return float64(s.status.LeaderCount)
+func (s *StoreInfo) leaderSize() uint64 {
+	return s.status.LeaderSize
+}
+
+// leaderScore is the leader region size with the size policy, otherwise it
+// is the leader count. It is divided by the leader weight of the store.
+func (s *StoreInfo) leaderScore(policy string) float64 {
+	if policy == LeaderSchedulePolicySize {
+		return float64(s.status.LeaderSize) / s.status.Settings.LeaderWeight
+	}
+	return float64(s.status.LeaderCount) / s.status.Settings.LeaderWeight
 }
 
-func (s *storeInfo) regionCount() uint64 {
+func (s *StoreInfo) regionCount() uint64 {
 	return uint64(s.status.RegionCount)
 }
 
-func (s *storeInfo) regionScore() float64 {
+func (s *StoreInfo) regionSize() uint64 {
+	return s.status.RegionSize
+}
+
+// regionScore is the total size of the regions relative to the capacity, so
+// stores with similar region counts but different region sizes are still
+// balanced by data volume. The leader count and the written bytes are added
+// to the size by their weights.
+func (s *StoreInfo) regionScore(weights StoreScoreConfig) float64 {
 	if s.status.GetCapacity() == 0 {
 		return 0
 	}
-	return float64(s.status.RegionCount) / float64(s.status.GetCapacity())
+	size := weights.RegionSizeWeight*float64(s.status.RegionSize) +
+		weights.LeaderCountWeight*float64(s.status.LeaderCount)*defaultRegionSize +
+		weights.FlowWeight*float64(s.status.GetBytesWritten())
+	return size / s.capacityFactor(weights)
 }
 
-func (s *storeInfo) storageSize() uint64 {
+// capacityFactor is the capacity to the power of the capacity weight, times
+// the region weight of the store, the region score is relative to it.
+func (s *StoreInfo) capacityFactor(weights StoreScoreConfig) float64 {
+	return math.Pow(float64(s.status.GetCapacity()), weights.CapacityWeight) * s.status.Settings.RegionWeight
+}
+
+// snapshotLimit returns the max snapshot count of the store.
+func (s *StoreInfo) snapshotLimit(opt ScheduleOptions) uint64 {
+	if s.status.Settings.SnapshotLimit > 0 {
+		return s.status.Settings.SnapshotLimit
+	}
+	return opt.GetMaxSnapshotCount()
+}
+
+func (s *StoreInfo) storageSize() uint64 {
 	return s.status.UsedSize
 }
 
-func (s *storeInfo) availableRatio() float64 {
+func (s *StoreInfo) availableRatio() float64 {
 	if s.status.GetCapacity() == 0 {
 		return 0
 	}
 	return float64(s.status.GetAvailable()) / float64(s.status.GetCapacity())
 }
 
-func (s *storeInfo) resourceCount(kind ResourceKind) uint64 {
+// usedRatio returns the ratio of the used space, a store without capacity is
+// treated as full.
+func (s *StoreInfo) usedRatio() float64 {
+	return 1 - s.availableRatio()
+}
+
+// isLowSpace checks whether the used space ratio of the store is over the
+// low space ratio, the stores without capacity are not low space as their
+// space is unknown.
+func (s *StoreInfo) isLowSpace(lowSpaceRatio float64) bool {
+	return s.status.GetCapacity() > 0 && s.usedRatio() > lowSpaceRatio
+}
+
+func (s *StoreInfo) resourceCount(kind ResourceKind) uint64 {
 	switch kind {
 	case LeaderKind:
 		return s.leaderCount()
@@ -111,18 +165,18 @@ func (s *storeInfo) resourceCount(kind ResourceKind) uint64 {
 	}
 }
 
-func (s *storeInfo) resourceScore(kind ResourceKind) float64 {
+func (s *StoreInfo) resourceScore(kind ResourceKind, leaderPolicy string, weights StoreScoreConfig) float64 {
 	switch kind {
 	case LeaderKind:
-		return s.leaderScore()
+		return s.leaderScore(leaderPolicy)
 	case RegionKind:
-		return s.regionScore()
+		return s.regionScore(weights)
 	default:
 		return 0
 	}
 }
 
-func (s *storeInfo) getLabelValue(key string) string {
+func (s *StoreInfo) getLabelValue(key string) string {
 	for _, label := range s.GetLabels() {
 		if label.GetKey() == key {
 			return label.GetValue()
@@ -131,42 +185,100 @@ func (s *storeInfo) getLabelValue(key string) string {
 	return ""
 }
 
-func (s *storeInfo) getLocationID(keys []string) string {
-	id := ""
-	for _, k := range keys {
-		v := s.getLabelValue(k)
-		if len(v) == 0 {
-			return ""
+// EngineLabelKey is the store label key of the storage engine. The stores
+// labeled with a special engine, such as columnar stores for analytics, only
+// hold the replicas placed by the rules targeting them.
+const EngineLabelKey = "engine"
+
+// specialEngines are the engines of the special stores, the stores labeled
+// with the other engines, e.g. "tikv", are ordinary.
+var specialEngines = map[string]struct{}{
+	"tiflash":  {},
+	"columnar": {},
+}
+
+// isSpecialEngine checks whether the store is labeled with a special engine.
+func (s *StoreInfo) isSpecialEngine() bool {
+	_, ok := specialEngines[s.getLabelValue(EngineLabelKey)]
+	return ok
+}
+
+// getLocationDiffLevel returns the first level of the location labels that
+// the stores are different at, or len(keys) if they are in the same location.
+// A missing label is treated as the same location as any store at the level,
+// so the isolation known by the other levels still counts.
+func (s *StoreInfo) getLocationDiffLevel(other *StoreInfo, keys []string) int {
+	for i, k := range keys {
+		v1, v2 := s.getLabelValue(k), other.getLabelValue(k)
+		if len(v1) != 0 && len(v2) != 0 && v1 != v2 {
+			return i
 		}
-		id += v
 	}
-	return id
+	return len(keys)
+}
+
+// StoreSettings are the schedule settings of a store set by the API. The
+// leader and region scores of the store are divided by the weights, so a store
+// with higher weights gets more leaders or regions. The snapshot limit
+// replaces max-snapshot-count for the store if it is not 0. Destroyed confirms
+// that a tombstone store is physically destroyed, so it never comes back.
+type StoreSettings struct {
+	LeaderWeight  float64 `json:"leader_weight"`
+	RegionWeight  float64 `json:"region_weight"`
+	SnapshotLimit uint64  `json:"snapshot_limit"`
+	Destroyed     bool    `json:"destroyed"`
+}
+
+func newStoreSettings() StoreSettings {
+	return StoreSettings{
+		LeaderWeight: 1,
+		RegionWeight: 1,
+	}
+}
+
+func (s StoreSettings) validate() error {
+	if s.LeaderWeight <= 0 || s.RegionWeight <= 0 {
+		return errors.New("store weight should be positive")
+	}
+	return nil
 }
 
 // StoreStatus contains information about a store's status.
 type StoreStatus struct {
 	*pdpb.StoreStats
+	Settings StoreSettings
 
 	// Blocked means that the store is blocked from balance.
-	blocked         bool
-	LeaderCount     int
-	RegionCount     int
-	LastHeartbeatTS time.Time `json:"last_heartbeat_ts"`
+	blocked bool
+	// tombstoneTS is when the store is found to be tombstone.
+	tombstoneTS      time.Time
+	LeaderCount      int
+	LeaderSize       uint64
+	RegionCount      int
+	RegionSize       uint64
+	PendingPeerCount int
+	LastHeartbeatTS  time.Time `json:"last_heartbeat_ts"`
 }
 
 func newStoreStatus() *StoreStatus {
 	return &StoreStatus{
 		StoreStats: &pdpb.StoreStats{},
+		Settings:   newStoreSettings(),
 	}
 }
 
 func (s *StoreStatus) clone() *StoreStatus {
 	return &StoreStatus{
-		StoreStats:      proto.Clone(s.StoreStats).(*pdpb.StoreStats),
-		blocked:         s.blocked,
-		LeaderCount:     s.LeaderCount,
-		RegionCount:     s.RegionCount,
-		LastHeartbeatTS: s.LastHeartbeatTS,
+		StoreStats:       proto.Clone(s.StoreStats).(*pdpb.StoreStats),
+		Settings:         s.Settings,
+		blocked:          s.blocked,
+		tombstoneTS:      s.tombstoneTS,
+		LeaderCount:      s.LeaderCount,
+		LeaderSize:       s.LeaderSize,
+		RegionCount:      s.RegionCount,
+		RegionSize:       s.RegionSize,
+		PendingPeerCount: s.PendingPeerCount,
+		LastHeartbeatTS:  s.LastHeartbeatTS,
 	}
 }
 
@@ -184,9 +296,8 @@ func (s *StoreStatus) GetUptime() time.Duration {
 	return 0
 }
 
-const defaultStoreDownTime = time.Minute
-
-// IsDown returns whether the store is down
-func (s *StoreStatus) IsDown() bool {
-	return time.Now().Sub(s.LastHeartbeatTS) > defaultStoreDownTime
+// IsDown returns whether the store hasn't sent heartbeats for maxDownTime,
+// which is max-store-down-time of the schedule config.
+func (s *StoreStatus) IsDown(maxDownTime time.Duration) bool {
+	return time.Since(s.LastHeartbeatTS) > maxDownTime
 }
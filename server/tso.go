@@ -22,6 +22,8 @@ import (
 	"github.com/coreos/etcd/clientv3"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
+	"github.com/pingcap/pd/pkg/monotime"
 )
 
 const (
@@ -29,15 +31,22 @@ const (
 	updateTimestampStep  = 50 * time.Millisecond
 	updateTimestampGuard = time.Millisecond
 	maxLogical           = int64(1 << 18)
+	// the leader campaigns again after clockJumpRetryDelay if its clock jumps,
+	// it's longer than the lease so the other members can be elected first.
+	clockJumpRetryDelay = 5 * time.Second
 )
 
 var (
 	zeroTime = time.Time{}
+
+	errClockJump = errors.New("clock jumps")
 )
 
 type atomicObject struct {
 	physical time.Time
 	logical  int64
+	// monotonic is the reading of the monotonic clock when physical is read.
+	monotonic time.Duration
 }
 
 func (s *Server) getTimestampPath() string {
@@ -82,6 +91,13 @@ func (s *Server) syncTimestamp() error {
 
 	var now time.Time
 
+	// The saved timestamp is at most tso-save-interval ahead of the clock of
+	// the former leader, waiting longer means the clock is behind too much.
+	now = time.Now()
+	if jump := last.Sub(now); jump > s.cfg.TsoMaxClockJump.Duration {
+		return errors.Trace(s.clockJumped(-jump, last, now))
+	}
+
 	for {
 		now = time.Now()
 		if wait := last.Sub(now) + updateTimestampGuard; wait > 0 {
@@ -100,7 +116,8 @@ func (s *Server) syncTimestamp() error {
 	log.Infof("sync and save timestamp: last %v save %v", last, save)
 
 	current := &atomicObject{
-		physical: now,
+		physical:  now,
+		monotonic: monotime.Now(),
 	}
 	s.ts.Store(current)
 
@@ -108,8 +125,19 @@ func (s *Server) syncTimestamp() error {
 }
 
 func (s *Server) updateTimestamp() error {
-	prev := s.ts.Load().(*atomicObject).physical
-	now := time.Now()
+	prevObj := s.ts.Load().(*atomicObject)
+	prev := prevObj.physical
+	now, mono := time.Now(), monotime.Now()
+
+	// The monotonic clock doesn't jump, the difference of the wall clock from
+	// it is how far the wall clock jumps.
+	jump := time.Duration(now.UnixNano()-prev.UnixNano()) - (mono - prevObj.monotonic)
+	if v, ok := failpoint.Eval("tsoClockJump"); ok {
+		jump += time.Duration(v.(int)) * time.Millisecond
+	}
+	if jump > s.cfg.TsoMaxClockJump.Duration || -jump > s.cfg.TsoMaxClockJump.Duration {
+		return errors.Trace(s.clockJumped(jump, prev, now))
+	}
 
 	since := now.Sub(prev)
 	if since > 3*updateTimestampStep {
@@ -132,13 +160,26 @@ func (s *Server) updateTimestamp() error {
 	}
 
 	current := &atomicObject{
-		physical: now,
+		physical:  now,
+		monotonic: mono,
 	}
 	s.ts.Store(current)
 
 	return nil
 }
 
+// clockJumped reports the jump of the clock and returns errClockJump, the
+// leader stops serving the TSO until the clock is valid.
+func (s *Server) clockJumped(jump time.Duration, prev, now time.Time) error {
+	direction := "forward"
+	if jump < 0 {
+		direction, jump = "backward", -jump
+	}
+	tsoClockJumpCounter.WithLabelValues(direction).Inc()
+	s.notifyAlert(alertClockJump, 0, "the clock of %s jumps %s by %v, prev: %v, now: %v, stop serving the TSO", s.Name(), direction, jump, prev, now)
+	return errors.Annotatef(errClockJump, "%s by %v", direction, jump)
+}
+
 const maxRetryCount = 100
 
 func (s *Server) getRespTS(count uint32) (pdpb.Timestamp, error) {
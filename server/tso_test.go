@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"golang.org/x/net/context"
@@ -64,6 +65,17 @@ func (s *testTsoSuite) testGetTimestamp(c *C, n int) *pdpb.Timestamp {
 	return res
 }
 
+func (s *testTsoSuite) TestClockJumpBackward(c *C) {
+	// The saved timestamp is too far ahead of the clock.
+	last := time.Now().Add(2 * s.svr.cfg.TsoMaxClockJump.Duration)
+	c.Assert(s.svr.saveTimestamp(last), IsNil)
+	defer s.svr.saveTimestamp(time.Now())
+
+	err := s.svr.syncTimestamp()
+	c.Assert(errors.Cause(err), Equals, errClockJump)
+	c.Assert(err, ErrorMatches, ".*backward.*")
+}
+
 func mustGetLeader(c *C, client *clientv3.Client, leaderPath string) *pdpb.Member {
 	for i := 0; i < 20; i++ {
 		leader, err := getLeader(client, leaderPath)
@@ -104,3 +116,76 @@ func (s *testTsoSuite) TestTso(c *C) {
 
 	wg.Wait()
 }
+
+func (s *testTsoSuite) TestTsoBatch(c *C) {
+	tsoClient, err := s.grpcPDClient.Tso(context.Background())
+	c.Assert(err, IsNil)
+	defer tsoClient.CloseSend()
+
+	// Send all requests before receiving, so they can be merged.
+	n := maxMergeTsoRequests * 3
+	for i := 1; i <= n; i++ {
+		req := &pdpb.TsoRequest{
+			Header: newRequestHeader(s.svr.clusterID),
+			Count:  uint32(i),
+		}
+		c.Assert(tsoClient.Send(req), IsNil)
+	}
+
+	last := &pdpb.Timestamp{}
+	for i := 1; i <= n; i++ {
+		resp, err := tsoClient.Recv()
+		c.Assert(err, IsNil)
+		c.Assert(resp.GetCount(), Equals, uint32(i))
+
+		// The first logical of the range must be greater than the last one
+		// of the previous response.
+		ts := resp.GetTimestamp()
+		c.Assert(ts.GetPhysical(), Not(Less), last.GetPhysical())
+		if ts.GetPhysical() == last.GetPhysical() {
+			c.Assert(ts.GetLogical()-int64(i), Not(Less), last.GetLogical())
+		}
+		last = ts
+	}
+}
+
+var _ = Suite(&testFollowerTsoSuite{})
+
+type testFollowerTsoSuite struct{}
+
+func (s *testFollowerTsoSuite) TestForwardTso(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	tsoClient, err := mustNewGrpcClient(c, follower.GetAddr()).Tso(context.Background())
+	c.Assert(err, IsNil)
+	defer tsoClient.CloseSend()
+
+	last := &pdpb.Timestamp{}
+	for i := 0; i < 10; i++ {
+		req := &pdpb.TsoRequest{
+			Header: newRequestHeader(leader.clusterID),
+			Count:  10,
+		}
+		c.Assert(tsoClient.Send(req), IsNil)
+		resp, err := tsoClient.Recv()
+		c.Assert(err, IsNil)
+		c.Assert(resp.GetCount(), Equals, uint32(10))
+
+		ts := resp.GetTimestamp()
+		c.Assert(ts.GetPhysical(), Not(Less), last.GetPhysical())
+		if ts.GetPhysical() == last.GetPhysical() {
+			c.Assert(ts.GetLogical(), Greater, last.GetLogical())
+		}
+		last = ts
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
@@ -49,20 +50,23 @@ const (
 
 // Version information.
 var (
-	PDBuildTS = "None"
-	PDGitHash = "None"
+	PDReleaseVersion = "None"
+	PDBuildTS        = "None"
+	PDGitHash        = "None"
 )
 
 // LogPDInfo prints the PD version information.
 func LogPDInfo() {
 	log.Infof("Welcome to Placement Driver (PD).")
 	log.Infof("Version:")
+	log.Infof("Release Version: %s", PDReleaseVersion)
 	log.Infof("Git Commit Hash: %s", PDGitHash)
 	log.Infof("UTC Build Time:  %s", PDBuildTS)
 }
 
 // PrintPDInfo prints the PD version information without log info.
 func PrintPDInfo() {
+	fmt.Println("Release Version:", PDReleaseVersion)
 	fmt.Println("Git Commit Hash:", PDGitHash)
 	fmt.Println("UTC Build Time: ", PDBuildTS)
 }
@@ -289,6 +293,13 @@ func minDuration(a, b time.Duration) time.Duration {
 	return b
 }
 
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func diffRegionPeersInfo(origin *RegionInfo, other *RegionInfo) string {
 	var ret []string
 	for _, a := range origin.Peers {
@@ -342,3 +353,21 @@ func parseTimestamp(data []byte) (time.Time, error) {
 
 	return time.Unix(0, int64(nano)), nil
 }
+
+// getLocalEndpoint returns the endpoint to connect a listen url locally, the
+// unspecified address like 0.0.0.0 is replaced by the loopback address.
+func getLocalEndpoint(u url.URL) string {
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return u.String()
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		if ip.To4() != nil {
+			host = "127.0.0.1"
+		} else {
+			host = "::1"
+		}
+		u.Host = net.JoinHostPort(host, port)
+	}
+	return u.String()
+}
@@ -15,6 +15,7 @@ package server
 
 import (
 	"math/rand"
+	"net/url"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -59,3 +60,19 @@ func (s *testUtilSuite) TestParseTimestap(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(nt.Equal(zeroTime), IsTrue)
 }
+
+func (s *testUtilSuite) TestGetLocalEndpoint(c *C) {
+	testCases := []struct {
+		listen, endpoint string
+	}{
+		{"http://0.0.0.0:2379", "http://127.0.0.1:2379"},
+		{"http://[::]:2379", "http://[::1]:2379"},
+		{"http://192.168.1.1:2379", "http://192.168.1.1:2379"},
+		{"unix://localhost:2379", "unix://localhost:2379"},
+	}
+	for _, t := range testCases {
+		u, err := url.Parse(t.listen)
+		c.Assert(err, IsNil)
+		c.Assert(getLocalEndpoint(*u), Equals, t.endpoint)
+	}
+}